@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
+	"github.com/dgraph-io/ristretto/v2"
+	"go.uber.org/zap"
+)
+
+// Cache comparison mode (-compare)
+//
+// Replays one generated workload against EnterpriseCache, a sync.Map with
+// per-entry TTL and ristretto, each bounded to the same number of entries
+// where the implementation allows it, and prints hit rate, Get latency,
+// throughput and heap growth side by side. The workload is cache-aside: a
+// Get miss is followed by a Set, so admission and eviction policies show up
+// in the hit rate.
+
+// compareOptions describes the workload every cache is run against
+type compareOptions struct {
+	Ops       int     // Operations in total, split across workers
+	Workers   int     // Concurrent goroutines
+	Keys      int     // Distinct keys
+	Capacity  int     // Entries each cache may hold
+	ReadRatio float64 // Fraction of operations that are Gets; the rest overwrite
+	ValueSize int     // Bytes per value
+	TTL       time.Duration
+	Zipf      bool // Skewed keys; uniform otherwise
+	ZipfS     float64
+	ZipfV     float64
+	Seed      int64
+}
+
+// comparedCache is the surface the comparison drives
+type comparedCache interface {
+	Get(key string) bool
+	Set(key string, value []byte, ttl time.Duration)
+	Close()
+}
+
+// compareResult is one row of the comparison table
+type compareResult struct {
+	Name      string
+	Bounded   bool
+	Gets      uint64
+	Hits      uint64
+	P50       time.Duration
+	P99       time.Duration
+	P999      time.Duration
+	OpsPerSec float64
+	HeapBytes int64
+}
+
+// compareOp is one generated operation
+type compareOp struct {
+	key   int32
+	write bool
+}
+
+// runComparison runs the workload against each cache in turn and prints
+// the table to stdout
+func runComparison(opts compareOptions) error {
+	if opts.Workers < 1 || opts.Ops < opts.Workers || opts.Keys < 1 || opts.Capacity < 1 {
+		return fmt.Errorf("invalid comparison workload: %+v", opts)
+	}
+	keys := make([]string, opts.Keys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("k_%d", i)
+	}
+	plans := generateWorkload(opts)
+	values := make([][]byte, 64)
+	rng := rand.New(rand.NewSource(opts.Seed))
+	for i := range values {
+		values[i] = make([]byte, opts.ValueSize)
+		rng.Read(values[i])
+	}
+
+	builders := []struct {
+		name    string
+		bounded bool
+		build   func() (comparedCache, error)
+	}{
+		{"EnterpriseCache", true, func() (comparedCache, error) { return newEnterpriseCompared(opts) }},
+		{"sync.Map+TTL", false, func() (comparedCache, error) { return &syncMapCache{}, nil }},
+		{"ristretto", true, func() (comparedCache, error) { return newRistrettoCompared(opts) }},
+	}
+
+	fmt.Printf("workload: ops=%d workers=%d keys=%d capacity=%d reads=%.0f%% value=%dB zipf=%v\n",
+		opts.Ops, opts.Workers, opts.Keys, opts.Capacity, opts.ReadRatio*100, opts.ValueSize, opts.Zipf)
+
+	results := make([]compareResult, 0, len(builders))
+	for _, b := range builders {
+		runtime.GC()
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+
+		c, err := b.build()
+		if err != nil {
+			return fmt.Errorf("%s: %w", b.name, err)
+		}
+		res := runWorkload(c, plans, keys, values, opts.TTL)
+		res.Name, res.Bounded = b.name, b.bounded
+
+		runtime.GC()
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		res.HeapBytes = int64(after.HeapAlloc) - int64(before.HeapAlloc)
+		runtime.KeepAlive(c)
+		c.Close()
+
+		results = append(results, res)
+	}
+
+	printComparison(results)
+	return nil
+}
+
+// generateWorkload builds each worker's operations up front, so every
+// cache sees exactly the same sequence
+func generateWorkload(opts compareOptions) [][]compareOp {
+	plans := make([][]compareOp, opts.Workers)
+	perWorker := opts.Ops / opts.Workers
+	for w := range plans {
+		rng := rand.New(rand.NewSource(opts.Seed + int64(w)))
+		var zipf *rand.Zipf
+		if opts.Zipf {
+			zipf = rand.NewZipf(rng, opts.ZipfS, opts.ZipfV, uint64(opts.Keys-1))
+		}
+		plan := make([]compareOp, perWorker)
+		for i := range plan {
+			if zipf != nil {
+				plan[i].key = int32(zipf.Uint64())
+			} else {
+				plan[i].key = int32(rng.Intn(opts.Keys))
+			}
+			plan[i].write = rng.Float64() >= opts.ReadRatio
+		}
+		plans[w] = plan
+	}
+	return plans
+}
+
+// runWorkload replays plans against c and measures every Get
+func runWorkload(c comparedCache, plans [][]compareOp, keys []string, values [][]byte, ttl time.Duration) compareResult {
+	var gets, hits uint64
+	latencies := make([][]time.Duration, len(plans))
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w, plan := range plans {
+		wg.Add(1)
+		go func(w int, plan []compareOp) {
+			defer wg.Done()
+			lat := make([]time.Duration, 0, len(plan))
+			var localGets, localHits uint64
+			for i, op := range plan {
+				key := keys[op.key]
+				value := values[i%len(values)]
+				if op.write {
+					c.Set(key, value, ttl)
+					continue
+				}
+				t0 := time.Now()
+				hit := c.Get(key)
+				lat = append(lat, time.Since(t0))
+				localGets++
+				if hit {
+					localHits++
+				} else {
+					c.Set(key, value, ttl)
+				}
+			}
+			latencies[w] = lat
+			atomic.AddUint64(&gets, localGets)
+			atomic.AddUint64(&hits, localHits)
+		}(w, plan)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var all []time.Duration
+	total := 0
+	for w, lat := range latencies {
+		all = append(all, lat...)
+		total += len(plans[w])
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	return compareResult{
+		Gets:      gets,
+		Hits:      hits,
+		P50:       percentile(all, 0.50),
+		P99:       percentile(all, 0.99),
+		P999:      percentile(all, 0.999),
+		OpsPerSec: float64(total) / elapsed.Seconds(),
+	}
+}
+
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func printComparison(results []compareResult) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "cache\tbounded\thit rate\tget p50\tget p99\tget p99.9\tops/sec\theap MB\t")
+	for _, r := range results {
+		hitRate := 0.0
+		if r.Gets > 0 {
+			hitRate = float64(r.Hits) / float64(r.Gets) * 100
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%.2f%%\t%v\t%v\t%v\t%.0f\t%.1f\t\n",
+			r.Name, r.Bounded, hitRate, r.P50, r.P99, r.P999, r.OpsPerSec, float64(r.HeapBytes)/1024/1024)
+	}
+	tw.Flush()
+}
+
+// enterpriseCompared adapts EnterpriseCache with the extras that would
+// skew a like-for-like comparison (warmup, its own breaker) turned off
+type enterpriseCompared struct {
+	ec *cache.EnterpriseCache
+}
+
+func newEnterpriseCompared(opts compareOptions) (*enterpriseCompared, error) {
+	cfg := cache.DefaultCacheConfig()
+	cfg.MaxEntries = opts.Capacity
+	cfg.PreallocateEntries = opts.Capacity
+	cfg.BloomFilterSize = uint(opts.Keys)
+	cfg.EnableWarmup = false
+	cfg.EnableCircuitBreaker = false
+	cfg.EnableHealthChecks = false
+	ec, err := cache.NewEnterpriseCache(cfg, zap.NewNop())
+	if err != nil {
+		return nil, err
+	}
+	return &enterpriseCompared{ec: ec}, nil
+}
+
+func (c *enterpriseCompared) Get(key string) bool {
+	_, ok := c.ec.Get(key)
+	return ok
+}
+
+func (c *enterpriseCompared) Set(key string, value []byte, ttl time.Duration) {
+	_ = c.ec.Set(key, value, ttl)
+}
+
+func (c *enterpriseCompared) Close() {
+	_ = c.ec.Shutdown(context.Background())
+}
+
+// syncMapCache is the baseline: a sync.Map with lazy expiry and no bound
+type syncMapCache struct {
+	m sync.Map
+}
+
+type syncMapEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (c *syncMapCache) Get(key string) bool {
+	v, ok := c.m.Load(key)
+	if !ok {
+		return false
+	}
+	if time.Now().After(v.(syncMapEntry).expiresAt) {
+		c.m.Delete(key)
+		return false
+	}
+	return true
+}
+
+func (c *syncMapCache) Set(key string, value []byte, ttl time.Duration) {
+	c.m.Store(key, syncMapEntry{value: value, expiresAt: time.Now().Add(ttl)})
+}
+
+func (c *syncMapCache) Close() {}
+
+// ristrettoCompared bounds ristretto by entry count, each entry costing 1
+type ristrettoCompared struct {
+	rc *ristretto.Cache[string, []byte]
+}
+
+func newRistrettoCompared(opts compareOptions) (*ristrettoCompared, error) {
+	rc, err := ristretto.NewCache(&ristretto.Config[string, []byte]{
+		NumCounters: int64(opts.Capacity) * 10, // Ten counters per entry, as ristretto recommends
+		MaxCost:     int64(opts.Capacity),
+		BufferItems: 64,
+		// Cost counts entries, not bytes
+		IgnoreInternalCost: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ristrettoCompared{rc: rc}, nil
+}
+
+func (c *ristrettoCompared) Get(key string) bool {
+	_, ok := c.rc.Get(key)
+	return ok
+}
+
+func (c *ristrettoCompared) Set(key string, value []byte, ttl time.Duration) {
+	c.rc.SetWithTTL(key, value, 1, ttl)
+}
+
+func (c *ristrettoCompared) Close() {
+	c.rc.Close()
+}
@@ -27,6 +27,12 @@ func main() {
     var useZipf bool
     var zipfS float64
     var zipfV float64
+    var compare bool
+    var compareOps int
+    var compareKeys int
+    var compareCapacity int
+    var compareReads float64
+    var compareValue int
 
     flag.IntVar(&durationSec, "duration", 300, "duration in seconds (5-15 minutes recommended)")
     flag.IntVar(&tps, "tps", 2000, "target total operations per second")
@@ -35,8 +41,35 @@ func main() {
     flag.BoolVar(&useZipf, "zipf", false, "use Zipfian key distribution (hot keys)")
     flag.Float64Var(&zipfS, "zipf_s", 1.07, "Zipf s parameter (skew)")
     flag.Float64Var(&zipfV, "zipf_v", 1.0, "Zipf v parameter")
+    flag.BoolVar(&compare, "compare", false, "compare EnterpriseCache with sync.Map+TTL and ristretto on one workload, then exit")
+    flag.IntVar(&compareOps, "compare_ops", 2000000, "operations per cache in -compare mode")
+    flag.IntVar(&compareKeys, "compare_keys", 100000, "distinct keys in -compare mode")
+    flag.IntVar(&compareCapacity, "compare_capacity", 10000, "entries each cache may hold in -compare mode")
+    flag.Float64Var(&compareReads, "compare_reads", 0.9, "fraction of -compare operations that are reads")
+    flag.IntVar(&compareValue, "compare_value", 1024, "value size in bytes in -compare mode")
     flag.Parse()
 
+    if compare {
+        err := runComparison(compareOptions{
+            Ops:       compareOps,
+            Workers:   workers,
+            Keys:      compareKeys,
+            Capacity:  compareCapacity,
+            ReadRatio: compareReads,
+            ValueSize: compareValue,
+            TTL:       cache.DefaultCacheConfig().DefaultTTL,
+            Zipf:      useZipf,
+            ZipfS:     zipfS,
+            ZipfV:     zipfV,
+            Seed:      1,
+        })
+        if err != nil {
+            fmt.Println("comparison failed:", err)
+            os.Exit(1)
+        }
+        return
+    }
+
     if durationSec <= 0 {
         fmt.Println("invalid duration")
         return
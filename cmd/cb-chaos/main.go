@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
@@ -17,6 +20,7 @@ import (
 type FailureInjectionTool struct {
 	breakers  map[string]*circuitbreaker.EnterpriseCircuitBreaker
 	scenarios map[string]FailureScenario
+	remote    *RemoteTargets // Set to inject into a running service's breakers
 }
 
 // FailureScenario defines a specific failure injection scenario
@@ -29,6 +33,7 @@ type FailureScenario struct {
 	Intensity    float64                `json:"intensity"` // 0.0 - 1.0
 	Schedule     ScheduleType           `json:"schedule"`
 	Parameters   map[string]interface{} `json:"parameters"`
+	SteadyState  *SteadyStateHypothesis `json:"steady_state,omitempty"`
 }
 
 // FailureType defines different types of failures to inject
@@ -39,12 +44,18 @@ type FailureType struct {
 }
 
 // ScheduleType defines when failures should occur
+//
+// A "cron" schedule only runs in server mode: each activation executes the
+// scenario for its Duration, injecting every Interval (or once if unset).
 type ScheduleType struct {
-	Type       string                 `json:"type"` // immediate, delayed, periodic, random
-	StartDelay time.Duration          `json:"start_delay,omitempty"`
-	Interval   time.Duration          `json:"interval,omitempty"`
-	EndTime    *time.Time             `json:"end_time,omitempty"`
-	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Type        string                 `json:"type"` // immediate, delayed, periodic, random, cron
+	StartDelay  time.Duration          `json:"start_delay,omitempty"`
+	Interval    time.Duration          `json:"interval,omitempty"`
+	EndTime     *time.Time             `json:"end_time,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+	Cron        string                 `json:"cron,omitempty"`     // e.g. "0 10 * * 1-5"
+	Timezone    string                 `json:"timezone,omitempty"` // IANA name, default local
+	SkipWindows []SkipWindow           `json:"skip_windows,omitempty"`
 }
 
 // InjectionResult tracks the results of failure injection
@@ -57,6 +68,9 @@ type InjectionResult struct {
 	CircuitBreakers  []CircuitBreakerState `json:"circuit_breakers"`
 	Events           []InjectionEvent      `json:"events"`
 	DroppedEvents    int64                 `json:"dropped_events,omitempty"`
+	Aborted          bool                  `json:"aborted"`
+	Abort            *AbortRecord          `json:"abort,omitempty"`
+	SteadyState      []ProbeReport         `json:"steady_state,omitempty"`
 	Summary          InjectionSummary      `json:"summary"`
 }
 
@@ -99,17 +113,41 @@ func main() {
 		serverMode   = flag.Bool("server", false, "Run in server mode for remote control")
 		serverPort   = flag.String("port", "8091", "Server mode port")
 		dryRun       = flag.Bool("dry-run", false, "Perform dry run without actual injection")
+		resultsDir   = flag.String("results-dir", "chaos-results", "Directory for scheduled run results and history (server mode)")
+		retainRuns   = flag.Int("retain-runs", 200, "Stored results kept, newest first; 0 keeps all (server mode)")
+		retainAge    = flag.Duration("retain-age", 30*24*time.Hour, "Delete stored results older than this; 0 keeps them (server mode)")
+		monitorURL   = flag.String("monitor", "", "Inject into the breakers of the cb-monitor at this URL")
+		apiURL       = flag.String("api", "", "Inject into the chain breakers of the API server at this URL (admin key from ADMIN_API_KEY)")
 	)
 	flag.Parse()
 
 	tool := NewFailureInjectionTool()
 
+	switch {
+	case *monitorURL != "" && *apiURL != "":
+		log.Fatal("Use one of -monitor and -api")
+	case *monitorURL != "":
+		tool.remote = NewRemoteTargets(remoteMonitor, *monitorURL, os.Getenv("ADMIN_API_KEY"))
+		log.Printf("Injecting into breakers of cb-monitor at %s", *monitorURL)
+	case *apiURL != "":
+		tool.remote = NewRemoteTargets(remoteAPI, *apiURL, os.Getenv("ADMIN_API_KEY"))
+		log.Printf("Injecting into chain breakers of API server at %s", *apiURL)
+	}
+
 	// Initialize built-in scenarios
 	tool.initializeBuiltInScenarios()
 
 	if *serverMode {
+		if *scenarioFile != "" {
+			scenario, err := loadScenarioFromFile(*scenarioFile)
+			if err != nil {
+				log.Fatalf("Failed to load scenario: %v", err)
+			}
+			tool.scenarios[scenario.Name] = scenario
+		}
+
 		log.Printf("Starting failure injection server on port %s", *serverPort)
-		startServer(tool, *serverPort)
+		startServer(tool, *serverPort, *resultsDir, RetentionPolicy{MaxRuns: *retainRuns, MaxAge: *retainAge})
 		return
 	}
 
@@ -124,6 +162,9 @@ func main() {
 	} else {
 		// Create default scenario
 		scenario = createDefaultScenario(*duration, *intensity, *targets)
+		if tool.remote != nil && *targets == "" {
+			scenario.Targets = nil // Discovered from the remote service
+		}
 	}
 
 	log.Printf("Starting failure injection scenario: %s", scenario.Name)
@@ -178,17 +219,41 @@ func (fit *FailureInjectionTool) ExecuteScenario(scenario FailureScenario) (*Inj
 	log.Printf("Executing scenario: %s", scenario.Name)
 	log.Printf("Description: %s", scenario.Description)
 
+	// Without named targets, a remote run targets every breaker it finds
+	if fit.remote != nil && len(scenario.Targets) == 0 {
+		targets, err := fit.remote.Discover(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Discovered %d remote breaker(s): %v", len(targets), targets)
+		scenario.Targets = targets
+	}
+
 	// Capture initial states
 	initialStates := make(map[string]string)
-	for _, target := range scenario.Targets {
-		if cb, exists := fit.breakers[target]; exists {
-			initialStates[target] = cb.State().String()
-		}
+	for target, status := range fit.breakerStates(scenario.Targets) {
+		initialStates[target] = status.State
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), scenario.Duration)
 	defer cancel()
 
+	// Guard the run with the steady state hypothesis, if declared
+	var guard *steadyStateGuard
+	var guardDone chan struct{}
+	if scenario.SteadyState != nil && len(scenario.SteadyState.Probes) > 0 {
+		guard = newSteadyStateGuard(*scenario.SteadyState)
+		if err := guard.verify(ctx); err != nil {
+			return nil, fmt.Errorf("steady state not met before injection: %w", err)
+		}
+
+		guardDone = make(chan struct{})
+		go func() {
+			defer close(guardDone)
+			guard.run(ctx, cancel)
+		}()
+	}
+
 	// Execute failure injection based on schedule
 	switch scenario.Schedule.Type {
 	case "immediate":
@@ -206,25 +271,50 @@ func (fit *FailureInjectionTool) ExecuteScenario(scenario FailureScenario) (*Inj
 		if err != nil {
 			return nil, err
 		}
+	case "cron":
+		// The scheduler decides when a run starts; within the run, inject
+		// periodically if an interval is set and once otherwise
+		var err error
+		if scenario.Schedule.Interval > 0 {
+			err = fit.executePeriodicFailures(ctx, scenario, result)
+		} else {
+			err = fit.executeImmediateFailures(ctx, scenario, result)
+		}
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("unsupported schedule type: %s", scenario.Schedule.Type)
 	}
 
+	if guard != nil {
+		// Immediate schedules return before the deadline; keep guarding
+		// until the scenario duration is over or the hypothesis breaks.
+		<-ctx.Done()
+		<-guardDone
+
+		result.Abort, result.SteadyState = guard.result()
+		if result.Abort != nil {
+			result.Aborted = true
+			result.Abort.Restored = fit.restoreForcedStates(scenario.Targets, initialStates)
+		}
+	}
+
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 
 	// Capture final states and collect metrics
+	finalStates := fit.breakerStates(scenario.Targets)
 	for _, target := range scenario.Targets {
-		if cb, exists := fit.breakers[target]; exists {
-			finalState := cb.State().String()
-			metrics := cb.GetMetrics()
-
+		if status, exists := finalStates[target]; exists {
 			cbState := CircuitBreakerState{
 				Name:         target,
 				InitialState: initialStates[target],
-				FinalState:   finalState,
-				StateChanges: int(metrics.StateChanges),
-				Metrics:      metrics,
+				FinalState:   status.State,
+				Metrics:      status.Metrics,
+			}
+			if status.Metrics != nil {
+				cbState.StateChanges = int(status.Metrics.StateChanges)
 			}
 
 			result.CircuitBreakers = append(result.CircuitBreakers, cbState)
@@ -243,6 +333,23 @@ func newRand() *rand.Rand {
 	return rand.New(rand.NewSource(time.Now().UnixNano()))
 }
 
+// sleepCtx waits for d and reports false if ctx ended first, so an aborted
+// run does not keep injecting after its delay
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
 const maxEventsStored = 10000 // safety cap to avoid unbounded memory growth; tune as needed
 
 // appendEvent appends an event to result.Events with a cap and increments DroppedEvents if capped
@@ -266,6 +373,23 @@ func (fit *FailureInjectionTool) DryRun(scenario FailureScenario) error {
 	}
 
 	log.Printf("DRY RUN: Schedule: %s", scenario.Schedule.Type)
+	if scenario.Schedule.Type == "cron" {
+		schedule, err := scenarioCron(scenario)
+		if err != nil {
+			return err
+		}
+		next := time.Now()
+		for i := 0; i < 3; i++ {
+			if next = schedule.Next(next); next.IsZero() {
+				break
+			}
+			if w, skipped := activeSkipWindow(scenario.Schedule.SkipWindows, next); skipped {
+				log.Printf("DRY RUN: Would skip run at %s (%s)", next.Format(time.RFC3339), w.Reason)
+				continue
+			}
+			log.Printf("DRY RUN: Would run at %s", next.Format(time.RFC3339))
+		}
+	}
 	log.Printf("DRY RUN: Intensity: %.2f", scenario.Intensity)
 
 	return nil
@@ -274,8 +398,8 @@ func (fit *FailureInjectionTool) DryRun(scenario FailureScenario) error {
 // executeImmediateFailures executes failures immediately upon scenario start
 func (fit *FailureInjectionTool) executeImmediateFailures(ctx context.Context, scenario FailureScenario, result *InjectionResult) error {
 	// Wait for start delay if specified
-	if scenario.Schedule.StartDelay > 0 {
-		time.Sleep(scenario.Schedule.StartDelay)
+	if !sleepCtx(ctx, scenario.Schedule.StartDelay) {
+		return nil
 	}
 
 	r := newRand()
@@ -298,8 +422,8 @@ func (fit *FailureInjectionTool) executeImmediateFailures(ctx context.Context, s
 // executePeriodicFailures executes failures at regular intervals
 func (fit *FailureInjectionTool) executePeriodicFailures(ctx context.Context, scenario FailureScenario, result *InjectionResult) error {
 	// Wait for start delay if specified
-	if scenario.Schedule.StartDelay > 0 {
-		time.Sleep(scenario.Schedule.StartDelay)
+	if !sleepCtx(ctx, scenario.Schedule.StartDelay) {
+		return nil
 	}
 
 	ticker := time.NewTicker(scenario.Schedule.Interval)
@@ -330,8 +454,8 @@ func (fit *FailureInjectionTool) executePeriodicFailures(ctx context.Context, sc
 // executeRandomFailures executes failures at random intervals
 func (fit *FailureInjectionTool) executeRandomFailures(ctx context.Context, scenario FailureScenario, result *InjectionResult) error {
 	// Wait for start delay if specified
-	if scenario.Schedule.StartDelay > 0 {
-		time.Sleep(scenario.Schedule.StartDelay)
+	if !sleepCtx(ctx, scenario.Schedule.StartDelay) {
+		return nil
 	}
 
 	for {
@@ -342,7 +466,9 @@ func (fit *FailureInjectionTool) executeRandomFailures(ctx context.Context, scen
 			// Random delay between failures and random selections using per-goroutine PRNG
 			r := newRand()
 			delay := time.Duration(r.Float64() * float64(scenario.Schedule.Interval))
-			time.Sleep(delay)
+			if !sleepCtx(ctx, delay) {
+				return nil
+			}
 
 			// Select random target
 			if len(scenario.Targets) == 0 {
@@ -377,6 +503,12 @@ func (fit *FailureInjectionTool) injectFailure(target string, failureType Failur
 		Parameters: failureType.Parameters,
 	}
 
+	if fit.remote != nil {
+		event = fit.injectRemoteFailure(event, failureType)
+		log.Printf("Injected failure: %s on %s - %s", failureType.Type, target, event.Description)
+		return event
+	}
+
 	cb, exists := fit.breakers[target]
 	if !exists {
 		event.Success = false
@@ -468,7 +600,7 @@ func (fit *FailureInjectionTool) generateRecommendations(result *InjectionResult
 				fmt.Sprintf("Circuit breaker %s did not change state - consider reviewing thresholds", cb.Name))
 		}
 
-		if cb.Metrics.FailureRate < 0.1 {
+		if cb.Metrics != nil && cb.Metrics.FailureRate < 0.1 {
 			recommendations = append(recommendations,
 				fmt.Sprintf("Circuit breaker %s has low failure rate - injection may not be effective", cb.Name))
 		}
@@ -479,6 +611,11 @@ func (fit *FailureInjectionTool) generateRecommendations(result *InjectionResult
 		}
 	}
 
+	if result.Abort != nil {
+		recommendations = append(recommendations,
+			fmt.Sprintf("Run aborted: steady state probe %s failed (%s) - investigate before raising intensity", result.Abort.Probe, result.Abort.Reason))
+	}
+
 	// Analyze failure injection effectiveness
 	if result.FailuresInjected == 0 {
 		recommendations = append(recommendations, "No failures were injected - review scenario configuration")
@@ -572,8 +709,16 @@ func createDefaultScenario(duration time.Duration, intensity float64, targets st
 }
 
 func loadScenarioFromFile(filename string) (FailureScenario, error) {
-	// Implementation would load JSON scenario from file
-	return FailureScenario{}, fmt.Errorf("file loading not implemented")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return FailureScenario{}, err
+	}
+
+	var scenario FailureScenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return FailureScenario{}, fmt.Errorf("parse scenario %s: %w", filename, err)
+	}
+	return scenario, nil
 }
 
 func printResults(result *InjectionResult) {
@@ -584,6 +729,22 @@ func printResults(result *InjectionResult) {
 	fmt.Printf("Events: %d\n", len(result.Events))
 	fmt.Printf("Effectiveness Score: %.2f\n", result.Summary.EffectivenessScore)
 
+	if result.Aborted {
+		fmt.Println("\n=== Aborted ===")
+		fmt.Printf("Probe %s violated %d time(s) at %s: %s\n",
+			result.Abort.Probe, result.Abort.Violations, result.Abort.Time.Format(time.RFC3339), result.Abort.Reason)
+		if len(result.Abort.Restored) > 0 {
+			fmt.Printf("Restored: %v\n", result.Abort.Restored)
+		}
+	}
+
+	if len(result.SteadyState) > 0 {
+		fmt.Println("\n=== Steady State ===")
+		for _, p := range result.SteadyState {
+			fmt.Printf("%s: %d checks, %d violations\n", p.Name, p.Checks, p.Violations)
+		}
+	}
+
 	fmt.Println("\n=== Circuit Breaker States ===")
 	for _, cb := range result.CircuitBreakers {
 		fmt.Printf("%s: %s -> %s (%d state changes)\n",
@@ -607,8 +768,49 @@ func saveResults(result *InjectionResult, filename string) error {
 	return os.WriteFile(filename, data, 0644)
 }
 
-func startServer(tool *FailureInjectionTool, port string) {
-	// Implementation would start HTTP server for remote control
-	log.Printf("Server mode not fully implemented")
-	select {} // Block forever
+func startServer(tool *FailureInjectionTool, port, resultsDir string, retention RetentionPolicy) {
+	results, err := NewResultStore(resultsDir, retention)
+	if err != nil {
+		log.Fatalf("Failed to open results store: %v", err)
+	}
+	scheduler, err := NewExperimentScheduler(tool, resultsDir, results)
+	if err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scheduler.Start(ctx)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/scenarios", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tool.scenarios)
+	})
+	mux.HandleFunc("/history", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(scheduler.History())
+	})
+	mux.HandleFunc("GET /runs", results.handleListRuns)
+	mux.HandleFunc("GET /runs/{id}", results.handleGetRun)
+
+	server := &http.Server{
+		Addr:         ":" + port,
+		Handler:      mux,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	log.Println("Failure injection server stopped")
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+)
+
+// Remote target kinds
+const (
+	remoteMonitor = "monitor" // cb-monitor: /api/breakers
+	remoteAPI     = "api"     // Sprint admin API: /api/v1/admin/breakers
+)
+
+// RemoteTargets injects into the breakers of a running service instead of
+// breakers registered in this process. Against cb-monitor only breaker
+// states can be forced; the Sprint admin API also takes error and latency
+// faults, which trip its chain breakers the way a failing backend would.
+type RemoteTargets struct {
+	kind     string
+	base     string // Breakers collection URL
+	adminKey string
+	client   *http.Client
+}
+
+// NewRemoteTargets drives the breakers of the kind of service at baseURL,
+// authenticating with adminKey
+func NewRemoteTargets(kind, baseURL, adminKey string) *RemoteTargets {
+	path := "/api/breakers"
+	if kind == remoteAPI {
+		path = "/api/v1/admin/breakers"
+	}
+	return &RemoteTargets{
+		kind:     kind,
+		base:     strings.TrimRight(baseURL, "/") + path,
+		adminKey: adminKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Statuses returns the service's breakers by name
+func (rt *RemoteTargets) Statuses(ctx context.Context) (map[string]schemas.BreakerStatusV1, error) {
+	var statuses map[string]schemas.BreakerStatusV1
+	if err := rt.call(ctx, http.MethodGet, rt.base, nil, &statuses); err != nil {
+		return nil, fmt.Errorf("list breakers: %w", err)
+	}
+	return statuses, nil
+}
+
+// Discover returns the names of the service's breakers, sorted
+func (rt *RemoteTargets) Discover(ctx context.Context) ([]string, error) {
+	statuses, err := rt.Statuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(statuses))
+	for name := range statuses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetState forces a breaker open or closed, or resets it
+func (rt *RemoteTargets) SetState(ctx context.Context, name, state string) error {
+	return rt.call(ctx, http.MethodPost, rt.breakerURL(name, "state"), map[string]string{"state": state}, nil)
+}
+
+// InjectFault starts a fault on the backend a breaker guards. Parameters
+// may set "probability" (share of calls affected, default 1), "latency"
+// (added delay, default "2s") and "duration" (default "30s").
+func (rt *RemoteTargets) InjectFault(ctx context.Context, name, faultType string, params map[string]interface{}) error {
+	if rt.kind != remoteAPI {
+		return fmt.Errorf("%s fault injection needs -api; cb-monitor only forces breaker states", faultType)
+	}
+	body := map[string]interface{}{
+		"type":     faultType,
+		"duration": paramString(params, "duration", "30s"),
+	}
+	if faultType == "latency" {
+		body["latency"] = paramString(params, "latency", "2s")
+	}
+	if p, ok := params["probability"].(float64); ok {
+		body["probability"] = p
+	}
+	return rt.call(ctx, http.MethodPost, rt.breakerURL(name, "fault"), body, nil)
+}
+
+func (rt *RemoteTargets) breakerURL(name, action string) string {
+	return rt.base + "/" + url.PathEscape(name) + "/" + action
+}
+
+// call sends body as JSON and decodes the response into out, if set
+func (rt *RemoteTargets) call(ctx context.Context, method, target string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if rt.adminKey != "" {
+		req.Header.Set("X-Admin-Key", rt.adminKey)
+	}
+
+	resp, err := rt.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: status %d: %s", method, target, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func paramString(params map[string]interface{}, key, def string) string {
+	if v, ok := params[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// breakerStates returns the current status of each target that exists,
+// from this process or the remote service
+func (fit *FailureInjectionTool) breakerStates(targets []string) map[string]schemas.BreakerStatusV1 {
+	states := make(map[string]schemas.BreakerStatusV1)
+	if fit.remote != nil {
+		all, err := fit.remote.Statuses(context.Background())
+		if err != nil {
+			log.Printf("Failed to read remote breaker states: %v", err)
+			return states
+		}
+		for _, target := range targets {
+			if status, ok := all[target]; ok {
+				states[target] = status
+			}
+		}
+		return states
+	}
+
+	for _, target := range targets {
+		if cb, exists := fit.breakers[target]; exists {
+			states[target] = schemas.NewBreakerStatus(target, cb)
+		}
+	}
+	return states
+}
+
+// setBreakerState forces target open or closed, or resets it
+func (fit *FailureInjectionTool) setBreakerState(target, state string) error {
+	if fit.remote != nil {
+		return fit.remote.SetState(context.Background(), target, state)
+	}
+	cb, exists := fit.breakers[target]
+	if !exists {
+		return fmt.Errorf("target circuit breaker not found")
+	}
+	switch state {
+	case "open":
+		cb.ForceOpen()
+	case "closed":
+		cb.ForceClose()
+	default:
+		cb.Reset()
+	}
+	return nil
+}
+
+// injectRemoteFailure is injectFailure for remote targets
+func (fit *FailureInjectionTool) injectRemoteFailure(event InjectionEvent, failureType FailureType) InjectionEvent {
+	ctx := context.Background()
+	var err error
+	switch failureType.Type {
+	case "force_open":
+		err = fit.remote.SetState(ctx, event.Target, "open")
+		event.Description = "Forced remote circuit breaker to open state"
+	case "force_close":
+		err = fit.remote.SetState(ctx, event.Target, "closed")
+		event.Description = "Forced remote circuit breaker to close state"
+	case "simulate_errors":
+		err = fit.remote.InjectFault(ctx, event.Target, "errors", failureType.Parameters)
+		event.Description = "Injected errors into the remote backend"
+	case "simulate_high_latency":
+		err = fit.remote.InjectFault(ctx, event.Target, "latency", failureType.Parameters)
+		event.Description = "Injected latency into the remote backend"
+	default:
+		event.Error = "unsupported failure type for remote targets"
+		event.Description = fmt.Sprintf("Cannot inject %s remotely", failureType.Type)
+		return event
+	}
+
+	if err != nil {
+		event.Error = err.Error()
+		event.Description = fmt.Sprintf("Failed to inject %s: %v", failureType.Type, err)
+		return event
+	}
+	event.Success = true
+	return event
+}
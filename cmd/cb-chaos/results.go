@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunSummary is the index entry for one stored InjectionResult
+type RunSummary struct {
+	ID                 string    `json:"id"`
+	Scenario           string    `json:"scenario"`
+	StartedAt          time.Time `json:"started_at"`
+	EndedAt            time.Time `json:"ended_at"`
+	Aborted            bool      `json:"aborted"`
+	FailuresInjected   int64     `json:"failures_injected"`
+	EffectivenessScore float64   `json:"effectiveness_score"`
+}
+
+// RetentionPolicy bounds how many results a ResultStore keeps; zero
+// values disable a limit
+type RetentionPolicy struct {
+	MaxRuns int           // Newest runs kept
+	MaxAge  time.Duration // Runs started longer ago than this are deleted
+}
+
+const indexFile = "index.json"
+
+// ResultStore keeps every run's InjectionResult as dir/<id>.json, with
+// dir/index.json summarising them so /runs does not read every result
+type ResultStore struct {
+	dir    string
+	policy RetentionPolicy
+
+	mu    sync.Mutex
+	index []RunSummary // Oldest first
+}
+
+// NewResultStore opens dir, rebuilding the index from the result files if
+// it is missing, and applies policy
+func NewResultStore(dir string, policy RetentionPolicy) (*ResultStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create results directory: %w", err)
+	}
+	rs := &ResultStore{dir: dir, policy: policy}
+
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &rs.index); err != nil {
+			return nil, fmt.Errorf("read results index: %w", err)
+		}
+	case os.IsNotExist(err):
+		if err := rs.rebuildIndex(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("read results index: %w", err)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err := rs.pruneLocked(time.Now()); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Save stores result and returns its run ID
+func (rs *ResultStore) Save(result *InjectionResult) (string, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	base := fmt.Sprintf("%s-%s", sanitizeFileName(result.ScenarioName), result.StartTime.UTC().Format("20060102T150405Z"))
+	id := base
+	for n := 2; rs.findLocked(id) >= 0; n++ {
+		id = base + "-" + strconv.Itoa(n)
+	}
+	if err := saveResults(result, filepath.Join(rs.dir, id+".json")); err != nil {
+		return "", err
+	}
+
+	rs.index = append(rs.index, summarize(id, result))
+	sort.SliceStable(rs.index, func(i, j int) bool { return rs.index[i].StartedAt.Before(rs.index[j].StartedAt) })
+	if err := rs.pruneLocked(time.Now()); err != nil {
+		log.Printf("Failed to apply result retention: %v", err)
+	}
+	return id, rs.writeIndexLocked()
+}
+
+// List returns stored runs, newest first, optionally for one scenario
+func (rs *ResultStore) List(scenario string, limit int) []RunSummary {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	runs := make([]RunSummary, 0, len(rs.index))
+	for i := len(rs.index) - 1; i >= 0; i-- {
+		if scenario != "" && rs.index[i].Scenario != scenario {
+			continue
+		}
+		runs = append(runs, rs.index[i])
+		if limit > 0 && len(runs) == limit {
+			break
+		}
+	}
+	return runs
+}
+
+// Load returns the full result of run id
+func (rs *ResultStore) Load(id string) (*InjectionResult, error) {
+	rs.mu.Lock()
+	known := rs.findLocked(id) >= 0
+	rs.mu.Unlock()
+	if !known {
+		return nil, os.ErrNotExist
+	}
+
+	data, err := os.ReadFile(filepath.Join(rs.dir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var result InjectionResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (rs *ResultStore) findLocked(id string) int {
+	for i, run := range rs.index {
+		if run.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// pruneLocked deletes runs outside the retention policy
+func (rs *ResultStore) pruneLocked(now time.Time) error {
+	keep := rs.index[:0]
+	var expired []RunSummary
+	for i, run := range rs.index {
+		tooMany := rs.policy.MaxRuns > 0 && len(rs.index)-i > rs.policy.MaxRuns
+		tooOld := rs.policy.MaxAge > 0 && now.Sub(run.StartedAt) > rs.policy.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, run)
+			continue
+		}
+		keep = append(keep, run)
+	}
+	rs.index = keep
+	if len(expired) == 0 {
+		return nil
+	}
+
+	for _, run := range expired {
+		if err := os.Remove(filepath.Join(rs.dir, run.ID+".json")); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to delete expired result %s: %v", run.ID, err)
+		}
+	}
+	log.Printf("Deleted %d chaos result(s) past retention", len(expired))
+	return rs.writeIndexLocked()
+}
+
+// writeIndexLocked replaces the index file atomically
+func (rs *ResultStore) writeIndexLocked() error {
+	data, err := json.MarshalIndent(rs.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(rs.dir, indexFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(rs.dir, indexFile))
+}
+
+// rebuildIndex summarises the result files already in dir, such as those
+// written before the index existed
+func (rs *ResultStore) rebuildIndex() error {
+	files, err := filepath.Glob(filepath.Join(rs.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".json")
+		if id+".json" == indexFile {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var result InjectionResult
+		if err := json.Unmarshal(data, &result); err != nil || result.ScenarioName == "" {
+			continue
+		}
+		rs.index = append(rs.index, summarize(id, &result))
+	}
+	sort.SliceStable(rs.index, func(i, j int) bool { return rs.index[i].StartedAt.Before(rs.index[j].StartedAt) })
+	if len(rs.index) > 0 {
+		log.Printf("Indexed %d existing chaos result(s) in %s", len(rs.index), rs.dir)
+	}
+	return rs.writeIndexLocked()
+}
+
+func summarize(id string, result *InjectionResult) RunSummary {
+	return RunSummary{
+		ID:                 id,
+		Scenario:           result.ScenarioName,
+		StartedAt:          result.StartTime,
+		EndedAt:            result.EndTime,
+		Aborted:            result.Aborted,
+		FailuresInjected:   result.FailuresInjected,
+		EffectivenessScore: result.Summary.EffectivenessScore,
+	}
+}
+
+// handleListRuns serves GET /runs?scenario=&limit=
+func (rs *ResultStore) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rs.List(r.URL.Query().Get("scenario"), limit))
+}
+
+// handleGetRun serves GET /runs/{id}
+func (rs *ResultStore) handleGetRun(w http.ResponseWriter, r *http.Request) {
+	result, err := rs.Load(r.PathValue("id"))
+	if os.IsNotExist(err) {
+		http.Error(w, "Run not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
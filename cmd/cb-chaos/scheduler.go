@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SkipWindow suppresses scheduled runs between Start and End, e.g. during
+// a release freeze or planned maintenance
+type SkipWindow struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// RunRecord is one entry in the scheduled run history
+type RunRecord struct {
+	Scenario    string    `json:"scenario"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	StartedAt   time.Time `json:"started_at,omitempty"`
+	EndedAt     time.Time `json:"ended_at,omitempty"`
+	Status      string    `json:"status"` // completed, aborted, failed, skipped
+	Reason      string    `json:"reason,omitempty"`
+	RunID       string    `json:"run_id,omitempty"` // Result served at /runs/{id}
+	ResultFile  string    `json:"result_file,omitempty"`
+}
+
+const (
+	historyFile       = "history.jsonl"
+	maxHistoryEntries = 500
+)
+
+// ExperimentScheduler runs cron-scheduled scenarios in server mode and keeps
+// their history. Each run's InjectionResult goes to the result store and
+// every RunRecord is appended to dir/history.jsonl.
+type ExperimentScheduler struct {
+	tool    *FailureInjectionTool
+	dir     string
+	results *ResultStore
+
+	mu      sync.Mutex
+	history []RunRecord
+	running map[string]bool
+}
+
+// NewExperimentScheduler prepares dir and loads any previous history from it
+func NewExperimentScheduler(tool *FailureInjectionTool, dir string, results *ResultStore) (*ExperimentScheduler, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create results directory: %w", err)
+	}
+
+	s := &ExperimentScheduler{
+		tool:    tool,
+		dir:     dir,
+		results: results,
+		running: make(map[string]bool),
+	}
+	if err := s.loadHistory(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start launches one loop per cron scenario. Scenarios with an invalid
+// expression are logged and left out rather than failing the server.
+func (s *ExperimentScheduler) Start(ctx context.Context) {
+	names := make([]string, 0, len(s.tool.scenarios))
+	for name, scenario := range s.tool.scenarios {
+		if scenario.Schedule.Type == "cron" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		scenario := s.tool.scenarios[name]
+		schedule, err := scenarioCron(scenario)
+		if err != nil {
+			log.Printf("Not scheduling %s: %v", name, err)
+			continue
+		}
+		log.Printf("Scheduled %s (%s), next run %s", name, scenario.Schedule.Cron,
+			schedule.Next(time.Now()).Format(time.RFC3339))
+		go s.loop(ctx, scenario, schedule)
+	}
+}
+
+// History returns run records, oldest first
+func (s *ExperimentScheduler) History() []RunRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make([]RunRecord, len(s.history))
+	copy(history, s.history)
+	return history
+}
+
+func (s *ExperimentScheduler) loop(ctx context.Context, scenario FailureScenario, schedule *CronSchedule) {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Cron expression for %s never fires, stopping its schedule", scenario.Name)
+			return
+		}
+		if !sleepCtx(ctx, time.Until(next)) {
+			return
+		}
+
+		if window, ok := activeSkipWindow(scenario.Schedule.SkipWindows, next); ok {
+			reason := "skip window"
+			if window.Reason != "" {
+				reason += ": " + window.Reason
+			}
+			s.record(RunRecord{Scenario: scenario.Name, ScheduledAt: next, Status: "skipped", Reason: reason})
+			continue
+		}
+
+		// A run longer than the cron period must not overlap with the next one
+		s.mu.Lock()
+		busy := s.running[scenario.Name]
+		s.running[scenario.Name] = true
+		s.mu.Unlock()
+		if busy {
+			s.record(RunRecord{Scenario: scenario.Name, ScheduledAt: next, Status: "skipped", Reason: "previous run still in progress"})
+			continue
+		}
+
+		go s.run(scenario, next)
+	}
+}
+
+func (s *ExperimentScheduler) run(scenario FailureScenario, scheduledAt time.Time) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, scenario.Name)
+		s.mu.Unlock()
+	}()
+
+	record := RunRecord{Scenario: scenario.Name, ScheduledAt: scheduledAt, StartedAt: time.Now()}
+	result, err := s.tool.ExecuteScenario(scenario)
+	record.EndedAt = time.Now()
+
+	switch {
+	case err != nil:
+		record.Status = "failed"
+		record.Reason = err.Error()
+	case result.Aborted:
+		record.Status = "aborted"
+		record.Reason = result.Abort.Reason
+	default:
+		record.Status = "completed"
+	}
+
+	if result != nil {
+		if id, err := s.results.Save(result); err != nil {
+			log.Printf("Failed to save results for %s: %v", scenario.Name, err)
+		} else {
+			record.RunID = id
+			record.ResultFile = id + ".json"
+		}
+	}
+
+	s.record(record)
+}
+
+func (s *ExperimentScheduler) record(rec RunRecord) {
+	log.Printf("Scheduled run %s at %s: %s %s", rec.Scenario, rec.ScheduledAt.Format(time.RFC3339), rec.Status, rec.Reason)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history, rec)
+	if len(s.history) > maxHistoryEntries {
+		s.history = s.history[len(s.history)-maxHistoryEntries:]
+	}
+
+	if err := s.appendHistory(rec); err != nil {
+		log.Printf("Failed to persist run history: %v", err)
+	}
+}
+
+func (s *ExperimentScheduler) appendHistory(rec RunRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(s.dir, historyFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func (s *ExperimentScheduler) loadHistory() error {
+	f, err := os.Open(filepath.Join(s.dir, historyFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("open run history: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec RunRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Tolerate a torn last line from an unclean shutdown
+			continue
+		}
+		s.history = append(s.history, rec)
+	}
+	if len(s.history) > maxHistoryEntries {
+		s.history = s.history[len(s.history)-maxHistoryEntries:]
+	}
+	return scanner.Err()
+}
+
+// scenarioCron parses a scenario's cron expression in its configured timezone
+func scenarioCron(scenario FailureScenario) (*CronSchedule, error) {
+	if scenario.Schedule.Cron == "" {
+		return nil, fmt.Errorf("cron schedule without an expression")
+	}
+
+	loc := time.Local
+	if scenario.Schedule.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(scenario.Schedule.Timezone); err != nil {
+			return nil, fmt.Errorf("timezone %q: %w", scenario.Schedule.Timezone, err)
+		}
+	}
+	return ParseCron(scenario.Schedule.Cron, loc)
+}
+
+func activeSkipWindow(windows []SkipWindow, t time.Time) (SkipWindow, bool) {
+	for _, w := range windows {
+		if !t.Before(w.Start) && t.Before(w.End) {
+			return w, true
+		}
+	}
+	return SkipWindow{}, false
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SteadyStateHypothesis declares the conditions that must keep holding while
+// failures are injected. A scenario without probes runs unguarded.
+type SteadyStateHypothesis struct {
+	Probes   []SteadyStateProbe `json:"probes"`
+	Interval time.Duration      `json:"interval,omitempty"` // Default 5s
+}
+
+// SteadyStateProbe is a single guardrail check.
+//
+// "http" probes pass when the URL answers with ExpectStatus (default 200).
+// "metric" probes scrape a Prometheus text endpoint and compare the sample
+// named by Metric (e.g. `cache_hit_rate` or `requests_total{chain="btc"}`)
+// against Min and/or Max.
+type SteadyStateProbe struct {
+	Name         string        `json:"name"`
+	Type         string        `json:"type"` // http, metric
+	URL          string        `json:"url"`
+	ExpectStatus int           `json:"expect_status,omitempty"`
+	Metric       string        `json:"metric,omitempty"`
+	Min          *float64      `json:"min,omitempty"`
+	Max          *float64      `json:"max,omitempty"`
+	Timeout      time.Duration `json:"timeout,omitempty"`   // Default 2s
+	Tolerance    int           `json:"tolerance,omitempty"` // Consecutive violations allowed before aborting
+}
+
+// AbortRecord explains why a run was stopped early
+type AbortRecord struct {
+	Time       time.Time `json:"time"`
+	Probe      string    `json:"probe"`
+	Reason     string    `json:"reason"`
+	Violations int       `json:"violations"`
+	Restored   []string  `json:"restored,omitempty"`
+}
+
+// ProbeReport summarizes one probe over the run
+type ProbeReport struct {
+	Name       string `json:"name"`
+	Checks     int    `json:"checks"`
+	Violations int    `json:"violations"`
+	LastError  string `json:"last_error,omitempty"`
+}
+
+// steadyStateGuard evaluates the hypothesis on an interval and cancels the
+// injection once any probe exceeds its tolerance.
+type steadyStateGuard struct {
+	hypothesis SteadyStateHypothesis
+	client     *http.Client
+
+	mu          sync.Mutex
+	consecutive []int
+	reports     []ProbeReport
+	abort       *AbortRecord
+}
+
+func newSteadyStateGuard(h SteadyStateHypothesis) *steadyStateGuard {
+	if h.Interval <= 0 {
+		h.Interval = 5 * time.Second
+	}
+	g := &steadyStateGuard{
+		hypothesis:  h,
+		client:      &http.Client{},
+		consecutive: make([]int, len(h.Probes)),
+		reports:     make([]ProbeReport, len(h.Probes)),
+	}
+	for i, p := range h.Probes {
+		g.reports[i].Name = probeName(p, i)
+	}
+	return g
+}
+
+// verify checks every probe once and fails on the first violation. Used
+// before injection starts: there is no point breaking a system that is
+// already outside its steady state.
+func (g *steadyStateGuard) verify(ctx context.Context) error {
+	for i, p := range g.hypothesis.Probes {
+		if err := g.check(ctx, p); err != nil {
+			return fmt.Errorf("probe %s: %w", probeName(p, i), err)
+		}
+	}
+	return nil
+}
+
+// run evaluates probes until ctx is done or the hypothesis is violated, in
+// which case abort is called exactly once.
+func (g *steadyStateGuard) run(ctx context.Context, abort func()) {
+	ticker := time.NewTicker(g.hypothesis.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if g.evaluate(ctx) {
+				abort()
+				return
+			}
+		}
+	}
+}
+
+// evaluate runs one round of checks and reports whether the run must abort
+func (g *steadyStateGuard) evaluate(ctx context.Context) bool {
+	for i, p := range g.hypothesis.Probes {
+		err := g.check(ctx, p)
+		if ctx.Err() != nil {
+			// The run ended mid-check; a cancelled request is not a violation
+			return false
+		}
+
+		g.mu.Lock()
+		g.reports[i].Checks++
+		if err == nil {
+			g.consecutive[i] = 0
+			g.mu.Unlock()
+			continue
+		}
+
+		g.consecutive[i]++
+		g.reports[i].Violations++
+		g.reports[i].LastError = err.Error()
+		violations := g.consecutive[i]
+		if violations <= p.Tolerance {
+			g.mu.Unlock()
+			log.Printf("Steady state probe %s violated (%d/%d): %v", g.reports[i].Name, violations, p.Tolerance, err)
+			continue
+		}
+
+		g.abort = &AbortRecord{
+			Time:       time.Now(),
+			Probe:      g.reports[i].Name,
+			Reason:     err.Error(),
+			Violations: violations,
+		}
+		g.mu.Unlock()
+		log.Printf("Steady state hypothesis violated by %s, aborting injection: %v", g.reports[i].Name, err)
+		return true
+	}
+	return false
+}
+
+// result returns the abort record (nil if the run completed) and per-probe reports
+func (g *steadyStateGuard) result() (*AbortRecord, []ProbeReport) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	reports := make([]ProbeReport, len(g.reports))
+	copy(reports, g.reports)
+	return g.abort, reports
+}
+
+func (g *steadyStateGuard) check(ctx context.Context, p SteadyStateProbe) error {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch p.Type {
+	case "http":
+		return g.checkHTTP(ctx, p)
+	case "metric":
+		return g.checkMetric(ctx, p)
+	default:
+		return fmt.Errorf("unsupported probe type: %s", p.Type)
+	}
+}
+
+func (g *steadyStateGuard) checkHTTP(ctx context.Context, p SteadyStateProbe) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	expect := p.ExpectStatus
+	if expect == 0 {
+		expect = http.StatusOK
+	}
+	if resp.StatusCode != expect {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, expect)
+	}
+	return nil
+}
+
+func (g *steadyStateGuard) checkMetric(ctx context.Context, p SteadyStateProbe) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("metrics endpoint returned status %d", resp.StatusCode)
+	}
+
+	value, found, err := scrapeMetric(bufio.NewScanner(resp.Body), p.Metric)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("metric %s not found", p.Metric)
+	}
+	if p.Min != nil && value < *p.Min {
+		return fmt.Errorf("%s = %g, below minimum %g", p.Metric, value, *p.Min)
+	}
+	if p.Max != nil && value > *p.Max {
+		return fmt.Errorf("%s = %g, above maximum %g", p.Metric, value, *p.Max)
+	}
+	return nil
+}
+
+// scrapeMetric finds the first sample in Prometheus text format whose series
+// matches metric exactly, or whose name matches when metric has no labels.
+func scrapeMetric(scanner *bufio.Scanner, metric string) (float64, bool, error) {
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Labels may contain spaces; the value is the field after the closing brace
+		series, rest := fields[0], fields[1:]
+		if i := strings.IndexByte(line, '}'); i >= 0 {
+			series = line[:i+1]
+			rest = strings.Fields(line[i+1:])
+			if len(rest) == 0 {
+				continue
+			}
+		}
+
+		name := series
+		if i := strings.IndexByte(series, '{'); i >= 0 {
+			name = series[:i]
+		}
+		if series != metric && name != metric {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(rest[0], 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("parse %s: %w", series, err)
+		}
+		return value, true, nil
+	}
+	return 0, false, scanner.Err()
+}
+
+// restoreForcedStates undoes forced states left behind by an aborted run.
+// Breakers that were already forced before the run go back to that state;
+// breakers forced during the run return to normal operation.
+func (fit *FailureInjectionTool) restoreForcedStates(targets []string, initialStates map[string]string) []string {
+	var restored []string
+	for target, status := range fit.breakerStates(targets) {
+		current, initial := status.State, initialStates[target]
+		if current == initial || (current != "force-open" && current != "force-close") {
+			continue
+		}
+
+		state := "reset"
+		switch initial {
+		case "force-open":
+			state = "open"
+		case "force-close":
+			state = "closed"
+		}
+		if err := fit.setBreakerState(target, state); err != nil {
+			log.Printf("Failed to restore %s from %s: %v", target, current, err)
+			continue
+		}
+		restored = append(restored, target)
+		log.Printf("Restored %s from %s (%s)", target, current, state)
+	}
+	sort.Strings(restored)
+	return restored
+}
+
+func probeName(p SteadyStateProbe, i int) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("probe-%d", i+1)
+}
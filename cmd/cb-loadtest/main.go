@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/trafficcapture"
 )
 
 // LoadTestConfig defines configuration for load testing
@@ -25,6 +28,8 @@ type LoadTestConfig struct {
 	BreakerConfig circuitbreaker.Config
 	TestScenario  string
 	OutputFile    string
+	CaptureFile   string  // Traffic capture replayed by the replay scenario
+	ReplaySpeed   float64 // Replay time compression; 2 replays twice as fast
 }
 
 // TestResult captures the results of a load test
@@ -42,6 +47,17 @@ type TestResult struct {
 	Duration           time.Duration    `json:"duration"`
 	StateChanges       []StateChange    `json:"state_changes"`
 	ErrorTypes         map[string]int64 `json:"error_types"`
+	Replay             *ReplaySummary   `json:"replay,omitempty"`
+}
+
+// ReplaySummary compares a replayed capture with what the breaker did to it
+type ReplaySummary struct {
+	CaptureFile            string        `json:"capture_file"`
+	Speed                  float64       `json:"speed"`
+	CapturedSpan           time.Duration `json:"captured_span"`
+	RecordedFailures       int64         `json:"recorded_failures"`
+	FailuresShortCircuited int64         `json:"failures_short_circuited"` // Recorded failures rejected without reaching upstream
+	SuccessesRejected      int64         `json:"successes_rejected"`       // Recorded successes the breaker turned away
 }
 
 // StateChange records when the circuit breaker changed state
@@ -62,16 +78,18 @@ type RequestLatency struct {
 
 func main() {
 	var (
-		duration    = flag.Duration("duration", time.Minute*5, "Test duration")
+		duration    = flag.Duration("duration", time.Minute*5, "Test duration (replay runs the whole capture)")
 		concurrency = flag.Int("concurrency", 10, "Number of concurrent workers")
 		requestRate = flag.Float64("rate", 100.0, "Requests per second")
 		failureRate = flag.Float64("failure-rate", 0.1, "Simulated failure rate (0.0-1.0)")
 		latencyMin  = flag.Duration("latency-min", time.Millisecond*10, "Minimum simulated latency")
 		latencyMax  = flag.Duration("latency-max", time.Millisecond*100, "Maximum simulated latency")
-		scenario    = flag.String("scenario", "standard", "Test scenario (standard, spike, gradual-failure, recovery)")
+		scenario    = flag.String("scenario", "standard", "Test scenario (standard, spike, gradual-failure, recovery, replay)")
 		outputFile  = flag.String("output", "", "Output file for results (JSON format)")
 		tier        = flag.String("tier", "business", "Circuit breaker tier (free, business, enterprise)")
 		configFile  = flag.String("config", "", "Custom circuit breaker configuration file")
+		captureFile = flag.String("capture", "", "Traffic capture (JSON lines from TRAFFIC_CAPTURE_PATH) for the replay scenario")
+		replaySpeed = flag.Float64("speed", 1.0, "Replay speed multiplier for arrivals and latencies")
 	)
 	flag.Parse()
 
@@ -84,6 +102,11 @@ func main() {
 		LatencyMax:   *latencyMax,
 		TestScenario: *scenario,
 		OutputFile:   *outputFile,
+		CaptureFile:  *captureFile,
+		ReplaySpeed:  *replaySpeed,
+	}
+	if config.TestScenario == "replay" && config.CaptureFile == "" {
+		log.Fatalf("The replay scenario requires -capture")
 	}
 
 	// Create circuit breaker configuration
@@ -135,15 +158,26 @@ func main() {
 	}
 
 	log.Printf("Starting load test with configuration:")
-	log.Printf("  Duration: %v", config.Duration)
+	if config.TestScenario == "replay" {
+		log.Printf("  Capture: %s", config.CaptureFile)
+		log.Printf("  Speed: %.2fx", config.ReplaySpeed)
+	} else {
+		log.Printf("  Duration: %v", config.Duration)
+		log.Printf("  Request Rate: %.2f req/s", config.RequestRate)
+		log.Printf("  Failure Rate: %.1f%%", config.FailureRate*100)
+	}
 	log.Printf("  Concurrency: %d", config.Concurrency)
-	log.Printf("  Request Rate: %.2f req/s", config.RequestRate)
-	log.Printf("  Failure Rate: %.1f%%", config.FailureRate*100)
 	log.Printf("  Scenario: %s", config.TestScenario)
 	log.Printf("  Tier: %s", *tier)
 
 	// Run the load test
-	result, err := runLoadTest(config)
+	var result *TestResult
+	var err error
+	if config.TestScenario == "replay" {
+		result, err = runReplay(config)
+	} else {
+		result, err = runLoadTest(config)
+	}
 	if err != nil {
 		log.Fatalf("Load test failed: %v", err)
 	}
@@ -178,32 +212,10 @@ func runLoadTest(config LoadTestConfig) (*TestResult, error) {
 		circuitOpenCount   int64
 		latencies          []RequestLatency
 		latenciesMu        sync.Mutex
-		// stateChanges       []StateChange
-		// stateChangesMu     sync.Mutex
 		errorTypes         = make(map[string]int64)
 		errorTypesMu       sync.Mutex
 	)
 
-	// Setup state change monitoring
-	// TODO: Implement state change monitoring when Config supports callbacks
-	// originalCallback := config.BreakerConfig.OnStateChange
-	// config.BreakerConfig.OnStateChange = func(name string, from, to circuitbreaker.State) {
-	//     stateChangesMu.Lock()
-	//     stateChanges = append(stateChanges, StateChange{
-	//         Timestamp: time.Now(),
-	//         From:      from.String(),
-	//         To:        to.String(),
-	//         Reason:    "load_test_triggered",
-	//     })
-	//     stateChangesMu.Unlock()
-	//
-	//     if originalCallback != nil {
-	//         originalCallback(name, from, to)
-	//     }
-	//
-	//     log.Printf("Circuit breaker state changed: %s -> %s", from.String(), to.String())
-	// }
-
 	// Create test function based on scenario
 	testFunc := createTestFunction(config)
 
@@ -279,7 +291,7 @@ func runLoadTest(config LoadTestConfig) (*TestResult, error) {
 		CircuitOpenCount:   circuitOpenCount,
 		Duration:           actualDuration,
 		ThroughputRPS:      float64(totalRequests) / actualDuration.Seconds(),
-		StateChanges:       []StateChange{}, // TODO: Implement when state monitoring is available
+		StateChanges:       stateChanges(cb),
 		ErrorTypes:         errorTypes,
 	}
 
@@ -291,6 +303,135 @@ func runLoadTest(config LoadTestConfig) (*TestResult, error) {
 	return result, nil
 }
 
+// runReplay replays a traffic capture against the breaker. Each record is
+// issued at its captured offset from the first, takes its captured latency
+// and fails if the original request failed, so a breaker config can be
+// checked against failure patterns actually seen in production. Speed
+// divides both arrivals and latencies; breaker timeouts are not scaled.
+func runReplay(config LoadTestConfig) (*TestResult, error) {
+	records, err := trafficcapture.ReadFile(config.CaptureFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("capture %s has no records", config.CaptureFile)
+	}
+	// Records are written as requests finish; replay them in arrival order
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+
+	speed := config.ReplaySpeed
+	if speed <= 0 {
+		speed = 1
+	}
+	concurrency := config.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	cb, err := circuitbreaker.NewEnterpriseCircuitBreaker(config.BreakerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create circuit breaker: %w", err)
+	}
+	defer cb.Shutdown(context.Background())
+
+	var (
+		mu         sync.Mutex
+		latencies  []RequestLatency
+		errorTypes = make(map[string]int64)
+	)
+	result := &TestResult{ErrorTypes: errorTypes}
+	summary := &ReplaySummary{
+		CaptureFile:  config.CaptureFile,
+		Speed:        speed,
+		CapturedSpan: records[len(records)-1].Time.Sub(records[0].Time),
+	}
+
+	first := records[0].Time
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	startTime := time.Now()
+
+	for _, rec := range records {
+		due := time.Duration(float64(rec.Time.Sub(first)) / speed)
+		if wait := due - time.Since(startTime); wait > 0 {
+			time.Sleep(wait)
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(rec trafficcapture.Record) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			latency := time.Duration(float64(rec.Latency()) / speed)
+			requestStart := time.Now()
+			res, err := cb.ExecuteWithContext(context.Background(), func() (interface{}, error) {
+				time.Sleep(latency)
+				if rec.Failed() {
+					return nil, fmt.Errorf("recorded status %d", rec.Status)
+				}
+				return rec.Status, nil
+			})
+			requestLatency := time.Since(requestStart)
+			rejected := res != nil && res.FailureType == circuitbreaker.FailureTypeCircuit
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.TotalRequests++
+			latencies = append(latencies, RequestLatency{
+				timestamp: requestStart,
+				latency:   requestLatency,
+				success:   res != nil && res.Success,
+				error:     getErrorString(err),
+			})
+			if rec.Failed() {
+				summary.RecordedFailures++
+			}
+			if res != nil && res.Success {
+				result.SuccessfulRequests++
+				return
+			}
+			result.FailedRequests++
+			errorTypes[getErrorType(err, res)]++
+			if rejected {
+				result.CircuitOpenCount++
+				if rec.Failed() {
+					summary.FailuresShortCircuited++
+				} else {
+					summary.SuccessesRejected++
+				}
+			}
+		}(rec)
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(startTime)
+	result.ThroughputRPS = float64(result.TotalRequests) / result.Duration.Seconds()
+	result.StateChanges = stateChanges(cb)
+	result.Replay = summary
+	result.calculateLatencyMetrics(latencies)
+	return result, nil
+}
+
+// stateChanges converts the breaker's history into the report's state changes
+func stateChanges(cb *circuitbreaker.EnterpriseCircuitBreaker) []StateChange {
+	changes := []StateChange{}
+	for _, e := range cb.GetHistory() {
+		if e.Type == circuitbreaker.EventFailureBurst {
+			continue
+		}
+		changes = append(changes, StateChange{
+			Timestamp: e.Time,
+			From:      e.From,
+			To:        e.To,
+			Reason:    e.Reason,
+		})
+	}
+	return changes
+}
+
 // createTestFunction creates a test function based on the scenario
 func createTestFunction(config LoadTestConfig) func() (interface{}, error) {
 	switch config.TestScenario {
@@ -464,6 +605,14 @@ func printResults(result *TestResult) {
 	fmt.Printf("P95: %v\n", result.P95Latency)
 	fmt.Printf("P99: %v\n", result.P99Latency)
 
+	if r := result.Replay; r != nil {
+		fmt.Println("\n=== Replay ===")
+		fmt.Printf("Capture: %s (%v captured, %.2fx speed)\n", r.CaptureFile, r.CapturedSpan, r.Speed)
+		fmt.Printf("Recorded Failures: %d\n", r.RecordedFailures)
+		fmt.Printf("Failures Short-Circuited: %d\n", r.FailuresShortCircuited)
+		fmt.Printf("Successes Rejected: %d\n", r.SuccessesRejected)
+	}
+
 	if len(result.StateChanges) > 0 {
 		fmt.Println("\n=== State Changes ===")
 		for _, change := range result.StateChanges {
@@ -483,16 +632,11 @@ func printResults(result *TestResult) {
 
 // saveResults saves the test results to a JSON file
 func saveResults(result *TestResult, filename string) error {
-	// Implementation would marshal to JSON and save to file
-	// For now, just create an empty file
-	file, err := os.Create(filename)
+	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
-
-	fmt.Fprintf(file, "Load test results saved at %s\n", time.Now().Format(time.RFC3339))
-	return nil
+	return os.WriteFile(filename, data, 0o644)
 }
 
 // getErrorString safely extracts error message
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Alert routing
+//
+// Alerts always reach dashboard clients over the WebSocket. When an alert
+// config is loaded (-alerts), they are also delivered to the sinks whose
+// levels include the alert's level: Slack incoming webhooks, PagerDuty
+// Events v2 and generic HTTP endpoints. Repeats of the same condition are
+// suppressed for the dedup window, and silences mute a breaker (or all
+// breakers) for a while, e.g. during planned maintenance.
+
+const (
+	defaultDedupWindow   = 5 * time.Minute
+	alertQueueSize       = 100
+	alertDeliveryTimeout = 10 * time.Second
+	pagerDutyEventsURL   = "https://events.pagerduty.com/v2/enqueue"
+)
+
+// AlertSinkConfig configures one alert destination
+type AlertSinkConfig struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`   // slack, pagerduty or webhook
+	Levels     []string          `json:"levels"` // Empty receives every level
+	URL        string            `json:"url,omitempty"`
+	RoutingKey string            `json:"routing_key,omitempty"` // PagerDuty integration key
+	Template   string            `json:"template,omitempty"`    // webhook body; text/template over AlertMessage
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// AlertConfig is the file loaded by -alerts
+type AlertConfig struct {
+	DedupWindow string            `json:"dedup_window"` // Go duration; default 5m
+	Sinks       []AlertSinkConfig `json:"sinks"`
+}
+
+// AlertSilence mutes alerts for a breaker until a deadline. An empty
+// breaker mutes all of them; an empty level mutes every level.
+type AlertSilence struct {
+	ID      int       `json:"id"`
+	Breaker string    `json:"breaker,omitempty"`
+	Level   string    `json:"level,omitempty"`
+	Until   time.Time `json:"until"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+func (s AlertSilence) matches(alert AlertMessage, now time.Time) bool {
+	return now.Before(s.Until) &&
+		(s.Breaker == "" || s.Breaker == alert.Breaker) &&
+		(s.Level == "" || s.Level == alert.Level)
+}
+
+// alertSink delivers one alert to one destination
+type alertSink struct {
+	cfg      AlertSinkConfig
+	levels   map[string]bool
+	template *template.Template
+}
+
+func newAlertSink(cfg AlertSinkConfig) (*alertSink, error) {
+	sink := &alertSink{cfg: cfg, levels: make(map[string]bool)}
+	for _, level := range cfg.Levels {
+		sink.levels[level] = true
+	}
+
+	switch cfg.Type {
+	case "slack":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("sink %q: slack needs a url", cfg.Name)
+		}
+	case "pagerduty":
+		if cfg.RoutingKey == "" {
+			return nil, fmt.Errorf("sink %q: pagerduty needs a routing_key", cfg.Name)
+		}
+	case "webhook":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("sink %q: webhook needs a url", cfg.Name)
+		}
+		if cfg.Template != "" {
+			tmpl, err := template.New(cfg.Name).Funcs(template.FuncMap{"json": alertJSON}).Parse(cfg.Template)
+			if err != nil {
+				return nil, fmt.Errorf("sink %q: template: %w", cfg.Name, err)
+			}
+			sink.template = tmpl
+		}
+	default:
+		return nil, fmt.Errorf("sink %q: unknown type %q", cfg.Name, cfg.Type)
+	}
+	return sink, nil
+}
+
+func (s *alertSink) wants(level string) bool {
+	return len(s.levels) == 0 || s.levels[level]
+}
+
+// alertJSON is the template func for embedding values as JSON
+func alertJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// request builds the HTTP request delivering alert
+func (s *alertSink) request(ctx context.Context, alert AlertMessage) (*http.Request, error) {
+	var (
+		url  = s.cfg.URL
+		body []byte
+		err  error
+	)
+	switch s.cfg.Type {
+	case "slack":
+		body, err = json.Marshal(map[string]string{
+			"text": fmt.Sprintf("[%s] %s: %s", strings.ToUpper(alert.Level), alert.Breaker, alert.Message),
+		})
+	case "pagerduty":
+		if url == "" {
+			url = pagerDutyEventsURL
+		}
+		body, err = json.Marshal(map[string]interface{}{
+			"routing_key":  s.cfg.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    alert.Breaker + ":" + alert.Kind,
+			"payload": map[string]interface{}{
+				"summary":        fmt.Sprintf("%s: %s", alert.Breaker, alert.Message),
+				"source":         alert.Breaker,
+				"severity":       pagerDutySeverity(alert.Level),
+				"timestamp":      alert.Timestamp.UTC().Format(time.RFC3339),
+				"component":      "circuit-breaker",
+				"custom_details": alert.Metadata,
+			},
+		})
+	case "webhook":
+		if s.template == nil {
+			body, err = json.Marshal(alert)
+			break
+		}
+		var buf bytes.Buffer
+		err = s.template.Execute(&buf, alert)
+		body = buf.Bytes()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// pagerDutySeverity maps alert levels onto the Events v2 severities
+func pagerDutySeverity(level string) string {
+	switch level {
+	case "critical", "error", "warning", "info":
+		return level
+	default:
+		return "warning"
+	}
+}
+
+// AlertRouter fans alerts out to the configured sinks without blocking the
+// monitoring loop
+type AlertRouter struct {
+	sinks       []*alertSink
+	dedupWindow time.Duration
+	client      *http.Client
+	queue       chan routedAlert
+
+	mu        sync.Mutex
+	lastSent  map[string]time.Time // breaker|level|kind -> last delivery
+	silences  []AlertSilence
+	silenceID int
+}
+
+type routedAlert struct {
+	alert AlertMessage
+	sinks []*alertSink
+}
+
+// NewAlertRouter builds the sinks in cfg
+func NewAlertRouter(cfg AlertConfig) (*AlertRouter, error) {
+	r := &AlertRouter{
+		dedupWindow: defaultDedupWindow,
+		client:      &http.Client{Timeout: alertDeliveryTimeout},
+		queue:       make(chan routedAlert, alertQueueSize),
+		lastSent:    make(map[string]time.Time),
+	}
+	if cfg.DedupWindow != "" {
+		d, err := time.ParseDuration(cfg.DedupWindow)
+		if err != nil {
+			return nil, fmt.Errorf("dedup_window: %w", err)
+		}
+		r.dedupWindow = d
+	}
+	for _, sc := range cfg.Sinks {
+		sink, err := newAlertSink(sc)
+		if err != nil {
+			return nil, err
+		}
+		r.sinks = append(r.sinks, sink)
+	}
+	return r, nil
+}
+
+// LoadAlertRouter reads an AlertConfig from path
+func LoadAlertRouter(path string) (*AlertRouter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg AlertConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewAlertRouter(cfg)
+}
+
+// Start delivers queued alerts until stop is closed
+func (r *AlertRouter) Start(stop <-chan struct{}) {
+	go func() {
+		for {
+			select {
+			case ra := <-r.queue:
+				r.deliver(ra)
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Route queues alert for every sink that wants it, unless it is silenced or
+// a repeat within the dedup window. It reports whether the alert was queued.
+func (r *AlertRouter) Route(alert AlertMessage) bool {
+	now := time.Now()
+	key := alert.Breaker + "|" + alert.Level + "|" + alert.Kind
+
+	r.mu.Lock()
+	for _, s := range r.silences {
+		if s.matches(alert, now) {
+			r.mu.Unlock()
+			return false
+		}
+	}
+	if last, ok := r.lastSent[key]; ok && now.Sub(last) < r.dedupWindow {
+		r.mu.Unlock()
+		return false
+	}
+	r.lastSent[key] = now
+	r.mu.Unlock()
+
+	return r.enqueue(alert, "")
+}
+
+// Fire queues alert for the named sink, or every sink wanting its level when
+// sink is empty, bypassing silences and dedup
+func (r *AlertRouter) Fire(alert AlertMessage, sink string) bool {
+	return r.enqueue(alert, sink)
+}
+
+func (r *AlertRouter) enqueue(alert AlertMessage, name string) bool {
+	var sinks []*alertSink
+	for _, s := range r.sinks {
+		if (name == "" && s.wants(alert.Level)) || s.cfg.Name == name {
+			sinks = append(sinks, s)
+		}
+	}
+	if len(sinks) == 0 {
+		return false
+	}
+
+	select {
+	case r.queue <- routedAlert{alert: alert, sinks: sinks}:
+		return true
+	default:
+		log.Printf("Alert queue full, dropping %s alert for %s", alert.Level, alert.Breaker)
+		return false
+	}
+}
+
+func (r *AlertRouter) deliver(ra routedAlert) {
+	for _, sink := range ra.sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), alertDeliveryTimeout)
+		err := r.send(ctx, sink, ra.alert)
+		cancel()
+		if err != nil {
+			log.Printf("Alert delivery to %s (%s) failed: %v", sink.cfg.Name, sink.cfg.Type, err)
+		}
+	}
+}
+
+func (r *AlertRouter) send(ctx context.Context, sink *alertSink, alert AlertMessage) error {
+	req, err := sink.request(ctx, alert)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Silence adds s and returns it with its ID
+func (r *AlertRouter) Silence(s AlertSilence) AlertSilence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.silenceID++
+	s.ID = r.silenceID
+	r.silences = append(r.silences, s)
+	return s
+}
+
+// Unsilence removes the silence with id
+func (r *AlertRouter) Unsilence(id int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, s := range r.silences {
+		if s.ID == id {
+			r.silences = append(r.silences[:i], r.silences[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Silences returns the active silences, dropping expired ones
+func (r *AlertRouter) Silences() []AlertSilence {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	active := r.silences[:0]
+	for _, s := range r.silences {
+		if now.Before(s.Until) {
+			active = append(active, s)
+		}
+	}
+	r.silences = active
+	return append([]AlertSilence(nil), active...)
+}
+
+// Sinks returns the sink names and types
+func (r *AlertRouter) Sinks() []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(r.sinks))
+	for _, s := range r.sinks {
+		out = append(out, map[string]interface{}{
+			"name":   s.cfg.Name,
+			"type":   s.cfg.Type,
+			"levels": s.cfg.Levels,
+		})
+	}
+	return out
+}
+
+// Alert routing handlers
+
+// handleGetAlertSinks lists the configured sinks
+func (m *CircuitBreakerMonitor) handleGetAlertSinks(w http.ResponseWriter, r *http.Request) {
+	sinks := []map[string]interface{}{}
+	if m.router != nil {
+		sinks = m.router.Sinks()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sinks)
+}
+
+// handleTestAlert fires a test alert so a sink can be checked end to end:
+//
+//	POST /api/alerts/test {"level": "critical", "sink": "oncall"}
+//
+// Without a sink it goes to every sink receiving the level.
+func (m *CircuitBreakerMonitor) handleTestAlert(w http.ResponseWriter, r *http.Request) {
+	if m.router == nil {
+		http.Error(w, "Alert routing not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request struct {
+		Level   string `json:"level"`
+		Sink    string `json:"sink"`
+		Message string `json:"message"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if request.Level == "" {
+		request.Level = "warning"
+	}
+	if request.Message == "" {
+		request.Message = "Test alert from cb-monitor"
+	}
+
+	alert := AlertMessage{
+		Level:     request.Level,
+		Kind:      "test",
+		Message:   request.Message,
+		Breaker:   "cb-monitor",
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"test": true},
+	}
+	if !m.router.Fire(alert, request.Sink) {
+		http.Error(w, "No sink accepted the alert", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "queued"})
+}
+
+// handleGetSilences lists active silences
+func (m *CircuitBreakerMonitor) handleGetSilences(w http.ResponseWriter, r *http.Request) {
+	silences := []AlertSilence{}
+	if m.router != nil {
+		silences = m.router.Silences()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(silences)
+}
+
+// handleAddSilence mutes routed alerts for a while:
+//
+//	POST /api/alerts/silences {"breaker": "eth", "level": "warning", "duration": "30m", "reason": "maintenance"}
+func (m *CircuitBreakerMonitor) handleAddSilence(w http.ResponseWriter, r *http.Request) {
+	if m.router == nil {
+		http.Error(w, "Alert routing not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var request struct {
+		Breaker  string `json:"breaker"`
+		Level    string `json:"level"`
+		Duration string `json:"duration"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	d, err := time.ParseDuration(request.Duration)
+	if err != nil || d <= 0 {
+		http.Error(w, "duration must be a positive Go duration, e.g. 30m", http.StatusBadRequest)
+		return
+	}
+
+	silence := m.router.Silence(AlertSilence{
+		Breaker: request.Breaker,
+		Level:   request.Level,
+		Until:   time.Now().Add(d),
+		Reason:  request.Reason,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(silence)
+}
+
+// handleDeleteSilence lifts a silence early
+func (m *CircuitBreakerMonitor) handleDeleteSilence(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid silence id", http.StatusBadRequest)
+		return
+	}
+	if m.router == nil || !m.router.Unsilence(id) {
+		http.Error(w, "Silence not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
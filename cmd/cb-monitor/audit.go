@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+)
+
+// maxRecentAudit bounds the audit entries served by /api/audit
+const maxRecentAudit = 200
+
+// AuditEntry records one operator action on a breaker
+type AuditEntry struct {
+	Time    time.Time                     `json:"time"`
+	Action  string                        `json:"action"` // breaker.state, breaker.reset or breaker.config
+	Breaker string                        `json:"breaker"`
+	Actor   string                        `json:"actor"` // cert:<common name> under mutual TLS, else addr:<ip>
+	State   string                        `json:"state,omitempty"`
+	Changes []circuitbreaker.ConfigChange `json:"changes,omitempty"`
+	Reason  string                        `json:"reason,omitempty"`
+}
+
+// AuditLog keeps recent breaker overrides in memory and, with a path,
+// appends them to a JSON-lines file
+type AuditLog struct {
+	path string
+
+	mu     sync.Mutex
+	recent []AuditEntry
+}
+
+// NewAuditLog appends to path; empty keeps the log in memory only
+func NewAuditLog(path string) *AuditLog {
+	return &AuditLog{path: path}
+}
+
+// Record adds entry to the log
+func (a *AuditLog) Record(entry AuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	log.Printf("Audit: %s %s by %s", entry.Action, entry.Breaker, entry.Actor)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recent = append(a.recent, entry)
+	if len(a.recent) > maxRecentAudit {
+		a.recent = a.recent[len(a.recent)-maxRecentAudit:]
+	}
+	if a.path == "" {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o755); err != nil {
+		log.Printf("Failed to create audit log directory: %v", err)
+		return
+	}
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		log.Printf("Failed to open audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+	}
+}
+
+// Recent returns the entries kept in memory, oldest first
+func (a *AuditLog) Recent() []AuditEntry {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditEntry(nil), a.recent...)
+}
+
+// auditActor identifies who made a request: the client certificate when
+// mutual TLS is on, otherwise the remote address
+func auditActor(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return "cert:" + r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// handleGetAudit returns recent breaker overrides, oldest first
+func (m *CircuitBreakerMonitor) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m.audit.Recent())
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+)
+
+// Federation
+//
+// In a cluster every Sprint instance runs its own chain breakers. With
+// -federate, cb-monitor follows several instances at once: API servers are
+// polled as with -api, and other cb-monitors are subscribed to over their
+// WebSocket. Their breakers join the view as "<instance>/<breaker>" with
+// the instance label set, read-only like -api breakers, and a breaker open
+// on a quorum of instances raises a cluster-wide alert.
+
+// federationMember is one followed instance
+type federationMember struct {
+	name string
+	poll *RemoteBreakers // API server; nil when subscribed
+	ws   string          // cb-monitor WebSocket URL; empty when polled
+
+	mu       sync.RWMutex
+	statuses map[string]CircuitBreakerStatus // Subscribed members only
+	lastErr  error
+}
+
+// Federation merges the breakers of several instances
+type Federation struct {
+	members []*federationMember
+	quorum  int
+}
+
+// ParseFederation builds a Federation from "name=url,..." members. ws://
+// and wss:// URLs are cb-monitor WebSockets to subscribe to; http(s) URLs
+// are API servers, polled with adminKey. A quorum of 0 is a majority.
+func ParseFederation(spec, adminKey string, quorum int) (*Federation, error) {
+	f := &Federation{}
+	seen := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("federation member %q: want name=url", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("federation member %q listed twice", name)
+		}
+		seen[name] = true
+
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("federation member %q: invalid url %q", name, raw)
+		}
+		member := &federationMember{name: name}
+		switch u.Scheme {
+		case "ws", "wss":
+			member.ws = u.String()
+		case "http", "https":
+			member.poll = NewRemoteBreakers(u.String(), adminKey)
+		default:
+			return nil, fmt.Errorf("federation member %q: unsupported scheme %q", name, u.Scheme)
+		}
+		f.members = append(f.members, member)
+	}
+	if len(f.members) == 0 {
+		return nil, fmt.Errorf("no federation members")
+	}
+
+	f.quorum = quorum
+	if f.quorum <= 0 {
+		f.quorum = len(f.members)/2 + 1
+	}
+	if f.quorum > len(f.members) {
+		return nil, fmt.Errorf("quorum %d exceeds %d members", f.quorum, len(f.members))
+	}
+	return f, nil
+}
+
+// Run follows every member until ctx is cancelled
+func (f *Federation) Run(ctx context.Context, interval time.Duration) {
+	for _, member := range f.members {
+		if member.poll != nil {
+			go member.poll.Run(ctx, interval)
+		} else {
+			go member.subscribe(ctx, interval)
+		}
+	}
+}
+
+// subscribe follows a cb-monitor's status updates, reconnecting every
+// interval after the connection drops
+func (fm *federationMember) subscribe(ctx context.Context, interval time.Duration) {
+	for {
+		err := fm.follow(ctx)
+		fm.mu.Lock()
+		// A member that cannot be reached has no breakers to count
+		fm.statuses = nil
+		if err != nil && ctx.Err() == nil && fm.lastErr == nil {
+			log.Printf("Lost federation member %s: %v", fm.name, err)
+		}
+		fm.lastErr = err
+		fm.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// follow reads status updates from one WebSocket connection until it fails
+func (fm *federationMember) follow(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, fm.ws, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	fm.mu.Lock()
+	if fm.lastErr != nil {
+		log.Printf("Following federation member %s again", fm.name)
+	}
+	fm.lastErr = nil
+	fm.mu.Unlock()
+
+	for {
+		var msg struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.Type != schemas.TypeStatusUpdate {
+			continue
+		}
+		var statuses map[string]CircuitBreakerStatus
+		if err := json.Unmarshal(msg.Data, &statuses); err != nil {
+			return fmt.Errorf("decode status update: %w", err)
+		}
+		fm.mu.Lock()
+		fm.statuses = statuses
+		fm.mu.Unlock()
+	}
+}
+
+// breakers returns the member's last known breakers by name
+func (fm *federationMember) breakers() map[string]CircuitBreakerStatus {
+	if fm.poll != nil {
+		return fm.poll.Statuses()
+	}
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+	return fm.statuses
+}
+
+// Statuses returns every member's breakers as "<instance>/<breaker>" with
+// Instance set; empty without -federate
+func (f *Federation) Statuses() map[string]CircuitBreakerStatus {
+	if f == nil {
+		return nil
+	}
+	out := make(map[string]CircuitBreakerStatus)
+	for _, member := range f.members {
+		for name, status := range member.breakers() {
+			if status.Instance != "" {
+				// Already federated by the member; keep one level
+				continue
+			}
+			status.Instance = member.name
+			out[member.name+"/"+name] = status
+		}
+	}
+	return out
+}
+
+// QuorumAlerts returns a critical alert for each breaker open on at least
+// a quorum of members
+func (f *Federation) QuorumAlerts(now time.Time) []AlertMessage {
+	if f == nil {
+		return nil
+	}
+	open := make(map[string][]string)
+	for _, member := range f.members {
+		for name, status := range member.breakers() {
+			if status.Instance == "" && (status.State == "open" || status.State == "force-open") {
+				open[name] = append(open[name], member.name)
+			}
+		}
+	}
+
+	var alerts []AlertMessage
+	for name, instances := range open {
+		if len(instances) < f.quorum {
+			continue
+		}
+		sort.Strings(instances)
+		alerts = append(alerts, AlertMessage{
+			Level:     "critical",
+			Kind:      "cluster_open",
+			Message:   fmt.Sprintf("Circuit breaker open on %d of %d instances", len(instances), len(f.members)),
+			Breaker:   name,
+			Timestamp: now,
+			Metadata: map[string]interface{}{
+				"instances": instances,
+				"quorum":    f.quorum,
+				"members":   len(f.members),
+			},
+		})
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Breaker < alerts[j].Breaker })
+	return alerts
+}
+
+// remoteStatuses returns the read-only breakers: those polled with -api
+// and those of federation members
+func (m *CircuitBreakerMonitor) remoteStatuses() map[string]CircuitBreakerStatus {
+	out := make(map[string]CircuitBreakerStatus)
+	for name, status := range m.remote.Statuses() {
+		out[name] = status
+	}
+	for name, status := range m.federation.Statuses() {
+		out[name] = status
+	}
+	return out
+}
+
+// startFederation follows the members in spec, if any
+func (m *CircuitBreakerMonitor) startFederation(ctx context.Context, spec string, quorum int, interval time.Duration) {
+	if spec == "" {
+		return
+	}
+	f, err := ParseFederation(spec, os.Getenv("ADMIN_API_KEY"), quorum)
+	if err != nil {
+		log.Fatalf("Invalid -federate: %v", err)
+	}
+	m.federation = f
+	f.Run(ctx, interval)
+	log.Printf("Federating %d instance(s), cluster alerts at %d open", len(f.members), f.quorum)
+}
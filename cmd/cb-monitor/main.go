@@ -15,65 +15,65 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/credentials"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/tlsconfig"
 )
 
 // CircuitBreakerMonitor provides real-time monitoring of circuit breakers
 type CircuitBreakerMonitor struct {
-	breakers  map[string]*circuitbreaker.EnterpriseCircuitBreaker
-	mu        sync.RWMutex
-	upgrader  websocket.Upgrader
-	clients   map[*websocket.Conn]bool
-	clientsMu sync.RWMutex
-	broadcast chan MonitorMessage
-	stopChan  chan struct{}
+	breakers   map[string]*circuitbreaker.EnterpriseCircuitBreaker
+	mu         sync.RWMutex
+	upgrader   websocket.Upgrader
+	clients    map[*websocket.Conn]bool
+	clientsMu  sync.RWMutex
+	broadcast  chan schemas.MonitorMessage
+	stopChan   chan struct{}
+	alerts     []AlertMessage // Recent alerts, oldest first
+	alertsMu   sync.Mutex
+	router     *AlertRouter    // Delivers alerts to on-call sinks; nil without -alerts
+	audit      *AuditLog       // Operator overrides: state, reset and config changes
+	remote     *RemoteBreakers // Read-only breakers polled from an API server; nil without -api
+	federation *Federation     // Breakers of other instances; nil without -federate
+	slo        *SLOTracker     // Objectives and burn alerts; nil without -slo
 }
 
-// MonitorMessage represents a message sent to monitoring clients
-type MonitorMessage struct {
-	Type      string      `json:"type"`
-	Timestamp time.Time   `json:"timestamp"`
-	Data      interface{} `json:"data"`
-}
+// maxRecentAlerts bounds the alert history served by /api/alerts
+const maxRecentAlerts = 100
+
+// WebSocket messages follow the versioned schemas in internal/schemas,
+// served at /api/schemas
 
 // CircuitBreakerStatus represents the current status of a circuit breaker
-type CircuitBreakerStatus struct {
-	Name            string                                `json:"name"`
-	State           string                                `json:"state"`
-	Metrics         *circuitbreaker.CircuitBreakerMetrics `json:"metrics"`
-	Health          float64                               `json:"health"`
-	LastStateChange time.Time                             `json:"last_state_change"`
-	Configuration   CircuitBreakerConfig                  `json:"configuration"`
-}
+type CircuitBreakerStatus = schemas.BreakerStatusV1
 
 // CircuitBreakerConfig represents configuration summary
-type CircuitBreakerConfig struct {
-	MaxFailures      int           `json:"max_failures"`
-	ResetTimeout     time.Duration `json:"reset_timeout"`
-	FailureThreshold float64       `json:"failure_threshold"`
-	EnableAdaptive   bool          `json:"enable_adaptive"`
-	EnableHealth     bool          `json:"enable_health"`
-}
+type CircuitBreakerConfig = schemas.BreakerConfigV1
 
 // AlertMessage represents an alert condition
-type AlertMessage struct {
-	Level     string                 `json:"level"`
-	Message   string                 `json:"message"`
-	Breaker   string                 `json:"breaker"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata"`
-}
+type AlertMessage = schemas.AlertV1
 
 func main() {
 	var (
 		port       = flag.String("port", "8090", "Monitor server port")
 		configFile = flag.String("config", "", "Configuration file path")
 		interval   = flag.Duration("interval", time.Second*5, "Monitoring interval")
+		webDir     = flag.String("web-dir", "", "Serve dashboard assets from this directory instead of the embedded copy")
+		alertsFile = flag.String("alerts", "", "Alert routing config (Slack, PagerDuty and webhook sinks)")
+		auditFile  = flag.String("audit", "", "Append breaker overrides to this JSON-lines audit log")
+		apiURL     = flag.String("api", "", "Also show the chain breakers of the API server at this base URL")
+		federate   = flag.String("federate", "", "Also show the breakers of these instances, name=url,... (http(s) API servers are polled, ws(s) cb-monitors subscribed to)")
+		quorum     = flag.Int("quorum", 0, "Federated instances a breaker must be open on for a cluster alert (0 = majority)")
+		sloFile    = flag.String("slo", "", "SLO config: per-breaker objectives and error-budget burn alerts")
 	)
 	flag.Parse()
 
 	monitor := NewCircuitBreakerMonitor()
+	monitor.audit = NewAuditLog(*auditFile)
 
 	// Load configuration if provided
 	if *configFile != "" {
@@ -82,28 +82,85 @@ func main() {
 		}
 	}
 
+	if *alertsFile != "" {
+		router, err := LoadAlertRouter(*alertsFile)
+		if err != nil {
+			log.Fatalf("Failed to load alert routing: %v", err)
+		}
+		monitor.router = router
+		router.Start(monitor.stopChan)
+		log.Printf("Routing alerts to %d sink(s)", len(router.sinks))
+	}
+
+	if *sloFile != "" {
+		slo, err := LoadSLOTracker(*sloFile)
+		if err != nil {
+			log.Fatalf("Failed to load SLOs: %v", err)
+		}
+		monitor.slo = slo
+		log.Printf("Tracking %d SLO(s) with %d burn alert(s)", len(slo.objectives), len(slo.alerts))
+	}
+
 	// Start monitoring
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if *apiURL != "" {
+		// Admin routes on the API server take the same key it was started with
+		monitor.remote = NewRemoteBreakers(*apiURL, os.Getenv("ADMIN_API_KEY"))
+		go monitor.remote.Run(ctx, *interval)
+		log.Printf("Polling chain breakers from %s", *apiURL)
+	}
+	monitor.startFederation(ctx, *federate, *quorum, *interval)
+
 	monitor.Start(ctx, *interval)
 
+	// TLS settings come from the unified config (ENABLE_TLS, TLS_*)
+	appCfg := config.Load()
+	// Replace secretref:// values before anything is built from the config
+	if _, err := credentials.ResolveConfig(ctx, &appCfg, nil); err != nil {
+		log.Fatalf("Failed to resolve config secrets: %v", err)
+	}
+
 	// Setup HTTP server
 	router := mux.NewRouter()
 
+	// Breaker overrides change production behaviour; require a client
+	// certificate for them when mutual TLS is on
+	control := func(h http.HandlerFunc) http.Handler {
+		if appCfg.EnableMTLS {
+			return tlsconfig.RequireClientCert(h)
+		}
+		return h
+	}
+
 	// API endpoints
 	router.HandleFunc("/api/breakers", monitor.handleGetBreakers).Methods("GET")
 	router.HandleFunc("/api/breakers/{name}", monitor.handleGetBreaker).Methods("GET")
 	router.HandleFunc("/api/breakers/{name}/metrics", monitor.handleGetMetrics).Methods("GET")
-	router.HandleFunc("/api/breakers/{name}/state", monitor.handleSetState).Methods("POST")
-	router.HandleFunc("/api/breakers/{name}/reset", monitor.handleReset).Methods("POST")
+	router.HandleFunc("/api/breakers/{name}/history", monitor.handleGetHistory).Methods("GET")
+	router.Handle("/api/breakers/{name}/state", control(monitor.handleSetState)).Methods("POST")
+	router.Handle("/api/breakers/{name}/reset", control(monitor.handleReset)).Methods("POST")
+	router.HandleFunc("/api/breakers/{name}/config", monitor.handleGetConfig).Methods("GET")
+	router.Handle("/api/breakers/{name}/config", control(monitor.handleUpdateConfig)).Methods("PATCH")
+	router.HandleFunc("/api/audit", monitor.handleGetAudit).Methods("GET")
 	router.HandleFunc("/api/alerts", monitor.handleGetAlerts).Methods("GET")
+	router.HandleFunc("/api/slos", monitor.handleGetSLOs).Methods("GET")
+	router.PathPrefix("/api/schemas").Handler(schemas.Handler("/api/schemas")).Methods("GET")
+	router.HandleFunc("/api/alerts/sinks", monitor.handleGetAlertSinks).Methods("GET")
+	router.Handle("/api/alerts/test", control(monitor.handleTestAlert)).Methods("POST")
+	router.HandleFunc("/api/alerts/silences", monitor.handleGetSilences).Methods("GET")
+	router.Handle("/api/alerts/silences", control(monitor.handleAddSilence)).Methods("POST")
+	router.Handle("/api/alerts/silences/{id}", control(monitor.handleDeleteSilence)).Methods("DELETE")
 
 	// WebSocket endpoint for real-time updates
 	router.HandleFunc("/ws", monitor.handleWebSocket)
 
-	// Static file serving for web interface
-	router.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/monitor/")))
+	// Go runtime and process metrics, with any breaker metrics
+	router.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// Dashboard
+	router.PathPrefix("/").Handler(webHandler(*webDir))
 
 	server := &http.Server{
 		Addr:         ":" + *port,
@@ -111,11 +168,24 @@ func main() {
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
+	if appCfg.EnableTLS {
+		tlsCfg, err := tlsconfig.New(tlsconfig.FromConfig(appCfg))
+		if err != nil {
+			log.Fatalf("Failed to configure TLS: %v", err)
+		}
+		server.TLSConfig = tlsCfg
+	}
 
 	// Start server
 	go func() {
-		log.Printf("Circuit Breaker Monitor starting on port %s", *port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Circuit Breaker Monitor starting on port %s (tls=%t)", *port, server.TLSConfig != nil)
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
@@ -149,8 +219,9 @@ func NewCircuitBreakerMonitor() *CircuitBreakerMonitor {
 			},
 		},
 		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan MonitorMessage, 100),
+		broadcast: make(chan schemas.MonitorMessage, 100),
 		stopChan:  make(chan struct{}),
+		audit:     NewAuditLog(""),
 	}
 }
 
@@ -206,36 +277,26 @@ func (m *CircuitBreakerMonitor) collectAndBroadcastStatus() {
 	statuses := make(map[string]CircuitBreakerStatus)
 
 	for name, breaker := range m.breakers {
-		metrics := breaker.GetMetrics()
-
-		status := CircuitBreakerStatus{
-			Name:            name,
-			State:           breaker.State().String(),
-			Metrics:         metrics,
-			Health:          metrics.HealthScore,
-			LastStateChange: metrics.LastStateChange,
-			Configuration: CircuitBreakerConfig{
-				MaxFailures:      10, // This would come from breaker config
-				ResetTimeout:     time.Minute,
-				FailureThreshold: 0.5,
-				EnableAdaptive:   true,
-				EnableHealth:     true,
-			},
-		}
-
+		statuses[name] = schemas.NewBreakerStatus(name, breaker)
+	}
+	m.mu.RUnlock()
+	for name, status := range m.remoteStatuses() {
 		statuses[name] = status
+	}
 
-		// Check for alert conditions
+	// Check for alert conditions
+	for name, status := range statuses {
 		m.checkAlerts(name, status)
 	}
-	m.mu.RUnlock()
+	for _, alert := range m.federation.QuorumAlerts(time.Now()) {
+		m.sendAlert(alert)
+	}
+	for _, alert := range m.slo.Observe(statuses, time.Now()) {
+		m.sendAlert(alert)
+	}
 
 	// Broadcast status update
-	message := MonitorMessage{
-		Type:      "status_update",
-		Timestamp: time.Now(),
-		Data:      statuses,
-	}
+	message := schemas.NewStatusUpdate(statuses, time.Now())
 
 	select {
 	case m.broadcast <- message:
@@ -250,6 +311,7 @@ func (m *CircuitBreakerMonitor) checkAlerts(name string, status CircuitBreakerSt
 	if status.Metrics.FailureRate > 0.8 {
 		alert := AlertMessage{
 			Level:     "critical",
+			Kind:      "failure_rate",
 			Message:   fmt.Sprintf("High failure rate: %.2f%%", status.Metrics.FailureRate*100),
 			Breaker:   name,
 			Timestamp: time.Now(),
@@ -266,6 +328,7 @@ func (m *CircuitBreakerMonitor) checkAlerts(name string, status CircuitBreakerSt
 	if status.State == "open" {
 		alert := AlertMessage{
 			Level:     "warning",
+			Kind:      "open",
 			Message:   "Circuit breaker is open",
 			Breaker:   name,
 			Timestamp: time.Now(),
@@ -282,6 +345,7 @@ func (m *CircuitBreakerMonitor) checkAlerts(name string, status CircuitBreakerSt
 	if status.Health < 0.5 {
 		alert := AlertMessage{
 			Level:     "warning",
+			Kind:      "low_health",
 			Message:   fmt.Sprintf("Low health score: %.2f", status.Health),
 			Breaker:   name,
 			Timestamp: time.Now(),
@@ -295,14 +359,22 @@ func (m *CircuitBreakerMonitor) checkAlerts(name string, status CircuitBreakerSt
 	}
 }
 
-// sendAlert broadcasts an alert message
+// sendAlert records and broadcasts an alert message and routes it to the
+// configured sinks
 func (m *CircuitBreakerMonitor) sendAlert(alert AlertMessage) {
-	message := MonitorMessage{
-		Type:      "alert",
-		Timestamp: time.Now(),
-		Data:      alert,
+	if m.router != nil {
+		m.router.Route(alert)
 	}
 
+	m.alertsMu.Lock()
+	m.alerts = append(m.alerts, alert)
+	if len(m.alerts) > maxRecentAlerts {
+		m.alerts = m.alerts[len(m.alerts)-maxRecentAlerts:]
+	}
+	m.alertsMu.Unlock()
+
+	message := schemas.NewAlert(alert, time.Now())
+
 	select {
 	case m.broadcast <- message:
 	default:
@@ -315,14 +387,14 @@ func (m *CircuitBreakerMonitor) broadcastLoop() {
 	for {
 		select {
 		case message := <-m.broadcast:
-			m.clientsMu.RLock()
+			m.clientsMu.Lock()
 			for client := range m.clients {
 				if err := client.WriteJSON(message); err != nil {
 					client.Close()
 					delete(m.clients, client)
 				}
 			}
-			m.clientsMu.RUnlock()
+			m.clientsMu.Unlock()
 
 		case <-m.stopChan:
 			return
@@ -349,6 +421,9 @@ func (m *CircuitBreakerMonitor) handleGetBreakers(w http.ResponseWriter, r *http
 			LastStateChange: metrics.LastStateChange,
 		}
 	}
+	for name, status := range m.remoteStatuses() {
+		breakers[name] = status
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(breakers)
@@ -364,6 +439,11 @@ func (m *CircuitBreakerMonitor) handleGetBreaker(w http.ResponseWriter, r *http.
 	m.mu.RUnlock()
 
 	if !exists {
+		if status, ok := m.remoteStatuses()[name]; ok {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(status)
+			return
+		}
 		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
 		return
 	}
@@ -401,6 +481,45 @@ func (m *CircuitBreakerMonitor) handleGetMetrics(w http.ResponseWriter, r *http.
 	json.NewEncoder(w).Encode(metrics)
 }
 
+// BreakerHistoryExport is the incident export served by /api/breakers/{name}/history
+type BreakerHistoryExport struct {
+	Name       string                                `json:"name"`
+	ExportedAt time.Time                             `json:"exported_at"`
+	State      string                                `json:"state"`
+	Metrics    *circuitbreaker.CircuitBreakerMetrics `json:"metrics"`
+	Events     []circuitbreaker.Event                `json:"events"`
+}
+
+// handleGetHistory downloads a circuit breaker's recent events as JSON
+func (m *CircuitBreakerMonitor) handleGetHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name := vars["name"]
+
+	m.mu.RLock()
+	breaker, exists := m.breakers[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	export := BreakerHistoryExport{
+		Name:       name,
+		ExportedAt: time.Now().UTC(),
+		State:      breaker.State().String(),
+		Metrics:    breaker.GetMetrics(),
+		Events:     breaker.GetHistory(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q",
+		name+"-history-"+export.ExportedAt.Format("20060102T150405Z")+".json"))
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(export)
+}
+
 // handleSetState sets the state of a circuit breaker
 func (m *CircuitBreakerMonitor) handleSetState(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -435,6 +554,7 @@ func (m *CircuitBreakerMonitor) handleSetState(w http.ResponseWriter, r *http.Re
 		http.Error(w, "Invalid state", http.StatusBadRequest)
 		return
 	}
+	m.audit.Record(AuditEntry{Action: "breaker.state", Breaker: name, Actor: auditActor(r), State: request.State})
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
@@ -455,17 +575,171 @@ func (m *CircuitBreakerMonitor) handleReset(w http.ResponseWriter, r *http.Reque
 	}
 
 	breaker.Reset()
+	m.audit.Record(AuditEntry{Action: "breaker.reset", Breaker: name, Actor: auditActor(r)})
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "reset successful"})
 }
 
-// handleGetAlerts returns recent alerts
+// BreakerSettings are a breaker's tunable settings, as served by
+// GET /api/breakers/{name}/config. Durations are Go duration strings.
+type BreakerSettings struct {
+	FailureThreshold float64 `json:"failure_threshold"`
+	MaxFailures      int     `json:"max_failures"`
+	ResetTimeout     string  `json:"reset_timeout"`
+	Timeout          string  `json:"timeout"`
+	HalfOpenMaxCalls int     `json:"half_open_max_calls"`
+	HalfOpenTrials   int     `json:"half_open_trials"`
+	ProbeInterval    string  `json:"probe_interval"`
+	ProbeJitter      float64 `json:"probe_jitter"`
+	LatencyThreshold string  `json:"latency_threshold"`
+	HealthThreshold  float64 `json:"health_threshold"`
+	TripStrategy     string  `json:"trip_strategy"`
+	CooldownStrategy string  `json:"cooldown_strategy"`
+}
+
+func breakerSettings(cfg circuitbreaker.EnterpriseConfig) BreakerSettings {
+	return BreakerSettings{
+		FailureThreshold: cfg.FailureThreshold,
+		MaxFailures:      cfg.MaxFailures,
+		ResetTimeout:     cfg.ResetTimeout.String(),
+		Timeout:          cfg.Timeout.String(),
+		HalfOpenMaxCalls: cfg.HalfOpenMaxCalls,
+		HalfOpenTrials:   cfg.HalfOpenTrials,
+		ProbeInterval:    cfg.ProbeInterval.String(),
+		ProbeJitter:      cfg.ProbeJitter,
+		LatencyThreshold: cfg.LatencyThreshold.String(),
+		HealthThreshold:  cfg.HealthThreshold,
+		TripStrategy:     cfg.TripStrategy,
+		CooldownStrategy: cfg.CooldownStrategy,
+	}
+}
+
+// handleGetConfig returns a breaker's tunable settings
+func (m *CircuitBreakerMonitor) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	m.mu.RLock()
+	breaker, exists := m.breakers[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(breakerSettings(breaker.CurrentConfig()))
+}
+
+// handleUpdateConfig changes a live breaker's settings. Omitted fields are
+// left alone and the whole update is rejected if any field is invalid:
+//
+//	PATCH /api/breakers/{name}/config {"max_failures": 20, "reset_timeout": "45s", "reason": "provider incident"}
+func (m *CircuitBreakerMonitor) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var request struct {
+		FailureThreshold *float64 `json:"failure_threshold"`
+		MaxFailures      *int     `json:"max_failures"`
+		ResetTimeout     *string  `json:"reset_timeout"`
+		Timeout          *string  `json:"timeout"`
+		HalfOpenMaxCalls *int     `json:"half_open_max_calls"`
+		HalfOpenTrials   *int     `json:"half_open_trials"`
+		ProbeInterval    *string  `json:"probe_interval"`
+		ProbeJitter      *float64 `json:"probe_jitter"`
+		LatencyThreshold *string  `json:"latency_threshold"`
+		HealthThreshold  *float64 `json:"health_threshold"`
+		TripStrategy     *string  `json:"trip_strategy"`
+		CooldownStrategy *string  `json:"cooldown_strategy"`
+		Reason           string   `json:"reason"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&request); err != nil {
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update := circuitbreaker.ConfigUpdate{
+		FailureThreshold: request.FailureThreshold,
+		MaxFailures:      request.MaxFailures,
+		HalfOpenMaxCalls: request.HalfOpenMaxCalls,
+		HalfOpenTrials:   request.HalfOpenTrials,
+		ProbeJitter:      request.ProbeJitter,
+		HealthThreshold:  request.HealthThreshold,
+		TripStrategy:     request.TripStrategy,
+		CooldownStrategy: request.CooldownStrategy,
+	}
+	for _, d := range []struct {
+		field string
+		in    *string
+		out   **time.Duration
+	}{
+		{"reset_timeout", request.ResetTimeout, &update.ResetTimeout},
+		{"timeout", request.Timeout, &update.Timeout},
+		{"probe_interval", request.ProbeInterval, &update.ProbeInterval},
+		{"latency_threshold", request.LatencyThreshold, &update.LatencyThreshold},
+	} {
+		if d.in == nil {
+			continue
+		}
+		v, err := time.ParseDuration(*d.in)
+		if err != nil {
+			http.Error(w, d.field+" must be a Go duration, e.g. 30s", http.StatusBadRequest)
+			return
+		}
+		*d.out = &v
+	}
+
+	m.mu.RLock()
+	breaker, exists := m.breakers[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		http.Error(w, "Circuit breaker not found", http.StatusNotFound)
+		return
+	}
+
+	actor := auditActor(r)
+	reason := request.Reason
+	if reason == "" {
+		reason = "updated by " + actor
+	}
+	changes, err := breaker.UpdateConfig(update, reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if len(changes) == 0 {
+		changes = []circuitbreaker.ConfigChange{}
+	} else {
+		m.audit.Record(AuditEntry{
+			Action:  "breaker.config",
+			Breaker: name,
+			Actor:   actor,
+			Changes: changes,
+			Reason:  request.Reason,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":     name,
+		"changes":  changes,
+		"settings": breakerSettings(breaker.CurrentConfig()),
+	})
+}
+
+// handleGetAlerts returns recent alerts, oldest first
 func (m *CircuitBreakerMonitor) handleGetAlerts(w http.ResponseWriter, r *http.Request) {
-	// This would typically fetch from a persistent store
-	// For now, return empty array
+	m.alertsMu.Lock()
+	alerts := make([]AlertMessage, len(m.alerts))
+	copy(alerts, m.alerts)
+	m.alertsMu.Unlock()
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode([]AlertMessage{})
+	json.NewEncoder(w).Encode(alerts)
 }
 
 // handleWebSocket handles WebSocket connections for real-time updates
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteBreakers polls the chain breakers of an API server. They are shown
+// and alerted on like local breakers but cannot be overridden from here.
+type RemoteBreakers struct {
+	url      string
+	adminKey string
+	client   *http.Client
+
+	mu       sync.RWMutex
+	statuses map[string]CircuitBreakerStatus
+	lastErr  error
+}
+
+// NewRemoteBreakers polls the API server at baseURL with adminKey
+func NewRemoteBreakers(baseURL, adminKey string) *RemoteBreakers {
+	return &RemoteBreakers{
+		url:      strings.TrimRight(baseURL, "/") + "/api/v1/admin/breakers",
+		adminKey: adminKey,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Run polls every interval until ctx is cancelled
+func (rb *RemoteBreakers) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		rb.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (rb *RemoteBreakers) poll(ctx context.Context) {
+	statuses, err := rb.fetch(ctx)
+
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	if err != nil {
+		// Log once per outage rather than every interval
+		if rb.lastErr == nil {
+			log.Printf("Failed to poll API breakers: %v", err)
+		}
+		rb.lastErr = err
+		return
+	}
+	if rb.lastErr != nil {
+		log.Printf("Polling API breakers again")
+	}
+	rb.lastErr = nil
+	rb.statuses = statuses
+}
+
+func (rb *RemoteBreakers) fetch(ctx context.Context) (map[string]CircuitBreakerStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rb.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Admin-Key", rb.adminKey)
+	resp, err := rb.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var statuses map[string]CircuitBreakerStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// Statuses returns the last polled breakers by name; empty before the
+// first successful poll or without -api
+func (rb *RemoteBreakers) Statuses() map[string]CircuitBreakerStatus {
+	if rb == nil {
+		return nil
+	}
+	rb.mu.RLock()
+	defer rb.mu.RUnlock()
+	return rb.statuses
+}
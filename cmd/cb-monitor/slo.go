@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SLO tracking
+//
+// With -slo, breakers get service level objectives, e.g. 99.9% of calls
+// succeed over 30 days. Each collection interval the monitor adds the
+// calls a breaker made since the last one to per-minute buckets: calls
+// that failed or were rejected by an open circuit count against the
+// objective. From those it reports compliance and the error budget left
+// over the SLO window, and raises multi-window burn-rate alerts: a burn
+// alert fires when the budget is being spent faster than its threshold
+// over both its long and its short window, so it pages on a real trend
+// and stops soon after the trend does. History is kept in memory and
+// starts over when the monitor restarts.
+
+const (
+	defaultSLOWindow = 30 * 24 * time.Hour
+	sloBucketWidth   = time.Minute
+)
+
+// SLOConfig is one objective. Breaker "*" applies to every breaker
+// without an objective of its own.
+type SLOConfig struct {
+	Breaker   string  `json:"breaker"`
+	Objective float64 `json:"objective"`        // Fraction of good calls, e.g. 0.999
+	Window    string  `json:"window,omitempty"` // Go duration; default 720h
+}
+
+// BurnAlertConfig is one burn-rate alert, evaluated for every objective
+type BurnAlertConfig struct {
+	Name     string  `json:"name"`
+	Long     string  `json:"long"`      // Go duration
+	Short    string  `json:"short"`     // Go duration
+	BurnRate float64 `json:"burn_rate"` // Budget spend relative to spending it evenly over the window
+	Level    string  `json:"level"`
+}
+
+// SLOFile is the file loaded by -slo
+type SLOFile struct {
+	SLOs       []SLOConfig       `json:"slos"`
+	BurnAlerts []BurnAlertConfig `json:"burn_alerts,omitempty"` // Default: fast and slow
+}
+
+// defaultBurnAlerts page when 2% of a 30-day budget goes in an hour and
+// warn when 5% goes in six hours
+var defaultBurnAlerts = []BurnAlertConfig{
+	{Name: "fast", Long: "1h", Short: "5m", BurnRate: 14.4, Level: "critical"},
+	{Name: "slow", Long: "6h", Short: "30m", BurnRate: 6, Level: "warning"},
+}
+
+type sloObjective struct {
+	SLOConfig
+	window time.Duration
+}
+
+type burnAlert struct {
+	BurnAlertConfig
+	long, short time.Duration
+}
+
+// SLOStatus is a breaker's standing against its objective, as served by
+// /api/slos
+type SLOStatus struct {
+	Breaker         string             `json:"breaker"`
+	Objective       float64            `json:"objective"`
+	Window          string             `json:"window"`
+	Covered         string             `json:"covered"` // History behind the figures, up to Window
+	Calls           int64              `json:"calls"`
+	BadCalls        int64              `json:"bad_calls"`
+	Compliance      float64            `json:"compliance"`       // 1 with no calls yet
+	BudgetRemaining float64            `json:"budget_remaining"` // Fraction of the error budget left; negative once overspent
+	BurnRates       map[string]float64 `json:"burn_rates"`       // By window, e.g. "1h"
+	Firing          []string           `json:"firing"`           // Burn alerts currently firing
+}
+
+type sloBucket struct {
+	minute    int64
+	calls     int64
+	bad       int64
+	populated bool
+}
+
+// sloSeries is one breaker's call history in per-minute buckets, as a
+// ring covering its SLO window
+type sloSeries struct {
+	buckets             []sloBucket
+	first               time.Time
+	lastCalls, lastGood int64
+	seen                bool
+	firing              map[string]bool // Burn alert name -> firing
+}
+
+// SLOTracker computes compliance and burn rates for breakers with objectives
+type SLOTracker struct {
+	objectives map[string]sloObjective // Breaker name or "*"
+	alerts     []burnAlert
+
+	mu     sync.Mutex
+	series map[string]*sloSeries
+}
+
+// NewSLOTracker validates f
+func NewSLOTracker(f SLOFile) (*SLOTracker, error) {
+	t := &SLOTracker{
+		objectives: make(map[string]sloObjective),
+		series:     make(map[string]*sloSeries),
+	}
+	for _, c := range f.SLOs {
+		if c.Breaker == "" {
+			return nil, fmt.Errorf("slo needs a breaker (or \"*\")")
+		}
+		if c.Objective <= 0 || c.Objective >= 1 {
+			return nil, fmt.Errorf("slo %q: objective must be between 0 and 1, e.g. 0.999", c.Breaker)
+		}
+		if _, dup := t.objectives[c.Breaker]; dup {
+			return nil, fmt.Errorf("slo %q listed twice", c.Breaker)
+		}
+		o := sloObjective{SLOConfig: c, window: defaultSLOWindow}
+		if c.Window != "" {
+			d, err := time.ParseDuration(c.Window)
+			if err != nil || d < sloBucketWidth {
+				return nil, fmt.Errorf("slo %q: window must be a Go duration of at least 1m", c.Breaker)
+			}
+			o.window = d
+		}
+		t.objectives[c.Breaker] = o
+	}
+
+	alerts := f.BurnAlerts
+	if len(alerts) == 0 {
+		alerts = defaultBurnAlerts
+	}
+	for _, c := range alerts {
+		a := burnAlert{BurnAlertConfig: c}
+		var err error
+		if a.long, err = time.ParseDuration(c.Long); err != nil {
+			return nil, fmt.Errorf("burn alert %q: long: %w", c.Name, err)
+		}
+		if a.short, err = time.ParseDuration(c.Short); err != nil {
+			return nil, fmt.Errorf("burn alert %q: short: %w", c.Name, err)
+		}
+		if c.Name == "" || a.short <= 0 || a.long < a.short || c.BurnRate <= 0 {
+			return nil, fmt.Errorf("burn alert %q: needs a name, short <= long and a positive burn_rate", c.Name)
+		}
+		if c.Level == "" {
+			a.Level = "warning"
+		}
+		t.alerts = append(t.alerts, a)
+	}
+	return t, nil
+}
+
+// LoadSLOTracker reads an SLOFile from path
+func LoadSLOTracker(path string) (*SLOTracker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f SLOFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewSLOTracker(f)
+}
+
+func (t *SLOTracker) objectiveFor(name string) (sloObjective, bool) {
+	if o, ok := t.objectives[name]; ok {
+		return o, true
+	}
+	o, ok := t.objectives["*"]
+	return o, ok
+}
+
+// Observe adds the calls breakers made since the last observation and
+// returns an alert for each burn alert that started firing
+func (t *SLOTracker) Observe(statuses map[string]CircuitBreakerStatus, now time.Time) []AlertMessage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var alerts []AlertMessage
+	for name, status := range statuses {
+		o, ok := t.objectiveFor(name)
+		if !ok || status.Metrics == nil {
+			continue
+		}
+		s := t.series[name]
+		if s == nil {
+			s = &sloSeries{
+				buckets: make([]sloBucket, int(o.window/sloBucketWidth)),
+				first:   now,
+				firing:  make(map[string]bool),
+			}
+			t.series[name] = s
+		}
+
+		// Calls rejected by an open circuit failed as far as callers know
+		m := status.Metrics
+		calls := m.TotalRequests + m.CircuitOpenRequests
+		good := m.SuccessfulRequests
+		if s.seen && calls >= s.lastCalls && good >= s.lastGood {
+			s.add(now, calls-s.lastCalls, (calls-s.lastCalls)-(good-s.lastGood))
+		}
+		// A reset breaker starts its counters over; take the new baseline
+		s.lastCalls, s.lastGood, s.seen = calls, good, true
+
+		for _, a := range t.alerts {
+			long := s.burnRate(now, a.long, o.Objective)
+			short := s.burnRate(now, a.short, o.Objective)
+			firing := long >= a.BurnRate && short >= a.BurnRate
+			if firing && !s.firing[a.Name] {
+				alerts = append(alerts, AlertMessage{
+					Level: a.Level,
+					Kind:  "slo_burn_" + a.Name,
+					Message: fmt.Sprintf("Error budget burning %.1fx too fast over %s (objective %g%%)",
+						long, a.Long, o.Objective*100),
+					Breaker:   name,
+					Timestamp: now,
+					Metadata: map[string]interface{}{
+						"objective":       o.Objective,
+						"burn_rate_long":  long,
+						"burn_rate_short": short,
+						"long_window":     a.Long,
+						"short_window":    a.Short,
+						"threshold":       a.BurnRate,
+					},
+				})
+			}
+			s.firing[a.Name] = firing
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Breaker < alerts[j].Breaker })
+	return alerts
+}
+
+func (s *sloSeries) add(now time.Time, calls, bad int64) {
+	minute := now.Unix() / int64(sloBucketWidth/time.Second)
+	b := &s.buckets[minute%int64(len(s.buckets))]
+	if !b.populated || b.minute != minute {
+		*b = sloBucket{minute: minute, populated: true}
+	}
+	b.calls += calls
+	b.bad += bad
+}
+
+// sum totals the buckets within window of now
+func (s *sloSeries) sum(now time.Time, window time.Duration) (calls, bad int64) {
+	minute := now.Unix() / int64(sloBucketWidth/time.Second)
+	n := min(int64(window/sloBucketWidth), int64(len(s.buckets)))
+	for m := minute - n + 1; m <= minute; m++ {
+		if b := s.buckets[m%int64(len(s.buckets))]; b.populated && b.minute == m {
+			calls += b.calls
+			bad += b.bad
+		}
+	}
+	return calls, bad
+}
+
+// burnRate is the error rate over window relative to the budget
+func (s *sloSeries) burnRate(now time.Time, window time.Duration, objective float64) float64 {
+	calls, bad := s.sum(now, window)
+	if calls == 0 {
+		return 0
+	}
+	return float64(bad) / float64(calls) / (1 - objective)
+}
+
+// Statuses reports every tracked breaker against its objective
+func (t *SLOTracker) Statuses(now time.Time) []SLOStatus {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]SLOStatus, 0, len(t.series))
+	for name, s := range t.series {
+		o, ok := t.objectiveFor(name)
+		if !ok {
+			continue
+		}
+		calls, bad := s.sum(now, o.window)
+		st := SLOStatus{
+			Breaker:         name,
+			Objective:       o.Objective,
+			Window:          o.window.String(),
+			Covered:         min(now.Sub(s.first), o.window).Round(time.Second).String(),
+			Calls:           calls,
+			BadCalls:        bad,
+			Compliance:      1,
+			BudgetRemaining: 1,
+			BurnRates:       make(map[string]float64),
+			Firing:          []string{},
+		}
+		if calls > 0 {
+			st.Compliance = 1 - float64(bad)/float64(calls)
+			st.BudgetRemaining = 1 - float64(bad)/float64(calls)/(1-o.Objective)
+		}
+		for _, a := range t.alerts {
+			st.BurnRates[a.Long] = s.burnRate(now, a.long, o.Objective)
+			st.BurnRates[a.Short] = s.burnRate(now, a.short, o.Objective)
+			if s.firing[a.Name] {
+				st.Firing = append(st.Firing, a.Name)
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Breaker < out[j].Breaker })
+	return out
+}
+
+// handleGetSLOs returns each breaker's compliance, budget and burn rates
+func (m *CircuitBreakerMonitor) handleGetSLOs(w http.ResponseWriter, r *http.Request) {
+	slos := m.slo.Statuses(time.Now())
+	if slos == nil {
+		slos = []SLOStatus{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slos)
+}
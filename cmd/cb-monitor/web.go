@@ -0,0 +1,27 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+	"net/http"
+)
+
+// webAssets holds the dashboard so the binary works from any directory
+//
+//go:embed web
+var webAssets embed.FS
+
+// webHandler serves the dashboard from dir when set, for editing the assets
+// without rebuilding, and from the embedded copy otherwise.
+func webHandler(dir string) http.Handler {
+	if dir != "" {
+		log.Printf("Serving dashboard from %s", dir)
+		return http.FileServer(http.Dir(dir))
+	}
+	assets, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		log.Fatalf("Embedded dashboard assets missing: %v", err)
+	}
+	return http.FileServer(http.FS(assets))
+}
@@ -0,0 +1,234 @@
+// Circuit breaker dashboard: seeds from the REST API, then follows the
+// /ws channel for status updates and alerts.
+(function () {
+  'use strict';
+
+  var TIMELINE_WINDOW_MS = 15 * 60 * 1000;
+  var MAX_ALERTS = 100;
+  var SLO_REFRESH_MS = 15000;
+
+  var breakers = {};  // name -> latest status
+  var timelines = {}; // name -> [{state, at}]
+  var alerts = [];
+
+  function el(tag, cls, text) {
+    var e = document.createElement(tag);
+    if (cls) e.className = cls;
+    if (text !== undefined) e.textContent = text;
+    return e;
+  }
+
+  function stateClass(state) {
+    return 'state-' + (state || 'unknown');
+  }
+
+  function fmtTime(ts) {
+    var d = new Date(ts);
+    return isNaN(d.getTime()) || d.getFullYear() < 2000 ? '-' : d.toLocaleTimeString();
+  }
+
+  function fmtLatency(ns) {
+    if (!ns) return '-';
+    return (ns / 1e6).toFixed(1) + ' ms';
+  }
+
+  function recordState(name, state, at) {
+    var tl = timelines[name] || (timelines[name] = []);
+    var last = tl[tl.length - 1];
+    if (!last || last.state !== state) {
+      tl.push({ state: state, at: at });
+    }
+    var cutoff = Date.now() - TIMELINE_WINDOW_MS;
+    // Keep one entry before the window so the first segment starts at its edge
+    while (tl.length > 1 && tl[1].at < cutoff) tl.shift();
+  }
+
+  function applyStatuses(statuses) {
+    var now = Date.now();
+    Object.keys(statuses).forEach(function (name) {
+      breakers[name] = statuses[name];
+      recordState(name, statuses[name].state, now);
+    });
+    renderTable();
+    renderTimeline();
+  }
+
+  function renderTable() {
+    var tbody = document.querySelector('#breakers tbody');
+    tbody.innerHTML = '';
+    var names = Object.keys(breakers).sort();
+    if (names.length === 0) {
+      var empty = el('tr', 'empty');
+      var td = el('td', '', 'No breakers registered');
+      td.colSpan = 8;
+      empty.appendChild(td);
+      tbody.appendChild(empty);
+      return;
+    }
+
+    names.forEach(function (name) {
+      var b = breakers[name];
+      var m = b.metrics || {};
+      var tr = el('tr');
+      tr.appendChild(el('td', '', name));
+      var stateTd = el('td');
+      stateTd.appendChild(el('span', 'badge ' + stateClass(b.state), b.state));
+      tr.appendChild(stateTd);
+      tr.appendChild(el('td', '', String(m.total_requests || 0)));
+      tr.appendChild(el('td', '', ((m.failure_rate || 0) * 100).toFixed(1) + '%'));
+      tr.appendChild(el('td', '', (b.health || 0).toFixed(2)));
+      tr.appendChild(el('td', '', fmtLatency(m.average_latency)));
+      tr.appendChild(el('td', '', fmtTime(b.last_state_change)));
+
+      var actions = el('td');
+      var reset = el('button', '', 'Reset');
+      reset.onclick = function () { post('/api/breakers/' + encodeURIComponent(name) + '/reset'); };
+      var open = el('button', '', 'Force open');
+      open.onclick = function () { post('/api/breakers/' + encodeURIComponent(name) + '/state', { state: 'open' }); };
+      actions.appendChild(reset);
+      actions.appendChild(document.createTextNode(' '));
+      actions.appendChild(open);
+      tr.appendChild(actions);
+      tbody.appendChild(tr);
+    });
+  }
+
+  function renderTimeline() {
+    var root = document.getElementById('timeline');
+    root.innerHTML = '';
+    var now = Date.now();
+    var start = now - TIMELINE_WINDOW_MS;
+
+    Object.keys(timelines).sort().forEach(function (name) {
+      var row = el('div', 'row');
+      row.appendChild(el('div', 'label', name));
+      var track = el('div', 'track');
+      var tl = timelines[name];
+      tl.forEach(function (entry, i) {
+        var from = Math.max(entry.at, start);
+        var to = i + 1 < tl.length ? tl[i + 1].at : now;
+        if (to <= start) return;
+        var seg = el('div', 'seg ' + stateClass(entry.state));
+        seg.style.left = ((from - start) / TIMELINE_WINDOW_MS * 100) + '%';
+        seg.style.width = Math.max((to - from) / TIMELINE_WINDOW_MS * 100, 0.2) + '%';
+        seg.title = entry.state + ' since ' + new Date(entry.at).toLocaleTimeString();
+        track.appendChild(seg);
+      });
+      row.appendChild(track);
+      root.appendChild(row);
+    });
+  }
+
+  function fmtPct(v, digits) {
+    return (v * 100).toFixed(digits) + '%';
+  }
+
+  function renderSLOs(slos) {
+    var tbody = document.querySelector('#slos tbody');
+    tbody.innerHTML = '';
+    if (!slos || slos.length === 0) {
+      var empty = el('tr', 'empty');
+      var td = el('td', '', 'No SLOs configured');
+      td.colSpan = 6;
+      empty.appendChild(td);
+      tbody.appendChild(empty);
+      return;
+    }
+
+    slos.forEach(function (s) {
+      var tr = el('tr');
+      tr.appendChild(el('td', '', s.breaker));
+      tr.appendChild(el('td', '', fmtPct(s.objective, 2) + ' / ' + s.window));
+      tr.appendChild(el('td', '', fmtPct(s.compliance, 3) + ' (' + s.covered + ')'));
+      tr.appendChild(el('td', s.budget_remaining < 0.25 ? 'budget-low' : '', fmtPct(s.budget_remaining, 1)));
+      var burns = Object.keys(s.burn_rates || {}).map(function (w) {
+        return w + ' ' + s.burn_rates[w].toFixed(1) + 'x';
+      });
+      tr.appendChild(el('td', '', burns.join(', ') || '-'));
+      var firing = el('td');
+      (s.firing || []).forEach(function (name) {
+        firing.appendChild(el('span', 'badge state-open', name));
+        firing.appendChild(document.createTextNode(' '));
+      });
+      tr.appendChild(firing);
+      tbody.appendChild(tr);
+    });
+  }
+
+  function loadSLOs() {
+    fetch('/api/slos').then(function (r) { return r.json(); }).then(renderSLOs);
+  }
+
+  function addAlert(alert) {
+    alerts.unshift(alert);
+    if (alerts.length > MAX_ALERTS) alerts.length = MAX_ALERTS;
+    renderAlerts();
+  }
+
+  function renderAlerts() {
+    var list = document.getElementById('alerts');
+    list.innerHTML = '';
+    if (alerts.length === 0) {
+      list.appendChild(el('li', 'empty', 'No alerts'));
+      return;
+    }
+    alerts.forEach(function (a) {
+      var li = el('li');
+      li.appendChild(el('span', 'time', fmtTime(a.timestamp)));
+      li.appendChild(el('span', 'level-' + a.level, a.level.toUpperCase()));
+      li.appendChild(document.createTextNode(' ' + a.breaker + ': ' + a.message));
+      list.appendChild(li);
+    });
+  }
+
+  function post(url, body) {
+    fetch(url, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: body ? JSON.stringify(body) : undefined
+    }).then(refresh);
+  }
+
+  function refresh() {
+    fetch('/api/breakers').then(function (r) { return r.json(); }).then(applyStatuses);
+  }
+
+  function loadAlerts() {
+    fetch('/api/alerts').then(function (r) { return r.json(); }).then(function (list) {
+      // API returns oldest first
+      (list || []).forEach(addAlert);
+    });
+  }
+
+  function connect() {
+    var proto = location.protocol === 'https:' ? 'wss:' : 'ws:';
+    var ws = new WebSocket(proto + '//' + location.host + '/ws');
+    var conn = document.getElementById('conn');
+
+    ws.onopen = function () {
+      conn.textContent = 'live';
+      conn.className = 'conn conn-up';
+    };
+    ws.onclose = function () {
+      conn.textContent = 'disconnected';
+      conn.className = 'conn conn-down';
+      setTimeout(connect, 2000);
+    };
+    ws.onmessage = function (ev) {
+      var msg;
+      try { msg = JSON.parse(ev.data); } catch (e) { return; }
+      if (msg.type === 'status_update') {
+        applyStatuses(msg.data || {});
+      } else if (msg.type === 'alert') {
+        addAlert(msg.data);
+      }
+    };
+  }
+
+  refresh();
+  loadAlerts();
+  loadSLOs();
+  connect();
+  setInterval(renderTimeline, 5000);
+  setInterval(loadSLOs, SLO_REFRESH_MS);
+})();
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
@@ -14,6 +15,10 @@ import (
 )
 
 func main() {
+	profile := flag.String("profile", envOr("RUNTIME_PROFILE", "auto"), "Runtime profile for the interactive demo: auto, default, enterprise or turbo")
+	tier := flag.String("tier", envOr("TIER", "free"), "Service tier the auto profile is chosen for")
+	flag.Parse()
+
 	// Initialize logger
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -34,7 +39,14 @@ func main() {
 	runPerformanceBenchmarks(logger)
 
 	// Interactive optimization testing
-	runInteractiveDemo(logger)
+	runInteractiveDemo(*profile, *tier, logger)
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
 }
 
 func displaySystemInfo(logger *zap.Logger) {
@@ -49,6 +61,10 @@ func displaySystemInfo(logger *zap.Logger) {
 	fmt.Printf("Current GOMAXPROCS: %d\n", sysInfo["gomaxprocs"])
 	fmt.Printf("Optimal GOMAXPROCS: %d\n", sysInfo["optimal_threads"])
 	fmt.Printf("Real-Time Capable: %t\n", sysInfo["rt_capable"])
+	env := runtimeopt.DetectEnvironment()
+	fmt.Printf("Effective CPUs: %.1f (quota: %t)\n", env.CPUs, env.CPUQuota)
+	fmt.Printf("Memory Limit (MB): %d\n", env.MemoryLimit>>20)
+	fmt.Printf("Container: %t, NUMA Nodes: %d\n", env.Container, env.NUMANodes)
 	fmt.Printf("Current Heap (MB): %d\n", sysInfo["heap_alloc_mb"])
 	fmt.Printf("Pointer Size: %d bytes\n", sysInfo["pointer_size"])
 	fmt.Printf("Active Goroutines: %d\n", sysInfo["num_goroutine"])
@@ -192,14 +208,19 @@ func displayBenchmarkResults(stats BenchmarkStats) {
 		float64(stats.Allocations)/stats.Duration.Seconds())
 }
 
-func runInteractiveDemo(logger *zap.Logger) {
+func runInteractiveDemo(profile, tier string, logger *zap.Logger) {
 	fmt.Println("\n🎮 Interactive Optimization Demo:")
 	fmt.Println("----------------------------------")
-	fmt.Println("Running enterprise optimization with live monitoring...")
+	// Apply the profile the host supports for the tier, unless one is named
+	config, err := runtimeopt.AutoConfig(profile, tier, logger)
+	if err != nil {
+		logger.Error("Invalid runtime profile", zap.Error(err))
+		return
+	}
+	fmt.Println("Running the selected runtime profile with live monitoring...")
 	fmt.Println("Press Ctrl+C to stop")
 
-	// Apply enterprise optimizations
-	optimizer := runtimeopt.NewSystemOptimizer(runtimeopt.EnterpriseConfig(), logger)
+	optimizer := runtimeopt.NewSystemOptimizer(config, logger)
 	if err := optimizer.Apply(); err != nil {
 		logger.Error("Failed to apply optimizations", zap.Error(err))
 		return
@@ -0,0 +1,202 @@
+// Command sprintctl is an operator CLI for a running Sprint API server.
+//
+// Usage:
+//
+//	sprintctl keys export -passphrase <p> [-out keystore.archive.json]
+//	sprintctl keys import -passphrase <p> -in keystore.archive.json [-overwrite]
+//	sprintctl stream soak -api-key <k> [-chain bitcoin] [-conns 100] [-duration 5m] [-out report.json]
+//	sprintctl verify -base-url <url> [-api-key <k>] [-format json|junit] [-out report.xml]
+//
+// The admin key is read from -admin-key or the ADMIN_API_KEY environment variable.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// client calls admin endpoints on the Sprint API
+type client struct {
+	baseURL  string
+	adminKey string
+	http     *http.Client
+}
+
+func (c *client) post(path string, body interface{}) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(c.baseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+func (c *client) get(path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(c.baseURL, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// do sends req with the admin key and returns the body of a 200 response
+func (c *client) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("X-Admin-Key", c.adminKey)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("%s: %s", resp.Status, apiErr.Error)
+		}
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return data, nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: sprintctl <command> <subcommand> [flags]
+
+Commands:
+  keys export   Export all keystore entries to an encrypted archive
+  keys import   Import an encrypted keystore archive
+  stream soak   Hold many block streams open and report delivery latency,
+                reconnects and server memory per stream
+  verify        Run the API conformance suite against a deployment and
+                report per-check results as JSON or JUnit XML
+`)
+}
+
+// commonFlags registers connection flags shared by every subcommand
+func commonFlags(fs *flag.FlagSet) *client {
+	c := &client{http: &http.Client{Timeout: 60 * time.Second}}
+	fs.StringVar(&c.baseURL, "url", envOr("SPRINT_API_URL", "http://localhost:8080"), "Sprint API base URL")
+	fs.StringVar(&c.adminKey, "admin-key", os.Getenv("ADMIN_API_KEY"), "Admin API key")
+	return c
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) >= 2 && os.Args[1] == "verify" {
+		verifyCmd(os.Args[2:])
+		return
+	}
+	if len(os.Args) < 3 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] + " " + os.Args[2] {
+	case "keys export":
+		keysExport(os.Args[3:])
+	case "keys import":
+		keysImport(os.Args[3:])
+	case "stream soak":
+		streamSoak(os.Args[3:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func keysExport(args []string) {
+	fs := flag.NewFlagSet("keys export", flag.ExitOnError)
+	c := commonFlags(fs)
+	var (
+		passphrase = fs.String("passphrase", os.Getenv("SPRINT_ARCHIVE_PASSPHRASE"), "Archive passphrase")
+		out        = fs.String("out", "", "Output file (default stdout)")
+	)
+	fs.Parse(args)
+
+	if c.adminKey == "" || *passphrase == "" {
+		log.Fatalf("-admin-key and -passphrase are required")
+	}
+
+	archive, err := c.post("/api/v1/admin/keystore/export", map[string]string{"passphrase": *passphrase})
+	if err != nil {
+		log.Fatalf("export failed: %v", err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(archive)
+		return
+	}
+	if err := os.WriteFile(*out, archive, 0o600); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	log.Printf("keystore archive written to %s", *out)
+}
+
+func keysImport(args []string) {
+	fs := flag.NewFlagSet("keys import", flag.ExitOnError)
+	c := commonFlags(fs)
+	var (
+		passphrase = fs.String("passphrase", os.Getenv("SPRINT_ARCHIVE_PASSPHRASE"), "Archive passphrase")
+		in         = fs.String("in", "", "Archive file to import")
+		overwrite  = fs.Bool("overwrite", false, "Overwrite existing keystore entries")
+	)
+	fs.Parse(args)
+
+	if c.adminKey == "" || *passphrase == "" || *in == "" {
+		log.Fatalf("-admin-key, -passphrase and -in are required")
+	}
+
+	archive, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *in, err)
+	}
+	if !json.Valid(archive) {
+		log.Fatalf("%s is not a keystore archive", *in)
+	}
+
+	data, err := c.post("/api/v1/admin/keystore/import-archive", map[string]interface{}{
+		"passphrase": *passphrase,
+		"archive":    json.RawMessage(archive),
+		"overwrite":  *overwrite,
+	})
+	if err != nil {
+		log.Fatalf("import failed: %v", err)
+	}
+
+	var result struct {
+		Imported []string `json:"imported"`
+		Skipped  []string `json:"skipped"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		log.Fatalf("unexpected response: %v", err)
+	}
+	log.Printf("imported %d entries, skipped %d existing", len(result.Imported), len(result.Skipped))
+	for _, id := range result.Skipped {
+		log.Printf("  skipped %s (use -overwrite to replace)", id)
+	}
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/gorilla/websocket"
+)
+
+// Stream soak mode (sprintctl stream soak)
+//
+// Opens -conns block streams on one chain, ramped up over -ramp, and holds
+// them for -duration. Each stream reconnects the way sprintclient does,
+// resuming from its last height, and -churn drops a tenth of them at every
+// interval to exercise that path. The report covers delivery latency from
+// the server's detected_at to receipt (so it includes any clock offset
+// between the hosts), the spread of one block's arrival across streams,
+// reconnect times, blocks some stream never received, and, with an admin
+// key, the server's heap per stream from /api/v1/admin/streams.
+
+const (
+	soakPingInterval = 30 * time.Second // The server drops streams silent for 60s
+	soakSeenHashes   = 256
+	soakGrace        = 2 * time.Second // Blocks first seen this close to the end are not judged incomplete
+)
+
+// soakOptions configures one soak run
+type soakOptions struct {
+	URL         string
+	APIKey      string
+	Chain       string
+	Conns       int
+	Duration    time.Duration // Whole run, including the ramp
+	Ramp        time.Duration
+	Churn       time.Duration // Interval between forced disconnects; 0 disables
+	Compression string        // Passed as ?compression= when set
+}
+
+// soakReport is the JSON report of a soak run
+type soakReport struct {
+	Target      string        `json:"target"`
+	Chain       string        `json:"chain"`
+	StartedAt   time.Time     `json:"started_at"`
+	Duration    time.Duration `json:"duration"`
+	Compression string        `json:"compression,omitempty"`
+
+	Connections struct {
+		Requested    int   `json:"requested"`
+		Established  int   `json:"established"`
+		Rejected     int   `json:"rejected"` // Gave up after a permanent handshake error
+		DialFailures int64 `json:"dial_failures"`
+		Peak         int64 `json:"peak_concurrent"`
+	} `json:"connections"`
+
+	Events struct {
+		Blocks           int   `json:"blocks"` // Distinct blocks seen by any stream
+		Deliveries       int64 `json:"deliveries"`
+		Replayed         int64 `json:"replayed"`   // Delivered as catch-up after a reconnect
+		Duplicates       int64 `json:"duplicates"` // Dropped as already received by that stream
+		IncompleteBlocks int   `json:"incomplete_blocks"`
+	} `json:"events"`
+
+	LatencyMs      soakPercentiles `json:"latency_ms"`       // detected_at to receipt, live blocks only
+	FanoutSpreadMs soakPercentiles `json:"fanout_spread_ms"` // First to last stream receiving a live block
+
+	Reconnects struct {
+		Disconnects int64           `json:"disconnects"`
+		Forced      int64           `json:"forced"` // Dropped by -churn
+		TimeMs      soakPercentiles `json:"time_ms"`
+	} `json:"reconnects"`
+
+	Server *soakServerMemory `json:"server,omitempty"`
+	Errors map[string]int64  `json:"errors,omitempty"`
+}
+
+// soakPercentiles summarises a set of durations in milliseconds
+type soakPercentiles struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50"`
+	P95     float64 `json:"p95"`
+	P99     float64 `json:"p99"`
+	Max     float64 `json:"max"`
+}
+
+// soakServerMemory compares the server before the streams opened with the
+// server once they all had
+type soakServerMemory struct {
+	Baseline        soakServerSample `json:"baseline"`
+	Loaded          soakServerSample `json:"loaded"`
+	Final           soakServerSample `json:"final"`
+	BytesPerStream  float64          `json:"heap_bytes_per_stream"`
+	GoroutinesAdded int              `json:"goroutines_per_stream"`
+}
+
+// soakServerSample is the part of /api/v1/admin/streams the soak reads
+type soakServerSample struct {
+	Active int `json:"active"`
+	Memory struct {
+		HeapInuse  uint64 `json:"heap_inuse_bytes"`
+		Sys        uint64 `json:"sys_bytes"`
+		Goroutines int    `json:"goroutines"`
+	} `json:"memory"`
+}
+
+func streamSoak(args []string) {
+	fs := flag.NewFlagSet("stream soak", flag.ExitOnError)
+	c := commonFlags(fs)
+	var opts soakOptions
+	fs.StringVar(&opts.APIKey, "api-key", os.Getenv("SPRINT_API_KEY"), "Customer API key the streams authenticate with")
+	fs.StringVar(&opts.Chain, "chain", "bitcoin", "Chain to stream")
+	fs.IntVar(&opts.Conns, "conns", 100, "Concurrent streams")
+	fs.DurationVar(&opts.Duration, "duration", 5*time.Minute, "Length of the run, including the ramp")
+	fs.DurationVar(&opts.Ramp, "ramp", 10*time.Second, "Time over which streams are opened")
+	fs.DurationVar(&opts.Churn, "churn", 0, "Drop a tenth of the streams at this interval to test reconnects (0 disables)")
+	fs.StringVar(&opts.Compression, "compression", "", "Request compression on or off instead of the tier default")
+	out := fs.String("out", "", "Report file (default stdout)")
+	fs.Parse(args)
+	opts.URL = c.baseURL
+
+	if opts.APIKey == "" {
+		log.Fatalf("-api-key is required")
+	}
+	if opts.Conns < 1 || opts.Ramp < 0 || opts.Duration <= opts.Ramp {
+		log.Fatalf("-conns must be positive and -duration longer than -ramp")
+	}
+	if c.adminKey == "" {
+		log.Printf("no admin key: server memory per stream will not be reported")
+		c = nil
+	}
+
+	report, err := runSoak(context.Background(), opts, c)
+	if err != nil {
+		log.Fatalf("soak failed: %v", err)
+	}
+	data, _ := json.MarshalIndent(report, "", "  ")
+	if *out == "" {
+		os.Stdout.Write(append(data, '\n'))
+	} else if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	} else {
+		log.Printf("report written to %s", *out)
+	}
+}
+
+// runSoak runs the soak and builds the report. admin, if set, is used to
+// sample server memory.
+func runSoak(ctx context.Context, opts soakOptions, admin *client) (*soakReport, error) {
+	streamURL, err := soakStreamURL(opts)
+	if err != nil {
+		return nil, err
+	}
+	report := &soakReport{Target: opts.URL, Chain: opts.Chain, StartedAt: time.Now().UTC(), Compression: opts.Compression}
+	report.Connections.Requested = opts.Conns
+
+	var mem *soakServerMemory
+	if admin != nil {
+		baseline, err := sampleServer(admin)
+		if err != nil {
+			return nil, fmt.Errorf("sample server memory: %w", err)
+		}
+		mem = &soakServerMemory{Baseline: baseline}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	rec := newSoakRecorder()
+	dialer := &websocket.Dialer{
+		Proxy:             http.ProxyFromEnvironment,
+		HandshakeTimeout:  10 * time.Second,
+		EnableCompression: opts.Compression != "",
+	}
+	header := http.Header{}
+	header.Set("X-API-Key", opts.APIKey)
+	header.Set("User-Agent", "sprintctl-soak")
+
+	streams := make([]*soakStream, opts.Conns)
+	var wg sync.WaitGroup
+	log.Printf("opening %d %s streams over %s", opts.Conns, opts.Chain, opts.Ramp)
+	for i := range streams {
+		st := &soakStream{id: i, url: streamURL, header: header, dialer: dialer, rec: rec, seen: make(map[string]bool)}
+		streams[i] = st
+		wg.Add(1)
+		go func(delay time.Duration) {
+			defer wg.Done()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			st.run(ctx)
+		}(opts.Ramp * time.Duration(i) / time.Duration(opts.Conns))
+	}
+
+	if opts.Churn > 0 {
+		go churnStreams(ctx, streams, opts.Churn, rec)
+	}
+
+	// Give the last streams a moment to connect before sampling the loaded server
+	select {
+	case <-ctx.Done():
+	case <-time.After(opts.Ramp + 2*time.Second):
+	}
+	if mem != nil && ctx.Err() == nil {
+		if s, err := sampleServer(admin); err == nil {
+			mem.Loaded = s
+		} else {
+			log.Printf("failed to sample loaded server: %v", err)
+		}
+	}
+	log.Printf("%d streams connected, soaking until %s", rec.active.Load(), report.StartedAt.Add(opts.Duration).Local().Format(time.TimeOnly))
+
+	wg.Wait()
+	report.Duration = time.Since(report.StartedAt)
+
+	if mem != nil {
+		if s, err := sampleServer(admin); err == nil {
+			mem.Final = s
+		}
+		if added := mem.Loaded.Active - mem.Baseline.Active; added > 0 {
+			mem.BytesPerStream = (float64(mem.Loaded.Memory.HeapInuse) - float64(mem.Baseline.Memory.HeapInuse)) / float64(added)
+			mem.GoroutinesAdded = (mem.Loaded.Memory.Goroutines - mem.Baseline.Memory.Goroutines) / added
+		}
+		report.Server = mem
+	}
+	rec.fill(report, streams, time.Now().Add(-soakGrace))
+	log.Printf("%d blocks, %d deliveries, latency p50 %.1fms p99 %.1fms, %d disconnects, %d incomplete blocks",
+		report.Events.Blocks, report.Events.Deliveries, report.LatencyMs.P50, report.LatencyMs.P99,
+		report.Reconnects.Disconnects, report.Events.IncompleteBlocks)
+	return report, nil
+}
+
+// soakStreamURL is the stream endpoint for opts, with http(s) turned into ws(s)
+func soakStreamURL(opts soakOptions) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimRight(opts.URL, "/") + "/v1/" + url.PathEscape(opts.Chain) + "/stream")
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("-url must be http or https, got %q", opts.URL)
+	}
+	if opts.Compression != "" {
+		q := u.Query()
+		q.Set("compression", opts.Compression)
+		u.RawQuery = q.Encode()
+	}
+	return u, nil
+}
+
+func sampleServer(c *client) (soakServerSample, error) {
+	var s soakServerSample
+	data, err := c.get("/api/v1/admin/streams")
+	if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(data, &s)
+}
+
+// churnStreams closes a tenth of the connected streams every interval
+func churnStreams(ctx context.Context, streams []*soakStream, interval time.Duration, rec *soakRecorder) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	n := len(streams) / 10
+	if n < 1 {
+		n = 1
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, i := range rand.Perm(len(streams))[:n] {
+			if streams[i].drop() {
+				rec.forced.Add(1)
+			}
+		}
+	}
+}
+
+// soakStream is one subscription, reconnecting until the run ends
+type soakStream struct {
+	id     int
+	url    *url.URL
+	header http.Header
+	dialer *websocket.Dialer
+	rec    *soakRecorder
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+
+	firstConnected time.Time
+	rejected       bool
+	lastHeight     int64
+	received       bool
+	inReplay       bool
+	seen           map[string]bool
+	seenOrder      []string
+}
+
+func (st *soakStream) run(ctx context.Context) {
+	backoff := time.Second
+	var droppedAt time.Time
+	for ctx.Err() == nil {
+		conn, resp, err := st.dialer.DialContext(ctx, st.dialURL(), st.header)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			st.rec.dialFailures.Add(1)
+			if resp != nil {
+				resp.Body.Close()
+				st.rec.error(fmt.Sprintf("handshake %d", resp.StatusCode))
+				if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+					st.rejected = true
+					return
+				}
+				if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && time.Duration(secs)*time.Second > backoff {
+					backoff = time.Duration(secs) * time.Second
+				}
+			} else {
+				st.rec.error("dial")
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > 30*time.Second {
+				backoff = 30 * time.Second
+			}
+			continue
+		}
+
+		backoff = time.Second
+		now := time.Now()
+		if st.firstConnected.IsZero() {
+			st.firstConnected = now
+		} else {
+			st.rec.reconnected(now.Sub(droppedAt))
+		}
+		st.rec.connected()
+		st.read(ctx, conn)
+		st.rec.active.Add(-1)
+		if ctx.Err() != nil {
+			return
+		}
+		droppedAt = time.Now()
+		st.rec.disconnects.Add(1)
+	}
+}
+
+// dialURL resumes at the last height received, like sprintclient
+func (st *soakStream) dialURL() string {
+	if !st.received {
+		return st.url.String()
+	}
+	u := *st.url
+	q := u.Query()
+	q.Set("from_height", strconv.FormatInt(st.lastHeight, 10))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// drop closes the current connection; false when not connected
+func (st *soakStream) drop() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.conn == nil {
+		return false
+	}
+	st.conn.Close()
+	return true
+}
+
+func (st *soakStream) read(ctx context.Context, conn *websocket.Conn) {
+	st.mu.Lock()
+	st.conn = conn
+	st.mu.Unlock()
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		st.mu.Lock()
+		st.conn = nil
+		st.mu.Unlock()
+		conn.Close()
+	}()
+	go func() {
+		t := time.NewTicker(soakPingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-t.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			}
+		}
+	}()
+
+	st.inReplay = false
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		at := time.Now()
+		var msg schemas.BlockV1
+		if err := json.Unmarshal(data, &msg); err != nil {
+			st.rec.error("decode")
+			continue
+		}
+		switch msg.Type {
+		case "replay_start":
+			st.inReplay = true
+			continue
+		case "replay_end":
+			st.inReplay = false
+			continue
+		case schemas.TypeBlock:
+		default:
+			continue
+		}
+
+		if st.seen[msg.Hash] {
+			st.rec.duplicates.Add(1)
+			continue
+		}
+		st.remember(msg.Hash)
+		if h := int64(msg.Height); !st.received || h > st.lastHeight {
+			st.lastHeight = h
+		}
+		st.received = true
+		st.rec.deliver(st.id, msg, at, st.inReplay || msg.Backfilled)
+	}
+}
+
+func (st *soakStream) remember(hash string) {
+	st.seen[hash] = true
+	st.seenOrder = append(st.seenOrder, hash)
+	if len(st.seenOrder) > soakSeenHashes {
+		delete(st.seen, st.seenOrder[0])
+		st.seenOrder = st.seenOrder[1:]
+	}
+}
+
+// soakRecorder collects measurements from every stream
+type soakRecorder struct {
+	active       atomic.Int64
+	peak         atomic.Int64
+	dialFailures atomic.Int64
+	disconnects  atomic.Int64
+	forced       atomic.Int64
+	duplicates   atomic.Int64
+
+	mu         sync.Mutex
+	blocks     map[string]*soakBlock
+	latencies  []time.Duration
+	reconnects []time.Duration
+	deliveries int64
+	replayed   int64
+	errors     map[string]int64
+}
+
+// soakBlock tracks who received one block and when
+type soakBlock struct {
+	detected    time.Time
+	firstLive   time.Time
+	lastLive    time.Time
+	liveCount   int
+	receivedBy  map[int]bool
+	firstSeenAt time.Time
+}
+
+func newSoakRecorder() *soakRecorder {
+	return &soakRecorder{blocks: make(map[string]*soakBlock), errors: make(map[string]int64)}
+}
+
+func (r *soakRecorder) connected() {
+	n := r.active.Add(1)
+	for {
+		peak := r.peak.Load()
+		if n <= peak || r.peak.CompareAndSwap(peak, n) {
+			return
+		}
+	}
+}
+
+func (r *soakRecorder) reconnected(d time.Duration) {
+	r.mu.Lock()
+	r.reconnects = append(r.reconnects, d)
+	r.mu.Unlock()
+}
+
+func (r *soakRecorder) error(kind string) {
+	r.mu.Lock()
+	r.errors[kind]++
+	r.mu.Unlock()
+}
+
+func (r *soakRecorder) deliver(stream int, msg schemas.BlockV1, at time.Time, replayed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.blocks[msg.Hash]
+	if !ok {
+		b = &soakBlock{detected: msg.DetectedAt, receivedBy: make(map[int]bool), firstSeenAt: at}
+		r.blocks[msg.Hash] = b
+	}
+	b.receivedBy[stream] = true
+	r.deliveries++
+	if replayed {
+		r.replayed++
+		return
+	}
+	if !b.detected.IsZero() {
+		r.latencies = append(r.latencies, at.Sub(b.detected))
+	}
+	if b.liveCount == 0 || at.Before(b.firstLive) {
+		b.firstLive = at
+	}
+	if at.After(b.lastLive) {
+		b.lastLive = at
+	}
+	b.liveCount++
+}
+
+// fill writes the collected measurements into report. A block first seen
+// before cutoff is incomplete when a stream that was connected before it
+// was detected, and was not rejected, never received it.
+func (r *soakRecorder) fill(report *soakReport, streams []*soakStream, cutoff time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, st := range streams {
+		switch {
+		case st.rejected:
+			report.Connections.Rejected++
+		case !st.firstConnected.IsZero():
+			report.Connections.Established++
+		}
+	}
+	report.Connections.DialFailures = r.dialFailures.Load()
+	report.Connections.Peak = r.peak.Load()
+
+	var spreads []time.Duration
+	for _, b := range r.blocks {
+		if b.liveCount > 1 {
+			spreads = append(spreads, b.lastLive.Sub(b.firstLive))
+		}
+		if b.firstSeenAt.After(cutoff) {
+			continue
+		}
+		detected := b.detected
+		if detected.IsZero() {
+			detected = b.firstSeenAt
+		}
+		for _, st := range streams {
+			if st.rejected || st.firstConnected.IsZero() || st.firstConnected.After(detected) {
+				continue
+			}
+			if !b.receivedBy[st.id] {
+				report.Events.IncompleteBlocks++
+				break
+			}
+		}
+	}
+
+	report.Events.Blocks = len(r.blocks)
+	report.Events.Deliveries = r.deliveries
+	report.Events.Replayed = r.replayed
+	report.Events.Duplicates = r.duplicates.Load()
+	report.LatencyMs = percentilesMs(r.latencies)
+	report.FanoutSpreadMs = percentilesMs(spreads)
+	report.Reconnects.Disconnects = r.disconnects.Load()
+	report.Reconnects.Forced = r.forced.Load()
+	report.Reconnects.TimeMs = percentilesMs(r.reconnects)
+	if len(r.errors) > 0 {
+		report.Errors = r.errors
+	}
+}
+
+func percentilesMs(d []time.Duration) soakPercentiles {
+	if len(d) == 0 {
+		return soakPercentiles{}
+	}
+	sorted := append([]time.Duration(nil), d...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(q float64) float64 {
+		return float64(sorted[int(q*float64(len(sorted)-1))]) / float64(time.Millisecond)
+	}
+	return soakPercentiles{Samples: len(sorted), P50: at(0.50), P95: at(0.95), P99: at(0.99), Max: at(1)}
+}
@@ -0,0 +1,638 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/gorilla/websocket"
+)
+
+// Conformance mode (sprintctl verify)
+//
+// Runs black-box checks of the API contract against a running instance,
+// so an upgrade or a self-hosted config can be validated before traffic
+// moves to it: authentication, rate-limit headers, the universal endpoint,
+// stream resume and the error envelope. A check needing a key that was
+// not given is skipped, as is the rate-limit probe unless -rate-probe is
+// set, since it spends the key's quota. The report is JSON or JUnit XML,
+// and the command exits 1 if any check failed.
+
+// verifyOptions configures one conformance run
+type verifyOptions struct {
+	URL       string
+	APIKey    string
+	AdminKey  string
+	Chain     string
+	Timeout   time.Duration // Per check
+	RateProbe int           // Requests sent to provoke a 429; 0 skips the probe
+}
+
+// Check outcomes
+const (
+	verifyPass = "pass"
+	verifyFail = "fail"
+	verifySkip = "skip"
+)
+
+// verifyResult is the outcome of one check
+type verifyResult struct {
+	Suite      string  `json:"suite"`
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Message    string  `json:"message,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// verifyReport is the JSON report of a conformance run
+type verifyReport struct {
+	Target    string         `json:"target"`
+	Chain     string         `json:"chain"`
+	StartedAt time.Time      `json:"started_at"`
+	Duration  time.Duration  `json:"duration"`
+	Passed    int            `json:"passed"`
+	Failed    int            `json:"failed"`
+	Skipped   int            `json:"skipped"`
+	Results   []verifyResult `json:"results"`
+}
+
+// skipError marks a check that could not run with the given options
+type skipError string
+
+func (e skipError) Error() string { return string(e) }
+
+// verifyCheck is one conformance check
+type verifyCheck struct {
+	suite string
+	name  string
+	run   func(ctx context.Context, v *verifier) error
+}
+
+// verifier runs checks against one instance
+type verifier struct {
+	opts verifyOptions
+	http *http.Client
+}
+
+func verifyCmd(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	c := commonFlags(fs)
+	fs.StringVar(&c.baseURL, "base-url", c.baseURL, "Sprint API base URL (same as -url)")
+	var opts verifyOptions
+	fs.StringVar(&opts.APIKey, "api-key", os.Getenv("SPRINT_API_KEY"), "Customer API key for authenticated checks")
+	fs.StringVar(&opts.Chain, "chain", "bitcoin", "Chain used by the universal endpoint and stream checks")
+	fs.DurationVar(&opts.Timeout, "timeout", 15*time.Second, "Time limit per check")
+	fs.IntVar(&opts.RateProbe, "rate-probe", 0, "Requests sent to provoke a 429 when checking rate-limit headers (0 skips)")
+	format := fs.String("format", "json", "Report format: json or junit")
+	out := fs.String("out", "", "Report file (default stdout)")
+	fs.Parse(args)
+	opts.URL = strings.TrimRight(c.baseURL, "/")
+	opts.AdminKey = c.adminKey
+
+	if *format != "json" && *format != "junit" {
+		log.Fatalf("-format must be json or junit")
+	}
+
+	report := runVerify(context.Background(), opts)
+	var data []byte
+	if *format == "junit" {
+		data = append([]byte(xml.Header), verifyJUnit(report)...)
+	} else {
+		data, _ = json.MarshalIndent(report, "", "  ")
+	}
+	if *out == "" {
+		os.Stdout.Write(append(data, '\n'))
+	} else if err := os.WriteFile(*out, data, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	} else {
+		log.Printf("report written to %s", *out)
+	}
+
+	log.Printf("%d passed, %d failed, %d skipped", report.Passed, report.Failed, report.Skipped)
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runVerify runs every check in order and builds the report
+func runVerify(ctx context.Context, opts verifyOptions) *verifyReport {
+	v := &verifier{opts: opts, http: &http.Client{Timeout: opts.Timeout}}
+	report := &verifyReport{Target: opts.URL, Chain: opts.Chain, StartedAt: time.Now().UTC()}
+
+	for _, check := range verifyChecks {
+		checkCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		start := time.Now()
+		err := check.run(checkCtx, v)
+		cancel()
+
+		res := verifyResult{
+			Suite:      check.suite,
+			Name:       check.name,
+			Status:     verifyPass,
+			DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+		}
+		var skip skipError
+		switch {
+		case errors.As(err, &skip):
+			res.Status, res.Message = verifySkip, err.Error()
+			report.Skipped++
+		case err != nil:
+			res.Status, res.Message = verifyFail, err.Error()
+			report.Failed++
+			log.Printf("FAIL %s/%s: %v", check.suite, check.name, err)
+		default:
+			report.Passed++
+		}
+		report.Results = append(report.Results, res)
+	}
+	report.Duration = time.Since(report.StartedAt)
+	return report
+}
+
+// verifyChecks is the conformance suite, run in order
+var verifyChecks = []verifyCheck{
+	{"auth", "missing key rejected", checkMissingKey},
+	{"auth", "invalid key rejected", checkInvalidKey},
+	{"auth", "valid key accepted", checkValidKey},
+	{"auth", "admin route requires admin key", checkAdminRequired},
+	{"auth", "admin key accepted", checkAdminKey},
+	{"rate_limit", "429 carries Retry-After", checkRetryAfter},
+	{"universal", "chain and method echoed", checkUniversalEcho},
+	{"universal", "missing chain rejected", checkUniversalNoChain},
+	{"universal", "caller budget reported", checkBudgetHeader},
+	{"universal", "request ID echoed", checkRequestID},
+	{"stream", "resume replays from height", checkStreamResume},
+	{"stream", "invalid resume rejected", checkStreamBadResume},
+	{"errors", "JSON error envelope", checkErrorEnvelope},
+	{"errors", "invalid budget rejected", checkBadBudget},
+	{"errors", "request ID on errors", checkErrorRequestID},
+}
+
+// ===== REQUESTS =====
+
+// get requests path with header and returns the response with its body read
+func (v *verifier) get(ctx context.Context, path string, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.opts.URL+path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vals := range header {
+		req.Header[k] = vals
+	}
+	req.Header.Set("User-Agent", "sprintctl-verify")
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp, body, err
+}
+
+// keyHeader returns a header carrying the customer API key, skipping the
+// check when none was given
+func (v *verifier) keyHeader() (http.Header, error) {
+	if v.opts.APIKey == "" {
+		return nil, skipError("no -api-key")
+	}
+	return http.Header{"X-Api-Key": {v.opts.APIKey}}, nil
+}
+
+func (v *verifier) universalPath(method string) string {
+	return "/api/v1/universal/" + url.PathEscape(v.opts.Chain) + "/" + method
+}
+
+func expectStatus(resp *http.Response, want int) error {
+	if resp.StatusCode != want {
+		return fmt.Errorf("got %s, want %d", resp.Status, want)
+	}
+	return nil
+}
+
+// expectEnvelope checks a JSON error response: {"error": "...", "code": "..."}
+// with code optional
+func expectEnvelope(resp *http.Response, body []byte) error {
+	if ct, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); ct != "application/json" {
+		return fmt.Errorf("error response has Content-Type %q, want application/json", resp.Header.Get("Content-Type"))
+	}
+	var env map[string]interface{}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("error body is not a JSON object: %v", err)
+	}
+	if msg, _ := env["error"].(string); msg == "" {
+		return fmt.Errorf(`error body has no "error" message: %s`, strings.TrimSpace(string(body)))
+	}
+	if code, ok := env["code"]; ok {
+		if _, isString := code.(string); !isString {
+			return fmt.Errorf(`"code" is %T, want a string`, code)
+		}
+	}
+	return nil
+}
+
+// ===== AUTH =====
+
+func checkMissingKey(ctx context.Context, v *verifier) error {
+	resp, _, err := v.get(ctx, v.universalPath("ping"), nil)
+	if err != nil {
+		return err
+	}
+	return expectStatus(resp, http.StatusUnauthorized)
+}
+
+func checkInvalidKey(ctx context.Context, v *verifier) error {
+	resp, _, err := v.get(ctx, v.universalPath("ping"), http.Header{"X-Api-Key": {"sprintctl-verify-invalid-key"}})
+	if err != nil {
+		return err
+	}
+	return expectStatus(resp, http.StatusUnauthorized)
+}
+
+func checkValidKey(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	resp, _, err := v.get(ctx, v.universalPath("ping"), header)
+	if err != nil {
+		return err
+	}
+	return expectStatus(resp, http.StatusOK)
+}
+
+func checkAdminRequired(ctx context.Context, v *verifier) error {
+	header := http.Header{}
+	if v.opts.APIKey != "" {
+		header.Set("X-API-Key", v.opts.APIKey) // A customer key is not enough
+	}
+	resp, body, err := v.get(ctx, "/api/v1/admin/streams", header)
+	if err != nil {
+		return err
+	}
+	if err := expectStatus(resp, http.StatusUnauthorized); err != nil {
+		return err
+	}
+	return expectEnvelope(resp, body)
+}
+
+func checkAdminKey(ctx context.Context, v *verifier) error {
+	if v.opts.AdminKey == "" {
+		return skipError("no -admin-key")
+	}
+	resp, body, err := v.get(ctx, "/api/v1/admin/streams", http.Header{"X-Admin-Key": {v.opts.AdminKey}})
+	if err != nil {
+		return err
+	}
+	if err := expectStatus(resp, http.StatusOK); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// ===== RATE LIMITS =====
+
+func checkRetryAfter(ctx context.Context, v *verifier) error {
+	if v.opts.RateProbe <= 0 {
+		return skipError("-rate-probe not set")
+	}
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < v.opts.RateProbe; i++ {
+		resp, _, err := v.get(ctx, v.universalPath("ping"), header)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			continue
+		}
+		retry := resp.Header.Get("Retry-After")
+		if secs, err := strconv.Atoi(retry); err != nil || secs <= 0 {
+			return fmt.Errorf("429 after %d requests with Retry-After %q, want positive seconds", i+1, retry)
+		}
+		return nil
+	}
+	return skipError(fmt.Sprintf("no 429 within %d requests", v.opts.RateProbe))
+}
+
+// ===== UNIVERSAL ENDPOINT =====
+
+func checkUniversalEcho(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	resp, body, err := v.get(ctx, v.universalPath("ping"), header)
+	if err != nil {
+		return err
+	}
+	if err := expectStatus(resp, http.StatusOK); err != nil {
+		return err
+	}
+	var got struct {
+		Chain  string `json:"chain"`
+		Method string `json:"method"`
+		Tier   string `json:"tier"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		return fmt.Errorf("response is not a JSON object: %v", err)
+	}
+	if got.Method != "ping" || (got.Chain != "" && got.Chain != v.opts.Chain) {
+		return fmt.Errorf("response has chain %q, method %q, want %q, %q", got.Chain, got.Method, v.opts.Chain, "ping")
+	}
+	if got.Tier == "" {
+		return errors.New("response has no tier")
+	}
+	return nil
+}
+
+func checkUniversalNoChain(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	resp, body, err := v.get(ctx, "/api/v1/universal/", header)
+	if err != nil {
+		return err
+	}
+	if err := expectStatus(resp, http.StatusBadRequest); err != nil {
+		return err
+	}
+	return expectEnvelope(resp, body)
+}
+
+func checkBudgetHeader(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	const budgetMs = 10000
+	header.Set("X-Sprint-Budget-Ms", strconv.Itoa(budgetMs))
+	resp, _, err := v.get(ctx, v.universalPath("ping"), header)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusGatewayTimeout {
+		return fmt.Errorf("got %s, want 200 or 504", resp.Status)
+	}
+	remaining := resp.Header.Get("X-Sprint-Budget-Remaining-Ms")
+	if ms, err := strconv.Atoi(remaining); err != nil || ms < 0 || ms > budgetMs {
+		return fmt.Errorf("X-Sprint-Budget-Remaining-Ms is %q, want 0-%d", remaining, budgetMs)
+	}
+	return nil
+}
+
+func checkRequestID(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	id := fmt.Sprintf("sprintctl-verify-%d", time.Now().UnixNano())
+	header.Set("X-Request-ID", id)
+	resp, _, err := v.get(ctx, v.universalPath("ping"), header)
+	if err != nil {
+		return err
+	}
+	if got := resp.Header.Get("X-Request-ID"); got != id {
+		return fmt.Errorf("X-Request-ID is %q, want %q", got, id)
+	}
+	return nil
+}
+
+// ===== STREAMS =====
+
+// streamURL is the chain's stream endpoint with query, as ws(s)
+func (v *verifier) streamURL(query url.Values) (string, error) {
+	u, err := url.Parse(v.opts.URL + "/v1/" + url.PathEscape(v.opts.Chain) + "/stream")
+	if err != nil {
+		return "", err
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("base URL must be http or https")
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+func (v *verifier) dialStream(ctx context.Context, query url.Values) (*websocket.Conn, *http.Response, error) {
+	header, err := v.keyHeader()
+	if err != nil {
+		return nil, nil, err
+	}
+	streamURL, err := v.streamURL(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	dialer := &websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: v.opts.Timeout}
+	return dialer.DialContext(ctx, streamURL, header)
+}
+
+// checkStreamResume resumes a stream from height 1: the server must open a
+// replay section for that height, send only blocks at or above it, and
+// close the section with a count of what it sent
+func checkStreamResume(ctx context.Context, v *verifier) error {
+	const from = 1
+	conn, resp, err := v.dialStream(ctx, url.Values{"from_height": {strconv.Itoa(from)}})
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("handshake: %s", resp.Status)
+		}
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetReadDeadline(deadline)
+	}
+
+	var marker struct {
+		Type       string  `json:"type"`
+		Chain      string  `json:"chain"`
+		FromHeight *uint32 `json:"from_height"`
+		Count      int     `json:"count"`
+	}
+	if err := conn.ReadJSON(&marker); err != nil {
+		return fmt.Errorf("reading replay_start: %v", err)
+	}
+	if marker.Type != "replay_start" || marker.FromHeight == nil || *marker.FromHeight != from {
+		return fmt.Errorf("first frame is %q from height %v, want replay_start from %d", marker.Type, marker.FromHeight, from)
+	}
+
+	replayed := 0
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("replay not closed after %d blocks: %v", replayed, err)
+		}
+		var msg schemas.BlockV1
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return fmt.Errorf("undecodable frame during replay: %v", err)
+		}
+		switch msg.Type {
+		case "replay_end":
+			if err := json.Unmarshal(data, &marker); err != nil {
+				return err
+			}
+			if marker.Count != replayed {
+				return fmt.Errorf("replay_end counts %d blocks, %d were sent", marker.Count, replayed)
+			}
+			return nil
+		case schemas.TypeBlock:
+			if msg.Height < from {
+				return fmt.Errorf("replayed block at height %d, below from_height %d", msg.Height, from)
+			}
+			replayed++
+		}
+	}
+}
+
+func checkStreamBadResume(ctx context.Context, v *verifier) error {
+	conn, resp, err := v.dialStream(ctx, url.Values{"from_height": {"not-a-height"}})
+	var skip skipError
+	switch {
+	case errors.As(err, &skip):
+		return err
+	case err == nil:
+		conn.Close()
+		return errors.New("stream accepted from_height=not-a-height")
+	case resp == nil:
+		return err
+	}
+	return expectStatus(resp, http.StatusBadRequest)
+}
+
+// ===== ERRORS =====
+
+func checkErrorEnvelope(ctx context.Context, v *verifier) error {
+	resp, body, err := v.get(ctx, "/api/v1/admin/streams", nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 400 {
+		return fmt.Errorf("got %s without an admin key", resp.Status)
+	}
+	return expectEnvelope(resp, body)
+}
+
+func checkBadBudget(ctx context.Context, v *verifier) error {
+	header, err := v.keyHeader()
+	if err != nil {
+		return err
+	}
+	header.Set("X-Sprint-Budget-Ms", "soon")
+	resp, body, err := v.get(ctx, v.universalPath("ping"), header)
+	if err != nil {
+		return err
+	}
+	if err := expectStatus(resp, http.StatusBadRequest); err != nil {
+		return err
+	}
+	return expectEnvelope(resp, body)
+}
+
+func checkErrorRequestID(ctx context.Context, v *verifier) error {
+	resp, _, err := v.get(ctx, v.universalPath("ping"), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 400 {
+		return fmt.Errorf("got %s without an API key", resp.Status)
+	}
+	if resp.Header.Get("X-Request-ID") == "" {
+		return fmt.Errorf("%s has no X-Request-ID", resp.Status)
+	}
+	return nil
+}
+
+// ===== JUNIT =====
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Name    string           `xml:"name,attr"`
+	Tests   int              `xml:"tests,attr"`
+	Fails   int              `xml:"failures,attr"`
+	Skipped int              `xml:"skipped,attr"`
+	Time    string           `xml:"time,attr"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name    string          `xml:"name,attr"`
+	Tests   int             `xml:"tests,attr"`
+	Fails   int             `xml:"failures,attr"`
+	Skipped int             `xml:"skipped,attr"`
+	Time    string          `xml:"time,attr"`
+	Cases   []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// verifyJUnit renders report as JUnit XML, one testsuite per check suite
+func verifyJUnit(report *verifyReport) []byte {
+	seconds := func(ms float64) string { return strconv.FormatFloat(ms/1000, 'f', 3, 64) }
+	root := junitTestSuites{
+		Name:    "sprintctl verify " + report.Target,
+		Tests:   len(report.Results),
+		Fails:   report.Failed,
+		Skipped: report.Skipped,
+		Time:    seconds(float64(report.Duration) / float64(time.Millisecond)),
+	}
+
+	index := make(map[string]int)
+	suiteMs := make(map[string]float64)
+	for _, res := range report.Results {
+		i, ok := index[res.Suite]
+		if !ok {
+			i = len(root.Suites)
+			index[res.Suite] = i
+			root.Suites = append(root.Suites, junitTestSuite{Name: res.Suite})
+		}
+		s := &root.Suites[i]
+		tc := junitTestCase{Name: res.Name, ClassName: "sprintctl.verify." + res.Suite, Time: seconds(res.DurationMs)}
+		switch res.Status {
+		case verifyFail:
+			tc.Failure = &junitMessage{Message: res.Message}
+			s.Fails++
+		case verifySkip:
+			tc.Skipped = &junitMessage{Message: res.Message}
+			s.Skipped++
+		}
+		s.Tests++
+		suiteMs[res.Suite] += res.DurationMs
+		s.Cases = append(s.Cases, tc)
+	}
+	for name, i := range index {
+		root.Suites[i].Time = seconds(suiteMs[name])
+	}
+
+	data, _ := xml.MarshalIndent(root, "", "  ")
+	return data
+}
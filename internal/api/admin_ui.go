@@ -0,0 +1,187 @@
+// Package api provides the server-rendered admin status page
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
+	"go.uber.org/zap"
+)
+
+// ===== ADMIN STATUS PAGE =====
+
+// /admin/ui renders the node's health as a single HTML page, for small
+// deployments that run without Grafana. It reads the same state as
+// /readyz and /status; it adds no collection of its own beyond a short
+// log of tip SLA, canary and entropy alerts, shown alongside the chain
+// breakers' own trip history.
+
+// maxRecentAlerts bounds the alert log shown on the page
+const maxRecentAlerts = 50
+
+//go:embed admin_ui.html
+var adminUIHTML string
+
+var adminUITemplate = template.Must(template.New("admin_ui").Funcs(template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+	"ago": func(now, t time.Time) string {
+		if t.IsZero() {
+			return "never"
+		}
+		return now.Sub(t).Truncate(time.Second).String() + " ago"
+	},
+}).Parse(adminUIHTML))
+
+// adminAlert is one entry in the recent alert log
+type adminAlert struct {
+	At      time.Time
+	Kind    string // tip_sla, entropy, canary or breaker
+	Subject string // Chain, breaker or entropy source
+	Message string
+}
+
+// alertLog keeps the most recent alerts in memory. The zero value is
+// ready to use.
+type alertLog struct {
+	mu      sync.Mutex
+	entries []adminAlert // Oldest first
+}
+
+// record appends an alert, dropping the oldest beyond maxRecentAlerts
+func (l *alertLog) record(at time.Time, kind, subject, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, adminAlert{At: at, Kind: kind, Subject: subject, Message: message})
+	if over := len(l.entries) - maxRecentAlerts; over > 0 {
+		l.entries = append(l.entries[:0], l.entries[over:]...)
+	}
+}
+
+// recent returns the logged alerts, newest first
+func (l *alertLog) recent() []adminAlert {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]adminAlert, len(l.entries))
+	for i, a := range l.entries {
+		out[len(out)-1-i] = a
+	}
+	return out
+}
+
+// adminUIRelay is one relay's connection and provider health
+type adminUIRelay struct {
+	Chain     string
+	Connected bool
+	Peers     int
+	Endpoints []endpointhealth.Stats
+}
+
+// adminUICache is the cache's hit rate overall and per namespace
+type adminUICache struct {
+	HitRate    float64
+	Hits       int64
+	Misses     int64
+	Entries    int64
+	Namespaces []adminUINamespace
+}
+
+type adminUINamespace struct {
+	Prefix  string
+	Entries int64
+	HitRate float64
+}
+
+// adminUIView is the data the page is rendered from
+type adminUIView struct {
+	Now       time.Time
+	Readiness readiness
+	Breakers  []string // Chains, sorted, keying Readiness.Breakers
+	Tips      []TipStatus
+	Relays    []adminUIRelay
+	Cache     *adminUICache // nil without a cache
+	Alerts    []adminAlert
+}
+
+// adminUIView collects the page's data
+func (s *Server) adminUIView() adminUIView {
+	v := adminUIView{
+		Now:       s.clock.Now(),
+		Readiness: s.readiness(),
+		Tips:      s.TipStatus(),
+		Alerts:    s.alerts.recent(),
+	}
+	for chain := range v.Readiness.Breakers {
+		v.Breakers = append(v.Breakers, chain)
+	}
+	sort.Strings(v.Breakers)
+
+	for chain, b := range s.ChainBreakers() {
+		for _, ev := range b.GetHistory() {
+			if ev.Type != circuitbreaker.EventStateChange && ev.Type != circuitbreaker.EventTrip {
+				continue
+			}
+			msg := ev.From + " -> " + ev.To
+			if ev.Reason != "" {
+				msg += ": " + ev.Reason
+			}
+			v.Alerts = append(v.Alerts, adminAlert{At: ev.Time, Kind: "breaker", Subject: chain, Message: msg})
+		}
+	}
+	sort.SliceStable(v.Alerts, func(i, j int) bool { return v.Alerts[i].At.After(v.Alerts[j].At) })
+	if len(v.Alerts) > maxRecentAlerts {
+		v.Alerts = v.Alerts[:maxRecentAlerts]
+	}
+
+	if s.ethereumRelay != nil {
+		v.Relays = append(v.Relays, adminUIRelay{
+			Chain:     "ethereum",
+			Connected: s.ethereumRelay.IsConnected(),
+			Peers:     s.ethereumRelay.GetPeerCount(),
+			Endpoints: s.ethereumRelay.Endpoints(),
+		})
+	}
+	if s.solanaRelay != nil {
+		v.Relays = append(v.Relays, adminUIRelay{
+			Chain:     "solana",
+			Connected: s.solanaRelay.IsConnected(),
+			Peers:     s.solanaRelay.GetPeerCount(),
+			Endpoints: s.solanaRelay.Endpoints(),
+		})
+	}
+
+	if s.cache != nil {
+		m := s.cache.GetMetrics()
+		c := &adminUICache{HitRate: m.HitRate, Hits: m.CacheHits, Misses: m.CacheMisses, Entries: m.EntryCount}
+		for prefix, ns := range s.cache.NamespaceStats() {
+			n := adminUINamespace{Prefix: prefix, Entries: ns.Entries}
+			if total := ns.Hits + ns.Misses; total > 0 {
+				n.HitRate = float64(ns.Hits) / float64(total)
+			}
+			c.Namespaces = append(c.Namespaces, n)
+		}
+		sort.Slice(c.Namespaces, func(i, j int) bool { return c.Namespaces[i].Prefix < c.Namespaces[j].Prefix })
+		v.Cache = c
+	}
+	return v
+}
+
+// adminUIHandler serves the status page. It refreshes itself; nothing on
+// it changes state.
+func (s *Server) adminUIHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := adminUITemplate.Execute(w, s.adminUIView()); err != nil {
+		s.logger.Warn("Failed to render admin status page", zap.Error(err))
+	}
+}
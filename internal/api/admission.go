@@ -0,0 +1,249 @@
+// Package api provides tier-prioritized request admission
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== ADMISSION METRICS =====
+
+var (
+	admissionQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "admission_queue_depth",
+		Help: "Requests waiting for admission by tier",
+	}, []string{"tier"})
+	admissionQueueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "admission_queue_wait_seconds",
+		Help:    "Time requests spent in the admission queue by tier",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"tier"})
+	admissionRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "admission_rejected_total",
+		Help: "Requests rejected by the admission queue by tier and reason",
+	}, []string{"tier", "reason"})
+)
+
+// ===== ADMISSION QUEUE =====
+
+var (
+	errAdmissionQueueFull = errors.New("admission queue full")
+	errAdmissionTimeout   = errors.New("admission wait timed out")
+)
+
+// admissionPriority is the dequeue order when a slot frees up
+var admissionPriority = []config.Tier{
+	config.TierEnterprise,
+	config.TierTurbo,
+	config.TierBusiness,
+	config.TierPro,
+	config.TierFree,
+}
+
+// DefaultAdmissionQueueLimits bounds how many requests of each tier may
+// wait at once. Lower tiers get shorter queues since they are served last.
+func DefaultAdmissionQueueLimits() map[config.Tier]int {
+	return map[config.Tier]int{
+		config.TierEnterprise: 1024,
+		config.TierTurbo:      512,
+		config.TierBusiness:   256,
+		config.TierPro:        128,
+		config.TierFree:       64,
+	}
+}
+
+type admissionWaiter struct {
+	ready   chan struct{}
+	granted bool // Set under the queue lock when a slot is handed over
+}
+
+// AdmissionQueue caps concurrent requests and, once saturated, admits
+// waiting requests strictly by tier, FIFO within a tier
+type AdmissionQueue struct {
+	mu          sync.Mutex
+	maxInFlight int
+	inFlight    int
+	limits      map[config.Tier]int
+	queues      map[config.Tier][]*admissionWaiter
+	maxWait     time.Duration
+}
+
+// NewAdmissionQueue creates a queue admitting maxInFlight concurrent
+// requests. maxInFlight <= 0 disables queueing entirely.
+func NewAdmissionQueue(maxInFlight int, limits map[config.Tier]int, maxWait time.Duration) *AdmissionQueue {
+	if maxWait <= 0 {
+		maxWait = 2 * time.Second
+	}
+	return &AdmissionQueue{
+		maxInFlight: maxInFlight,
+		limits:      limits,
+		queues:      make(map[config.Tier][]*admissionWaiter),
+		maxWait:     maxWait,
+	}
+}
+
+// Acquire takes a slot for a request of tier, waiting behind higher tiers
+// if the server is saturated. It returns how long the request waited.
+// Callers must Release after a nil error.
+func (q *AdmissionQueue) Acquire(ctx context.Context, tier config.Tier) (time.Duration, error) {
+	if q.maxInFlight <= 0 {
+		return 0, nil
+	}
+	if _, known := q.limits[tier]; !known {
+		tier = config.TierFree
+	}
+
+	q.mu.Lock()
+	if q.inFlight < q.maxInFlight && q.waitingLocked() == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return 0, nil
+	}
+	if len(q.queues[tier]) >= q.limits[tier] {
+		q.mu.Unlock()
+		return 0, errAdmissionQueueFull
+	}
+	w := &admissionWaiter{ready: make(chan struct{})}
+	q.queues[tier] = append(q.queues[tier], w)
+	admissionQueueDepth.WithLabelValues(string(tier)).Set(float64(len(q.queues[tier])))
+	q.mu.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(q.maxWait)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-w.ready:
+		return time.Since(start), nil
+	case <-timer.C:
+		err = errAdmissionTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if w.granted {
+		// The slot was handed over while we gave up; take it anyway
+		return time.Since(start), nil
+	}
+	q.removeLocked(tier, w)
+	return time.Since(start), err
+}
+
+// Release frees a slot, handing it to the highest-priority waiter if any
+func (q *AdmissionQueue) Release() {
+	if q.maxInFlight <= 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, tier := range admissionPriority {
+		waiters := q.queues[tier]
+		if len(waiters) == 0 {
+			continue
+		}
+		w := waiters[0]
+		q.queues[tier] = waiters[1:]
+		admissionQueueDepth.WithLabelValues(string(tier)).Set(float64(len(q.queues[tier])))
+		w.granted = true
+		close(w.ready)
+		return
+	}
+	q.inFlight--
+}
+
+// Stats reports in-flight requests and queue depth per tier
+func (q *AdmissionQueue) Stats() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued := make(map[string]int, len(q.queues))
+	for tier, waiters := range q.queues {
+		queued[string(tier)] = len(waiters)
+	}
+	return map[string]interface{}{
+		"in_flight":     q.inFlight,
+		"max_in_flight": q.maxInFlight,
+		"queued":        queued,
+	}
+}
+
+func (q *AdmissionQueue) waitingLocked() int {
+	n := 0
+	for _, waiters := range q.queues {
+		n += len(waiters)
+	}
+	return n
+}
+
+func (q *AdmissionQueue) removeLocked(tier config.Tier, w *admissionWaiter) {
+	waiters := q.queues[tier]
+	for i, other := range waiters {
+		if other == w {
+			q.queues[tier] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	admissionQueueDepth.WithLabelValues(string(tier)).Set(float64(len(q.queues[tier])))
+}
+
+// ===== ADMISSION MIDDLEWARE =====
+
+// admissionMiddleware queues requests by tier once the server is saturated
+// and reports the time spent queued in X-Queue-Wait-Ms
+func (s *Server) admissionMiddleware(next http.Handler) http.Handler {
+	if s.admission == nil || s.admission.maxInFlight <= 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Streams hold their connection for minutes and would pin slots
+		if isShedExempt(r) || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tier := s.requestTier(r)
+		wait, err := s.admission.Acquire(r.Context(), tier)
+		admissionQueueWait.WithLabelValues(string(tier)).Observe(wait.Seconds())
+		w.Header().Set("X-Queue-Wait-Ms", strconv.FormatFloat(float64(wait.Microseconds())/1000, 'f', 1, 64))
+
+		if err != nil {
+			reason := "timeout"
+			if errors.Is(err, errAdmissionQueueFull) {
+				reason = "queue_full"
+			} else if !errors.Is(err, errAdmissionTimeout) {
+				// Client went away while queued
+				reason = "canceled"
+			}
+			admissionRejected.WithLabelValues(string(tier), reason).Inc()
+			s.logger.Debug("Request not admitted",
+				zap.String("tier", string(tier)),
+				zap.String("reason", reason),
+				zap.String("path", r.URL.Path))
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, `{"error":"Server saturated","message":"Request was not admitted: %s","tier":"%s"}`, err, tier)
+			return
+		}
+		defer s.admission.Release()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -2,17 +2,69 @@
 package api
 
 import (
+	"math"
 	"sync"
 	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 )
 
 // ===== PREDICTIVE ANALYTICS IMPLEMENTATION =====
 
+const (
+	// maxBlockHistory is how many blocks are kept per chain
+	maxBlockHistory = 100
+	// etaAlpha weights the newest interval in the moving average
+	etaAlpha = 0.1
+	// etaPriorBlocks is how many blocks the target interval counts for
+	// before enough real intervals are seen
+	etaPriorBlocks = 5
+	// etaConfidence is the coverage of the reported interval
+	etaConfidence = 0.90
+	// bitcoinRetargetInterval is the difficulty adjustment period in blocks
+	bitcoinRetargetInterval = 2016
+	// maxIntervalFactor drops intervals this many times the target, e.g.
+	// after the node was offline
+	maxIntervalFactor = 12
+)
+
+// etaModel selects how arrivals are modelled for a chain
+type etaModel string
+
+const (
+	// etaModelPoisson treats blocks as a Poisson process (proof of work)
+	etaModelPoisson etaModel = "poisson"
+	// etaModelSlot treats blocks as a near-fixed slot cadence (proof of stake)
+	etaModelSlot etaModel = "slot"
+)
+
+// chainETAParams are the per-chain model inputs
+type chainETAParams struct {
+	model          etaModel
+	targetInterval time.Duration
+	retarget       int64 // Difficulty adjustment period in blocks, 0 if none
+}
+
+var chainETADefaults = map[string]chainETAParams{
+	string(blocks.ChainBitcoin):  {model: etaModelPoisson, targetInterval: 10 * time.Minute, retarget: bitcoinRetargetInterval},
+	string(blocks.ChainLitecoin): {model: etaModelPoisson, targetInterval: 150 * time.Second, retarget: bitcoinRetargetInterval},
+	string(blocks.ChainDogecoin): {model: etaModelPoisson, targetInterval: time.Minute},
+	string(blocks.ChainEthereum): {model: etaModelSlot, targetInterval: 12 * time.Second},
+	string(blocks.ChainSolana):   {model: etaModelSlot, targetInterval: 400 * time.Millisecond},
+}
+
+func etaParamsFor(chain string) chainETAParams {
+	if p, ok := chainETADefaults[chain]; ok {
+		return p
+	}
+	return chainETADefaults[string(blocks.ChainBitcoin)]
+}
+
 // PredictiveAnalytics provides predictive analytics for block timing and fee estimation
 type PredictiveAnalytics struct {
-	blockHistory []BlockTiming
-	clock        Clock
-	mu           sync.RWMutex
+	chains map[string]*chainTiming
+	clock  Clock
+	mu     sync.RWMutex
 }
 
 // BlockTiming represents timing information for a block
@@ -22,74 +74,226 @@ type BlockTiming struct {
 	Size      int
 }
 
+// chainTiming is the interval model for one chain
+type chainTiming struct {
+	params  chainETAParams
+	history []BlockTiming
+
+	mean     float64 // EWMA of the per-block interval, seconds
+	variance float64 // EWMA of the squared deviation, seconds²
+	samples  int     // Intervals observed since the last reset
+
+	epochStart     BlockTiming // First block seen in the current difficulty epoch
+	epochStartSeen bool
+}
+
+// ETAEstimate is the predicted arrival of a chain's next block
+type ETAEstimate struct {
+	Chain           string  `json:"chain"`
+	Model           string  `json:"model"`
+	ExpectedSeconds float64 `json:"expected_seconds"` // From now until the next block
+	LowerSeconds    float64 `json:"lower_seconds"`
+	UpperSeconds    float64 `json:"upper_seconds"`
+	Confidence      float64 `json:"confidence"` // Coverage of [lower, upper]
+	IntervalSeconds float64 `json:"interval_seconds"`
+	TargetSeconds   float64 `json:"target_seconds"`
+	ElapsedSeconds  float64 `json:"elapsed_seconds"` // Since the last block
+	Samples         int     `json:"samples"`
+	LastHeight      int64   `json:"last_height,omitempty"`
+
+	// Difficulty adjustment, for chains that retarget by period
+	BlocksUntilRetarget     int64    `json:"blocks_until_retarget,omitempty"`
+	ProjectedAdjustmentPct  *float64 `json:"projected_adjustment_pct,omitempty"`
+	SecondsUntilRetargetEst float64  `json:"seconds_until_retarget_est,omitempty"`
+}
+
 // NewPredictiveAnalytics creates a new predictive analytics handler
 func NewPredictiveAnalytics(clock Clock) *PredictiveAnalytics {
 	return &PredictiveAnalytics{
-		blockHistory: make([]BlockTiming, 0, 100), // Keep last 100 blocks
-		clock:        clock,
+		chains: make(map[string]*chainTiming),
+		clock:  clock,
 	}
 }
 
 // RecordBlock records a new block for predictive analytics
-func (pa *PredictiveAnalytics) RecordBlock(height int64, size int) {
+func (pa *PredictiveAnalytics) RecordBlock(chain string, height int64, size int) {
+	if chain == "" {
+		chain = string(blocks.ChainBitcoin)
+	}
+
 	pa.mu.Lock()
 	defer pa.mu.Unlock()
 
-	block := BlockTiming{
-		Height:    height,
-		Timestamp: pa.clock.Now(),
-		Size:      size,
+	ct := pa.chains[chain]
+	if ct == nil {
+		params := etaParamsFor(chain)
+		ct = &chainTiming{
+			params:  params,
+			history: make([]BlockTiming, 0, maxBlockHistory),
+			mean:    params.targetInterval.Seconds(),
+		}
+		pa.chains[chain] = ct
 	}
+	ct.record(BlockTiming{Height: height, Timestamp: pa.clock.Now(), Size: size})
+}
 
-	pa.blockHistory = append(pa.blockHistory, block)
+func (ct *chainTiming) record(block BlockTiming) {
+	if n := len(ct.history); n > 0 {
+		prev := ct.history[n-1]
+		if block.Height <= prev.Height {
+			// Duplicate or reorg: the replacement block does not tell us
+			// anything about the arrival rate
+			return
+		}
+		ct.observe(prev, block)
+	}
+
+	if ct.params.retarget > 0 && block.Height%ct.params.retarget == 0 {
+		// New difficulty: the target interval is the best prior again
+		ct.mean = ct.params.targetInterval.Seconds()
+		ct.variance = 0
+		ct.samples = 0
+		ct.epochStart, ct.epochStartSeen = block, true
+	} else if !ct.epochStartSeen {
+		ct.epochStart, ct.epochStartSeen = block, true
+	}
 
-	// Keep only the last 100 blocks
-	if len(pa.blockHistory) > 100 {
-		pa.blockHistory = pa.blockHistory[1:]
+	ct.history = append(ct.history, block)
+	if len(ct.history) > maxBlockHistory {
+		ct.history = ct.history[1:]
 	}
 }
 
-// PredictNextBlockETA predicts the ETA for the next block
-func (pa *PredictiveAnalytics) PredictNextBlockETA() float64 {
+// observe folds the interval between two blocks into the moving average
+func (ct *chainTiming) observe(prev, block BlockTiming) {
+	blocksBetween := float64(block.Height - prev.Height)
+	interval := block.Timestamp.Sub(prev.Timestamp).Seconds() / blocksBetween
+	target := ct.params.targetInterval.Seconds()
+	if interval <= 0 || interval > target*maxIntervalFactor {
+		return
+	}
+
+	// Weight the target as etaPriorBlocks pseudo-observations so a few
+	// fast blocks after startup don't swing the estimate
+	alpha := etaAlpha
+	if prior := 1.0 / float64(ct.samples+etaPriorBlocks); prior > alpha {
+		alpha = prior
+	}
+	delta := interval - ct.mean
+	ct.mean += alpha * delta
+	ct.variance = (1 - alpha) * (ct.variance + alpha*delta*delta)
+	ct.samples++
+}
+
+// GetPredictiveETA returns the next block estimate for chain
+func (pa *PredictiveAnalytics) GetPredictiveETA(chain string) ETAEstimate {
+	if chain == "" {
+		chain = string(blocks.ChainBitcoin)
+	}
+
 	pa.mu.RLock()
 	defer pa.mu.RUnlock()
 
-	if len(pa.blockHistory) < 2 {
-		return 420.0 // Default 7 minutes
+	ct := pa.chains[chain]
+	if ct == nil {
+		params := etaParamsFor(chain)
+		ct = &chainTiming{params: params, mean: params.targetInterval.Seconds()}
+	}
+	return ct.estimate(chain, pa.clock.Now())
+}
+
+func (ct *chainTiming) estimate(chain string, now time.Time) ETAEstimate {
+	est := ETAEstimate{
+		Chain:           chain,
+		Model:           string(ct.params.model),
+		IntervalSeconds: ct.mean,
+		TargetSeconds:   ct.params.targetInterval.Seconds(),
+		Confidence:      etaConfidence,
+		Samples:         ct.samples,
 	}
 
-	// Calculate average interval between recent blocks
-	var totalInterval float64
-	count := 0
+	var last BlockTiming
+	if n := len(ct.history); n > 0 {
+		last = ct.history[n-1]
+		est.LastHeight = last.Height
+		est.ElapsedSeconds = math.Max(0, now.Sub(last.Timestamp).Seconds())
+	}
 
-	for i := 1; i < len(pa.blockHistory); i++ {
-		interval := pa.blockHistory[i].Timestamp.Sub(pa.blockHistory[i-1].Timestamp).Seconds()
-		if interval > 0 && interval < 3600 { // Reasonable bounds (max 1 hour)
-			totalInterval += interval
-			count++
+	tail := (1 - etaConfidence) / 2
+	switch ct.params.model {
+	case etaModelPoisson:
+		// Exponential waiting times are memoryless: the expected wait is the
+		// mean interval no matter how long ago the last block was
+		est.ExpectedSeconds = ct.mean
+		est.LowerSeconds = -ct.mean * math.Log(1-tail)
+		est.UpperSeconds = -ct.mean * math.Log(tail)
+	default:
+		// Slots tick at a near-fixed cadence, so the wait shrinks with the
+		// time already elapsed; skipped slots show up as variance
+		sd := math.Sqrt(ct.variance)
+		z := 1.6448536269514722 // Two-sided 90% normal quantile
+		remaining := ct.mean - est.ElapsedSeconds
+		if remaining < 0 {
+			remaining = ct.mean - math.Mod(est.ElapsedSeconds, ct.mean)
 		}
+		est.ExpectedSeconds = remaining
+		est.LowerSeconds = math.Max(0, remaining-z*sd)
+		est.UpperSeconds = remaining + z*sd
 	}
 
-	if count == 0 {
-		return 420.0
+	if ct.params.retarget > 0 && len(ct.history) > 0 {
+		next := (last.Height/ct.params.retarget + 1) * ct.params.retarget
+		est.BlocksUntilRetarget = next - last.Height
+		est.SecondsUntilRetargetEst = float64(est.BlocksUntilRetarget) * ct.mean
+
+		// Difficulty scales by target time over actual time for the epoch
+		if span := last.Height - ct.epochStart.Height; span > 0 {
+			actual := last.Timestamp.Sub(ct.epochStart.Timestamp).Seconds() / float64(span)
+			if actual > 0 {
+				pct := (ct.params.targetInterval.Seconds()/actual - 1) * 100
+				pct = math.Max(-75, math.Min(300, pct)) // Consensus clamps retargets to 4x
+				est.ProjectedAdjustmentPct = &pct
+			}
+		}
 	}
+	return est
+}
 
-	return totalInterval / float64(count)
+// PredictNextBlockETA predicts the ETA for the next Bitcoin block
+func (pa *PredictiveAnalytics) PredictNextBlockETA() float64 {
+	return pa.GetPredictiveETA(string(blocks.ChainBitcoin)).ExpectedSeconds
 }
 
 // GetAnalyticsSummary returns a summary of predictive analytics data
 func (pa *PredictiveAnalytics) GetAnalyticsSummary() map[string]interface{} {
 	pa.mu.RLock()
-	defer pa.mu.RUnlock()
+	chains := make([]string, 0, len(pa.chains))
+	total := 0
+	for name, ct := range pa.chains {
+		chains = append(chains, name)
+		total += len(ct.history)
+	}
+	btc := pa.chains[string(blocks.ChainBitcoin)]
+	var latest *BlockTiming
+	if btc != nil && len(btc.history) > 0 {
+		l := btc.history[len(btc.history)-1]
+		latest = &l
+	}
+	pa.mu.RUnlock()
+
+	etas := make(map[string]ETAEstimate, len(chains))
+	for _, name := range chains {
+		etas[name] = pa.GetPredictiveETA(name)
+	}
 
 	summary := map[string]interface{}{
-		"total_blocks_recorded":  len(pa.blockHistory),
+		"total_blocks_recorded":  total,
 		"next_block_eta_seconds": pa.PredictNextBlockETA(),
+		"chains":                 etas,
 		"timestamp":              pa.clock.Now().UTC().Format(time.RFC3339),
 	}
 
-	if len(pa.blockHistory) > 0 {
-		latest := pa.blockHistory[len(pa.blockHistory)-1]
+	if latest != nil {
 		summary["latest_block_height"] = latest.Height
 		summary["latest_block_timestamp"] = latest.Timestamp.Format(time.RFC3339)
 		summary["latest_block_size"] = latest.Size
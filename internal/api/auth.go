@@ -10,13 +10,24 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockbus"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockindex"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/eventsign"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/fees"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/lifecycle"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/loadshed"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/p2p"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/spv"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/timesync"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/webhooks"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +37,7 @@ import (
 type Server struct {
 	cfg       config.Config
 	blockChan chan blocks.BlockEvent
+	bus       *blockbus.Bus // Hot block fan-out to streams, cache and analytics
 	mem       *mempool.Mempool
 	cache     *cache.Cache
 	logger    *zap.Logger
@@ -55,6 +67,32 @@ type Server struct {
 	clock             Clock
 	randReader        RandomReader
 	enterpriseManager *EnterpriseSecurityManager
+	loadShedder       *loadshed.Shedder    // Sheds lower tiers under pressure
+	admission         *AdmissionQueue      // Orders queued requests by tier when saturated
+	compressionExempt map[string]bool      // Paths served uncompressed (fastpath snapshots)
+	webhooks          *webhooks.Dispatcher // Block and reorg callbacks; nil when disabled
+	blockIndex        atomic.Pointer[blockindex.Index] // Local block history; nil when disabled or not yet open
+	peerAuth          *p2p.Authenticator   // Peer key ring for admin rotation; nil when not wired
+	peerDedup         *p2p.EnterpriseP2PDeduper // P2P message deduper for admin tuning; nil when not wired
+	txPeers           *p2p.Client          // Bitcoin peers for /v1/{chain}/tx broadcasts; nil when not wired
+	billing           *billingState        // Key audit log and subscription webhook state
+	spv               *spv.RPCSource       // Bitcoin node used for SPV proofs; nil without RPC_URL
+	fees              *fees.Estimator      // Per-chain fee sources behind /v1/{chain}/fees
+	mining            *miningRPC           // Bitcoin node for template passthrough; nil without RPC_URL
+	costs             *costTable           // Upstream cost units per request method
+	tipSLA            *tipSLAMonitor       // Latest-block staleness per chain
+	canary            *canaryRunner        // Cross-source latest block checks
+	eventSigner       *eventsign.Signer    // Signs streamed and pushed blocks; nil when disabled
+	timeSync          *timesync.Monitor    // Local clock skew; nil when disabled
+	shadow            *shadowMirror        // Mirrors reads to SHADOW_TARGET_URL; nil when disabled
+	idempotency       *idempotencyStore    // Replays Idempotency-Key responses; nil without a cache
+	alerts            alertLog             // Recent alerts shown on /admin/ui
+
+	// Lifecycle
+	life       context.Context    // Server lifetime, set by Run; bounds relays connected on demand
+	draining   atomic.Bool        // Set once graceful shutdown starts
+	reloading  atomic.Bool        // Shutdown is a handover to a reloaded binary
+	components *lifecycle.Manager // Stopped in dependency order after HTTP and streams drain
 }
 
 // New creates a new API server instance
@@ -65,9 +103,10 @@ func New(cfg config.Config, blockChan chan blocks.BlockEvent, mem *mempool.Mempo
 	server := &Server{
 		cfg:               cfg,
 		blockChan:         blockChan,
+		bus:               blockbus.New(blockBusConfig(cfg), logger),
 		mem:               mem,
 		logger:            logger,
-		rateLimiter:       NewRateLimiter(clock),
+		rateLimiter:       newRateLimiterFromConfig(cfg, clock, logger),
 		keyManager:        NewCustomerKeyManagerWithConfig(cfg, clock, randReader),
 		adminAuth:         NewAdminAuth(),
 		wsLimiter:         NewWebSocketLimiter(cfg.WebSocketMaxGlobal, cfg.WebSocketMaxPerIP, cfg.WebSocketMaxPerChain),
@@ -80,20 +119,37 @@ func New(cfg config.Config, blockChan chan blocks.BlockEvent, mem *mempool.Mempo
 		clock:             clock,
 		randReader:        randReader,
 		enterpriseManager: nil, // Will be initialized in Run()
+		loadShedder:       loadshed.New(loadshed.DefaultConfig(), logger),
+		admission:         NewAdmissionQueue(cfg.AdmissionMaxInFlight, DefaultAdmissionQueueLimits(), cfg.AdmissionMaxWait),
+		billing:           newBillingState(cfg, logger),
+		spv:               newSPVSource(cfg),
+		fees:              newFeeEstimator(cfg, mem),
+		mining:            newMiningRPC(cfg),
+		costs:             newCostTable(cfg, logger),
+		tipSLA:            newTipSLAMonitor(cfg),
+		canary:            newCanaryRunner(),
 	}
 
-	// Initialize keystore manager (data/keystore)
-	if ks, err := NewKeystoreManager(filepath.Join("data", "keystore"), logger); err == nil {
+	// Initialize keystore manager (backend selected by KEYSTORE_BACKEND)
+	if ks, err := newKeystoreFromConfig(cfg, logger); err == nil {
 		server.keystore = ks
 	} else {
 		server.logger.Warn("Failed to initialize keystore manager", zap.Error(err))
 	}
+	server.components = server.newComponents()
+	server.timeSync = newTimeSync(server)
+	server.idempotency = newIdempotencyStore(server)
+	server.backends.breakers = newChainBreakers(server)
+	if server.backends.breakers != nil {
+		server.OnShutdown("chain breakers", server.backends.breakers.shutdown)
+	}
 
 	// Initialize default Bitcoin backend
 	btcBackend := &BitcoinBackend{
-		blockChan: blockChan,
+		bus:       server.bus,
 		mem:       mem,
 		cfg:       cfg,
+		predictor: server.predictor,
 	}
 	server.backends.Register("btc", btcBackend)
 	server.backends.Register("bitcoin", btcBackend) // alias for handlers
@@ -109,10 +165,11 @@ func NewWithCache(cfg config.Config, blockChan chan blocks.BlockEvent, mem *memp
 	server := &Server{
 		cfg:               cfg,
 		blockChan:         blockChan,
+		bus:               blockbus.New(blockBusConfig(cfg), logger),
 		mem:               mem,
 		cache:             cache,
 		logger:            logger,
-		rateLimiter:       NewRateLimiter(clock),
+		rateLimiter:       newRateLimiterFromConfig(cfg, clock, logger),
 		keyManager:        NewCustomerKeyManagerWithConfig(cfg, clock, randReader),
 		adminAuth:         NewAdminAuth(),
 		wsLimiter:         NewWebSocketLimiter(cfg.WebSocketMaxGlobal, cfg.WebSocketMaxPerIP, cfg.WebSocketMaxPerChain),
@@ -125,21 +182,38 @@ func NewWithCache(cfg config.Config, blockChan chan blocks.BlockEvent, mem *memp
 		clock:             clock,
 		randReader:        randReader,
 		enterpriseManager: nil, // Will be initialized in Run()
+		loadShedder:       loadshed.New(loadshed.DefaultConfig(), logger),
+		admission:         NewAdmissionQueue(cfg.AdmissionMaxInFlight, DefaultAdmissionQueueLimits(), cfg.AdmissionMaxWait),
+		billing:           newBillingState(cfg, logger),
+		spv:               newSPVSource(cfg),
+		fees:              newFeeEstimator(cfg, mem),
+		mining:            newMiningRPC(cfg),
+		costs:             newCostTable(cfg, logger),
+		tipSLA:            newTipSLAMonitor(cfg),
+		canary:            newCanaryRunner(),
 	}
 
-	// Initialize keystore manager (data/keystore)
-	if ks, err := NewKeystoreManager(filepath.Join("data", "keystore"), logger); err == nil {
+	// Initialize keystore manager (backend selected by KEYSTORE_BACKEND)
+	if ks, err := newKeystoreFromConfig(cfg, logger); err == nil {
 		server.keystore = ks
 	} else {
 		server.logger.Warn("Failed to initialize keystore manager", zap.Error(err))
 	}
+	server.components = server.newComponents()
+	server.timeSync = newTimeSync(server)
+	server.idempotency = newIdempotencyStore(server)
+	server.backends.breakers = newChainBreakers(server)
+	if server.backends.breakers != nil {
+		server.OnShutdown("chain breakers", server.backends.breakers.shutdown)
+	}
 
 	// Initialize default Bitcoin backend
 	btcBackend := &BitcoinBackend{
-		blockChan: blockChan,
+		bus:       server.bus,
 		mem:       mem,
 		cfg:       cfg,
 		cache:     cache,
+		predictor: server.predictor,
 	}
 	server.backends.Register("btc", btcBackend)
 	server.backends.Register("bitcoin", btcBackend)
@@ -184,6 +258,12 @@ type CustomerKey struct {
 	RateLimitRemaining int         `json:"rate_limit_remaining"`
 	ClientIP           string      `json:"client_ip"`
 	UserAgent          string      `json:"user_agent"`
+	CustomerID         string      `json:"customer_id,omitempty"`     // Billing customer the key belongs to
+	SubscriptionID     string      `json:"subscription_id,omitempty"` // Subscription that last set the tier
+	CostUnits          int64       `json:"cost_units"`                // Request cost units charged in CostPeriod
+	CostPeriod         string      `json:"cost_period,omitempty"`     // UTC calendar month CostUnits accrue to ("2026-10")
+	EgressBytes        int64       `json:"egress_bytes"`              // Stream bytes sent in EgressPeriod
+	EgressPeriod       string      `json:"egress_period,omitempty"`   // UTC calendar month EgressBytes accrue to
 }
 
 // NewCustomerKeyManager creates a new customer key manager
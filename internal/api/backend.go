@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockbus"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
@@ -27,6 +28,7 @@ type ChainBackend interface {
 type BackendRegistry struct {
 	mu       sync.RWMutex
 	backends map[string]ChainBackend
+	breakers *chainBreakers // Wraps registered backends; nil registers them as is
 }
 
 // NewBackendRegistry creates a new backend registry
@@ -38,6 +40,9 @@ func NewBackendRegistry() *BackendRegistry {
 
 // Register adds a new blockchain backend to the registry
 func (r *BackendRegistry) Register(name string, backend ChainBackend) {
+	if r.breakers != nil {
+		backend = r.breakers.wrap(name, backend)
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.backends[name] = backend
@@ -78,20 +83,21 @@ func (r *BackendRegistry) GetStatus() map[string]interface{} {
 
 // BitcoinBackend implements ChainBackend for Bitcoin
 type BitcoinBackend struct {
-	blockChan chan blocks.BlockEvent
+	bus       *blockbus.Bus
 	mem       *mempool.Mempool
 	cfg       config.Config
 	cache     *cache.Cache
+	predictor *PredictiveAnalytics
 }
 
 // GetLatestBlock returns the latest block
 func (b *BitcoinBackend) GetLatestBlock() (blocks.BlockEvent, error) {
-	select {
-	case block := <-b.blockChan:
-		return block, nil
-	default:
-		return blocks.BlockEvent{}, fmt.Errorf("no block available")
+	if b.bus != nil {
+		if block, ok := b.bus.Latest(); ok {
+			return block, nil
+		}
 	}
+	return blocks.BlockEvent{}, fmt.Errorf("no block available")
 }
 
 // GetMempoolSize returns the current mempool size
@@ -113,17 +119,32 @@ func (b *BitcoinBackend) GetStatus() map[string]interface{} {
 	}
 }
 
-// GetPredictiveETA returns predictive ETA for next block
+// GetPredictiveETA returns the expected seconds until the next block
 func (b *BitcoinBackend) GetPredictiveETA() float64 {
-	// Placeholder - would use actual predictive analytics
-	return 420.0 // 7 minutes
+	if b.predictor == nil {
+		return etaParamsFor(string(blocks.ChainBitcoin)).targetInterval.Seconds()
+	}
+	return b.predictor.GetPredictiveETA(string(blocks.ChainBitcoin)).ExpectedSeconds
 }
 
 // StreamBlocks streams blocks to the provided channel
 func (b *BitcoinBackend) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
-	// Placeholder - would implement actual block streaming
-	go func() {
-		<-ctx.Done()
-	}()
-	return nil
+	if b.bus == nil {
+		return fmt.Errorf("block bus not available")
+	}
+
+	sub := b.bus.Subscribe(blockbus.SubscribeOptions{Name: "backend_stream"})
+	defer b.bus.Unsubscribe(sub)
+
+	for {
+		block, err := sub.Next(ctx)
+		if err != nil {
+			return nil
+		}
+		select {
+		case blockChan <- block:
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
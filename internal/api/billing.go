@@ -0,0 +1,543 @@
+// Package api provides customer key provisioning and subscription billing webhooks
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+// ===== KEY PROVISIONING =====
+
+var (
+	errKeyNotFound  = errors.New("key not found")
+	errKeyAmbiguous = errors.New("key id matches more than one key")
+)
+
+// minKeyIDLength is the shortest hash prefix accepted as a key id; it is
+// the key_id returned when a key is generated
+const minKeyIDLength = 8
+
+// tierRank orders tiers from least to most capable
+var tierRank = map[config.Tier]int{
+	config.TierFree:       0,
+	config.TierPro:        1,
+	config.TierBusiness:   2,
+	config.TierTurbo:      3,
+	config.TierEnterprise: 4,
+}
+
+// parseTier accepts a tier name in any case
+func parseTier(s string) (config.Tier, bool) {
+	t := config.Tier(strings.ToLower(strings.TrimSpace(s)))
+	_, ok := tierRank[t]
+	return t, ok
+}
+
+// ProvisionKey creates a key at tier for customerID that expires after ttl
+func (ckm *CustomerKeyManager) ProvisionKey(tier config.Tier, customerID string, ttl time.Duration) (string, CustomerKey, error) {
+	newKey, err := ckm.GenerateKey(tier, "")
+	if err != nil {
+		return "", CustomerKey{}, err
+	}
+	hash := ckm.hashKey(newKey)
+
+	ckm.mu.Lock()
+	defer ckm.mu.Unlock()
+	key := ckm.keys[hash]
+	key.CustomerID = customerID
+	if ttl > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(ttl)
+	}
+	ckm.keys[hash] = key
+	return newKey, key, nil
+}
+
+// SetTier moves the key identified by id (its hash or a unique prefix of
+// it) to tier and resets its remaining allowance to the tier's limit. It
+// returns the updated key and the tier it had before.
+func (ckm *CustomerKeyManager) SetTier(id string, tier config.Tier, subscriptionID string) (CustomerKey, config.Tier, error) {
+	ckm.mu.Lock()
+	defer ckm.mu.Unlock()
+
+	hash, err := ckm.resolveHash(id)
+	if err != nil {
+		return CustomerKey{}, "", err
+	}
+	key := ckm.keys[hash]
+	previous := key.Tier
+	key.Tier = tier
+	key.RateLimitRemaining = ckm.getRateLimitForTier(tier)
+	if subscriptionID != "" {
+		key.SubscriptionID = subscriptionID
+	}
+	ckm.keys[hash] = key
+	return key, previous, nil
+}
+
+// Keys returns every key, oldest first
+func (ckm *CustomerKeyManager) Keys() []CustomerKey {
+	ckm.mu.RLock()
+	out := make([]CustomerKey, 0, len(ckm.keys))
+	for _, key := range ckm.keys {
+		out = append(out, key)
+	}
+	ckm.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Hash < out[j].Hash
+	})
+	return out
+}
+
+// KeysForCustomer returns the keys provisioned for customerID
+func (ckm *CustomerKeyManager) KeysForCustomer(customerID string) []CustomerKey {
+	ckm.mu.RLock()
+	defer ckm.mu.RUnlock()
+
+	var out []CustomerKey
+	for _, key := range ckm.keys {
+		if customerID != "" && key.CustomerID == customerID {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// resolveHash maps a key id to a full hash; callers must hold ckm.mu
+func (ckm *CustomerKeyManager) resolveHash(id string) (string, error) {
+	id = strings.ToLower(id)
+	if _, ok := ckm.keys[id]; ok {
+		return id, nil
+	}
+	if len(id) < minKeyIDLength {
+		return "", errKeyNotFound
+	}
+	match := ""
+	for hash := range ckm.keys {
+		if strings.HasPrefix(hash, id) {
+			if match != "" {
+				return "", errKeyAmbiguous
+			}
+			match = hash
+		}
+	}
+	if match == "" {
+		return "", errKeyNotFound
+	}
+	return match, nil
+}
+
+// provisionKeyRequest is the body of POST /api/v1/admin/keys
+type provisionKeyRequest struct {
+	Tier       string `json:"tier"`
+	CustomerID string `json:"customer_id"`
+	TTLDays    int    `json:"ttl_days"` // 0 keeps the default of one year
+}
+
+// setTierRequest is the body of POST /api/v1/admin/keys/{id}/tier
+type setTierRequest struct {
+	Tier   string `json:"tier"`
+	Reason string `json:"reason"`
+}
+
+// customerKeysAdminHandler provisions customer keys at any tier:
+//
+//	GET  /api/v1/admin/keys?customer_id=cus_123   list keys, filtered by customer_id, tier or subscription_id
+//	POST /api/v1/admin/keys                       create a key
+//	POST /api/v1/admin/keys/{id}/tier             upgrade or downgrade a key
+//
+// {id} is the key hash or the key_id prefix returned at creation.
+func (s *Server) customerKeysAdminHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/keys"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		p, ok := s.listRequest(w, r, config.TierEnterprise, "customer_id", "tier", "subscription_id")
+		if !ok {
+			return
+		}
+		keys, next, ok := filteredPage(s, w, s.keyManager.Keys(), p)
+		if !ok {
+			return
+		}
+		writeList(s, w, "keys", keys, p, next, nil)
+
+	case rest == "" && r.Method == http.MethodPost:
+		var req provisionKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		tier, ok := parseTier(req.Tier)
+		if !ok {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "unknown tier"})
+			return
+		}
+		if req.TTLDays < 0 {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "ttl_days must not be negative"})
+			return
+		}
+		newKey, key, err := s.keyManager.ProvisionKey(tier, req.CustomerID, time.Duration(req.TTLDays)*24*time.Hour)
+		if err != nil {
+			s.logger.Error("Failed to provision API key", zap.Error(err))
+			s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate secure key"})
+			return
+		}
+		s.billing.audit(keyAuditEntry{
+			Action:     "key.create",
+			KeyID:      key.Hash[:minKeyIDLength],
+			CustomerID: key.CustomerID,
+			NewTier:    key.Tier,
+			Actor:      "admin:" + getClientIP(r),
+		})
+		s.jsonResponse(w, http.StatusCreated, map[string]interface{}{
+			"api_key":     newKey,
+			"key_id":      key.Hash[:minKeyIDLength],
+			"tier":        string(key.Tier),
+			"customer_id": key.CustomerID,
+			"created_at":  key.CreatedAt.Format(time.RFC3339),
+			"expires_at":  key.ExpiresAt.Format(time.RFC3339),
+			"rate_limit":  s.keyManager.getRateLimitForTier(key.Tier),
+		})
+
+	case len(parts) == 2 && parts[1] == "tier" && r.Method == http.MethodPost:
+		var req setTierRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		tier, ok := parseTier(req.Tier)
+		if !ok {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "unknown tier"})
+			return
+		}
+		key, previous, err := s.keyManager.SetTier(parts[0], tier, "")
+		switch {
+		case errors.Is(err, errKeyNotFound):
+			s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		case err != nil:
+			s.jsonResponse(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		s.billing.audit(keyAuditEntry{
+			Action:     "key.tier",
+			KeyID:      key.Hash[:minKeyIDLength],
+			CustomerID: key.CustomerID,
+			OldTier:    previous,
+			NewTier:    key.Tier,
+			Actor:      "admin:" + getClientIP(r),
+			Reason:     req.Reason,
+		})
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+			"key_id":        key.Hash[:minKeyIDLength],
+			"previous_tier": string(previous),
+			"tier":          string(key.Tier),
+			"rate_limit":    s.keyManager.getRateLimitForTier(key.Tier),
+		})
+
+	default:
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// ===== KEY AUDIT LOG =====
+
+// keyAuditEntry records one change to a customer key
+type keyAuditEntry struct {
+	Time       time.Time   `json:"time"`
+	Action     string      `json:"action"` // key.create or key.tier
+	KeyID      string      `json:"key_id"`
+	CustomerID string      `json:"customer_id,omitempty"`
+	OldTier    config.Tier `json:"old_tier,omitempty"`
+	NewTier    config.Tier `json:"new_tier"`
+	Actor      string      `json:"actor"` // admin:<ip> or billing:<event id>
+	Reason     string      `json:"reason,omitempty"`
+}
+
+// billingState holds the key audit log and the webhook receiver's replay
+// protection
+type billingState struct {
+	secret     []byte
+	priceTiers map[string]config.Tier
+	auditPath  string
+	logger     *zap.Logger
+
+	mu          sync.Mutex
+	seen        map[string]time.Time // Event id -> when it was processed
+	lastApplied map[string]int64     // Subscription id -> creation time of the last applied event
+}
+
+// billingEventRetention bounds how long processed event ids are remembered;
+// providers stop retrying well before this
+const billingEventRetention = 72 * time.Hour
+
+func newBillingState(cfg config.Config, logger *zap.Logger) *billingState {
+	b := &billingState{
+		secret:      []byte(cfg.BillingWebhookSecret),
+		priceTiers:  make(map[string]config.Tier),
+		auditPath:   cfg.KeyAuditLogPath,
+		logger:      logger,
+		seen:        make(map[string]time.Time),
+		lastApplied: make(map[string]int64),
+	}
+	for _, pair := range cfg.BillingPriceTiers {
+		price, name, ok := strings.Cut(pair, "=")
+		tier, valid := parseTier(name)
+		if !ok || !valid {
+			logger.Warn("Ignoring invalid BILLING_PRICE_TIERS entry", zap.String("entry", pair))
+			continue
+		}
+		b.priceTiers[strings.TrimSpace(price)] = tier
+	}
+	return b
+}
+
+// audit logs entry and appends it to the audit file
+func (b *billingState) audit(entry keyAuditEntry) {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	b.logger.Info("Customer key audit",
+		zap.String("action", entry.Action),
+		zap.String("key_id", entry.KeyID),
+		zap.String("customer_id", entry.CustomerID),
+		zap.String("old_tier", string(entry.OldTier)),
+		zap.String("new_tier", string(entry.NewTier)),
+		zap.String("actor", entry.Actor))
+	if b.auditPath == "" {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(b.auditPath), 0o755); err != nil {
+		b.logger.Error("Failed to create key audit log directory", zap.Error(err))
+		return
+	}
+	f, err := os.OpenFile(b.auditPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		b.logger.Error("Failed to open key audit log", zap.Error(err))
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		b.logger.Error("Failed to write key audit log", zap.Error(err))
+	}
+}
+
+// ===== SUBSCRIPTION WEBHOOK =====
+
+// billingSignatureTolerance is the accepted age of a signed webhook
+const billingSignatureTolerance = 5 * time.Minute
+
+// billingEvent is the subset of a Stripe-style subscription event used here
+type billingEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object billingSubscription `json:"object"`
+	} `json:"data"`
+}
+
+type billingSubscription struct {
+	ID       string `json:"id"`
+	Customer string `json:"customer"`
+	Status   string `json:"status"`
+	Items    struct {
+		Data []struct {
+			Price struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// billingWebhookHandler receives subscription events from the payment
+// provider and moves every key of the subscribing customer to the tier of
+// the subscribed price. Cancelled or lapsed subscriptions drop keys to the
+// free tier. Events are authenticated with the Stripe-Signature scheme
+// (HMAC-SHA256 over "<timestamp>.<body>").
+func (s *Server) billingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+		return
+	}
+	if len(s.billing.secret) == 0 {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "billing webhooks not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 64<<10))
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if err := verifyBillingSignature(r.Header.Get("Stripe-Signature"), body, s.billing.secret, time.Now(), billingSignatureTolerance); err != nil {
+		s.logger.Warn("Rejected billing webhook", zap.String("ip", getClientIP(r)), zap.Error(err))
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid signature"})
+		return
+	}
+
+	var event billingEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid event"})
+		return
+	}
+	if !strings.HasPrefix(event.Type, "customer.subscription.") {
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"received": true, "ignored": event.Type})
+		return
+	}
+
+	sub := event.Data.Object
+	tier, ok := s.billing.tierFor(event.Type, sub)
+	if !ok {
+		s.logger.Warn("Billing webhook for unmapped price",
+			zap.String("event_id", event.ID),
+			zap.String("subscription_id", sub.ID))
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"received": true, "ignored": "unmapped price"})
+		return
+	}
+	if !s.billing.claim(event, time.Now()) {
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"received": true, "duplicate": true})
+		return
+	}
+
+	updated := 0
+	for _, key := range s.keyManager.KeysForCustomer(sub.Customer) {
+		if key.Tier == tier {
+			continue
+		}
+		changed, previous, err := s.keyManager.SetTier(key.Hash, tier, sub.ID)
+		if err != nil {
+			continue
+		}
+		updated++
+		s.billing.audit(keyAuditEntry{
+			Action:     "key.tier",
+			KeyID:      changed.Hash[:minKeyIDLength],
+			CustomerID: changed.CustomerID,
+			OldTier:    previous,
+			NewTier:    changed.Tier,
+			Actor:      "billing:" + event.ID,
+			Reason:     event.Type + " (" + sub.Status + ")",
+		})
+	}
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"received": true,
+		"tier":     string(tier),
+		"updated":  updated,
+	})
+}
+
+// tierFor returns the tier a subscription event grants. Active and trialing
+// subscriptions get the highest tier among their prices, falling back to a
+// "tier" metadata entry; anything else gets the free tier.
+func (b *billingState) tierFor(eventType string, sub billingSubscription) (config.Tier, bool) {
+	if eventType == "customer.subscription.deleted" || (sub.Status != "active" && sub.Status != "trialing") {
+		return config.TierFree, true
+	}
+	best, found := config.TierFree, false
+	for _, item := range sub.Items.Data {
+		if t, ok := b.priceTiers[item.Price.ID]; ok && (!found || tierRank[t] > tierRank[best]) {
+			best, found = t, true
+		}
+	}
+	if !found {
+		return parseTier(sub.Metadata["tier"])
+	}
+	return best, true
+}
+
+// claim records event as processed and reports whether it should be
+// applied: retries of an already processed event and events older than the
+// last one applied to the same subscription are skipped
+func (b *billingState) claim(event billingEvent, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, at := range b.seen {
+		if now.Sub(at) > billingEventRetention {
+			delete(b.seen, id)
+		}
+	}
+	if _, dup := b.seen[event.ID]; dup {
+		return false
+	}
+	b.seen[event.ID] = now
+
+	subID := event.Data.Object.ID
+	if last, ok := b.lastApplied[subID]; ok && event.Created < last {
+		return false
+	}
+	b.lastApplied[subID] = event.Created
+	return true
+}
+
+// verifyBillingSignature checks a Stripe-Signature header of the form
+// "t=<unix>,v1=<hex hmac>[,v1=...]" against body
+func verifyBillingSignature(header string, body, secret []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			timestamp = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("missing timestamp or signature")
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp outside tolerance (%s)", age.Round(time.Second))
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return nil
+		}
+	}
+	return errors.New("no matching signature")
+}
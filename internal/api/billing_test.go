@@ -0,0 +1,141 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+func signBilling(secret, body []byte, ts int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.%s", ts, body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyBillingSignature(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"id":"evt_1","type":"customer.subscription.updated"}`)
+	now := time.Unix(1700000000, 0)
+	ts := now.Unix()
+	good := signBilling(secret, body, ts)
+	stale := now.Add(-10 * time.Minute).Unix()
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		ok     bool
+	}{
+		{"good", fmt.Sprintf("t=%d,v1=%s", ts, good), body, true},
+		{"tampered body", fmt.Sprintf("t=%d,v1=%s", ts, good), []byte(`{"id":"evt_1","type":"customer.subscription.deleted"}`), false},
+		{"stale timestamp", fmt.Sprintf("t=%d,v1=%s", stale, signBilling(secret, body, stale)), body, false},
+		{"future timestamp", fmt.Sprintf("t=%d,v1=%s", ts+600, signBilling(secret, body, ts+600)), body, false},
+		{"rolled secret, second v1 matches", fmt.Sprintf("t=%d,v1=%s,v1=%s", ts, signBilling([]byte("whsec_old"), body, ts), good), body, true},
+		{"no v1 matches", fmt.Sprintf("t=%d,v1=%s,v1=%s", ts, signBilling([]byte("a"), body, ts), signBilling([]byte("b"), body, ts)), body, false},
+		{"missing t", "v1=" + good, body, false},
+		{"missing v1", "t=" + strconv.FormatInt(ts, 10), body, false},
+		{"signature over another timestamp", fmt.Sprintf("t=%d,v1=%s", ts+1, good), body, false},
+		{"empty", "", body, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyBillingSignature(tt.header, tt.body, secret, now, billingSignatureTolerance)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func billingTestEvent(t *testing.T, id, subID string, created int64) billingEvent {
+	t.Helper()
+	var ev billingEvent
+	raw := fmt.Sprintf(`{"id":%q,"type":"customer.subscription.updated","created":%d,"data":{"object":{"id":%q}}}`, id, created, subID)
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestBillingClaim(t *testing.T) {
+	b := newBillingState(config.Config{}, zap.NewNop())
+	now := time.Now()
+
+	if !b.claim(billingTestEvent(t, "evt_1", "sub_1", 100), now) {
+		t.Fatal("first event not applied")
+	}
+	if b.claim(billingTestEvent(t, "evt_1", "sub_1", 100), now) {
+		t.Fatal("retried event applied twice")
+	}
+	if !b.claim(billingTestEvent(t, "evt_3", "sub_1", 300), now) {
+		t.Fatal("newer event not applied")
+	}
+	if b.claim(billingTestEvent(t, "evt_2", "sub_1", 200), now) {
+		t.Fatal("event older than the last applied one was applied")
+	}
+	// Ordering is per subscription
+	if !b.claim(billingTestEvent(t, "evt_4", "sub_2", 50), now) {
+		t.Fatal("older event for another subscription not applied")
+	}
+	// Retried ids are forgotten only after the retention window
+	if !b.claim(billingTestEvent(t, "evt_3", "sub_1", 300), now.Add(billingEventRetention+time.Hour)) {
+		t.Fatal("event id remembered past retention")
+	}
+}
+
+func TestBillingTierFor(t *testing.T) {
+	b := newBillingState(config.Config{BillingPriceTiers: []string{"price_pro=pro", "price_biz=Business", "price_ent=enterprise", "bad"}}, zap.NewNop())
+
+	sub := func(status string, metadataTier string, prices ...string) billingSubscription {
+		var s billingSubscription
+		s.Status = status
+		for _, p := range prices {
+			item := struct {
+				Price struct {
+					ID string `json:"id"`
+				} `json:"price"`
+			}{}
+			item.Price.ID = p
+			s.Items.Data = append(s.Items.Data, item)
+		}
+		if metadataTier != "" {
+			s.Metadata = map[string]string{"tier": metadataTier}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		sub       billingSubscription
+		tier      config.Tier
+		ok        bool
+	}{
+		{"active", "customer.subscription.updated", sub("active", "", "price_pro"), config.TierPro, true},
+		{"trialing", "customer.subscription.created", sub("trialing", "", "price_biz"), config.TierBusiness, true},
+		{"deleted", "customer.subscription.deleted", sub("active", "", "price_ent"), config.TierFree, true},
+		{"past_due", "customer.subscription.updated", sub("past_due", "", "price_ent"), config.TierFree, true},
+		{"canceled", "customer.subscription.updated", sub("canceled", "", "price_pro"), config.TierFree, true},
+		{"multi-price takes the highest", "customer.subscription.updated", sub("active", "", "price_pro", "price_ent", "price_biz"), config.TierEnterprise, true},
+		{"unmapped prices ignored beside mapped ones", "customer.subscription.updated", sub("active", "", "price_other", "price_pro"), config.TierPro, true},
+		{"metadata fallback", "customer.subscription.updated", sub("active", "Turbo", "price_other"), config.TierTurbo, true},
+		{"unmapped", "customer.subscription.updated", sub("active", "", "price_other"), "", false},
+		{"unknown metadata tier", "customer.subscription.updated", sub("active", "platinum", "price_other"), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier, ok := b.tierFor(tt.eventType, tt.sub)
+			if ok != tt.ok || (ok && tier != tt.tier) {
+				t.Fatalf("tierFor = %q, %v; want %q, %v", tier, ok, tt.tier, tt.ok)
+			}
+		})
+	}
+}
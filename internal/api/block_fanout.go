@@ -0,0 +1,122 @@
+// Package api provides block bus wiring for the API server
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockbus"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"go.uber.org/zap"
+)
+
+// ===== BLOCK BUS WIRING =====
+
+// streamReplayDefault is the number of recent events a new stream client receives
+// before live delivery, so clients see the current tip without waiting a block interval.
+const streamReplayDefault = 1
+
+// BlockBus exposes the server's block bus so producers can publish directly
+func (s *Server) BlockBus() *blockbus.Bus {
+	return s.bus
+}
+
+// PublishBlock publishes a block event to all bus subscribers
+func (s *Server) PublishBlock(event blocks.BlockEvent) {
+	if s.bus != nil {
+		s.bus.Publish(event)
+	}
+}
+
+// startBlockBus pumps the legacy producer channel into the bus and attaches
+// the cache and analytics consumers. Everything stops when ctx is cancelled.
+func (s *Server) startBlockBus(ctx context.Context) {
+	if s.bus == nil {
+		return
+	}
+
+	if s.blockChan != nil {
+		go s.pumpBlockChan(ctx)
+	}
+
+	if s.cache != nil {
+		go s.consumeBlocks(ctx, "cache", func(event blocks.BlockEvent) {
+			if err := s.cache.SetLatestBlock(event); err != nil {
+				s.logger.Debug("Failed to cache latest block", zap.Error(err))
+			}
+		})
+	}
+
+	if s.predictor != nil {
+		go s.consumeBlocks(ctx, "analytics", func(event blocks.BlockEvent) {
+			if event.IsHeader || event.TxID != "" {
+				return
+			}
+			s.predictor.RecordBlock(normalizeChainName(string(event.Chain)), int64(event.Height), 0)
+		})
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.bus.Close()
+	}()
+}
+
+// pumpBlockChan forwards events from the producer channel into the bus
+func (s *Server) pumpBlockChan(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-s.blockChan:
+			if !ok {
+				return
+			}
+			s.bus.Publish(event)
+		}
+	}
+}
+
+// consumeBlocks runs fn for every event delivered to a named bus subscriber
+func (s *Server) consumeBlocks(ctx context.Context, name string, fn func(blocks.BlockEvent)) {
+	sub := s.bus.Subscribe(blockbus.SubscribeOptions{Name: name})
+	defer s.bus.Unsubscribe(sub)
+
+	for {
+		event, err := sub.Next(ctx)
+		if err != nil {
+			if dropped := sub.Dropped(); dropped > 0 {
+				s.logger.Info("Block bus consumer stopped",
+					zap.String("subscriber", name),
+					zap.Uint64("dropped", dropped))
+			}
+			return
+		}
+		fn(event)
+	}
+}
+
+// blockMatchesChain reports whether an event belongs to the requested chain.
+// Events without a chain predate multi-chain support and are treated as bitcoin.
+func blockMatchesChain(event blocks.BlockEvent, chain string) bool {
+	want := normalizeChainName(chain)
+	have := string(event.Chain)
+	if have == "" {
+		have = string(blocks.ChainBitcoin)
+	}
+	return want == normalizeChainName(have)
+}
+
+// normalizeChainName maps short chain aliases to their canonical names
+func normalizeChainName(chain string) string {
+	switch strings.ToLower(chain) {
+	case "btc", "bitcoin":
+		return string(blocks.ChainBitcoin)
+	case "eth", "ethereum":
+		return string(blocks.ChainEthereum)
+	case "sol", "solana":
+		return string(blocks.ChainSolana)
+	default:
+		return strings.ToLower(chain)
+	}
+}
@@ -0,0 +1,206 @@
+// Package api provides historical block lookups
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockindex"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/fastpath"
+	"go.uber.org/zap"
+)
+
+// ===== BLOCK INDEX =====
+
+// startBlockIndex opens the local block history and feeds it from the
+// block bus until ctx is cancelled
+func (s *Server) startBlockIndex(ctx context.Context) {
+	if !s.cfg.BlockIndexEnabled || s.bus == nil {
+		s.logger.Info("Block index disabled")
+		return
+	}
+
+	cfg := blockindex.DefaultConfig()
+	if s.cfg.BlockIndexRetentionDays > 0 {
+		cfg.Retention = time.Duration(s.cfg.BlockIndexRetentionDays) * 24 * time.Hour
+	}
+	index, err := blockindex.Open(s.cfg.BlockIndexPath, cfg, s.logger)
+	if errors.Is(err, blockindex.ErrLocked) {
+		// The previous binary still holds the file during a reload; take
+		// over once it exits
+		s.logger.Info("Block index locked, waiting for previous process", zap.String("path", s.cfg.BlockIndexPath))
+		go s.openBlockIndexWhenFree(ctx, cfg)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to open block index", zap.Error(err))
+		return
+	}
+	s.attachBlockIndex(ctx, index)
+}
+
+// openBlockIndexWhenFree retries opening a locked block index until it
+// succeeds or ctx is cancelled
+func (s *Server) openBlockIndexWhenFree(ctx context.Context, cfg blockindex.Config) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		index, err := blockindex.Open(s.cfg.BlockIndexPath, cfg, s.logger)
+		if errors.Is(err, blockindex.ErrLocked) {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to open block index", zap.Error(err))
+			return
+		}
+		s.attachBlockIndex(ctx, index)
+		s.logger.Info("Block index opened after previous process released it")
+		return
+	}
+}
+
+// attachBlockIndex feeds index from the block bus and closes it when ctx
+// is cancelled
+func (s *Server) attachBlockIndex(ctx context.Context, index *blockindex.Index) {
+	s.blockIndex.Store(index)
+
+	go s.consumeBlocks(ctx, "blockindex", func(event blocks.BlockEvent) {
+		if err := index.Put(event); err != nil {
+			s.logger.Warn("Failed to index block", zap.String("hash", event.Hash), zap.Error(err))
+		}
+	})
+	go index.RunPruner(ctx)
+	go func() {
+		<-ctx.Done()
+		s.blockIndex.Store(nil)
+		index.Close()
+	}()
+}
+
+// ===== BLOCK INDEX HANDLERS =====
+
+// chainBlocksHandler serves /v1/{chain}/blocks?from_height=&to_height=
+// with the standard list parameters; a cursor replaces from_height
+func (s *Server) chainBlocksHandler(chain string, w http.ResponseWriter, r *http.Request) {
+	index, ok := s.blockIndexRequest(w, r)
+	if !ok {
+		return
+	}
+	p, ok := s.listRequest(w, r, s.getCustomerTierFromContext(r))
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	fromParam := q.Get("from_height")
+	if p.Cursor != "" {
+		fromParam = p.Cursor
+	}
+	from, err := strconv.ParseUint(fromParam, 10, 32)
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+			"error": "from_height must be a block height",
+		})
+		return
+	}
+	pageSize := index.MaxRange()
+	if p.Limit < pageSize {
+		pageSize = p.Limit
+	}
+	to := from + uint64(index.MaxRange()) - 1
+	if v := q.Get("to_height"); v != "" {
+		if to, err = strconv.ParseUint(v, 10, 32); err != nil || to < from {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+				"error": "to_height must be a block height not below from_height",
+			})
+			return
+		}
+	}
+
+	chain = normalizeChainName(chain)
+	found, err := index.Range(chain, from, to)
+	if err != nil {
+		s.logger.Error("Block index range query failed", zap.String("chain", chain), zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{
+			"error": "Block index query failed",
+		})
+		return
+	}
+
+	if len(found) > pageSize {
+		found = found[:pageSize]
+	}
+
+	extra := map[string]interface{}{
+		"chain":       chain,
+		"from_height": from,
+		"to_height":   to,
+		"timestamp":   s.clock.Now().UTC().Format(time.RFC3339),
+	}
+	// A full page may have stopped short of to_height
+	var cursor string
+	if len(found) == pageSize && uint64(found[len(found)-1].Height) < to {
+		next := found[len(found)-1].Height + 1
+		extra["next_from_height"] = next
+		cursor = encodeCursor(strconv.FormatUint(uint64(next), 10))
+	}
+	writeList(s, w, "blocks", found, p, cursor, extra)
+}
+
+// chainBlockHandler serves /v1/{chain}/block/{hash}
+func (s *Server) chainBlockHandler(chain string, rest []string, w http.ResponseWriter, r *http.Request) {
+	index, ok := s.blockIndexRequest(w, r)
+	if !ok {
+		return
+	}
+	if len(rest) == 0 || rest[0] == "" {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+			"error": "Missing block hash. Use /v1/{chain}/block/{hash}",
+		})
+		return
+	}
+
+	chain = normalizeChainName(chain)
+	block, err := index.ByHash(chain, rest[0])
+	switch {
+	case errors.Is(err, blockindex.ErrNotFound):
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{
+			"error": "Block not found in index",
+		})
+	case err != nil:
+		s.logger.Error("Block index lookup failed", zap.String("chain", chain), zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{
+			"error": "Block index query failed",
+		})
+	default:
+		s.conditionalJSON(w, r, fastpath.BlockETag(chain, block.Hash), block)
+	}
+}
+
+// blockIndexRequest returns the open block index, or rejects requests it
+// cannot serve
+func (s *Server) blockIndexRequest(w http.ResponseWriter, r *http.Request) (*blockindex.Index, bool) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": "Method not allowed",
+		})
+		return nil, false
+	}
+	index := s.blockIndex.Load()
+	if index == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "Block index is not available",
+		})
+		return nil, false
+	}
+	return index, true
+}
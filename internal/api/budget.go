@@ -0,0 +1,119 @@
+// Package api provides caller-supplied latency budgets
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ===== CALLER BUDGETS =====
+
+// Services calling us under their own SLA send X-Sprint-Budget-Ms with the
+// time they can still spare. It tightens the request deadline (never
+// extends the tier's), so relay calls and cache loads running under the
+// request context stop when the caller would stop waiting. A request that
+// runs out of the caller's budget answers 504 with code "budget_exceeded"
+// and whatever partial data the handler gathered, instead of a late
+// answer nobody reads. Responses carry X-Sprint-Budget-Remaining-Ms for
+// the caller to pass on downstream.
+
+const (
+	headerBudget          = "X-Sprint-Budget-Ms"
+	headerBudgetRemaining = "X-Sprint-Budget-Remaining-Ms"
+
+	maxCallerBudget = 5 * time.Minute
+)
+
+var callerBudgetExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_caller_budget_exceeded_total",
+	Help: "Requests that ran out of the budget set by X-Sprint-Budget-Ms",
+}, []string{"tier"})
+
+// callerBudget is the budget a request arrived with
+type callerBudget struct {
+	budget   time.Duration
+	deadline time.Time
+	binding  bool // Tighter than the tier deadline, so it ends the request
+}
+
+type callerBudgetKey struct{}
+
+// parseCallerBudget reads X-Sprint-Budget-Ms; ok is false without one
+func parseCallerBudget(r *http.Request) (budget time.Duration, ok bool, err error) {
+	v := r.Header.Get(headerBudget)
+	if v == "" {
+		return 0, false, nil
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false, fmt.Errorf("%s must be a positive number of milliseconds", headerBudget)
+	}
+	return min(time.Duration(ms)*time.Millisecond, maxCallerBudget), true, nil
+}
+
+// callerBudgetFrom returns the request's caller budget, if it has one
+func callerBudgetFrom(ctx context.Context) (*callerBudget, bool) {
+	b, ok := ctx.Value(callerBudgetKey{}).(*callerBudget)
+	return b, ok
+}
+
+// budgetExceeded reports whether ctx ended because the caller's budget
+// ran out
+func budgetExceeded(ctx context.Context) bool {
+	b, ok := callerBudgetFrom(ctx)
+	return ok && b.binding && errors.Is(ctx.Err(), context.DeadlineExceeded) && !time.Now().Before(b.deadline)
+}
+
+// writeBudgetExceeded answers a request out of budget, with partial data
+// when the handler has any
+func (s *Server) writeBudgetExceeded(w http.ResponseWriter, r *http.Request, partial interface{}) {
+	b, _ := callerBudgetFrom(r.Context())
+	callerBudgetExceeded.WithLabelValues(string(s.requestTier(r))).Inc()
+
+	body := map[string]interface{}{
+		"error":      "latency budget exceeded",
+		"code":       "budget_exceeded",
+		"budget_ms":  b.budget.Milliseconds(),
+		"elapsed_ms": (b.budget - time.Until(b.deadline)).Milliseconds(),
+	}
+	if partial != nil {
+		body["partial"] = partial
+	}
+	s.jsonResponse(w, http.StatusGatewayTimeout, body)
+}
+
+// budgetWriter reports the caller's remaining budget with the response
+// and records whether one was written
+type budgetWriter struct {
+	http.ResponseWriter
+	deadline time.Time
+	written  bool
+}
+
+func (bw *budgetWriter) WriteHeader(code int) {
+	if !bw.written {
+		bw.written = true
+		remaining := max(time.Until(bw.deadline), 0)
+		bw.Header().Set(headerBudgetRemaining, strconv.FormatInt(remaining.Milliseconds(), 10))
+	}
+	bw.ResponseWriter.WriteHeader(code)
+}
+
+func (bw *budgetWriter) Write(p []byte) (int, error) {
+	if !bw.written {
+		bw.WriteHeader(http.StatusOK)
+	}
+	return bw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (bw *budgetWriter) Unwrap() http.ResponseWriter {
+	return bw.ResponseWriter
+}
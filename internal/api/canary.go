@@ -0,0 +1,460 @@
+// Package api provides canary checks that compare upstream sources
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== CANARY CHECKS =====
+
+// Health checks ask each component whether it is up. They cannot see a
+// relay that is connected but following a fork, or a provider that
+// answers promptly from a tip minutes old. Every CanaryInterval the
+// canary asks each source of a chain for its latest block, and the
+// sources that can look blocks up by height for one block a few heights
+// back, and compares the answers. A disagreement is logged, counted,
+// raised on /admin/ui and reported by /health until the sources agree.
+
+const (
+	canaryTimeout      = 10 * time.Second // Per lookup
+	canaryHistoryDepth = 6                // Heights below the tip for the historical lookup
+	canaryHashGrace    = 2 * time.Minute  // How long a P2P tip may be unknown to the other sources
+)
+
+// canaryTolerance is how many heights a source may trail the highest
+// answer and still agree; new blocks reach sources at different times
+var canaryTolerance = map[string]uint32{
+	string(blocks.ChainBitcoin):  1,
+	string(blocks.ChainEthereum): 2,
+	string(blocks.ChainSolana):   64,
+}
+
+var (
+	canaryLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "canary_latency_seconds",
+			Help:    "End-to-end latency of canary lookups by chain, source and check",
+			Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"chain", "source", "check"},
+	)
+
+	canaryLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "canary_lookups_total",
+			Help: "Canary lookups by chain, source, check and result",
+		},
+		[]string{"chain", "source", "check", "result"},
+	)
+
+	canaryDiscrepancies = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "canary_discrepancies_total",
+			Help: "Disagreements between sources found by canary runs",
+		},
+		[]string{"chain", "kind"}, // kind behind, fork, history, p2p
+	)
+
+	canarySourcesAgree = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "canary_sources_agree",
+			Help: "1 while the chain's sources agreed on the last canary run",
+		},
+		[]string{"chain"},
+	)
+)
+
+// canaryLookup fetches one block from a source
+type canaryLookup func(ctx context.Context) (blocks.BlockEvent, error)
+
+// canarySource is one source of a chain's latest block
+type canarySource struct {
+	name   string
+	latest canaryLookup
+}
+
+// CanaryProbe is one source's answer to one canary check
+type CanaryProbe struct {
+	Check     string  `json:"check"` // latest or historical
+	Source    string  `json:"source"`
+	Height    uint32  `json:"height,omitempty"`
+	Hash      string  `json:"hash,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+
+	detected time.Time // When a hash-only answer was first seen
+}
+
+// CanaryStatus is a chain's last canary run
+type CanaryStatus struct {
+	Chain            string        `json:"chain"`
+	CheckedAt        time.Time     `json:"checked_at"`
+	Agree            bool          `json:"agree"`
+	Discrepancies    []string      `json:"discrepancies,omitempty"`
+	Probes           []CanaryProbe `json:"probes"`
+	DisagreeingSince *time.Time    `json:"disagreeing_since,omitempty"`
+	Disagreements    uint64        `json:"disagreements"` // Runs that found a discrepancy
+}
+
+// canaryDiscrepancy is one disagreement found by a run
+type canaryDiscrepancy struct {
+	kind    string
+	message string
+}
+
+// canaryRunner holds the sources registered from outside the server, the
+// P2P tip seen on the block bus and each chain's last run
+type canaryRunner struct {
+	mu      sync.Mutex
+	extra   map[string][]canarySource
+	p2pTip  blocks.BlockEvent
+	p2pSeen time.Time
+	status  map[string]*CanaryStatus
+}
+
+func newCanaryRunner() *canaryRunner {
+	return &canaryRunner{
+		extra:  make(map[string][]canarySource),
+		status: make(map[string]*CanaryStatus),
+	}
+}
+
+// observe records a block announced by the P2P client. P2P events carry a
+// hash but no height, so the P2P tip is compared by hash.
+func (c *canaryRunner) observe(event blocks.BlockEvent, now time.Time) {
+	if event.TxID != "" || event.Backfilled || !strings.HasPrefix(event.Source, "p2p") {
+		return
+	}
+	c.mu.Lock()
+	c.p2pTip = event
+	c.p2pSeen = now
+	c.mu.Unlock()
+}
+
+// AddCanarySource adds a source of chain's latest block ("btc",
+// "ethereum", ...) to the canary, for upstreams the server does not own
+func (s *Server) AddCanarySource(chain, name string, latest func(context.Context) (blocks.BlockEvent, error)) {
+	chain = normalizeChainName(chain)
+	s.canary.mu.Lock()
+	s.canary.extra[chain] = append(s.canary.extra[chain], canarySource{name: name, latest: latest})
+	s.canary.mu.Unlock()
+}
+
+// CanaryStatus returns each chain's last canary run
+func (s *Server) CanaryStatus() []CanaryStatus {
+	s.canary.mu.Lock()
+	defer s.canary.mu.Unlock()
+	out := make([]CanaryStatus, 0, len(s.canary.status))
+	for _, st := range s.canary.status {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Chain < out[j].Chain })
+	return out
+}
+
+// canarySources returns the sources of chain's latest block: its backend,
+// its relay, the P2P tip for Bitcoin and any added with AddCanarySource
+func (s *Server) canarySources(chain string) []canarySource {
+	var sources []canarySource
+	if backend, ok := s.backends.Get(chain); ok {
+		sources = append(sources, canarySource{name: "backend", latest: func(context.Context) (blocks.BlockEvent, error) {
+			return backend.GetLatestBlock()
+		}})
+	}
+	if rc := s.canaryRelay(chain); rc != nil {
+		sources = append(sources, canarySource{name: "relay", latest: func(context.Context) (blocks.BlockEvent, error) {
+			ev, err := rc.GetLatestBlock()
+			if err != nil {
+				return blocks.BlockEvent{}, err
+			}
+			return *ev, nil
+		}})
+	}
+
+	s.canary.mu.Lock()
+	defer s.canary.mu.Unlock()
+	if chain == string(blocks.ChainBitcoin) && !s.canary.p2pSeen.IsZero() {
+		tip, seen := s.canary.p2pTip, s.canary.p2pSeen
+		sources = append(sources, canarySource{name: "p2p", latest: func(context.Context) (blocks.BlockEvent, error) {
+			tip.DetectedAt = seen
+			return tip, nil
+		}})
+	}
+	return append(sources, s.canary.extra[chain]...)
+}
+
+// canaryRelay returns the relay serving chain, nil if none
+func (s *Server) canaryRelay(chain string) interface {
+	GetLatestBlock() (*blocks.BlockEvent, error)
+	GetBlockByHeight(height uint64) (*blocks.BlockEvent, error)
+} {
+	switch {
+	case chain == string(blocks.ChainEthereum) && s.ethereumRelay != nil:
+		return s.ethereumRelay
+	case chain == string(blocks.ChainSolana) && s.solanaRelay != nil:
+		return s.solanaRelay
+	}
+	return nil
+}
+
+// canaryProbe runs one lookup under canaryTimeout. Backends and relays take
+// no context, so a lookup that overruns is abandoned rather than cancelled.
+func canaryProbe(ctx context.Context, chain, check, source string, lookup canaryLookup) CanaryProbe {
+	ctx, cancel := context.WithTimeout(ctx, canaryTimeout)
+	defer cancel()
+
+	type answer struct {
+		event blocks.BlockEvent
+		err   error
+	}
+	done := make(chan answer, 1)
+	start := time.Now()
+	go func() {
+		ev, err := lookup(ctx)
+		done <- answer{ev, err}
+	}()
+	var a answer
+	select {
+	case a = <-done:
+	case <-ctx.Done():
+		a.err = ctx.Err()
+	}
+	elapsed := time.Since(start)
+
+	p := CanaryProbe{Check: check, Source: source, LatencyMs: float64(elapsed.Microseconds()) / 1000}
+	canaryLatency.WithLabelValues(chain, source, check).Observe(elapsed.Seconds())
+	if a.err != nil {
+		p.Error = a.err.Error()
+		canaryLookups.WithLabelValues(chain, source, check, "error").Inc()
+		return p
+	}
+	canaryLookups.WithLabelValues(chain, source, check, "ok").Inc()
+	p.Height, p.Hash, p.detected = a.event.Height, a.event.Hash, a.event.DetectedAt
+	return p
+}
+
+// probeAll runs lookups concurrently, returning the probes in source order
+func probeAll(ctx context.Context, chain, check string, sources []canarySource) []CanaryProbe {
+	probes := make([]CanaryProbe, len(sources))
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func(i int, src canarySource) {
+			defer wg.Done()
+			probes[i] = canaryProbe(ctx, chain, check, src.name, src.latest)
+		}(i, src)
+	}
+	wg.Wait()
+	return probes
+}
+
+// compareLatest finds sources trailing the highest answer by more than the
+// chain's tolerance, sources on different blocks at the same height, and a
+// P2P tip the other sources have not heard of within canaryHashGrace
+func compareLatest(chain string, probes []CanaryProbe, now time.Time) []canaryDiscrepancy {
+	var top *CanaryProbe
+	hashes := make(map[string]bool)
+	for i := range probes {
+		p := &probes[i]
+		if p.Error != "" || p.Height == 0 {
+			continue
+		}
+		hashes[strings.ToLower(p.Hash)] = true
+		if top == nil || p.Height > top.Height {
+			top = p
+		}
+	}
+
+	var found []canaryDiscrepancy
+	byHeight := make(map[uint32]*CanaryProbe)
+	for i := range probes {
+		p := &probes[i]
+		switch {
+		case p.Error != "":
+		case p.Height == 0:
+			if top != nil && !hashes[strings.ToLower(p.Hash)] && now.Sub(p.detected) > canaryHashGrace {
+				found = append(found, canaryDiscrepancy{"p2p", fmt.Sprintf(
+					"%s tip %s unknown to other sources after %s", p.Source, p.Hash, canaryHashGrace)})
+			}
+		case top.Height-p.Height > canaryTolerance[chain]:
+			found = append(found, canaryDiscrepancy{"behind", fmt.Sprintf(
+				"%s at height %d, %s at %d", p.Source, p.Height, top.Source, top.Height)})
+		default:
+			if other, ok := byHeight[p.Height]; ok && !strings.EqualFold(other.Hash, p.Hash) {
+				found = append(found, canaryDiscrepancy{"fork", fmt.Sprintf(
+					"%s and %s disagree on block %d: %s vs %s", other.Source, p.Source, p.Height, other.Hash, p.Hash)})
+			} else if !ok {
+				byHeight[p.Height] = p
+			}
+		}
+	}
+	return found
+}
+
+// compareHistory finds sources answering the historical lookup with
+// different blocks
+func compareHistory(probes []CanaryProbe) []canaryDiscrepancy {
+	var first *CanaryProbe
+	var found []canaryDiscrepancy
+	for i := range probes {
+		p := &probes[i]
+		if p.Error != "" {
+			continue
+		}
+		if first == nil {
+			first = p
+			continue
+		}
+		if !strings.EqualFold(first.Hash, p.Hash) {
+			found = append(found, canaryDiscrepancy{"history", fmt.Sprintf(
+				"%s and %s disagree on block %d: %s vs %s", first.Source, p.Source, p.Height, first.Hash, p.Hash)})
+		}
+	}
+	return found
+}
+
+// historySources returns the sources that can look chain's blocks up by
+// height: its relay and the local block index
+func (s *Server) historySources(chain string, height uint32) []canarySource {
+	var sources []canarySource
+	if rc := s.canaryRelay(chain); rc != nil {
+		sources = append(sources, canarySource{name: "relay", latest: func(context.Context) (blocks.BlockEvent, error) {
+			ev, err := rc.GetBlockByHeight(uint64(height))
+			if err != nil {
+				return blocks.BlockEvent{}, err
+			}
+			return *ev, nil
+		}})
+	}
+	if index := s.blockIndex.Load(); index != nil {
+		sources = append(sources, canarySource{name: "index", latest: func(context.Context) (blocks.BlockEvent, error) {
+			found, err := index.Range(chain, uint64(height), uint64(height))
+			if err != nil {
+				return blocks.BlockEvent{}, err
+			}
+			if len(found) == 0 {
+				return blocks.BlockEvent{}, fmt.Errorf("block %d not indexed", height)
+			}
+			return found[0], nil
+		}})
+	}
+	return sources
+}
+
+// runCanary checks every chain with a source once
+func (s *Server) runCanary(ctx context.Context) {
+	for _, chain := range []blocks.Chain{blocks.ChainBitcoin, blocks.ChainEthereum, blocks.ChainSolana} {
+		sources := s.canarySources(string(chain))
+		if len(sources) == 0 {
+			continue
+		}
+		s.checkChain(ctx, string(chain), sources)
+	}
+}
+
+// checkChain runs the latest and historical checks for chain and records
+// the outcome
+func (s *Server) checkChain(ctx context.Context, chain string, sources []canarySource) {
+	probes := probeAll(ctx, chain, "latest", sources)
+	found := compareLatest(chain, probes, s.clock.Now())
+
+	var tip uint32
+	for _, p := range probes {
+		if p.Error == "" && p.Height > tip {
+			tip = p.Height
+		}
+	}
+	if tip > canaryHistoryDepth {
+		history := probeAll(ctx, chain, "historical", s.historySources(chain, tip-canaryHistoryDepth))
+		found = append(found, compareHistory(history)...)
+		probes = append(probes, history...)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	s.recordCanary(chain, probes, found)
+}
+
+// recordCanary stores a run's outcome, alerting when the chain's sources
+// start or stop disagreeing
+func (s *Server) recordCanary(chain string, probes []CanaryProbe, found []canaryDiscrepancy) {
+	now := s.clock.Now()
+	messages := make([]string, len(found))
+	for i, d := range found {
+		messages[i] = d.message
+		canaryDiscrepancies.WithLabelValues(chain, d.kind).Inc()
+	}
+
+	s.canary.mu.Lock()
+	st, ok := s.canary.status[chain]
+	if !ok {
+		st = &CanaryStatus{Chain: chain, Agree: true}
+		s.canary.status[chain] = st
+	}
+	wasAgreeing, agree := st.Agree, len(found) == 0
+	st.CheckedAt = now.UTC()
+	st.Agree = agree
+	st.Discrepancies = messages
+	st.Probes = probes
+	if !agree {
+		st.Disagreements++
+		if wasAgreeing {
+			since := now.UTC()
+			st.DisagreeingSince = &since
+		}
+	} else {
+		st.DisagreeingSince = nil
+	}
+	s.canary.mu.Unlock()
+
+	if agree {
+		canarySourcesAgree.WithLabelValues(chain).Set(1)
+		if !wasAgreeing {
+			s.logger.Info("Canary sources agree again", zap.String("chain", chain))
+			s.alerts.record(now, "canary", chain, "sources agree again")
+		}
+		return
+	}
+	canarySourcesAgree.WithLabelValues(chain).Set(0)
+	if wasAgreeing {
+		s.logger.Error("Canary sources disagree",
+			zap.String("chain", chain),
+			zap.Strings("discrepancies", messages))
+		s.alerts.record(now, "canary", chain, strings.Join(messages, "; "))
+	}
+}
+
+// startCanary watches the block bus for the P2P tip and runs the canary
+// every CanaryInterval until ctx is cancelled
+func (s *Server) startCanary(ctx context.Context) {
+	if s.cfg.CanaryInterval <= 0 {
+		return
+	}
+	if s.bus != nil {
+		go s.consumeBlocks(ctx, "canary", func(event blocks.BlockEvent) {
+			s.canary.observe(event, s.clock.Now())
+		})
+	}
+	go func() {
+		t := time.NewTicker(s.cfg.CanaryInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				s.runCanary(ctx)
+			}
+		}
+	}()
+}
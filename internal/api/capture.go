@@ -0,0 +1,79 @@
+// Package api provides traffic capture for offline circuit breaker replay
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/trafficcapture"
+	"go.uber.org/zap"
+)
+
+// ===== TRAFFIC CAPTURE =====
+
+// captureMiddleware records the timing and outcome of sampled API requests
+// to TrafficCapturePath, for `cb-loadtest -scenario replay`. It sits inside
+// load shedding and admission so the capture holds what the handlers and
+// upstreams did, not requests the server turned away itself. Health,
+// admin, stream and WebSocket traffic is not captured.
+func (s *Server) captureMiddleware(next http.Handler) http.Handler {
+	if s.cfg.TrafficCapturePath == "" {
+		return next
+	}
+
+	capture, err := trafficcapture.NewWriter(s.cfg.TrafficCapturePath, float64(s.cfg.TrafficCaptureSample)/100)
+	if err != nil {
+		s.logger.Warn("Traffic capture disabled", zap.Error(err))
+		return next
+	}
+	s.OnShutdown("traffic capture", func(context.Context) error {
+		capture.Close()
+		if n := capture.Dropped(); n > 0 {
+			s.logger.Warn("Traffic capture dropped records", zap.Int64("dropped", n))
+		}
+		return nil
+	})
+	s.logger.Info("Traffic capture enabled",
+		zap.String("path", s.cfg.TrafficCapturePath),
+		zap.Int("sample_pct", s.cfg.TrafficCaptureSample))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimRight(r.URL.Path, "/")
+		if isShedExempt(r) || r.Header.Get("Upgrade") != "" || strings.HasSuffix(path, "/stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rw, r)
+
+		capture.Write(trafficcapture.Record{
+			Time:      start.UTC(),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Chain:     captureChain(path),
+			Tier:      string(s.requestTier(r)),
+			Status:    rw.statusCode,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		})
+	})
+}
+
+// captureChain extracts the chain from /v1/{chain}/... and
+// /api/v1/universal/{chain}/... paths
+func captureChain(path string) string {
+	var rest string
+	switch {
+	case strings.HasPrefix(path, "/v1/"):
+		rest = strings.TrimPrefix(path, "/v1/")
+	case strings.HasPrefix(path, "/api/v1/universal/"):
+		rest = strings.TrimPrefix(path, "/api/v1/universal/")
+	default:
+		return ""
+	}
+	chain, _, _ := strings.Cut(rest, "/")
+	return normalizeChainName(chain)
+}
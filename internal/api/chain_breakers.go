@@ -0,0 +1,314 @@
+// Package api provides per-chain circuit breakers around backend calls
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== CHAIN BREAKERS =====
+
+// A flapping chain backend can hold every handler goroutine that calls
+// it. Each chain's backend is wrapped in its own breaker: calls time out,
+// a tripped breaker fails fast, and while it is open the last good value
+// is served instead of an error where one exists.
+
+var chainBreakerFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_chain_breaker_fallbacks_total",
+	Help: "Backend calls answered from the last good value because the chain's breaker refused or the call failed",
+}, []string{"chain", "call"})
+
+// chainBreakers holds one breaker per chain, shared by the chain's aliases
+type chainBreakers struct {
+	timeout time.Duration
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	byChain map[string]*guardedBackend
+}
+
+// newChainBreakers returns the breaker set; nil when disabled
+func newChainBreakers(s *Server) *chainBreakers {
+	if !s.cfg.ChainBreakerEnabled {
+		return nil
+	}
+	timeout := s.cfg.ChainBreakerTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+	return &chainBreakers{
+		timeout: timeout,
+		logger:  s.logger.Named("chain_breaker"),
+		byChain: make(map[string]*guardedBackend),
+	}
+}
+
+// wrap returns backend guarded by the breaker for name's chain. Aliases of
+// one chain share a breaker and its fallback values.
+func (c *chainBreakers) wrap(name string, backend ChainBackend) ChainBackend {
+	chain := normalizeChainName(name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if g, ok := c.byChain[chain]; ok {
+		g.setInner(backend)
+		return g
+	}
+	breaker, err := circuitbreaker.NewEnterpriseCircuitBreaker(circuitbreaker.Config{
+		Name:                   "chain-" + chain,
+		FailureThreshold:       0.5,
+		SuccessThreshold:       2,
+		Timeout:                c.timeout,
+		HalfOpenMaxConcurrency: 1,
+		MinSamples:             10,
+		TripStrategy:           "percentage",
+		CooldownStrategy:       "exponential",
+	})
+	if err != nil {
+		c.logger.Error("Failed to create chain breaker, calling backend directly",
+			zap.String("chain", chain), zap.Error(err))
+		return backend
+	}
+	g := &guardedBackend{chain: chain, breaker: breaker, inner: backend}
+	c.byChain[chain] = g
+	return g
+}
+
+// guarded returns the guarded backend for a chain or its breaker's name
+func (c *chainBreakers) guarded(name string) (*guardedBackend, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g, ok := c.byChain[normalizeChainName(strings.TrimPrefix(name, "chain-"))]
+	return g, ok
+}
+
+// Breakers returns each chain's breaker by chain name
+func (c *chainBreakers) Breakers() map[string]*circuitbreaker.EnterpriseCircuitBreaker {
+	out := make(map[string]*circuitbreaker.EnterpriseCircuitBreaker)
+	if c == nil {
+		return out
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for chain, g := range c.byChain {
+		out[chain] = g.breaker
+	}
+	return out
+}
+
+// shutdown stops the breakers' background workers
+func (c *chainBreakers) shutdown(ctx context.Context) error {
+	for _, b := range c.Breakers() {
+		if err := b.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// guardedBackend is a ChainBackend whose calls pass through a breaker
+type guardedBackend struct {
+	chain   string
+	breaker *circuitbreaker.EnterpriseCircuitBreaker
+
+	fault atomic.Pointer[chainFault] // Injected through the admin API, for chaos runs
+
+	mu         sync.RWMutex
+	inner      ChainBackend
+	lastBlock  *blocks.BlockEvent
+	lastStatus map[string]interface{}
+	lastPool   int
+	lastETA    float64
+}
+
+func (g *guardedBackend) setInner(b ChainBackend) {
+	g.mu.Lock()
+	g.inner = b
+	g.mu.Unlock()
+}
+
+func (g *guardedBackend) backend() ChainBackend {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.inner
+}
+
+// guardedCall runs fn through g's breaker. The result is only read once
+// fn has returned; a call abandoned at the timeout keeps running in the
+// breaker's goroutine but its value is discarded.
+func guardedCall[T any](g *guardedBackend, fn func() (T, error)) (T, error) {
+	var value T
+	res, err := g.breaker.Execute(func() (interface{}, error) {
+		if err := g.injectFault(); err != nil {
+			return nil, err
+		}
+		v, err := fn()
+		value = v
+		return nil, err
+	})
+	var zero T
+	switch {
+	case err != nil:
+		return zero, err
+	case !res.Success:
+		return zero, res.Error
+	}
+	return value, nil
+}
+
+// GetLatestBlock implements ChainBackend, falling back to the last block seen
+func (g *guardedBackend) GetLatestBlock() (blocks.BlockEvent, error) {
+	block, err := guardedCall(g, g.backend().GetLatestBlock)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err == nil {
+		g.lastBlock = &block
+		return block, nil
+	}
+	if g.lastBlock != nil {
+		chainBreakerFallbacks.WithLabelValues(g.chain, "latest_block").Inc()
+		return *g.lastBlock, nil
+	}
+	return blocks.BlockEvent{}, err
+}
+
+// GetMempoolSize implements ChainBackend, falling back to the last size seen
+func (g *guardedBackend) GetMempoolSize() int {
+	size, err := guardedCall(g, func() (int, error) { return g.backend().GetMempoolSize(), nil })
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		chainBreakerFallbacks.WithLabelValues(g.chain, "mempool_size").Inc()
+		return g.lastPool
+	}
+	g.lastPool = size
+	return size
+}
+
+// GetStatus implements ChainBackend. The breaker state is added to the
+// status, and a status served from the last good one is marked stale.
+func (g *guardedBackend) GetStatus() map[string]interface{} {
+	status, err := guardedCall(g, func() (map[string]interface{}, error) { return g.backend().GetStatus(), nil })
+	g.mu.Lock()
+	if err == nil {
+		g.lastStatus = status
+	} else {
+		chainBreakerFallbacks.WithLabelValues(g.chain, "status").Inc()
+	}
+	last := g.lastStatus
+	g.mu.Unlock()
+
+	out := make(map[string]interface{}, len(last)+2)
+	for k, v := range last {
+		out[k] = v
+	}
+	out["circuit_breaker"] = g.breaker.State().String()
+	if err != nil {
+		out["stale"] = true
+		if last == nil {
+			out["chain"] = g.chain
+			out["status"] = "unavailable"
+		}
+	}
+	return out
+}
+
+// GetPredictiveETA implements ChainBackend, falling back to the last estimate
+func (g *guardedBackend) GetPredictiveETA() float64 {
+	eta, err := guardedCall(g, func() (float64, error) { return g.backend().GetPredictiveETA(), nil })
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		chainBreakerFallbacks.WithLabelValues(g.chain, "predictive_eta").Inc()
+		return g.lastETA
+	}
+	g.lastETA = eta
+	return eta
+}
+
+// StreamBlocks implements ChainBackend. A stream is long-lived, so only
+// its start is guarded: an open breaker refuses new streams.
+func (g *guardedBackend) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
+	if !g.breaker.Allow() {
+		return fmt.Errorf("%s backend unavailable: circuit breaker is %s", g.chain, g.breaker.State())
+	}
+	return g.backend().StreamBlocks(ctx, blockChan)
+}
+
+// ChainBreakers returns the breaker guarding each chain's backend by chain
+// name; empty when disabled
+func (s *Server) ChainBreakers() map[string]*circuitbreaker.EnterpriseCircuitBreaker {
+	return s.backends.breakers.Breakers()
+}
+
+// readiness is whether the server should receive traffic, as served by
+// /readyz and shown on /admin/ui
+type readiness struct {
+	Status   string            // ready, draining or unavailable
+	Code     int               // HTTP status for /readyz
+	Breakers map[string]string // Breaker state by chain
+}
+
+// readiness reports whether the server should receive traffic: not while
+// draining, and not while every chain's breaker is open. One chain failing
+// leaves the others serving, so it does not make the node unready.
+func (s *Server) readiness() readiness {
+	breakers := s.ChainBreakers()
+	rd := readiness{Status: "ready", Code: http.StatusOK, Breakers: make(map[string]string, len(breakers))}
+	open := 0
+	for chain, b := range breakers {
+		state := b.State()
+		rd.Breakers[chain] = state.String()
+		if state == circuitbreaker.StateOpen || state == circuitbreaker.StateForceOpen {
+			open++
+		}
+	}
+
+	switch {
+	case s.draining.Load():
+		rd.Status, rd.Code = "draining", http.StatusServiceUnavailable
+	case len(breakers) > 0 && open == len(breakers):
+		rd.Status, rd.Code = "unavailable", http.StatusServiceUnavailable
+	}
+	return rd
+}
+
+// readyzHandler serves s.readiness
+func (s *Server) readyzHandler(w http.ResponseWriter, r *http.Request) {
+	rd := s.readiness()
+	s.jsonResponse(w, rd.Code, map[string]interface{}{
+		"status":           rd.Status,
+		"circuit_breakers": rd.Breakers,
+		"timestamp":        s.clock.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// breakersAdminHandler serves the chain breakers in the cb-monitor status
+// format, for cb-monitor -api
+func (s *Server) breakersAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	statuses := make(map[string]schemas.BreakerStatusV1)
+	for _, b := range s.ChainBreakers() {
+		name := b.CurrentConfig().Name
+		statuses[name] = schemas.NewBreakerStatus(name, b)
+	}
+	s.jsonResponse(w, http.StatusOK, statuses)
+}
@@ -0,0 +1,175 @@
+// Package api provides fault injection into chain backends for chaos runs
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== CHAIN FAULTS =====
+
+// cb-chaos -api drives the chain breakers of a running server through
+// /api/v1/admin/breakers/{name}: forcing their state, and injecting
+// errors or latency into the backend calls they guard, so the breaker
+// trips the way it would in an outage. A fault expires on its own, so an
+// interrupted chaos run does not leave a chain failing.
+
+// Injected fault types
+const (
+	faultErrors  = "errors"
+	faultLatency = "latency"
+
+	maxFaultDuration = time.Hour
+)
+
+var chainFaultsInjected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_chain_faults_injected_total",
+	Help: "Backend calls failed or delayed by a fault injected through the admin API",
+}, []string{"chain", "type"})
+
+// errInjectedFault fails backend calls under an errors fault
+var errInjectedFault = errors.New("injected fault")
+
+// chainFault is a fault active on a chain's backend calls until Until
+type chainFault struct {
+	Type        string        `json:"type"`
+	Probability float64       `json:"probability"`
+	Latency     time.Duration `json:"latency,omitempty"`
+	Until       time.Time     `json:"until"`
+}
+
+// injectFault applies g's fault, if one is active, to a backend call
+func (g *guardedBackend) injectFault() error {
+	f := g.fault.Load()
+	if f == nil || !time.Now().Before(f.Until) || rand.Float64() >= f.Probability {
+		return nil
+	}
+	chainFaultsInjected.WithLabelValues(g.chain, f.Type).Inc()
+	if f.Type == faultLatency {
+		time.Sleep(f.Latency)
+		return nil
+	}
+	return errInjectedFault
+}
+
+// breakerStateRequest is the body of POST /api/v1/admin/breakers/{name}/state
+type breakerStateRequest struct {
+	State string `json:"state"` // open, closed or reset
+}
+
+// chainFaultRequest is the body of POST /api/v1/admin/breakers/{name}/fault
+type chainFaultRequest struct {
+	Type        string   `json:"type"`        // errors or latency
+	Probability *float64 `json:"probability"` // Share of calls affected; default 1
+	Latency     string   `json:"latency"`     // Added delay for latency faults, e.g. "2s"
+	Duration    string   `json:"duration"`    // How long the fault lasts, e.g. "30s"
+}
+
+// breakerControlAdminHandler forces chain breaker states and injects
+// faults:
+//
+//	POST   /api/v1/admin/breakers/{name}/state  {"state":"open"}
+//	POST   /api/v1/admin/breakers/{name}/fault  {"type":"errors","duration":"30s"}
+//	DELETE /api/v1/admin/breakers/{name}/fault
+//
+// name is the breaker's name (e.g. "chain-bitcoin") or its chain's.
+func (s *Server) breakerControlAdminHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/breakers"), "/")
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 {
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	g, ok := s.backends.breakers.guarded(parts[0])
+	if !ok {
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": "circuit breaker not found"})
+		return
+	}
+	name := g.breaker.CurrentConfig().Name
+
+	switch {
+	case parts[1] == "state" && r.Method == http.MethodPost:
+		var req breakerStateRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		switch req.State {
+		case "open":
+			g.breaker.ForceOpen()
+		case "closed":
+			g.breaker.ForceClose()
+		case "reset":
+			g.breaker.Reset()
+		default:
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "state must be open, closed or reset"})
+			return
+		}
+		s.requestLogger(r).Info("Chain breaker state set via admin API",
+			zap.String("breaker", name), zap.String("state", req.State))
+		s.jsonResponse(w, http.StatusOK, map[string]string{"name": name, "state": g.breaker.State().String()})
+
+	case parts[1] == "fault" && r.Method == http.MethodPost:
+		var req chainFaultRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		fault, err := req.fault()
+		if err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		g.fault.Store(fault)
+		s.requestLogger(r).Info("Chain fault injected via admin API",
+			zap.String("breaker", name), zap.String("type", fault.Type),
+			zap.Float64("probability", fault.Probability), zap.Time("until", fault.Until))
+		s.jsonResponse(w, http.StatusOK, fault)
+
+	case parts[1] == "fault" && r.Method == http.MethodDelete:
+		g.fault.Store(nil)
+		s.requestLogger(r).Info("Chain fault cleared via admin API", zap.String("breaker", name))
+		s.jsonResponse(w, http.StatusOK, map[string]string{"name": name, "status": "cleared"})
+
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// fault validates the request
+func (req chainFaultRequest) fault() (*chainFault, error) {
+	f := &chainFault{Type: req.Type, Probability: 1}
+	if req.Probability != nil {
+		if *req.Probability <= 0 || *req.Probability > 1 {
+			return nil, errors.New("probability must be in (0, 1]")
+		}
+		f.Probability = *req.Probability
+	}
+
+	switch req.Type {
+	case faultErrors:
+	case faultLatency:
+		latency, err := time.ParseDuration(req.Latency)
+		if err != nil || latency <= 0 {
+			return nil, errors.New("latency must be a positive duration")
+		}
+		f.Latency = latency
+	default:
+		return nil, errors.New("type must be errors or latency")
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 || duration > maxFaultDuration {
+		return nil, errors.New("duration must be a positive duration of at most 1h")
+	}
+	f.Until = time.Now().Add(duration)
+	return f, nil
+}
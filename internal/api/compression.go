@@ -0,0 +1,289 @@
+// Package api provides negotiated response compression
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ===== COMPRESSION METRICS =====
+
+var (
+	compressionResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_compression_responses_total",
+		Help: "Responses by negotiated content encoding (identity = not compressed)",
+	}, []string{"encoding"})
+	compressionBytesIn = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_compression_bytes_in_total",
+		Help: "Response bytes before compression by encoding",
+	}, []string{"encoding"})
+	compressionBytesSaved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_compression_bytes_saved_total",
+		Help: "Response bytes saved by compression by encoding",
+	}, []string{"encoding"})
+)
+
+// ===== ENCODER POOLS =====
+
+const (
+	encodingGzip     = "gzip"
+	encodingBrotli   = "br"
+	encodingIdentity = "identity"
+)
+
+// compressor is the subset of gzip.Writer and brotli.Writer the
+// middleware needs
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+var compressorPools = map[string]*sync.Pool{
+	encodingGzip: {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}},
+	// Quality 4 keeps brotli CPU cost close to gzip's default level
+	encodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, 4)
+	}},
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header,
+// honouring q-values and preferring br on a tie. It returns "" if neither
+// is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		switch name {
+		case encodingBrotli, encodingGzip:
+		case "*":
+			name = encodingBrotli
+		default:
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// ===== COMPRESSION MIDDLEWARE =====
+
+// compressionMiddleware compresses responses of at least
+// CompressionMinBytes with the client's preferred encoding. WebSocket
+// upgrades and the preserialized fastpath endpoints are passed through
+// untouched; the latter are tiny and latency-critical.
+func (s *Server) compressionMiddleware(next http.Handler) http.Handler {
+	if !s.cfg.CompressionEnabled {
+		return next
+	}
+	minBytes := s.cfg.CompressionMinBytes
+	if minBytes <= 0 {
+		minBytes = 1024
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" || r.Method == http.MethodHead || s.compressionExempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		addVary(w.Header(), "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minBytes: minBytes}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// addVary appends values to the Vary header unless already listed
+func addVary(h http.Header, values ...string) {
+	present := make(map[string]bool)
+	for _, line := range h.Values("Vary") {
+		for _, v := range strings.Split(line, ",") {
+			present[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+	}
+	for _, v := range values {
+		if !present[strings.ToLower(v)] {
+			h.Add("Vary", v)
+			present[strings.ToLower(v)] = true
+		}
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether
+// the body reaches the size threshold, then either streams it through a
+// pooled encoder or writes it as is
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minBytes int
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     compressor // nil when passing through
+	counter *countingWriter
+	in      int64
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.status == 0 {
+		cw.status = code
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minBytes {
+			return len(p), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return cw.write(p)
+}
+
+// decide commits the headers, choosing compression if the buffered body
+// reached the threshold, and flushes the buffer
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	h := cw.Header()
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	compress := len(cw.buf) >= cw.minBytes &&
+		h.Get("Content-Encoding") == "" &&
+		cw.status != http.StatusNoContent && cw.status != http.StatusNotModified &&
+		!strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+	if compress {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The encoded body is a different representation
+			h.Set("ETag", "W/"+etag)
+		}
+		cw.counter = &countingWriter{w: cw.ResponseWriter}
+		cw.enc = compressorPools[cw.encoding].Get().(compressor)
+		cw.enc.Reset(cw.counter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := cw.write(buf)
+	return err
+}
+
+func (cw *compressWriter) write(p []byte) (int, error) {
+	if cw.enc == nil {
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.in += int64(len(p))
+	return cw.enc.Write(p)
+}
+
+// Flush commits whatever is buffered so streaming handlers still stream
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets handlers take over the connection before any body is written
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hj, ok := cw.ResponseWriter.(http.Hijacker); ok && !cw.decided {
+		cw.decided = true
+		return hj.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Close finishes the response and returns the encoder to its pool
+func (cw *compressWriter) Close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			// Handler wrote nothing; let net/http send its default response
+			return
+		}
+		_ = cw.decide()
+	}
+	if cw.enc == nil {
+		compressionResponses.WithLabelValues(encodingIdentity).Inc()
+		return
+	}
+
+	_ = cw.enc.Close()
+	cw.enc.Reset(io.Discard)
+	compressorPools[cw.encoding].Put(cw.enc)
+	cw.enc = nil
+
+	compressionResponses.WithLabelValues(cw.encoding).Inc()
+	compressionBytesIn.WithLabelValues(cw.encoding).Add(float64(cw.in))
+	if saved := cw.in - cw.counter.n; saved > 0 {
+		compressionBytesSaved.WithLabelValues(cw.encoding).Add(float64(saved))
+	}
+}
+
+// countingWriter counts bytes written to the client after compression
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,77 @@
+// Package api provides conditional GET support for the read endpoints
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/fastpath"
+	"go.uber.org/zap"
+)
+
+// ===== CACHE CONTROL =====
+
+// cacheControlForTier lets lower tiers be served from shared caches for a
+// few seconds, while paid low-latency tiers always revalidate so they see a
+// new block as soon as it lands. Revalidation is cheap: an unchanged block
+// answers 304 without a body.
+func cacheControlForTier(tier config.Tier) string {
+	switch tier {
+	case config.TierEnterprise, config.TierTurbo:
+		return "private, no-cache"
+	case config.TierBusiness:
+		return "private, max-age=1"
+	case config.TierPro:
+		return "private, max-age=2"
+	default:
+		return "public, max-age=5"
+	}
+}
+
+// setCacheHeaders sets the tier-aware Cache-Control header. Responses vary
+// by API key because the key decides the tier.
+func (s *Server) setCacheHeaders(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Cache-Control", cacheControlForTier(s.requestTier(r)))
+	addVary(h, "X-API-Key", "Authorization")
+}
+
+// withCacheHeaders wraps a read handler, e.g. a fastpath snapshot handler,
+// with tier-aware Cache-Control
+func (s *Server) withCacheHeaders(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.setCacheHeaders(w, r)
+		next(w, r)
+	}
+}
+
+// ===== CONDITIONAL RESPONSES =====
+
+// conditionalJSON writes data as JSON with an ETag and Cache-Control, or
+// 304 Not Modified if If-None-Match already names that ETag. An empty etag
+// is derived from the encoded body.
+func (s *Server) conditionalJSON(w http.ResponseWriter, r *http.Request, etag string, data interface{}) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		s.logger.Error("Failed to encode JSON response", zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{
+			"error": "Internal encoding error",
+		})
+		return
+	}
+	if etag == "" {
+		etag = fastpath.BodyETag(body)
+	}
+
+	s.setCacheHeaders(w, r)
+	w.Header().Set("ETag", etag)
+	if fastpath.ETagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,218 @@
+// Package api provides per-request cost accounting and monthly budgets
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== REQUEST COST ACCOUNTING =====
+
+// Methods differ in what they cost upstream: a block or log query touches
+// far more provider capacity than a slot or status lookup. Each request is
+// charged cost units from the table below, accumulated per key for the
+// calendar month and checked against the tier's MonthlyCostBudget.
+
+var (
+	costUnitsCharged = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_cost_units_total",
+			Help: "Request cost units charged by tier and method",
+		},
+		[]string{"tier", "method"},
+	)
+
+	costBudgetRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_cost_budget_exceeded_total",
+			Help: "Requests rejected because the key's monthly cost budget was spent",
+		},
+		[]string{"tier"},
+	)
+)
+
+// defaultCostUnits is charged for methods missing from the table
+const defaultCostUnits = 1
+
+// defaultMethodCosts maps request methods to cost units. Universal API
+// requests use their {method} segment; other routes are named in
+// routeCostMethods.
+var defaultMethodCosts = map[string]int64{
+	"ping":             1,
+	"peers":            1,
+	"peer_count":       1,
+	"sync":             1,
+	"sync_status":      1,
+	"status":           2,
+	"network_info":     2,
+	"latest":           2,
+	"latest_block":     2,
+	"receipt":          5,
+	"spv_proof":        10,
+	"logs":             20, // eth_getLogs range scans
+	"getmininginfo":    2,
+	"getblocktemplate": 25,
+}
+
+// routeCostMethods names the cost method of routes outside the universal API
+var routeCostMethods = map[string]string{
+	"/api/v1/spv/proof":             "spv_proof",
+	"/api/v1/bitcoin/blocktemplate": "getblocktemplate",
+	"/api/v1/bitcoin/mininginfo":    "getmininginfo",
+}
+
+// costTable resolves the cost of a request
+type costTable struct {
+	costs map[string]int64
+}
+
+// newCostTable applies METHOD_COSTS overrides ("logs=40,receipt=3") to the
+// default table; malformed entries are logged and skipped
+func newCostTable(cfg config.Config, logger *zap.Logger) *costTable {
+	costs := make(map[string]int64, len(defaultMethodCosts))
+	for method, units := range defaultMethodCosts {
+		costs[method] = units
+	}
+	for _, entry := range strings.Split(cfg.MethodCosts, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		method, value, ok := strings.Cut(entry, "=")
+		units, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if !ok || err != nil || units < 0 {
+			logger.Warn("Ignoring invalid METHOD_COSTS entry", zap.String("entry", entry))
+			continue
+		}
+		costs[strings.TrimSpace(method)] = units
+	}
+	return &costTable{costs: costs}
+}
+
+// method names the cost method of a request path
+func (ct *costTable) method(path string) string {
+	if m, ok := routeCostMethods[path]; ok {
+		return m
+	}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		if p == "universal" {
+			// /api/v1/universal/{chain}/{method}; no method means ping
+			if len(parts) > i+2 {
+				return parts[i+2]
+			}
+			return "ping"
+		}
+	}
+	return "default"
+}
+
+// units returns the cost of method
+func (ct *costTable) units(method string) int64 {
+	if units, ok := ct.costs[method]; ok {
+		return units
+	}
+	return defaultCostUnits
+}
+
+// costPeriod is the UTC calendar month now falls in
+func costPeriod(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+// costPeriodEnd is when the budget for now's month resets
+func costPeriodEnd(now time.Time) time.Time {
+	y, m, _ := now.UTC().Date()
+	return time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ChargeCost adds units to the key's spend for the current month unless
+// that would exceed budget (0 = unlimited). It returns the month's spend
+// after the charge, or the unchanged spend when the charge is refused.
+func (ckm *CustomerKeyManager) ChargeCost(key string, units, budget int64) (int64, bool) {
+	ckm.mu.Lock()
+	defer ckm.mu.Unlock()
+
+	hash := ckm.keyHashes[key]
+	customerKey, exists := ckm.keys[hash]
+	if !exists {
+		return 0, false
+	}
+
+	if period := costPeriod(ckm.clock.Now()); customerKey.CostPeriod != period {
+		customerKey.CostPeriod = period
+		customerKey.CostUnits = 0
+	}
+	if budget > 0 && customerKey.CostUnits+units > budget {
+		ckm.keys[hash] = customerKey
+		return customerKey.CostUnits, false
+	}
+	customerKey.CostUnits += units
+	ckm.keys[hash] = customerKey
+	return customerKey.CostUnits, true
+}
+
+// getTierCostBudget returns the tier's monthly cost budget (0 = unlimited)
+func (s *Server) getTierCostBudget(tier config.Tier) int64 {
+	if tierLimit, exists := s.cfg.RateLimits[tier]; exists {
+		return tierLimit.MonthlyCostBudget
+	}
+	return 0
+}
+
+// chargeRequestCost charges the request to the key's monthly budget and
+// sets the budget headers. It writes a 402 and returns false when the
+// budget is spent.
+func (s *Server) chargeRequestCost(w http.ResponseWriter, r *http.Request, apiKey string, key *CustomerKey) bool {
+	method := s.costs.method(r.URL.Path)
+	units := s.costs.units(method)
+	budget := s.getTierCostBudget(key.Tier)
+	resetAt := costPeriodEnd(s.clock.Now())
+
+	spent, ok := s.keyManager.ChargeCost(apiKey, units, budget)
+	w.Header().Set("X-Cost-Units", strconv.FormatInt(units, 10))
+	if budget > 0 {
+		remaining := budget - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-Budget-Limit", strconv.FormatInt(budget, 10))
+		w.Header().Set("X-Budget-Remaining", strconv.FormatInt(remaining, 10))
+		w.Header().Set("X-Budget-Reset", resetAt.Format(time.RFC3339))
+	}
+
+	if !ok {
+		costBudgetRejections.WithLabelValues(string(key.Tier)).Inc()
+		s.logger.Warn("Monthly cost budget exhausted",
+			zap.String("key_hash", key.Hash[:8]),
+			zap.String("tier", string(key.Tier)),
+			zap.String("method", method),
+			zap.Int64("spent", spent),
+			zap.Int64("budget", budget))
+		s.jsonResponse(w, http.StatusPaymentRequired, map[string]interface{}{
+			"error":      fmt.Sprintf("monthly cost budget exhausted: %s costs %d units, %d of %d remaining", method, units, budget-spent, budget),
+			"method":     method,
+			"cost_units": units,
+			"budget":     budget,
+			"spent":      spent,
+			"resets_at":  resetAt.Format(time.RFC3339),
+		})
+		return false
+	}
+
+	// Universal methods come from the URL; keep unknown ones out of labels
+	label := method
+	if _, known := s.costs.costs[method]; !known {
+		label = "other"
+	}
+	costUnitsCharged.WithLabelValues(string(key.Tier), label).Add(float64(units))
+	return true
+}
@@ -0,0 +1,122 @@
+// Package api provides per-request deadlines derived from tier latency targets
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ===== REQUEST DEADLINES =====
+
+var (
+	requestDeadlineExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_request_deadline_exceeded_total",
+		Help: "Requests still running when their tier deadline expired",
+	}, []string{"tier"})
+
+	requestClientCanceled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_request_client_canceled_total",
+		Help: "Requests whose client disconnected before the handler finished",
+	}, []string{"tier"})
+)
+
+// requestBudget returns how long a request may run. Streams, admin and
+// health endpoints are unbounded; log queries scan block ranges upstream
+// and keep their own, longer budget.
+func (s *Server) requestBudget(r *http.Request, tier config.Tier) (time.Duration, bool) {
+	path := strings.TrimRight(r.URL.Path, "/")
+	switch {
+	case isShedExempt(r), r.Header.Get("Upgrade") != "":
+		return 0, false
+	case strings.HasPrefix(path, "/api/v1/admin/"), strings.HasSuffix(path, "/stream"):
+		return 0, false
+	case strings.HasSuffix(path, "/logs"):
+		return ethereumLogsTimeout, true
+	}
+	return s.getChainLatencyTarget(universalChain(path), tier) + s.cfg.RequestDeadlineSlack, true
+}
+
+// universalChain returns the chain segment of a /universal/{chain} path,
+// or "" for other paths
+func universalChain(path string) string {
+	_, rest, ok := strings.Cut(path, "/universal/")
+	if !ok {
+		return ""
+	}
+	chain, _, _ := strings.Cut(rest, "/")
+	return chain
+}
+
+// deadlineMiddleware bounds each request by its tier's latency target plus
+// RequestDeadlineSlack, or by the caller's X-Sprint-Budget-Ms when that is
+// tighter. Handlers pass r.Context() to relay calls and cache loaders, so
+// upstream work stops at the deadline or as soon as the client disconnects
+// instead of finishing for nobody.
+func (s *Server) deadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tier := s.requestTier(r)
+		budget, ok := s.requestBudget(r, tier)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		caller, hasCaller, err := parseCallerBudget(r)
+		if err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		tierBound := s.cfg.RequestDeadlineSlack > 0
+		if !tierBound && !hasCaller {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var cb *callerBudget
+		if hasCaller {
+			cb = &callerBudget{budget: caller, deadline: time.Now().Add(caller), binding: !tierBound || caller < budget}
+			if cb.binding {
+				budget = caller
+			}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), budget)
+		defer cancel()
+		if cb != nil {
+			ctx = context.WithValue(ctx, callerBudgetKey{}, cb)
+			bw := &budgetWriter{ResponseWriter: w, deadline: cb.deadline}
+			w = bw
+			defer func() {
+				// The handler gave up without answering
+				if !bw.written && budgetExceeded(ctx) {
+					s.writeBudgetExceeded(w, r.WithContext(ctx), nil)
+				}
+			}()
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		switch {
+		case r.Context().Err() != nil:
+			requestClientCanceled.WithLabelValues(string(tier)).Inc()
+		case budgetExceeded(ctx):
+			// The caller gave up first; the tier deadline was not missed
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			requestDeadlineExceeded.WithLabelValues(string(tier)).Inc()
+		}
+	})
+}
+
+// relayContext is the context relays connected on demand run under. Their
+// connections outlive the request that triggered them, so it is the
+// server's lifetime rather than the request's.
+func (s *Server) relayContext() context.Context {
+	if s.life != nil {
+		return s.life
+	}
+	return context.Background()
+}
@@ -0,0 +1,68 @@
+// Package api provides runtime metrics and profiling endpoints
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+)
+
+// ===== RUNTIME METRICS AND PROFILING =====
+
+// The default Prometheus registry carries the Go runtime and process
+// collectors (goroutines, GC pauses, heap, open file descriptors, CPU)
+// next to every metric registered with promauto. /metrics appends it to
+// the tier metrics, and /debug/pprof serves the standard profiles to
+// admins, so a slow node can be profiled where it runs.
+
+// processStart is when the process started, for the uptime gauge
+var processStart = time.Now()
+
+var _ = promauto.NewGaugeFunc(
+	prometheus.GaugeOpts{
+		Name: "bitcoin_sprint_uptime_seconds",
+		Help: "Seconds since the process started",
+	},
+	func() float64 { return time.Since(processStart).Seconds() },
+)
+
+// writeRegistryMetrics writes the default registry in the text exposition
+// format. Metrics that fail to gather are logged and left out.
+func (s *Server) writeRegistryMetrics(w io.Writer) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		s.logger.Warn("Failed to gather some metrics", zap.Error(err))
+	}
+	enc := expfmt.NewEncoder(w, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			s.logger.Warn("Failed to encode metrics", zap.String("metric", mf.GetName()), zap.Error(err))
+			return
+		}
+	}
+}
+
+// pprofHandler serves net/http/pprof under /debug/pprof/. CPU profiles and
+// traces are bounded by the server's write timeout.
+func (s *Server) pprofHandler(w http.ResponseWriter, r *http.Request) {
+	switch strings.TrimPrefix(r.URL.Path, "/debug/pprof/") {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// The index, and named profiles such as heap and goroutine
+		pprof.Index(w, r)
+	}
+}
@@ -0,0 +1,221 @@
+// Package api provides stream egress accounting, egress quotas and the usage API
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== STREAM EGRESS ACCOUNTING =====
+
+// Enterprise agreements cap data egress. Every byte written to a stream
+// connection, framing and compression included, is counted on its lease
+// and charged to the API key for the calendar month, and the usage API
+// and billing read the same totals. Bytes are charged in batches of
+// egressFlushBytes, so a stream can overrun its quota by up to one batch
+// before it is closed. Anonymous streams are counted but have no key to
+// charge.
+
+var (
+	streamEgressBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stream_egress_bytes_total",
+			Help: "Bytes written to stream connections by tier",
+		},
+		[]string{"tier"},
+	)
+
+	streamEgressQuotaExceeded = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stream_egress_quota_exceeded_total",
+			Help: "Streams refused or closed because the key's monthly egress quota was spent",
+		},
+		[]string{"tier", "action"}, // action refused, closed
+	)
+)
+
+// egressFlushBytes is how many bytes a lease gathers before charging them
+const egressFlushBytes = 64 << 10
+
+// ChargeEgress adds n bytes to the key's egress for the current month and
+// reports the month's total and whether it is within quota (0 =
+// unlimited). Unlike ChargeCost the bytes are always added: they have
+// already been sent.
+func (ckm *CustomerKeyManager) ChargeEgress(hash string, n, quota int64) (int64, bool) {
+	ckm.mu.Lock()
+	defer ckm.mu.Unlock()
+
+	key, exists := ckm.keys[hash]
+	if !exists {
+		return 0, true
+	}
+	if period := costPeriod(ckm.clock.Now()); key.EgressPeriod != period {
+		key.EgressPeriod = period
+		key.EgressBytes = 0
+	}
+	key.EgressBytes += n
+	ckm.keys[hash] = key
+	return key.EgressBytes, quota <= 0 || key.EgressBytes <= quota
+}
+
+// keyByHash returns the key with hash, with its monthly counters zeroed if
+// they belong to an earlier month
+func (ckm *CustomerKeyManager) keyByHash(hash string) (CustomerKey, bool) {
+	ckm.mu.RLock()
+	defer ckm.mu.RUnlock()
+
+	key, exists := ckm.keys[hash]
+	if !exists {
+		return CustomerKey{}, false
+	}
+	period := costPeriod(ckm.clock.Now())
+	if key.CostPeriod != period {
+		key.CostUnits = 0
+	}
+	if key.EgressPeriod != period {
+		key.EgressBytes = 0
+	}
+	return key, true
+}
+
+// getTierEgressQuota returns the tier's monthly egress quota in bytes (0 = unlimited)
+func (s *Server) getTierEgressQuota(tier config.Tier) int64 {
+	if limit, ok := s.cfg.RateLimits[tier]; ok {
+		return limit.MonthlyEgressMB << 20
+	}
+	return 0
+}
+
+// keyedStream reports whether a stream's KeyID is an API key hash rather
+// than "ip:<addr>"
+func keyedStream(keyID string) bool {
+	return !strings.HasPrefix(keyID, "ip:")
+}
+
+// egressAllowed refuses a stream whose key has already spent its monthly
+// egress quota, writing a 402 like an exhausted cost budget
+func (s *Server) egressAllowed(w http.ResponseWriter, client StreamClient) bool {
+	quota := s.getTierEgressQuota(client.Tier)
+	if quota <= 0 || !keyedStream(client.KeyID) {
+		return true
+	}
+	key, ok := s.keyManager.keyByHash(client.KeyID)
+	if !ok || key.EgressBytes < quota {
+		return true
+	}
+
+	streamEgressQuotaExceeded.WithLabelValues(string(client.Tier), "refused").Inc()
+	s.logger.Debug("WebSocket stream rejected",
+		zap.String("ip", client.IP),
+		zap.String("tier", string(client.Tier)),
+		zap.String("chain", client.Chain),
+		zap.String("reason", "egress_quota"))
+	s.jsonResponse(w, http.StatusPaymentRequired, map[string]interface{}{
+		"error":        "monthly egress quota exhausted",
+		"egress_bytes": key.EgressBytes,
+		"quota_bytes":  quota,
+		"resets_at":    costPeriodEnd(s.clock.Now()).Format(time.RFC3339),
+	})
+	return false
+}
+
+// egressCharger returns the func that charges a lease's bytes to its key,
+// ending the stream once the key's quota is spent; nil for anonymous
+// streams
+func (s *Server) egressCharger(lease *StreamLease) func(n int64) {
+	client := lease.client
+	if !keyedStream(client.KeyID) {
+		return nil
+	}
+	quota := s.getTierEgressQuota(client.Tier)
+	return func(n int64) {
+		used, ok := s.keyManager.ChargeEgress(client.KeyID, n, quota)
+		if ok || !lease.overQuota.CompareAndSwap(false, true) {
+			return
+		}
+		streamEgressQuotaExceeded.WithLabelValues(string(client.Tier), "closed").Inc()
+		s.logger.Warn("Monthly egress quota exhausted, closing stream",
+			zap.String("key_hash", client.KeyID[:min(8, len(client.KeyID))]),
+			zap.String("tier", string(client.Tier)),
+			zap.String("chain", client.Chain),
+			zap.Int64("egress_bytes", used),
+			zap.Int64("quota_bytes", quota))
+		lease.cancelStream()
+	}
+}
+
+// recordEgress counts n bytes written to a lease's connection
+func (s *Server) recordEgress(lease *StreamLease, n int) {
+	lease.sent.Add(int64(n))
+	streamEgressBytes.WithLabelValues(string(lease.client.Tier)).Add(float64(n))
+	if lease.charge == nil {
+		return
+	}
+	if lease.unbilled.Add(int64(n)) >= egressFlushBytes {
+		if pending := lease.unbilled.Swap(0); pending > 0 {
+			lease.charge(pending)
+		}
+	}
+}
+
+// ===== USAGE API =====
+
+// usageHandler reports the caller's usage for the current month against
+// its tier's limits: requests, cost units, stream egress and open streams
+func (s *Server) usageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	hash, _ := r.Context().Value("customer_key_hash").(string)
+	key, ok := s.keyManager.keyByHash(hash)
+	if !ok {
+		s.jsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "unknown API key"})
+		return
+	}
+
+	now := s.clock.Now()
+	budget := s.getTierCostBudget(key.Tier)
+	quota := s.getTierEgressQuota(key.Tier)
+
+	streams := []StreamInfo{}
+	var streamBytes int64
+	for _, st := range s.wsLimiter.Streams() {
+		if st.KeyID != key.Hash {
+			continue
+		}
+		st.KeyID = st.KeyID[:8]
+		streamBytes += st.BytesSent
+		streams = append(streams, st)
+	}
+
+	cost := map[string]interface{}{"units": key.CostUnits, "budget": budget}
+	if budget > 0 {
+		cost["remaining"] = max(budget-key.CostUnits, 0)
+	}
+	egress := map[string]interface{}{"bytes": key.EgressBytes, "quota_bytes": quota}
+	if quota > 0 {
+		egress["remaining_bytes"] = max(quota-key.EgressBytes, 0)
+	}
+
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"key_id":    key.Hash[:8],
+		"tier":      string(key.Tier),
+		"period":    costPeriod(now),
+		"resets_at": costPeriodEnd(now).Format(time.RFC3339),
+		"requests":  key.RequestCount,
+		"cost":      cost,
+		"egress":    egress,
+		"streams": map[string]interface{}{
+			"active":     len(streams),
+			"bytes_sent": streamBytes, // Since each stream opened; charged to egress in batches
+			"list":       streams,
+		},
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/entropy"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
 	"go.uber.org/zap"
 )
@@ -36,6 +37,7 @@ func (esm *EnterpriseSecurityManager) RegisterEnterpriseRoutes() {
 		zap.Strings("endpoints", []string{
 			"POST /api/v1/enterprise/entropy/fast",
 			"POST /api/v1/enterprise/entropy/hybrid",
+			"GET /api/v1/enterprise/entropy/health",
 			"GET /api/v1/enterprise/system/fingerprint",
 			"GET /api/v1/enterprise/system/temperature",
 			"POST /api/v1/enterprise/buffer/new",
@@ -46,6 +48,9 @@ func (esm *EnterpriseSecurityManager) RegisterEnterpriseRoutes() {
 			"GET /api/v1/enterprise/security/compliance-report",
 		}))
 	
+	// Surface entropy health alerts in the server log
+	esm.watchEntropyHealth()
+
 	// Register bloom endpoints if CGO is enabled
 	esm.RegisterBloomEndpoints()
 }
@@ -468,6 +473,41 @@ func (esm *EnterpriseSecurityManager) handleComplianceReport(w http.ResponseWrit
 // === BITCOIN BLOOM FILTER ENDPOINTS ===
 // Bloom filter endpoints are available only when built with cgo. See enterprise_bloom_cgo.go.
 
+// handleEntropyHealth reports continuous health test results for entropy sources
+func (esm *EnterpriseSecurityManager) handleEntropyHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		esm.jsonError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	report := entropy.Health()
+	status := http.StatusOK
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	esm.jsonResponse(w, status, report)
+}
+
+// watchEntropyHealth logs entropy health alerts so degraded sources are visible
+func (esm *EnterpriseSecurityManager) watchEntropyHealth() {
+	entropy.SetHealthAlertHandler(func(alert entropy.HealthAlert) {
+		fields := []zap.Field{
+			zap.String("source", alert.Source),
+			zap.String("test", alert.Test),
+			zap.String("state", string(alert.State)),
+		}
+		if esm.server != nil {
+			esm.server.alerts.record(esm.server.clock.Now(), "entropy", alert.Source,
+				alert.Test+" "+string(alert.State))
+		}
+		if alert.State == entropy.StateHealthy {
+			esm.logger.Info("Entropy source recovered", fields...)
+			return
+		}
+		esm.logger.Error("Entropy source health test failed, falling back", fields...)
+	})
+}
+
 // === UTILITY METHODS ===
 
 // jsonResponse sends a JSON response
@@ -0,0 +1,253 @@
+// Package api provides Ethereum receipt and log endpoints
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+	"go.uber.org/zap"
+)
+
+// ===== ETHEREUM RECEIPTS AND LOGS =====
+
+// ethereumLogsTimeout bounds a full chunked log query across providers
+const ethereumLogsTimeout = 60 * time.Second
+
+// maxReceiptsPerRequest bounds the transactions one receipt request may name
+const maxReceiptsPerRequest = 100
+
+// ethereumLogsHandler serves GET /api/v1/universal/ethereum/logs
+//
+// Query parameters: fromBlock, toBlock (decimal, 0x hex or "latest"),
+// address (comma separated or repeated) and topic0..topic3 (comma
+// separated alternatives).
+func (s *Server) ethereumLogsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	fromBlock, fromLatest, err := parseBlockParam(q.Get("fromBlock"))
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid fromBlock: " + err.Error()})
+		return
+	}
+	toBlock, toLatest, err := parseBlockParam(q.Get("toBlock"))
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid toBlock: " + err.Error()})
+		return
+	}
+
+	filter := relay.LogFilter{FromBlock: fromBlock, ToBlock: toBlock}
+	filter.Addresses = splitParamList(q["address"])
+	for i := 0; i < 4; i++ {
+		filter.Topics = append(filter.Topics, splitParamList(q["topic"+strconv.Itoa(i)]))
+	}
+	// Drop trailing wildcard positions
+	for len(filter.Topics) > 0 && len(filter.Topics[len(filter.Topics)-1]) == 0 {
+		filter.Topics = filter.Topics[:len(filter.Topics)-1]
+	}
+
+	if !s.ensureEthereumRelay(w) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ethereumLogsTimeout)
+	defer cancel()
+
+	// Like eth_getLogs, both ends default to the latest block
+	if fromLatest || toLatest {
+		latest, err := s.ethereumRelay.BlockNumber(ctx)
+		if budgetExceeded(ctx) {
+			s.writeBudgetExceeded(w, r, nil)
+			return
+		}
+		if err != nil {
+			s.jsonResponse(w, http.StatusBadGateway, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if fromLatest {
+			filter.FromBlock = latest
+		}
+		if toLatest {
+			filter.ToBlock = latest
+		}
+	}
+
+	logs, err := s.ethereumRelay.GetLogs(ctx, filter)
+	if budgetExceeded(ctx) {
+		s.writeBudgetExceeded(w, r, nil)
+		return
+	}
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, relay.ErrLogRangeTooLarge) || errors.Is(err, relay.ErrInvalidLogRange) {
+			status = http.StatusBadRequest
+		}
+		s.logger.Warn("Ethereum log query failed", zap.Error(err))
+		s.jsonResponse(w, status, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if logs == nil {
+		logs = []relay.EthereumLog{}
+	}
+
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"chain":      "ethereum",
+		"from_block": filter.FromBlock,
+		"to_block":   filter.ToBlock,
+		"count":      len(logs),
+		"logs":       logs,
+	})
+}
+
+// ethereumReceiptHandler serves GET /api/v1/universal/ethereum/receipt/{tx}
+// (or ?tx=). Several comma separated or repeated hashes are looked up in
+// one batch and answered as a list.
+func (s *Server) ethereumReceiptHandler(w http.ResponseWriter, r *http.Request, txHash string) {
+	hashes := splitParamList(r.URL.Query()["tx"])
+	if txHash != "" {
+		hashes = splitParamList([]string{txHash})
+	}
+	if len(hashes) == 0 || len(hashes) > maxReceiptsPerRequest {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "tx must name 1 to " + strconv.Itoa(maxReceiptsPerRequest) + " transactions"})
+		return
+	}
+	for _, hash := range hashes {
+		if !isHexHash(hash) {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]interface{}{"error": "tx must be a 0x-prefixed 32-byte hash", "tx": hash})
+			return
+		}
+	}
+
+	if !s.ensureEthereumRelay(w) {
+		return
+	}
+	if len(hashes) > 1 {
+		s.ethereumReceiptsBatch(w, r, hashes)
+		return
+	}
+	txHash = hashes[0]
+
+	receipt, err := s.ethereumRelay.GetTransactionReceipt(r.Context(), txHash)
+	if budgetExceeded(r.Context()) {
+		s.writeBudgetExceeded(w, r, nil)
+		return
+	}
+	if errors.Is(err, relay.ErrReceiptNotFound) {
+		s.jsonResponse(w, http.StatusNotFound, map[string]interface{}{"error": err.Error(), "tx": txHash})
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Ethereum receipt lookup failed", zap.String("tx", txHash), zap.Error(err))
+		s.jsonResponse(w, http.StatusBadGateway, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"chain":   "ethereum",
+		"receipt": receipt,
+	})
+}
+
+// ethereumReceiptsBatch answers a multi-transaction receipt request. Each
+// entry carries its receipt or its own error.
+func (s *Server) ethereumReceiptsBatch(w http.ResponseWriter, r *http.Request, hashes []string) {
+	results, err := s.ethereumRelay.GetTransactionReceipts(r.Context(), hashes)
+	if err != nil && budgetExceeded(r.Context()) {
+		s.writeBudgetExceeded(w, r, nil)
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Ethereum receipt batch failed", zap.Int("count", len(hashes)), zap.Error(err))
+		s.jsonResponse(w, http.StatusBadGateway, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	receipts := make([]map[string]interface{}, len(results))
+	found := 0
+	for i, res := range results {
+		entry := map[string]interface{}{"tx": hashes[i]}
+		if res.Err != nil {
+			entry["error"] = res.Err.Error()
+		} else {
+			entry["receipt"] = res.Receipt
+			found++
+		}
+		receipts[i] = entry
+	}
+	body := map[string]interface{}{
+		"chain":    "ethereum",
+		"count":    len(receipts),
+		"found":    found,
+		"receipts": receipts,
+	}
+	// Receipts that arrived in time are still worth returning
+	if found < len(receipts) && budgetExceeded(r.Context()) {
+		s.writeBudgetExceeded(w, r, body)
+		return
+	}
+	s.jsonResponse(w, http.StatusOK, body)
+}
+
+// ensureEthereumRelay connects the relay on demand and writes a 503 if it
+// is unavailable
+func (s *Server) ensureEthereumRelay(w http.ResponseWriter) bool {
+	if s.ethereumRelay == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "Ethereum relay not configured"})
+		return false
+	}
+	if s.ethereumRelay.IsConnected() {
+		return true
+	}
+
+	if err := s.ethereumRelay.Connect(s.relayContext()); err != nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]interface{}{"error": "Failed to connect to Ethereum network: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// parseBlockParam accepts decimal, 0x hex, "earliest", or empty/"latest"
+// which is reported through latest
+func parseBlockParam(v string) (height uint64, latest bool, err error) {
+	v = strings.TrimSpace(strings.ToLower(v))
+	switch {
+	case v == "" || v == "latest":
+		return 0, true, nil
+	case v == "earliest":
+		return 0, false, nil
+	case strings.HasPrefix(v, "0x"):
+		height, err = strconv.ParseUint(v[2:], 16, 64)
+	default:
+		height, err = strconv.ParseUint(v, 10, 64)
+	}
+	return height, false, err
+}
+
+// splitParamList flattens repeated and comma separated query values
+func splitParamList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func isHexHash(v string) bool {
+	if len(v) != 66 || !strings.HasPrefix(v, "0x") {
+		return false
+	}
+	for _, c := range v[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
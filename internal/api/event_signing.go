@@ -0,0 +1,96 @@
+// Package api provides Ed25519 signing of block events and the published keys
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/eventsign"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"go.uber.org/zap"
+)
+
+// ===== EVENT SIGNING =====
+
+// eventSigningKeystorePrefix namespaces signing seeds in the keystore
+const eventSigningKeystorePrefix = "event-signing-"
+
+// startEventSigning loads the signing seed from the keystore, generating
+// and storing one the first time a key ID is used. Without
+// EVENT_SIGNING_KEY_ID, stream and webhook events go out unsigned.
+func (s *Server) startEventSigning() {
+	keyID := s.cfg.EventSigningKeyID
+	if keyID == "" {
+		return
+	}
+	if s.keystore == nil {
+		s.logger.Error("Event signing needs the keystore, events will be unsigned")
+		return
+	}
+	if s.cfg.EventSigningKeyPassword == "" {
+		s.logger.Error("EVENT_SIGNING_KEY_PASSWORD not set, events will be unsigned")
+		return
+	}
+
+	id := eventSigningKeystorePrefix + keyID
+	seed, err := s.keystore.Load(id, s.cfg.EventSigningKeyPassword)
+	if errors.Is(err, ErrKeystoreNotFound) {
+		if seed, err = eventsign.GenerateSeed(); err == nil {
+			err = s.keystore.Save(id, seed, s.cfg.EventSigningKeyPassword)
+		}
+		if err == nil {
+			s.logger.Info("Generated event signing key", zap.String("key_id", keyID))
+		}
+	}
+	if err != nil {
+		s.logger.Error("Failed to load event signing key, events will be unsigned",
+			zap.String("key_id", keyID), zap.Error(err))
+		return
+	}
+
+	signer, err := eventsign.NewSigner(keyID, seed)
+	for i := range seed {
+		seed[i] = 0
+	}
+	if err != nil {
+		s.logger.Error("Invalid event signing key, events will be unsigned",
+			zap.String("key_id", keyID), zap.Error(err))
+		return
+	}
+	s.eventSigner = signer
+	s.logger.Info("Signing block events",
+		zap.String("key_id", keyID),
+		zap.String("public_key", signer.PublicKey().PublicKey))
+}
+
+// streamBlock wraps ev for the block stream, signed when signing is on
+func (s *Server) streamBlock(ev blocks.BlockEvent) schemas.BlockV1 {
+	msg := schemas.NewBlock(ev)
+	if s.eventSigner == nil {
+		return msg
+	}
+	sig, err := s.eventSigner.Sign(ev)
+	if err != nil {
+		s.logger.Warn("Failed to sign stream event", zap.Error(err))
+		return msg
+	}
+	msg.KeyID, msg.Signature = s.eventSigner.KeyID(), sig
+	return msg
+}
+
+// signingKeysHandler publishes the keys block events are signed with:
+//
+//	GET /api/v1/signing-keys
+func (s *Server) signingKeysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	keys := []eventsign.PublicKey{}
+	if s.eventSigner != nil {
+		keys = append(keys, s.eventSigner.PublicKey())
+	}
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
@@ -3,9 +3,12 @@ package api
 
 import (
 	"context"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockbus"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/fastpath"
 	"go.uber.org/zap"
 )
@@ -63,74 +66,105 @@ func (f *FastpathIntegration) Stop() {
 	f.cancel()
 }
 
-// refreshLatestSnapshot fetches the latest block and updates the snapshot
+// refreshLatestSnapshot seeds every chain's latest snapshot from its backend
 func (f *FastpathIntegration) refreshLatestSnapshot() {
-	backend, exists := f.server.backends.Get("bitcoin")
-	if !exists {
-		f.logger.Warn("Bitcoin backend not available for refreshing latest snapshot")
-		return
+	for _, name := range f.server.backends.List() {
+		backend, exists := f.server.backends.Get(name)
+		if !exists {
+			continue
+		}
+
+		block, err := backend.GetLatestBlock()
+		if err != nil {
+			f.logger.Debug("No latest block available for snapshot",
+				zap.String("chain", name),
+				zap.Error(err))
+			continue
+		}
+		f.refreshBlock(name, block)
 	}
+}
 
-	block, err := backend.GetLatestBlock()
-	if err != nil {
-		f.logger.Error("Failed to get latest block for snapshot", zap.Error(err))
+// refreshBlock preserializes a block into the chain's fastpath snapshot
+func (f *FastpathIntegration) refreshBlock(chain string, block blocks.BlockEvent) {
+	if block.Chain != "" {
+		chain = string(block.Chain)
+	}
+	snaps := fastpath.Default.Chain(normalizeChainName(chain))
+	if err := snaps.RefreshBlock(block); err != nil {
+		f.logger.Error("Failed to serialize fastpath block snapshot",
+			zap.String("chain", snaps.Chain()),
+			zap.Error(err))
 		return
 	}
-
-	// Update the snapshot using direct field access
-	fastpath.RefreshLatest(int64(block.Height), block.Hash)
-	f.logger.Debug("Updated latest snapshot with direct field access",
+	f.logger.Debug("Updated latest snapshot",
+		zap.String("chain", snaps.Chain()),
 		zap.Uint32("height", block.Height),
 		zap.String("hash", block.Hash))
 }
 
-// refreshStatusSnapshot updates the status snapshot
+// refreshStatusSnapshot updates the status snapshot of every registered chain
 func (f *FastpathIntegration) refreshStatusSnapshot() {
 	// Get current status information
 	status := "ok" // Default to ok
 	uptime := time.Since(f.server.startTime).Seconds()
-	connections := 0
 
-	// Try to get connection count from backend
-	backend, exists := f.server.backends.Get("bitcoin")
-	if exists {
-		// Check if backend has a GetConnectionCount method
+	// Check system health
+	if !f.server.IsHealthy() {
+		status = "degraded"
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range f.server.backends.List() {
+		chain := normalizeChainName(name)
+		if seen[chain] {
+			continue
+		}
+		seen[chain] = true
+
+		backend, exists := f.server.backends.Get(name)
+		if !exists {
+			continue
+		}
+
+		// Try to get connection count from backend
+		connections := 0
 		if connGetter, ok := backend.(interface{ GetConnectionCount() (int, error) }); ok {
 			count, err := connGetter.GetConnectionCount()
 			if err == nil {
 				connections = count
 			} else {
-				f.logger.Debug("Failed to get connection count", zap.Error(err))
+				f.logger.Debug("Failed to get connection count", zap.String("chain", chain), zap.Error(err))
 			}
 		}
-	}
 
-	// Check system health
-	if !f.server.IsHealthy() {
-		status = "degraded"
+		// Update the snapshot
+		fastpath.Default.Chain(chain).RefreshStatus(status, connections, int64(uptime))
+		f.logger.Debug("Updated status snapshot",
+			zap.String("chain", chain),
+			zap.String("status", status),
+			zap.Int("connections", connections),
+			zap.Float64("uptime_seconds", uptime))
 	}
-
-	// Update the snapshot
-	fastpath.RefreshStatus(status, connections, int64(uptime))
-	f.logger.Debug("Updated status snapshot",
-		zap.String("status", status),
-		zap.Int("connections", connections),
-		zap.Float64("uptime_seconds", uptime))
 }
 
-// runLatestRefresher runs a loop that refreshes the latest snapshot periodically
+// runLatestRefresher refreshes latest snapshots on every BlockEvent from the block bus
 func (f *FastpathIntegration) runLatestRefresher() {
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	if f.server.bus == nil {
+		f.logger.Warn("Block bus not available, latest snapshots will not refresh")
+		return
+	}
+
+	sub := f.server.bus.Subscribe(blockbus.SubscribeOptions{Name: "fastpath", Replay: 1})
+	defer f.server.bus.Unsubscribe(sub)
 
 	for {
-		select {
-		case <-ticker.C:
-			f.refreshLatestSnapshot()
-		case <-f.ctx.Done():
+		block, err := sub.Next(f.ctx)
+		if err != nil {
 			f.logger.Info("Latest snapshot refresher stopped")
 			return
 		}
+		f.refreshBlock("bitcoin", block)
 	}
 }
 
@@ -149,3 +183,36 @@ func (f *FastpathIntegration) runStatusRefresher() {
 		}
 	}
 }
+
+// registerFastpathRoutes registers /v1/{chain}/latest and /v1/{chain}/status
+// fastpath handlers for every supported chain and alias. Until a chain has a
+// snapshot the request falls through to the regular chain-aware handler.
+func (s *Server) registerFastpathRoutes() []string {
+	fallback := http.HandlerFunc(s.chainAwareHandler)
+
+	names := append([]string{"btc"}, s.cfg.SupportedChains...)
+	registered := make(map[string]bool)
+	routes := make([]string, 0, len(names)*4)
+	for _, alias := range names {
+		for _, name := range []string{alias, normalizeChainName(alias)} {
+			if name == "" || registered[name] {
+				continue
+			}
+			registered[name] = true
+
+			snaps := fastpath.Default.Chain(normalizeChainName(name))
+			latest := "/v1/" + name + "/latest"
+			status := "/v1/" + name + "/status"
+			s.httpMux.HandleFunc(latest, s.withCacheHeaders(snaps.LatestHandler(fallback)))
+			s.httpMux.HandleFunc(status, s.withCacheHeaders(snaps.StatusHandler(fallback)))
+			routes = append(routes, latest, status)
+		}
+	}
+
+	// Snapshots are preserialized for latency; compressing them per request would undo that
+	s.compressionExempt = make(map[string]bool, len(routes))
+	for _, route := range routes {
+		s.compressionExempt[route] = true
+	}
+	return routes
+}
@@ -0,0 +1,156 @@
+// Package api provides the chain-aware fee estimation endpoint
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/fees"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+	"go.uber.org/zap"
+)
+
+// ===== FEE ESTIMATION =====
+
+const (
+	feeCacheNamespace = "fee_estimate:"
+	feeCacheTTL       = time.Minute // Longest refresh interval of any tier
+	feeSourceTimeout  = 10 * time.Second
+)
+
+// feeRefreshIntervals is the oldest estimate each tier is served; anything
+// older is re-fetched on request
+var feeRefreshIntervals = map[config.Tier]time.Duration{
+	config.TierFree:       time.Minute,
+	config.TierPro:        30 * time.Second,
+	config.TierBusiness:   15 * time.Second,
+	config.TierTurbo:      5 * time.Second,
+	config.TierEnterprise: 5 * time.Second,
+}
+
+// feesResponse is an estimate with its freshness
+type feesResponse struct {
+	*fees.Estimate
+	AgeSeconds             float64 `json:"age_seconds"`
+	RefreshIntervalSeconds float64 `json:"refresh_interval_seconds"`
+}
+
+// newFeeEstimator registers a source for every chain with one configured
+func newFeeEstimator(cfg config.Config, mem *mempool.Mempool) *fees.Estimator {
+	estimator := fees.NewEstimator()
+	if mem != nil {
+		estimator.Register(string(blocks.ChainBitcoin), fees.MempoolSource{Mempool: mem})
+	}
+	if len(cfg.EthereumHTTPEndpoints) > 0 {
+		estimator.Register(string(blocks.ChainEthereum), fees.NewFeeHistorySource(cfg.EthereumHTTPEndpoints, feeSourceTimeout))
+	}
+	if len(cfg.SolanaHTTPEndpoints) > 0 {
+		estimator.Register(string(blocks.ChainSolana), fees.NewPrioritizationFeeSource(cfg.SolanaHTTPEndpoints, feeSourceTimeout))
+	}
+	return estimator
+}
+
+// startFeeEstimates lets the cache load estimates on a miss and keeps them
+// warm under steady traffic so most requests never wait on an upstream node
+func (s *Server) startFeeEstimates() {
+	if s.cache == nil || s.fees == nil {
+		return
+	}
+	load := func(ctx context.Context, key string) (any, error) {
+		return s.fees.Estimate(ctx, strings.TrimPrefix(key, feeCacheNamespace))
+	}
+	err := s.cache.SetLoader(feeCacheNamespace, cache.LoaderPolicy{
+		Loader:  load,
+		TTL:     feeCacheTTL,
+		Timeout: feeSourceTimeout,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to register fee estimate loader", zap.Error(err))
+		return
+	}
+	err = s.cache.SetRefreshAhead(feeCacheNamespace, cache.RefreshAheadPolicy{
+		TTL:     feeCacheTTL,
+		Timeout: feeSourceTimeout,
+		Loader:  load,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to enable fee estimate refresh-ahead", zap.Error(err))
+		return
+	}
+	s.logger.Info("Fee estimation enabled", zap.Strings("chains", s.fees.Chains()))
+}
+
+// feeEstimate returns the cached estimate for chain unless it is older than
+// maxAge
+func (s *Server) feeEstimate(ctx context.Context, chain string, maxAge time.Duration) (*fees.Estimate, error) {
+	if s.cache == nil {
+		return s.fees.Estimate(ctx, chain)
+	}
+
+	key := feeCacheNamespace + chain
+	// A miss is only possible before startFeeEstimates registers the loader
+	v, _, err := s.cache.Load(ctx, key)
+	if err != nil && !errors.Is(err, cache.ErrCacheMiss) {
+		return nil, err
+	}
+	if est, ok := v.(*fees.Estimate); ok && s.clock.Now().Sub(est.UpdatedAt) <= maxAge {
+		return est, nil
+	}
+
+	est, err := s.fees.Estimate(ctx, chain)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(key, est, feeCacheTTL); err != nil {
+		s.logger.Debug("Failed to cache fee estimate", zap.String("chain", chain), zap.Error(err))
+	}
+	return est, nil
+}
+
+// chainFeesHandler serves /v1/{chain}/fees. Estimates are refreshed at most
+// as often as the caller's tier allows.
+func (s *Server) chainFeesHandler(chain string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.fees == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "fee estimation not configured"})
+		return
+	}
+
+	chain = normalizeChainName(chain)
+	maxAge, ok := feeRefreshIntervals[s.getCustomerTierFromContext(r)]
+	if !ok {
+		maxAge = feeRefreshIntervals[config.TierFree]
+	}
+
+	est, err := s.feeEstimate(r.Context(), chain, maxAge)
+	switch {
+	case err != nil && budgetExceeded(r.Context()):
+		s.writeBudgetExceeded(w, r, nil)
+		return
+	case errors.Is(err, fees.ErrUnsupportedChain):
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": "Fee estimation not available for chain " + chain})
+		return
+	case errors.Is(err, fees.ErrNoData):
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		s.logger.Warn("Fee estimation failed", zap.String("chain", chain), zap.Error(err))
+		s.jsonResponse(w, http.StatusBadGateway, map[string]string{"error": "fee sources unavailable"})
+		return
+	}
+
+	s.jsonResponse(w, http.StatusOK, feesResponse{
+		Estimate:               est,
+		AgeSeconds:             s.clock.Now().Sub(est.UpdatedAt).Seconds(),
+		RefreshIntervalSeconds: maxAge.Seconds(),
+	})
+}
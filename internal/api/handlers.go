@@ -11,9 +11,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/fastpath"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/tlsconfig"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -54,16 +55,37 @@ func (s *Server) universalChainHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get customer tier from context (set by auth middleware)
 	customerTier := s.getCustomerTierFromContext(r)
+
+	// Ethereum receipts and logs are served straight from the relay
+	if normalizeChainName(chain) == "ethereum" && (method == "logs" || method == "receipt") {
+		if latencyOptimizer != nil {
+			defer func() {
+				latencyOptimizer.TrackRequest(chain, time.Since(start))
+				latencyOptimizer.TrackKey(chain, method)
+			}()
+		}
+		if method == "logs" {
+			s.ethereumLogsHandler(w, r)
+		} else {
+			txHash := ""
+			if len(pathParts) > idx+3 {
+				txHash = pathParts[idx+3]
+			}
+			s.ethereumReceiptHandler(w, r, txHash)
+		}
+		return
+	}
 	
 	// Track latency for P99 optimization
 	defer func() {
 		duration := time.Since(start)
 		if latencyOptimizer != nil {
 			latencyOptimizer.TrackRequest(chain, duration)
+			latencyOptimizer.TrackKey(chain, method)
 		}
 
 		// Log if we're meeting our flat P99 target (tier-dependent)
-		targetLatency := s.getTierLatencyTarget(customerTier)
+		targetLatency := s.getChainLatencyTarget(chain, customerTier)
 		if duration > targetLatency {
 			s.logger.Warn("P99 target exceeded",
 				zap.String("chain", chain),
@@ -74,7 +96,11 @@ func (s *Server) universalChainHandler(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	// Apply tier-based features and performance optimizations
-	response := s.buildTierAwareResponse(chain, method, customerTier, start)
+	response := s.buildTierAwareResponse(r.Context(), chain, method, customerTier, start)
+	if budgetExceeded(r.Context()) {
+		s.writeBudgetExceeded(w, r, response)
+		return
+	}
 	
 	// Apply tier-specific caching strategy
 	if s.shouldUsePredictiveCache(customerTier) {
@@ -98,7 +124,7 @@ func (s *Server) universalChainHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add tier-specific performance guarantees
-	response["tier_guarantees"] = s.getTierGuarantees(customerTier)
+	response["tier_guarantees"] = s.getTierGuarantees(chain, customerTier)
 	
 	s.jsonResponse(w, http.StatusOK, response)
 }
@@ -127,19 +153,15 @@ func (s *Server) getCustomerTierFromContext(r *http.Request) config.Tier {
 	return config.TierFree // Default to free tier
 }
 
+// getTierLatencyTarget returns the tier's configured P99 latency target
 func (s *Server) getTierLatencyTarget(tier config.Tier) time.Duration {
-	switch tier {
-	case config.TierEnterprise:
-		return 50 * time.Millisecond  // Enterprise: Sub-50ms
-	case config.TierTurbo:
-		return 75 * time.Millisecond  // Turbo: Sub-75ms
-	case config.TierBusiness:
-		return 100 * time.Millisecond // Business: Sub-100ms
-	case config.TierPro:
-		return 150 * time.Millisecond // Pro: Sub-150ms
-	default:
-		return 250 * time.Millisecond // Free: Sub-250ms
-	}
+	return s.cfg.SLA.For("", tier).LatencyTarget
+}
+
+// getChainLatencyTarget returns the tier's P99 latency target on chain,
+// which may be overridden per chain
+func (s *Server) getChainLatencyTarget(chain string, tier config.Tier) time.Duration {
+	return s.cfg.SLA.For(normalizeChainName(chain), tier).LatencyTarget
 }
 
 func (s *Server) shouldUsePredictiveCache(tier config.Tier) bool {
@@ -155,50 +177,22 @@ func (s *Server) isEnterpriseTier(tier config.Tier) bool {
 	return tier == config.TierEnterprise
 }
 
-func (s *Server) getTierGuarantees(tier config.Tier) map[string]interface{} {
-	switch tier {
-	case config.TierEnterprise:
-		return map[string]interface{}{
-			"sla_uptime":        "99.99%",
-			"max_latency":       "50ms P99",
-			"rate_limit":        "50,000 req/hour",
-			"support":           "24/7 dedicated",
-			"custom_endpoints":  "Available",
-			"data_retention":    "7 years",
-		}
-	case config.TierTurbo:
-		return map[string]interface{}{
-			"sla_uptime":        "99.9%",
-			"max_latency":       "75ms P99",
-			"rate_limit":        "10,000 req/hour",
-			"support":           "Priority support",
-			"data_retention":    "2 years",
-		}
-	case config.TierBusiness:
-		return map[string]interface{}{
-			"sla_uptime":        "99.5%",
-			"max_latency":       "100ms P99",
-			"rate_limit":        "5,000 req/hour",
-			"support":           "Business hours",
-			"data_retention":    "1 year",
-		}
-	case config.TierPro:
-		return map[string]interface{}{
-			"sla_uptime":        "99%",
-			"max_latency":       "150ms P99",
-			"rate_limit":        "1,000 req/hour",
-			"support":           "Email support",
-			"data_retention":    "6 months",
-		}
-	default: // TierFree
-		return map[string]interface{}{
-			"sla_uptime":        "95%",
-			"max_latency":       "250ms P99",
-			"rate_limit":        "100 req/hour",
-			"support":           "Community forum",
-			"data_retention":    "30 days",
-		}
+// getTierGuarantees returns the tier's SLA terms on chain, as configured
+func (s *Server) getTierGuarantees(chain string, tier config.Tier) map[string]interface{} {
+	sla := s.cfg.SLA.For(normalizeChainName(chain), tier)
+	guarantees := map[string]interface{}{
+		"sla_uptime":     sla.Uptime,
+		"max_latency":    fmt.Sprintf("%dms P99", sla.LatencyTarget.Milliseconds()),
+		"support":        sla.Support,
+		"data_retention": sla.DataRetention,
+	}
+	if limit, ok := s.cfg.RateLimits[tier]; ok && limit.RequestsPerHour > 0 {
+		guarantees["rate_limit"] = fmt.Sprintf("%d req/hour", limit.RequestsPerHour)
 	}
+	if sla.CustomEndpoints != "" {
+		guarantees["custom_endpoints"] = sla.CustomEndpoints
+	}
+	return guarantees
 }
 
 // adminOnly is a convenience wrapper to protect admin endpoints with admin keys.
@@ -213,6 +207,11 @@ func (s *Server) adminOnly(h func(http.ResponseWriter, *http.Request)) http.Hand
 			s.jsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "admin access required"})
 			return
 		}
+		// Admin and keystore (secrets) routes also need a client certificate
+		if s.cfg.EnableMTLS && !tlsconfig.HasVerifiedClientCert(r) {
+			s.jsonResponse(w, http.StatusForbidden, map[string]string{"error": "client certificate required"})
+			return
+		}
 		h(w, r)
 	}
 }
@@ -343,7 +342,70 @@ func (s *Server) keystoreImportHandler(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, http.StatusCreated, map[string]string{"id": req.ID})
 }
 
-func (s *Server) buildTierAwareResponse(chain, method string, tier config.Tier, start time.Time) map[string]interface{} {
+func (s *Server) keystoreExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.keystore == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "keystore not initialized"})
+		return
+	}
+	// Expect JSON body: { "passphrase": "..." }
+	var req struct {
+		Passphrase string `json:"passphrase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+	if req.Passphrase == "" {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "passphrase required"})
+		return
+	}
+	archive, err := s.keystore.ExportAll(req.Passphrase)
+	if err != nil {
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=keystore-%s.archive.json", time.Now().UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+func (s *Server) keystoreImportArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if s.keystore == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "keystore not initialized"})
+		return
+	}
+	// Expect JSON body: { "passphrase": "...", "archive": {...}, "overwrite": false }
+	var req struct {
+		Passphrase string          `json:"passphrase"`
+		Archive    json.RawMessage `json:"archive"`
+		Overwrite  bool            `json:"overwrite"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid body"})
+		return
+	}
+	if req.Passphrase == "" || len(req.Archive) == 0 {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "passphrase and archive required"})
+		return
+	}
+	result, err := s.keystore.ImportAll(req.Archive, req.Passphrase, req.Overwrite)
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	s.jsonResponse(w, http.StatusOK, result)
+}
+
+func (s *Server) buildTierAwareResponse(ctx context.Context, chain, method string, tier config.Tier, start time.Time) map[string]interface{} {
 	// Base response structure
 	response := map[string]interface{}{
 		"chain":     chain,
@@ -352,7 +414,7 @@ func (s *Server) buildTierAwareResponse(chain, method string, tier config.Tier,
 		"timestamp": start.Unix(),
 		"sprint_advantages": map[string]interface{}{
 			"unified_api":         "Single endpoint works across all chains",
-			"flat_p99":            fmt.Sprintf("Sub-%dms guaranteed response time", s.getTierLatencyTarget(tier)/time.Millisecond),
+			"flat_p99":            fmt.Sprintf("Sub-%dms guaranteed response time", s.getChainLatencyTarget(chain, tier)/time.Millisecond),
 			"predictive_cache":    s.getCacheDescription(tier),
 			"enterprise_security": s.getSecurityDescription(tier),
 		},
@@ -360,10 +422,10 @@ func (s *Server) buildTierAwareResponse(chain, method string, tier config.Tier,
 
 	// Handle real data for supported chains with tier-specific features
 	if chain == "ethereum" {
-		response = s.handleEthereumRequest(method, start)
+		response = s.handleEthereumRequest(ctx, method, start)
 		response["tier"] = string(tier)
 	} else if chain == "solana" {
-		response = s.handleSolanaRequest(method, start)
+		response = s.handleSolanaRequest(ctx, method, start)
 		response["tier"] = string(tier)
 	} else {
 		// Add competitive comparison based on tier
@@ -372,10 +434,11 @@ func (s *Server) buildTierAwareResponse(chain, method string, tier config.Tier,
 
 	// Add performance metrics
 	duration := time.Since(start)
+	target := s.getChainLatencyTarget(chain, tier)
 	response["performance"] = map[string]interface{}{
 		"response_time": fmt.Sprintf("%.2fms", float64(duration.Nanoseconds())/1e6),
-		"tier_target":   fmt.Sprintf("%.0fms", float64(s.getTierLatencyTarget(tier)/time.Millisecond)),
-		"target_met":    duration <= s.getTierLatencyTarget(tier),
+		"tier_target":   fmt.Sprintf("%.0fms", float64(target/time.Millisecond)),
+		"target_met":    duration <= target,
 	}
 
 	return response
@@ -425,8 +488,9 @@ func (s *Server) getTierCompetitiveAdvantage(tier config.Tier) map[string]interf
 	return base
 }
 
-// handleEthereumRequest handles Ethereum-specific requests using the real relay
-func (s *Server) handleEthereumRequest(method string, start time.Time) map[string]interface{} {
+// handleEthereumRequest handles Ethereum-specific requests using the real
+// relay; upstream calls end with ctx
+func (s *Server) handleEthereumRequest(ctx context.Context, method string, start time.Time) map[string]interface{} {
 	response := map[string]interface{}{
 		"chain":     "ethereum",
 		"method":    method,
@@ -437,9 +501,7 @@ func (s *Server) handleEthereumRequest(method string, start time.Time) map[strin
 
 	// Ensure Ethereum relay is connected
 	if s.ethereumRelay != nil && !s.ethereumRelay.IsConnected() {
-		ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
-		defer cancel()
-		if err := s.ethereumRelay.Connect(ctx); err != nil {
+		if err := s.ethereumRelay.Connect(s.relayContext()); err != nil {
 			response["error"] = fmt.Sprintf("Failed to connect to Ethereum network: %v", err)
 			return response
 		}
@@ -451,9 +513,7 @@ func (s *Server) handleEthereumRequest(method string, start time.Time) map[strin
 		// Lightweight reachability check
 		ok := true
 		if s.ethereumRelay != nil && !s.ethereumRelay.IsConnected() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := s.ethereumRelay.Connect(ctx); err != nil {
+			if err := s.ethereumRelay.Connect(s.relayContext()); err != nil {
 				ok = false
 				response["error"] = fmt.Sprintf("Ping failed: %v", err)
 			}
@@ -463,13 +523,13 @@ func (s *Server) handleEthereumRequest(method string, start time.Time) map[strin
 			"peer_count": s.ethereumRelay.GetPeerCount(),
 		}
 	case "latest", "latest_block":
-		if block, err := s.ethereumRelay.GetLatestBlock(); err != nil {
+		if block, err := s.ethereumRelay.LatestBlock(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get latest block: %v", err)
 		} else {
 			response["data"] = block
 		}
 	case "status", "network_info":
-		if info, err := s.ethereumRelay.GetNetworkInfo(); err != nil {
+		if info, err := s.ethereumRelay.NetworkInfo(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get network info: %v", err)
 		} else {
 			response["data"] = info
@@ -480,7 +540,7 @@ func (s *Server) handleEthereumRequest(method string, start time.Time) map[strin
 			"peer_count": peerCount,
 		}
 	case "sync", "sync_status":
-		if status, err := s.ethereumRelay.GetSyncStatus(); err != nil {
+		if status, err := s.ethereumRelay.SyncStatus(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get sync status: %v", err)
 		} else {
 			response["data"] = status
@@ -648,22 +708,31 @@ func (s *Server) latestHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.turboJsonResponse(w, http.StatusOK, block)
+	s.conditionalJSON(w, r, fastpath.BlockETag("bitcoin", block.Hash), block)
 }
 
 // streamHandler handles WebSocket streaming of blocks
 func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
-	// Acquire WebSocket connection slot
-	clientIP := getClientIP(r)
-	if !s.wsLimiter.Acquire(clientIP) {
-		http.Error(w, "WebSocket connection limit reached", http.StatusTooManyRequests)
+	// Optional catch-up replay (?from_height= or ?from_time=)
+	replay, err := parseStreamReplay(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := parseStreamEncoding(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer s.wsLimiter.Release(clientIP)
 
-	// Get the backend for streaming
-	backend, exists := s.backends.Get("bitcoin")
-	if !exists {
+	// Acquire WebSocket stream slot (global, per-IP, per-chain and per-key quota)
+	lease, ok := s.acquireStream(w, r, "bitcoin")
+	if !ok {
+		return
+	}
+	defer lease.Release()
+
+	// Ensure the bitcoin backend is available for streaming
+	if _, exists := s.backends.Get("bitcoin"); !exists {
 		http.Error(w, "Bitcoin backend not available", http.StatusServiceUnavailable)
 		return
 	}
@@ -699,7 +768,7 @@ func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Upgrade the connection
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgradeStream(w, r, &upgrader, lease)
 	if err != nil {
 		s.logger.Error("Failed to upgrade to WebSocket",
 			zap.Error(err),
@@ -715,6 +784,7 @@ func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
 	// Handle ping/pong to keep connection alive
 	conn.SetPingHandler(func(string) error {
 		// Reset the read deadline on ping
+		lease.Touch()
 		conn.SetReadDeadline(s.clock.Now().Add(60 * time.Second))
 		return conn.WriteControl(
 			websocket.PongMessage,
@@ -726,6 +796,7 @@ func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
 	// Create context with timeout/cancel for the stream
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
+	lease.Bind(cancel)
 
 	// Start a goroutine to read from the connection
 	// This is needed to process control messages
@@ -740,41 +811,13 @@ func (s *Server) streamHandler(w http.ResponseWriter, r *http.Request) {
 			}
 
 			// Reset the read deadline
+			lease.Touch()
 			conn.SetReadDeadline(s.clock.Now().Add(60 * time.Second))
 		}
 	}()
 
-	// Create a channel for streaming blocks from the backend
-	blockChan := make(chan blocks.BlockEvent, 10)
-
-	// Start streaming from the backend
-	go backend.StreamBlocks(ctx, blockChan)
-
-	// Stream blocks to client
-	for {
-		select {
-		case blk, ok := <-blockChan:
-			if !ok {
-				// Channel closed
-				return
-			}
-
-			// Set a write deadline
-			conn.SetWriteDeadline(s.clock.Now().Add(10 * time.Second))
-
-			if err := conn.WriteJSON(blk); err != nil {
-				s.logger.Debug("Error writing to WebSocket",
-					zap.Error(err),
-					zap.String("ip", getClientIP(r)),
-				)
-				return
-			}
-
-		case <-ctx.Done():
-			// Context cancelled (client disconnected or timeout)
-			return
-		}
-	}
+	// Stream blocks from the block bus; a slow client only overwrites its own ring
+	s.runBlockStream(ctx, conn, lease, "ws_stream", "bitcoin", replay)
 }
 
 // healthHandler handles health check requests
@@ -842,6 +885,17 @@ func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
 		},
 		"server_addr": r.Host,
 	}
+	if s.timeSync != nil {
+		resp["clock"] = s.timeSync.Status()
+	}
+	if canary := s.CanaryStatus(); len(canary) > 0 {
+		resp["canary"] = canary
+		for _, st := range canary {
+			if !st.Agree {
+				resp["status"] = "degraded" // Sources disagree; still serving
+			}
+		}
+	}
 
 	s.turboJsonResponse(w, http.StatusOK, resp)
 }
@@ -904,6 +958,14 @@ func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	metrics = append(metrics, "# HELP bitcoin_sprint_fastpath_status_hits_total Total number of /v1/btc/status endpoint hits")
 	metrics = append(metrics, "# TYPE bitcoin_sprint_fastpath_status_hits_total counter")
 	metrics = append(metrics, fmt.Sprintf("bitcoin_sprint_fastpath_status_hits_total %d", fastpath.GetStatusHits()))
+
+	metrics = append(metrics, "# HELP bitcoin_sprint_fastpath_chain_hits_total Fastpath snapshot hits per chain and endpoint")
+	metrics = append(metrics, "# TYPE bitcoin_sprint_fastpath_chain_hits_total counter")
+	for chain, hits := range fastpath.Default.Stats() {
+		for endpoint, n := range hits {
+			metrics = append(metrics, fmt.Sprintf("bitcoin_sprint_fastpath_chain_hits_total{chain=%q,endpoint=%q} %d", chain, endpoint, n))
+		}
+	}
 	
 	metrics = append(metrics, "# HELP bitcoin_sprint_fastpath_latency_target Targeted p99 latency in milliseconds")
 	metrics = append(metrics, "# TYPE bitcoin_sprint_fastpath_latency_target gauge")
@@ -928,6 +990,10 @@ func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 	for _, metric := range metrics {
 		fmt.Fprintln(w, metric)
 	}
+
+	// Runtime, process and application collectors
+	fmt.Fprintln(w)
+	s.writeRegistryMetrics(w)
 }
 
 // versionHandler handles version information requests
@@ -1047,7 +1113,10 @@ func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
 		status["ethereum_connections"] = 0
 	}
 
-	s.jsonResponse(w, http.StatusOK, status)
+	// Latest block age per chain against the staleness SLA
+	status["tip_sla"] = s.TipStatus()
+
+	s.conditionalJSON(w, r, "", status)
 }
 
 // mempoolHandler handles mempool information requests
@@ -1068,13 +1137,19 @@ func (s *Server) mempoolHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	mempoolSize := backend.GetMempoolSize()
-
 	resp := map[string]interface{}{
-		"size":      mempoolSize,
+		"size":      backend.GetMempoolSize(),
 		"timestamp": s.clock.Now().UTC().Format(time.RFC3339),
 	}
 
+	// Include vsize and fee histogram so fee estimators get actionable data
+	if s.mem != nil {
+		summary := s.mem.Summary(mempool.DefaultFeeBuckets)
+		resp["vsize"] = summary.VSize
+		resp["median_fee_rate"] = summary.MedianFeeRate
+		resp["fee_histogram"] = summary.FeeHistogram
+	}
+
 	s.jsonResponse(w, http.StatusOK, resp)
 }
 
@@ -1157,6 +1232,16 @@ func (s *Server) chainAwareHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Feed the latency model; streams are long-lived and broadcasts wait
+	// on upstream nodes, either would skew it
+	if latencyOptimizer != nil && endpoint != "stream" && endpoint != "tx" {
+		start := time.Now()
+		latencyOptimizer.TrackKey(chain, endpoint)
+		defer func() {
+			latencyOptimizer.TrackRequest(chain, time.Since(start))
+		}()
+	}
+
 	// Route to appropriate handler based on endpoint
 	switch endpoint {
 	case "latest":
@@ -1167,6 +1252,16 @@ func (s *Server) chainAwareHandler(w http.ResponseWriter, r *http.Request) {
 		s.chainStreamHandler(backend, w, r)
 	case "metrics":
 		s.chainMetricsHandler(backend, w, r)
+	case "mempool":
+		s.chainMempoolHandler(chain, pathParts[3:], w, r)
+	case "blocks":
+		s.chainBlocksHandler(chain, w, r)
+	case "block":
+		s.chainBlockHandler(chain, pathParts[3:], w, r)
+	case "fees":
+		s.chainFeesHandler(chain, w, r)
+	case "tx":
+		s.chainTxHandler(chain, w, r)
 	default:
 		http.Error(w, fmt.Sprintf("Unknown endpoint '%s'", endpoint), http.StatusNotFound)
 	}
@@ -1188,7 +1283,7 @@ func (s *Server) chainLatestHandler(backend ChainBackend, w http.ResponseWriter,
 		return
 	}
 
-	s.jsonResponse(w, http.StatusOK, block)
+	s.conditionalJSON(w, r, fastpath.BlockETag(normalizeChainName(string(block.Chain)), block.Hash), block)
 }
 
 // chainStatusHandler handles /v1/{chain}/status requests
@@ -1199,7 +1294,7 @@ func (s *Server) chainStatusHandler(backend ChainBackend, w http.ResponseWriter,
 	}
 
 	status := backend.GetStatus()
-	s.jsonResponse(w, http.StatusOK, status)
+	s.conditionalJSON(w, r, "", status)
 }
 
 // chainMetricsHandler handles /v1/{chain}/metrics requests
@@ -1209,9 +1304,13 @@ func (s *Server) chainMetricsHandler(backend ChainBackend, w http.ResponseWriter
 		return
 	}
 
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	chain := normalizeChainName(pathParts[1]) // Already validated in chainAwareHandler
+
 	metrics := map[string]interface{}{
 		"mempool_size":   backend.GetMempoolSize(),
 		"predictive_eta": backend.GetPredictiveETA(),
+		"eta":            s.predictor.GetPredictiveETA(chain),
 		"timestamp":      s.clock.Now().UTC().Format(time.RFC3339),
 	}
 
@@ -1224,13 +1323,23 @@ func (s *Server) chainStreamHandler(backend ChainBackend, w http.ResponseWriter,
 	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	chain := pathParts[1] // Already validated in chainAwareHandler
 
-	// Acquire WebSocket connection for specific chain
-	clientIP := getClientIP(r)
-	if !s.wsLimiter.AcquireForChain(clientIP, chain) {
-		http.Error(w, fmt.Sprintf("WebSocket connection limit reached for %s chain", chain), http.StatusTooManyRequests)
+	// Optional catch-up replay (?from_height= or ?from_time=)
+	replay, err := parseStreamReplay(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := parseStreamEncoding(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer s.wsLimiter.ReleaseForChain(clientIP, chain)
+
+	// Acquire WebSocket stream slot for the specific chain
+	lease, ok := s.acquireStream(w, r, chain)
+	if !ok {
+		return
+	}
+	defer lease.Release()
 
 	// WebSocket upgrade logic (similar to existing streamHandler)
 	upgrader := websocket.Upgrader{
@@ -1258,7 +1367,7 @@ func (s *Server) chainStreamHandler(backend ChainBackend, w http.ResponseWriter,
 		HandshakeTimeout: 10 * time.Second,
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := s.upgradeStream(w, r, &upgrader, lease)
 	if err != nil {
 		s.logger.Error("Failed to upgrade to WebSocket", zap.Error(err))
 		return
@@ -1268,12 +1377,14 @@ func (s *Server) chainStreamHandler(backend ChainBackend, w http.ResponseWriter,
 	conn.SetReadDeadline(s.clock.Now().Add(60 * time.Second))
 
 	conn.SetPingHandler(func(string) error {
+		lease.Touch()
 		conn.SetReadDeadline(s.clock.Now().Add(60 * time.Second))
 		return conn.WriteControl(websocket.PongMessage, []byte{}, s.clock.Now().Add(10*time.Second))
 	})
 
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
+	lease.Bind(cancel)
 
 	// Start reader goroutine
 	go func() {
@@ -1282,39 +1393,32 @@ func (s *Server) chainStreamHandler(backend ChainBackend, w http.ResponseWriter,
 			if _, _, err := conn.ReadMessage(); err != nil {
 				return
 			}
+			lease.Touch()
 			conn.SetReadDeadline(s.clock.Now().Add(60 * time.Second))
 		}
 	}()
 
-	// Stream blocks from the specific chain backend
-	blockChan := make(chan blocks.BlockEvent, 100)
-	go backend.StreamBlocks(ctx, blockChan)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case blk := <-blockChan:
-			conn.SetWriteDeadline(s.clock.Now().Add(10 * time.Second))
-			if err := conn.WriteJSON(blk); err != nil {
-				s.logger.Debug("Error writing to WebSocket", zap.Error(err))
-				return
-			}
-		}
-	}
+	// Stream blocks for the specific chain from the block bus
+	s.runBlockStream(ctx, conn, lease, "ws_chain_stream", normalizeChainName(chain), replay)
 }
 
 // ===== SIMPLE INLINE COMPONENT HANDLERS =====
 
-// simpleLatencyHandler provides basic latency information
+// simpleLatencyHandler reports the per-chain latency model and its recent
+// timeout and pre-warm decisions
 func (s *Server) simpleLatencyHandler(w http.ResponseWriter, r *http.Request) {
-	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
-		"endpoint":    "/api/v1/latency",
-		"description": "Simple latency monitoring endpoint",
-		"status":      "active",
-		"target_p99":  "100ms",
-		"note":        "Inlined latency optimizer available",
-	})
+	if latencyOptimizer == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"error": "Latency optimizer not initialized",
+		})
+		return
+	}
+
+	response := latencyOptimizer.Snapshot()
+	response["endpoint"] = "/api/v1/latency"
+	response["decisions"] = latencyOptimizer.Decisions()
+	response["timestamp"] = s.clock.Now().UTC().Format(time.RFC3339)
+	s.jsonResponse(w, http.StatusOK, response)
 }
 
 // simpleCacheHandler provides basic cache information
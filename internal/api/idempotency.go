@@ -0,0 +1,247 @@
+// Package api provides Idempotency-Key handling for mutating endpoints
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== IDEMPOTENCY KEYS =====
+
+// Clients retry key provisioning, keystore saves and webhook registrations
+// after timeouts, and each retry used to create a duplicate. A request
+// carrying an Idempotency-Key header runs once; repeats within the TTL get
+// the original response back with Idempotent-Replayed: true. Keys are
+// scoped to the caller's credentials, method and path, so two customers
+// cannot collide, and reusing a key with a different body is rejected.
+
+const (
+	idempotencyNamespace  = "idem:"
+	idempotencyMaxKey     = 255     // Longest Idempotency-Key accepted
+	idempotencyMaxRequest = 8 << 20 // Request bodies read to fingerprint
+	idempotencyMaxBody    = 1 << 20 // Larger responses are not kept for replay
+	idempotencyMaxEntries = 10000
+)
+
+var idempotencyRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "api_idempotency_requests_total",
+	Help: "Requests carrying an Idempotency-Key by outcome",
+}, []string{"result"}) // stored, replayed, in_progress, mismatch, not_stored
+
+// idempotentResponse is a response kept for replay
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"` // SHA-256 of the request body
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header"`
+	Body        []byte      `json:"body"`
+}
+
+// idempotencyStore keeps responses in the server cache and tracks keys
+// whose first request is still running
+type idempotencyStore struct {
+	cache *cache.Cache
+	ttl   time.Duration
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// newIdempotencyStore returns the store; nil when disabled or without a cache
+func newIdempotencyStore(s *Server) *idempotencyStore {
+	if s.cache == nil || s.cfg.IdempotencyTTL <= 0 {
+		return nil
+	}
+	err := s.cache.SetNamespace(cache.NamespaceConfig{
+		Prefix:     idempotencyNamespace,
+		DefaultTTL: s.cfg.IdempotencyTTL,
+		MaxTTL:     s.cfg.IdempotencyTTL,
+		Admission:  cache.AdmissionAlways, // A stored response must survive until the client retries
+		MaxEntries: idempotencyMaxEntries,
+	})
+	if err != nil {
+		s.logger.Warn("Idempotency keys disabled: cache namespace rejected", zap.Error(err))
+		return nil
+	}
+	return &idempotencyStore{
+		cache:    s.cache,
+		ttl:      s.cfg.IdempotencyTTL,
+		inFlight: make(map[string]bool),
+	}
+}
+
+// begin returns the stored response for key, or claims key for a new
+// request. busy is set when another request with key is still running.
+func (st *idempotencyStore) begin(key string) (stored *idempotentResponse, busy bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if v, ok := st.cache.Get(key); ok {
+		if resp, ok := v.(*idempotentResponse); ok {
+			return resp, false
+		}
+	}
+	if st.inFlight[key] {
+		return nil, true
+	}
+	st.inFlight[key] = true
+	return nil, false
+}
+
+// finish stores resp for key, if given, and releases the claim
+func (st *idempotencyStore) finish(key string, resp *idempotentResponse) error {
+	var err error
+	if resp != nil {
+		err = st.cache.Set(key, resp, st.ttl)
+	}
+	st.mu.Lock()
+	delete(st.inFlight, key)
+	st.mu.Unlock()
+	return err
+}
+
+// idempotent makes next replay its first response to repeated requests
+// with the same Idempotency-Key. Requests without the header, and all
+// requests when the store is disabled, pass straight through.
+func (s *Server) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" || s.idempotency == nil || !idempotentMethod(r.Method) {
+			next(w, r)
+			return
+		}
+		if len(key) > idempotencyMaxKey {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+				"error": "Idempotency-Key must be at most " + strconv.Itoa(idempotencyMaxKey) + " characters",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotencyMaxRequest))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				s.jsonResponse(w, http.StatusRequestEntityTooLarge, map[string]string{"error": "request body too large"})
+				return
+			}
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "failed to read request body"})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+		fingerprint := hex.EncodeToString(sum[:])
+		cacheKey := idempotencyCacheKey(r, key)
+
+		stored, busy := s.idempotency.begin(cacheKey)
+		switch {
+		case busy:
+			idempotencyRequests.WithLabelValues("in_progress").Inc()
+			w.Header().Set("Retry-After", "1")
+			s.jsonResponse(w, http.StatusConflict, map[string]string{
+				"error": "a request with this Idempotency-Key is still in progress",
+			})
+			return
+		case stored != nil && stored.Fingerprint != fingerprint:
+			idempotencyRequests.WithLabelValues("mismatch").Inc()
+			s.jsonResponse(w, http.StatusUnprocessableEntity, map[string]string{
+				"error": "Idempotency-Key was already used with a different request body",
+			})
+			return
+		case stored != nil:
+			idempotencyRequests.WithLabelValues("replayed").Inc()
+			for k, v := range stored.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w, status: http.StatusOK}
+		var resp *idempotentResponse
+		defer func() {
+			if err := s.idempotency.finish(cacheKey, resp); err != nil {
+				s.logger.Debug("Failed to store idempotent response", zap.Error(err))
+			}
+		}()
+		next(rec, r)
+
+		// Server errors are left retryable rather than replayed
+		if rec.status >= http.StatusInternalServerError || rec.truncated {
+			idempotencyRequests.WithLabelValues("not_stored").Inc()
+			return
+		}
+		idempotencyRequests.WithLabelValues("stored").Inc()
+		resp = &idempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      rec.status,
+			Header:      rec.header,
+			Body:        rec.body.Bytes(),
+		}
+	}
+}
+
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotencyCacheKey scopes key to the caller's credentials and the route,
+// hashed so no credential ends up in the cache
+func idempotencyCacheKey(r *http.Request, key string) string {
+	h := sha256.New()
+	for _, cred := range []string{
+		r.Header.Get("X-Admin-Key"), r.URL.Query().Get("admin_key"),
+		r.Header.Get("X-API-Key"), r.URL.Query().Get("api_key"),
+		r.Method, r.URL.Path, key,
+	} {
+		h.Write([]byte(cred))
+		h.Write([]byte{0})
+	}
+	return idempotencyNamespace + hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecorder passes the response through while keeping a copy
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status    int
+	header    http.Header
+	body      bytes.Buffer
+	truncated bool
+	wrote     bool
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if !rec.wrote {
+		rec.status = code
+		rec.header = rec.ResponseWriter.Header().Clone()
+		rec.wrote = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(p []byte) (int, error) {
+	if !rec.wrote {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.body.Len()+len(p) > idempotencyMaxBody {
+		rec.truncated = true
+	} else if !rec.truncated {
+		rec.body.Write(p)
+	}
+	return rec.ResponseWriter.Write(p)
+}
@@ -13,8 +13,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -22,13 +20,13 @@ import (
 	"go.uber.org/zap"
 )
 
-// KeystoreManager provides simple encrypted, file-backed key storage.
-// Each entry is saved as a JSON file under the configured directory with
-// a `.keystore` extension.
+// KeystoreManager provides simple encrypted key storage. Entries are
+// password-encrypted JSON blobs persisted through a KeystoreBackend; the
+// default backend saves each one as a `.keystore` file under a directory.
 type KeystoreManager struct {
-	dir    string
-	mu     sync.RWMutex
-	logger *zap.Logger
+	backend KeystoreBackend
+	mu      sync.RWMutex
+	logger  *zap.Logger
 }
 
 // keystoreFile is the on-disk JSON structure for a stored key blob.
@@ -50,24 +48,30 @@ type keystoreFile struct {
 // NewKeystoreManager creates or opens a keystore directory. If the directory
 // does not exist it will be created with 0700 permissions.
 func NewKeystoreManager(dir string, logger *zap.Logger) (*KeystoreManager, error) {
-	if dir == "" {
-		return nil, fmt.Errorf("keystore directory path is empty")
-	}
-
-	if err := os.MkdirAll(dir, 0o700); err != nil {
-		return nil, fmt.Errorf("failed to create keystore directory: %w", err)
+	backend, err := NewFileKeystoreBackend(dir)
+	if err != nil {
+		return nil, err
 	}
+	return NewKeystoreManagerWithBackend(backend, logger), nil
+}
 
+// NewKeystoreManagerWithBackend creates a keystore manager on top of backend.
+func NewKeystoreManagerWithBackend(backend KeystoreBackend, logger *zap.Logger) *KeystoreManager {
 	if logger == nil {
 		// Create a noop logger fallback
 		logger = zap.NewNop()
 	}
 
-	return &KeystoreManager{dir: dir, logger: logger}, nil
+	return &KeystoreManager{backend: backend, logger: logger}
+}
+
+// Backend returns the storage backend name
+func (ks *KeystoreManager) Backend() string {
+	return ks.backend.Name()
 }
 
 // Save encrypts the plaintext with the provided password and stores it under
-// the given id. If a file for the id already exists it will be overwritten.
+// the given id. If an entry for the id already exists it will be overwritten.
 func (ks *KeystoreManager) Save(id string, plaintext []byte, password string) error {
 	if id == "" {
 		return fmt.Errorf("id required")
@@ -89,18 +93,9 @@ func (ks *KeystoreManager) Save(id string, plaintext []byte, password string) er
 	p := 1
 	dkLen := 32
 
-	key, err := scrypt.Key([]byte(password), salt, N, r, p, dkLen)
+	gcm, err := deriveKeystoreAEAD(password, salt, N, r, p, dkLen)
 	if err != nil {
-		return fmt.Errorf("kdf failed: %w", err)
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return fmt.Errorf("failed to create cipher: %w", err)
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return fmt.Errorf("failed to create gcm: %w", err)
+		return err
 	}
 
 	nonce := make([]byte, gcm.NonceSize())
@@ -123,36 +118,46 @@ func (ks *KeystoreManager) Save(id string, plaintext []byte, password string) er
 	f.Nonce = base64.StdEncoding.EncodeToString(nonce)
 	f.Ciphertext = base64.StdEncoding.EncodeToString(ciphertext)
 
-	// Marshal and persist atomically
 	data, err := json.MarshalIndent(&f, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal keystore file: %w", err)
 	}
 
-	target := filepath.Join(ks.dir, fmt.Sprintf("%s.keystore", id))
-	tmp := target + ".tmp"
-
-	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		return fmt.Errorf("failed to write keystore file: %w", err)
-	}
-	if err := os.Rename(tmp, target); err != nil {
-		return fmt.Errorf("failed to atomically persist keystore file: %w", err)
+	if err := ks.backend.Put(id, data); err != nil {
+		return err
 	}
 
 	ks.logger.Info("keystore saved", zap.String("id", id))
 	return nil
 }
 
+// deriveKeystoreAEAD derives an AES-GCM cipher from a password using scrypt.
+func deriveKeystoreAEAD(password string, salt []byte, N, r, p, dkLen int) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), salt, N, r, p, dkLen)
+	if err != nil {
+		return nil, fmt.Errorf("kdf failed: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return gcm, nil
+}
+
 // Load decrypts and returns the plaintext stored under id using the password.
 func (ks *KeystoreManager) Load(id string, password string) ([]byte, error) {
 	if id == "" {
 		return nil, fmt.Errorf("id required")
 	}
 
-	target := filepath.Join(ks.dir, fmt.Sprintf("%s.keystore", id))
-	data, err := os.ReadFile(target)
+	data, err := ks.backend.Get(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+		return nil, err
 	}
 
 	var f keystoreFile
@@ -165,18 +170,9 @@ func (ks *KeystoreManager) Load(id string, password string) ([]byte, error) {
 		return nil, fmt.Errorf("invalid salt encoding: %w", err)
 	}
 
-	key, err := scrypt.Key([]byte(password), salt, f.KDF.N, f.KDF.R, f.KDF.P, f.KDF.DKLen)
-	if err != nil {
-		return nil, fmt.Errorf("kdf failed: %w", err)
-	}
-
-	block, err := aes.NewCipher(key)
+	gcm, err := deriveKeystoreAEAD(password, salt, f.KDF.N, f.KDF.R, f.KDF.P, f.KDF.DKLen)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gcm: %w", err)
+		return nil, err
 	}
 
 	nonce, err := base64.StdEncoding.DecodeString(f.Nonce)
@@ -197,40 +193,24 @@ func (ks *KeystoreManager) Load(id string, password string) ([]byte, error) {
 	return plaintext, nil
 }
 
-// List returns all stored keystore IDs in the directory (without extension).
+// List returns all stored keystore IDs.
 func (ks *KeystoreManager) List() ([]string, error) {
-	entries, err := os.ReadDir(ks.dir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read keystore dir: %w", err)
-	}
-
-	ids := make([]string, 0, len(entries))
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		name := e.Name()
-		if filepath.Ext(name) == ".keystore" {
-			ids = append(ids, name[:len(name)-len(".keystore")])
-		}
-	}
-	return ids, nil
+	return ks.backend.List()
 }
 
-// Delete removes an on-disk keystore entry
+// Delete removes a keystore entry
 func (ks *KeystoreManager) Delete(id string) error {
 	if id == "" {
 		return fmt.Errorf("id required")
 	}
-	target := filepath.Join(ks.dir, fmt.Sprintf("%s.keystore", id))
-	if err := os.Remove(target); err != nil {
-		return fmt.Errorf("failed to delete keystore file: %w", err)
+	if err := ks.backend.Delete(id); err != nil {
+		return err
 	}
 	ks.logger.Info("keystore deleted", zap.String("id", id))
 	return nil
 }
 
-// ImportRaw persists a raw, pre-encrypted keystore JSON blob (verbatim) to disk.
+// ImportRaw persists a raw, pre-encrypted keystore JSON blob (verbatim).
 // This is useful when importing keystore files created externally.
 func (ks *KeystoreManager) ImportRaw(id string, raw []byte) error {
 	if id == "" {
@@ -241,9 +221,8 @@ func (ks *KeystoreManager) ImportRaw(id string, raw []byte) error {
 	if err := json.Unmarshal(raw, &f); err != nil {
 		return fmt.Errorf("invalid keystore json: %w", err)
 	}
-	target := filepath.Join(ks.dir, fmt.Sprintf("%s.keystore", id))
-	if err := os.WriteFile(target, raw, 0o600); err != nil {
-		return fmt.Errorf("failed to write keystore file: %w", err)
+	if err := ks.backend.Put(id, raw); err != nil {
+		return err
 	}
 	ks.logger.Info("keystore imported", zap.String("id", id))
 	return nil
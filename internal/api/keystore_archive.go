@@ -0,0 +1,258 @@
+// Package api provides passphrase-wrapped export and import of the whole
+// keystore. An archive is a single JSON envelope whose payload (every raw
+// keystore file plus an integrity manifest) is encrypted with AES-GCM under
+// a scrypt-derived key. Entries stay encrypted with their own passwords, so
+// the archive passphrase only protects the bundle in transit.
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const keystoreArchiveVersion = 1
+
+// keystoreArchive is the outer, unencrypted envelope of an export.
+type keystoreArchive struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Cipher    string    `json:"cipher"`
+	KDF       struct {
+		Salt  string `json:"salt"`
+		N     int    `json:"n"`
+		R     int    `json:"r"`
+		P     int    `json:"p"`
+		DKLen int    `json:"dklen"`
+	} `json:"kdf"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// KeystoreManifestEntry records the size and digest of one archived keystore file.
+type KeystoreManifestEntry struct {
+	ID     string `json:"id"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// KeystoreManifest lists archive contents. Digest covers all entries in order.
+type KeystoreManifest struct {
+	CreatedAt time.Time               `json:"created_at"`
+	Entries   []KeystoreManifestEntry `json:"entries"`
+	Digest    string                  `json:"digest"`
+}
+
+// keystoreArchivePayload is the plaintext sealed inside the archive.
+type keystoreArchivePayload struct {
+	Manifest KeystoreManifest           `json:"manifest"`
+	Entries  map[string]json.RawMessage `json:"entries"`
+}
+
+// KeystoreImportResult summarises an ImportAll run.
+type KeystoreImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped"`
+}
+
+// validKeystoreID rejects ids that could escape the keystore directory.
+func validKeystoreID(id string) bool {
+	return id != "" && id != "." && id != ".." &&
+		!strings.ContainsAny(id, `/\`) && !strings.Contains(id, "..")
+}
+
+// manifestDigest hashes the ordered entry list so reordering or dropping
+// entries is detected in addition to per-entry tampering.
+func manifestDigest(entries []KeystoreManifestEntry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s:%s:%d\n", e.ID, e.SHA256, e.Size)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ExportAll bundles every keystore entry into one archive encrypted with passphrase.
+func (ks *KeystoreManager) ExportAll(passphrase string) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase required")
+	}
+
+	ks.mu.RLock()
+	ids, err := ks.List()
+	if err != nil {
+		ks.mu.RUnlock()
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	payload := keystoreArchivePayload{
+		Manifest: KeystoreManifest{CreatedAt: time.Now().UTC(), Entries: make([]KeystoreManifestEntry, 0, len(ids))},
+		Entries:  make(map[string]json.RawMessage, len(ids)),
+	}
+	for _, id := range ids {
+		raw, err := ks.backend.Get(id)
+		if err != nil {
+			ks.mu.RUnlock()
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		payload.Entries[id] = raw
+		payload.Manifest.Entries = append(payload.Manifest.Entries, KeystoreManifestEntry{
+			ID:     id,
+			SHA256: hex.EncodeToString(sum[:]),
+			Size:   len(raw),
+		})
+	}
+	ks.mu.RUnlock()
+	payload.Manifest.Digest = manifestDigest(payload.Manifest.Entries)
+
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive payload: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	N, r, p, dkLen := 1<<15, 8, 1, 32
+	gcm, err := deriveKeystoreAEAD(passphrase, salt, N, r, p, dkLen)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	archive := keystoreArchive{
+		Version:   keystoreArchiveVersion,
+		CreatedAt: payload.Manifest.CreatedAt,
+		Cipher:    "AES-GCM",
+	}
+	archive.KDF.Salt = base64.StdEncoding.EncodeToString(salt)
+	archive.KDF.N = N
+	archive.KDF.R = r
+	archive.KDF.P = p
+	archive.KDF.DKLen = dkLen
+	archive.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	archive.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, plaintext, nil))
+
+	out, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode archive: %w", err)
+	}
+	ks.logger.Info("keystore exported", zap.Int("entries", len(ids)))
+	return out, nil
+}
+
+// ImportAll decrypts an archive produced by ExportAll, verifies its manifest
+// and writes every entry. Existing ids are skipped unless overwrite is set.
+// Nothing is written if any integrity check fails.
+func (ks *KeystoreManager) ImportAll(data []byte, passphrase string, overwrite bool) (*KeystoreImportResult, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase required")
+	}
+
+	var archive keystoreArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if archive.Version != keystoreArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", archive.Version)
+	}
+	if archive.Cipher != "AES-GCM" {
+		return nil, fmt.Errorf("unsupported archive cipher %q", archive.Cipher)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(archive.KDF.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt encoding: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(archive.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nonce encoding: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(archive.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ciphertext encoding: %w", err)
+	}
+
+	gcm, err := deriveKeystoreAEAD(passphrase, salt, archive.KDF.N, archive.KDF.R, archive.KDF.P, archive.KDF.DKLen)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length")
+	}
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decryption failed: %w", err)
+	}
+
+	var payload keystoreArchivePayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		return nil, fmt.Errorf("invalid archive payload: %w", err)
+	}
+
+	// Verify everything before touching disk
+	if manifestDigest(payload.Manifest.Entries) != payload.Manifest.Digest {
+		return nil, fmt.Errorf("manifest digest mismatch")
+	}
+	if len(payload.Manifest.Entries) != len(payload.Entries) {
+		return nil, fmt.Errorf("manifest lists %d entries, archive has %d", len(payload.Manifest.Entries), len(payload.Entries))
+	}
+	for _, e := range payload.Manifest.Entries {
+		if !validKeystoreID(e.ID) {
+			return nil, fmt.Errorf("invalid keystore id %q", e.ID)
+		}
+		raw, ok := payload.Entries[e.ID]
+		if !ok {
+			return nil, fmt.Errorf("entry %s missing from archive", e.ID)
+		}
+		sum := sha256.Sum256(raw)
+		if len(raw) != e.Size || hex.EncodeToString(sum[:]) != e.SHA256 {
+			return nil, fmt.Errorf("entry %s failed integrity check", e.ID)
+		}
+		var f keystoreFile
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("entry %s is not a keystore file: %w", e.ID, err)
+		}
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	existing := make(map[string]bool)
+	if ids, err := ks.List(); err == nil {
+		for _, id := range ids {
+			existing[id] = true
+		}
+	}
+
+	result := &KeystoreImportResult{Imported: []string{}, Skipped: []string{}}
+	for _, e := range payload.Manifest.Entries {
+		if existing[e.ID] && !overwrite {
+			result.Skipped = append(result.Skipped, e.ID)
+			continue
+		}
+		if err := ks.ImportRaw(e.ID, payload.Entries[e.ID]); err != nil {
+			return result, err
+		}
+		result.Imported = append(result.Imported, e.ID)
+	}
+
+	ks.logger.Info("keystore archive imported",
+		zap.Int("imported", len(result.Imported)),
+		zap.Int("skipped", len(result.Skipped)))
+	return result, nil
+}
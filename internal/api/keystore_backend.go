@@ -0,0 +1,187 @@
+// Package api provides the storage backends behind the keystore manager.
+// The manager always encrypts entries with their password before handing
+// them to a backend; backends add a second layer appropriate to the host:
+// plain files, the OS secret store, or an HSM-held wrapping key.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+// Keystore backend names accepted by config.KeystoreBackend
+const (
+	KeystoreBackendFile   = "file"
+	KeystoreBackendOS     = "os"
+	KeystoreBackendPKCS11 = "pkcs11"
+
+	defaultKeystoreService = "bitcoin-sprint-keystore"
+)
+
+// ErrKeystoreNotFound is returned by backends when an id has no entry.
+var ErrKeystoreNotFound = errors.New("keystore entry not found")
+
+// KeystoreBackend persists opaque keystore blobs by id.
+type KeystoreBackend interface {
+	// Name identifies the backend in logs and API responses
+	Name() string
+	// Put stores blob under id, replacing any existing entry
+	Put(id string, blob []byte) error
+	// Get returns the blob stored under id
+	Get(id string) ([]byte, error)
+	// List returns every stored id
+	List() ([]string, error)
+	// Delete removes the entry for id
+	Delete(id string) error
+}
+
+// PKCS11Config selects the HSM token and AES wrapping key for the pkcs11 backend.
+type PKCS11Config struct {
+	ModulePath string // Path to the vendor PKCS#11 library
+	TokenLabel string // Token label; empty selects the first present token
+	PIN        string // User PIN
+	KeyLabel   string // Label of an existing AES key on the token
+}
+
+// NewKeystoreBackend builds the backend selected by cfg.KeystoreBackend.
+func NewKeystoreBackend(cfg config.Config, logger *zap.Logger) (KeystoreBackend, error) {
+	dir := cfg.KeystoreDir
+	if dir == "" {
+		dir = filepath.Join("data", "keystore")
+	}
+
+	switch strings.ToLower(cfg.KeystoreBackend) {
+	case "", KeystoreBackendFile:
+		return NewFileKeystoreBackend(dir)
+	case KeystoreBackendOS:
+		return newOSKeystoreBackend(dir, cfg.KeystoreService)
+	case KeystoreBackendPKCS11:
+		return newPKCS11KeystoreBackend(dir, PKCS11Config{
+			ModulePath: cfg.KeystorePKCS11Module,
+			TokenLabel: cfg.KeystorePKCS11Token,
+			PIN:        cfg.KeystorePKCS11PIN,
+			KeyLabel:   cfg.KeystorePKCS11KeyLabel,
+		}, logger)
+	default:
+		return nil, fmt.Errorf("unknown keystore backend %q", cfg.KeystoreBackend)
+	}
+}
+
+// newKeystoreFromConfig opens the configured keystore for the API server.
+func newKeystoreFromConfig(cfg config.Config, logger *zap.Logger) (*KeystoreManager, error) {
+	backend, err := NewKeystoreBackend(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Keystore initialized", zap.String("backend", backend.Name()))
+	return NewKeystoreManagerWithBackend(backend, logger), nil
+}
+
+// ===== FILE BACKEND =====
+
+// FileKeystoreBackend stores each entry as <dir>/<id><ext> with 0600 permissions.
+type FileKeystoreBackend struct {
+	dir string
+	ext string
+}
+
+// NewFileKeystoreBackend creates or opens a keystore directory. If the
+// directory does not exist it will be created with 0700 permissions.
+func NewFileKeystoreBackend(dir string) (*FileKeystoreBackend, error) {
+	return newFileBlobStore(dir, ".keystore")
+}
+
+func newFileBlobStore(dir, ext string) (*FileKeystoreBackend, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("keystore directory path is empty")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create keystore directory: %w", err)
+	}
+	return &FileKeystoreBackend{dir: dir, ext: ext}, nil
+}
+
+// Name returns the backend name
+func (fb *FileKeystoreBackend) Name() string {
+	return KeystoreBackendFile
+}
+
+func (fb *FileKeystoreBackend) path(id string) (string, error) {
+	if !validKeystoreID(id) {
+		return "", fmt.Errorf("invalid keystore id %q", id)
+	}
+	return filepath.Join(fb.dir, id+fb.ext), nil
+}
+
+// Put writes the blob atomically via a temporary file and rename
+func (fb *FileKeystoreBackend) Put(id string, blob []byte) error {
+	target, err := fb.path(id)
+	if err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, blob, 0o600); err != nil {
+		return fmt.Errorf("failed to write keystore file: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		return fmt.Errorf("failed to atomically persist keystore file: %w", err)
+	}
+	return nil
+}
+
+// Get reads the blob for id
+func (fb *FileKeystoreBackend) Get(id string) ([]byte, error) {
+	target, err := fb.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrKeystoreNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore file: %w", err)
+	}
+	return data, nil
+}
+
+// List returns ids of all files with the backend extension
+func (fb *FileKeystoreBackend) List() ([]string, error) {
+	entries, err := os.ReadDir(fb.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore dir: %w", err)
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if filepath.Ext(name) == fb.ext {
+			ids = append(ids, strings.TrimSuffix(name, fb.ext))
+		}
+	}
+	return ids, nil
+}
+
+// Delete removes the file for id
+func (fb *FileKeystoreBackend) Delete(id string) error {
+	target, err := fb.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrKeystoreNotFound
+		}
+		return fmt.Errorf("failed to delete keystore file: %w", err)
+	}
+	return nil
+}
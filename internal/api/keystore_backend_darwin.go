@@ -0,0 +1,183 @@
+//go:build darwin
+
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// keychainIndexAccount holds the JSON list of ids; the security CLI cannot
+// enumerate the accounts of a service on its own.
+const keychainIndexAccount = "__index__"
+
+// keychainItemNotFound is the security tool's exit status for
+// errSecItemNotFound
+const keychainItemNotFound = 44
+
+// keychainBackend stores entries as generic passwords in the macOS login
+// keychain through the security CLI.
+type keychainBackend struct {
+	service string
+	bin     string
+	mu      sync.Mutex
+}
+
+func newOSKeystoreBackend(_ string, service string) (KeystoreBackend, error) {
+	bin, err := exec.LookPath("security")
+	if err != nil {
+		return nil, fmt.Errorf("os keystore requires the macOS security tool: %w", err)
+	}
+	if service == "" {
+		service = defaultKeystoreService
+	}
+	// The service is quoted in commands written to security -i
+	if strings.ContainsAny(service, "\"\\\n\r") {
+		return nil, fmt.Errorf("keystore service %q must not contain quotes, backslashes or newlines", service)
+	}
+	return &keychainBackend{service: service, bin: bin}, nil
+}
+
+func (kb *keychainBackend) Name() string {
+	return "keychain"
+}
+
+func (kb *keychainBackend) run(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(kb.bin, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("security %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// notFound reports whether err is the security tool failing because the
+// item does not exist, as opposed to a locked keychain or denied access
+func notFound(err error) bool {
+	var exitErr *exec.ExitError
+	return errors.As(err, &exitErr) && exitErr.ExitCode() == keychainItemNotFound
+}
+
+func (kb *keychainBackend) put(account string, blob []byte) error {
+	// The secret would be visible to every local user in the process
+	// list as an argument, so the command is written to security -i on
+	// stdin. -U updates an existing item in place. security -i keeps
+	// going after a failed command, so anything on stderr is a failure.
+	command := fmt.Sprintf("add-generic-password -U -s \"%s\" -a \"%s\" -w \"%s\"\n",
+		kb.service, account, base64.StdEncoding.EncodeToString(blob))
+	var stderr bytes.Buffer
+	cmd := exec.Command(kb.bin, "-i")
+	cmd.Stdin = strings.NewReader(command)
+	cmd.Stderr = &stderr
+	if _, err := cmd.Output(); err != nil {
+		return fmt.Errorf("security add-generic-password failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 {
+		return fmt.Errorf("security add-generic-password failed: %s", strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func (kb *keychainBackend) get(account string) ([]byte, error) {
+	out, err := kb.run("find-generic-password", "-s", kb.service, "-a", account, "-w")
+	if notFound(err) {
+		return nil, ErrKeystoreNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(string(out)))
+}
+
+// index returns the stored ids. Only a missing index is empty; any other
+// failure is returned so a locked keychain cannot lead to the index being
+// overwritten.
+func (kb *keychainBackend) index() ([]string, error) {
+	raw, err := kb.get(keychainIndexAccount)
+	if errors.Is(err, ErrKeystoreNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(raw, &ids); err != nil {
+		return nil, fmt.Errorf("keychain index: %w", err)
+	}
+	return ids, nil
+}
+
+func (kb *keychainBackend) setIndex(ids []string) error {
+	sort.Strings(ids)
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return kb.put(keychainIndexAccount, raw)
+}
+
+func (kb *keychainBackend) Put(id string, blob []byte) error {
+	if !validKeystoreID(id) || id == keychainIndexAccount {
+		return fmt.Errorf("invalid keystore id %q", id)
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if err := kb.put(id, blob); err != nil {
+		return err
+	}
+	ids, err := kb.index()
+	if err != nil {
+		return err
+	}
+	for _, existing := range ids {
+		if existing == id {
+			return nil
+		}
+	}
+	return kb.setIndex(append(ids, id))
+}
+
+func (kb *keychainBackend) Get(id string) ([]byte, error) {
+	if id == keychainIndexAccount {
+		return nil, ErrKeystoreNotFound
+	}
+	return kb.get(id)
+}
+
+func (kb *keychainBackend) List() ([]string, error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	return kb.index()
+}
+
+func (kb *keychainBackend) Delete(id string) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	_, err := kb.run("delete-generic-password", "-s", kb.service, "-a", id)
+	if notFound(err) {
+		return ErrKeystoreNotFound
+	}
+	if err != nil {
+		return err
+	}
+	ids, err := kb.index()
+	if err != nil {
+		return err
+	}
+	kept := ids[:0]
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kb.setIndex(kept)
+}
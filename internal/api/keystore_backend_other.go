@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package api
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newOSKeystoreBackend(_ string, _ string) (KeystoreBackend, error) {
+	return nil, fmt.Errorf("os keystore backend is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,79 @@
+//go:build windows
+
+package api
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// dpapiBackend wraps entries with Windows DPAPI (CryptProtectData) under the
+// service account's credentials before writing them to disk, so a copied
+// keystore directory is useless on another machine or account.
+type dpapiBackend struct {
+	files   *FileKeystoreBackend
+	entropy []byte
+}
+
+func newOSKeystoreBackend(dir string, service string) (KeystoreBackend, error) {
+	files, err := newFileBlobStore(dir, ".dpapi")
+	if err != nil {
+		return nil, err
+	}
+	if service == "" {
+		service = defaultKeystoreService
+	}
+	return &dpapiBackend{files: files, entropy: []byte(service)}, nil
+}
+
+func (db *dpapiBackend) Name() string {
+	return "dpapi"
+}
+
+func newDataBlob(b []byte) *windows.DataBlob {
+	if len(b) == 0 {
+		return &windows.DataBlob{}
+	}
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+}
+
+// takeDataBlob copies a DPAPI-allocated blob into Go memory and frees it
+func takeDataBlob(blob *windows.DataBlob) []byte {
+	out := make([]byte, blob.Size)
+	copy(out, unsafe.Slice(blob.Data, blob.Size))
+	windows.LocalFree(windows.Handle(unsafe.Pointer(blob.Data)))
+	return out
+}
+
+func (db *dpapiBackend) Put(id string, blob []byte) error {
+	var out windows.DataBlob
+	desc, _ := windows.UTF16PtrFromString(id)
+	if err := windows.CryptProtectData(newDataBlob(blob), desc, newDataBlob(db.entropy), 0, nil,
+		windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("dpapi protect failed: %w", err)
+	}
+	return db.files.Put(id, takeDataBlob(&out))
+}
+
+func (db *dpapiBackend) Get(id string) ([]byte, error) {
+	sealed, err := db.files.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(newDataBlob(sealed), nil, newDataBlob(db.entropy), 0, nil,
+		windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return nil, fmt.Errorf("dpapi unprotect failed: %w", err)
+	}
+	return takeDataBlob(&out), nil
+}
+
+func (db *dpapiBackend) List() ([]string, error) {
+	return db.files.List()
+}
+
+func (db *dpapiBackend) Delete(id string) error {
+	return db.files.Delete(id)
+}
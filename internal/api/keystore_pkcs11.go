@@ -0,0 +1,168 @@
+//go:build pkcs11 && cgo
+
+package api
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	"go.uber.org/zap"
+)
+
+const pkcs11IVSize = 12
+
+// pkcs11Backend seals entries with an AES key that never leaves the HSM and
+// stores the sealed blobs (iv || ciphertext+tag) on disk.
+type pkcs11Backend struct {
+	files   *FileKeystoreBackend
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	key     pkcs11.ObjectHandle
+	mu      sync.Mutex // PKCS#11 sessions are not safe for concurrent operations
+	logger  *zap.Logger
+}
+
+func newPKCS11KeystoreBackend(dir string, cfg PKCS11Config, logger *zap.Logger) (KeystoreBackend, error) {
+	if cfg.ModulePath == "" || cfg.KeyLabel == "" {
+		return nil, fmt.Errorf("pkcs11 keystore requires module path and key label")
+	}
+	files, err := newFileBlobStore(dir, ".p11")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := pkcs11.New(cfg.ModulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load pkcs11 module %s", cfg.ModulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("pkcs11 initialize failed: %w", err)
+	}
+
+	pb := &pkcs11Backend{files: files, ctx: ctx, logger: logger}
+	if err := pb.open(cfg); err != nil {
+		ctx.Finalize()
+		ctx.Destroy()
+		return nil, err
+	}
+	return pb, nil
+}
+
+// open finds the token, logs in and resolves the wrapping key handle
+func (pb *pkcs11Backend) open(cfg PKCS11Config) error {
+	slots, err := pb.ctx.GetSlotList(true)
+	if err != nil {
+		return fmt.Errorf("pkcs11 slot list failed: %w", err)
+	}
+
+	slot, found := uint(0), false
+	for _, s := range slots {
+		info, err := pb.ctx.GetTokenInfo(s)
+		if err != nil {
+			continue
+		}
+		if cfg.TokenLabel == "" || strings.TrimSpace(info.Label) == cfg.TokenLabel {
+			slot, found = s, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("pkcs11 token %q not found", cfg.TokenLabel)
+	}
+
+	session, err := pb.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return fmt.Errorf("pkcs11 open session failed: %w", err)
+	}
+	if cfg.PIN != "" {
+		if err := pb.ctx.Login(session, pkcs11.CKU_USER, cfg.PIN); err != nil {
+			pb.ctx.CloseSession(session)
+			return fmt.Errorf("pkcs11 login failed: %w", err)
+		}
+	}
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, pkcs11.CKK_AES),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, cfg.KeyLabel),
+	}
+	if err := pb.ctx.FindObjectsInit(session, template); err != nil {
+		pb.ctx.CloseSession(session)
+		return fmt.Errorf("pkcs11 find key failed: %w", err)
+	}
+	objs, _, err := pb.ctx.FindObjects(session, 1)
+	pb.ctx.FindObjectsFinal(session)
+	if err != nil || len(objs) == 0 {
+		pb.ctx.CloseSession(session)
+		return fmt.Errorf("pkcs11 AES key %q not found on token", cfg.KeyLabel)
+	}
+
+	pb.session = session
+	pb.key = objs[0]
+	pb.logger.Info("PKCS#11 keystore key resolved",
+		zap.String("token", cfg.TokenLabel),
+		zap.String("key", cfg.KeyLabel))
+	return nil
+}
+
+func (pb *pkcs11Backend) Name() string {
+	return KeystoreBackendPKCS11
+}
+
+func (pb *pkcs11Backend) Put(id string, blob []byte) error {
+	iv := make([]byte, pkcs11IVSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return fmt.Errorf("failed to generate iv: %w", err)
+	}
+	params := pkcs11.NewGCMParams(iv, []byte(id), 128)
+	defer params.Free()
+
+	pb.mu.Lock()
+	err := pb.ctx.EncryptInit(pb.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, pb.key)
+	var sealed []byte
+	if err == nil {
+		sealed, err = pb.ctx.Encrypt(pb.session, blob)
+	}
+	pb.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("pkcs11 encrypt failed: %w", err)
+	}
+	return pb.files.Put(id, append(iv, sealed...))
+}
+
+func (pb *pkcs11Backend) Get(id string) ([]byte, error) {
+	data, err := pb.files.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) <= pkcs11IVSize {
+		return nil, fmt.Errorf("pkcs11 keystore entry %s is truncated", id)
+	}
+	// The id is bound as AAD so sealed files cannot be swapped between ids
+	params := pkcs11.NewGCMParams(data[:pkcs11IVSize], []byte(id), 128)
+	defer params.Free()
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if err := pb.ctx.DecryptInit(pb.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}, pb.key); err != nil {
+		return nil, fmt.Errorf("pkcs11 decrypt failed: %w", err)
+	}
+	plain, err := pb.ctx.Decrypt(pb.session, data[pkcs11IVSize:])
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11 decrypt failed: %w", err)
+	}
+	return plain, nil
+}
+
+func (pb *pkcs11Backend) List() ([]string, error) {
+	return pb.files.List()
+}
+
+func (pb *pkcs11Backend) Delete(id string) error {
+	return pb.files.Delete(id)
+}
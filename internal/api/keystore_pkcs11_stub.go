@@ -0,0 +1,13 @@
+//go:build !(pkcs11 && cgo)
+
+package api
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+func newPKCS11KeystoreBackend(_ string, _ PKCS11Config, _ *zap.Logger) (KeystoreBackend, error) {
+	return nil, fmt.Errorf("pkcs11 keystore backend requires a build with -tags pkcs11 and cgo enabled")
+}
@@ -0,0 +1,382 @@
+// Package api provides the adaptive timeout and pre-warming engine behind the latency optimizer
+package api
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== ADAPTIVE LATENCY MODEL =====
+
+var (
+	latencyAdaptiveTimeout = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "latency_adaptive_timeout_seconds",
+		Help: "Request timeout currently chosen by the latency model per chain",
+	}, []string{"chain"})
+
+	latencyModelP99 = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "latency_model_p99_seconds",
+		Help: "Rolling P99 latency observed by the latency model per chain",
+	}, []string{"chain"})
+
+	latencyPrewarmTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "latency_prewarm_total",
+		Help: "Hot keys pre-warmed by the latency model by chain and result",
+	}, []string{"chain", "result"})
+)
+
+// Decision kinds recorded by the latency model
+const (
+	latencyDecisionTimeout = "timeout"
+	latencyDecisionPrewarm = "prewarm"
+
+	latencyDecisionHistory = 50
+)
+
+// LatencyPolicy tunes how the optimizer turns observed latency into actions.
+type LatencyPolicy struct {
+	TargetP99         time.Duration // Tier P99 target
+	WarmRatio         float64       // Pre-warm once P99 reaches this fraction of the target
+	TimeoutMultiplier float64       // Timeout = P99 * multiplier, clamped to [MinTimeout, MaxTimeout]
+	MinTimeout        time.Duration
+	MaxTimeout        time.Duration
+	WarmCooldown      time.Duration // Minimum gap between pre-warm rounds per chain
+	MinSamples        int           // Samples required before the model acts
+	MaxWarmKeys       int           // Hot keys refreshed per pre-warm round
+}
+
+// DefaultLatencyPolicy returns the policy used until the server applies its tier target
+func DefaultLatencyPolicy() LatencyPolicy {
+	return LatencyPolicy{
+		TargetP99:         100 * time.Millisecond,
+		WarmRatio:         0.8,
+		TimeoutMultiplier: 3,
+		MinTimeout:        100 * time.Millisecond,
+		MaxTimeout:        5 * time.Second,
+		WarmCooldown:      15 * time.Second,
+		MinSamples:        20,
+		MaxWarmKeys:       4,
+	}
+}
+
+// LatencyDecision is one action taken by the model, kept for /api/v1/latency
+type LatencyDecision struct {
+	Time   time.Time `json:"time"`
+	Chain  string    `json:"chain"`
+	Kind   string    `json:"kind"`
+	P99Ms  float64   `json:"p99_ms"`
+	FromMs float64   `json:"from_ms,omitempty"`
+	ToMs   float64   `json:"to_ms,omitempty"`
+	Keys   []string  `json:"keys,omitempty"`
+	Reason string    `json:"reason"`
+}
+
+// TimeoutApplier pushes a chain's new request timeout to its relay. It is
+// called with the optimizer lock held and must not call back into it.
+type TimeoutApplier func(chain string, timeout time.Duration)
+
+// KeyWarmer refreshes hot keys of a chain ahead of demand and returns how
+// many were warmed successfully.
+type KeyWarmer func(ctx context.Context, chain string, keys []string) int
+
+// SetPolicy replaces the tuning policy; per-chain state is kept
+func (lo *LatencyOptimizer) SetPolicy(p LatencyPolicy) {
+	lo.mutex.Lock()
+	defer lo.mutex.Unlock()
+	lo.policy = p
+	lo.targetP99 = p.TargetP99
+}
+
+// Policy returns the current tuning policy
+func (lo *LatencyOptimizer) Policy() LatencyPolicy {
+	lo.mutex.RLock()
+	defer lo.mutex.RUnlock()
+	return lo.policy
+}
+
+// SetTimeoutApplier registers the hook that receives per-chain timeout changes
+func (lo *LatencyOptimizer) SetTimeoutApplier(fn TimeoutApplier) {
+	lo.mutex.Lock()
+	defer lo.mutex.Unlock()
+	lo.applyTimeout = fn
+}
+
+// SetWarmer registers the hook used to pre-warm hot keys
+func (lo *LatencyOptimizer) SetWarmer(fn KeyWarmer) {
+	lo.mutex.Lock()
+	defer lo.mutex.Unlock()
+	lo.warmer = fn
+}
+
+// TrackKey counts an access to key on chain so the hottest ones can be pre-warmed
+func (lo *LatencyOptimizer) TrackKey(chain, key string) {
+	if key == "" {
+		return
+	}
+	chain = normalizeChainName(chain)
+	lo.mutex.Lock()
+	defer lo.mutex.Unlock()
+
+	keys, ok := lo.hotKeys[chain]
+	if !ok {
+		keys = make(map[string]int)
+		lo.hotKeys[chain] = keys
+	}
+	keys[key]++
+}
+
+// Timeout returns the request timeout the model currently chooses for chain
+func (lo *LatencyOptimizer) Timeout(chain string) time.Duration {
+	lo.mutex.RLock()
+	defer lo.mutex.RUnlock()
+	if d, ok := lo.timeouts[normalizeChainName(chain)]; ok {
+		return d
+	}
+	return lo.adaptiveTimeout
+}
+
+// Decisions returns the most recent model decisions, oldest first
+func (lo *LatencyOptimizer) Decisions() []LatencyDecision {
+	lo.mutex.RLock()
+	defer lo.mutex.RUnlock()
+	out := make([]LatencyDecision, len(lo.decisions))
+	copy(out, lo.decisions)
+	return out
+}
+
+// Snapshot describes the per-chain model state for the latency endpoint
+func (lo *LatencyOptimizer) Snapshot() map[string]interface{} {
+	lo.mutex.RLock()
+	defer lo.mutex.RUnlock()
+
+	chains := make(map[string]interface{}, len(lo.chainLatencies))
+	for chain, tracker := range lo.chainLatencies {
+		timeout, adapted := lo.timeouts[chain]
+		if !adapted {
+			timeout = lo.adaptiveTimeout
+		}
+		entry := map[string]interface{}{
+			"p50_ms":          durationMillis(tracker.currentP50),
+			"p95_ms":          durationMillis(tracker.currentP95),
+			"p99_ms":          durationMillis(tracker.currentP99),
+			"sample_count":    len(tracker.samples),
+			"violations":      tracker.violations,
+			"timeout_ms":      durationMillis(timeout),
+			"timeout_adapted": adapted,
+			"hot_keys":        lo.topKeysLocked(chain, lo.policy.MaxWarmKeys),
+			"warm_threshold":  durationMillis(lo.warmThresholdLocked()),
+		}
+		if last, ok := lo.lastWarm[chain]; ok {
+			entry["last_prewarm"] = last.UTC().Format(time.RFC3339)
+		}
+		chains[chain] = entry
+	}
+
+	return map[string]interface{}{
+		"target_p99_ms":      durationMillis(lo.policy.TargetP99),
+		"warm_ratio":         lo.policy.WarmRatio,
+		"timeout_multiplier": lo.policy.TimeoutMultiplier,
+		"min_timeout_ms":     durationMillis(lo.policy.MinTimeout),
+		"max_timeout_ms":     durationMillis(lo.policy.MaxTimeout),
+		"chains":             chains,
+	}
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+func (lo *LatencyOptimizer) warmThresholdLocked() time.Duration {
+	return time.Duration(float64(lo.policy.TargetP99) * lo.policy.WarmRatio)
+}
+
+// topKeysLocked returns up to n keys of chain ordered by access count
+func (lo *LatencyOptimizer) topKeysLocked(chain string, n int) []string {
+	counts := lo.hotKeys[chain]
+	keys := make([]string, 0, len(counts))
+	for k, c := range counts {
+		if c > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if n > 0 && len(keys) > n {
+		keys = keys[:n]
+	}
+	return keys
+}
+
+func (lo *LatencyOptimizer) recordDecisionLocked(d LatencyDecision) {
+	lo.decisions = append(lo.decisions, d)
+	if len(lo.decisions) > latencyDecisionHistory {
+		lo.decisions = lo.decisions[len(lo.decisions)-latencyDecisionHistory:]
+	}
+}
+
+// evaluateLocked runs after every P99 update and adjusts the chain's timeout
+// and triggers a pre-warm round when P99 approaches the target.
+func (lo *LatencyOptimizer) evaluateLocked(chain string, tracker *LatencyTracker) {
+	p := lo.policy
+	if len(tracker.samples) < p.MinSamples {
+		return
+	}
+	p99 := tracker.currentP99
+	latencyModelP99.WithLabelValues(chain).Set(p99.Seconds())
+
+	next := time.Duration(float64(p99) * p.TimeoutMultiplier)
+	if next < p.MinTimeout {
+		next = p.MinTimeout
+	}
+	if p.MaxTimeout > 0 && next > p.MaxTimeout {
+		next = p.MaxTimeout
+	}
+	current, ok := lo.timeouts[chain]
+	if !ok {
+		current = lo.adaptiveTimeout
+	}
+	// Hysteresis: ignore changes under 10% so relays are not reconfigured on every sample
+	if delta := next - current; !ok || delta > current/10 || -delta > current/10 {
+		lo.timeouts[chain] = next
+		tracker.adaptations++
+		latencyAdaptiveTimeout.WithLabelValues(chain).Set(next.Seconds())
+		lo.recordDecisionLocked(LatencyDecision{
+			Time: time.Now(), Chain: chain, Kind: latencyDecisionTimeout,
+			P99Ms: durationMillis(p99), FromMs: durationMillis(current), ToMs: durationMillis(next),
+			Reason: "timeout tracks rolling p99",
+		})
+		if lo.applyTimeout != nil {
+			lo.applyTimeout(chain, next)
+		}
+	}
+
+	if lo.warmer == nil || p99 < lo.warmThresholdLocked() {
+		return
+	}
+	if last, ok := lo.lastWarm[chain]; ok && time.Since(last) < p.WarmCooldown {
+		return
+	}
+	keys := lo.topKeysLocked(chain, p.MaxWarmKeys)
+	if len(keys) == 0 {
+		return
+	}
+
+	lo.lastWarm[chain] = time.Now()
+	// Decay counts so keys that cooled off drop out of later rounds
+	for k := range lo.hotKeys[chain] {
+		lo.hotKeys[chain][k] /= 2
+	}
+	lo.recordDecisionLocked(LatencyDecision{
+		Time: time.Now(), Chain: chain, Kind: latencyDecisionPrewarm,
+		P99Ms: durationMillis(p99), Keys: keys,
+		Reason: "p99 approaching tier target",
+	})
+
+	warm, timeout := lo.warmer, next
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		warmed := warm(ctx, chain, keys)
+		latencyPrewarmTotal.WithLabelValues(chain, "warmed").Add(float64(warmed))
+		if failed := len(keys) - warmed; failed > 0 {
+			latencyPrewarmTotal.WithLabelValues(chain, "failed").Add(float64(failed))
+		}
+	}()
+}
+
+// ===== SERVER WIRING =====
+
+// startLatencyOptimizer aims the optimizer at the configured tier target and
+// connects its timeout and pre-warm decisions to the relays and backends.
+func (s *Server) startLatencyOptimizer() {
+	if latencyOptimizer == nil {
+		return
+	}
+
+	policy := DefaultLatencyPolicy()
+	policy.TargetP99 = s.getTierLatencyTarget(s.cfg.Tier)
+	if policy.MinTimeout < policy.TargetP99 {
+		policy.MinTimeout = policy.TargetP99
+	}
+	latencyOptimizer.SetPolicy(policy)
+
+	// Relays never get a longer timeout than they were configured with
+	relays := make(map[string]relay.RelayClient)
+	baselines := make(map[string]time.Duration)
+	if s.ethereumRelay != nil {
+		relays["ethereum"] = s.ethereumRelay
+		baselines["ethereum"] = s.ethereumRelay.GetConfig().Timeout
+	}
+	if s.solanaRelay != nil {
+		relays["solana"] = s.solanaRelay
+		baselines["solana"] = s.solanaRelay.GetConfig().Timeout
+	}
+	latencyOptimizer.SetTimeoutApplier(func(chain string, timeout time.Duration) {
+		rc, ok := relays[chain]
+		if !ok {
+			return
+		}
+		if base := baselines[chain]; base > 0 && timeout > base {
+			timeout = base
+		}
+		cfg := rc.GetConfig()
+		cfg.Timeout = timeout
+		if err := rc.UpdateConfig(cfg); err != nil {
+			s.logger.Warn("Failed to apply adaptive relay timeout",
+				zap.String("chain", chain), zap.Error(err))
+		}
+	})
+
+	latencyOptimizer.SetWarmer(s.warmHotKeys)
+}
+
+// warmHotKeys refreshes the snapshots behind the chain endpoints that have
+// been requested most, so the next request is served without a backend trip.
+func (s *Server) warmHotKeys(ctx context.Context, chain string, keys []string) int {
+	backend, ok := s.backends.Get(chain)
+	if !ok {
+		return 0
+	}
+
+	warmed := 0
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		switch key {
+		case "latest":
+			block, err := backend.GetLatestBlock()
+			if err != nil {
+				continue
+			}
+			if s.fastpathIntegration != nil {
+				s.fastpathIntegration.refreshBlock(chain, block)
+			}
+			if s.cache != nil && blockMatchesChain(block, "bitcoin") {
+				_ = s.cache.SetLatestBlock(block)
+			}
+			warmed++
+		case "status":
+			_ = backend.GetStatus()
+			warmed++
+		case "mempool":
+			_ = backend.GetMempoolSize()
+			warmed++
+		}
+	}
+	s.logger.Debug("Pre-warmed hot keys",
+		zap.String("chain", chain),
+		zap.Strings("keys", keys),
+		zap.Int("warmed", warmed))
+	return warmed
+}
@@ -0,0 +1,257 @@
+// Package api provides graceful shutdown and zero-downtime reload for the API server
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/lifecycle"
+	"go.uber.org/zap"
+)
+
+// ===== GRACEFUL SHUTDOWN =====
+
+// readyFDEnv names the inherited pipe a reloaded binary reports readiness on
+const readyFDEnv = "SPRINT_READY_FD"
+
+// The server's own transports. Every OnShutdown component depends on all
+// of them, so they stop last; among themselves they stop in the reverse
+// of the order newComponents adds them.
+const (
+	componentFastpath    = "fastpath"
+	componentEventSigner = "event signer"
+	componentRateLimiter = "rate limiter"
+	componentRelays      = "relays"
+)
+
+var serverComponents = []string{componentFastpath, componentEventSigner, componentRateLimiter, componentRelays}
+
+// newComponents returns the shutdown manager with the server's transports
+func (s *Server) newComponents() *lifecycle.Manager {
+	m := lifecycle.New(s.logger.Named("lifecycle"), 10*time.Second)
+	m.Add(lifecycle.Component{Name: componentFastpath, Stop: func(context.Context) error {
+		if s.fastpathIntegration != nil {
+			s.fastpathIntegration.Stop()
+		}
+		return nil
+	}})
+	m.Add(lifecycle.Component{Name: componentEventSigner, Stop: func(context.Context) error {
+		if s.eventSigner != nil {
+			s.eventSigner.Close()
+		}
+		return nil
+	}})
+	m.Add(lifecycle.Component{Name: componentRateLimiter, Stop: func(context.Context) error {
+		return s.rateLimiter.Close()
+	}})
+	m.Add(lifecycle.Component{Name: componentRelays, Stop: func(context.Context) error {
+		var errs []error
+		if s.ethereumRelay != nil {
+			if err := s.ethereumRelay.Disconnect(); err != nil {
+				errs = append(errs, fmt.Errorf("ETH relay: %w", err))
+			}
+		}
+		if s.solanaRelay != nil {
+			if err := s.solanaRelay.Disconnect(); err != nil {
+				errs = append(errs, fmt.Errorf("SOL relay: %w", err))
+			}
+		}
+		return errors.Join(errs...)
+	}})
+	return m
+}
+
+// OnShutdown registers fn to stop a component during graceful shutdown,
+// after HTTP requests and streams have drained and the cache snapshot is
+// written but before the relays and the server's other transports stop.
+// dependsOn names other OnShutdown components fn's component uses; those
+// are stopped after it. Otherwise components stop in the reverse of the
+// order they were registered, like deferred calls. Use it to stop
+// components the server does not own, such as the P2P client.
+func (s *Server) OnShutdown(name string, fn func(context.Context) error, dependsOn ...string) {
+	err := s.components.Add(lifecycle.Component{
+		Name:      name,
+		DependsOn: append(append([]string(nil), serverComponents...), dependsOn...),
+		Stop:      fn,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to register shutdown step", zap.String("step", name), zap.Error(err))
+	}
+}
+
+// gracefulShutdown stops the server in order: stop accepting and drain
+// in-flight requests, close streams with a close frame, stop background
+// consumers via endLife, write the cache snapshot, then stop components
+// in dependency order: OnShutdown components first, then the relays, rate
+// limit store, event signer and fastpath.
+func (s *Server) gracefulShutdown(endLife context.CancelFunc) {
+	s.draining.Store(true)
+	timeout := s.cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	s.logger.Info("Graceful shutdown started",
+		zap.Duration("timeout", timeout),
+		zap.Bool("reload", s.reloading.Load()))
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Hijacked WebSocket connections are invisible to http.Server.Shutdown,
+	// so streams are closed alongside the request drain
+	streamsDone := make(chan struct{})
+	go func() {
+		defer close(streamsDone)
+		if n := s.wsLimiter.CloseAll(); n > 0 {
+			s.logger.Info("Closing WebSocket streams", zap.Int("streams", n))
+		}
+		if err := s.waitStreamsDrained(drainCtx); err != nil {
+			s.logger.Warn("WebSocket streams did not drain", zap.Int("remaining", s.wsLimiter.Active()))
+		}
+	}()
+	if s.srv != nil {
+		if err := s.srv.Shutdown(drainCtx); err != nil {
+			s.logger.Error("HTTP server shutdown error", zap.Error(err))
+		}
+	}
+	<-streamsDone
+
+	// Nothing is being served any more; stop the bus and its consumers
+	endLife()
+
+	stepCtx, stepCancel := context.WithTimeout(context.Background(), timeout)
+	defer stepCancel()
+
+	if s.cache != nil && s.cfg.CacheSnapshotPath != "" {
+		if n, err := s.cache.SaveSnapshot(s.cfg.CacheSnapshotPath); err != nil {
+			s.logger.Error("Failed to write cache snapshot", zap.Error(err))
+		} else {
+			s.logger.Info("Cache snapshot written", zap.String("path", s.cfg.CacheSnapshotPath), zap.Int("blocks", n))
+		}
+	}
+
+	// Each step is bounded by its own timeout and the report is logged
+	s.components.Stop(stepCtx)
+	s.logger.Info("Graceful shutdown complete")
+}
+
+// waitStreamsDrained waits until every stream has released its lease
+func (s *Server) waitStreamsDrained(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for s.wsLimiter.Active() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// restoreCacheSnapshot seeds the cache with the tips saved at the last
+// shutdown so /latest answers immediately after a restart or reload
+func (s *Server) restoreCacheSnapshot() {
+	if s.cache == nil || s.cfg.CacheSnapshotPath == "" {
+		return
+	}
+	// Tips older than the idle timeout are not worth serving
+	n, err := s.cache.LoadSnapshot(s.cfg.CacheSnapshotPath, s.cfg.IdleTimeout)
+	if err != nil {
+		s.logger.Warn("Failed to restore cache snapshot", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		s.logger.Info("Restored cache snapshot", zap.Int("blocks", n))
+	}
+}
+
+// ===== ZERO-DOWNTIME RELOAD =====
+
+// listen binds the API address, with SO_REUSEPORT when reloads are enabled
+// so the old and new binaries can accept on the same port during handover
+func (s *Server) listen(addr string) (net.Listener, error) {
+	if !s.cfg.APIReusePort {
+		return net.Listen("tcp", addr)
+	}
+	lc := net.ListenConfig{Control: reusePortControl}
+	return lc.Listen(context.Background(), "tcp", addr)
+}
+
+// reload starts a new copy of the running binary and waits for it to
+// report that it is listening. On success the caller shuts this process
+// down gracefully; on failure the new process is killed and this one keeps
+// serving.
+func (s *Server) reload() error {
+	if !s.cfg.APIReusePort {
+		return errors.New("reload requires API_REUSE_PORT=true")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %w", err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create readiness pipe: %w", err)
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{w} // fd 3 in the child
+	cmd.Env = append(os.Environ(), readyFDEnv+"=3")
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to start new binary: %w", err)
+	}
+	w.Close()
+
+	timeout := s.cfg.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	_ = r.SetReadDeadline(time.Now().Add(timeout))
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err == nil && line != "ready\n" {
+		err = fmt.Errorf("unexpected readiness message %q", line)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("new binary did not become ready: %w", err)
+	}
+	// The new process outlives this one, so it is never waited on
+	pid := cmd.Process.Pid
+	_ = cmd.Process.Release()
+	s.logger.Info("New binary is serving, handing over", zap.Int("pid", pid))
+	return nil
+}
+
+// notifyReady tells the parent of a reload that this process is listening
+func notifyReady(logger *zap.Logger) {
+	v := os.Getenv(readyFDEnv)
+	if v == "" {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	if f == nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.WriteString("ready\n"); err != nil {
+		logger.Warn("Failed to report readiness to previous process", zap.Error(err))
+	}
+}
@@ -0,0 +1,249 @@
+// Package api provides shared pagination, filtering and field selection for list endpoints
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+// ===== LIST CONVENTIONS =====
+//
+// List endpoints accept the same query parameters:
+//
+//	limit=N          page size; listDefaultLimit by default, capped per tier
+//	cursor=...       opaque position; pass back the previous page's next_cursor
+//	fields=a,b       return only these fields of each item
+//	<filter>=value   exact-match filters declared by the endpoint
+//
+// and respond with the endpoint's collection key (e.g. "keys", "txs") plus
+// "count", "limit" and, when more items follow, "next_cursor".
+
+const listDefaultLimit = 100
+
+// listLimitCaps is the largest page each tier may request
+var listLimitCaps = map[config.Tier]int{
+	config.TierFree:       100,
+	config.TierPro:        250,
+	config.TierBusiness:   500,
+	config.TierTurbo:      1000,
+	config.TierEnterprise: 1000,
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// listParams are the parsed list query parameters
+type listParams struct {
+	Limit   int
+	Cursor  string            // Decoded position; empty on the first page
+	Fields  []string          // Projection; empty returns whole items
+	Filters map[string]string // Declared filters present in the query
+}
+
+// parseListParams reads the list parameters for a request at tier, keeping
+// only the filters the endpoint declares
+func parseListParams(r *http.Request, tier config.Tier, filters ...string) (listParams, error) {
+	q := r.URL.Query()
+	p := listParams{Limit: listDefaultLimit, Filters: make(map[string]string)}
+
+	limitCap, ok := listLimitCaps[tier]
+	if !ok {
+		limitCap = listLimitCaps[config.TierFree]
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("limit must be a positive integer")
+		}
+		p.Limit = n
+	}
+	if p.Limit > limitCap {
+		p.Limit = limitCap
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(raw) == 0 {
+			return p, errInvalidCursor
+		}
+		p.Cursor = string(raw)
+	}
+
+	if v := q.Get("fields"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				p.Fields = append(p.Fields, f)
+			}
+		}
+	}
+
+	for _, name := range filters {
+		if v := q.Get(name); v != "" {
+			p.Filters[name] = v
+		}
+	}
+	return p, nil
+}
+
+// encodeCursor makes an opaque cursor from an endpoint-defined position
+func encodeCursor(pos string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pos))
+}
+
+// offset interprets the cursor as a list offset, for endpoints that page
+// through an ordered snapshot
+func (p listParams) offset() (int, error) {
+	if p.Cursor == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(p.Cursor)
+	if err != nil || n < 0 {
+		return 0, errInvalidCursor
+	}
+	return n, nil
+}
+
+// pageSlice returns the page of items the cursor points at and the cursor
+// of the following page, empty on the last page
+func pageSlice[T any](items []T, p listParams) ([]T, string, error) {
+	off, err := p.offset()
+	if err != nil {
+		return nil, "", err
+	}
+	if off >= len(items) {
+		return items[:0], "", nil
+	}
+	end := off + p.Limit
+	if end >= len(items) {
+		return items[off:], "", nil
+	}
+	return items[off:end], encodeCursor(strconv.Itoa(end)), nil
+}
+
+// filterItems keeps items whose JSON fields equal every filter value,
+// ignoring case
+func filterItems[T any](items []T, filters map[string]string) ([]T, error) {
+	if len(filters) == 0 {
+		return items, nil
+	}
+	out := make([]T, 0, len(items))
+	for _, item := range items {
+		fields, err := jsonFields(item)
+		if err != nil {
+			return nil, err
+		}
+		match := true
+		for name, want := range filters {
+			if !strings.EqualFold(fieldString(fields[name]), want) {
+				match = false
+				break
+			}
+		}
+		if match {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+// projectFields reduces each item to the requested JSON fields. Unknown
+// field names are ignored.
+func projectFields[T any](items []T, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+	out := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		all, err := jsonFields(item)
+		if err != nil {
+			return nil, err
+		}
+		projected := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				projected[f] = v
+			}
+		}
+		out = append(out, projected)
+	}
+	return out, nil
+}
+
+func jsonFields(item interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// fieldString renders a JSON value for comparison: strings unquoted,
+// everything else as written
+func fieldString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// writeList sends a page of items under key with the standard list fields.
+// extra carries endpoint-specific fields such as totals.
+func writeList[T any](s *Server, w http.ResponseWriter, key string, items []T, p listParams, next string, extra map[string]interface{}) {
+	body, err := projectFields(items, p.Fields)
+	if err != nil {
+		s.logger.Error("Failed to project list fields", zap.String("list", key), zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "failed to encode list"})
+		return
+	}
+
+	resp := map[string]interface{}{
+		key:     body,
+		"count": len(items),
+		"limit": p.Limit,
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	if next != "" {
+		resp["next_cursor"] = next
+	}
+	s.jsonResponse(w, http.StatusOK, resp)
+}
+
+// listRequest parses list parameters, answering 400 itself on bad input
+func (s *Server) listRequest(w http.ResponseWriter, r *http.Request, tier config.Tier, filters ...string) (listParams, bool) {
+	p, err := parseListParams(r, tier, filters...)
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return p, false
+	}
+	return p, true
+}
+
+// filteredPage filters items, then returns the requested page of them
+func filteredPage[T any](s *Server, w http.ResponseWriter, items []T, p listParams) ([]T, string, bool) {
+	items, err := filterItems(items, p.Filters)
+	if err != nil {
+		s.logger.Error("Failed to filter list", zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "failed to filter list"})
+		return nil, "", false
+	}
+	page, next, err := pageSlice(items, p)
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, "", false
+	}
+	return page, next, true
+}
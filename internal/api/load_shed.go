@@ -0,0 +1,92 @@
+// Package api provides load shedding integration for the API server
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/cache"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/loadshed"
+)
+
+// ===== LOAD SHEDDING =====
+
+// AddLoadShedSignal feeds an extra pressure source into load shedding, e.g.
+// the p2p block queue:
+//
+//	s.AddLoadShedSignal("p2p_queue", func() float64 {
+//		depth, maxDepth := client.QueueDepth()
+//		return float64(depth) / float64(maxDepth)
+//	})
+func (s *Server) AddLoadShedSignal(name string, fn loadshed.Signal) {
+	s.loadShedder.AddSignal(name, fn)
+}
+
+// startLoadShedding registers the cache signal and starts sampling
+func (s *Server) startLoadShedding(ctx context.Context) {
+	if !s.cfg.LoadShedEnabled {
+		s.logger.Info("Load shedding disabled")
+		return
+	}
+
+	if s.cache != nil {
+		s.loadShedder.AddSignal("cache_memory", s.cache.MemoryPressure)
+	}
+	s.loadShedder.Start(ctx)
+	s.logger.Info("Load shedding enabled")
+}
+
+// shedOnMemoryPressure has the webhook queue and the predictive cache back
+// off while the cache reports high memory pressure
+func (s *Server) shedOnMemoryPressure() {
+	if s.cache == nil {
+		return
+	}
+	s.cache.OnMemoryPressure(func(level cache.MemoryPressureLevel) {
+		shed := level >= cache.PressureHigh
+		if s.webhooks != nil {
+			s.webhooks.ShedLoad(shed)
+		}
+		if predictiveCache != nil {
+			predictiveCache.ShedLoad(shed)
+		}
+	})
+}
+
+// loadShedMiddleware rejects lower-tier requests with 429 while the
+// pressure score is above their threshold
+func (s *Server) loadShedMiddleware(next http.Handler) http.Handler {
+	if !s.cfg.LoadShedEnabled {
+		return next
+	}
+
+	return s.loadShedder.Middleware(s.requestTier, isShedExempt, next)
+}
+
+// requestTier resolves the caller's tier from its API key. Anonymous and
+// unknown callers are treated as free tier.
+func (s *Server) requestTier(r *http.Request) config.Tier {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get("api_key")
+	}
+	if apiKey == "" {
+		return config.TierFree
+	}
+	if key, ok := s.keyManager.ValidateKey(apiKey); ok {
+		return key.Tier
+	}
+	return config.TierFree
+}
+
+// isShedExempt keeps health, metrics, profiling and admin endpoints
+// reachable so the service can be observed and operated while it sheds
+func isShedExempt(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/version", "/status", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(r.URL.Path, "/api/v1/admin/") || strings.HasPrefix(r.URL.Path, "/debug/pprof/")
+}
@@ -0,0 +1,149 @@
+// Package api provides mempool inspection endpoints
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+)
+
+// ===== MEMPOOL INSPECTION HANDLERS =====
+
+// mempoolTx is the public representation of a mempool entry
+type mempoolTx struct {
+	TxID    string  `json:"txid"`
+	FeeRate float64 `json:"fee_rate"`
+	VSize   int     `json:"vsize"`
+	AddedAt string  `json:"added_at"`
+}
+
+func toMempoolTx(e mempool.TransactionEntry) mempoolTx {
+	return mempoolTx{
+		TxID:    e.TxID,
+		FeeRate: e.FeeRate,
+		VSize:   e.Size,
+		AddedAt: e.AddedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// chainMempoolHandler routes /v1/{chain}/mempool/{txs|summary|tx/{txid}}
+func (s *Server) chainMempoolHandler(chain string, rest []string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": "Method not allowed",
+		})
+		return
+	}
+
+	if normalizeChainName(chain) != "bitcoin" || s.mem == nil {
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{
+			"error": "Mempool inspection not available for chain " + chain,
+		})
+		return
+	}
+
+	if len(rest) == 0 {
+		s.mempoolSummaryHandler(w, r)
+		return
+	}
+
+	switch rest[0] {
+	case "summary":
+		s.mempoolSummaryHandler(w, r)
+	case "txs":
+		s.mempoolTxsHandler(w, r)
+	case "tx":
+		if len(rest) < 2 || rest[1] == "" {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+				"error": "Missing txid. Use /v1/btc/mempool/tx/{txid}",
+			})
+			return
+		}
+		s.mempoolTxHandler(strings.ToLower(rest[1]), w, r)
+	default:
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{
+			"error": "Unknown mempool endpoint '" + rest[0] + "'",
+		})
+	}
+}
+
+// mempoolSummaryHandler returns size, vsize and the fee histogram
+func (s *Server) mempoolSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	s.jsonResponse(w, http.StatusOK, s.mem.Summary(mempool.DefaultFeeBuckets))
+}
+
+// mempoolTxsHandler returns a page of txids ordered by fee rate. It takes
+// the standard list parameters; offset is still accepted in place of a
+// cursor.
+func (s *Server) mempoolTxsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	p, ok := s.listRequest(w, r, s.getCustomerTierFromContext(r))
+	if !ok {
+		return
+	}
+
+	offset, err := p.offset()
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if v := q.Get("offset"); v != "" && p.Cursor == "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "offset must be a non-negative integer"})
+			return
+		}
+		offset = n
+	}
+
+	minFee := 0.0
+	if v := q.Get("min_fee_rate"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "min_fee_rate must be a non-negative number"})
+			return
+		}
+		minFee = f
+	}
+
+	entries, total := s.mem.Page(mempool.PageOptions{Offset: offset, Limit: p.Limit, MinFeeRate: minFee})
+	txs := make([]mempoolTx, 0, len(entries))
+	for _, e := range entries {
+		txs = append(txs, toMempoolTx(e))
+	}
+
+	extra := map[string]interface{}{
+		"total":  total,
+		"offset": offset,
+	}
+	var cursor string
+	if next := offset + len(txs); next < total {
+		extra["next_offset"] = next
+		cursor = encodeCursor(strconv.Itoa(next))
+	}
+	writeList(s, w, "txs", txs, p, cursor, extra)
+}
+
+// mempoolTxHandler returns a single mempool entry
+func (s *Server) mempoolTxHandler(txid string, w http.ResponseWriter, r *http.Request) {
+	entry, ok := s.mem.Get(txid)
+	if !ok {
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{
+			"error": "Transaction not in mempool",
+			"txid":  txid,
+		})
+		return
+	}
+
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"txid":       entry.TxID,
+		"fee_rate":   entry.FeeRate,
+		"vsize":      entry.Size,
+		"priority":   entry.Priority,
+		"added_at":   entry.AddedAt.UTC(),
+		"expires_at": entry.ExpiresAt.UTC(),
+	})
+}
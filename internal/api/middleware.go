@@ -48,7 +48,7 @@ func (s *Server) securityMiddleware(next http.Handler) http.Handler {
 			}
 
 			if apiKey == "" {
-				s.logger.Warn("Missing API key for protected endpoint",
+				s.requestLogger(r).Warn("Missing API key for protected endpoint",
 					zap.String("ip", getClientIP(r)),
 					zap.String("path", r.URL.Path),
 				)
@@ -60,7 +60,7 @@ func (s *Server) securityMiddleware(next http.Handler) http.Handler {
 
 			// Validate API key (simple validation for soak test)
 			if !s.validateAPIKey(apiKey) {
-				s.logger.Warn("Invalid API key",
+				s.requestLogger(r).Warn("Invalid API key",
 					zap.String("ip", getClientIP(r)),
 					zap.String("path", r.URL.Path),
 				)
@@ -70,7 +70,7 @@ func (s *Server) securityMiddleware(next http.Handler) http.Handler {
 				return
 			}
 
-			s.logger.Debug("API key validated",
+			s.requestLogger(r).Debug("API key validated",
 				zap.String("path", r.URL.Path),
 				zap.String("ip", getClientIP(r)),
 			)
@@ -83,10 +83,11 @@ func (s *Server) securityMiddleware(next http.Handler) http.Handler {
 			generalRateLimit = 100 // fallback default
 		}
 		if !s.rateLimiter.Allow(clientIP, float64(generalRateLimit), 1) {
-			s.logger.Warn("Rate limit exceeded",
+			s.requestLogger(r).Warn("Rate limit exceeded",
 				zap.String("ip", clientIP),
 				zap.String("path", r.URL.Path),
 			)
+			w.Header().Set("Retry-After", "1")
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
@@ -102,7 +103,7 @@ func (s *Server) recoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		defer func() {
 			if rec := recover(); rec != nil {
 				stack := debug.Stack()
-				s.logger.Error("Panic in handler",
+				s.requestLogger(r).Error("Panic in handler",
 					zap.Any("panic", rec),
 					zap.String("stack", string(stack)),
 					zap.String("url", r.URL.String()),
@@ -125,7 +126,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		if apiKey == "" {
-			s.logger.Warn("Missing API key",
+			s.requestLogger(r).Warn("Missing API key",
 				zap.String("ip", getClientIP(r)),
 				zap.String("path", r.URL.Path),
 			)
@@ -137,7 +138,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 		customerKey, valid := s.keyManager.ValidateKey(apiKey)
 		if !valid {
 			// Log failed auth attempts (potential brute force)
-			s.logger.Warn("Invalid API key",
+			s.requestLogger(r).Warn("Invalid API key",
 				zap.String("ip", getClientIP(r)),
 				zap.String("path", r.URL.Path),
 				zap.String("user_agent", r.UserAgent()),
@@ -150,22 +151,29 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 		keyIdentifier := string(customerKey.Hash)
 		tierRateLimit := s.getTierRateLimit(customerKey.Tier)
 		if !s.rateLimiter.Allow(keyIdentifier, tierRateLimit, 1) {
-			s.logger.Warn("Tier rate limit exceeded",
+			s.requestLogger(r).Warn("Tier rate limit exceeded",
 				zap.String("key_hash", customerKey.Hash[:8]),
 				zap.String("tier", string(customerKey.Tier)),
 				zap.Float64("limit", tierRateLimit),
 				zap.String("ip", getClientIP(r)),
 				zap.String("path", r.URL.Path),
 			)
+			w.Header().Set("Retry-After", "1")
 			http.Error(w, "Rate limit exceeded", http.StatusTooManyRequests)
 			return
 		}
 
+		// Charge the request against the key's monthly cost budget
+		if !s.chargeRequestCost(w, r, apiKey, customerKey) {
+			return
+		}
+
 		// Update key usage statistics
 		s.keyManager.UpdateKeyUsage(apiKey, getClientIP(r), r.UserAgent())
 
 		// Add customer tier to request context for handlers to use
 		ctx := context.WithValue(r.Context(), "customer_tier", customerKey.Tier)
+		ctx = context.WithValue(ctx, "customer_key_hash", keyIdentifier)
 		r = r.WithContext(ctx)
 
 		// Use custom response writer to ensure status code is always set
@@ -177,7 +185,7 @@ func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
 		next(customWriter, r)
 
 		// Log request (successful auth)
-		s.logger.Debug("Authorized request",
+		s.requestLogger(r).Debug("Authorized request",
 			zap.String("path", r.URL.Path),
 			zap.Int("status", customWriter.statusCode),
 			zap.String("tier", string(customerKey.Tier)),
@@ -322,6 +330,7 @@ func (s *Server) requiresAuth(path string) bool {
 		"/api/simple-signup",
 		"/api/signup",
 		"/api/entropy", // Public entropy endpoint
+		"/api/v1/billing/webhook", // Authenticated by the payment provider's signature
 	}
 
 	for _, publicPath := range publicPaths {
@@ -0,0 +1,207 @@
+// Package api provides the Bitcoin mining RPC passthrough for pool operators
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+// ===== MINING PASSTHROUGH =====
+
+// Per-key limits for template fetches. Each getblocktemplate makes the node
+// assemble a block, so these are far below the tier's general limit.
+const (
+	miningRateBurst   = 5   // Requests allowed back to back
+	miningRatePerSec  = 0.5 // Sustained requests per second
+	miningMaxBodySize = 4 << 10
+)
+
+// rpcInWarmup and rpcClientInInitialDownload are the bitcoind error codes
+// for a node that cannot build templates yet
+const (
+	rpcInWarmup                = -28
+	rpcClientInInitialDownload = -10
+)
+
+// bitcoindError is a JSON-RPC error returned by bitcoind
+type bitcoindError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *bitcoindError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// miningRPC forwards mining calls to the configured bitcoind
+type miningRPC struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// newMiningRPC uses the configured bitcoind RPC endpoint
+func newMiningRPC(cfg config.Config) *miningRPC {
+	if cfg.RPCURL == "" {
+		return nil
+	}
+	timeout := cfg.RPCTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &miningRPC{
+		url:      cfg.RPCURL,
+		username: cfg.RPCUsername,
+		password: cfg.RPCPassword,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// call returns the raw result of method, untouched
+func (m *miningRPC) call(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "mining",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(m.username, m.password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	// bitcoind reports RPC errors with a 404 or 500 status and a JSON body
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *bitcoindError  `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return nil, fmt.Errorf("%s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if rpcResp.Error != nil {
+		return nil, fmt.Errorf("%s: %w", method, rpcResp.Error)
+	}
+	return rpcResp.Result, nil
+}
+
+// blockTemplateRequest is the subset of the getblocktemplate request object
+// customers may set. Long polling and proposal mode are not forwarded: a
+// long poll would pin a node connection until the next block.
+type blockTemplateRequest struct {
+	Rules        []string `json:"rules"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// blockTemplateHandler forwards getblocktemplate (Enterprise tier):
+//
+//	GET  /api/v1/bitcoin/blocktemplate
+//	POST /api/v1/bitcoin/blocktemplate {"rules": ["segwit"], "capabilities": [...]}
+func (s *Server) blockTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.allowMining(w, r) {
+		return
+	}
+
+	tmplReq := blockTemplateRequest{Rules: []string{"segwit"}}
+	if r.Method == http.MethodPost {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, miningMaxBodySize))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&tmplReq); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid template request: " + err.Error()})
+			return
+		}
+		// bitcoind rejects template requests without segwit support
+		if !containsRule(tmplReq.Rules, "segwit") {
+			tmplReq.Rules = append(tmplReq.Rules, "segwit")
+		}
+	}
+
+	s.forwardMiningCall(w, r, "getblocktemplate", []interface{}{tmplReq})
+}
+
+// miningInfoHandler forwards getmininginfo (Enterprise tier):
+//
+//	GET /api/v1/bitcoin/mininginfo
+func (s *Server) miningInfoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.allowMining(w, r) {
+		return
+	}
+	s.forwardMiningCall(w, r, "getmininginfo", []interface{}{})
+}
+
+// allowMining enforces the tier gate and the per-key mining rate limit,
+// writing the error response when the request may not proceed
+func (s *Server) allowMining(w http.ResponseWriter, r *http.Request) bool {
+	if !s.isEnterpriseTier(s.getCustomerTierFromContext(r)) {
+		s.jsonResponse(w, http.StatusForbidden, map[string]string{"error": "mining RPC passthrough requires the enterprise tier"})
+		return false
+	}
+	if s.mining == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "bitcoin node RPC not configured"})
+		return false
+	}
+
+	keyHash, _ := r.Context().Value("customer_key_hash").(string)
+	if !s.rateLimiter.Allow(keyHash+":mining", miningRateBurst, miningRatePerSec) {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", 1/miningRatePerSec))
+		s.jsonResponse(w, http.StatusTooManyRequests, map[string]string{"error": "mining RPC rate limit exceeded"})
+		return false
+	}
+	return true
+}
+
+// forwardMiningCall writes the node's result as the response body
+func (s *Server) forwardMiningCall(w http.ResponseWriter, r *http.Request, method string, params []interface{}) {
+	result, err := s.mining.call(r.Context(), method, params)
+	if err != nil {
+		var rerr *bitcoindError
+		if errors.As(err, &rerr) && (rerr.Code == rpcInWarmup || rerr.Code == rpcClientInInitialDownload) {
+			s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": rerr.Message})
+			return
+		}
+		s.logger.Warn("Mining RPC passthrough failed", zap.String("method", method), zap.Error(err))
+		s.jsonResponse(w, http.StatusBadGateway, map[string]string{"error": method + " failed"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(result)
+}
+
+func containsRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
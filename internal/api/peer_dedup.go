@@ -0,0 +1,135 @@
+// Package api provides admin endpoints for P2P and Solana deduplication tuning
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/p2p"
+	"go.uber.org/zap"
+)
+
+// ===== P2P DEDUP TUNING =====
+
+// SetPeerDeduper exposes the p2p client's deduper through
+// /api/v1/admin/p2p/dedup
+func (s *Server) SetPeerDeduper(d *p2p.EnterpriseP2PDeduper) {
+	s.peerDedup = d
+}
+
+// dedupTTLRequest is the body of POST /api/v1/admin/p2p/dedup/ttl
+type dedupTTLRequest struct {
+	Min string `json:"min"` // e.g. "30s"
+	Max string `json:"max"` // e.g. "20m"
+}
+
+// peerDedupAdminHandler shows and tunes the P2P message deduper:
+//
+//	GET    /api/v1/admin/p2p/dedup               duplicate rates, adaptive TTLs, peer reputations
+//	POST   /api/v1/admin/p2p/dedup/ttl           pin adaptive TTL bounds {"min":"30s","max":"20m"}
+//	DELETE /api/v1/admin/p2p/dedup/peers/{id}    clear a peer's reputation and blacklist
+func (s *Server) peerDedupAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if s.peerDedup == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "p2p deduper not configured"})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/p2p/dedup"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		s.jsonResponse(w, http.StatusOK, s.peerDedup.GetStats())
+
+	case rest == "ttl" && r.Method == http.MethodPost:
+		var req dedupTTLRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		minTTL, err1 := time.ParseDuration(req.Min)
+		maxTTL, err2 := time.ParseDuration(req.Max)
+		if err1 != nil || err2 != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "min and max must be durations"})
+			return
+		}
+		if err := s.peerDedup.SetTTLBounds(minTTL, maxTTL); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s.requestLogger(r).Info("P2P dedup TTL bounds pinned via admin API",
+			zap.Duration("min_ttl", minTTL), zap.Duration("max_ttl", maxTTL))
+		s.jsonResponse(w, http.StatusOK, s.peerDedup.GetStats())
+
+	case len(parts) == 2 && parts[0] == "peers" && r.Method == http.MethodDelete:
+		if !s.peerDedup.ResetPeerReputation(parts[1]) {
+			s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": "peer not tracked"})
+			return
+		}
+		s.jsonResponse(w, http.StatusOK, map[string]string{"peer_id": parts[1], "status": "reset"})
+
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+// ===== SOLANA DEDUP TUNING =====
+
+// solanaDedupTuneRequest is the body of POST /api/v1/admin/solana/dedup;
+// omitted fields are left unchanged
+type solanaDedupTuneRequest struct {
+	Min        string   `json:"min"`         // e.g. "5s"; set together with max
+	Max        string   `json:"max"`         // e.g. "5m"
+	TargetRate *float64 `json:"target_rate"` // Duplicate rate to steer toward, e.g. 0.3
+}
+
+// solanaDedupAdminHandler shows and tunes the Solana relay's block deduper:
+//
+//	GET  /api/v1/admin/solana/dedup    TTLs, duplicate rates, suppressed count
+//	POST /api/v1/admin/solana/dedup    {"min":"5s","max":"5m","target_rate":0.3}
+func (s *Server) solanaDedupAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if s.solanaRelay == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "solana relay not configured"})
+		return
+	}
+	sd := s.solanaRelay.Deduper()
+
+	switch r.Method {
+	case http.MethodGet:
+		s.jsonResponse(w, http.StatusOK, sd.GetStats())
+
+	case http.MethodPost:
+		var req solanaDedupTuneRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		if req.Min != "" || req.Max != "" {
+			minTTL, err1 := time.ParseDuration(req.Min)
+			maxTTL, err2 := time.ParseDuration(req.Max)
+			if err1 != nil || err2 != nil {
+				s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "min and max must be durations"})
+				return
+			}
+			if err := sd.SetTTLBounds(minTTL, maxTTL); err != nil {
+				s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			s.requestLogger(r).Info("Solana dedup TTL bounds pinned via admin API",
+				zap.Duration("min_ttl", minTTL), zap.Duration("max_ttl", maxTTL))
+		}
+		if req.TargetRate != nil {
+			if err := sd.SetTargetDuplicateRate(*req.TargetRate); err != nil {
+				s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			s.requestLogger(r).Info("Solana dedup target rate set via admin API",
+				zap.Float64("target_rate", *req.TargetRate))
+		}
+		s.jsonResponse(w, http.StatusOK, sd.GetStats())
+
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
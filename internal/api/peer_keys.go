@@ -0,0 +1,107 @@
+// Package api provides admin endpoints for peer key rotation
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/p2p"
+	"go.uber.org/zap"
+)
+
+// ===== PEER KEY ROTATION =====
+
+// defaultKeyRetireGrace keeps a retired key valid long enough for every
+// node to pick up the new primary
+const defaultKeyRetireGrace = 10 * time.Minute
+
+// SetPeerAuthenticator exposes the p2p authenticator's key ring through
+// /api/v1/admin/p2p/keys
+func (s *Server) SetPeerAuthenticator(auth *p2p.Authenticator) {
+	s.peerAuth = auth
+}
+
+// peerKeyRequest is the body of POST /api/v1/admin/p2p/keys
+type peerKeyRequest struct {
+	ID      string `json:"id"`
+	Secret  string `json:"secret"` // base64
+	Primary bool   `json:"primary"`
+}
+
+// peerKeysAdminHandler manages the peer authentication key ring:
+//
+//	GET    /api/v1/admin/p2p/keys                  list keys
+//	POST   /api/v1/admin/p2p/keys                  add a key, optionally as primary
+//	POST   /api/v1/admin/p2p/keys/{id}/primary     sign with {id}
+//	DELETE /api/v1/admin/p2p/keys/{id}?grace=10m   retire {id} after the grace period
+func (s *Server) peerKeysAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if s.peerAuth == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "peer authentication not configured"})
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/admin/p2p/keys"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"keys": s.peerAuth.Keys()})
+
+	case rest == "" && r.Method == http.MethodPost:
+		var req peerKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+			return
+		}
+		secret, err := base64.StdEncoding.DecodeString(req.Secret)
+		if err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "secret must be base64"})
+			return
+		}
+		defer func() {
+			for i := range secret {
+				secret[i] = 0
+			}
+		}()
+		if err := s.peerAuth.AddKey(req.ID, secret); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if req.Primary {
+			if err := s.peerAuth.SetPrimary(req.ID); err != nil {
+				s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		s.requestLogger(r).Info("Peer key added via admin API", zap.String("key_id", req.ID), zap.Bool("primary", req.Primary))
+		s.jsonResponse(w, http.StatusCreated, map[string]interface{}{"keys": s.peerAuth.Keys()})
+
+	case len(parts) == 2 && parts[1] == "primary" && r.Method == http.MethodPost:
+		if err := s.peerAuth.SetPrimary(parts[0]); err != nil {
+			s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"keys": s.peerAuth.Keys()})
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		grace := defaultKeyRetireGrace
+		if g := r.URL.Query().Get("grace"); g != "" {
+			d, err := time.ParseDuration(g)
+			if err != nil || d < 0 {
+				s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid grace duration"})
+				return
+			}
+			grace = d
+		}
+		if err := s.peerAuth.RetireKey(parts[0], grace); err != nil {
+			s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s.jsonResponse(w, http.StatusOK, map[string]interface{}{"keys": s.peerAuth.Keys()})
+
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
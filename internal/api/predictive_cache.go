@@ -0,0 +1,425 @@
+// Package api provides the access-pattern learner behind the predictive cache
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ===== PREDICTIVE CACHE LEARNER =====
+
+var (
+	predictiveCachePredictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "predictive_cache_predictions_total",
+		Help: "Next-access predictions checked against the actual access, by outcome (accurate, early, late)",
+	}, []string{"outcome"})
+
+	predictiveCachePredictionError = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "predictive_cache_prediction_error_seconds",
+		Help:    "Absolute difference between predicted and actual next access",
+		Buckets: prometheus.ExponentialBuckets(0.01, 4, 8),
+	})
+
+	predictiveCacheAccuracy = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "predictive_cache_prediction_accuracy",
+		Help: "Fraction of next-access predictions that were accurate",
+	})
+
+	predictiveCacheHot = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "predictive_cache_hot_predictions_total",
+		Help: "Keys whose TTL was extended as predicted hot, by whether the extension served a hit",
+	}, []string{"result"})
+
+	predictiveCacheRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "predictive_cache_refreshes_total",
+		Help: "Proactive cache refreshes by result",
+	}, []string{"result"})
+)
+
+const (
+	predictionHistory      = 16                     // Recent accesses kept per key
+	predictionMinTolerance = 100 * time.Millisecond // Smallest window counted as accurate
+	refreshTimeout         = 5 * time.Second
+)
+
+// CacheRefresher re-fetches the value for chain/method ahead of a predicted access.
+type CacheRefresher func(ctx context.Context, chain, method string) (interface{}, error)
+
+// NewPredictionEngine creates a learner with a 30s prediction horizon
+func NewPredictionEngine() *PredictionEngine {
+	return &PredictionEngine{
+		patterns:      make(map[string]*AccessPattern),
+		mlModel:       &SimpleMLModel{},
+		predictionTTL: 30 * time.Second,
+		alpha:         0.3,
+		minTTL:        time.Second,
+		maxTTL:        5 * time.Minute,
+		hotThreshold:  0.6,
+		maxPatterns:   10000,
+	}
+}
+
+// tolerance is how far off a prediction may be and still count as accurate
+func (ap *AccessPattern) tolerance() time.Duration {
+	if t := ap.IntervalEWMA / 2; t > predictionMinTolerance {
+		return t
+	}
+	return predictionMinTolerance
+}
+
+// UpdatePattern records an access, scores the previous prediction for key
+// against it and predicts the next access from the interval EWMA.
+func (pe *PredictionEngine) UpdatePattern(key string, access time.Time) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	ap, ok := pe.patterns[key]
+	if !ok {
+		if len(pe.patterns) >= pe.maxPatterns {
+			pe.dropColdestLocked()
+		}
+		ap = &AccessPattern{Frequency: make(map[time.Duration]int)}
+		pe.patterns[key] = ap
+	}
+
+	if !ap.NextAccess.IsZero() {
+		pe.scoreLocked(ap, access)
+	}
+
+	if n := len(ap.LastAccesses); n > 0 {
+		interval := access.Sub(ap.LastAccesses[n-1])
+		if interval > 0 {
+			ap.Frequency[interval.Truncate(time.Second)]++
+			prev := ap.IntervalEWMA
+			if prev == 0 {
+				ap.IntervalEWMA = interval
+			} else {
+				ap.IntervalEWMA = time.Duration(pe.alpha*float64(interval) + (1-pe.alpha)*float64(prev))
+				ap.TrendScore = float64(prev) / float64(ap.IntervalEWMA)
+			}
+		}
+	}
+
+	ap.Count++
+	ap.LastAccesses = append(ap.LastAccesses, access)
+	if len(ap.LastAccesses) > predictionHistory {
+		ap.LastAccesses = ap.LastAccesses[1:]
+	}
+	if ap.IntervalEWMA > 0 {
+		ap.NextAccess = access.Add(ap.IntervalEWMA)
+	}
+}
+
+func (pe *PredictionEngine) scoreLocked(ap *AccessPattern, actual time.Time) {
+	diff := actual.Sub(ap.NextAccess)
+	predictiveCachePredictionError.Observe(math.Abs(diff.Seconds()))
+
+	tol := ap.tolerance()
+	switch {
+	case diff < -tol:
+		pe.early++
+		predictiveCachePredictions.WithLabelValues("early").Inc()
+	case diff > tol:
+		pe.late++
+		predictiveCachePredictions.WithLabelValues("late").Inc()
+	default:
+		pe.accurate++
+		predictiveCachePredictions.WithLabelValues("accurate").Inc()
+	}
+	total := pe.accurate + pe.early + pe.late
+	predictiveCacheAccuracy.Set(float64(pe.accurate) / float64(total))
+}
+
+// dropColdestLocked forgets the pattern with the oldest last access
+func (pe *PredictionEngine) dropColdestLocked() {
+	var coldest string
+	var oldest time.Time
+	for key, ap := range pe.patterns {
+		last := ap.LastAccesses[len(ap.LastAccesses)-1]
+		if coldest == "" || last.Before(oldest) {
+			coldest, oldest = key, last
+		}
+	}
+	delete(pe.patterns, coldest)
+}
+
+// PredictFutureAccess estimates the probability that key is accessed within
+// the prediction horizon, treating accesses as arrivals at the EWMA rate and
+// decaying keys that have gone quiet for longer than their usual interval.
+func (pe *PredictionEngine) PredictFutureAccess(key string) float64 {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+	return pe.predictLocked(key, time.Now())
+}
+
+func (pe *PredictionEngine) predictLocked(key string, now time.Time) float64 {
+	ap, ok := pe.patterns[key]
+	if !ok || ap.IntervalEWMA <= 0 {
+		return 0.5 // No pattern yet
+	}
+	ewma := float64(ap.IntervalEWMA)
+	p := 1 - math.Exp(-float64(pe.predictionTTL)/ewma)
+
+	quiet := now.Sub(ap.LastAccesses[len(ap.LastAccesses)-1]) - 2*ap.IntervalEWMA
+	if quiet > 0 {
+		p *= math.Exp(-float64(quiet) / ewma)
+	}
+	return p
+}
+
+// PredictOptimalTTL sizes the TTL to cover the next expected access: twice
+// the access interval, doubled again for hot keys, within [minTTL, maxTTL].
+func (pe *PredictionEngine) PredictOptimalTTL(key, chain string) time.Duration {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	ap, ok := pe.patterns[key]
+	if !ok || ap.IntervalEWMA <= 0 {
+		return pe.predictionTTL
+	}
+	ttl := 2 * ap.IntervalEWMA
+	if pe.predictLocked(key, time.Now()) >= pe.hotThreshold {
+		ttl *= 2
+	}
+	return pe.clampTTL(ttl)
+}
+
+func (pe *PredictionEngine) clampTTL(ttl time.Duration) time.Duration {
+	if ttl < pe.minTTL {
+		return pe.minTTL
+	}
+	if ttl > pe.maxTTL {
+		return pe.maxTTL
+	}
+	return ttl
+}
+
+// nextAccess returns the predicted next access of a hot key, or false when
+// the key is not hot or has no prediction.
+func (pe *PredictionEngine) nextAccess(key string) (time.Time, time.Duration, bool) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	ap, ok := pe.patterns[key]
+	if !ok || ap.NextAccess.IsZero() || pe.predictLocked(key, time.Now()) < pe.hotThreshold {
+		return time.Time{}, 0, false
+	}
+	return ap.NextAccess, ap.tolerance(), true
+}
+
+// Stats summarizes learner accuracy for the cache stats endpoint
+func (pe *PredictionEngine) Stats() map[string]interface{} {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	total := pe.accurate + pe.early + pe.late
+	accuracy := 0.0
+	if total > 0 {
+		accuracy = float64(pe.accurate) / float64(total)
+	}
+	return map[string]interface{}{
+		"tracked_keys":     len(pe.patterns),
+		"predictions":      total,
+		"accurate":         pe.accurate,
+		"early":            pe.early,
+		"late":             pe.late,
+		"accuracy":         accuracy,
+		"horizon_seconds":  pe.predictionTTL.Seconds(),
+		"hot_threshold":    pe.hotThreshold,
+		"max_ttl_seconds":  pe.maxTTL.Seconds(),
+		"min_ttl_seconds":  pe.minTTL.Seconds(),
+		"interval_weight":  pe.alpha,
+		"pattern_capacity": pe.maxPatterns,
+	}
+}
+
+// ===== CACHE MAINTENANCE =====
+
+// SetRefresher registers the fetcher used for proactive refreshes
+func (pc *PredictiveCache) SetRefresher(fn CacheRefresher) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.refresher = fn
+}
+
+// ShedLoad stops TTL extensions and proactive refreshes while shed is
+// set, e.g. under memory pressure, and cancels the refreshes pending
+func (pc *PredictiveCache) ShedLoad(shed bool) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	pc.shedding = shed
+	if shed {
+		for _, entry := range pc.cache {
+			pc.stopRefreshLocked(entry)
+		}
+	}
+}
+
+// afterHitLocked extends the TTL of a hot key so it survives until its
+// predicted next access, and settles an earlier extension as a hit.
+func (pc *PredictiveCache) afterHitLocked(entry *CacheEntry) {
+	if entry.extended {
+		entry.extended = false
+		predictiveCacheHot.WithLabelValues("hit").Inc()
+	}
+
+	next, tol, hot := pc.predictions.nextAccess(entry.Key)
+	entry.Prediction = pc.predictions.PredictFutureAccess(entry.Key)
+	if !hot || pc.shedding {
+		return
+	}
+	want := next.Add(tol).Sub(entry.Created)
+	if want > pc.predictions.maxTTL {
+		want = pc.predictions.maxTTL
+	}
+	if want > entry.TTL {
+		entry.TTL = want
+		entry.extended = true
+	}
+	pc.scheduleRefreshLocked(entry)
+}
+
+// scheduleRefreshLocked arranges for a hot key to be re-fetched just before
+// its predicted next access, so the access finds a fresh value.
+func (pc *PredictiveCache) scheduleRefreshLocked(entry *CacheEntry) {
+	if pc.refresher == nil || pc.shedding {
+		return
+	}
+	next, tol, hot := pc.predictions.nextAccess(entry.Key)
+	if !hot {
+		return
+	}
+	delay := time.Until(next.Add(-tol))
+	if delay <= 0 {
+		return
+	}
+
+	pc.stopRefreshLocked(entry)
+	key := entry.Key
+	entry.refreshTimer = time.AfterFunc(delay, func() { pc.refresh(key) })
+}
+
+func (pc *PredictiveCache) stopRefreshLocked(entry *CacheEntry) {
+	if entry.refreshTimer != nil {
+		entry.refreshTimer.Stop()
+		entry.refreshTimer = nil
+	}
+}
+
+// refresh re-fetches a cached key in place; keys evicted meanwhile are left alone
+func (pc *PredictiveCache) refresh(key string) {
+	pc.mutex.RLock()
+	fetch := pc.refresher
+	scheduled := pc.cache[key]
+	pc.mutex.RUnlock()
+	if fetch == nil || scheduled == nil {
+		return
+	}
+
+	chain, method, _ := strings.Cut(key, ":")
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	value, err := fetch(ctx, chain, method)
+	if err != nil {
+		predictiveCacheRefreshes.WithLabelValues("error").Inc()
+		return
+	}
+
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+	entry := pc.cache[key]
+	if entry != scheduled {
+		return // Evicted or replaced by Set meanwhile
+	}
+	entry.Value = value
+	entry.Created = time.Now()
+	entry.TTL = pc.predictions.PredictOptimalTTL(key, chain)
+	entry.refreshTimer = nil
+	predictiveCacheRefreshes.WithLabelValues("ok").Inc()
+}
+
+// preCacheRequest loads chain/method into the cache ahead of demand
+func (pc *PredictiveCache) preCacheRequest(chain, method string) {
+	pc.mutex.RLock()
+	fetch, shedding := pc.refresher, pc.shedding
+	pc.mutex.RUnlock()
+	if fetch == nil || shedding {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	value, err := fetch(ctx, chain, method)
+	if err != nil {
+		predictiveCacheRefreshes.WithLabelValues("error").Inc()
+		return
+	}
+	pc.Set(&UnifiedRequest{Chain: chain, Method: method}, value)
+	predictiveCacheRefreshes.WithLabelValues("ok").Inc()
+}
+
+// evict removes key once it has really expired
+func (pc *PredictiveCache) evict(key string) {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	entry, exists := pc.cache[key]
+	if !exists || time.Since(entry.Created) <= entry.TTL {
+		return // Refreshed or replaced since the expired read
+	}
+	pc.removeLocked(entry)
+}
+
+// evictLeastPredicted drops the entry least likely to be accessed again
+func (pc *PredictiveCache) evictLeastPredicted() {
+	var victim *CacheEntry
+	lowest := math.Inf(1)
+	for _, entry := range pc.cache {
+		p := pc.predictions.PredictFutureAccess(entry.Key)
+		if p < lowest || (p == lowest && entry.LastAccess.Before(victim.LastAccess)) {
+			victim, lowest = entry, p
+		}
+	}
+	if victim != nil {
+		pc.removeLocked(victim)
+	}
+}
+
+func (pc *PredictiveCache) removeLocked(entry *CacheEntry) {
+	if entry.extended {
+		predictiveCacheHot.WithLabelValues("miss").Inc()
+	}
+	pc.stopRefreshLocked(entry)
+	delete(pc.cache, entry.Key)
+	pc.currentSize--
+}
+
+// startPredictiveCache lets the predictive cache refresh chain data from the
+// registered backends ahead of predicted accesses.
+func (s *Server) startPredictiveCache() {
+	if predictiveCache == nil {
+		return
+	}
+	predictiveCache.SetRefresher(func(ctx context.Context, chain, method string) (interface{}, error) {
+		backend, ok := s.backends.Get(chain)
+		if !ok {
+			return nil, fmt.Errorf("chain %q not supported", chain)
+		}
+		switch method {
+		case "latest", "latest_block":
+			return backend.GetLatestBlock()
+		case "status":
+			return backend.GetStatus(), nil
+		case "mempool":
+			return backend.GetMempoolSize(), nil
+		default:
+			return nil, fmt.Errorf("method %q cannot be refreshed", method)
+		}
+	})
+}
@@ -2,18 +2,47 @@
 package api
 
 import (
+	"context"
 	"math"
 	"sync"
 	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
 )
 
 // ===== RATE LIMITER IMPLEMENTATION =====
 
-// RateLimiter manages rate limiting for API requests
+// Shared store calls run on the request path, so they are bounded tightly;
+// after a failure the store is bypassed for a cooldown instead of paying
+// the timeout on every request.
+const (
+	rateLimitStoreTimeout  = 50 * time.Millisecond
+	rateLimitStoreCooldown = 5 * time.Second
+)
+
+// RateLimitStore holds the token buckets behind a RateLimiter
+type RateLimitStore interface {
+	// Take removes one token from the bucket for key, creating it full,
+	// and reports whether a token was available
+	Take(ctx context.Context, key string, capacity, refillRate float64) (bool, error)
+	// Name identifies the store in metrics and logs
+	Name() string
+	// Close releases store resources
+	Close() error
+}
+
+// RateLimiter manages rate limiting for API requests. Buckets live in the
+// configured store; a shared store enforces limits across every replica,
+// and while it is unreachable the limiter degrades to local buckets.
 type RateLimiter struct {
-	buckets map[string]*TokenBucket
-	clock   Clock
-	mu      sync.RWMutex
+	local  *memoryRateLimitStore
+	shared RateLimitStore // nil for per-instance limits
+	clock  Clock
+	logger *zap.Logger
+
+	mu            sync.Mutex
+	degradedUntil time.Time // Shared store bypassed until then
 }
 
 // TokenBucket implements the token bucket algorithm for rate limiting
@@ -26,32 +55,149 @@ type TokenBucket struct {
 	mu             sync.Mutex
 }
 
-// NewRateLimiter creates a new rate limiter
+// NewRateLimiter creates a new rate limiter with per-instance buckets
 func NewRateLimiter(clock Clock) *RateLimiter {
 	return &RateLimiter{
-		buckets: make(map[string]*TokenBucket),
-		clock:   clock,
+		local: newMemoryRateLimitStore(clock),
+		clock: clock,
 	}
 }
 
+// NewRateLimiterWithStore creates a rate limiter backed by a shared store,
+// falling back to per-instance buckets while the store fails
+func NewRateLimiterWithStore(clock Clock, store RateLimitStore, logger *zap.Logger) *RateLimiter {
+	rl := NewRateLimiter(clock)
+	rl.shared = store
+	rl.logger = logger
+	return rl
+}
+
+// newRateLimiterFromConfig uses Redis buckets when RATE_LIMIT_REDIS_URL is set
+func newRateLimiterFromConfig(cfg config.Config, clock Clock, logger *zap.Logger) *RateLimiter {
+	if cfg.RateLimitRedisURL == "" {
+		return NewRateLimiter(clock)
+	}
+	store, err := NewRedisRateLimitStore(cfg.RateLimitRedisURL, "")
+	if err != nil {
+		logger.Warn("Rate limit store unavailable, using per-instance limits", zap.Error(err))
+		return NewRateLimiter(clock)
+	}
+	logger.Info("Rate limit store configured", zap.String("store", store.Name()))
+	return NewRateLimiterWithStore(clock, store, logger)
+}
+
 // Allow checks if a request from the given identifier is allowed
 func (rl *RateLimiter) Allow(identifier string, capacity float64, refillRate float64) bool {
+	if rl.shared != nil && rl.sharedAvailable() {
+		ctx, cancel := context.WithTimeout(context.Background(), rateLimitStoreTimeout)
+		allowed, err := rl.shared.Take(ctx, identifier, capacity, refillRate)
+		cancel()
+		if err == nil {
+			rateLimitDecisions.WithLabelValues(rl.shared.Name(), decisionLabel(allowed)).Inc()
+			return allowed
+		}
+		rl.degrade(err)
+	}
+
+	allowed, _ := rl.local.Take(context.Background(), identifier, capacity, refillRate)
+	rateLimitDecisions.WithLabelValues(rl.local.Name(), decisionLabel(allowed)).Inc()
+	return allowed
+}
+
+// Close closes the shared store, if any
+func (rl *RateLimiter) Close() error {
+	if rl == nil || rl.shared == nil {
+		return nil
+	}
+	return rl.shared.Close()
+}
+
+func (rl *RateLimiter) sharedAvailable() bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	if rl.degradedUntil.IsZero() {
+		return true
+	}
+	if rl.clock.Now().Before(rl.degradedUntil) {
+		return false
+	}
+	// Cooldown over: let the next request probe the store
+	rl.degradedUntil = time.Time{}
+	rateLimitStoreDegraded.Set(0)
+	if rl.logger != nil {
+		rl.logger.Info("Retrying shared rate limit store", zap.String("store", rl.shared.Name()))
+	}
+	return true
+}
+
+func (rl *RateLimiter) degrade(err error) {
+	rateLimitStoreErrors.WithLabelValues(rl.shared.Name()).Inc()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if !rl.degradedUntil.IsZero() {
+		return
+	}
+	rl.degradedUntil = rl.clock.Now().Add(rateLimitStoreCooldown)
+	rateLimitStoreDegraded.Set(1)
+	if rl.logger != nil {
+		rl.logger.Warn("Shared rate limit store failed, enforcing per-instance limits",
+			zap.String("store", rl.shared.Name()),
+			zap.Duration("retry_in", rateLimitStoreCooldown),
+			zap.Error(err))
+	}
+}
+
+func decisionLabel(allowed bool) string {
+	if allowed {
+		return "allowed"
+	}
+	return "limited"
+}
+
+// ===== MEMORY STORE =====
 
-	bucket, exists := rl.buckets[identifier]
+// memoryRateLimitStore keeps buckets in process memory
+type memoryRateLimitStore struct {
+	buckets map[string]*TokenBucket
+	clock   Clock
+	mu      sync.Mutex
+}
+
+func newMemoryRateLimitStore(clock Clock) *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: make(map[string]*TokenBucket),
+		clock:   clock,
+	}
+}
+
+// Take never fails
+func (ms *memoryRateLimitStore) Take(_ context.Context, key string, capacity, refillRate float64) (bool, error) {
+	ms.mu.Lock()
+	bucket, exists := ms.buckets[key]
 	if !exists {
 		bucket = &TokenBucket{
 			tokens:         capacity,
 			capacity:       capacity,
 			refillRate:     refillRate,
-			lastRefillTime: rl.clock.Now(),
-			clock:          rl.clock,
+			lastRefillTime: ms.clock.Now(),
+			clock:          ms.clock,
 		}
-		rl.buckets[identifier] = bucket
+		ms.buckets[key] = bucket
 	}
+	ms.mu.Unlock()
+
+	return bucket.Allow(), nil
+}
+
+// Name returns the store name
+func (ms *memoryRateLimitStore) Name() string {
+	return "memory"
+}
 
-	return bucket.Allow()
+// Close is a no-op
+func (ms *memoryRateLimitStore) Close() error {
+	return nil
 }
 
 // Allow checks if the token bucket allows a request
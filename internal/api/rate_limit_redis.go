@@ -0,0 +1,117 @@
+// Package api provides the Redis rate limit store shared by API replicas
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	rateLimitDecisions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_rate_limit_decisions_total",
+			Help: "Rate limit decisions by store and result",
+		},
+		[]string{"store", "result"},
+	)
+
+	rateLimitStoreErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_rate_limit_store_errors_total",
+			Help: "Shared rate limit store errors (requests fall back to per-instance limits)",
+		},
+		[]string{"store"},
+	)
+
+	rateLimitStoreDegraded = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "api_rate_limit_store_degraded",
+			Help: "1 while the shared rate limit store is bypassed after a failure",
+		},
+	)
+)
+
+// ===== REDIS STORE =====
+
+// tokenBucketScript refills and takes from a bucket in one round trip, so
+// concurrent replicas cannot both spend the last token. Redis server time
+// is used so replica clock skew does not mint tokens. Buckets expire once
+// they would be full again.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+local ttl = 3600000
+if rate > 0 then
+	ttl = math.ceil(capacity / rate * 1000) + 1000
+end
+redis.call('PEXPIRE', KEYS[1], ttl)
+return allowed
+`)
+
+// RedisRateLimitStore implements RateLimitStore with Redis hashes
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimitStore connects to Redis using a redis:// URL. An
+// unreachable server is not an error here: the limiter degrades to local
+// buckets until it answers.
+func NewRedisRateLimitStore(url, prefix string) (*RedisRateLimitStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if prefix == "" {
+		prefix = "sprint:ratelimit:"
+	}
+	// Fail fast: the limiter falls back rather than waiting on retries
+	opts.MaxRetries = -1
+	opts.DialTimeout = time.Second
+
+	return &RedisRateLimitStore{client: redis.NewClient(opts), prefix: prefix}, nil
+}
+
+// Take runs the token bucket script for key
+func (rs *RedisRateLimitStore) Take(ctx context.Context, key string, capacity, refillRate float64) (bool, error) {
+	allowed, err := tokenBucketScript.Run(ctx, rs.client, []string{rs.prefix + key}, capacity, refillRate).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
+
+// Name returns the store name
+func (rs *RedisRateLimitStore) Name() string {
+	return "redis"
+}
+
+// Close closes the Redis client
+func (rs *RedisRateLimitStore) Close() error {
+	return rs.client.Close()
+}
@@ -0,0 +1,34 @@
+// Package api provides request IDs for log correlation
+package api
+
+import (
+	"net/http"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/reqid"
+	"go.uber.org/zap"
+)
+
+// ===== REQUEST IDS =====
+
+// Every request gets an ID, the caller's X-Request-ID when it sends a
+// usable one, returned in the response's X-Request-ID. The ID rides in the
+// request context to the relay calls and cache loads made for the request,
+// which add it to their log lines as request_id.
+
+// requestIDMiddleware assigns the request its ID
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(reqid.Header)
+		if !reqid.Valid(id) {
+			id = reqid.New()
+			r.Header.Set(reqid.Header, id)
+		}
+		w.Header().Set(reqid.Header, id)
+		next.ServeHTTP(w, r.WithContext(reqid.WithID(r.Context(), id)))
+	})
+}
+
+// requestLogger returns the server logger with r's request ID on every line
+func (s *Server) requestLogger(r *http.Request) *zap.Logger {
+	return reqid.Logger(r.Context(), s.logger)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package api
+
+import (
+	"errors"
+	"syscall"
+)
+
+// reusePortControl is unavailable on this platform; API_REUSE_PORT fails the bind
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return errors.New("SO_REUSEPORT is not supported on this platform")
+}
@@ -3,12 +3,17 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/PayRpc/Bitcoin-Sprint/internal/fastpath"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/tlsconfig"
 	"go.uber.org/zap"
 )
 
@@ -37,14 +42,13 @@ func (s *Server) RegisterSprintValueRoutes() {
 		s.httpMux.HandleFunc("/api/v1/sprint/value", s.auth(SprintValueHandler))
 		
 		// Register optimized p99 latency endpoints using fastpath (NO AUTH - public read-only endpoints)
-		s.httpMux.HandleFunc("/v1/btc/latest", fastpath.LatestHandler)
-		s.httpMux.HandleFunc("/v1/btc/status", fastpath.StatusHandler)
+		fastpathRoutes := s.registerFastpathRoutes()
 
 		s.logger.Info("Sprint competitive advantage routes registered",
 			zap.String("universal_endpoint", "/api/v1/universal/{chain}/{method}"),
 			zap.String("auth_required", "true"),
 			zap.String("value_props", "flat_p99,unified_api,predictive_cache,enterprise_tiers"),
-			zap.String("fastpath_endpoints", "/v1/btc/latest,/v1/btc/status"),
+			zap.Strings("fastpath_endpoints", fastpathRoutes),
 			zap.String("fastpath_latency", "p99 ≤ 5ms"))
 	}
 }
@@ -59,11 +63,24 @@ func (s *Server) InitializeEnterpriseManager() {
 	}
 }
 
-// Run starts the API server and blocks until shutdown
+// Run starts the API server and blocks until shutdown. Cancelling ctx,
+// SIGINT or SIGTERM starts a graceful shutdown; with API_REUSE_PORT, SIGHUP
+// hands over to a freshly started copy of the binary first.
 func (s *Server) Run(ctx context.Context) {
 	// Set server start time for uptime tracking
 	s.startTime = time.Now()
 
+	// ctx only triggers shutdown; everything the server runs uses a lifetime
+	// context that ends once requests and streams have drained, so in-flight
+	// work is not cancelled the moment a signal arrives
+	trigger, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	trigger, triggerShutdown := context.WithCancel(trigger)
+	defer triggerShutdown()
+	ctx, endLife := context.WithCancel(context.Background())
+	defer endLife()
+	s.life = ctx
+
 	// Ensure we're using a proper binding address
 	if s.cfg.APIHost == "" {
 		s.cfg.APIHost = "0.0.0.0" // Default to all interfaces if not specified
@@ -106,9 +123,13 @@ func (s *Server) Run(ctx context.Context) {
 
 	// Core routes (public)
 	s.httpMux.HandleFunc("/health", s.healthHandler)
+	s.httpMux.HandleFunc("/readyz", s.readyzHandler)
 	s.httpMux.HandleFunc("/version", s.versionHandler)
 	s.httpMux.HandleFunc("/status", s.statusHandler)
 	s.httpMux.HandleFunc("/metrics", s.metricsHandler)
+	s.httpMux.Handle("/api/v1/schemas", schemas.Handler("/api/v1/schemas"))
+	s.httpMux.Handle("/api/v1/schemas/", schemas.Handler("/api/v1/schemas"))
+	s.httpMux.HandleFunc("/api/v1/signing-keys", s.signingKeysHandler)
 
 	// Competitive advantage and universal API routes
 	s.RegisterSprintValueRoutes()
@@ -125,6 +146,7 @@ func (s *Server) Run(ctx context.Context) {
 		// Entropy
 		s.httpMux.HandleFunc("/api/v1/enterprise/entropy/fast", s.enterpriseManager.handleFastEntropy)
 		s.httpMux.HandleFunc("/api/v1/enterprise/entropy/hybrid", s.enterpriseManager.handleHybridEntropy)
+		s.httpMux.HandleFunc("/api/v1/enterprise/entropy/health", s.enterpriseManager.handleEntropyHealth)
 		// Secure buffer
 		s.httpMux.HandleFunc("/api/v1/enterprise/buffer/new", s.enterpriseManager.handleNewSecureBuffer)
 		// Audit
@@ -136,14 +158,56 @@ func (s *Server) Run(ctx context.Context) {
 	// Admin keystore endpoints (require admin auth middleware applied by s.auth)
 	if s.httpMux != nil {
 		s.httpMux.HandleFunc("/api/v1/admin/keystore/list", s.adminOnly(s.keystoreListHandler))
-		s.httpMux.HandleFunc("/api/v1/admin/keystore/save", s.adminOnly(s.keystoreSaveHandler))
+		s.httpMux.HandleFunc("/api/v1/admin/keystore/save", s.adminOnly(s.idempotent(s.keystoreSaveHandler)))
 		s.httpMux.HandleFunc("/api/v1/admin/keystore/load", s.adminOnly(s.keystoreLoadHandler))
 		s.httpMux.HandleFunc("/api/v1/admin/keystore/delete", s.adminOnly(s.keystoreDeleteHandler))
-		s.httpMux.HandleFunc("/api/v1/admin/keystore/import", s.adminOnly(s.keystoreImportHandler))
+		s.httpMux.HandleFunc("/api/v1/admin/keystore/import", s.adminOnly(s.idempotent(s.keystoreImportHandler)))
+		s.httpMux.HandleFunc("/api/v1/admin/keystore/export", s.adminOnly(s.keystoreExportHandler))
+		s.httpMux.HandleFunc("/api/v1/admin/keystore/import-archive", s.adminOnly(s.keystoreImportArchiveHandler))
 	}
 
+	// Admin stream capacity view
+	s.httpMux.HandleFunc("/api/v1/admin/streams", s.adminOnly(s.streamsAdminHandler))
+	s.httpMux.HandleFunc("/api/v1/admin/shadow", s.adminOnly(s.shadowAdminHandler))
+	s.httpMux.HandleFunc("/api/v1/admin/breakers", s.adminOnly(s.breakersAdminHandler))
+	s.httpMux.HandleFunc("/api/v1/admin/breakers/", s.adminOnly(s.breakerControlAdminHandler))
+
+	// Server-rendered status page for deployments without Grafana
+	s.httpMux.HandleFunc("/admin/ui", s.adminOnly(s.adminUIHandler))
+
+	// Profiling without a redeploy
+	s.httpMux.HandleFunc("/debug/pprof/", s.adminOnly(s.pprofHandler))
+
+	// Admin peer key rotation
+	s.httpMux.HandleFunc("/api/v1/admin/p2p/keys", s.adminOnly(s.idempotent(s.peerKeysAdminHandler)))
+	s.httpMux.HandleFunc("/api/v1/admin/p2p/keys/", s.adminOnly(s.idempotent(s.peerKeysAdminHandler)))
+
+	// Admin P2P and Solana dedup statistics and tuning
+	s.httpMux.HandleFunc("/api/v1/admin/p2p/dedup", s.adminOnly(s.peerDedupAdminHandler))
+	s.httpMux.HandleFunc("/api/v1/admin/p2p/dedup/", s.adminOnly(s.peerDedupAdminHandler))
+	s.httpMux.HandleFunc("/api/v1/admin/solana/dedup", s.adminOnly(s.solanaDedupAdminHandler))
+
+	// Customer key provisioning and payment provider subscription webhooks
+	s.httpMux.HandleFunc("/api/v1/admin/keys", s.adminOnly(s.idempotent(s.customerKeysAdminHandler)))
+	s.httpMux.HandleFunc("/api/v1/admin/keys/", s.adminOnly(s.idempotent(s.customerKeysAdminHandler)))
+	s.httpMux.HandleFunc("/api/v1/billing/webhook", s.billingWebhookHandler)
+
+	// SPV inclusion proofs for light clients
+	s.httpMux.HandleFunc("/api/v1/spv/proof", s.auth(s.spvProofHandler))
+
+	// Mining RPC passthrough for pool operators
+	s.httpMux.HandleFunc("/api/v1/bitcoin/blocktemplate", s.auth(s.blockTemplateHandler))
+	s.httpMux.HandleFunc("/api/v1/bitcoin/mininginfo", s.auth(s.miningInfoHandler))
+
+	// Webhook registration (dispatcher starts with the block bus)
+	s.httpMux.HandleFunc("/api/v1/webhooks", s.auth(s.idempotent(s.webhooksHandler)))
+	s.httpMux.HandleFunc("/api/v1/webhooks/", s.auth(s.idempotent(s.webhooksHandler)))
+
+	// Per-key usage: requests, cost units and stream egress this month
+	s.httpMux.HandleFunc("/api/v1/usage", s.auth(s.usageHandler))
+
 	// Wrap with security middleware
-	handler := s.securityMiddleware(s.httpMux)
+	handler := s.requestIDMiddleware(s.securityMiddleware(s.deadlineMiddleware(s.loadShedMiddleware(s.admissionMiddleware(s.captureMiddleware(s.compressionMiddleware(s.shadowMiddleware(s.httpMux))))))))
 	s.logger.Info("Security middleware applied")
 
 	// Create server with comprehensive configuration for reliable binding and connections
@@ -175,18 +239,14 @@ func (s *Server) Run(ctx context.Context) {
 		// Small delay to ensure server is up
 		time.Sleep(200 * time.Millisecond)
 		if s.ethereumRelay != nil && !s.ethereumRelay.IsConnected() {
-			ctxC, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := s.ethereumRelay.Connect(ctxC); err != nil {
+			if err := s.ethereumRelay.Connect(ctx); err != nil {
 				s.logger.Warn("ETH relay warm-up failed", zap.Error(err))
 			} else {
 				s.logger.Info("ETH relay pre-warmed")
 			}
 		}
 		if s.solanaRelay != nil && !s.solanaRelay.IsConnected() {
-			ctxC, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := s.solanaRelay.Connect(ctxC); err != nil {
+			if err := s.solanaRelay.Connect(ctx); err != nil {
 				s.logger.Warn("SOL relay warm-up failed", zap.Error(err))
 			} else {
 				s.logger.Info("SOL relay pre-warmed")
@@ -212,24 +272,59 @@ func (s *Server) Run(ctx context.Context) {
 		}
 	}()
 
+	// Start hot block fan-out before any stream clients can connect
+	s.restoreCacheSnapshot()
+	s.startBlockBus(ctx)
+	s.startEventSigning()
+	s.startWebhooks(ctx)
+	s.startTipSLA(ctx)
+	s.startCanary(ctx)
+	s.startTimeSync(ctx)
+	s.startBlockIndex(ctx)
+	s.startFeeEstimates()
+
+	// Point the latency model at the tier target and wire its actions
+	s.startLatencyOptimizer()
+	s.startPredictiveCache()
+	s.startLoadShedding(ctx)
+	s.shedOnMemoryPressure()
+
+	// Reap streams idle longer than the configured WebSocket idle timeout
+	s.wsLimiter.StartReaper(ctx, s.cfg.IdleTimeout, s.logger)
+
 	// Graceful shutdown watcher
+	shutdownDone := make(chan struct{})
 	go func() {
-		<-ctx.Done()
+		defer close(shutdownDone)
+		<-trigger.Done()
 		s.logger.Info("Shutdown signal received, stopping HTTP server")
-		
-		// Stop fastpath integration first
-		if s.fastpathIntegration != nil {
-			s.logger.Info("Stopping fastpath integration")
-			s.fastpathIntegration.Stop()
-		}
-		
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := s.srv.Shutdown(shutdownCtx); err != nil {
-			s.logger.Error("HTTP server shutdown error", zap.Error(err))
-		}
+		s.gracefulShutdown(endLife)
 	}()
 
+	// Zero-downtime reload: start the new binary, then drain this one
+	if s.cfg.APIReusePort {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(hup)
+			for {
+				select {
+				case <-trigger.Done():
+					return
+				case <-hup:
+					s.logger.Info("Reload requested")
+					if err := s.reload(); err != nil {
+						s.logger.Error("Reload failed, continuing to serve", zap.Error(err))
+						continue
+					}
+					s.reloading.Store(true)
+					triggerShutdown()
+					return
+				}
+			}
+		}()
+	}
+
 	// Only start listening if we created the server ourselves
 	s.logger.Info("Starting HTTP server listen", zap.String("addr", addr))
 
@@ -261,7 +356,7 @@ func (s *Server) Run(ctx context.Context) {
 	// Defer diagnostics until after listener is created to avoid false failures
 
 	// Try to listen on the specified port with explicit socket options
-	listener, err := net.Listen("tcp", addr)
+	listener, err := s.listen(addr)
 	if err != nil {
 		s.logger.Error("Failed to create listener",
 			zap.String("addr", addr),
@@ -279,6 +374,25 @@ func (s *Server) Run(ctx context.Context) {
 
 	s.logger.Info("HTTP server listener created successfully", zap.String("addr", addr))
 
+	// Terminate TLS in-process when configured
+	scheme := "http"
+	if s.cfg.EnableTLS {
+		tlsCfg, err := tlsconfig.New(tlsconfig.FromConfig(s.cfg))
+		if err != nil {
+			s.logger.Error("Failed to configure TLS", zap.Error(err))
+			listener.Close()
+			return
+		}
+		s.srv.TLSConfig = tlsCfg
+		scheme = "https"
+		s.logger.Info("TLS enabled",
+			zap.String("min_version", s.cfg.TLSMinVersion),
+			zap.Bool("autocert", len(s.cfg.TLSAutocertHosts) > 0),
+			zap.Bool("client_certs", tlsCfg.ClientCAs != nil))
+	} else if s.cfg.EnableMTLS {
+		s.logger.Warn("ENABLE_MTLS requires ENABLE_TLS; admin and secrets endpoints will reject all requests")
+	}
+
 	// Start the HTTP server with our prepared listener
 	// Spawn a self-test once the listener is ready
 	go func() {
@@ -296,11 +410,14 @@ func (s *Server) Run(ctx context.Context) {
 			TLSHandshakeTimeout:   5 * time.Second,
 			ExpectContinueTimeout: 1 * time.Second,
 			ResponseHeaderTimeout: 5 * time.Second,
+			// Liveness probe only: the certificate names the public host,
+			// not the loopback address
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 		}
 
 		client := http.Client{Timeout: 5 * time.Second, Transport: transport}
-		localURL := fmt.Sprintf("http://127.0.0.1:%d/health", s.cfg.APIPort)
-		bindURL := fmt.Sprintf("http://%s:%d/health", s.cfg.APIHost, s.cfg.APIPort)
+		localURL := fmt.Sprintf("%s://127.0.0.1:%d/health", scheme, s.cfg.APIPort)
+		bindURL := fmt.Sprintf("%s://%s:%d/health", scheme, s.cfg.APIHost, s.cfg.APIPort)
 
 		// Try up to 5 times alternating URLs
 		for attempt := 0; attempt < 5; attempt++ {
@@ -340,10 +457,20 @@ func (s *Server) Run(ctx context.Context) {
 		}
 	}()
 
-	if err := s.srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+	// The port is bound; a reload parent can start draining
+	notifyReady(s.logger)
+
+	if s.srv.TLSConfig != nil {
+		err = s.srv.ServeTLS(listener, "", "")
+	} else {
+		err = s.srv.Serve(listener)
+	}
+	if err != nil && err != http.ErrServerClosed {
 		s.logger.Error("HTTP server error", zap.Error(err))
 		return
 	}
+	// Serve returns as soon as the listener closes; wait for the drain
+	<-shutdownDone
 
 	s.logger.Info("HTTP server shutdown completed", zap.String("addr", addr))
 }
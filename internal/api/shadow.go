@@ -0,0 +1,538 @@
+// Package api provides shadow traffic mirroring for provider and backend migrations
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== SHADOW TRAFFIC =====
+
+// shadowMiddleware replays a sample of read requests against
+// ShadowTargetURL, typically a second deployment pointed at a new provider
+// or running a backend refactor. The client is always answered by this
+// server; the shadow response is only compared with it, and differences in
+// status, body and latency are collected in the report served at
+// /api/v1/admin/shadow. It sits inside compression so both sides are
+// compared uncompressed.
+
+const (
+	// shadowMaxBody bounds the bytes kept from either response; longer
+	// bodies are compared by status only
+	shadowMaxBody = 1 << 20
+
+	// shadowMaxRoutes bounds the routes tracked; the rest share "other"
+	shadowMaxRoutes = 256
+
+	// shadowDivergenceSamples is how many recent divergences the report keeps
+	shadowDivergenceSamples = 50
+)
+
+// shadowForwardHeaders are copied to the mirrored request so the shadow
+// authenticates and negotiates the same way
+var shadowForwardHeaders = []string{"Authorization", "X-API-Key", "Accept", "User-Agent"}
+
+// shadowIDSegment matches path segments that identify a resource rather
+// than a route: heights, hashes and transaction IDs
+var shadowIDSegment = regexp.MustCompile(`^(\d+|(0x)?[0-9a-fA-F]{32,})$`)
+
+var (
+	shadowRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_shadow_requests_total",
+		Help: "Mirrored requests by comparison result",
+	}, []string{"result"})
+
+	shadowLatencyDelta = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "api_shadow_latency_delta_seconds",
+		Help:    "Shadow latency minus primary latency for mirrored requests",
+		Buckets: []float64{-1, -0.25, -0.05, -0.01, 0, 0.01, 0.05, 0.25, 1},
+	})
+)
+
+// Shadow comparison results
+const (
+	shadowMatch       = "match"
+	shadowStatusDiff  = "status_mismatch"
+	shadowBodyDiff    = "body_mismatch"
+	shadowError       = "error"
+	shadowDropped     = "dropped"
+	shadowNotCompared = "not_compared"
+)
+
+const (
+	shadowOtherRoute   = "other" // Route key once shadowMaxRoutes are tracked
+	shadowJSONRootPath = "$"
+)
+
+// ShadowRouteStats compares primary and shadow for one route
+type ShadowRouteStats struct {
+	Route           string  `json:"route"`
+	Mirrored        int64   `json:"mirrored"`
+	Matched         int64   `json:"matched"`
+	StatusMismatch  int64   `json:"status_mismatch"`
+	BodyMismatch    int64   `json:"body_mismatch"`
+	Errors          int64   `json:"errors"`
+	PrimaryAvgMs    float64 `json:"primary_avg_ms"`
+	ShadowAvgMs     float64 `json:"shadow_avg_ms"`
+	ShadowSlowerPct float64 `json:"shadow_slower_pct"`
+	DivergenceRate  float64 `json:"divergence_rate"`
+	primaryTotal    time.Duration
+	shadowTotal     time.Duration
+	shadowSlower    int64
+	compared        int64
+}
+
+// ShadowDivergence is one mirrored request whose responses differed
+type ShadowDivergence struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Result        string    `json:"result"`
+	PrimaryStatus int       `json:"primary_status"`
+	ShadowStatus  int       `json:"shadow_status,omitempty"`
+	Field         string    `json:"field,omitempty"` // First differing JSON path
+	Error         string    `json:"error,omitempty"`
+}
+
+// ShadowReport is the divergence report served to operators
+type ShadowReport struct {
+	Target      string             `json:"target"`
+	SamplePct   float64            `json:"sample_pct"`
+	Since       time.Time          `json:"since"`
+	Mirrored    int64              `json:"mirrored"`
+	Dropped     int64              `json:"dropped"`
+	Routes      []ShadowRouteStats `json:"routes"`
+	Divergences []ShadowDivergence `json:"recent_divergences"`
+}
+
+// shadowMirror sends mirrored requests and accumulates the report
+type shadowMirror struct {
+	target    *url.URL
+	sample    float64
+	client    *http.Client
+	ignore    map[string]bool
+	slots     chan struct{}
+	logger    *zap.Logger
+	clockNow  func() time.Time
+	ctx       context.Context
+	cancelAll context.CancelFunc
+
+	mu          sync.Mutex
+	since       time.Time
+	mirrored    int64
+	dropped     int64
+	routes      map[string]*ShadowRouteStats
+	divergences []ShadowDivergence
+}
+
+// shadowExchange is one request and the primary's answer to it
+type shadowExchange struct {
+	method    string
+	path      string
+	rawQuery  string
+	header    http.Header
+	status    int
+	body      []byte
+	truncated bool
+	latency   time.Duration
+}
+
+func (s *Server) shadowMiddleware(next http.Handler) http.Handler {
+	if s.cfg.ShadowTargetURL == "" {
+		return next
+	}
+	target, err := url.Parse(s.cfg.ShadowTargetURL)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		s.logger.Warn("Shadow traffic disabled: invalid SHADOW_TARGET_URL",
+			zap.String("url", s.cfg.ShadowTargetURL), zap.Error(err))
+		return next
+	}
+
+	mirror := newShadowMirror(target, float64(s.cfg.ShadowSamplePct)/100, s.cfg.ShadowTimeout,
+		s.cfg.ShadowMaxInFlight, s.cfg.ShadowIgnoreFields, s.logger, s.clock.Now)
+	s.shadow = mirror
+	s.OnShutdown("shadow traffic", func(context.Context) error {
+		mirror.cancelAll()
+		return nil
+	})
+	s.logger.Info("Shadow traffic enabled",
+		zap.String("target", target.Redacted()),
+		zap.Int("sample_pct", s.cfg.ShadowSamplePct))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !mirror.eligible(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &shadowRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		mirror.dispatch(shadowExchange{
+			method:    r.Method,
+			path:      r.URL.Path,
+			rawQuery:  r.URL.RawQuery,
+			header:    r.Header.Clone(),
+			status:    rec.status,
+			body:      rec.body.Bytes(),
+			truncated: rec.truncated,
+			latency:   time.Since(start),
+		})
+	})
+}
+
+func newShadowMirror(target *url.URL, sample float64, timeout time.Duration, maxInFlight int,
+	ignore []string, logger *zap.Logger, now func() time.Time) *shadowMirror {
+	if sample <= 0 || sample > 1 {
+		sample = 1
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = 32
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &shadowMirror{
+		target:    target,
+		sample:    sample,
+		client:    &http.Client{Timeout: timeout},
+		ignore:    make(map[string]bool, len(ignore)),
+		slots:     make(chan struct{}, maxInFlight),
+		logger:    logger,
+		clockNow:  now,
+		ctx:       ctx,
+		cancelAll: cancel,
+		since:     now(),
+		routes:    make(map[string]*ShadowRouteStats),
+	}
+	for _, f := range ignore {
+		m.ignore[strings.TrimSpace(f)] = true
+	}
+	return m
+}
+
+// eligible reports whether r is a sampled read worth mirroring. Writes are
+// never mirrored; neither are streams, WebSockets, health and admin.
+func (m *shadowMirror) eligible(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	path := strings.TrimRight(r.URL.Path, "/")
+	if isShedExempt(r) || r.Header.Get("Upgrade") != "" || strings.HasSuffix(path, "/stream") {
+		return false
+	}
+	return m.sample >= 1 || rand.Float64() < m.sample
+}
+
+// dispatch mirrors ex in the background, dropping it if too many mirrored
+// requests are already in flight so a slow shadow never builds a backlog
+func (m *shadowMirror) dispatch(ex shadowExchange) {
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		shadowRequests.WithLabelValues(shadowDropped).Inc()
+		m.mu.Lock()
+		m.dropped++
+		m.mu.Unlock()
+		return
+	}
+	go func() {
+		defer func() { <-m.slots }()
+		m.mirror(ex)
+	}()
+}
+
+// mirror sends ex to the shadow and records how its answer compared
+func (m *shadowMirror) mirror(ex shadowExchange) {
+	u := *m.target
+	u.Path = strings.TrimRight(u.Path, "/") + ex.path
+	u.RawQuery = ex.rawQuery
+	req, err := http.NewRequestWithContext(m.ctx, ex.method, u.String(), nil)
+	if err != nil {
+		m.record(ex, 0, 0, shadowError, "", err)
+		return
+	}
+	for _, h := range shadowForwardHeaders {
+		if v := ex.header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+	req.Header.Set("X-Sprint-Shadow", "1")
+
+	start := time.Now()
+	resp, err := m.client.Do(req)
+	if err != nil {
+		m.record(ex, 0, 0, shadowError, "", err)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, shadowMaxBody+1))
+	resp.Body.Close()
+	latency := time.Since(start)
+	if err != nil {
+		m.record(ex, resp.StatusCode, latency, shadowError, "", err)
+		return
+	}
+
+	result, field := shadowMatch, ""
+	switch {
+	case resp.StatusCode != ex.status:
+		result = shadowStatusDiff
+	case ex.truncated || len(body) > shadowMaxBody || ex.method == http.MethodHead:
+		result = shadowNotCompared
+	default:
+		if field = m.diff(ex.body, body); field != "" {
+			result = shadowBodyDiff
+		}
+	}
+	m.record(ex, resp.StatusCode, latency, result, field, nil)
+}
+
+// diff returns the first JSON path where the bodies differ, ignoring the
+// configured volatile fields, or "" when they match. Non-JSON bodies must
+// be byte-identical.
+func (m *shadowMirror) diff(primary, shadow []byte) string {
+	var a, b interface{}
+	if json.Unmarshal(primary, &a) != nil || json.Unmarshal(shadow, &b) != nil {
+		if bytes.Equal(primary, shadow) {
+			return ""
+		}
+		return shadowJSONRootPath
+	}
+	return m.diffValue(shadowJSONRootPath, a, b)
+}
+
+func (m *shadowMirror) diffValue(path string, a, b interface{}) string {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			return path
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if m.ignore[k] {
+				continue
+			}
+			if d := m.diffValue(path+"."+k, av[k], bv[k]); d != "" {
+				return d
+			}
+		}
+		return ""
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return path
+		}
+		for i := range av {
+			if d := m.diffValue(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i]); d != "" {
+				return d
+			}
+		}
+		return ""
+	default:
+		if a != b {
+			return path
+		}
+		return ""
+	}
+}
+
+// record folds one comparison into the report
+func (m *shadowMirror) record(ex shadowExchange, shadowStatus int, latency time.Duration, result, field string, err error) {
+	shadowRequests.WithLabelValues(result).Inc()
+	if err == nil {
+		shadowLatencyDelta.Observe((latency - ex.latency).Seconds())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mirrored++
+	key := shadowRoute(ex.method, ex.path)
+	st, ok := m.routes[key]
+	if !ok {
+		if len(m.routes) >= shadowMaxRoutes {
+			key = shadowOtherRoute
+			st = m.routes[key]
+		}
+		if st == nil {
+			st = &ShadowRouteStats{Route: key}
+			m.routes[key] = st
+		}
+	}
+	st.Mirrored++
+	switch result {
+	case shadowMatch:
+		st.Matched++
+	case shadowStatusDiff:
+		st.StatusMismatch++
+	case shadowBodyDiff:
+		st.BodyMismatch++
+	case shadowError:
+		st.Errors++
+	}
+	if err == nil {
+		st.compared++
+		st.primaryTotal += ex.latency
+		st.shadowTotal += latency
+		if latency > ex.latency {
+			st.shadowSlower++
+		}
+	}
+
+	if result == shadowMatch || result == shadowNotCompared {
+		return
+	}
+	d := ShadowDivergence{
+		Time:          m.clockNow().UTC(),
+		Method:        ex.method,
+		Path:          ex.path,
+		Result:        result,
+		PrimaryStatus: ex.status,
+		ShadowStatus:  shadowStatus,
+		Field:         field,
+	}
+	if err != nil {
+		d.Error = err.Error()
+	}
+	m.divergences = append(m.divergences, d)
+	if len(m.divergences) > shadowDivergenceSamples {
+		m.divergences = m.divergences[len(m.divergences)-shadowDivergenceSamples:]
+	}
+	if result != shadowError {
+		m.logger.Debug("Shadow response diverged",
+			zap.String("path", ex.path),
+			zap.String("result", result),
+			zap.String("field", field))
+	}
+}
+
+// report returns the current divergence report, routes by traffic
+func (m *shadowMirror) report() ShadowReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rep := ShadowReport{
+		Target:      m.target.Redacted(),
+		SamplePct:   m.sample * 100,
+		Since:       m.since.UTC(),
+		Mirrored:    m.mirrored,
+		Dropped:     m.dropped,
+		Routes:      make([]ShadowRouteStats, 0, len(m.routes)),
+		Divergences: append([]ShadowDivergence{}, m.divergences...),
+	}
+	for _, st := range m.routes {
+		out := *st
+		if out.compared > 0 {
+			out.PrimaryAvgMs = float64(out.primaryTotal.Microseconds()) / 1000 / float64(out.compared)
+			out.ShadowAvgMs = float64(out.shadowTotal.Microseconds()) / 1000 / float64(out.compared)
+			out.ShadowSlowerPct = 100 * float64(out.shadowSlower) / float64(out.compared)
+		}
+		if out.Mirrored > 0 {
+			out.DivergenceRate = float64(out.StatusMismatch+out.BodyMismatch) / float64(out.Mirrored)
+		}
+		rep.Routes = append(rep.Routes, out)
+	}
+	sort.Slice(rep.Routes, func(i, j int) bool { return rep.Routes[i].Mirrored > rep.Routes[j].Mirrored })
+	return rep
+}
+
+// reset clears the report, e.g. after deploying a fix to the shadow
+func (m *shadowMirror) reset() {
+	m.mu.Lock()
+	m.since = m.clockNow()
+	m.mirrored, m.dropped = 0, 0
+	m.routes = make(map[string]*ShadowRouteStats)
+	m.divergences = nil
+	m.mu.Unlock()
+}
+
+// shadowRoute collapses resource IDs so /api/v1/blocks/800000 and
+// /api/v1/blocks/800001 report as one route
+func shadowRoute(method, path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	for i, seg := range segs {
+		if shadowIDSegment.MatchString(seg) {
+			segs[i] = "{id}"
+		}
+	}
+	return method + " /" + strings.Join(segs, "/")
+}
+
+// shadowRecorder passes the response through while keeping a copy of the
+// status and the start of the body for comparison
+type shadowRecorder struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	truncated bool
+	wrote     bool
+}
+
+func (rec *shadowRecorder) WriteHeader(code int) {
+	if !rec.wrote {
+		rec.status = code
+		rec.wrote = true
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *shadowRecorder) Write(p []byte) (int, error) {
+	rec.wrote = true
+	if room := shadowMaxBody - rec.body.Len(); room > 0 {
+		if len(p) > room {
+			rec.body.Write(p[:room])
+			rec.truncated = true
+		} else {
+			rec.body.Write(p)
+		}
+	} else if len(p) > 0 {
+		rec.truncated = true
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// shadowAdminHandler serves the divergence report:
+//
+//	GET    /api/v1/admin/shadow  current report
+//	DELETE /api/v1/admin/shadow  start a new report
+func (s *Server) shadowAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if s.shadow == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "shadow traffic not configured"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.jsonResponse(w, http.StatusOK, s.shadow.report())
+	case http.MethodDelete:
+		s.shadow.reset()
+		s.logger.Info("Shadow traffic report reset via admin API")
+		s.jsonResponse(w, http.StatusOK, s.shadow.report())
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
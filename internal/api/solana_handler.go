@@ -6,8 +6,9 @@ import (
 	"time"
 )
 
-// handleSolanaRequest handles Solana-specific requests using the real relay
-func (s *Server) handleSolanaRequest(method string, start time.Time) map[string]interface{} {
+// handleSolanaRequest handles Solana-specific requests using the real relay;
+// upstream calls end with ctx
+func (s *Server) handleSolanaRequest(ctx context.Context, method string, start time.Time) map[string]interface{} {
 	response := map[string]interface{}{
 		"chain":     "solana",
 		"method":    method,
@@ -18,9 +19,7 @@ func (s *Server) handleSolanaRequest(method string, start time.Time) map[string]
 
 	// Ensure Solana relay is connected
 	if s.solanaRelay != nil && !s.solanaRelay.IsConnected() {
-		ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
-		defer cancel()
-		if err := s.solanaRelay.Connect(ctx); err != nil {
+		if err := s.solanaRelay.Connect(s.relayContext()); err != nil {
 			response["error"] = fmt.Sprintf("Failed to connect to Solana network: %v", err)
 			return response
 		}
@@ -31,9 +30,7 @@ func (s *Server) handleSolanaRequest(method string, start time.Time) map[string]
 	case "ping":
 		ok := true
 		if s.solanaRelay != nil && !s.solanaRelay.IsConnected() {
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			defer cancel()
-			if err := s.solanaRelay.Connect(ctx); err != nil {
+			if err := s.solanaRelay.Connect(s.relayContext()); err != nil {
 				ok = false
 				response["error"] = fmt.Sprintf("Ping failed: %v", err)
 			}
@@ -43,13 +40,13 @@ func (s *Server) handleSolanaRequest(method string, start time.Time) map[string]
 			"peer_count": s.solanaRelay.GetPeerCount(),
 		}
 	case "latest", "latest_block":
-		if block, err := s.solanaRelay.GetLatestBlock(); err != nil {
+		if block, err := s.solanaRelay.LatestBlock(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get latest block: %v", err)
 		} else {
 			response["data"] = block
 		}
 	case "status", "network_info":
-		if info, err := s.solanaRelay.GetNetworkInfo(); err != nil {
+		if info, err := s.solanaRelay.NetworkInfo(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get network info: %v", err)
 		} else {
 			response["data"] = info
@@ -60,7 +57,7 @@ func (s *Server) handleSolanaRequest(method string, start time.Time) map[string]
 			"peer_count": peerCount,
 		}
 	case "sync", "sync_status":
-		if status, err := s.solanaRelay.GetSyncStatus(); err != nil {
+		if status, err := s.solanaRelay.SyncStatus(ctx); err != nil {
 			response["error"] = fmt.Sprintf("Failed to get sync status: %v", err)
 		} else {
 			response["data"] = status
@@ -4,6 +4,7 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,6 +14,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
 )
 
 // ===== 1. TAIL LATENCY ELIMINATION (FLAT P99) =====
@@ -25,11 +28,22 @@ type LatencyOptimizer struct {
 	circuitBreakers map[string]*CircuitBreaker
 	predictiveCache *PredictiveCache
 	entropyBuffer   *EntropyMemoryBuffer
+
+	// Adaptive model state, see latency_adaptive.go
+	policy       LatencyPolicy
+	timeouts     map[string]time.Duration
+	hotKeys      map[string]map[string]int
+	lastWarm     map[string]time.Time
+	decisions    []LatencyDecision
+	applyTimeout TimeoutApplier
+	warmer       KeyWarmer
 }
 
 type LatencyTracker struct {
 	samples     []time.Duration
 	maxSamples  int
+	currentP50  time.Duration
+	currentP95  time.Duration
 	currentP99  time.Duration
 	lastUpdated time.Time
 	violations  int
@@ -44,10 +58,15 @@ func NewLatencyOptimizer() *LatencyOptimizer {
 		circuitBreakers: make(map[string]*CircuitBreaker),
 		predictiveCache: NewPredictiveCache(),
 		entropyBuffer:   NewEntropyMemoryBuffer(),
+		policy:          DefaultLatencyPolicy(),
+		timeouts:        make(map[string]time.Duration),
+		hotKeys:         make(map[string]map[string]int),
+		lastWarm:        make(map[string]time.Time),
 	}
 }
 
 func (lo *LatencyOptimizer) TrackRequest(chain string, duration time.Duration) {
+	chain = normalizeChainName(chain)
 	lo.mutex.Lock()
 	defer lo.mutex.Unlock()
 
@@ -75,6 +94,8 @@ func (lo *LatencyOptimizer) TrackRequest(chain string, duration time.Duration) {
 		})
 
 		p99Index := int(math.Ceil(0.99*float64(len(sorted)))) - 1
+		tracker.currentP50 = sorted[len(sorted)/2]
+		tracker.currentP95 = sorted[int(math.Ceil(0.95*float64(len(sorted))))-1]
 		tracker.currentP99 = sorted[p99Index]
 		tracker.lastUpdated = time.Now()
 
@@ -83,6 +104,7 @@ func (lo *LatencyOptimizer) TrackRequest(chain string, duration time.Duration) {
 			tracker.violations++
 			lo.adaptLatencyStrategy(chain, tracker)
 		}
+		lo.evaluateLocked(chain, tracker)
 	}
 
 	// Update metrics
@@ -159,16 +181,12 @@ func (lo *LatencyOptimizer) GetActualStats() map[string]interface{} {
 }
 
 func (lo *LatencyOptimizer) adaptLatencyStrategy(chain string, tracker *LatencyTracker) {
-	tracker.adaptations++
-
-	// Adaptive strategies to maintain flat P99
+	// Adaptive strategies to maintain flat P99. Per-chain timeouts and hot-key
+	// pre-warming are handled by evaluateLocked.
 	if tracker.violations > 5 {
 		// Enable aggressive caching
 		lo.predictiveCache.EnableAggressive(chain)
 
-		// Reduce timeout for faster failures
-		lo.adaptiveTimeout = time.Duration(float64(lo.adaptiveTimeout) * 0.8)
-
 		// Pre-warm entropy buffer
 		lo.entropyBuffer.PreWarm(chain)
 
@@ -285,7 +303,7 @@ func (ual *UnifiedAPILayer) processUnifiedRequest(req *UnifiedRequest, start tim
 	}
 
 	// Execute request with timeout and circuit breaking
-	ctx, cancel := context.WithTimeout(context.Background(), latencyOptimizer.adaptiveTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), latencyOptimizer.Timeout(req.Chain))
 	defer cancel()
 
 	result, err := ual.executeWithCircuitBreaker(ctx, req, adapter)
@@ -325,6 +343,8 @@ type PredictiveCache struct {
 	entropyOptimizer *EntropyOptimizer
 	maxSize          int
 	currentSize      int
+	refresher        CacheRefresher // Re-fetches a chain/method for proactive refresh
+	shedding         bool           // No TTL extensions or refreshes under memory pressure
 }
 
 type CacheEntry struct {
@@ -335,18 +355,35 @@ type CacheEntry struct {
 	AccessCount int
 	Prediction  float64 // Likelihood of future access
 	TTL         time.Duration
+
+	extended     bool        // TTL was stretched because the key was predicted hot
+	refreshTimer *time.Timer // Pending proactive refresh
 }
 
 type PredictionEngine struct {
+	mutex         sync.Mutex
 	patterns      map[string]*AccessPattern
 	mlModel       *SimpleMLModel
-	predictionTTL time.Duration
+	predictionTTL time.Duration // Horizon used for access probability and unknown keys
+
+	// Learner tuning, see predictive_cache.go
+	alpha        float64 // EWMA weight of the newest interval
+	minTTL       time.Duration
+	maxTTL       time.Duration
+	hotThreshold float64 // Access probability above which a key is hot
+	maxPatterns  int
+
+	accurate, early, late uint64
 }
 
 type AccessPattern struct {
 	Frequency    map[time.Duration]int // Access frequency by time intervals
 	LastAccesses []time.Time
-	TrendScore   float64
+	TrendScore   float64 // >1 when accesses are speeding up
+
+	IntervalEWMA time.Duration // Smoothed gap between accesses
+	NextAccess   time.Time     // Predicted time of the next access
+	Count        int
 }
 
 func NewPredictiveCache() *PredictiveCache {
@@ -358,8 +395,8 @@ func NewPredictiveCache() *PredictiveCache {
 }
 
 func (pc *PredictiveCache) Get(req *UnifiedRequest) interface{} {
-	pc.mutex.RLock()
-	defer pc.mutex.RUnlock()
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
 
 	key := pc.generateKey(req)
 	entry, exists := pc.cache[key]
@@ -379,6 +416,7 @@ func (pc *PredictiveCache) Get(req *UnifiedRequest) interface{} {
 
 	// Update prediction score
 	pc.predictions.UpdatePattern(key, entry.LastAccess)
+	pc.afterHitLocked(entry)
 
 	metricsTracker.IncrementCounter("sprint_cache_hits", req.Chain, req.Method)
 	return entry.Value
@@ -402,13 +440,18 @@ func (pc *PredictiveCache) Set(req *UnifiedRequest, value interface{}) {
 		Prediction: pc.predictions.PredictFutureAccess(key),
 	}
 
-	// Evict if necessary
-	if pc.currentSize >= pc.maxSize {
-		pc.evictLeastPredicted()
+	if old, exists := pc.cache[key]; exists {
+		pc.stopRefreshLocked(old)
+	} else {
+		// Evict if necessary
+		if pc.currentSize >= pc.maxSize {
+			pc.evictLeastPredicted()
+		}
+		pc.currentSize++
 	}
 
 	pc.cache[key] = entry
-	pc.currentSize++
+	pc.scheduleRefreshLocked(entry)
 }
 
 // GetActualCacheStats returns real cache performance metrics
@@ -440,7 +483,7 @@ func (pc *PredictiveCache) GetActualCacheStats() map[string]interface{} {
 		"hit_rate_percent":  fmt.Sprintf("%.1f%%", hitRate),
 		"total_requests":    totalRequests,
 		"total_hits":        totalHits,
-		"prediction_engine": "Active",
+		"prediction_engine": pc.predictions.Stats(),
 		"last_updated":      time.Now().Format(time.RFC3339),
 	}
 }
@@ -704,7 +747,6 @@ type EntropyOptimizer struct{}
 func NewResponseNormalizer() *ResponseNormalizer { return &ResponseNormalizer{} }
 func NewRequestValidator() *RequestValidator     { return &RequestValidator{} }
 func NewMonetizationEngine() *MonetizationEngine { return &MonetizationEngine{} }
-func NewPredictionEngine() *PredictionEngine     { return &PredictionEngine{} }
 
 func (rn *ResponseNormalizer) Normalize(response interface{}) interface{} { return response }
 func (rv *RequestValidator) Validate(req *UnifiedRequest) error           { return nil }
@@ -716,17 +758,47 @@ func (ual *UnifiedAPILayer) executeWithCircuitBreaker(ctx context.Context, req *
 func (pc *PredictiveCache) generateKey(req *UnifiedRequest) string {
 	return fmt.Sprintf("%s:%s", req.Chain, req.Method)
 }
-func (pc *PredictiveCache) evict(key string)                            {}
-func (pc *PredictiveCache) evictLeastPredicted()                        {}
-func (pc *PredictiveCache) preCacheRequest(chain, req string)           {}
-func (pe *PredictionEngine) UpdatePattern(key string, access time.Time) {}
-func (pe *PredictionEngine) PredictOptimalTTL(key, chain string) time.Duration {
-	return 5 * time.Minute
-}
-func (pe *PredictionEngine) PredictFutureAccess(key string) float64 { return 0.5 }
-func (emb *EntropyMemoryBuffer) backgroundEntropyGeneration()       {}
+func (emb *EntropyMemoryBuffer) backgroundEntropyGeneration() {}
+
+// generateHighQualityEntropy fills a chain buffer with one batched
+// SecureBuffer request rather than one FFI call per 32 bytes
 func (emb *EntropyMemoryBuffer) generateHighQualityEntropy(size int) []byte {
-	return make([]byte, size)
+	out := make([]byte, size)
+	n := (size + securebuf.EntropySize - 1) / securebuf.EntropySize
+	batch := <-securebuf.RequestFastEntropy(n)
+	if batch.Err != nil {
+		return emb.generateFastEntropy(size)
+	}
+	for i := range batch.Buffers {
+		copy(out[i*securebuf.EntropySize:], batch.Buffers[i][:])
+		clear(batch.Buffers[i][:])
+	}
+	return out
+}
+
+// generateFastEntropy serves small on-demand requests from the shared pool
+func (emb *EntropyMemoryBuffer) generateFastEntropy(size int) []byte {
+	out := make([]byte, size)
+	if _, err := securebuf.SharedEntropyPool().Read(out); err != nil {
+		rand.Read(out)
+	}
+	return out
+}
+
+// refreshBuffer regenerates a chain buffer without holding the lock during
+// the request
+func (emb *EntropyMemoryBuffer) refreshBuffer(chain string) {
+	emb.mutex.RLock()
+	buffer, exists := emb.buffers[chain]
+	emb.mutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	data := emb.generateHighQualityEntropy(buffer.Size)
+
+	emb.mutex.Lock()
+	buffer.Data = data
+	buffer.LastRefresh = time.Now()
+	emb.mutex.Unlock()
 }
-func (emb *EntropyMemoryBuffer) generateFastEntropy(size int) []byte { return make([]byte, size) }
-func (emb *EntropyMemoryBuffer) refreshBuffer(chain string)          {}
@@ -0,0 +1,69 @@
+// Package api provides the SPV inclusion proof endpoint
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/spv"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"go.uber.org/zap"
+)
+
+// ===== SPV PROOFS =====
+
+// spvProofHandler returns a Merkle inclusion proof for a Bitcoin
+// transaction so light clients can verify a confirmation against their own
+// header chain instead of trusting this server (Enterprise tier):
+//
+//	GET /api/v1/spv/proof?txid={txid}&block={block hash}
+func (s *Server) spvProofHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if !s.isEnterpriseTier(s.getCustomerTierFromContext(r)) {
+		s.jsonResponse(w, http.StatusForbidden, map[string]string{"error": "SPV proofs require the enterprise tier"})
+		return
+	}
+	if s.spv == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "bitcoin node RPC not configured"})
+		return
+	}
+
+	q := r.URL.Query()
+	txid, err := chainhash.NewHashFromStr(q.Get("txid"))
+	if err != nil || len(q.Get("txid")) != 2*chainhash.HashSize {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "txid must be a 64-character hex hash"})
+		return
+	}
+	blockHash, err := chainhash.NewHashFromStr(q.Get("block"))
+	if err != nil || len(q.Get("block")) != 2*chainhash.HashSize {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "block must be a 64-character hex hash"})
+		return
+	}
+
+	proof, err := s.spv.Proof(r.Context(), *txid, *blockHash)
+	switch {
+	case errors.Is(err, spv.ErrTxNotInBlock), errors.Is(err, spv.ErrBlockNotFound):
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case err != nil:
+		s.logger.Warn("SPV proof failed",
+			zap.String("txid", txid.String()),
+			zap.String("block", blockHash.String()),
+			zap.Error(err))
+		s.jsonResponse(w, http.StatusBadGateway, map[string]string{"error": "failed to build proof"})
+		return
+	}
+	s.jsonResponse(w, http.StatusOK, proof)
+}
+
+// newSPVSource uses the configured bitcoind RPC endpoint
+func newSPVSource(cfg config.Config) *spv.RPCSource {
+	if cfg.RPCURL == "" {
+		return nil
+	}
+	return spv.NewRPCSource(cfg.RPCURL, cfg.RPCUsername, cfg.RPCPassword, cfg.RPCTimeout)
+}
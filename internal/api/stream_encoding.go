@@ -0,0 +1,58 @@
+// Package api provides binary block encoding for streams
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/gorilla/websocket"
+)
+
+// ===== STREAM ENCODING =====
+
+// Blocks are streamed as JSON text frames unless the client asks for
+// protobuf, by offering the schemas.SubprotocolBlockProto subprotocol or
+// with ?encoding=proto for clients that cannot set one. ?encoding=json
+// keeps JSON whatever the client offers.
+
+// Stream encodings
+const (
+	streamEncodingJSON  = "json"
+	streamEncodingProto = "proto"
+)
+
+// parseStreamEncoding returns the encoding forced by ?encoding=, or ""
+// when the subprotocol decides
+func parseStreamEncoding(r *http.Request) (string, error) {
+	switch enc := strings.ToLower(r.URL.Query().Get("encoding")); enc {
+	case "":
+		return "", nil
+	case streamEncodingJSON:
+		return streamEncodingJSON, nil
+	case streamEncodingProto, "protobuf":
+		return streamEncodingProto, nil
+	default:
+		return "", fmt.Errorf("encoding must be json or proto")
+	}
+}
+
+// offerStreamSubprotocols offers the block subprotocols the request
+// allows; the upgrader picks the first the client also offers
+func offerStreamSubprotocols(r *http.Request, upgrader *websocket.Upgrader) {
+	switch enc, _ := parseStreamEncoding(r); enc {
+	case streamEncodingJSON:
+		upgrader.Subprotocols = []string{schemas.SubprotocolBlockJSON}
+	case streamEncodingProto:
+		upgrader.Subprotocols = []string{schemas.SubprotocolBlockProto}
+	default:
+		upgrader.Subprotocols = []string{schemas.SubprotocolBlockProto, schemas.SubprotocolBlockJSON}
+	}
+}
+
+// streamsProto reports whether blocks on conn are sent as protobuf
+func streamsProto(r *http.Request, conn *websocket.Conn) bool {
+	enc, _ := parseStreamEncoding(r)
+	return enc == streamEncodingProto || (enc == "" && conn.Subprotocol() == schemas.SubprotocolBlockProto)
+}
@@ -0,0 +1,221 @@
+// Package api provides catch-up replay for block stream subscribers
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blockbus"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// ===== STREAM REPLAY =====
+
+// Replay marker frames sent around catch-up events
+const (
+	streamMarkerReplayStart = "replay_start"
+	streamMarkerReplayEnd   = "replay_end"
+)
+
+// streamReplay is a reconnecting client's request to catch up from a
+// height or a point in time before switching to live blocks.
+type streamReplay struct {
+	fromHeight uint32
+	hasHeight  bool
+	fromTime   time.Time
+}
+
+// streamMarker separates replayed events from live ones on the wire
+type streamMarker struct {
+	Type         string     `json:"type"`
+	Chain        string     `json:"chain"`
+	FromHeight   *uint32    `json:"from_height,omitempty"`
+	FromTime     *time.Time `json:"from_time,omitempty"`
+	Count        int        `json:"count"`
+	OldestHeight uint32     `json:"oldest_height,omitempty"`
+	Truncated    bool       `json:"truncated,omitempty"` // requested range starts before retained history
+}
+
+// parseStreamReplay reads ?from_height=H or ?from_time=<RFC3339|unix seconds>.
+// It returns nil when the client did not ask for a replay.
+func parseStreamReplay(r *http.Request) (*streamReplay, error) {
+	q := r.URL.Query()
+	h, t := q.Get("from_height"), q.Get("from_time")
+	if h == "" && t == "" {
+		return nil, nil
+	}
+
+	rp := &streamReplay{}
+	if h != "" {
+		n, err := strconv.ParseUint(h, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("from_height must be a block height")
+		}
+		rp.fromHeight = uint32(n)
+		rp.hasHeight = true
+	}
+	if t != "" {
+		if ts, err := time.Parse(time.RFC3339, t); err == nil {
+			rp.fromTime = ts
+		} else if secs, err := strconv.ParseInt(t, 10, 64); err == nil {
+			rp.fromTime = time.Unix(secs, 0)
+		} else {
+			return nil, fmt.Errorf("from_time must be RFC3339 or unix seconds")
+		}
+	}
+	return rp, nil
+}
+
+// matches reports whether a retained event falls inside the replay range
+func (rp *streamReplay) matches(ev blocks.BlockEvent) bool {
+	if rp.hasHeight && ev.Height < rp.fromHeight {
+		return false
+	}
+	if !rp.fromTime.IsZero() {
+		seen := ev.DetectedAt
+		if seen.IsZero() {
+			seen = ev.Timestamp
+		}
+		if seen.Before(rp.fromTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockBusConfig sizes the bus history from config so replay can reach back far enough
+func blockBusConfig(cfg config.Config) blockbus.Config {
+	busCfg := blockbus.DefaultConfig()
+	if cfg.BlockReplaySize > 0 {
+		busCfg.ReplaySize = cfg.BlockReplaySize
+	}
+	return busCfg
+}
+
+// runBlockStream subscribes a WebSocket client to blocks of one chain and
+// writes them until ctx ends. With a replay request, retained blocks in the
+// range are sent first between replay_start and replay_end markers.
+func (s *Server) runBlockStream(ctx context.Context, conn *websocket.Conn, lease *StreamLease, name, chain string, replay *streamReplay) {
+	opts := blockbus.SubscribeOptions{Name: name, Replay: streamReplayDefault}
+	if replay != nil {
+		opts.ReplayFilter = func(ev blocks.BlockEvent) bool {
+			return blockMatchesChain(ev, chain) && replay.matches(ev)
+		}
+	}
+	sub := s.bus.Subscribe(opts)
+	defer s.bus.Unsubscribe(sub)
+
+	write := func(messageType int, data []byte) error {
+		conn.SetWriteDeadline(s.clock.Now().Add(10 * time.Second))
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			s.logger.Debug("Error writing to WebSocket", zap.Error(err), zap.Uint64("dropped", sub.Dropped()))
+			return err
+		}
+		if lease.payload != nil {
+			lease.payload.Add(float64(len(data)))
+		}
+		lease.Touch()
+		return nil
+	}
+	writeJSON := func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return write(websocket.TextMessage, data)
+	}
+	// Markers stay JSON; blocks are protobuf when negotiated
+	var buf []byte
+	writeBlock := func(blk blocks.BlockEvent) error {
+		if !lease.proto {
+			return writeJSON(s.streamBlock(blk))
+		}
+		buf = schemas.AppendBlockProto(buf[:0], s.streamBlock(blk))
+		return write(websocket.BinaryMessage, buf)
+	}
+
+	replayOpen, replayed := false, 0
+	endReplay := func() error {
+		replayOpen = false
+		return writeJSON(streamMarker{Type: streamMarkerReplayEnd, Chain: chain, Count: replayed})
+	}
+
+	if replay != nil {
+		start := s.replayStartMarker(chain, replay, sub.ReplayPending())
+		if writeJSON(start) != nil {
+			return
+		}
+		replayOpen = true
+		if start.Count == 0 && endReplay() != nil {
+			return
+		}
+	}
+
+	for {
+		blk, fromReplay, err := sub.Receive(ctx)
+		if err != nil {
+			// Context cancelled (client disconnected, idle reaped or
+			// shutdown) or bus closed
+			s.closeStream(conn, lease)
+			return
+		}
+		if !blockMatchesChain(blk, chain) {
+			continue
+		}
+		// Close the replay section before the first live event in any case
+		if replayOpen && !fromReplay && endReplay() != nil {
+			return
+		}
+		if writeBlock(blk) != nil {
+			return
+		}
+		if replayOpen {
+			replayed++
+			if sub.ReplayPending() == 0 && endReplay() != nil {
+				return
+			}
+		}
+	}
+}
+
+// replayStartMarker describes the catch-up range, flagging requests that
+// reach back further than the bus still retains
+func (s *Server) replayStartMarker(chain string, replay *streamReplay, count int) streamMarker {
+	m := streamMarker{Type: streamMarkerReplayStart, Chain: chain, Count: count}
+	if !replay.fromTime.IsZero() {
+		t := replay.fromTime
+		m.FromTime = &t
+	}
+	if replay.hasHeight {
+		h := replay.fromHeight
+		m.FromHeight = &h
+	}
+
+	var oldest *blocks.BlockEvent
+	for _, ev := range s.bus.Recent(blockBusConfig(s.cfg).ReplaySize) {
+		if blockMatchesChain(ev, chain) {
+			ev := ev
+			oldest = &ev
+			break
+		}
+	}
+	if oldest == nil {
+		return m
+	}
+	m.OldestHeight = oldest.Height
+	if replay.hasHeight && replay.fromHeight < oldest.Height {
+		m.Truncated = true
+	}
+	if !replay.fromTime.IsZero() && replay.fromTime.Before(oldest.DetectedAt) {
+		m.Truncated = true
+	}
+	return m
+}
@@ -0,0 +1,348 @@
+// Package api provides the latest-block staleness SLA
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== TIP STALENESS SLA =====
+
+// A chain whose latest block is older than its configured max age is
+// serving stale data even though every connection looks healthy. The
+// first breach raises an alert and forces relief: the chain's relay fails
+// over to its next provider and the hooks registered with OnTipStale run
+// (for Bitcoin, typically preferring the P2P client over a relay). Relief
+// repeats every max age until a new tip arrives.
+
+// tipReliefTimeout bounds each relief hook
+const tipReliefTimeout = 15 * time.Second
+
+var (
+	tipAgeSeconds = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "api_tip_age_seconds",
+			Help: "Seconds since the chain's latest block arrived",
+		},
+		[]string{"chain"},
+	)
+
+	tipSLABreached = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "api_tip_sla_breached",
+			Help: "1 while the chain's latest block is older than its SLA",
+		},
+		[]string{"chain"},
+	)
+
+	tipSLAActions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_tip_sla_actions_total",
+			Help: "Relief actions taken for stale chain tips by action and result",
+		},
+		[]string{"chain", "action", "result"},
+	)
+)
+
+// TipState is a chain's standing against its staleness SLA
+type TipState string
+
+const (
+	TipFresh TipState = "fresh"
+	TipStale TipState = "stale"
+)
+
+// TipStatus is a chain's latest block and its age against the SLA
+type TipStatus struct {
+	Chain         string     `json:"chain"`
+	Height        uint32     `json:"height"`
+	Hash          string     `json:"hash"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	AgeSeconds    float64    `json:"tip_age_seconds"`
+	MaxAgeSeconds float64    `json:"max_age_seconds"`
+	State         TipState   `json:"state"`
+	StaleSince    *time.Time `json:"stale_since,omitempty"`
+	Breaches      uint64     `json:"breaches"`
+	LastAction    string     `json:"last_action,omitempty"`
+}
+
+// tipReliefHook is a relief action registered for one chain
+type tipReliefHook struct {
+	name string
+	fn   func(context.Context, TipStatus) error
+}
+
+// chainTip is the latest block seen for one chain
+type chainTip struct {
+	block      blocks.BlockEvent
+	received   time.Time
+	staleSince time.Time // Zero while fresh
+	lastRelief time.Time
+	breaches   uint64
+	lastAction string
+}
+
+// tipSLAMonitor tracks the latest block per chain. Chains are checked once
+// their first block arrives, so a chain the deployment does not serve
+// never alerts.
+type tipSLAMonitor struct {
+	maxAge map[string]time.Duration
+
+	mu    sync.Mutex
+	tips  map[string]*chainTip
+	hooks map[string][]tipReliefHook
+}
+
+// newTipSLAMonitor uses the per-chain max ages from cfg
+func newTipSLAMonitor(cfg config.Config) *tipSLAMonitor {
+	maxAge := make(map[string]time.Duration)
+	for chain, age := range map[blocks.Chain]time.Duration{
+		blocks.ChainBitcoin:  cfg.BitcoinTipMaxAge,
+		blocks.ChainEthereum: cfg.EthereumTipMaxAge,
+		blocks.ChainSolana:   cfg.SolanaTipMaxAge,
+	} {
+		if age > 0 {
+			maxAge[string(chain)] = age
+		}
+	}
+	return &tipSLAMonitor{
+		maxAge: maxAge,
+		tips:   make(map[string]*chainTip),
+		hooks:  make(map[string][]tipReliefHook),
+	}
+}
+
+// observe records a live block. Transactions and backfilled blocks say
+// nothing about the tip, and a lower height is a late duplicate.
+func (m *tipSLAMonitor) observe(event blocks.BlockEvent, now time.Time) {
+	if event.TxID != "" || event.Backfilled {
+		return
+	}
+	chain := string(event.Chain)
+	if chain == "" {
+		chain = string(blocks.ChainBitcoin)
+	}
+	chain = normalizeChainName(chain)
+	if _, ok := m.maxAge[chain]; !ok {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tip, ok := m.tips[chain]
+	if !ok {
+		tip = &chainTip{}
+		m.tips[chain] = tip
+	} else if event.Height < tip.block.Height {
+		return
+	}
+	tip.block = event
+	tip.received = now
+}
+
+// evaluate updates each chain's state at now. It returns the chains due
+// for relief, which are those that just went stale and those still stale
+// a full max age after their last relief, and the chains that recovered.
+func (m *tipSLAMonitor) evaluate(now time.Time) (due, recovered []TipStatus) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for chain, tip := range m.tips {
+		maxAge := m.maxAge[chain]
+		age := now.Sub(tip.received)
+		tipAgeSeconds.WithLabelValues(chain).Set(age.Seconds())
+
+		if age <= maxAge {
+			if !tip.staleSince.IsZero() {
+				tip.staleSince = time.Time{}
+				tipSLABreached.WithLabelValues(chain).Set(0)
+				recovered = append(recovered, tip.status(chain, maxAge, now))
+			}
+			continue
+		}
+
+		if tip.staleSince.IsZero() {
+			tip.staleSince = now
+			tip.breaches++
+			tipSLABreached.WithLabelValues(chain).Set(1)
+		} else if now.Sub(tip.lastRelief) < maxAge {
+			continue
+		}
+		tip.lastRelief = now
+		due = append(due, tip.status(chain, maxAge, now))
+	}
+	return due, recovered
+}
+
+// relieved records the last relief action taken for chain
+func (m *tipSLAMonitor) relieved(chain, action string) {
+	m.mu.Lock()
+	if tip, ok := m.tips[chain]; ok {
+		tip.lastAction = action
+	}
+	m.mu.Unlock()
+}
+
+// reliefHooks returns the hooks registered for chain
+func (m *tipSLAMonitor) reliefHooks(chain string) []tipReliefHook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]tipReliefHook(nil), m.hooks[chain]...)
+}
+
+// snapshot returns every tracked chain's status at now, by chain name
+func (m *tipSLAMonitor) snapshot(now time.Time) map[string]TipStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]TipStatus, len(m.tips))
+	for chain, tip := range m.tips {
+		out[chain] = tip.status(chain, m.maxAge[chain], now)
+	}
+	return out
+}
+
+// checkInterval is a quarter of the tightest SLA, at least a second
+func (m *tipSLAMonitor) checkInterval() time.Duration {
+	interval := time.Duration(0)
+	for _, age := range m.maxAge {
+		if interval == 0 || age/4 < interval {
+			interval = age / 4
+		}
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return interval
+}
+
+func (t *chainTip) status(chain string, maxAge time.Duration, now time.Time) TipStatus {
+	st := TipStatus{
+		Chain:         chain,
+		Height:        t.block.Height,
+		Hash:          t.block.Hash,
+		ReceivedAt:    t.received.UTC(),
+		AgeSeconds:    now.Sub(t.received).Seconds(),
+		MaxAgeSeconds: maxAge.Seconds(),
+		State:         TipFresh,
+		Breaches:      t.breaches,
+		LastAction:    t.lastAction,
+	}
+	if !t.staleSince.IsZero() {
+		since := t.staleSince.UTC()
+		st.State = TipStale
+		st.StaleSince = &since
+	}
+	return st
+}
+
+// OnTipStale registers fn as a relief action for chain ("btc", "bitcoin",
+// "eth", ...), run when its tip breaches the staleness SLA and again every
+// max age while it stays stale. Use it for components the server does not
+// own, such as switching Bitcoin block intake from a relay to P2P.
+func (s *Server) OnTipStale(chain, name string, fn func(context.Context, TipStatus) error) {
+	chain = normalizeChainName(chain)
+	s.tipSLA.mu.Lock()
+	s.tipSLA.hooks[chain] = append(s.tipSLA.hooks[chain], tipReliefHook{name: name, fn: fn})
+	s.tipSLA.mu.Unlock()
+}
+
+// TipStatus returns the staleness SLA status of every chain with a tip
+func (s *Server) TipStatus() []TipStatus {
+	snap := s.tipSLA.snapshot(s.clock.Now())
+	out := make([]TipStatus, 0, len(snap))
+	for _, st := range snap {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Chain < out[j].Chain })
+	return out
+}
+
+// startTipSLA feeds the block bus into the staleness monitor and checks
+// it until ctx is cancelled
+func (s *Server) startTipSLA(ctx context.Context) {
+	if s.bus == nil || len(s.tipSLA.maxAge) == 0 {
+		return
+	}
+
+	go s.consumeBlocks(ctx, "tip_sla", func(event blocks.BlockEvent) {
+		s.tipSLA.observe(event, s.clock.Now())
+	})
+	go func() {
+		t := time.NewTicker(s.tipSLA.checkInterval())
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				due, recovered := s.tipSLA.evaluate(s.clock.Now())
+				for _, st := range recovered {
+					s.logger.Info("Chain tip back within SLA",
+						zap.String("chain", st.Chain),
+						zap.Uint32("height", st.Height))
+					s.alerts.record(s.clock.Now(), "tip_sla", st.Chain,
+						fmt.Sprintf("recovered at height %d", st.Height))
+				}
+				for _, st := range due {
+					go s.relieveStaleTip(ctx, st)
+				}
+			}
+		}
+	}()
+}
+
+// relieveStaleTip raises the alert for a stale chain and runs its relief
+// actions: relay failover for chains the server relays itself, then the
+// registered hooks
+func (s *Server) relieveStaleTip(ctx context.Context, st TipStatus) {
+	s.logger.Error("Chain tip older than SLA, forcing failover",
+		zap.String("chain", st.Chain),
+		zap.Uint32("height", st.Height),
+		zap.String("hash", st.Hash),
+		zap.Float64("tip_age_seconds", st.AgeSeconds),
+		zap.Float64("max_age_seconds", st.MaxAgeSeconds),
+		zap.Uint64("breaches", st.Breaches))
+	tipSLAActions.WithLabelValues(st.Chain, "alert", "ok").Inc()
+	s.alerts.record(s.clock.Now(), "tip_sla", st.Chain,
+		fmt.Sprintf("tip at height %d is %.0fs old, SLA %.0fs", st.Height, st.AgeSeconds, st.MaxAgeSeconds))
+
+	var failover func(reason string) (string, bool)
+	switch {
+	case st.Chain == string(blocks.ChainEthereum) && s.ethereumRelay != nil:
+		failover = s.ethereumRelay.Failover
+	case st.Chain == string(blocks.ChainSolana) && s.solanaRelay != nil:
+		failover = s.solanaRelay.Failover
+	}
+	if failover != nil {
+		if _, ok := failover("stale_tip"); ok {
+			tipSLAActions.WithLabelValues(st.Chain, "relay_failover", "ok").Inc()
+			s.tipSLA.relieved(st.Chain, "relay_failover")
+		} else {
+			tipSLAActions.WithLabelValues(st.Chain, "relay_failover", "skipped").Inc()
+		}
+	}
+
+	for _, hook := range s.tipSLA.reliefHooks(st.Chain) {
+		hctx, cancel := context.WithTimeout(ctx, tipReliefTimeout)
+		err := hook.fn(hctx, st)
+		cancel()
+		if err != nil {
+			s.logger.Warn("Stale tip relief action failed",
+				zap.String("chain", st.Chain),
+				zap.String("action", hook.name),
+				zap.Error(err))
+			tipSLAActions.WithLabelValues(st.Chain, hook.name, "error").Inc()
+			continue
+		}
+		tipSLAActions.WithLabelValues(st.Chain, hook.name, "ok").Inc()
+		s.tipSLA.relieved(st.Chain, hook.name)
+	}
+}
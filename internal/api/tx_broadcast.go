@@ -0,0 +1,413 @@
+// Package api provides multi-path transaction broadcast
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/p2p"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ===== TRANSACTION BROADCAST =====
+
+// A transaction sent to one provider reaches the network only if that
+// provider accepts it and relays it on. POST /v1/{chain}/tx submits a
+// signed transaction on every path configured for the chain at once:
+// the Bitcoin node, each Esplora endpoint and the connected P2P peers for
+// Bitcoin, and each HTTP RPC endpoint for Ethereum and Solana. The
+// response reports each path's answer; the time until the first path
+// accepts the transaction is recorded as its first-seen time.
+
+const (
+	txBroadcastTimeout = 15 * time.Second // All paths
+	txP2PWait          = 3 * time.Second  // How long peers have to fetch an announced transaction
+	txMaxBodySize      = 1 << 20
+)
+
+var (
+	txBroadcastPaths = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tx_broadcast_path_results_total",
+		Help: "Transaction submissions by chain, path and result (accepted, known, rejected, error)",
+	}, []string{"chain", "path", "result"})
+	txBroadcastFirstSeen = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "tx_broadcast_first_seen_seconds",
+		Help:    "Time from submission until the first path accepted a transaction, by chain",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"chain"})
+)
+
+// txHTTPClient submits to relay endpoints; each call is bounded by the
+// request context
+var txHTTPClient = &http.Client{Timeout: txBroadcastTimeout}
+
+// SetTxPeers lets /v1/{chain}/tx announce Bitcoin transactions to the p2p
+// client's peers
+func (s *Server) SetTxPeers(c *p2p.Client) {
+	s.txPeers = c
+}
+
+// txRejectedError is a path refusing the transaction itself, as opposed
+// to failing to answer
+type txRejectedError struct {
+	Message string
+}
+
+func (e *txRejectedError) Error() string {
+	return e.Message
+}
+
+// txPath is one way to submit a transaction
+type txPath struct {
+	name   string
+	submit func(ctx context.Context, raw []byte) (txSubmission, error)
+}
+
+// txSubmission is what a path reports back
+type txSubmission struct {
+	TxID           string
+	Peers          int
+	PeersRequested int
+}
+
+// txBroadcastRequest is the body of POST /v1/{chain}/tx
+type txBroadcastRequest struct {
+	Tx string `json:"tx"` // Hex; Solana also accepts base64
+}
+
+// txPathResult is one path's answer
+type txPathResult struct {
+	Path           string  `json:"path"`
+	Accepted       bool    `json:"accepted"`
+	AlreadyKnown   bool    `json:"already_known,omitempty"`
+	TxID           string  `json:"txid,omitempty"`
+	Error          string  `json:"error,omitempty"`
+	LatencyMs      float64 `json:"latency_ms"`
+	Peers          int     `json:"peers,omitempty"`           // P2P: peers announced to
+	PeersRequested int     `json:"peers_requested,omitempty"` // P2P: peers that fetched it
+}
+
+// txBroadcastResponse is the answer to POST /v1/{chain}/tx
+type txBroadcastResponse struct {
+	Chain       string         `json:"chain"`
+	TxID        string         `json:"txid,omitempty"`
+	Accepted    bool           `json:"accepted"`
+	FirstSeenMs float64        `json:"first_seen_ms,omitempty"`
+	Paths       []txPathResult `json:"paths"`
+}
+
+// chainTxHandler serves POST /v1/{chain}/tx {"tx": "<signed transaction>"}.
+// It answers 200 when any path accepted the transaction and 502 with
+// every path's error when none did.
+func (s *Server) chainTxHandler(chain string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	chain = normalizeChainName(chain)
+	paths := s.txPaths(chain)
+	if len(paths) == 0 {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "no broadcast paths configured for chain " + chain})
+		return
+	}
+
+	var req txBroadcastRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, txMaxBodySize)).Decode(&req); err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+		return
+	}
+	raw, txid, err := decodeRawTx(chain, req.Tx)
+	if err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), txBroadcastTimeout)
+	defer cancel()
+	resp := s.broadcastTx(ctx, chain, raw, paths)
+	if txid != "" {
+		resp.TxID = txid
+	}
+
+	status := http.StatusOK
+	if !resp.Accepted {
+		status = http.StatusBadGateway
+	}
+	s.requestLogger(r).Info("Transaction broadcast",
+		zap.String("chain", chain),
+		zap.String("txid", resp.TxID),
+		zap.Bool("accepted", resp.Accepted),
+		zap.Float64("first_seen_ms", resp.FirstSeenMs))
+	s.jsonResponse(w, status, resp)
+}
+
+// broadcastTx submits raw on every path concurrently
+func (s *Server) broadcastTx(ctx context.Context, chain string, raw []byte, paths []txPath) txBroadcastResponse {
+	resp := txBroadcastResponse{Chain: chain, Paths: make([]txPathResult, len(paths))}
+
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path txPath) {
+			defer wg.Done()
+			start := time.Now()
+			sub, err := path.submit(ctx, raw)
+			res := txPathResult{
+				Path:           path.name,
+				TxID:           sub.TxID,
+				LatencyMs:      float64(time.Since(start).Microseconds()) / 1000,
+				Peers:          sub.Peers,
+				PeersRequested: sub.PeersRequested,
+			}
+
+			result := "accepted"
+			switch {
+			case err == nil:
+				res.Accepted = true
+			case txAlreadyKnown(err):
+				res.Accepted, res.AlreadyKnown = true, true
+				result = "known"
+			default:
+				res.Error = err.Error()
+				result = "error"
+				var rejected *txRejectedError
+				if errors.As(err, &rejected) {
+					result = "rejected"
+				}
+			}
+			txBroadcastPaths.WithLabelValues(chain, path.name, result).Inc()
+			resp.Paths[i] = res
+		}(i, path)
+	}
+	wg.Wait()
+
+	for _, res := range resp.Paths {
+		if !res.Accepted {
+			continue
+		}
+		if !resp.Accepted || res.LatencyMs < resp.FirstSeenMs {
+			resp.FirstSeenMs = res.LatencyMs
+		}
+		resp.Accepted = true
+		if resp.TxID == "" {
+			resp.TxID = res.TxID
+		}
+	}
+	if resp.Accepted {
+		txBroadcastFirstSeen.WithLabelValues(chain).Observe(resp.FirstSeenMs / 1000)
+	}
+	return resp
+}
+
+// decodeRawTx decodes a signed transaction. Bitcoin transactions are
+// parsed, so a malformed one is refused here and its txid is known
+// before any path answers.
+func decodeRawTx(chain, encoded string) ([]byte, string, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, "", errors.New("tx is required")
+	}
+	raw, err := hex.DecodeString(strings.TrimPrefix(encoded, "0x"))
+	if err != nil && chain == string(blocks.ChainSolana) {
+		raw, err = base64.StdEncoding.DecodeString(encoded)
+	}
+	if err != nil {
+		return nil, "", errors.New("tx must be hex encoded")
+	}
+
+	if chain != string(blocks.ChainBitcoin) {
+		return raw, "", nil
+	}
+	var tx wire.MsgTx
+	reader := bytes.NewReader(raw)
+	if err := tx.Deserialize(reader); err != nil || reader.Len() != 0 {
+		return nil, "", errors.New("tx is not a valid Bitcoin transaction")
+	}
+	return raw, tx.TxHash().String(), nil
+}
+
+// txAlreadyKnown recognizes the ways nodes say they already have the
+// transaction, which means it is out there
+func txAlreadyKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, hint := range []string{"already in block chain", "already-in-mempool", "already known", "alreadyprocessed", "already been processed"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// txPaths returns the broadcast paths configured for chain
+func (s *Server) txPaths(chain string) []txPath {
+	var paths []txPath
+	switch chain {
+	case string(blocks.ChainBitcoin):
+		if s.mining != nil {
+			paths = append(paths, txPath{name: "node", submit: s.submitTxNode})
+		}
+		for _, endpoint := range esploraTxEndpoints(s.cfg.GetStringSlice("ESPLORA_ENDPOINTS")) {
+			paths = append(paths, txPath{name: "esplora:" + endpointHost(endpoint), submit: esploraTxSubmitter(endpoint)})
+		}
+		if s.txPeers != nil {
+			paths = append(paths, txPath{name: "p2p", submit: s.submitTxPeers})
+		}
+	case string(blocks.ChainEthereum):
+		for _, endpoint := range s.cfg.EthereumHTTPEndpoints {
+			paths = append(paths, txPath{name: "rpc:" + endpointHost(endpoint), submit: rpcTxSubmitter(endpoint, "eth_sendRawTransaction", func(raw []byte) []interface{} {
+				return []interface{}{"0x" + hex.EncodeToString(raw)}
+			})})
+		}
+	case string(blocks.ChainSolana):
+		for _, endpoint := range s.cfg.SolanaHTTPEndpoints {
+			paths = append(paths, txPath{name: "rpc:" + endpointHost(endpoint), submit: rpcTxSubmitter(endpoint, "sendTransaction", func(raw []byte) []interface{} {
+				return []interface{}{base64.StdEncoding.EncodeToString(raw), map[string]string{"encoding": "base64"}}
+			})})
+		}
+	}
+	return paths
+}
+
+// esploraTxEndpoints are the configured Esplora endpoints, or the
+// relay's fallbacks
+func esploraTxEndpoints(configured []string) []string {
+	if len(configured) == 0 {
+		configured = []string{"https://blockstream.info/api", "https://mempool.space/api"}
+	}
+	endpoints := make([]string, 0, len(configured))
+	for _, endpoint := range configured {
+		endpoints = append(endpoints, strings.TrimRight(endpoint, "/"))
+	}
+	return endpoints
+}
+
+// endpointHost names a path by host so provider keys in URLs stay out of
+// responses and metrics
+func endpointHost(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
+
+// submitTxNode sends the transaction to the configured bitcoind
+func (s *Server) submitTxNode(ctx context.Context, raw []byte) (txSubmission, error) {
+	result, err := s.mining.call(ctx, "sendrawtransaction", []interface{}{hex.EncodeToString(raw)})
+	var rpcErr *bitcoindError
+	if errors.As(err, &rpcErr) {
+		return txSubmission{}, &txRejectedError{Message: rpcErr.Message}
+	}
+	if err != nil {
+		return txSubmission{}, err
+	}
+	var sub txSubmission
+	if err := json.Unmarshal(result, &sub.TxID); err != nil {
+		return txSubmission{}, fmt.Errorf("sendrawtransaction: %w", err)
+	}
+	return sub, nil
+}
+
+// submitTxPeers announces the transaction to the connected peers and
+// counts it accepted once a peer fetches it
+func (s *Server) submitTxPeers(ctx context.Context, raw []byte) (txSubmission, error) {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(raw)); err != nil {
+		return txSubmission{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, txP2PWait)
+	defer cancel()
+	res, err := s.txPeers.BroadcastTx(ctx, &tx)
+	sub := txSubmission{Peers: res.Peers, PeersRequested: res.Requested}
+	if err != nil {
+		return sub, err
+	}
+	if res.Requested == 0 {
+		return sub, fmt.Errorf("no peer fetched the transaction within %s", txP2PWait)
+	}
+	sub.TxID = res.TxID
+	return sub, nil
+}
+
+// esploraTxSubmitter posts the hex transaction to endpoint/tx, which
+// answers with the txid
+func esploraTxSubmitter(endpoint string) func(context.Context, []byte) (txSubmission, error) {
+	return func(ctx context.Context, raw []byte) (txSubmission, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/tx", strings.NewReader(hex.EncodeToString(raw)))
+		if err != nil {
+			return txSubmission{}, err
+		}
+		req.Header.Set("Content-Type", "text/plain")
+		resp, err := txHTTPClient.Do(req)
+		if err != nil {
+			return txSubmission{}, err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		text := strings.TrimSpace(string(body))
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			return txSubmission{TxID: text}, nil
+		case resp.StatusCode == http.StatusBadRequest:
+			return txSubmission{}, &txRejectedError{Message: text}
+		default:
+			return txSubmission{}, fmt.Errorf("status %d: %s", resp.StatusCode, text)
+		}
+	}
+}
+
+// rpcTxSubmitter calls a JSON-RPC send method on endpoint, which answers
+// with the transaction hash or signature
+func rpcTxSubmitter(endpoint, method string, params func([]byte) []interface{}) func(context.Context, []byte) (txSubmission, error) {
+	return func(ctx context.Context, raw []byte) (txSubmission, error) {
+		body, err := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      1,
+			"method":  method,
+			"params":  params(raw),
+		})
+		if err != nil {
+			return txSubmission{}, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return txSubmission{}, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := txHTTPClient.Do(req)
+		if err != nil {
+			return txSubmission{}, err
+		}
+		defer resp.Body.Close()
+
+		var rpcResp struct {
+			Result string `json:"result"`
+			Error  *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+			return txSubmission{}, fmt.Errorf("%s: status %d: %w", method, resp.StatusCode, err)
+		}
+		if rpcResp.Error != nil {
+			return txSubmission{}, &txRejectedError{Message: rpcResp.Error.Message}
+		}
+		return txSubmission{TxID: rpcResp.Result}, nil
+	}
+}
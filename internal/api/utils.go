@@ -32,11 +32,15 @@ type RandomReader interface {
 	Read(p []byte) (n int, err error)
 }
 
-// RealRandomReader implements RandomReader using crypto/rand
+// RealRandomReader implements RandomReader using the shared SecureBuffer
+// entropy pool, falling back to crypto/rand
 type RealRandomReader struct{}
 
 // Read reads random bytes
 func (RealRandomReader) Read(p []byte) (n int, err error) {
+	if n, err := securebuf.SharedEntropyPool().Read(p); err == nil {
+		return n, nil
+	}
 	return rand.Read(p)
 }
 
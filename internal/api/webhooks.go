@@ -0,0 +1,198 @@
+// Package api provides webhook registration endpoints
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/webhooks"
+	"go.uber.org/zap"
+)
+
+// ===== WEBHOOK DISPATCH =====
+
+// startWebhooks loads persisted subscriptions and feeds the block bus into
+// the webhook dispatcher until ctx is cancelled
+func (s *Server) startWebhooks(ctx context.Context) {
+	if !s.cfg.WebhooksEnabled || s.bus == nil {
+		s.logger.Info("Webhooks disabled")
+		return
+	}
+
+	deadLetters, err := webhooks.NewFileDeadLetterStore(filepath.Join(s.cfg.WebhookDir, "dead_letters.json"), 0)
+	if err != nil {
+		s.logger.Error("Failed to open webhook dead letters", zap.Error(err))
+		return
+	}
+	cfg := webhooks.DefaultConfig()
+	cfg.StatePath = filepath.Join(s.cfg.WebhookDir, "subscriptions.json")
+	cfg.MaxAttempts = s.cfg.WebhookMaxAttempts
+	cfg.RatePerDestination = float64(s.cfg.WebhookRatePerHost)
+	cfg.Signer = s.eventSigner
+
+	dispatcher, err := webhooks.New(cfg, deadLetters, s.logger)
+	if err != nil {
+		s.logger.Error("Failed to start webhook dispatcher", zap.Error(err))
+		return
+	}
+	dispatcher.Start()
+	s.webhooks = dispatcher
+
+	go s.consumeBlocks(ctx, "webhooks", dispatcher.Publish)
+	go func() {
+		<-ctx.Done()
+		dispatcher.Stop()
+	}()
+}
+
+// ===== WEBHOOK HANDLERS =====
+
+// webhookRequest is the body of POST /api/v1/webhooks
+type webhookRequest struct {
+	URL    string               `json:"url"`
+	Chain  string               `json:"chain"`
+	Events []webhooks.EventType `json:"events"`
+}
+
+// webhooksHandler serves /api/v1/webhooks and its sub-resources:
+//
+//	GET    /api/v1/webhooks?chain=                       list subscriptions
+//	POST   /api/v1/webhooks                              register a callback
+//	DELETE /api/v1/webhooks/{id}                         remove a callback
+//	GET    /api/v1/webhooks/dead-letters                 list failed deliveries
+//	POST   /api/v1/webhooks/dead-letters/{id}/replay     retry a failed delivery
+//	DELETE /api/v1/webhooks/dead-letters/{id}            discard a failed delivery
+func (s *Server) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	if s.webhooks == nil {
+		s.jsonResponse(w, http.StatusServiceUnavailable, map[string]string{
+			"error": "Webhooks are not enabled",
+		})
+		return
+	}
+	owner, ok := s.requestKeyHash(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/webhooks"), "/")
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		p, ok := s.listRequest(w, r, s.getCustomerTierFromContext(r), "chain")
+		if !ok {
+			return
+		}
+		subs, next, ok := filteredPage(s, w, s.webhooks.List(owner), p)
+		if !ok {
+			return
+		}
+		writeList(s, w, "webhooks", subs, p, next, nil)
+	case rest == "" && r.Method == http.MethodPost:
+		s.registerWebhook(owner, w, r)
+	case parts[0] == "dead-letters":
+		s.webhookDeadLetters(owner, parts[1:], w, r)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.webhookResult(w, s.webhooks.Delete(owner, parts[0]), http.StatusNoContent)
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": "Method not allowed",
+		})
+	}
+}
+
+func (s *Server) registerWebhook(owner string, w http.ResponseWriter, r *http.Request) {
+	var req webhookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&req); err != nil {
+		s.jsonResponse(w, http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+		return
+	}
+
+	chain := normalizeChainName(req.Chain)
+	if chain == "" {
+		chain = normalizeChainName(s.cfg.DefaultChain)
+	}
+	sub, err := s.webhooks.Register(owner, req.URL, chain, req.Events)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, webhooks.ErrTooManyWebhooks) {
+			status = http.StatusConflict
+		}
+		s.jsonResponse(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	s.logger.Info("Webhook registered",
+		zap.String("id", sub.ID),
+		zap.String("chain", sub.Chain),
+		zap.String("key_hash", owner[:8]))
+	s.jsonResponse(w, http.StatusCreated, sub)
+}
+
+func (s *Server) webhookDeadLetters(owner string, parts []string, w http.ResponseWriter, r *http.Request) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		p, ok := s.listRequest(w, r, s.getCustomerTierFromContext(r), "subscription_id")
+		if !ok {
+			return
+		}
+		letters, err := s.webhooks.DeadLetters().List(owner)
+		if err != nil {
+			s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		page, next, ok := filteredPage(s, w, letters, p)
+		if !ok {
+			return
+		}
+		writeList(s, w, "dead_letters", page, p, next, nil)
+	case len(parts) == 2 && parts[1] == "replay" && r.Method == http.MethodPost:
+		s.webhookResult(w, s.webhooks.Replay(owner, parts[0]), http.StatusAccepted)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		dl, ok := s.webhooks.DeadLetters().Get(parts[0])
+		if !ok || dl.Owner != owner {
+			s.webhookResult(w, webhooks.ErrNotFound, 0)
+			return
+		}
+		s.webhookResult(w, s.webhooks.DeadLetters().Delete(parts[0]), http.StatusNoContent)
+	default:
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{
+			"error": "Method not allowed",
+		})
+	}
+}
+
+// webhookResult maps a dispatcher error to a response
+func (s *Server) webhookResult(w http.ResponseWriter, err error, okStatus int) {
+	switch {
+	case err == nil:
+		w.WriteHeader(okStatus)
+	case errors.Is(err, webhooks.ErrNotFound):
+		s.jsonResponse(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	default:
+		s.logger.Error("Webhook operation failed", zap.Error(err))
+		s.jsonResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
+
+// requestKeyHash returns the hash of the caller's valid API key
+func (s *Server) requestKeyHash(r *http.Request) (string, bool) {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get("api_key")
+	}
+	if apiKey == "" {
+		return "", false
+	}
+	key, ok := s.keyManager.ValidateKey(apiKey)
+	if !ok {
+		return "", false
+	}
+	return key.Hash, true
+}
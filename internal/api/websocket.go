@@ -2,7 +2,54 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	wsActiveStreams = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "websocket_active_streams",
+			Help: "Active WebSocket streams by chain and tier",
+		},
+		[]string{"chain", "tier"},
+	)
+
+	wsStreamRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "websocket_stream_rejections_total",
+			Help: "WebSocket streams rejected by limit type",
+		},
+		[]string{"reason", "tier"},
+	)
+
+	wsStreamsReaped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "websocket_streams_reaped_total",
+			Help: "WebSocket streams closed for inactivity",
+		},
+		[]string{"chain", "tier"},
+	)
+)
+
+// Stream limit errors returned by AcquireStream
+var (
+	ErrStreamGlobalLimit = errors.New("global WebSocket connection limit reached")
+	ErrStreamIPLimit     = errors.New("per-IP WebSocket connection limit reached")
+	ErrStreamChainLimit  = errors.New("per-chain WebSocket connection limit reached")
+	ErrStreamKeyQuota    = errors.New("concurrent stream quota for this API key reached")
 )
 
 // ===== WEBSOCKET LIMITER IMPLEMENTATION =====
@@ -15,6 +62,12 @@ type WebSocketLimiter struct {
 	maxPerIP    int
 	maxPerChain int
 	mu          sync.RWMutex
+
+	// Per-key stream quotas and lease tracking
+	streamMu  sync.Mutex
+	perKey    map[string]int
+	leases    map[uint64]*StreamLease
+	nextLease uint64
 }
 
 // NewWebSocketLimiter creates a new WebSocket connection limiter
@@ -25,6 +78,8 @@ func NewWebSocketLimiter(maxGlobal, maxPerIP, maxPerChain int) *WebSocketLimiter
 		perChainSem: make(map[string]chan struct{}),
 		maxPerIP:    maxPerIP,
 		maxPerChain: maxPerChain,
+		perKey:      make(map[string]int),
+		leases:      make(map[uint64]*StreamLease),
 	}
 }
 
@@ -152,3 +207,392 @@ func (wsl *WebSocketLimiter) ReleaseForChain(clientIP, chain string) {
 		// No slot to release
 	}
 }
+
+// ===== STREAM QUOTAS =====
+
+// StreamClient identifies who is opening a stream
+type StreamClient struct {
+	IP      string
+	KeyID   string // API key hash, or "ip:<addr>" for anonymous clients
+	Tier    config.Tier
+	Chain   string
+	MaxKeys int // Concurrent streams allowed for KeyID; 0 means unlimited
+}
+
+// StreamLease is a held stream slot. Handlers Touch it on activity and
+// Release it when the stream ends; the reaper cancels idle leases.
+type StreamLease struct {
+	id         uint64
+	client     StreamClient
+	startedAt  time.Time
+	lastActive atomic.Int64
+	reaped     atomic.Bool
+	released   atomic.Bool
+	limiter    *WebSocketLimiter
+
+	// Set by upgradeStream: whether permessage-deflate is in use, and the
+	// counter for message bytes before compression
+	compressed bool
+	payload    prometheus.Counter
+	proto      bool // Blocks are sent as protobuf binary frames
+
+	// Egress: bytes written to the connection, those not yet charged to
+	// the key's monthly total, and the func that charges them (set by
+	// upgradeStream)
+	sent      atomic.Int64
+	unbilled  atomic.Int64
+	overQuota atomic.Bool
+	charge    func(n int64)
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// Bind attaches the cancel func invoked if the lease is reaped
+func (l *StreamLease) Bind(cancel context.CancelFunc) {
+	l.mu.Lock()
+	l.cancel = cancel
+	l.mu.Unlock()
+}
+
+// Touch records stream activity
+func (l *StreamLease) Touch() {
+	l.lastActive.Store(time.Now().UnixNano())
+}
+
+// Reaped reports whether the lease was closed for inactivity
+func (l *StreamLease) Reaped() bool {
+	return l.reaped.Load()
+}
+
+// Release frees all slots held by the lease; it is safe to call twice
+func (l *StreamLease) Release() {
+	if !l.released.CompareAndSwap(false, true) {
+		return
+	}
+	if n := l.unbilled.Swap(0); n > 0 && l.charge != nil {
+		l.charge(n)
+	}
+	wsl := l.limiter
+	wsl.streamMu.Lock()
+	delete(wsl.leases, l.id)
+	if n := wsl.perKey[l.client.KeyID] - 1; n > 0 {
+		wsl.perKey[l.client.KeyID] = n
+	} else {
+		delete(wsl.perKey, l.client.KeyID)
+	}
+	wsl.streamMu.Unlock()
+
+	wsl.ReleaseForChain(l.client.IP, l.client.Chain)
+	wsActiveStreams.WithLabelValues(l.client.Chain, string(l.client.Tier)).Dec()
+}
+
+func (l *StreamLease) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, l.lastActive.Load()))
+}
+
+// AcquireStream reserves global, per-IP, per-chain and per-key slots
+func (wsl *WebSocketLimiter) AcquireStream(client StreamClient) (*StreamLease, error) {
+	tier := string(client.Tier)
+
+	wsl.streamMu.Lock()
+	if client.MaxKeys > 0 && wsl.perKey[client.KeyID] >= client.MaxKeys {
+		wsl.streamMu.Unlock()
+		wsStreamRejections.WithLabelValues("key_quota", tier).Inc()
+		return nil, ErrStreamKeyQuota
+	}
+	wsl.perKey[client.KeyID]++
+	wsl.nextLease++
+	lease := &StreamLease{
+		id:        wsl.nextLease,
+		client:    client,
+		startedAt: time.Now(),
+		limiter:   wsl,
+	}
+	lease.Touch()
+	wsl.leases[lease.id] = lease
+	wsl.streamMu.Unlock()
+
+	if err := wsl.acquireSlots(client.IP, client.Chain, tier); err != nil {
+		wsl.streamMu.Lock()
+		delete(wsl.leases, lease.id)
+		if n := wsl.perKey[client.KeyID] - 1; n > 0 {
+			wsl.perKey[client.KeyID] = n
+		} else {
+			delete(wsl.perKey, client.KeyID)
+		}
+		wsl.streamMu.Unlock()
+		lease.released.Store(true)
+		return nil, err
+	}
+
+	wsActiveStreams.WithLabelValues(client.Chain, tier).Inc()
+	return lease, nil
+}
+
+// acquireSlots is AcquireForChain with the failing limit reported
+func (wsl *WebSocketLimiter) acquireSlots(clientIP, chain, tier string) error {
+	if wsl.AcquireForChain(clientIP, chain) {
+		return nil
+	}
+	// Work out which limit tripped for metrics and the client error
+	switch {
+	case len(wsl.globalSem) >= cap(wsl.globalSem):
+		wsStreamRejections.WithLabelValues("global", tier).Inc()
+		return ErrStreamGlobalLimit
+	case wsl.semFull(wsl.perIPSem, clientIP):
+		wsStreamRejections.WithLabelValues("ip", tier).Inc()
+		return ErrStreamIPLimit
+	default:
+		wsStreamRejections.WithLabelValues("chain", tier).Inc()
+		return ErrStreamChainLimit
+	}
+}
+
+func (wsl *WebSocketLimiter) semFull(sems map[string]chan struct{}, key string) bool {
+	wsl.mu.RLock()
+	defer wsl.mu.RUnlock()
+	sem := sems[key]
+	return sem != nil && len(sem) >= cap(sem)
+}
+
+// ReapIdle cancels leases with no activity for idleTimeout and returns how many were reaped
+func (wsl *WebSocketLimiter) ReapIdle(idleTimeout time.Duration) int {
+	if idleTimeout <= 0 {
+		return 0
+	}
+	now := time.Now()
+	var idle []*StreamLease
+	wsl.streamMu.Lock()
+	for _, l := range wsl.leases {
+		if !l.reaped.Load() && l.idleFor(now) > idleTimeout {
+			idle = append(idle, l)
+		}
+	}
+	wsl.streamMu.Unlock()
+
+	for _, l := range idle {
+		l.reaped.Store(true)
+		l.cancelStream()
+		wsStreamsReaped.WithLabelValues(l.client.Chain, string(l.client.Tier)).Inc()
+	}
+	return len(idle)
+}
+
+// CloseAll cancels every active stream and returns how many were open.
+// Handlers send the close frame; leases are released as they exit.
+func (wsl *WebSocketLimiter) CloseAll() int {
+	wsl.streamMu.Lock()
+	open := make([]*StreamLease, 0, len(wsl.leases))
+	for _, l := range wsl.leases {
+		open = append(open, l)
+	}
+	wsl.streamMu.Unlock()
+
+	for _, l := range open {
+		l.cancelStream()
+	}
+	return len(open)
+}
+
+// Active returns the number of streams holding a lease
+func (wsl *WebSocketLimiter) Active() int {
+	wsl.streamMu.Lock()
+	defer wsl.streamMu.Unlock()
+	return len(wsl.leases)
+}
+
+func (l *StreamLease) cancelStream() {
+	l.mu.Lock()
+	cancel := l.cancel
+	l.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// StartReaper periodically reaps idle streams until ctx is done
+func (wsl *WebSocketLimiter) StartReaper(ctx context.Context, idleTimeout time.Duration, logger *zap.Logger) {
+	if idleTimeout <= 0 {
+		return
+	}
+	interval := idleTimeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n := wsl.ReapIdle(idleTimeout); n > 0 {
+					logger.Info("Reaped idle WebSocket streams",
+						zap.Int("count", n),
+						zap.Duration("idle_timeout", idleTimeout))
+				}
+			}
+		}
+	}()
+}
+
+// StreamInfo describes an active stream for operators
+type StreamInfo struct {
+	KeyID       string    `json:"key_id"`
+	Tier        string    `json:"tier"`
+	Chain       string    `json:"chain"`
+	IP          string    `json:"ip"`
+	StartedAt   time.Time `json:"started_at"`
+	IdleSeconds float64   `json:"idle_seconds"`
+	Compressed  bool      `json:"compressed"`
+	BytesSent   int64     `json:"bytes_sent"`
+}
+
+// Streams returns all active streams, longest-running first
+func (wsl *WebSocketLimiter) Streams() []StreamInfo {
+	now := time.Now()
+	wsl.streamMu.Lock()
+	out := make([]StreamInfo, 0, len(wsl.leases))
+	for _, l := range wsl.leases {
+		out = append(out, StreamInfo{
+			KeyID:       l.client.KeyID,
+			Tier:        string(l.client.Tier),
+			Chain:       l.client.Chain,
+			IP:          l.client.IP,
+			StartedAt:   l.startedAt,
+			IdleSeconds: l.idleFor(now).Seconds(),
+			Compressed:  l.compressed,
+			BytesSent:   l.sent.Load(),
+		})
+	}
+	wsl.streamMu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].StartedAt.Before(out[j].StartedAt) })
+	return out
+}
+
+// getTierStreamQuota returns the concurrent stream quota per key for a tier (0 = unlimited)
+func (s *Server) getTierStreamQuota(tier config.Tier) int {
+	if limit, ok := s.cfg.RateLimits[tier]; ok {
+		return limit.ConcurrentStreams
+	}
+	switch tier {
+	case config.TierEnterprise:
+		return 0
+	case config.TierTurbo:
+		return 50
+	case config.TierBusiness:
+		return 20
+	case config.TierPro:
+		return 5
+	default:
+		return 1
+	}
+}
+
+// acquireStream resolves the caller's key and tier and reserves a stream
+// slot, writing a 429 and returning false when any limit is hit.
+func (s *Server) acquireStream(w http.ResponseWriter, r *http.Request, chain string) (*StreamLease, bool) {
+	client := StreamClient{
+		IP:    getClientIP(r),
+		Tier:  config.TierFree,
+		Chain: normalizeChainName(chain),
+	}
+	client.KeyID = "ip:" + client.IP
+
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get("api_key")
+	}
+	if apiKey != "" {
+		if key, valid := s.keyManager.ValidateKey(apiKey); valid {
+			client.KeyID = key.Hash
+			client.Tier = key.Tier
+		}
+	}
+	client.MaxKeys = s.getTierStreamQuota(client.Tier)
+	if !s.egressAllowed(w, client) {
+		return nil, false
+	}
+
+	lease, err := s.wsLimiter.AcquireStream(client)
+	if err != nil {
+		s.logger.Debug("WebSocket stream rejected",
+			zap.String("ip", client.IP),
+			zap.String("tier", string(client.Tier)),
+			zap.String("chain", client.Chain),
+			zap.Error(err))
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return nil, false
+	}
+	return lease, true
+}
+
+// closeStream tells a client why the server ended its stream: idle reaping,
+// shutdown, or a reload it should reconnect after
+func (s *Server) closeStream(conn *websocket.Conn, lease *StreamLease) {
+	var code int
+	var reason string
+	switch {
+	case lease.Reaped():
+		code, reason = websocket.CloseGoingAway, "idle stream reaped"
+	case lease.overQuota.Load():
+		code, reason = websocket.ClosePolicyViolation, "monthly egress quota exhausted"
+	case s.reloading.Load():
+		code, reason = websocket.CloseServiceRestart, "server restarting"
+	case s.draining.Load():
+		code, reason = websocket.CloseGoingAway, "server shutting down"
+	default:
+		return
+	}
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, s.clock.Now().Add(time.Second))
+}
+
+// streamsAdminHandler lists active streams and per-key usage
+func (s *Server) streamsAdminHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.jsonResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	streams := s.wsLimiter.Streams()
+	byTier := make(map[string]int)
+	byKey := make(map[string]int)
+	keyBytes := make(map[string]int64)
+	for i := range streams {
+		byTier[streams[i].Tier]++
+		byKey[streams[i].KeyID]++
+		keyBytes[streams[i].KeyID] += streams[i].BytesSent
+		// Only expose a key hash prefix
+		if len(streams[i].KeyID) > 8 && streams[i].KeyID[:3] != "ip:" {
+			streams[i].KeyID = streams[i].KeyID[:8]
+		}
+	}
+	topKeys := make([]map[string]interface{}, 0, len(byKey))
+	for k, n := range byKey {
+		sent := keyBytes[k]
+		if len(k) > 8 && k[:3] != "ip:" {
+			k = k[:8]
+		}
+		topKeys = append(topKeys, map[string]interface{}{"key_id": k, "streams": n, "bytes_sent": sent})
+	}
+	sort.Slice(topKeys, func(i, j int) bool { return topKeys[i]["streams"].(int) > topKeys[j]["streams"].(int) })
+
+	// Process memory, so a stream soak can estimate the cost per stream
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	s.jsonResponse(w, http.StatusOK, map[string]interface{}{
+		"active":       len(streams),
+		"by_tier":      byTier,
+		"by_key":       topKeys,
+		"streams":      streams,
+		"idle_timeout": s.cfg.IdleTimeout.String(),
+		"memory": map[string]interface{}{
+			"heap_inuse_bytes": mem.HeapInuse,
+			"sys_bytes":        mem.Sys,
+			"goroutines":       runtime.NumGoroutine(),
+		},
+	})
+}
@@ -0,0 +1,132 @@
+// Package api provides negotiated permessage-deflate for block streams
+package api
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ===== STREAM COMPRESSION =====
+
+// Busy feeds such as Solana slots cost clients a lot of bandwidth, and
+// block JSON compresses well. Compression is offered to tiers listed in
+// WSCompressionTiers by default; latency-sensitive tiers leave it off and
+// opt in with ?compression=on, and any client may opt out with
+// ?compression=off. Either way it only applies if the client offers
+// permessage-deflate.
+
+var (
+	wsPayloadBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_stream_payload_bytes_total",
+		Help: "Message bytes written to block streams before compression",
+	}, []string{"chain", "compressed"})
+
+	wsWireBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_stream_wire_bytes_total",
+		Help: "Bytes written to block stream connections, including framing",
+	}, []string{"chain", "compressed"})
+)
+
+// streamCompression reports whether a stream for tier should be compressed
+func (s *Server) streamCompression(r *http.Request, tier config.Tier) bool {
+	switch strings.ToLower(r.URL.Query().Get("compression")) {
+	case "on", "true", "1":
+		return true
+	case "off", "false", "0":
+		return false
+	}
+	for _, t := range s.cfg.WSCompressionTiers {
+		if config.Tier(strings.ToLower(t)) == tier {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeStream upgrades a block stream, negotiating permessage-deflate
+// when the tier policy and the client both want it and the block
+// encoding, and meters the bytes it sends, charging them to the lease's
+// key as egress
+func (s *Server) upgradeStream(w http.ResponseWriter, r *http.Request, upgrader *websocket.Upgrader, lease *StreamLease) (*websocket.Conn, error) {
+	compress := s.streamCompression(r, lease.client.Tier) && offersDeflate(r)
+	upgrader.EnableCompression = compress
+
+	chain := normalizeChainName(lease.client.Chain)
+	label := strconv.FormatBool(compress)
+	lease.charge = s.egressCharger(lease)
+	mw := &meteredHijacker{
+		ResponseWriter: w,
+		wire:           wsWireBytes.WithLabelValues(chain, label),
+		onWrite:        func(n int) { s.recordEgress(lease, n) },
+	}
+	offerStreamSubprotocols(r, upgrader)
+	conn, err := upgrader.Upgrade(mw, r, nil)
+	if err != nil {
+		return nil, err
+	}
+	lease.proto = streamsProto(r, conn)
+	if compress {
+		conn.EnableWriteCompression(true)
+		if err := conn.SetCompressionLevel(s.cfg.WSCompressionLevel); err != nil {
+			s.logger.Debug("Invalid WebSocket compression level, using default")
+		}
+	}
+	lease.compressed = compress
+	lease.payload = wsPayloadBytes.WithLabelValues(chain, label)
+	return conn, nil
+}
+
+// offersDeflate reports whether the client offered permessage-deflate
+func offersDeflate(r *http.Request) bool {
+	for _, ext := range r.Header.Values("Sec-WebSocket-Extensions") {
+		if strings.Contains(ext, "permessage-deflate") {
+			return true
+		}
+	}
+	return false
+}
+
+// meteredHijacker counts what is written to the hijacked connection, which
+// for a compressed stream is the compressed size
+type meteredHijacker struct {
+	http.ResponseWriter
+	wire    prometheus.Counter
+	onWrite func(n int)
+}
+
+// Hijack implements http.Hijacker
+func (m *meteredHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := m.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &meteredConn{Conn: conn, wire: m.wire, onWrite: m.onWrite}, brw, nil
+}
+
+type meteredConn struct {
+	net.Conn
+	wire    prometheus.Counter
+	onWrite func(n int)
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.wire.Add(float64(n))
+	if c.onWrite != nil && n > 0 {
+		c.onWrite(n)
+	}
+	return n, err
+}
@@ -0,0 +1,466 @@
+// Package blockbus provides an in-process fan-out bus for hot block events.
+//
+// Producers publish BlockEvents once; every subscriber owns a bounded ring
+// buffer so a slow consumer never blocks the producer or its peers. When a
+// subscriber's ring is full the oldest event is overwritten and counted as a
+// drop. New subscribers can ask for the last N published events to be
+// replayed before live delivery starts.
+package blockbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// ErrClosed is returned by Subscription.Next once the subscription or bus is closed.
+var ErrClosed = errors.New("blockbus: subscription closed")
+
+var (
+	busPublished = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "blockbus_published_total",
+			Help: "Block events published to the block bus",
+		},
+		[]string{"chain"},
+	)
+
+	busDelivered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "blockbus_delivered_total",
+			Help: "Block events consumed by block bus subscribers",
+		},
+		[]string{"subscriber"},
+	)
+
+	busDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "blockbus_dropped_total",
+			Help: "Block events overwritten in a subscriber ring before being consumed",
+		},
+		[]string{"subscriber"},
+	)
+
+	busSubscribers = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "blockbus_subscribers",
+			Help: "Active block bus subscribers",
+		},
+		[]string{"subscriber"},
+	)
+)
+
+// Config controls ring sizes for the bus
+type Config struct {
+	// ReplaySize is the number of most recent events retained for replay
+	ReplaySize int
+	// BufferSize is the default per-subscriber ring capacity
+	BufferSize int
+}
+
+// DefaultConfig returns the default bus configuration
+func DefaultConfig() Config {
+	return Config{
+		ReplaySize: 64,
+		BufferSize: 256,
+	}
+}
+
+// Bus fans out block events to independent subscriber rings
+type Bus struct {
+	cfg    Config
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	subs   map[uint64]*Subscription
+	nextID uint64
+	closed bool
+
+	// replay ring of the most recent events
+	history []blocks.BlockEvent
+	histPos int
+	histLen int
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// New creates a new block bus
+func New(cfg Config, logger *zap.Logger) *Bus {
+	if cfg.ReplaySize <= 0 {
+		cfg.ReplaySize = DefaultConfig().ReplaySize
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = DefaultConfig().BufferSize
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Bus{
+		cfg:     cfg,
+		logger:  logger,
+		subs:    make(map[uint64]*Subscription),
+		history: make([]blocks.BlockEvent, cfg.ReplaySize),
+	}
+}
+
+// Publish delivers an event to every subscriber without blocking
+func (b *Bus) Publish(event blocks.BlockEvent) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.history[b.histPos] = event
+	b.histPos = (b.histPos + 1) % len(b.history)
+	if b.histLen < len(b.history) {
+		b.histLen++
+	}
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	b.mu.Unlock()
+
+	b.published.Add(1)
+	busPublished.WithLabelValues(string(event.Chain)).Inc()
+
+	for _, sub := range subs {
+		if sub.push(event) {
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// PublishPooled publishes *event and releases it to the event pool. The
+// bus keeps copies, so it is the last holder of a pooled event; event must
+// not be used after the call.
+func (b *Bus) PublishPooled(event *blocks.BlockEvent) {
+	b.Publish(*event)
+	event.Release()
+}
+
+// Latest returns the most recently published event
+func (b *Bus) Latest() (blocks.BlockEvent, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.histLen == 0 {
+		return blocks.BlockEvent{}, false
+	}
+	idx := (b.histPos - 1 + len(b.history)) % len(b.history)
+	return b.history[idx], true
+}
+
+// Recent returns up to n of the most recent events, oldest first
+func (b *Bus) Recent(n int) []blocks.BlockEvent {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.recentLocked(n)
+}
+
+func (b *Bus) recentLocked(n int) []blocks.BlockEvent {
+	if n > b.histLen {
+		n = b.histLen
+	}
+	if n <= 0 {
+		return nil
+	}
+	out := make([]blocks.BlockEvent, 0, n)
+	start := (b.histPos - n + len(b.history)) % len(b.history)
+	for i := 0; i < n; i++ {
+		out = append(out, b.history[(start+i)%len(b.history)])
+	}
+	return out
+}
+
+// SubscribeOptions configures a new subscription
+type SubscribeOptions struct {
+	// Name groups subscribers for metrics (e.g. "ws_stream", "cache")
+	Name string
+	// BufferSize overrides the bus default ring capacity
+	BufferSize int
+	// Replay is the number of recent events delivered before live events
+	Replay int
+	// ReplayFilter, when set, replays every retained event it accepts
+	// instead of the last Replay events
+	ReplayFilter func(blocks.BlockEvent) bool
+}
+
+// Subscribe registers a new subscriber and seeds it with replayed events
+func (b *Bus) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Name == "" {
+		opts.Name = "default"
+	}
+	size := opts.BufferSize
+	if size <= 0 {
+		size = b.cfg.BufferSize
+	}
+
+	sub := &Subscription{
+		bus:    b,
+		name:   opts.Name,
+		ring:   make([]blocks.BlockEvent, size),
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		since:  time.Now(),
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		sub.close()
+		return sub
+	}
+	b.nextID++
+	sub.id = b.nextID
+	// Seed under the bus lock so no live event can interleave with the replay
+	replay := b.recentLocked(opts.Replay)
+	if opts.ReplayFilter != nil {
+		replay = replay[:0]
+		for _, ev := range b.recentLocked(b.histLen) {
+			if opts.ReplayFilter(ev) {
+				replay = append(replay, ev)
+			}
+		}
+	}
+	if opts.ReplayFilter != nil && len(replay) > len(sub.ring) {
+		// A filtered catch-up must not lose history to the ring size; keep
+		// the usual headroom for live events on top
+		sub.ring = make([]blocks.BlockEvent, len(replay)+size)
+	}
+	for _, ev := range replay {
+		sub.push(ev)
+	}
+	sub.replayPending = sub.count
+	b.subs[sub.id] = sub
+	b.mu.Unlock()
+
+	busSubscribers.WithLabelValues(sub.name).Inc()
+	b.logger.Debug("Block bus subscriber added",
+		zap.String("subscriber", sub.name),
+		zap.Uint64("id", sub.id),
+		zap.Int("buffer", size),
+		zap.Int("replay", len(replay)),
+	)
+	return sub
+}
+
+// Unsubscribe removes a subscriber and wakes any blocked reader
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	if sub == nil {
+		return
+	}
+	b.mu.Lock()
+	_, ok := b.subs[sub.id]
+	delete(b.subs, sub.id)
+	b.mu.Unlock()
+
+	if ok {
+		busSubscribers.WithLabelValues(sub.name).Dec()
+	}
+	sub.close()
+}
+
+// Close closes the bus and all subscriptions
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	subs := b.subs
+	b.subs = make(map[uint64]*Subscription)
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		busSubscribers.WithLabelValues(sub.name).Dec()
+		sub.close()
+	}
+}
+
+// Stats describes the current bus state
+type Stats struct {
+	Published   uint64            `json:"published"`
+	Dropped     uint64            `json:"dropped"`
+	Subscribers int               `json:"subscribers"`
+	Retained    int               `json:"retained"`
+	Consumers   []SubscriberStats `json:"consumers"`
+}
+
+// SubscriberStats describes a single subscriber
+type SubscriberStats struct {
+	ID        uint64    `json:"id"`
+	Name      string    `json:"name"`
+	Pending   int       `json:"pending"`
+	Capacity  int       `json:"capacity"`
+	Delivered uint64    `json:"delivered"`
+	Dropped   uint64    `json:"dropped"`
+	Since     time.Time `json:"since"`
+}
+
+// Stats returns a snapshot of bus and subscriber counters
+func (b *Bus) Stats() Stats {
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, sub := range b.subs {
+		subs = append(subs, sub)
+	}
+	stats := Stats{
+		Published:   b.published.Load(),
+		Dropped:     b.dropped.Load(),
+		Subscribers: len(b.subs),
+		Retained:    b.histLen,
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		stats.Consumers = append(stats.Consumers, sub.Stats())
+	}
+	return stats
+}
+
+// ===== SUBSCRIPTION IMPLEMENTATION =====
+
+// Subscription is a single consumer's view of the bus
+type Subscription struct {
+	bus  *Bus
+	id   uint64
+	name string
+
+	mu    sync.Mutex
+	ring  []blocks.BlockEvent
+	head  int
+	count int
+
+	// replayPending counts replayed events still at the front of the ring
+	replayPending int
+
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	since     time.Time
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// push appends an event and reports whether an older event was overwritten
+func (s *Subscription) push(event blocks.BlockEvent) bool {
+	s.mu.Lock()
+	overwritten := false
+	if s.count == len(s.ring) {
+		// Ring full: drop the oldest event to make room
+		s.head = (s.head + 1) % len(s.ring)
+		s.count--
+		overwritten = true
+		if s.replayPending > 0 {
+			s.replayPending--
+		}
+	}
+	s.ring[(s.head+s.count)%len(s.ring)] = event
+	s.count++
+	s.mu.Unlock()
+
+	if overwritten {
+		s.dropped.Add(1)
+		busDropped.WithLabelValues(s.name).Inc()
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return overwritten
+}
+
+// TryNext returns the next pending event without blocking
+func (s *Subscription) TryNext() (blocks.BlockEvent, bool) {
+	ev, _, ok := s.tryNext()
+	return ev, ok
+}
+
+// tryNext pops the next event and reports whether it came from the replay
+func (s *Subscription) tryNext() (blocks.BlockEvent, bool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count == 0 {
+		return blocks.BlockEvent{}, false, false
+	}
+	ev := s.ring[s.head]
+	s.ring[s.head] = blocks.BlockEvent{}
+	s.head = (s.head + 1) % len(s.ring)
+	s.count--
+	replayed := s.replayPending > 0
+	if replayed {
+		s.replayPending--
+	}
+	s.delivered.Add(1)
+	busDelivered.WithLabelValues(s.name).Inc()
+	return ev, replayed, true
+}
+
+// Next blocks until an event is available, the context ends or the subscription closes
+func (s *Subscription) Next(ctx context.Context) (blocks.BlockEvent, error) {
+	ev, _, err := s.Receive(ctx)
+	return ev, err
+}
+
+// Receive is Next that also reports whether the event was replayed
+// history rather than a live publish
+func (s *Subscription) Receive(ctx context.Context) (blocks.BlockEvent, bool, error) {
+	for {
+		if ev, replayed, ok := s.tryNext(); ok {
+			return ev, replayed, nil
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			// Drain anything pushed before close
+			if ev, replayed, ok := s.tryNext(); ok {
+				return ev, replayed, nil
+			}
+			return blocks.BlockEvent{}, false, ErrClosed
+		case <-ctx.Done():
+			return blocks.BlockEvent{}, false, ctx.Err()
+		}
+	}
+}
+
+// ReplayPending returns how many replayed events have not been read yet
+func (s *Subscription) ReplayPending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replayPending
+}
+
+// Dropped returns the number of events this subscriber lost to overwrites
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Stats returns a snapshot of the subscriber counters
+func (s *Subscription) Stats() SubscriberStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SubscriberStats{
+		ID:        s.id,
+		Name:      s.name,
+		Pending:   s.count,
+		Capacity:  len(s.ring),
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Since:     s.since,
+	}
+}
+
+func (s *Subscription) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
@@ -0,0 +1,139 @@
+package blockbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+)
+
+func TestReplayAndDropAccounting(t *testing.T) {
+	bus := New(Config{ReplaySize: 4, BufferSize: 2}, nil)
+	defer bus.Close()
+
+	for h := uint32(1); h <= 6; h++ {
+		bus.Publish(blocks.BlockEvent{Height: h, Chain: blocks.ChainBitcoin})
+	}
+
+	// Only the last two of the three replayed events fit in the ring
+	sub := bus.Subscribe(SubscribeOptions{Name: "test", Replay: 3})
+	if got := sub.Dropped(); got != 1 {
+		t.Fatalf("dropped = %d, want 1", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, want := range []uint32{5, 6} {
+		ev, err := sub.Next(ctx)
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if ev.Height != want {
+			t.Fatalf("height = %d, want %d", ev.Height, want)
+		}
+	}
+
+	bus.Publish(blocks.BlockEvent{Height: 7})
+	if ev, err := sub.Next(ctx); err != nil || ev.Height != 7 {
+		t.Fatalf("live event = %v, %v", ev.Height, err)
+	}
+
+	if latest, ok := bus.Latest(); !ok || latest.Height != 7 {
+		t.Fatalf("latest = %v, %v", latest.Height, ok)
+	}
+
+	bus.Unsubscribe(sub)
+	if _, err := sub.Next(ctx); err != ErrClosed {
+		t.Fatalf("Next after unsubscribe = %v, want ErrClosed", err)
+	}
+}
+
+func TestFilteredReplayMarksReplayedEvents(t *testing.T) {
+	bus := New(Config{ReplaySize: 8, BufferSize: 2}, nil)
+	defer bus.Close()
+
+	for h := uint32(1); h <= 6; h++ {
+		bus.Publish(blocks.BlockEvent{Height: h, Chain: blocks.ChainBitcoin})
+	}
+
+	// Four events match; the ring grows so none are dropped
+	sub := bus.Subscribe(SubscribeOptions{
+		Name:         "catchup",
+		ReplayFilter: func(ev blocks.BlockEvent) bool { return ev.Height >= 3 },
+	})
+	if got := sub.ReplayPending(); got != 4 {
+		t.Fatalf("replay pending = %d, want 4", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for want := uint32(3); want <= 6; want++ {
+		ev, replayed, err := sub.Receive(ctx)
+		if err != nil || ev.Height != want || !replayed {
+			t.Fatalf("replayed event = %d, %v, %v; want %d", ev.Height, replayed, err, want)
+		}
+	}
+
+	bus.Publish(blocks.BlockEvent{Height: 7})
+	if ev, replayed, err := sub.Receive(ctx); err != nil || ev.Height != 7 || replayed {
+		t.Fatalf("live event = %d, replayed=%v, %v", ev.Height, replayed, err)
+	}
+}
+
+func TestPublishPooledReleasesEvent(t *testing.T) {
+	bus := New(Config{ReplaySize: 4, BufferSize: 4}, nil)
+	defer bus.Close()
+
+	ev := blocks.AcquireEvent()
+	ev.Hash, ev.Height, ev.Chain = "slot:9", 9, blocks.ChainSolana
+	bus.PublishPooled(ev)
+
+	if latest, ok := bus.Latest(); !ok || latest.Hash != "slot:9" || latest.Height != 9 {
+		t.Fatalf("latest = %+v, %v", latest, ok)
+	}
+	if ev.Hash != "" || ev.Height != 0 {
+		t.Fatalf("released event not zeroed: %+v", ev)
+	}
+}
+
+// benchmarkChannelPublish feeds events to the bus the way the Solana relay
+// does: a producer hands them by pointer across a 2000-deep channel
+func benchmarkChannelPublish(b *testing.B, pooled bool) {
+	bus := New(Config{BufferSize: 2000}, nil)
+	defer bus.Close()
+	sub := bus.Subscribe(SubscribeOptions{Name: "bench"})
+	defer bus.Unsubscribe(sub)
+
+	ch := make(chan *blocks.BlockEvent, 2000)
+	now := time.Now()
+	b.ReportAllocs()
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			var ev *blocks.BlockEvent
+			if pooled {
+				ev = blocks.AcquireEvent()
+			} else {
+				ev = new(blocks.BlockEvent)
+			}
+			ev.Hash = "slot"
+			ev.Height = uint32(i)
+			ev.Timestamp, ev.DetectedAt = now, now
+			ev.Source, ev.Tier, ev.Chain = "solana-relay", "enterprise", blocks.ChainSolana
+			ch <- ev
+		}
+		close(ch)
+	}()
+	for ev := range ch {
+		if pooled {
+			bus.PublishPooled(ev)
+		} else {
+			bus.Publish(*ev)
+		}
+		sub.TryNext()
+	}
+}
+
+func BenchmarkChannelPublishFresh(b *testing.B)  { benchmarkChannelPublish(b, false) }
+func BenchmarkChannelPublishPooled(b *testing.B) { benchmarkChannelPublish(b, true) }
@@ -0,0 +1,306 @@
+// Package blockindex keeps a local, pruned history of block events per
+// chain so height-range and hash lookups can be served without asking
+// upstream providers.
+//
+// Events are stored in a bbolt file with one bucket per chain:
+//
+//	<chain>/hash    block hash           -> JSON blocks.BlockEvent
+//	<chain>/height  big-endian height    -> block hash (latest wins on reorg)
+//	<chain>/time    unix nanos + hash    -> nil, for pruning by age
+package blockindex
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+var (
+	indexedBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "blockindex_blocks_indexed_total",
+		Help: "Block events written to the local block index",
+	}, []string{"chain"})
+
+	prunedBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "blockindex_blocks_pruned_total",
+		Help: "Block events removed from the local block index by retention",
+	}, []string{"chain"})
+)
+
+var (
+	bucketHash   = []byte("hash")
+	bucketHeight = []byte("height")
+	bucketTime   = []byte("time")
+)
+
+var (
+	// ErrNotFound is returned when a block is not in the index
+	ErrNotFound = errors.New("block not in index")
+	// ErrLocked is returned by Open while another process holds the file,
+	// e.g. the previous binary during a zero-downtime reload
+	ErrLocked = errors.New("block index locked by another process")
+)
+
+// Config controls retention and query limits
+type Config struct {
+	Retention     time.Duration // Blocks older than this are pruned
+	PruneInterval time.Duration
+	MaxRange      int // Most blocks returned by one range query
+}
+
+// DefaultConfig keeps a week of blocks
+func DefaultConfig() Config {
+	return Config{
+		Retention:     7 * 24 * time.Hour,
+		PruneInterval: time.Hour,
+		MaxRange:      1000,
+	}
+}
+
+// Index is a bbolt-backed block history
+type Index struct {
+	db     *bolt.DB
+	cfg    Config
+	logger *zap.Logger
+}
+
+// Open opens or creates the index at path
+func Open(path string, cfg Config, logger *zap.Logger) (*Index, error) {
+	def := DefaultConfig()
+	if cfg.Retention <= 0 {
+		cfg.Retention = def.Retention
+	}
+	if cfg.PruneInterval <= 0 {
+		cfg.PruneInterval = def.PruneInterval
+	}
+	if cfg.MaxRange <= 0 {
+		cfg.MaxRange = def.MaxRange
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create block index directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open block index: %w", err)
+	}
+	return &Index{db: db, cfg: cfg, logger: logger}, nil
+}
+
+// Close closes the underlying database
+func (ix *Index) Close() error {
+	return ix.db.Close()
+}
+
+// MaxRange is the most blocks a range query returns
+func (ix *Index) MaxRange() int {
+	return ix.cfg.MaxRange
+}
+
+// Put indexes a block event. Header-only and transaction events are
+// ignored; a block at an already indexed height replaces it in height
+// lookups but stays reachable by hash until pruned.
+func (ix *Index) Put(event blocks.BlockEvent) error {
+	if event.IsHeader || event.TxID != "" || event.Hash == "" {
+		return nil
+	}
+	chain := chainName(event.Chain)
+	hash := strings.ToLower(event.Hash)
+	seen := event.DetectedAt
+	if seen.IsZero() {
+		seen = time.Now()
+	}
+
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode block event: %w", err)
+	}
+
+	err = ix.db.Update(func(tx *bolt.Tx) error {
+		b, err := chainBucket(tx, chain)
+		if err != nil {
+			return err
+		}
+		byHash := b.Bucket(bucketHash)
+		if byHash.Get([]byte(hash)) != nil {
+			return nil
+		}
+		if err := byHash.Put([]byte(hash), value); err != nil {
+			return err
+		}
+		if err := b.Bucket(bucketHeight).Put(heightKey(uint64(event.Height)), []byte(hash)); err != nil {
+			return err
+		}
+		return b.Bucket(bucketTime).Put(timeKey(seen, hash), nil)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to index block %s: %w", hash, err)
+	}
+	indexedBlocks.WithLabelValues(chain).Inc()
+	return nil
+}
+
+// ByHash returns the indexed block with hash
+func (ix *Index) ByHash(chain, hash string) (blocks.BlockEvent, error) {
+	var event blocks.BlockEvent
+	err := ix.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(chainName(blocks.Chain(chain))))
+		if b == nil {
+			return ErrNotFound
+		}
+		v := b.Bucket(bucketHash).Get([]byte(strings.ToLower(hash)))
+		if v == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(v, &event)
+	})
+	return event, err
+}
+
+// Range returns the blocks with from <= height <= to in ascending order,
+// at most MaxRange of them. Heights without an indexed block are skipped.
+func (ix *Index) Range(chain string, from, to uint64) ([]blocks.BlockEvent, error) {
+	if to < from {
+		return nil, fmt.Errorf("to_height %d is below from_height %d", to, from)
+	}
+	out := make([]blocks.BlockEvent, 0)
+	err := ix.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(chainName(blocks.Chain(chain))))
+		if b == nil {
+			return nil
+		}
+		byHash := b.Bucket(bucketHash)
+		c := b.Bucket(bucketHeight).Cursor()
+		for k, hash := c.Seek(heightKey(from)); k != nil && len(out) < ix.cfg.MaxRange; k, hash = c.Next() {
+			if binary.BigEndian.Uint64(k) > to {
+				break
+			}
+			v := byHash.Get(hash)
+			if v == nil {
+				continue
+			}
+			var event blocks.BlockEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+			out = append(out, event)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Prune removes blocks first seen before cutoff and returns how many were
+// removed
+func (ix *Index) Prune(cutoff time.Time) (int, error) {
+	total := 0
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			n, err := pruneChain(b, cutoff)
+			if n > 0 {
+				prunedBlocks.WithLabelValues(string(name)).Add(float64(n))
+			}
+			total += n
+			return err
+		})
+	})
+	return total, err
+}
+
+func pruneChain(b *bolt.Bucket, cutoff time.Time) (int, error) {
+	byHash, byHeight := b.Bucket(bucketHash), b.Bucket(bucketHeight)
+	c := b.Bucket(bucketTime).Cursor()
+	limit := uint64(cutoff.UnixNano())
+
+	n := 0
+	for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k[:8]) < limit; k, _ = c.First() {
+		hash := k[8:]
+		if v := byHash.Get(hash); v != nil {
+			var event blocks.BlockEvent
+			if err := json.Unmarshal(v, &event); err == nil {
+				hk := heightKey(uint64(event.Height))
+				if string(byHeight.Get(hk)) == string(hash) {
+					if err := byHeight.Delete(hk); err != nil {
+						return n, err
+					}
+				}
+			}
+			if err := byHash.Delete(hash); err != nil {
+				return n, err
+			}
+		}
+		if err := c.Delete(); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RunPruner prunes on the configured interval until ctx is cancelled
+func (ix *Index) RunPruner(ctx context.Context) {
+	ticker := time.NewTicker(ix.cfg.PruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := ix.Prune(time.Now().Add(-ix.cfg.Retention))
+			if err != nil {
+				ix.logger.Warn("Block index prune failed", zap.Error(err))
+			} else if n > 0 {
+				ix.logger.Debug("Pruned block index", zap.Int("blocks", n))
+			}
+		}
+	}
+}
+
+func chainBucket(tx *bolt.Tx, chain string) (*bolt.Bucket, error) {
+	b, err := tx.CreateBucketIfNotExists([]byte(chain))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{bucketHash, bucketHeight, bucketTime} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// chainName normalises the chain bucket name; events without a chain are
+// Bitcoin
+func chainName(chain blocks.Chain) string {
+	if chain == "" {
+		return string(blocks.ChainBitcoin)
+	}
+	return strings.ToLower(string(chain))
+}
+
+func heightKey(height uint64) []byte {
+	k := make([]byte, 8)
+	binary.BigEndian.PutUint64(k, height)
+	return k
+}
+
+func timeKey(t time.Time, hash string) []byte {
+	k := make([]byte, 8, 8+len(hash))
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, hash...)
+}
@@ -0,0 +1,60 @@
+package blockindex
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"go.uber.org/zap"
+)
+
+func TestIndexRangeLookupAndPrune(t *testing.T) {
+	ix, err := Open(filepath.Join(t.TempDir(), "index.db"), Config{MaxRange: 3}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ix.Close()
+
+	old := time.Now().Add(-48 * time.Hour)
+	put := func(hash string, height uint32, seen time.Time) {
+		t.Helper()
+		if err := ix.Put(blocks.BlockEvent{Hash: hash, Height: height, DetectedAt: seen, Chain: blocks.ChainBitcoin}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("aa", 100, old)
+	put("bb", 101, time.Now())
+	put("cc", 102, time.Now())
+	put("cc2", 102, time.Now()) // reorg replaces height 102
+	put("dd", 104, time.Now())
+	if err := ix.Put(blocks.BlockEvent{Hash: "ee", Height: 0, IsHeader: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ix.Range("bitcoin", 100, 110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Hash != "aa" || got[2].Hash != "cc2" {
+		t.Fatalf("Range = %+v, want aa, bb, cc2 (capped at 3)", got)
+	}
+	if b, err := ix.ByHash("bitcoin", "CC"); err != nil || b.Height != 102 {
+		t.Fatalf("ByHash(cc) = %+v, %v", b, err)
+	}
+	if _, err := ix.ByHash("bitcoin", "ee"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("header event was indexed: %v", err)
+	}
+
+	n, err := ix.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil || n != 1 {
+		t.Fatalf("Prune = %d, %v; want 1", n, err)
+	}
+	if _, err := ix.ByHash("bitcoin", "aa"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("pruned block still indexed: %v", err)
+	}
+	if got, _ := ix.Range("bitcoin", 100, 100); len(got) != 0 {
+		t.Fatalf("pruned height still indexed: %+v", got)
+	}
+}
@@ -75,6 +75,7 @@ type BlockEvent struct {
 	Chain       Chain       `json:"chain"`
 	Status      BlockStatus `json:"status"`
 	ProcessedAt *time.Time  `json:"processed_at,omitempty"`
+	Backfilled  bool        `json:"backfilled,omitempty"` // Replayed after a stream gap, not live
 }
 
 // ErrAlreadyProcessing indicates a duplicate in-flight block event.
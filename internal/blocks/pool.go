@@ -0,0 +1,22 @@
+package blocks
+
+import "sync"
+
+// eventPool recycles BlockEvents on hot paths such as the Solana slot
+// feed, which hands an event across a 2000-deep channel every few hundred
+// milliseconds per connection
+var eventPool = sync.Pool{New: func() interface{} { return new(BlockEvent) }}
+
+// AcquireEvent returns a zeroed BlockEvent from the pool. One holder owns
+// it at a time; whoever consumes it last, typically after copying it into
+// the block bus or a stream channel, calls Release.
+func AcquireEvent() *BlockEvent {
+	return eventPool.Get().(*BlockEvent)
+}
+
+// Release zeroes e and returns it to the pool. e must not be used
+// afterwards by anyone.
+func (e *BlockEvent) Release() {
+	*e = BlockEvent{}
+	eventPool.Put(e)
+}
@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// BatchEntry is one value to store with SetMulti
+type BatchEntry struct {
+	Key   string
+	Value interface{}
+	TTL   time.Duration
+}
+
+// BatchResult is the outcome of one key in GetMulti. Err is nil on a hit,
+// ErrCacheMiss or ErrCacheExpired on a miss, or another error if the lookup
+// could not be made.
+type BatchResult struct {
+	Value interface{}
+	Err   error
+}
+
+// Found reports whether the key was a cache hit
+func (r BatchResult) Found() bool { return r.Err == nil }
+
+// GetMulti looks up several keys at once. Keys are grouped by L1 shard so
+// each shard lock is taken once per call; results are returned per key and
+// a miss on one key does not affect the others.
+func (ec *EnterpriseCache) GetMulti(keys []string) map[string]BatchResult {
+	startTime := time.Now()
+	defer func() {
+		ec.recordLatency(time.Since(startTime))
+	}()
+
+	results := make(map[string]BatchResult, len(keys))
+	unique := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if _, dup := results[key]; !dup {
+			results[key] = BatchResult{Err: ErrCacheMiss}
+			unique = append(unique, key)
+		}
+	}
+	if len(unique) == 0 {
+		return results
+	}
+	atomic.AddInt64(&ec.totalRequests, int64(len(unique)))
+
+	if ec.circuitBreaker != nil && !ec.circuitBreaker.AllowRequest() {
+		for _, key := range unique {
+			results[key] = BatchResult{Err: ErrCircuitOpen}
+		}
+		atomic.AddInt64(&ec.cacheMisses, int64(len(unique)))
+		return results
+	}
+
+	// Keys the bloom filter has never seen are misses without a shard visit
+	lookup := unique
+	if ec.bloomFilter != nil {
+		lookup = make([]string, 0, len(unique))
+		for _, key := range unique {
+			if ec.bloomFilter.MightContain(key) {
+				lookup = append(lookup, key)
+			}
+		}
+	}
+
+	found := ec.getManyFromL1(lookup, results)
+	for _, key := range unique {
+		ec.recordNamespaceLookup(key, results[key].Err == nil)
+	}
+
+	atomic.AddInt64(&ec.cacheHits, int64(found))
+	atomic.AddInt64(&ec.cacheMisses, int64(len(unique)-found))
+	if ec.circuitBreaker != nil {
+		if found < len(lookup) {
+			ec.circuitBreaker.RecordFailure()
+		} else if len(lookup) > 0 {
+			ec.circuitBreaker.RecordSuccess()
+		}
+	}
+	return results
+}
+
+// getManyFromL1 fills results for keys from L1 and returns the number of hits
+func (ec *EnterpriseCache) getManyFromL1(keys []string, results map[string]BatchResult) int {
+	backend := ec.levels[L1Memory]
+	if backend == nil {
+		for _, key := range keys {
+			results[key] = BatchResult{Err: ErrBackendMissing}
+		}
+		return 0
+	}
+
+	found := 0
+	hit := func(key string, entry *CacheEntry) {
+		ec.touchKey(key)
+		ec.recordCacheHit(L1Memory)
+		value, _ := ec.deserializeEntry(entry)
+		results[key] = BatchResult{Value: value}
+		found++
+	}
+
+	readShard := func(mb *MemoryBackend, shardKeys []string) {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+		for _, key := range shardKeys {
+			entry, err := mb.getLocked(key)
+			if err != nil {
+				results[key] = BatchResult{Err: err}
+				continue
+			}
+			hit(key, entry)
+		}
+	}
+
+	switch b := backend.(type) {
+	case *MemoryBackend:
+		readShard(b, keys)
+	case *ShardedMemoryBackend:
+		for idx, shardKeys := range b.groupByShard(keys) {
+			readShard(b.shards[idx], shardKeys)
+		}
+	default:
+		for _, key := range keys {
+			entry, err := b.Get(key)
+			if err != nil {
+				results[key] = BatchResult{Err: err}
+				continue
+			}
+			hit(key, entry)
+		}
+	}
+	return found
+}
+
+// SetMulti stores several entries at once, grouping them by L1 shard so each
+// shard lock is taken once. It returns the keys that could not be stored with
+// their errors, or nil when every entry was stored.
+func (ec *EnterpriseCache) SetMulti(entries []BatchEntry) map[string]error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var failed map[string]error
+	fail := func(key string, err error) {
+		if failed == nil {
+			failed = make(map[string]error)
+		}
+		failed[key] = err
+	}
+
+	if ec.circuitBreaker != nil && !ec.circuitBreaker.AllowRequest() {
+		for _, e := range entries {
+			fail(e.Key, ErrCircuitOpen)
+		}
+		return failed
+	}
+
+	// Later entries for the same key win, as with sequential Set calls
+	prepared := make(map[string]*CacheEntry, len(entries))
+	order := make([]string, 0, len(entries))
+	for _, e := range entries {
+		entry, err := ec.createCacheEntry(e.Key, e.Value, e.TTL)
+		if err != nil {
+			fail(e.Key, err)
+			continue
+		}
+		if _, seen := prepared[e.Key]; !seen {
+			order = append(order, e.Key)
+		}
+		delete(failed, e.Key)
+		entry.Level = L1Memory
+		prepared[e.Key] = entry
+	}
+
+	if ec.isMemoryPressureHigh() {
+		ec.triggerEviction()
+	}
+
+	writeShard := func(mb *MemoryBackend, keys []string) {
+		mb.mu.Lock()
+		defer mb.mu.Unlock()
+		for _, key := range keys {
+			mb.setWithAdmissionLocked(ec, key, *prepared[key])
+		}
+	}
+
+	switch b := ec.levels[L1Memory].(type) {
+	case nil:
+		for _, key := range order {
+			fail(key, ErrBackendMissing)
+		}
+	case *MemoryBackend:
+		writeShard(b, order)
+	case *ShardedMemoryBackend:
+		for idx, keys := range b.groupByShard(order) {
+			writeShard(b.shards[idx], keys)
+		}
+	default:
+		for _, key := range order {
+			if err := b.Set(key, prepared[key]); err != nil {
+				fail(key, err)
+			}
+		}
+	}
+
+	for _, key := range order {
+		if _, bad := failed[key]; bad {
+			continue
+		}
+		ec.trackNamespaceWrite(key, prepared[key].Size)
+		if ec.bloomFilter != nil {
+			ec.bloomFilter.Add(key)
+		}
+	}
+
+	if ec.circuitBreaker != nil {
+		if len(failed) == 0 {
+			ec.circuitBreaker.RecordSuccess()
+		} else {
+			ec.circuitBreaker.RecordFailure()
+		}
+	}
+	return failed
+}
+
+// groupByShard buckets keys by shard index, preserving their order
+func (s *ShardedMemoryBackend) groupByShard(keys []string) map[uint64][]string {
+	groups := make(map[uint64][]string)
+	for _, key := range keys {
+		idx := s.shardIndex(key)
+		groups[idx] = append(groups[idx], key)
+	}
+	return groups
+}
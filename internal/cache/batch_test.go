@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetMultiSetMultiAcrossShards(t *testing.T) {
+	cfg := smallConfig()
+	cfg.ShardCount = 4
+	cfg.MaxEntries = 256
+	cfg.EnableCircuitBreaker = false
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make([]BatchEntry, 0, 12)
+	keys := make([]string, 0, 13)
+	for i := 0; i < 12; i++ {
+		key := fmt.Sprintf("k%d", i)
+		entries = append(entries, BatchEntry{Key: key, Value: i, TTL: time.Minute})
+		keys = append(keys, key)
+	}
+	entries = append(entries, BatchEntry{Key: "bad", Value: make(chan int), TTL: time.Minute})
+
+	failed := c.SetMulti(entries)
+	if len(failed) != 1 || failed["bad"] == nil {
+		t.Fatalf("SetMulti failures = %v; want only \"bad\"", failed)
+	}
+
+	keys = append(keys, "missing")
+	results := c.GetMulti(keys)
+	for i := 0; i < 12; i++ {
+		r := results[fmt.Sprintf("k%d", i)]
+		if !r.Found() || r.Value.(int) != i {
+			t.Fatalf("k%d = %+v; want hit with %d", i, r, i)
+		}
+	}
+	if r := results["missing"]; r.Found() || !errors.Is(r.Err, ErrCacheMiss) {
+		t.Fatalf("missing = %+v; want ErrCacheMiss", r)
+	}
+
+	// Single-key Get sees batch writes
+	if v, ok := c.Get("k3"); !ok || v.(int) != 3 {
+		t.Fatalf("Get(k3) = %v, %v", v, ok)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	xsync "golang.org/x/sync/singleflight"
 	"io"
@@ -20,7 +21,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
-	"github.com/PayRpc/Bitcoin-Sprint/internal/entropy"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
 	"go.uber.org/zap"
 )
 
@@ -56,6 +57,14 @@ const (
 	CompressionZstd
 )
 
+// Errors reported by memory backends and the batch APIs
+var (
+	ErrCacheMiss      = errors.New("key not found")
+	ErrCacheExpired   = errors.New("entry expired")
+	ErrCircuitOpen    = errors.New("cache circuit breaker open")
+	ErrBackendMissing = errors.New("L1 backend not available")
+)
+
 // CacheEntry represents a cached item with full metadata
 type CacheEntry struct {
 	Key            string      `json:"key"`
@@ -92,10 +101,12 @@ type BlockCache struct {
 // EnterpriseCache manages multi-tiered, high-performance caching
 type EnterpriseCache struct {
 	// Core cache storage
-	mu          sync.RWMutex
-	latestBlock BlockCache
-	blockCache  map[int64]*CacheEntry  // height -> entry
-	hashCache   map[string]*CacheEntry // hash -> entry
+	mu            sync.RWMutex
+	latestBlock   BlockCache
+	latestByChain map[blocks.Chain]blocks.BlockEvent // Tip per chain, for snapshots
+	latestWrites  map[blocks.Chain]*latestWrite      // Coalescing state per chain
+	blockCache    map[int64]*CacheEntry              // height -> entry
+	hashCache     map[string]*CacheEntry             // hash -> entry
 
 	// Configuration
 	config  *CacheConfig
@@ -117,6 +128,12 @@ type EnterpriseCache struct {
 	door interface{ TestAndAdd([]byte) bool }
 	// Collapse duplicate loads to avoid stampedes
 	group xsync.Group
+	// Background refresh of hot keys before they expire
+	refreshAhead *refreshAheadRegistry
+	// Per-prefix loaders that populate misses
+	loaders *loaderRegistry
+	// Per-prefix TTL, compression, admission and budgets
+	namespaces *namespaceRegistry
 
 	// Monitoring and health
 	healthChecker  *CacheHealthChecker
@@ -140,6 +157,12 @@ type EnterpriseCache struct {
 	clock Clock
 	// test hook: notifier channel to signal background refresh completion
 	refreshNotify chan string
+	// Churn and compaction history for fragmentation reporting
+	compaction compactionTracker
+	// Memory pressure level and its subscribers
+	pressure pressureWatch
+	// Recent block intervals per chain, for namespace TTL rules
+	activity blockActivity
 }
 
 // Clock provides a testable time source
@@ -172,6 +195,8 @@ type CacheConfig struct {
 	MemoryLimit     int64         `json:"memory_limit"`
 	MemoryThreshold float64       `json:"memory_threshold"`
 	GCInterval      time.Duration `json:"gc_interval"`
+	// How often memory pressure is sampled for OnMemoryPressure subscribers
+	PressureInterval time.Duration `json:"pressure_interval"`
 
 	// Tiered caching
 	EnableL2Disk         bool     `json:"enable_l2_disk"`
@@ -190,11 +215,23 @@ type CacheConfig struct {
 	FailureThreshold     int           `json:"failure_threshold"`
 	SuccessThreshold     int           `json:"success_threshold"`
 	Timeout              time.Duration `json:"timeout"`
+	HalfOpenProbes       int           `json:"half_open_probes"` // Concurrent probe requests admitted while half-open
 
 	// Cache warming
 	EnableWarmup   bool     `json:"enable_warmup"`
 	WarmupPrefetch int      `json:"warmup_prefetch"`
 	WarmupChains   []string `json:"warmup_chains"`
+
+	// Latest block writes for one chain closer together than this are
+	// coalesced into the last of them (0 = write every block)
+	LatestBlockCoalesce time.Duration `json:"latest_block_coalesce"`
+
+	// Per-prefix overrides; keys outside every namespace use the settings above
+	Namespaces []NamespaceConfig `json:"namespaces"`
+
+	// Plug-in stores behind L1; nil leaves the tier out
+	L2Backend *BackendConfig `json:"l2_backend,omitempty"`
+	L3Backend *BackendConfig `json:"l3_backend,omitempty"`
 }
 
 // CacheBackend interface for different cache storage backends
@@ -216,6 +253,13 @@ type BackendStats struct {
 	Misses     int64 `json:"misses"`
 	Operations int64 `json:"operations"`
 	Errors     int64 `json:"errors"`
+	Expired    int64 `json:"expired"` // Entries removed by proactive expiration
+
+	// Churn since startup; PeakEntries is the high-water mark since the
+	// last compaction, which the entry maps stay sized for
+	Inserts     int64 `json:"inserts"`
+	Removals    int64 `json:"removals"`
+	PeakEntries int64 `json:"peak_entries"`
 }
 
 // CacheMetrics tracks comprehensive cache performance
@@ -259,6 +303,9 @@ type CacheMetrics struct {
 	LastError   *time.Time    `json:"last_error,omitempty"`
 	Uptime      time.Duration `json:"uptime"`
 
+	CircuitBreaker *CircuitBreakerStats `json:"circuit_breaker,omitempty"`
+	Compaction     *CompactionStats     `json:"compaction,omitempty"`
+
 	// Strategy-specific metrics
 	StrategyMetrics map[string]interface{} `json:"strategy_metrics"`
 }
@@ -292,15 +339,23 @@ type CacheHealthChecker struct {
 	mu          sync.RWMutex
 }
 
-// CacheCircuitBreaker prevents cascade failures in cache operations
+// CacheCircuitBreaker prevents cascade failures in cache operations. After
+// the open timeout it admits a limited number of probe requests (half-open)
+// and closes only once successThreshold of them succeed.
 type CacheCircuitBreaker struct {
-	failures    int64
-	successes   int64
-	lastFailure time.Time
-	state       CircuitState
-	threshold   int
-	timeout     time.Duration
-	mu          sync.RWMutex
+	state            int32 // CircuitState; read without the lock on the hot path
+	failures         int64 // Consecutive failures while closed
+	successes        int64 // Probe successes while half-open
+	lastFailure      time.Time
+	stateChangedAt   time.Time
+	lastProbe        time.Time
+	probesInFlight   int
+	trips            int64
+	threshold        int
+	successThreshold int
+	probeBudget      int
+	timeout          time.Duration
+	mu               sync.Mutex
 }
 
 // CircuitState represents circuit breaker states
@@ -312,6 +367,30 @@ const (
 	CircuitHalfOpen
 )
 
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerStats is a snapshot of the cache circuit breaker
+type CircuitBreakerStats struct {
+	State          string    `json:"state"`
+	StateChangedAt time.Time `json:"state_changed_at"`
+	LastFailure    time.Time `json:"last_failure,omitempty"`
+	Failures       int64     `json:"consecutive_failures"`
+	ProbesInFlight int       `json:"probes_in_flight"`
+	ProbeBudget    int       `json:"probe_budget"`
+	Trips          int64     `json:"trips"`
+}
+
 // CacheWarmupManager handles intelligent cache preloading
 type CacheWarmupManager struct {
 	cache      *EnterpriseCache
@@ -347,6 +426,9 @@ type MemoryBackend struct {
 	lru     *list.List // list of *CacheEntry, front is most recently used
 	maxSize int
 	stats   BackendStats
+	peak    int // Most entries held since the last compaction
+
+	expiries expiryHeap // Pending expirations, reaped by the cleanup worker
 }
 
 // NewEnterpriseCache creates a production-ready cache system
@@ -374,6 +456,9 @@ func NewEnterpriseCache(config *CacheConfig, logger *zap.Logger) (*EnterpriseCac
 		shutdownChan:    make(chan struct{}),
 		clock:           realClock{},
 		refreshNotify:   make(chan string, 16),
+		refreshAhead:    newRefreshAheadRegistry(),
+		loaders:         newLoaderRegistry(),
+		namespaces:      newNamespaceRegistry(),
 		metrics:         &CacheMetrics{},
 	}
 
@@ -387,6 +472,13 @@ func NewEnterpriseCache(config *CacheConfig, logger *zap.Logger) (*EnterpriseCac
 	// Note: monitor memory usage closely when using this in production.
 	debug.SetGCPercent(200)
 
+	for _, ns := range config.Namespaces {
+		if err := cache.SetNamespace(ns); err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid cache namespace: %w", err)
+		}
+	}
+
 	// Initialize bloom filter if enabled
 	if config.EnableBloomFilter {
 		cache.bloomFilter = NewBloomFilter(config.BloomFilterSize, config.BloomFilterHashes)
@@ -416,6 +508,7 @@ func NewEnterpriseCache(config *CacheConfig, logger *zap.Logger) (*EnterpriseCac
 		cache.circuitBreaker = NewCacheCircuitBreaker(
 			config.FailureThreshold,
 			config.SuccessThreshold,
+			config.HalfOpenProbes,
 			config.Timeout,
 		)
 	}
@@ -521,13 +614,17 @@ func NewShardedMemoryBackend(shardCount int, maxEntries int) CacheBackend {
 }
 
 func (s *ShardedMemoryBackend) pickShard(key string) *MemoryBackend {
+	return s.shards[s.shardIndex(key)]
+}
+
+func (s *ShardedMemoryBackend) shardIndex(key string) uint64 {
 	// simple xxhash using FNV-like mix for speed (don't import extra dep)
 	var h uint64 = 1469598103934665603
 	for i := 0; i < len(key); i++ {
 		h ^= uint64(key[i])
 		h *= 1099511628211
 	}
-	return s.shards[h&s.shardMask]
+	return h & s.shardMask
 }
 
 func (s *ShardedMemoryBackend) Get(key string) (*CacheEntry, error) {
@@ -567,6 +664,10 @@ func (s *ShardedMemoryBackend) Stats() BackendStats {
 		agg.Operations += st.Operations
 		agg.Size += st.Size
 		agg.Errors += st.Errors
+		agg.Expired += st.Expired
+		agg.Inserts += st.Inserts
+		agg.Removals += st.Removals
+		agg.PeakEntries += st.PeakEntries
 	}
 	return agg
 }
@@ -578,6 +679,10 @@ var (
 	cacheAdmission = promauto.NewCounterVec(prometheus.CounterOpts{Name: "cache_admissions_total", Help: "TinyLFU admissions"}, []string{"result", "reason"})
 	cacheSWR       = promauto.NewCounterVec(prometheus.CounterOpts{Name: "cache_swr_refresh_total", Help: "SWR background refreshes"}, []string{"result"})
 	cacheSF        = promauto.NewCounter(prometheus.CounterOpts{Name: "cache_singleflight_collapses_total", Help: "number of coalesced loads"})
+
+	cacheCircuitState      = promauto.NewGauge(prometheus.GaugeOpts{Name: "cache_circuit_state", Help: "Cache circuit breaker state (0 closed, 1 open, 2 half-open)"})
+	cacheCircuitTrips      = promauto.NewCounter(prometheus.CounterOpts{Name: "cache_circuit_trips_total", Help: "Cache circuit breaker transitions to open"})
+	cacheCircuitRejections = promauto.NewCounter(prometheus.CounterOpts{Name: "cache_circuit_rejections_total", Help: "Cache operations refused by an open or saturated half-open breaker"})
 )
 
 func (c *EnterpriseCache) recordAdmission(ok bool, via string) {
@@ -608,6 +713,10 @@ func (c *EnterpriseCache) touchKey(key string) {
 }
 
 func (c *EnterpriseCache) admitTinyLFU(key string, victimKey string) bool {
+	if c.admitsAlways(key) {
+		c.recordAdmission(true, "namespace")
+		return true
+	}
 	if c.freq == nil {
 		return true
 	}
@@ -625,15 +734,24 @@ func (c *EnterpriseCache) admitTinyLFU(key string, victimKey string) bool {
 	return ok
 }
 
-// GetOrLoad collapses duplicate concurrent loads using singleflight
+// GetOrLoad collapses duplicate concurrent loads using singleflight. A
+// caller whose ctx ends stops waiting without failing the load for the
+// others; the loader runs with the ctx of the caller that started it.
 func (ec *EnterpriseCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, loader func(context.Context) (any, error)) (any, bool, error) {
 	// fast path
 	if entry := ec.getFromL1(key); entry != nil {
+		ec.recordNamespaceLookup(key, true)
+		ec.refreshAheadOnHit(key, entry)
 		v, _ := ec.deserializeEntry(entry)
 		return v, true, nil
 	}
+	ec.recordNamespaceLookup(key, false)
+	ec.refreshAheadOnMiss(key)
+	if v, ok := ec.getFromLowerTiers(key); ok {
+		return v, true, nil
+	}
 
-	v, err, shared := ec.group.Do(key, func() (any, error) {
+	load := func() (any, error) {
 		// double-check after acquiring singleflight
 		if entry := ec.getFromL1(key); entry != nil {
 			v, _ := ec.deserializeEntry(entry)
@@ -646,17 +764,41 @@ func (ec *EnterpriseCache) GetOrLoad(ctx context.Context, key string, ttl time.D
 		// set with TTL
 		_ = ec.Set(key, val, ttl)
 		return val, nil
-	})
-
-	if shared {
-		cacheSF.Inc()
 	}
+
+	v, err := ec.collapse(ctx, key, load)
 	if err != nil {
 		return nil, false, err
 	}
 	return v, false, nil
 }
 
+// collapse runs load once for all concurrent callers with key and returns
+// its result to each. A caller whose ctx ends stops waiting.
+func (ec *EnterpriseCache) collapse(ctx context.Context, key string, load func() (any, error)) (any, error) {
+	for attempt := 0; ; attempt++ {
+		var res xsync.Result
+		select {
+		case res = <-ec.group.DoChan(key, load):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if res.Shared {
+			cacheSF.Inc()
+		}
+		// The load we joined was cut short by its own caller going away;
+		// retry once under this caller's ctx
+		if res.Shared && attempt == 0 && ctx.Err() == nil && isContextError(res.Err) {
+			continue
+		}
+		return res.Val, res.Err
+	}
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
 // GetSWR returns stale-while-revalidate semantics for hot endpoints
 func (ec *EnterpriseCache) GetSWR(ctx context.Context, key string, loader func(context.Context) (any, error), hardTTL, softTTL time.Duration) (any, bool, error) {
 	// check L1
@@ -732,6 +874,7 @@ func DefaultCacheConfig() *CacheConfig {
 		MemoryLimit:          2 * 1024 * 1024 * 1024, // 2GB
 		MemoryThreshold:      0.95,                   // 95% - delay evictions, high-memory mode
 		GCInterval:           10 * time.Minute,
+		PressureInterval:     5 * time.Second,
 		EnableL2Disk:         false,
 		EnableL3Distributed:  false,
 		EnableMetrics:        true,
@@ -742,9 +885,12 @@ func DefaultCacheConfig() *CacheConfig {
 		FailureThreshold:     20, // much higher tolerance to avoid false opens under heavy load
 		SuccessThreshold:     10, // require more stable successes to close
 		Timeout:              30 * time.Second,
+		HalfOpenProbes:       4,
 		EnableWarmup:         true,
 		WarmupPrefetch:       100,
 		WarmupChains:         []string{"bitcoin", "ethereum"},
+		Namespaces:           DefaultNamespaces(),
+		LatestBlockCoalesce:  100 * time.Millisecond,
 	}
 }
 
@@ -768,7 +914,8 @@ func (ec *EnterpriseCache) Get(key string) (interface{}, bool) {
 	// Check bloom filter first (if enabled)
 	if ec.bloomFilter != nil && !ec.bloomFilter.MightContain(key) {
 		atomic.AddInt64(&ec.cacheMisses, 1)
-		return nil, false
+		ec.recordNamespaceLookup(key, false)
+		return ec.loadOnMiss(key)
 	}
 
 	// Try L1 cache first
@@ -776,11 +923,29 @@ func (ec *EnterpriseCache) Get(key string) (interface{}, bool) {
 		ec.touchKey(key)
 		atomic.AddInt64(&ec.cacheHits, 1)
 		ec.recordCacheHit(L1Memory)
+		ec.recordNamespaceLookup(key, true)
+		ec.refreshAheadOnHit(key, entry)
+		if ec.circuitBreaker != nil {
+			ec.circuitBreaker.RecordSuccess()
+		}
+		if isNegativeEntry(entry) {
+			return nil, false
+		}
+		ec.revalidateIfStale(key, entry)
 		return ec.deserializeEntry(entry)
 	}
 
 	// Cache miss
 	atomic.AddInt64(&ec.cacheMisses, 1)
+	ec.recordNamespaceLookup(key, false)
+	ec.refreshAheadOnMiss(key)
+	// A miss a lower tier or the namespace loader fills is not a cache failure
+	if v, ok := ec.getFromLowerTiers(key); ok {
+		return v, true
+	}
+	if v, ok := ec.loadOnMiss(key); ok {
+		return v, true
+	}
 	if ec.circuitBreaker != nil {
 		ec.circuitBreaker.RecordFailure()
 	}
@@ -816,6 +981,9 @@ func (ec *EnterpriseCache) Set(key string, value interface{}, ttl time.Duration)
 		return err
 	}
 
+	ec.trackNamespaceWrite(key, entry.Size)
+	ec.writeLowerTiers(key, entry)
+
 	// Add to bloom filter
 	if ec.bloomFilter != nil {
 		ec.bloomFilter.Add(key)
@@ -828,8 +996,9 @@ func (ec *EnterpriseCache) Set(key string, value interface{}, ttl time.Duration)
 	return nil
 }
 
-// SetLatestBlock updates the latest block with enterprise features
-func (ec *EnterpriseCache) SetLatestBlock(block blocks.BlockEvent) error {
+// writeLatestBlock stores block as the latest block, in the dedicated
+// field and as a regular cache entry
+func (ec *EnterpriseCache) writeLatestBlock(block blocks.BlockEvent) error {
 	key := fmt.Sprintf("latest_block_%s", block.Chain)
 
 	// Create enhanced block cache entry
@@ -857,7 +1026,10 @@ func (ec *EnterpriseCache) SetLatestBlock(block blocks.BlockEvent) error {
 	}
 
 	ec.mu.Lock()
-	ec.latestBlock = blockCache
+	// A newer block of the chain may have arrived while this one was built
+	if current, ok := ec.latestByChain[block.Chain]; !ok || current.Hash == block.Hash {
+		ec.latestBlock = blockCache
+	}
 	ec.mu.Unlock()
 
 	// Store in regular cache as well
@@ -916,11 +1088,17 @@ func (ec *EnterpriseCache) GetMetrics() *CacheMetrics {
 	ec.metrics.Decompressions = atomic.LoadInt64(&ec.decompressions)
 	ec.metrics.CurrentSize = ec.getCurrentSize()
 	ec.metrics.EntryCount = ec.getEntryCount()
-	ec.metrics.MemoryUsage = ec.getMemoryUsage()
 
 	if ec.healthChecker != nil {
 		ec.metrics.HealthScore = ec.healthChecker.GetHealthScore()
 	}
+	if ec.circuitBreaker != nil {
+		stats := ec.circuitBreaker.Stats()
+		ec.metrics.CircuitBreaker = &stats
+	}
+	compaction := ec.CompactionStats()
+	ec.metrics.Compaction = &compaction
+	ec.metrics.MemoryUsage = compaction.HeapAlloc
 
 	return ec.metrics
 }
@@ -929,6 +1107,9 @@ func (ec *EnterpriseCache) GetMetrics() *CacheMetrics {
 func (ec *EnterpriseCache) Shutdown(ctx context.Context) error {
 	ec.logger.Info("Shutting down enterprise cache")
 
+	// Write out queued tier writes while the tiers are still open
+	ec.flushWriteBehind(ctx)
+
 	// Signal shutdown
 	close(ec.shutdownChan)
 
@@ -962,9 +1143,8 @@ func (ec *EnterpriseCache) Shutdown(ctx context.Context) error {
 // Helper methods for internal operations
 
 func (ec *EnterpriseCache) initializeEntropy() error {
-	seed, err := entropy.FastEntropy()
-	if err != nil {
-		seed = make([]byte, 32)
+	seed := make([]byte, securebuf.EntropySize)
+	if _, err := securebuf.SharedEntropyPool().Read(seed); err != nil {
 		if _, err := rand.Read(seed); err != nil {
 			return fmt.Errorf("failed to generate entropy seed: %w", err)
 		}
@@ -980,7 +1160,7 @@ func (ec *EnterpriseCache) initializeBackends() error {
 	} else {
 		ec.levels[L1Memory] = NewMemoryBackend(ec.config.MaxEntries)
 	}
-	return nil
+	return ec.initializeTiers()
 }
 
 func (ec *EnterpriseCache) startBackgroundWorkers() {
@@ -997,6 +1177,12 @@ func (ec *EnterpriseCache) startBackgroundWorkers() {
 	// GC worker
 	ec.workerGroup.Add(1)
 	go ec.gcWorker()
+
+	// Memory pressure notifications
+	if ec.config.MemoryLimit > 0 {
+		ec.workerGroup.Add(1)
+		go ec.pressureWorker()
+	}
 }
 
 func (ec *EnterpriseCache) strategyName() string {
@@ -1050,6 +1236,12 @@ func (ec *EnterpriseCache) setToL1(key string, entry *CacheEntry) error {
 }
 
 func (ec *EnterpriseCache) createCacheEntry(key string, value interface{}, ttl time.Duration) (*CacheEntry, error) {
+	ns := ec.namespaceFor(key)
+	return ec.newCacheEntry(ns, key, value, ec.policyTTL(ns, ttl))
+}
+
+// newCacheEntry builds the entry for key, expiring after ttl as given
+func (ec *EnterpriseCache) newCacheEntry(ns *cacheNamespace, key string, value interface{}, ttl time.Duration) (*CacheEntry, error) {
 	now := ec.clock.Now()
 
 	// Serialize value
@@ -1071,6 +1263,13 @@ func (ec *EnterpriseCache) createCacheEntry(key string, value interface{}, ttl t
 		Version:      1,
 	}
 
+	if err := ns.compressEntry(entry, data); err != nil {
+		return nil, fmt.Errorf("failed to compress value: %w", err)
+	}
+	if entry.Compressed {
+		atomic.AddInt64(&ec.compressions, 1)
+	}
+
 	return entry, nil
 }
 
@@ -1150,6 +1349,14 @@ func (ec *EnterpriseCache) deserializeEntry(entry *CacheEntry) (interface{}, boo
 	atomic.AddInt64(&entry.AccessCount, 1)
 	entry.LastAccessed = time.Now()
 
+	if entry.Compressed && entry.CompressedData != nil {
+		value, err := ec.decompressEntryValue(entry)
+		if err != nil {
+			ec.logger.Warn("Failed to decompress cache entry", zap.String("key", entry.Key), zap.Error(err))
+			return nil, false
+		}
+		return value, true
+	}
 	return entry.Value, true
 }
 
@@ -1177,6 +1384,16 @@ func (ec *EnterpriseCache) isMemoryPressureHigh() bool {
 	return float64(memStats.Alloc) > float64(ec.config.MemoryLimit)*ec.config.MemoryThreshold
 }
 
+// MemoryPressure reports heap usage relative to the eviction threshold, so
+// 1.0 means the cache is about to start evicting. Zero without a memory limit.
+func (ec *EnterpriseCache) MemoryPressure() float64 {
+	limit := float64(ec.config.MemoryLimit) * ec.config.MemoryThreshold
+	if limit <= 0 {
+		return 0
+	}
+	return float64(ec.getMemoryUsage()) / limit
+}
+
 func (ec *EnterpriseCache) triggerEviction() {
 	atomic.AddInt64(&ec.evictions, 1)
 
@@ -1307,23 +1524,34 @@ func (ec *EnterpriseCache) gcWorker() {
 
 func (ec *EnterpriseCache) cleanup() {
 	// Remove expired entries from all levels
+	t := now()
 	for level, backend := range ec.levels {
+		reaped := 0
+		if ex, ok := backend.(expirer); ok {
+			reaped = ex.ReapExpired(t)
+		}
 		stats := backend.Stats()
 		ec.logger.Debug("Cache cleanup",
 			zap.String("level", fmt.Sprintf("L%d", int(level)+1)),
+			zap.Int("reaped", reaped),
 			zap.Int64("entries", stats.Entries),
 			zap.Int64("size", stats.Size))
 	}
 }
 
 func (ec *EnterpriseCache) updateMetrics() {
+	ec.sampleChurn()
 	metrics := ec.GetMetrics()
 
 	ec.logger.Debug("Cache metrics update",
 		zap.Int64("total_requests", metrics.TotalRequests),
 		zap.Float64("hit_rate", metrics.HitRate),
 		zap.Int64("memory_usage", metrics.MemoryUsage),
-		zap.Int64("evictions", metrics.Evictions))
+		zap.Int64("evictions", metrics.Evictions),
+		zap.Int64("live_bytes", metrics.Compaction.LiveBytes),
+		zap.Int64("heap_inuse", metrics.Compaction.HeapInuse),
+		zap.Float64("map_slack", metrics.Compaction.MapSlack),
+		zap.Float64("churn_per_sec", metrics.Compaction.ChurnPerSec))
 }
 
 // Supporting types and functions
@@ -1411,39 +1639,78 @@ func (chc *CacheHealthChecker) GetHealthScore() float64 {
 	return chc.healthScore
 }
 
-func NewCacheCircuitBreaker(failureThreshold, successThreshold int, timeout time.Duration) *CacheCircuitBreaker {
+func NewCacheCircuitBreaker(failureThreshold, successThreshold, halfOpenProbes int, timeout time.Duration) *CacheCircuitBreaker {
+	if successThreshold <= 0 {
+		successThreshold = 1
+	}
+	if halfOpenProbes <= 0 {
+		halfOpenProbes = 1
+	}
+	cacheCircuitState.Set(float64(CircuitClosed))
 	return &CacheCircuitBreaker{
-		threshold: failureThreshold,
-		timeout:   timeout,
-		state:     CircuitClosed,
+		threshold:        failureThreshold,
+		successThreshold: successThreshold,
+		probeBudget:      halfOpenProbes,
+		timeout:          timeout,
+		state:            int32(CircuitClosed),
+		stateChangedAt:   time.Now(),
 	}
 }
 
+// AllowRequest reports whether a cache operation may proceed. While half-open
+// only probeBudget operations are in flight at once; a probe whose outcome
+// is never recorded (e.g. a Get short-circuited by the bloom filter) gives
+// its slot back after the open timeout.
 func (cb *CacheCircuitBreaker) AllowRequest() bool {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
+	if CircuitState(atomic.LoadInt32(&cb.state)) == CircuitClosed {
+		return true
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
 
-	switch cb.state {
+	now := time.Now()
+	switch CircuitState(cb.state) {
 	case CircuitClosed:
 		return true
 	case CircuitOpen:
-		return time.Since(cb.lastFailure) > cb.timeout
-	case CircuitHalfOpen:
-		return true
-	default:
+		if now.Sub(cb.lastFailure) <= cb.timeout {
+			cacheCircuitRejections.Inc()
+			return false
+		}
+		cb.setState(CircuitHalfOpen, now)
+	}
+
+	if cb.probesInFlight >= cb.probeBudget && now.Sub(cb.lastProbe) > cb.timeout {
+		cb.probesInFlight = 0
+	}
+	if cb.probesInFlight >= cb.probeBudget {
+		cacheCircuitRejections.Inc()
 		return false
 	}
+	cb.probesInFlight++
+	cb.lastProbe = now
+	return true
 }
 
 func (cb *CacheCircuitBreaker) RecordSuccess() {
+	if CircuitState(atomic.LoadInt32(&cb.state)) == CircuitClosed {
+		if atomic.LoadInt64(&cb.failures) != 0 {
+			atomic.StoreInt64(&cb.failures, 0)
+		}
+		return
+	}
+
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	atomic.AddInt64(&cb.successes, 1)
-
-	if cb.state == CircuitHalfOpen {
-		cb.state = CircuitClosed
-		atomic.StoreInt64(&cb.failures, 0)
+	if CircuitState(cb.state) != CircuitHalfOpen {
+		return
+	}
+	cb.releaseProbe()
+	cb.successes++
+	if cb.successes >= int64(cb.successThreshold) {
+		cb.setState(CircuitClosed, time.Now())
 	}
 }
 
@@ -1451,16 +1718,67 @@ func (cb *CacheCircuitBreaker) RecordFailure() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	failures := atomic.AddInt64(&cb.failures, 1)
-	cb.lastFailure = time.Now()
+	now := time.Now()
+	cb.lastFailure = now
 
-	if cb.state == CircuitClosed && int(failures) >= cb.threshold {
-		cb.state = CircuitOpen
-	} else if cb.state == CircuitHalfOpen {
-		cb.state = CircuitOpen
+	switch CircuitState(cb.state) {
+	case CircuitClosed:
+		if int(atomic.AddInt64(&cb.failures, 1)) >= cb.threshold {
+			cb.setState(CircuitOpen, now)
+		}
+	case CircuitHalfOpen:
+		cb.releaseProbe()
+		cb.setState(CircuitOpen, now)
 	}
 }
 
+// State returns the current state and when it was entered
+func (cb *CacheCircuitBreaker) State() (CircuitState, time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return CircuitState(cb.state), cb.stateChangedAt
+}
+
+// Stats returns a snapshot of the breaker
+func (cb *CacheCircuitBreaker) Stats() CircuitBreakerStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return CircuitBreakerStats{
+		State:          CircuitState(cb.state).String(),
+		StateChangedAt: cb.stateChangedAt,
+		LastFailure:    cb.lastFailure,
+		Failures:       atomic.LoadInt64(&cb.failures),
+		ProbesInFlight: cb.probesInFlight,
+		ProbeBudget:    cb.probeBudget,
+		Trips:          cb.trips,
+	}
+}
+
+func (cb *CacheCircuitBreaker) releaseProbe() {
+	if cb.probesInFlight > 0 {
+		cb.probesInFlight--
+	}
+}
+
+// setState moves to a new state and resets its counters; cb.mu is held
+func (cb *CacheCircuitBreaker) setState(state CircuitState, now time.Time) {
+	if CircuitState(cb.state) == state {
+		return
+	}
+	atomic.StoreInt32(&cb.state, int32(state))
+	cb.stateChangedAt = now
+	cb.probesInFlight = 0
+	cb.successes = 0
+	switch state {
+	case CircuitOpen:
+		cb.trips++
+		cacheCircuitTrips.Inc()
+	case CircuitClosed:
+		atomic.StoreInt64(&cb.failures, 0)
+	}
+	cacheCircuitState.Set(float64(state))
+}
+
 func NewCacheWarmupManager(cache *EnterpriseCache, logger *zap.Logger) *CacheWarmupManager {
 	return &CacheWarmupManager{
 		cache:      cache,
@@ -1482,12 +1800,16 @@ func NewMemoryBackend(maxEntries int) *MemoryBackend {
 func (mb *MemoryBackend) Get(key string) (*CacheEntry, error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
+	return mb.getLocked(key)
+}
 
+// getLocked looks up key; the caller holds mb.mu
+func (mb *MemoryBackend) getLocked(key string) (*CacheEntry, error) {
 	ele, exists := mb.entries[key]
 	if !exists {
 		atomic.AddInt64(&mb.stats.Misses, 1)
 		atomic.AddInt64(&mb.stats.Operations, 1)
-		return nil, fmt.Errorf("key not found")
+		return nil, ErrCacheMiss
 	}
 
 	entry := ele.Value.(*CacheEntry)
@@ -1497,9 +1819,10 @@ func (mb *MemoryBackend) Get(key string) (*CacheEntry, error) {
 		// Remove expired entry
 		mb.lru.Remove(ele)
 		delete(mb.entries, key)
+		mb.removed(1)
 		atomic.AddInt64(&mb.stats.Misses, 1)
 		atomic.AddInt64(&mb.stats.Operations, 1)
-		return nil, fmt.Errorf("entry expired")
+		return nil, ErrCacheExpired
 	}
 
 	// Move to front as most recently used
@@ -1520,6 +1843,7 @@ func (mb *MemoryBackend) Set(key string, entry *CacheEntry) error {
 	if ele, exists := mb.entries[key]; exists {
 		ele.Value = entry
 		mb.lru.MoveToFront(ele)
+		mb.trackExpiry(entry)
 		atomic.AddInt64(&mb.stats.Operations, 1)
 		return nil
 	}
@@ -1531,11 +1855,14 @@ func (mb *MemoryBackend) Set(key string, entry *CacheEntry) error {
 			oldEntry := lruEle.Value.(*CacheEntry)
 			delete(mb.entries, oldEntry.Key)
 			mb.lru.Remove(lruEle)
+			mb.removed(1)
 		}
 	}
 
 	ele := mb.lru.PushFront(entry)
 	mb.entries[key] = ele
+	mb.inserted()
+	mb.trackExpiry(entry)
 	atomic.AddInt64(&mb.stats.Operations, 1)
 	return nil
 }
@@ -1546,17 +1873,24 @@ func (mb *MemoryBackend) Set(key string, entry *CacheEntry) error {
 func (mb *MemoryBackend) setWithAdmission(c *EnterpriseCache, key string, entry CacheEntry) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
+	mb.setWithAdmissionLocked(c, key, entry)
+}
 
+// setWithAdmissionLocked is setWithAdmission for callers already holding mb.mu
+func (mb *MemoryBackend) setWithAdmissionLocked(c *EnterpriseCache, key string, entry CacheEntry) {
 	c.touchKey(key)
 	if ele, exists := mb.entries[key]; exists {
 		ele.Value = &entry
 		mb.lru.MoveToFront(ele)
+		mb.trackExpiry(&entry)
 		return
 	}
 
 	if mb.lru.Len() < mb.maxSize || mb.maxSize == 0 {
 		ele := mb.lru.PushFront(&entry)
 		mb.entries[key] = ele
+		mb.inserted()
+		mb.trackExpiry(&entry)
 		return
 	}
 
@@ -1565,6 +1899,8 @@ func (mb *MemoryBackend) setWithAdmission(c *EnterpriseCache, key string, entry
 	if lruEle == nil {
 		ele := mb.lru.PushFront(&entry)
 		mb.entries[key] = ele
+		mb.inserted()
+		mb.trackExpiry(&entry)
 		return
 	}
 	victim := lruEle.Value.(*CacheEntry)
@@ -1573,8 +1909,11 @@ func (mb *MemoryBackend) setWithAdmission(c *EnterpriseCache, key string, entry
 		// evict victim
 		delete(mb.entries, victimKey)
 		mb.lru.Remove(lruEle)
+		mb.removed(1)
 		ele := mb.lru.PushFront(&entry)
 		mb.entries[key] = ele
+		mb.inserted()
+		mb.trackExpiry(&entry)
 	} else {
 		// rejected candidate; record op and return
 		atomic.AddInt64(&mb.stats.Operations, 1)
@@ -1589,6 +1928,7 @@ func (mb *MemoryBackend) Delete(key string) error {
 	if ele, exists := mb.entries[key]; exists {
 		mb.lru.Remove(ele)
 		delete(mb.entries, key)
+		mb.removed(1)
 	}
 	atomic.AddInt64(&mb.stats.Operations, 1)
 	return nil
@@ -1598,8 +1938,11 @@ func (mb *MemoryBackend) Clear() error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
+	mb.removed(len(mb.entries))
 	mb.entries = make(map[string]*list.Element)
 	mb.lru.Init()
+	mb.expiries = nil
+	mb.peak = 0
 	atomic.AddInt64(&mb.stats.Operations, 1)
 
 	return nil
@@ -1622,6 +1965,7 @@ func (mb *MemoryBackend) Stats() BackendStats {
 	mb.mu.RLock()
 	stats := mb.stats
 	stats.Entries = int64(len(mb.entries))
+	stats.PeakEntries = int64(mb.peak)
 	// Collect sizes of entries while holding the lock to ensure consistency
 	var total int64
 	for _, ele := range mb.entries {
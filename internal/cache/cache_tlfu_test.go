@@ -1,12 +1,15 @@
 package cache
 
 import (
+	"container/list"
 	"context"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 )
 
 // fakeClock for deterministic test timing
@@ -104,6 +107,171 @@ func TestSWRReturnsStaleThenRefreshes(t *testing.T) {
 
 }
 
+func TestLoaderPopulatesGet(t *testing.T) {
+	c, _ := NewEnterpriseCache(smallConfig(), nil)
+	fc := &fakeClock{t: time.Now()}
+	c.SetClock(fc)
+	var calls int32
+	var val atomic.Value
+	val.Store("v1")
+	err := c.SetLoader("acct:", LoaderPolicy{
+		TTL:         time.Minute,
+		StaleTTL:    time.Minute,
+		NegativeTTL: time.Minute,
+		Loader: func(ctx context.Context, key string) (any, error) {
+			atomic.AddInt32(&calls, 1)
+			if key == "acct:missing" {
+				return nil, ErrNotFound
+			}
+			time.Sleep(10 * time.Millisecond)
+			return val.Load().(string), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent misses through plain Get share one load
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, ok := c.Get("acct:a"); !ok || v.(string) != "v1" {
+				t.Errorf("Get = %v %v", v, ok)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("loader called %d times; want 1", got)
+	}
+
+	// Not-found results are remembered
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.Load(context.Background(), "acct:missing"); err != ErrNotFound {
+			t.Fatalf("Load missing err = %v", err)
+		}
+	}
+	if _, ok := c.Get("acct:missing"); ok {
+		t.Fatal("negative entry returned as a hit")
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("loader called %d times after negative lookups; want 2", got)
+	}
+
+	// Past TTL the stale value is served while it reloads in the background
+	val.Store("v2")
+	fc.Advance(90 * time.Second)
+	if v, hit, err := c.Load(context.Background(), "acct:a"); err != nil || !hit || v.(string) != "v1" {
+		t.Fatalf("stale Load = %v %v %v", v, hit, err)
+	}
+	select {
+	case <-c.RefreshNotify():
+	case <-time.After(time.Second):
+		t.Fatal("revalidation did not complete")
+	}
+	if v, _ := c.Get("acct:a"); v.(string) != "v2" {
+		t.Fatalf("want revalidated v2, got %v", v)
+	}
+
+	// Keys without a loader still miss
+	if _, _, err := c.Load(context.Background(), "other"); err != ErrCacheMiss {
+		t.Fatalf("Load without loader err = %v", err)
+	}
+}
+
+func TestPluginTiers(t *testing.T) {
+	cfg := smallConfig()
+	cfg.L2Backend = &BackendConfig{Backend: "memory"}
+	cfg.L3Backend = &BackendConfig{Backend: "memory"}
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("block:1", "abc", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// An L1 miss is served from L2 and promoted back into L1
+	c.levels[L1Memory].Delete("block:1")
+	if v, ok := c.Get("block:1"); !ok || v.(string) != "abc" {
+		t.Fatalf("L2 Get = %v %v", v, ok)
+	}
+	if c.getFromL1("block:1") == nil {
+		t.Fatal("L2 hit not promoted to L1")
+	}
+
+	// An L3 hit also backfills L2
+	c.levels[L1Memory].Delete("block:1")
+	c.levels[L2Disk].Delete("block:1")
+	if v, ok := c.Get("block:1"); !ok || v.(string) != "abc" {
+		t.Fatalf("L3 Get = %v %v", v, ok)
+	}
+	if e, err := c.levels[L2Disk].Get("block:1"); err != nil || e.Value.(string) != "abc" {
+		t.Fatalf("L2 after backfill = %v %v", e, err)
+	}
+	if m := c.GetMetrics(); m.L2Hits != 1 || m.L3Hits != 1 {
+		t.Fatalf("tier hits L2=%d L3=%d", m.L2Hits, m.L3Hits)
+	}
+
+	cfg.L2Backend = &BackendConfig{Backend: "no-such-store"}
+	if _, err := NewEnterpriseCache(cfg, nil); err == nil {
+		t.Fatal("unknown tier backend accepted")
+	}
+}
+
+func TestWriteBehindTiers(t *testing.T) {
+	cfg := smallConfig()
+	cfg.L2Backend = &BackendConfig{Backend: "memory"}
+	cfg.Namespaces = []NamespaceConfig{{Prefix: "fee:", WritePolicy: WriteBehind}}
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("fee:btc", "12", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if e, err := c.levels[L2Disk].Get("fee:btc"); err == nil && e.Value.(string) == "12" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("write-behind entry never reached L2")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Bounds are enforced on a queue with no worker draining it
+	fc := &fakeClock{t: time.Now()}
+	c.SetClock(fc)
+	q := &writeBehindQueue{ec: c, prefix: "test:", limit: 2, maxLag: time.Second,
+		order: list.New(), keys: make(map[string]*list.Element), notify: make(chan struct{}, 1)}
+	for _, key := range []string{"test:a", "test:b", "test:b", "test:c"} {
+		q.enqueue(key, &CacheEntry{Key: key, Value: key, ExpiresAt: fc.Now().Add(time.Minute)})
+	}
+	if q.pending() != 2 || q.lost != 1 {
+		t.Fatalf("after overflow pending=%d lost=%d, want 2 and 1", q.pending(), q.lost)
+	}
+	if _, ok := q.get("test:a"); ok {
+		t.Fatal("oldest write survived overflow")
+	}
+	fc.Advance(2 * time.Second)
+	if !q.writeNext() || q.lost != 2 {
+		t.Fatalf("write past max lag not dropped: lost=%d", q.lost)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if left := q.flush(ctx); left != 1 || q.lost != 3 || q.pending() != 0 {
+		t.Fatalf("flush after deadline left=%d lost=%d pending=%d", left, q.lost, q.pending())
+	}
+
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestTinyLFUProtectsHotVictim(t *testing.T) {
 	c, _ := NewEnterpriseCache(smallConfig(), nil)
 	fc := &fakeClock{t: time.Now()}
@@ -125,3 +293,174 @@ func TestTinyLFUProtectsHotVictim(t *testing.T) {
 		t.Fatal("hot key evicted by cold candidate; TinyLFU admission broken")
 	}
 }
+
+func TestRefreshAheadReloadsHotKey(t *testing.T) {
+	c, _ := NewEnterpriseCache(smallConfig(), nil)
+	fc := &fakeClock{t: time.Now()}
+	c.SetClock(fc)
+	var val atomic.Value
+	val.Store("v1")
+	err := c.SetRefreshAhead("fee:", RefreshAheadPolicy{
+		Threshold: 0.25,
+		Loader:    func(ctx context.Context, key string) (any, error) { return val.Load().(string), nil },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = c.Set("fee:btc", "v1", time.Minute)
+	val.Store("v2")
+
+	// Plenty of TTL left: no refresh
+	if v, ok := c.Get("fee:btc"); !ok || v.(string) != "v1" {
+		t.Fatalf("get: %v %v", v, ok)
+	}
+	select {
+	case k := <-c.RefreshNotify():
+		t.Fatalf("unexpected early refresh of %s", k)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// Inside the refresh window the stale value is served and reloaded behind
+	fc.Advance(50 * time.Second)
+	if v, ok := c.Get("fee:btc"); !ok || v.(string) != "v1" {
+		t.Fatalf("get in window: %v %v", v, ok)
+	}
+	select {
+	case <-c.RefreshNotify():
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("refresh-ahead did not run")
+	}
+	if v, ok := c.Get("fee:btc"); !ok || v.(string) != "v2" {
+		t.Fatalf("want refreshed v2, got %v %v", v, ok)
+	}
+}
+
+func TestNamespaceBudgetAndCompression(t *testing.T) {
+	cfg := smallConfig()
+	cfg.Namespaces = []NamespaceConfig{
+		{Prefix: "block:", DefaultTTL: time.Minute, MaxEntries: 2, Admission: AdmissionAlways},
+		{Prefix: "acct:", DefaultTTL: time.Minute, Compression: CompressionGzip, CompressionThreshold: 8},
+	}
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ttl 0 picks up the namespace default
+	for i := 1; i <= 3; i++ {
+		_ = c.Set(fmt.Sprintf("block:%d", i), i, 0)
+	}
+	if _, ok := c.Get("block:1"); ok {
+		t.Fatal("oldest block key survived namespace budget")
+	}
+	if v, ok := c.Get("block:3"); !ok || v.(int) != 3 {
+		t.Fatalf("block:3 = %v %v", v, ok)
+	}
+
+	_ = c.Set("acct:a", map[string]interface{}{"balance": "123456789"}, 0)
+	v, ok := c.Get("acct:a")
+	if !ok || v.(map[string]interface{})["balance"] != "123456789" {
+		t.Fatalf("compressed acct:a = %v %v", v, ok)
+	}
+
+	stats := c.NamespaceStats()
+	if stats["block:"].Entries != 2 || stats["block:"].Evictions != 1 || stats["block:"].Misses != 1 {
+		t.Fatalf("block stats = %+v", stats["block:"])
+	}
+}
+
+func TestNamespaceTTLRules(t *testing.T) {
+	cfg := smallConfig()
+	cfg.Namespaces = []NamespaceConfig{{
+		Prefix:     "fee:",
+		DefaultTTL: time.Minute,
+		MaxTTL:     2 * time.Minute,
+		TTLRules: []TTLRule{
+			{Name: "fast-blocks", Chain: "bitcoin", BlockIntervalBelow: 5 * time.Minute, TTL: 10 * time.Second},
+			{Name: "us-open", From: "13:30", To: "20:00", Factor: 0.5},
+			{Name: "overnight", From: "22:00", To: "02:00", Factor: 4},
+		},
+	}}
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &fakeClock{t: time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)}
+	c.SetClock(fc)
+
+	ttl := func() time.Duration {
+		e, err := c.createCacheEntry("fee:bitcoin", 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e.ExpiresAt.Sub(e.CreatedAt)
+	}
+	if got := ttl(); got != time.Minute {
+		t.Fatalf("no rule: ttl = %v", got)
+	}
+	fc.Advance(5 * time.Hour) // 14:00
+	if got := ttl(); got != 30*time.Second {
+		t.Fatalf("us-open: ttl = %v", got)
+	}
+	fc.Advance(9 * time.Hour) // 23:00, capped by MaxTTL
+	if got := ttl(); got != 2*time.Minute {
+		t.Fatalf("overnight: ttl = %v", got)
+	}
+
+	// Blocks every two minutes take precedence until the chain goes quiet
+	for i := 0; i < 3; i++ {
+		_ = c.SetLatestBlock(blocks.BlockEvent{Chain: "bitcoin", Hash: fmt.Sprint(i)})
+		fc.Advance(2 * time.Minute)
+	}
+	if got := ttl(); got != 10*time.Second {
+		t.Fatalf("fast-blocks: ttl = %v", got)
+	}
+	fc.Advance(10 * time.Minute)
+	if got := ttl(); got != 2*time.Minute {
+		t.Fatalf("quiet chain: ttl = %v", got)
+	}
+
+	cfg.Namespaces[0].TTLRules = []TTLRule{{Name: "bad", From: "25:00", To: "01:00", Factor: 2}}
+	if err := c.SetNamespace(cfg.Namespaces[0]); err == nil {
+		t.Fatal("invalid TTL rule accepted")
+	}
+}
+
+func TestSetLatestBlockCoalesces(t *testing.T) {
+	cfg := smallConfig()
+	cfg.LatestBlockCoalesce = 50 * time.Millisecond
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Shutdown(context.Background())
+
+	key := "latest_block_" + string(blocks.ChainSolana)
+	for slot := uint32(1); slot <= 5; slot++ {
+		ev := blocks.BlockEvent{Chain: blocks.ChainSolana, Hash: fmt.Sprintf("slot:%d", slot), Height: slot}
+		if err := c.SetLatestBlock(ev); err != nil {
+			t.Fatal(err)
+		}
+		_ = c.SetLatestBlock(ev) // Same hash again: no write
+		if got, ok := c.GetLatestBlock(); !ok || got.Height != slot {
+			t.Fatalf("latest = %d, %v; want %d", got.Height, ok, slot)
+		}
+	}
+
+	// Only slot 1 has been written through; the rest wait for the window
+	v, ok := c.Get(key)
+	if !ok || v.(BlockCache).Block.Height != 1 {
+		t.Fatalf("cache entry before flush = %+v, %v", v, ok)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if v, ok := c.Get(key); ok && v.(BlockCache).Block.Height == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("coalesced latest block was never written")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
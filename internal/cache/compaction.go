@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"container/list"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Compaction and fragmentation reporting
+//
+// A long-running cache can hold far more memory than its live entries
+// need, for two different reasons. Go maps never shrink, so shards that
+// once held many more entries keep bucket arrays sized for that peak; and
+// the heap keeps spans that were freed but not yet returned to the OS.
+// CompactionStats separates the two from a genuine leak (live bytes that
+// keep growing), and Compact rebuilds the maps and releases the memory.
+
+var (
+	cacheLiveBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_live_bytes",
+		Help: "Bytes held by live cache entries",
+	})
+	cacheMapSlack = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_map_slack_ratio",
+		Help: "Share of entry map capacity left empty since the peak (0 none, 1 all)",
+	})
+	cacheChurn = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cache_entry_churn_per_second",
+		Help: "Entry inserts plus removals per second over the last metrics interval",
+	})
+	cacheCompactions = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cache_compactions_total",
+		Help: "Compact calls",
+	})
+	cacheCompactionReclaimed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "cache_compaction_reclaimed_bytes_total",
+		Help: "Memory returned to the OS by Compact",
+	})
+)
+
+// CompactionStats relates the cache's live data to the process heap
+type CompactionStats struct {
+	LiveEntries int64 `json:"live_entries"`
+	LiveBytes   int64 `json:"live_bytes"`   // Sum of entry sizes
+	PeakEntries int64 `json:"peak_entries"` // Since the last compaction
+	// MapSlack is 1 - LiveEntries/PeakEntries: how much of the entry maps'
+	// capacity is empty. High slack is what Compact recovers.
+	MapSlack float64 `json:"map_slack"`
+
+	Inserts     int64   `json:"inserts"`
+	Removals    int64   `json:"removals"`
+	ChurnPerSec float64 `json:"churn_per_sec"` // Over the last metrics interval
+
+	// Process heap, from runtime.MemStats
+	HeapAlloc    int64 `json:"heap_alloc"`    // Bytes in reachable or not yet collected objects
+	HeapInuse    int64 `json:"heap_inuse"`    // Bytes in spans with at least one object
+	HeapIdle     int64 `json:"heap_idle"`     // Bytes in spans with no objects
+	HeapReleased int64 `json:"heap_released"` // Idle bytes returned to the OS
+	// Fragmentation is the share of in-use spans not holding objects
+	Fragmentation float64 `json:"fragmentation"`
+	// LiveRatio is LiveBytes/HeapAlloc. A falling ratio with flat live
+	// bytes points at fragmentation or other allocations, not the cache.
+	LiveRatio float64 `json:"live_ratio"`
+
+	Compactions    int64      `json:"compactions"`
+	LastCompaction *time.Time `json:"last_compaction,omitempty"`
+	LastReclaimed  int64      `json:"last_reclaimed"` // Bytes returned to the OS by the last Compact
+}
+
+// CompactionResult reports one Compact call
+type CompactionResult struct {
+	Entries        int64         `json:"entries"`
+	PeakEntries    int64         `json:"peak_entries"`    // Before compaction
+	RetainedBefore int64         `json:"retained_before"` // Heap bytes held from the OS
+	RetainedAfter  int64         `json:"retained_after"`
+	Reclaimed      int64         `json:"reclaimed"`
+	Duration       time.Duration `json:"duration"`
+}
+
+// compactionTracker keeps churn samples and compaction history
+type compactionTracker struct {
+	mu            sync.Mutex
+	lastChurn     int64
+	lastSample    time.Time
+	churnPerSec   float64
+	compactions   int64
+	lastAt        time.Time
+	lastReclaimed int64
+}
+
+// compacter is implemented by backends whose maps can be rebuilt
+type compacter interface {
+	compact()
+}
+
+// inserted records a new entry; the caller holds mb.mu
+func (mb *MemoryBackend) inserted() {
+	atomic.AddInt64(&mb.stats.Inserts, 1)
+	if n := len(mb.entries); n > mb.peak {
+		mb.peak = n
+	}
+}
+
+// removed records n entries leaving; the caller holds mb.mu
+func (mb *MemoryBackend) removed(n int) {
+	atomic.AddInt64(&mb.stats.Removals, int64(n))
+}
+
+// compact copies the entries into a map sized for them, dropping the
+// buckets left over from the peak
+func (mb *MemoryBackend) compact() {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	entries := make(map[string]*list.Element, len(mb.entries))
+	for key, ele := range mb.entries {
+		entries[key] = ele
+	}
+	mb.entries = entries
+	mb.rebuildExpiries()
+	mb.peak = len(entries)
+}
+
+// compact rebuilds one shard at a time so lookups elsewhere continue
+func (s *ShardedMemoryBackend) compact() {
+	for _, sh := range s.shards {
+		sh.compact()
+	}
+}
+
+// backendTotals sums the stats of every level
+func (ec *EnterpriseCache) backendTotals() BackendStats {
+	var agg BackendStats
+	for _, backend := range ec.levels {
+		st := backend.Stats()
+		agg.Entries += st.Entries
+		agg.Size += st.Size
+		agg.Inserts += st.Inserts
+		agg.Removals += st.Removals
+		agg.PeakEntries += st.PeakEntries
+	}
+	return agg
+}
+
+// CompactionStats returns live data against heap usage
+func (ec *EnterpriseCache) CompactionStats() CompactionStats {
+	totals := ec.backendTotals()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	st := CompactionStats{
+		LiveEntries:  totals.Entries,
+		LiveBytes:    totals.Size,
+		PeakEntries:  totals.PeakEntries,
+		Inserts:      totals.Inserts,
+		Removals:     totals.Removals,
+		HeapAlloc:    int64(ms.HeapAlloc),
+		HeapInuse:    int64(ms.HeapInuse),
+		HeapIdle:     int64(ms.HeapIdle),
+		HeapReleased: int64(ms.HeapReleased),
+	}
+	if st.PeakEntries > 0 {
+		st.MapSlack = 1 - float64(st.LiveEntries)/float64(st.PeakEntries)
+	}
+	if ms.HeapInuse > 0 && ms.HeapInuse > ms.HeapAlloc {
+		st.Fragmentation = float64(ms.HeapInuse-ms.HeapAlloc) / float64(ms.HeapInuse)
+	}
+	if ms.HeapAlloc > 0 {
+		st.LiveRatio = float64(st.LiveBytes) / float64(ms.HeapAlloc)
+	}
+
+	ec.compaction.mu.Lock()
+	st.ChurnPerSec = ec.compaction.churnPerSec
+	st.Compactions = ec.compaction.compactions
+	st.LastReclaimed = ec.compaction.lastReclaimed
+	if !ec.compaction.lastAt.IsZero() {
+		at := ec.compaction.lastAt
+		st.LastCompaction = &at
+	}
+	ec.compaction.mu.Unlock()
+
+	cacheLiveBytes.Set(float64(st.LiveBytes))
+	cacheMapSlack.Set(st.MapSlack)
+	return st
+}
+
+// sampleChurn updates the churn rate; the metrics worker calls it every
+// MetricsInterval
+func (ec *EnterpriseCache) sampleChurn() {
+	totals := ec.backendTotals()
+	churn := totals.Inserts + totals.Removals
+	t := ec.clock.Now()
+
+	ec.compaction.mu.Lock()
+	defer ec.compaction.mu.Unlock()
+	if !ec.compaction.lastSample.IsZero() {
+		if elapsed := t.Sub(ec.compaction.lastSample).Seconds(); elapsed > 0 {
+			ec.compaction.churnPerSec = float64(churn-ec.compaction.lastChurn) / elapsed
+			cacheChurn.Set(ec.compaction.churnPerSec)
+		}
+	}
+	ec.compaction.lastChurn = churn
+	ec.compaction.lastSample = t
+}
+
+// Compact rebuilds the entry maps at their live size and returns free heap
+// memory to the OS. Shards are rebuilt one at a time, each locked only
+// while its own map is copied, but the final debug.FreeOSMemory is a full
+// stop-the-world collection, so prefer running it off-peak.
+func (ec *EnterpriseCache) Compact() CompactionResult {
+	start := time.Now()
+	before := ec.backendTotals()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	retainedBefore := int64(ms.HeapSys - ms.HeapReleased)
+
+	for _, backend := range ec.levels {
+		if c, ok := backend.(compacter); ok {
+			c.compact()
+		}
+	}
+	debug.FreeOSMemory()
+
+	runtime.ReadMemStats(&ms)
+	res := CompactionResult{
+		Entries:        ec.backendTotals().Entries,
+		PeakEntries:    before.PeakEntries,
+		RetainedBefore: retainedBefore,
+		RetainedAfter:  int64(ms.HeapSys - ms.HeapReleased),
+		Duration:       time.Since(start),
+	}
+	if res.RetainedBefore > res.RetainedAfter {
+		res.Reclaimed = res.RetainedBefore - res.RetainedAfter
+	}
+
+	ec.compaction.mu.Lock()
+	ec.compaction.compactions++
+	ec.compaction.lastAt = ec.clock.Now()
+	ec.compaction.lastReclaimed = res.Reclaimed
+	ec.compaction.mu.Unlock()
+	cacheCompactions.Inc()
+	cacheCompactionReclaimed.Add(float64(res.Reclaimed))
+
+	ec.logger.Info("Cache compacted",
+		zap.Int64("entries", res.Entries),
+		zap.Int64("peak_entries", res.PeakEntries),
+		zap.Int64("reclaimed_bytes", res.Reclaimed),
+		zap.Duration("took", res.Duration))
+	return res
+}
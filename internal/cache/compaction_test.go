@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCompactionStatsAndCompact(t *testing.T) {
+	cfg := smallConfig()
+	cfg.MaxEntries = 1000
+	cfg.ShardCount = 4
+	cfg.EnableCircuitBreaker = false
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc := &fakeClock{t: time.Now()}
+	c.SetClock(fc)
+
+	for i := 0; i < 200; i++ {
+		if err := c.Set(fmt.Sprintf("k%d", i), i, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.sampleChurn()
+	for i := 0; i < 150; i++ {
+		c.levels[L1Memory].Delete(fmt.Sprintf("k%d", i))
+	}
+	fc.t = fc.t.Add(10 * time.Second)
+	c.sampleChurn()
+
+	st := c.GetMetrics().Compaction
+	if st.LiveEntries != 50 || st.Inserts != 200 || st.Removals != 150 {
+		t.Fatalf("entries %d, inserts %d, removals %d", st.LiveEntries, st.Inserts, st.Removals)
+	}
+	if st.PeakEntries < 200 || st.MapSlack < 0.7 {
+		t.Fatalf("peak %d, slack %.2f", st.PeakEntries, st.MapSlack)
+	}
+	if st.ChurnPerSec != 15 {
+		t.Fatalf("churn %.2f/s, want 15", st.ChurnPerSec)
+	}
+
+	res := c.Compact()
+	if res.Entries != 50 || res.PeakEntries != st.PeakEntries {
+		t.Fatalf("compact result %+v", res)
+	}
+	after := c.CompactionStats()
+	if after.PeakEntries != 50 || after.MapSlack != 0 || after.Compactions != 1 || after.LastCompaction == nil {
+		t.Fatalf("after compact %+v", after)
+	}
+	for i := 150; i < 200; i++ {
+		if v, ok := c.Get(fmt.Sprintf("k%d", i)); !ok || v != i {
+			t.Fatalf("k%d lost by compaction", i)
+		}
+	}
+}
+
+func TestMemoryPressureNotifications(t *testing.T) {
+	for _, tc := range []struct {
+		ratio   float64
+		current MemoryPressureLevel
+		want    MemoryPressureLevel
+	}{
+		{0.5, PressureNormal, PressureNormal},
+		{0.8, PressureNormal, PressureElevated},
+		{0.78, PressureElevated, PressureElevated}, // Within hysteresis
+		{0.74, PressureElevated, PressureNormal},
+		{1.3, PressureNormal, PressureCritical},
+		{0.97, PressureHigh, PressureHigh},
+		{0.9, PressureCritical, PressureElevated},
+	} {
+		if got := pressureLevel(tc.ratio, tc.current); got != tc.want {
+			t.Errorf("pressureLevel(%.2f, %s) = %s, want %s", tc.ratio, tc.current, got, tc.want)
+		}
+	}
+
+	cfg := smallConfig()
+	cfg.EnableCircuitBreaker = false
+	cfg.PressureInterval = time.Hour // Checked by hand below
+	c, err := NewEnterpriseCache(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []MemoryPressureLevel
+	c.OnMemoryPressure(func(l MemoryPressureLevel) { got = append(got, l) })
+
+	c.config.MemoryLimit = 1 // Any heap is far past the threshold
+	c.checkPressure()
+	c.checkPressure()
+	c.config.MemoryLimit = 1 << 50
+	c.checkPressure()
+
+	if len(got) != 2 || got[0] != PressureCritical || got[1] != PressureNormal {
+		t.Fatalf("notified %v, want [critical normal]", got)
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Proactive expiration
+//
+// Each MemoryBackend keeps a min-heap of entry expirations so the cleanup
+// worker can drop expired entries without waiting for a Get to touch them.
+// Overwrites and deletes leave their old heap items behind; those are
+// recognised and skipped when they surface, and the heap is rebuilt when
+// stale items outnumber live entries.
+
+var cacheExpiredReaped = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "cache_expired_reaped_total",
+	Help: "Expired entries removed by the cleanup worker before any lookup",
+})
+
+// maxReapPerPass bounds how long one shard's lock is held per cleanup pass
+const maxReapPerPass = 4096
+
+// expiryCompactSlack is how many stale heap items are tolerated beyond the
+// live entry count before the heap is rebuilt
+const expiryCompactSlack = 1024
+
+type expiryItem struct {
+	at  time.Time
+	key string
+}
+
+// expiryHeap orders items by expiration, earliest first
+type expiryHeap []expiryItem
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x any)        { *h = append(*h, x.(expiryItem)) }
+func (h *expiryHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// expirer is implemented by backends that can drop expired entries
+type expirer interface {
+	ReapExpired(t time.Time) int
+}
+
+// trackExpiry schedules entry for reaping; the caller holds mb.mu
+func (mb *MemoryBackend) trackExpiry(entry *CacheEntry) {
+	if len(mb.expiries) > 2*len(mb.entries)+expiryCompactSlack {
+		mb.rebuildExpiries()
+	}
+	heap.Push(&mb.expiries, expiryItem{at: entry.ExpiresAt, key: entry.Key})
+}
+
+// rebuildExpiries drops stale heap items; the caller holds mb.mu
+func (mb *MemoryBackend) rebuildExpiries() {
+	h := make(expiryHeap, 0, len(mb.entries))
+	for key, ele := range mb.entries {
+		h = append(h, expiryItem{at: ele.Value.(*CacheEntry).ExpiresAt, key: key})
+	}
+	heap.Init(&h)
+	mb.expiries = h
+}
+
+// ReapExpired removes entries that expired at or before t and returns how
+// many it removed. At most maxReapPerPass entries go per call.
+func (mb *MemoryBackend) ReapExpired(t time.Time) int {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	reaped := 0
+	for len(mb.expiries) > 0 && reaped < maxReapPerPass {
+		next := mb.expiries[0]
+		if next.at.After(t) {
+			break
+		}
+		heap.Pop(&mb.expiries)
+
+		ele, ok := mb.entries[next.key]
+		if !ok {
+			continue // Deleted or evicted
+		}
+		// An overwritten entry has its own, later heap item
+		if entry := ele.Value.(*CacheEntry); entry.ExpiresAt.After(t) {
+			continue
+		}
+		mb.lru.Remove(ele)
+		delete(mb.entries, next.key)
+		reaped++
+	}
+
+	if reaped > 0 {
+		mb.removed(reaped)
+		atomic.AddInt64(&mb.stats.Expired, int64(reaped))
+		cacheExpiredReaped.Add(float64(reaped))
+	}
+	return reaped
+}
+
+// ReapExpired reaps every shard
+func (s *ShardedMemoryBackend) ReapExpired(t time.Time) int {
+	total := 0
+	for _, sh := range s.shards {
+		total += sh.ReapExpired(t)
+	}
+	return total
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Every block on the bus reaches SetLatestBlock, and each full write
+// hashes, marshals and possibly compresses the block before storing it
+// twice. At Solana slot rates, with several relays announcing the same
+// slot, most of those writes are redundant. A block whose hash matches the
+// chain's last one only extends the entry's expiry; writes closer together
+// than LatestBlockCoalesce are folded into the newest of them, written
+// when the window closes. Readers of GetLatestBlock see every block at
+// once; only the regular cache entry lags by up to the window.
+
+var cacheLatestWrites = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_latest_block_writes_total",
+	Help: "Latest block updates per chain by outcome (written, coalesced, unchanged)",
+}, []string{"chain", "result"})
+
+// latestWrite is one chain's coalescing state. Fields other than writeMu
+// and done are guarded by EnterpriseCache.mu.
+type latestWrite struct {
+	hash    string             // Last block seen
+	written time.Time          // Last full write started
+	pending *blocks.BlockEvent // Newest block held back by the window
+	timer   *time.Timer        // Flushes pending when the window closes
+	seq     uint64             // Claims on a full write, in arrival order
+
+	writeMu sync.Mutex // Orders full writes
+	done    uint64     // Highest seq written
+}
+
+// SetLatestBlock updates the latest block for the block's chain,
+// coalescing redundant writes
+func (ec *EnterpriseCache) SetLatestBlock(block blocks.BlockEvent) error {
+	chain := string(block.Chain)
+	now := time.Now()
+
+	ec.mu.Lock()
+	if ec.latestWrites == nil {
+		ec.latestWrites = make(map[blocks.Chain]*latestWrite)
+	}
+	if ec.latestByChain == nil {
+		ec.latestByChain = make(map[blocks.Chain]blocks.BlockEvent)
+	}
+	w, ok := ec.latestWrites[block.Chain]
+	if !ok {
+		w = &latestWrite{}
+		ec.latestWrites[block.Chain] = w
+	}
+
+	if block.Hash != "" && block.Hash == w.hash {
+		if ec.latestBlock.Block.Chain == block.Chain && ec.latestBlock.Block.Hash == block.Hash {
+			ec.latestBlock.ExpiresAt = now.Add(ec.config.DefaultTTL)
+		}
+		ec.mu.Unlock()
+		cacheLatestWrites.WithLabelValues(chain, "unchanged").Inc()
+		return nil
+	}
+	w.hash = block.Hash
+	if block.TxID == "" && !block.Backfilled {
+		ec.activity.observe(chain, ec.clock.Now())
+	}
+	ec.latestByChain[block.Chain] = block
+
+	if window := ec.config.LatestBlockCoalesce; window > 0 && now.Sub(w.written) < window {
+		// Readers see the block now; the full write waits for the window
+		ec.latestBlock = BlockCache{
+			Block:        block,
+			CachedAt:     now,
+			ExpiresAt:    now.Add(ec.config.DefaultTTL),
+			LastAccessed: now,
+			Level:        L1Memory,
+		}
+		w.pending = &block
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.written.Add(window).Sub(now), func() { ec.flushLatestBlock(block.Chain) })
+		}
+		ec.mu.Unlock()
+		cacheLatestWrites.WithLabelValues(chain, "coalesced").Inc()
+		return nil
+	}
+
+	// A newer block supersedes anything pending
+	w.pending = nil
+	w.written = now
+	w.seq++
+	seq := w.seq
+	ec.mu.Unlock()
+	return ec.writeLatestOrdered(w, seq, block)
+}
+
+// flushLatestBlock writes the block held back for chain, if any
+func (ec *EnterpriseCache) flushLatestBlock(chain blocks.Chain) {
+	ec.mu.Lock()
+	w := ec.latestWrites[chain]
+	w.timer = nil
+	block := w.pending
+	w.pending = nil
+	if block == nil {
+		ec.mu.Unlock()
+		return
+	}
+	w.written = time.Now()
+	w.seq++
+	seq := w.seq
+	ec.mu.Unlock()
+
+	if err := ec.writeLatestOrdered(w, seq, *block); err != nil {
+		ec.logger.Debug("Failed to write coalesced latest block",
+			zap.String("chain", string(chain)), zap.Error(err))
+	}
+}
+
+// writeLatestOrdered runs the full write for the seq'th claim, unless a
+// later claim has already been written
+func (ec *EnterpriseCache) writeLatestOrdered(w *latestWrite, seq uint64, block blocks.BlockEvent) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if seq < w.done {
+		return nil
+	}
+	w.done = seq
+	cacheLatestWrites.WithLabelValues(string(block.Chain), "written").Inc()
+	return ec.writeLatestBlock(block)
+}
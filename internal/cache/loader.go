@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/reqid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var cacheLoaderLoads = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_loader_loads_total",
+	Help: "Loads run by registered namespace loaders by result",
+}, []string{"namespace", "result"}) // loaded, not_found, error, revalidated, revalidate_error
+
+// negativeMetadataKey marks an entry recording that the loader found nothing
+const negativeMetadataKey = "negative"
+
+// Loader loads the value for key on a cache miss
+type Loader func(ctx context.Context, key string) (any, error)
+
+// LoaderPolicy lets the cache populate misses for keys under a prefix
+// itself, so every reader gets the same stampede protection, staleness and
+// negative caching without passing a loader to GetOrLoad.
+type LoaderPolicy struct {
+	Loader Loader
+
+	TTL         time.Duration // Fresh lifetime of loaded values; 0 uses the namespace default
+	StaleTTL    time.Duration // Served stale this long past TTL while reloading in the background; 0 disables
+	NegativeTTL time.Duration // How long a loader's ErrNotFound is remembered; 0 disables
+	Timeout     time.Duration // Per-load timeout
+}
+
+type loaderRegistry struct {
+	mu           sync.RWMutex
+	policies     map[string]LoaderPolicy // prefix -> policy
+	revalidating sync.Map                // key -> struct{}, one background reload per key
+}
+
+func newLoaderRegistry() *loaderRegistry {
+	return &loaderRegistry{policies: make(map[string]LoaderPolicy)}
+}
+
+// SetLoader registers policy for keys starting with prefix. Get and Load
+// then populate misses for those keys by calling policy.Loader once per
+// key, however many readers are waiting. The longest matching prefix wins.
+func (ec *EnterpriseCache) SetLoader(prefix string, policy LoaderPolicy) error {
+	if prefix == "" {
+		return fmt.Errorf("loader prefix must not be empty")
+	}
+	if policy.Loader == nil {
+		return fmt.Errorf("loader policy for %q has no loader", prefix)
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = 10 * time.Second
+	}
+
+	ec.loaders.mu.Lock()
+	ec.loaders.policies[prefix] = policy
+	ec.loaders.mu.Unlock()
+	return nil
+}
+
+// RemoveLoader stops populating misses for prefix. Entries already loaded
+// stay until they expire.
+func (ec *EnterpriseCache) RemoveLoader(prefix string) {
+	ec.loaders.mu.Lock()
+	delete(ec.loaders.policies, prefix)
+	ec.loaders.mu.Unlock()
+}
+
+// loaderFor returns the policy for key, if any
+func (ec *EnterpriseCache) loaderFor(key string) (string, LoaderPolicy, bool) {
+	ec.loaders.mu.RLock()
+	defer ec.loaders.mu.RUnlock()
+
+	var (
+		best   string
+		policy LoaderPolicy
+		found  bool
+	)
+	for prefix, p := range ec.loaders.policies {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(best) {
+			best, policy, found = prefix, p, true
+		}
+	}
+	return best, policy, found
+}
+
+// Load returns the value for key, loading it through the registered loader
+// on a miss. hit reports whether it was served from the cache. Keys
+// without a loader behave like Get, returning ErrCacheMiss when absent;
+// values the loader did not find return ErrNotFound.
+func (ec *EnterpriseCache) Load(ctx context.Context, key string) (v any, hit bool, err error) {
+	if entry := ec.getFromL1(key); entry != nil {
+		ec.recordNamespaceLookup(key, true)
+		ec.refreshAheadOnHit(key, entry)
+		if isNegativeEntry(entry) {
+			return nil, true, ErrNotFound
+		}
+		ec.revalidateIfStale(key, entry)
+		v, _ := ec.deserializeEntry(entry)
+		return v, true, nil
+	}
+	ec.recordNamespaceLookup(key, false)
+	ec.refreshAheadOnMiss(key)
+	if v, ok := ec.getFromLowerTiers(key); ok {
+		return v, true, nil
+	}
+
+	prefix, policy, ok := ec.loaderFor(key)
+	if !ok {
+		return nil, false, ErrCacheMiss
+	}
+	v, err = ec.loadThrough(ctx, prefix, key, policy)
+	return v, false, err
+}
+
+// loadThrough runs policy's loader for key once for all concurrent callers
+func (ec *EnterpriseCache) loadThrough(ctx context.Context, prefix, key string, policy LoaderPolicy) (any, error) {
+	return ec.collapse(ctx, key, func() (any, error) {
+		// Another caller may have stored it while this one queued
+		if entry := ec.getFromL1(key); entry != nil && !isStaleEntry(entry, ec.clock.Now()) {
+			if isNegativeEntry(entry) {
+				return nil, ErrNotFound
+			}
+			v, _ := ec.deserializeEntry(entry)
+			return v, nil
+		}
+		return ec.runLoader(ctx, prefix, key, policy)
+	})
+}
+
+// loadOnMiss is Get's miss path: it loads key through its registered
+// loader, if any, bounded by the loader timeout
+func (ec *EnterpriseCache) loadOnMiss(key string) (interface{}, bool) {
+	prefix, policy, ok := ec.loaderFor(key)
+	if !ok {
+		return nil, false
+	}
+	v, err := ec.loadThrough(ec.ctx, prefix, key, policy)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// runLoader calls policy.Loader for key and stores the outcome
+func (ec *EnterpriseCache) runLoader(ctx context.Context, prefix, key string, policy LoaderPolicy) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, policy.Timeout)
+	defer cancel()
+
+	v, err := policy.Loader(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		cacheLoaderLoads.WithLabelValues(prefix, "not_found").Inc()
+		if policy.NegativeTTL > 0 {
+			if err := ec.storeLoaded(key, nil, policy.NegativeTTL, 0, true); err != nil {
+				ec.logger.Debug("Failed to cache negative load", zap.String("key", key), reqid.Field(ctx), zap.Error(err))
+			}
+		}
+		return nil, ErrNotFound
+	case err != nil:
+		cacheLoaderLoads.WithLabelValues(prefix, "error").Inc()
+		ec.logger.Debug("Cache load failed", zap.String("key", key), reqid.Field(ctx), zap.Error(err))
+		return nil, err
+	}
+
+	cacheLoaderLoads.WithLabelValues(prefix, "loaded").Inc()
+	ec.logger.Debug("Cache loaded", zap.String("key", key), reqid.Field(ctx))
+	if err := ec.storeLoaded(key, v, policy.TTL, policy.StaleTTL, false); err != nil {
+		ec.logger.Debug("Failed to cache loaded value", zap.String("key", key), reqid.Field(ctx), zap.Error(err))
+	}
+	return v, nil
+}
+
+// storeLoaded stores a loaded value that is fresh for ttl and kept stale
+// for a further stale
+func (ec *EnterpriseCache) storeLoaded(key string, value any, ttl, stale time.Duration, negative bool) error {
+	if ec.circuitBreaker != nil && !ec.circuitBreaker.AllowRequest() {
+		return ErrCircuitOpen
+	}
+	ns := ec.namespaceFor(key)
+	ttl = ec.policyTTL(ns, ttl)
+	entry, err := ec.newCacheEntry(ns, key, value, ttl+stale)
+	if err != nil {
+		return err
+	}
+	if stale > 0 {
+		entry.SoftExpiresAt = entry.CreatedAt.Add(ttl)
+	}
+	if negative {
+		entry.Metadata[negativeMetadataKey] = true
+	}
+	if err := ec.setToL1(key, entry); err != nil {
+		return err
+	}
+	ec.trackNamespaceWrite(key, entry.Size)
+	if !negative {
+		ec.writeLowerTiers(key, entry)
+	}
+	if ec.bloomFilter != nil {
+		ec.bloomFilter.Add(key)
+	}
+	return nil
+}
+
+// revalidateIfStale reloads entry in the background once it is past its
+// fresh lifetime; readers keep getting the stale value meanwhile
+func (ec *EnterpriseCache) revalidateIfStale(key string, entry *CacheEntry) {
+	if !isStaleEntry(entry, ec.clock.Now()) {
+		return
+	}
+	prefix, policy, ok := ec.loaderFor(key)
+	if !ok || policy.StaleTTL <= 0 {
+		return
+	}
+	if _, busy := ec.loaders.revalidating.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer ec.loaders.revalidating.Delete(key)
+		_, err := ec.collapse(ec.ctx, key, func() (any, error) {
+			return ec.runLoader(ec.ctx, prefix, key, policy)
+		})
+		if err != nil {
+			cacheLoaderLoads.WithLabelValues(prefix, "revalidate_error").Inc()
+		} else {
+			cacheLoaderLoads.WithLabelValues(prefix, "revalidated").Inc()
+		}
+		// non-blocking notify for tests
+		select {
+		case ec.refreshNotify <- key:
+		default:
+		}
+	}()
+}
+
+func isStaleEntry(entry *CacheEntry, now time.Time) bool {
+	return !entry.SoftExpiresAt.IsZero() && now.After(entry.SoftExpiresAt)
+}
+
+func isNegativeEntry(entry *CacheEntry) bool {
+	negative, _ := entry.Metadata[negativeMetadataKey].(bool)
+	return negative
+}
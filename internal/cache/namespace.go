@@ -0,0 +1,370 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	cacheNamespaceLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_namespace_lookups_total",
+		Help: "Cache lookups per namespace by result",
+	}, []string{"namespace", "result"})
+	cacheNamespaceEntries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cache_namespace_entries",
+		Help: "Tracked entries per namespace",
+	}, []string{"namespace"})
+	cacheNamespaceBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cache_namespace_bytes",
+		Help: "Tracked entry bytes per namespace",
+	}, []string{"namespace"})
+	cacheNamespaceEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_namespace_budget_evictions_total",
+		Help: "Entries evicted to keep a namespace within its size budget",
+	}, []string{"namespace"})
+)
+
+// defaultNamespace labels keys that match no configured namespace
+const defaultNamespace = "default"
+
+// AdmissionPolicy decides whether a new key may displace an L1 victim
+type AdmissionPolicy int
+
+const (
+	// AdmissionTinyLFU admits a key only if it is at least as frequent as the victim
+	AdmissionTinyLFU AdmissionPolicy = iota
+	// AdmissionAlways admits every key, for data that must be cached on first write
+	AdmissionAlways
+)
+
+// NamespaceConfig tunes caching for keys starting with Prefix, so e.g.
+// block data and account state can have different lifetimes and budgets.
+// Zero fields fall back to the cache-wide behaviour.
+type NamespaceConfig struct {
+	Prefix string `json:"prefix"`
+
+	DefaultTTL time.Duration `json:"default_ttl"` // Used when Set is called with ttl <= 0
+	MaxTTL     time.Duration `json:"max_ttl"`     // Caps caller TTLs
+
+	// Compression stores serialized values compressed once they exceed
+	// CompressionThreshold bytes. Compressed values are returned decoded
+	// from JSON, i.e. as maps, slices and float64s rather than their
+	// original Go types.
+	Compression          CompressionType `json:"compression"`
+	CompressionThreshold int64           `json:"compression_threshold"`
+
+	Admission AdmissionPolicy `json:"admission"`
+
+	MaxEntries int   `json:"max_entries"` // 0 = unlimited
+	MaxBytes   int64 `json:"max_bytes"`   // 0 = unlimited
+
+	// WritePolicy decides whether Set waits for the L2 and L3 tiers. Under
+	// WriteBehind at most WriteBehindQueue writes are queued, and writes
+	// queued longer than WriteBehindMaxLag are dropped rather than written
+	// late.
+	WritePolicy       WritePolicy   `json:"write_policy"`
+	WriteBehindQueue  int           `json:"write_behind_queue"`   // 0 = 10000
+	WriteBehindMaxLag time.Duration `json:"write_behind_max_lag"` // 0 = 30s
+
+	// TTLRules adjust TTLs by time of day or chain activity; the first
+	// matching rule wins
+	TTLRules []TTLRule `json:"ttl_rules,omitempty"`
+}
+
+// DefaultNamespaces returns the standard block, fee and account namespaces
+func DefaultNamespaces() []NamespaceConfig {
+	return []NamespaceConfig{
+		{
+			Prefix:     "block:",
+			DefaultTTL: 10 * time.Minute,
+			Admission:  AdmissionAlways,
+			MaxEntries: 20000,
+		},
+		{
+			Prefix:     "fee:",
+			DefaultTTL: 30 * time.Second,
+			MaxTTL:     2 * time.Minute,
+			Admission:  AdmissionAlways,
+			MaxEntries: 1000,
+		},
+		{
+			Prefix:               "acct:",
+			DefaultTTL:           15 * time.Second,
+			MaxTTL:               time.Minute,
+			Compression:          CompressionGzip,
+			CompressionThreshold: 4096,
+			MaxBytes:             256 << 20,
+		},
+	}
+}
+
+// NamespaceStats is the tracked usage of one namespace
+type NamespaceStats struct {
+	Entries   int64 `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"`
+
+	WriteBehindPending int64 `json:"write_behind_pending,omitempty"`
+	WriteBehindLost    int64 `json:"write_behind_lost,omitempty"`
+}
+
+// cacheNamespace tracks keys written to one namespace in insertion order,
+// so budget evictions drop the oldest keys first. Keys that expired or
+// were evicted by L1 on their own are only forgotten when they reach the
+// front of the queue; deleting them again is harmless.
+type cacheNamespace struct {
+	cfg      NamespaceConfig
+	behind   *writeBehindQueue // Set under WriteBehind
+	ttlRules []ttlRule
+
+	mu    sync.Mutex
+	order *list.List               // of *namespaceKey, oldest first
+	keys  map[string]*list.Element // key -> element in order
+	bytes int64
+
+	hits, misses, evictions int64
+}
+
+type namespaceKey struct {
+	key  string
+	size int64
+}
+
+type namespaceRegistry struct {
+	mu         sync.RWMutex
+	namespaces map[string]*cacheNamespace // prefix -> namespace
+}
+
+func newNamespaceRegistry() *namespaceRegistry {
+	return &namespaceRegistry{namespaces: make(map[string]*cacheNamespace)}
+}
+
+// SetNamespace adds or replaces the configuration for a key prefix.
+// Replacing a namespace resets its tracked usage; writes it still had
+// queued for the tiers are written out in the background.
+func (ec *EnterpriseCache) SetNamespace(cfg NamespaceConfig) error {
+	if cfg.Prefix == "" {
+		return fmt.Errorf("cache namespace prefix must not be empty")
+	}
+	if cfg.Compression != CompressionNone && cfg.Compression != CompressionGzip {
+		return fmt.Errorf("namespace %q: unsupported compression type: %v", cfg.Prefix, cfg.Compression)
+	}
+	if cfg.WritePolicy != WriteThrough && cfg.WritePolicy != WriteBehind {
+		return fmt.Errorf("namespace %q: unsupported write policy: %v", cfg.Prefix, cfg.WritePolicy)
+	}
+	rules, err := compileTTLRules(cfg.TTLRules)
+	if err != nil {
+		return fmt.Errorf("namespace %q: %w", cfg.Prefix, err)
+	}
+
+	ns := &cacheNamespace{
+		cfg:      cfg,
+		order:    list.New(),
+		keys:     make(map[string]*list.Element),
+		ttlRules: rules,
+	}
+	if cfg.WritePolicy == WriteBehind {
+		ns.behind = ec.newWriteBehindQueue(cfg)
+	}
+	ec.namespaces.mu.Lock()
+	old := ec.namespaces.namespaces[cfg.Prefix]
+	ec.namespaces.namespaces[cfg.Prefix] = ns
+	ec.namespaces.mu.Unlock()
+	if old != nil && old.behind != nil {
+		close(old.behind.stop)
+	}
+	return nil
+}
+
+// NamespaceStats returns tracked usage per namespace prefix
+func (ec *EnterpriseCache) NamespaceStats() map[string]NamespaceStats {
+	ec.namespaces.mu.RLock()
+	defer ec.namespaces.mu.RUnlock()
+
+	out := make(map[string]NamespaceStats, len(ec.namespaces.namespaces))
+	for prefix, ns := range ec.namespaces.namespaces {
+		ns.mu.Lock()
+		st := NamespaceStats{
+			Entries:   int64(ns.order.Len()),
+			Bytes:     ns.bytes,
+			Hits:      atomic.LoadInt64(&ns.hits),
+			Misses:    atomic.LoadInt64(&ns.misses),
+			Evictions: atomic.LoadInt64(&ns.evictions),
+		}
+		ns.mu.Unlock()
+		if ns.behind != nil {
+			st.WriteBehindPending = ns.behind.pending()
+			st.WriteBehindLost = atomic.LoadInt64(&ns.behind.lost)
+		}
+		out[prefix] = st
+	}
+	return out
+}
+
+// namespaceFor returns the namespace with the longest prefix matching key
+func (ec *EnterpriseCache) namespaceFor(key string) *cacheNamespace {
+	ec.namespaces.mu.RLock()
+	defer ec.namespaces.mu.RUnlock()
+
+	var best *cacheNamespace
+	for prefix, ns := range ec.namespaces.namespaces {
+		if strings.HasPrefix(key, prefix) && (best == nil || len(prefix) > len(best.cfg.Prefix)) {
+			best = ns
+		}
+	}
+	return best
+}
+
+// recordNamespaceLookup counts a hit or miss against key's namespace
+func (ec *EnterpriseCache) recordNamespaceLookup(key string, hit bool) {
+	ns := ec.namespaceFor(key)
+	label := defaultNamespace
+	if ns != nil {
+		label = ns.cfg.Prefix
+		if hit {
+			atomic.AddInt64(&ns.hits, 1)
+		} else {
+			atomic.AddInt64(&ns.misses, 1)
+		}
+	}
+	if hit {
+		cacheNamespaceLookups.WithLabelValues(label, "hit").Inc()
+	} else {
+		cacheNamespaceLookups.WithLabelValues(label, "miss").Inc()
+	}
+}
+
+// namespaceTTL applies the namespace default and cap to a caller TTL
+func (ns *cacheNamespace) namespaceTTL(ttl time.Duration) time.Duration {
+	if ns == nil {
+		return ttl
+	}
+	if ttl <= 0 && ns.cfg.DefaultTTL > 0 {
+		ttl = ns.cfg.DefaultTTL
+	}
+	if ns.cfg.MaxTTL > 0 && ttl > ns.cfg.MaxTTL {
+		ttl = ns.cfg.MaxTTL
+	}
+	return ttl
+}
+
+// admitsAlways reports whether key bypasses TinyLFU admission
+func (ec *EnterpriseCache) admitsAlways(key string) bool {
+	ns := ec.namespaceFor(key)
+	return ns != nil && ns.cfg.Admission == AdmissionAlways
+}
+
+// trackNamespaceWrite records a stored key and evicts the namespace's
+// oldest keys while it is over budget
+func (ec *EnterpriseCache) trackNamespaceWrite(key string, size int64) {
+	ns := ec.namespaceFor(key)
+	if ns == nil {
+		return
+	}
+
+	ns.mu.Lock()
+	if ele, ok := ns.keys[key]; ok {
+		nk := ele.Value.(*namespaceKey)
+		ns.bytes += size - nk.size
+		nk.size = size
+		ns.order.MoveToBack(ele)
+	} else {
+		ns.keys[key] = ns.order.PushBack(&namespaceKey{key: key, size: size})
+		ns.bytes += size
+	}
+
+	var evict []string
+	for ns.overBudgetLocked() {
+		front := ns.order.Front()
+		nk := front.Value.(*namespaceKey)
+		if nk.key == key {
+			// Never evict the entry just written, even if it alone is over budget
+			break
+		}
+		ns.order.Remove(front)
+		delete(ns.keys, nk.key)
+		ns.bytes -= nk.size
+		evict = append(evict, nk.key)
+	}
+	entries, bytes := ns.order.Len(), ns.bytes
+	ns.mu.Unlock()
+
+	cacheNamespaceEntries.WithLabelValues(ns.cfg.Prefix).Set(float64(entries))
+	cacheNamespaceBytes.WithLabelValues(ns.cfg.Prefix).Set(float64(bytes))
+	if len(evict) == 0 {
+		return
+	}
+
+	atomic.AddInt64(&ns.evictions, int64(len(evict)))
+	atomic.AddInt64(&ec.evictions, int64(len(evict)))
+	cacheNamespaceEvictions.WithLabelValues(ns.cfg.Prefix).Add(float64(len(evict)))
+	if backend := ec.levels[L1Memory]; backend != nil {
+		for _, k := range evict {
+			_ = backend.Delete(k)
+		}
+	}
+}
+
+func (ns *cacheNamespace) overBudgetLocked() bool {
+	if ns.cfg.MaxEntries > 0 && ns.order.Len() > ns.cfg.MaxEntries {
+		return true
+	}
+	return ns.cfg.MaxBytes > 0 && ns.bytes > ns.cfg.MaxBytes
+}
+
+// compressEntry gzips the serialized value of entry if its namespace asks for it
+func (ns *cacheNamespace) compressEntry(entry *CacheEntry, data []byte) error {
+	if ns == nil || ns.cfg.Compression != CompressionGzip || int64(len(data)) <= ns.cfg.CompressionThreshold {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	entry.CompressedData = buf.Bytes()
+	entry.Compressed = true
+	entry.Value = nil
+	entry.Size = int64(buf.Len())
+	return nil
+}
+
+// decompressEntryValue decodes a value stored by compressEntry
+func (ec *EnterpriseCache) decompressEntryValue(entry *CacheEntry) (interface{}, error) {
+	data, err := gunzip(entry.CompressedData)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&ec.decompressions, 1)
+	return value, nil
+}
+
+func gunzip(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// The cache samples memory pressure for the whole process and grades it
+// into levels. Other subsystems (the p2p block pipeline, the webhook
+// queue, the predictive cache) subscribe with OnMemoryPressure and shed
+// their own load as the level rises, so they back off together on one
+// reading instead of each guessing from runtime.MemStats.
+
+var cacheMemoryPressureLevel = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "cache_memory_pressure_level",
+	Help: "Memory pressure level reported to subscribers (0 normal, 1 elevated, 2 high, 3 critical)",
+})
+
+// MemoryPressureLevel grades heap usage against the cache's eviction
+// threshold
+type MemoryPressureLevel int
+
+const (
+	// PressureNormal is below 80% of the eviction threshold
+	PressureNormal MemoryPressureLevel = iota
+	// PressureElevated is approaching the threshold
+	PressureElevated
+	// PressureHigh is past the threshold: the cache is evicting
+	PressureHigh
+	// PressureCritical is 20% past the threshold: eviction is not keeping up
+	PressureCritical
+)
+
+func (l MemoryPressureLevel) String() string {
+	switch l {
+	case PressureNormal:
+		return "normal"
+	case PressureElevated:
+		return "elevated"
+	case PressureHigh:
+		return "high"
+	case PressureCritical:
+		return "critical"
+	default:
+		return "unknown"
+	}
+}
+
+// pressureStarts holds the MemoryPressure ratio at which each level
+// starts. A level is left only once the ratio falls pressureHysteresis
+// below its start, so a reading hovering at a boundary does not flap.
+var pressureStarts = [...]float64{
+	PressureElevated: 0.8,
+	PressureHigh:     1.0,
+	PressureCritical: 1.2,
+}
+
+const (
+	pressureHysteresis      = 0.05
+	defaultPressureInterval = 5 * time.Second
+)
+
+// pressureWatch holds the current level and its subscribers
+type pressureWatch struct {
+	mu    sync.Mutex
+	level MemoryPressureLevel
+	subs  []func(MemoryPressureLevel)
+}
+
+// pressureLevel grades ratio, given the current level
+func pressureLevel(ratio float64, current MemoryPressureLevel) MemoryPressureLevel {
+	for l := PressureCritical; l > PressureNormal; l-- {
+		start := pressureStarts[l]
+		if l <= current {
+			start -= pressureHysteresis
+		}
+		if ratio >= start {
+			return l
+		}
+	}
+	return PressureNormal
+}
+
+// OnMemoryPressure registers fn to run whenever the memory pressure level
+// changes, including back to PressureNormal so subscribers can resume. A
+// subscriber registering under pressure is told the current level at
+// once. fn runs on the cache's pressure worker and must not block.
+func (ec *EnterpriseCache) OnMemoryPressure(fn func(MemoryPressureLevel)) {
+	ec.pressure.mu.Lock()
+	ec.pressure.subs = append(ec.pressure.subs, fn)
+	level := ec.pressure.level
+	ec.pressure.mu.Unlock()
+
+	if level != PressureNormal {
+		fn(level)
+	}
+}
+
+// PressureLevel returns the memory pressure level last reported to
+// subscribers
+func (ec *EnterpriseCache) PressureLevel() MemoryPressureLevel {
+	ec.pressure.mu.Lock()
+	defer ec.pressure.mu.Unlock()
+	return ec.pressure.level
+}
+
+// checkPressure samples memory pressure and tells subscribers if its level
+// changed
+func (ec *EnterpriseCache) checkPressure() {
+	ratio := ec.MemoryPressure()
+
+	ec.pressure.mu.Lock()
+	prev := ec.pressure.level
+	level := pressureLevel(ratio, prev)
+	if level == prev {
+		ec.pressure.mu.Unlock()
+		return
+	}
+	ec.pressure.level = level
+	subs := make([]func(MemoryPressureLevel), len(ec.pressure.subs))
+	copy(subs, ec.pressure.subs)
+	ec.pressure.mu.Unlock()
+
+	cacheMemoryPressureLevel.Set(float64(level))
+	log := ec.logger.Info
+	if level > prev {
+		log = ec.logger.Warn
+	}
+	log("Cache memory pressure level changed",
+		zap.Stringer("from", prev),
+		zap.Stringer("to", level),
+		zap.Float64("pressure", ratio),
+		zap.Int("subscribers", len(subs)))
+
+	for _, fn := range subs {
+		fn(level)
+	}
+}
+
+func (ec *EnterpriseCache) pressureWorker() {
+	defer ec.workerGroup.Done()
+
+	interval := ec.config.PressureInterval
+	if interval <= 0 {
+		interval = defaultPressureInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ec.shutdownChan:
+			return
+		case <-ticker.C:
+			ec.checkPressure()
+		}
+	}
+}
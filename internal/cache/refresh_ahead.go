@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	cacheRefreshAheadLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_refresh_ahead_lookups_total",
+		Help: "Lookups in refresh-ahead namespaces (hit = served from cache, miss = entry had expired)",
+	}, []string{"namespace", "result"})
+	cacheRefreshAheadRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_refresh_ahead_refreshes_total",
+		Help: "Background refresh-ahead loads by result",
+	}, []string{"namespace", "result"})
+)
+
+// defaultRefreshAheadThreshold refreshes once 80% of an entry's TTL is used
+const defaultRefreshAheadThreshold = 0.2
+
+// RefreshAheadLoader reloads the value for key
+type RefreshAheadLoader func(ctx context.Context, key string) (any, error)
+
+// RefreshAheadPolicy keeps hot keys from expiring under steady traffic.
+// When an entry whose key starts with the policy's namespace is read with
+// less than Threshold of its TTL left, Loader is run in the background and
+// the fresh value replaces the entry before it expires.
+type RefreshAheadPolicy struct {
+	Threshold float64       // Fraction of TTL remaining that triggers a refresh, 0-1
+	TTL       time.Duration // TTL for refreshed entries; 0 keeps the entry's own TTL
+	Timeout   time.Duration // Per-refresh load timeout
+	Loader    RefreshAheadLoader
+}
+
+type refreshAheadRegistry struct {
+	mu       sync.RWMutex
+	policies map[string]RefreshAheadPolicy // namespace prefix -> policy
+	inflight sync.Map                      // key -> struct{}, one refresh per key
+}
+
+func newRefreshAheadRegistry() *refreshAheadRegistry {
+	return &refreshAheadRegistry{policies: make(map[string]RefreshAheadPolicy)}
+}
+
+// SetRefreshAhead registers policy for keys starting with namespace, e.g.
+// "latest_block:" or "fee_estimate:". The longest matching namespace wins.
+func (ec *EnterpriseCache) SetRefreshAhead(namespace string, policy RefreshAheadPolicy) error {
+	if namespace == "" {
+		return fmt.Errorf("refresh-ahead namespace must not be empty")
+	}
+	if policy.Loader == nil {
+		return fmt.Errorf("refresh-ahead policy for %q has no loader", namespace)
+	}
+	if policy.Threshold <= 0 || policy.Threshold >= 1 {
+		policy.Threshold = defaultRefreshAheadThreshold
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = 10 * time.Second
+	}
+
+	ec.refreshAhead.mu.Lock()
+	ec.refreshAhead.policies[namespace] = policy
+	ec.refreshAhead.mu.Unlock()
+	return nil
+}
+
+// RemoveRefreshAhead stops refreshing keys in namespace
+func (ec *EnterpriseCache) RemoveRefreshAhead(namespace string) {
+	ec.refreshAhead.mu.Lock()
+	delete(ec.refreshAhead.policies, namespace)
+	ec.refreshAhead.mu.Unlock()
+}
+
+// refreshAheadPolicy returns the policy for key, if any
+func (ec *EnterpriseCache) refreshAheadPolicy(key string) (string, RefreshAheadPolicy, bool) {
+	ec.refreshAhead.mu.RLock()
+	defer ec.refreshAhead.mu.RUnlock()
+
+	var (
+		best   string
+		policy RefreshAheadPolicy
+		found  bool
+	)
+	for namespace, p := range ec.refreshAhead.policies {
+		if strings.HasPrefix(key, namespace) && len(namespace) > len(best) {
+			best, policy, found = namespace, p, true
+		}
+	}
+	return best, policy, found
+}
+
+// refreshAheadOnHit schedules a background refresh if entry is close to
+// expiring and its namespace has a policy
+func (ec *EnterpriseCache) refreshAheadOnHit(key string, entry *CacheEntry) {
+	namespace, policy, ok := ec.refreshAheadPolicy(key)
+	if !ok {
+		return
+	}
+	cacheRefreshAheadLookups.WithLabelValues(namespace, "hit").Inc()
+
+	ttl := entry.ExpiresAt.Sub(entry.CreatedAt)
+	remaining := entry.ExpiresAt.Sub(ec.clock.Now())
+	if ttl <= 0 || remaining > time.Duration(float64(ttl)*policy.Threshold) {
+		return
+	}
+	if policy.TTL > 0 {
+		ttl = policy.TTL
+	}
+
+	if _, busy := ec.refreshAhead.inflight.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	go ec.runRefreshAhead(namespace, key, ttl, policy)
+}
+
+// refreshAheadOnMiss counts a miss in a refresh-ahead namespace: the key
+// expired or was never loaded despite the policy
+func (ec *EnterpriseCache) refreshAheadOnMiss(key string) {
+	if namespace, _, ok := ec.refreshAheadPolicy(key); ok {
+		cacheRefreshAheadLookups.WithLabelValues(namespace, "miss").Inc()
+	}
+}
+
+func (ec *EnterpriseCache) runRefreshAhead(namespace, key string, ttl time.Duration, policy RefreshAheadPolicy) {
+	defer ec.refreshAhead.inflight.Delete(key)
+
+	ctx, cancel := context.WithTimeout(ec.ctx, policy.Timeout)
+	defer cancel()
+
+	value, err := policy.Loader(ctx, key)
+	if err == nil {
+		err = ec.Set(key, value, ttl)
+	}
+	if err != nil {
+		cacheRefreshAheadRefreshes.WithLabelValues(namespace, "error").Inc()
+		ec.logger.Debug("Refresh-ahead load failed",
+			zap.String("key", key),
+			zap.Error(err))
+	} else {
+		cacheRefreshAheadRefreshes.WithLabelValues(namespace, "success").Inc()
+	}
+
+	// non-blocking notify for tests
+	select {
+	case ec.refreshNotify <- key:
+	default:
+	}
+}
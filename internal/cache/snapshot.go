@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+)
+
+// cacheSnapshot is the on-disk form written by SaveSnapshot
+type cacheSnapshot struct {
+	SavedAt time.Time           `json:"saved_at"`
+	Latest  []blocks.BlockEvent `json:"latest"`
+}
+
+// SaveSnapshot writes the latest block of each chain to path so a restarted
+// or reloaded process can answer tip queries before the next block arrives.
+// It returns how many blocks were written.
+func (ec *EnterpriseCache) SaveSnapshot(path string) (int, error) {
+	ec.mu.RLock()
+	snap := cacheSnapshot{SavedAt: time.Now().UTC(), Latest: make([]blocks.BlockEvent, 0, len(ec.latestByChain))}
+	for _, block := range ec.latestByChain {
+		snap.Latest = append(snap.Latest, block)
+	}
+	ec.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode cache snapshot: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create cache snapshot directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return 0, fmt.Errorf("failed to write cache snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("failed to replace cache snapshot: %w", err)
+	}
+	return len(snap.Latest), nil
+}
+
+// LoadSnapshot restores the blocks saved by SaveSnapshot. A missing file is
+// not an error, and a snapshot older than maxAge is ignored because its tips
+// would be stale. It returns how many blocks were restored.
+func (ec *EnterpriseCache) LoadSnapshot(path string, maxAge time.Duration) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache snapshot: %w", err)
+	}
+	var snap cacheSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("failed to decode cache snapshot: %w", err)
+	}
+	if maxAge > 0 && time.Since(snap.SavedAt) > maxAge {
+		return 0, nil
+	}
+
+	restored := 0
+	for _, block := range snap.Latest {
+		if err := ec.SetLatestBlock(block); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+	return restored, nil
+}
@@ -0,0 +1,271 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/cachebackend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var cacheTierRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "cache_tier_requests_total",
+	Help: "Lower-tier cache operations by level, backend and result",
+}, []string{"level", "backend", "op", "result"}) // op get/set; result hit, miss, ok, error
+
+// defaultTierTimeout bounds each call into a lower-tier backend
+const defaultTierTimeout = 500 * time.Millisecond
+
+// BackendConfig selects a plug-in store, registered with
+// cachebackend.RegisterBackendFactory, for the L2 or L3 tier
+type BackendConfig struct {
+	Backend string               `json:"backend"` // Registered name, e.g. "s3"
+	Options cachebackend.Options `json:"options"`
+	Timeout time.Duration        `json:"timeout"` // Per operation; 0 uses 500ms
+}
+
+// tierBackend adapts a plug-in store to CacheBackend. Values cross the
+// boundary as JSON, so like compressed namespaces they come back as maps,
+// slices and float64s rather than their original Go types.
+type tierBackend struct {
+	level   CacheLevel
+	name    string
+	store   cachebackend.Backend
+	timeout time.Duration
+	errors  int64
+}
+
+// openTier opens the configured store for level
+func openTier(level CacheLevel, cfg *BackendConfig) (*tierBackend, error) {
+	store, err := cachebackend.Open(cfg.Backend, cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTierTimeout
+	}
+	return &tierBackend{level: level, name: cfg.Backend, store: store, timeout: timeout}, nil
+}
+
+func (tb *tierBackend) label() string { return fmt.Sprintf("L%d", int(tb.level)+1) }
+
+func (tb *tierBackend) count(op, result string) {
+	if result == "error" {
+		atomic.AddInt64(&tb.errors, 1)
+	}
+	cacheTierRequests.WithLabelValues(tb.label(), tb.name, op, result).Inc()
+}
+
+// Get implements CacheBackend
+func (tb *tierBackend) Get(key string) (*CacheEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tb.timeout)
+	defer cancel()
+
+	e, err := tb.store.Get(ctx, key)
+	if errors.Is(err, cachebackend.ErrNotFound) || (err == nil && e.Expired(now())) {
+		tb.count("get", "miss")
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		tb.count("get", "error")
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(e.Value, &value); err != nil {
+		tb.count("get", "error")
+		return nil, fmt.Errorf("decode %s entry: %w", tb.label(), err)
+	}
+	tb.count("get", "hit")
+	return &CacheEntry{
+		Key:          key,
+		Value:        value,
+		Size:         int64(len(e.Value)),
+		CreatedAt:    e.CreatedAt,
+		LastAccessed: now(),
+		ExpiresAt:    e.ExpiresAt,
+		Level:        tb.level,
+		Metadata:     make(map[string]interface{}),
+		Version:      1,
+	}, nil
+}
+
+// Set implements CacheBackend
+func (tb *tierBackend) Set(key string, entry *CacheEntry) error {
+	data, err := entryJSON(entry)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tb.timeout)
+	defer cancel()
+
+	err = tb.store.Set(ctx, &cachebackend.Entry{
+		Key:       key,
+		Value:     data,
+		CreatedAt: entry.CreatedAt,
+		ExpiresAt: entry.ExpiresAt,
+	})
+	if err != nil {
+		tb.count("set", "error")
+		return err
+	}
+	tb.count("set", "ok")
+	return nil
+}
+
+// Delete implements CacheBackend
+func (tb *tierBackend) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), tb.timeout)
+	defer cancel()
+	return tb.store.Delete(ctx, key)
+}
+
+// Clear implements CacheBackend
+func (tb *tierBackend) Clear() error {
+	ctx, cancel := context.WithTimeout(context.Background(), tb.timeout)
+	defer cancel()
+	return tb.store.Clear(ctx)
+}
+
+// Size implements CacheBackend
+func (tb *tierBackend) Size() int64 { return tb.store.Stats().Size }
+
+// Stats implements CacheBackend
+func (tb *tierBackend) Stats() BackendStats {
+	st := tb.store.Stats()
+	return BackendStats{
+		Entries:    st.Entries,
+		Size:       st.Size,
+		Hits:       st.Hits,
+		Misses:     st.Misses,
+		Operations: st.Hits + st.Misses,
+		Errors:     st.Errors + atomic.LoadInt64(&tb.errors),
+	}
+}
+
+// Close implements CacheBackend
+func (tb *tierBackend) Close() error { return tb.store.Close() }
+
+// entryJSON returns the JSON encoding of entry's value
+func entryJSON(entry *CacheEntry) ([]byte, error) {
+	if entry.Compressed && entry.CompressedData != nil {
+		// compressEntry gzips the JSON encoding
+		return gunzip(entry.CompressedData)
+	}
+	return json.Marshal(entry.Value)
+}
+
+// initializeTiers opens the configured L2 and L3 stores
+func (ec *EnterpriseCache) initializeTiers() error {
+	for _, t := range []struct {
+		level CacheLevel
+		cfg   *BackendConfig
+	}{
+		{L2Disk, ec.config.L2Backend},
+		{L3Distributed, ec.config.L3Backend},
+	} {
+		if t.cfg == nil || t.cfg.Backend == "" {
+			continue
+		}
+		tier, err := openTier(t.level, t.cfg)
+		if err != nil {
+			return fmt.Errorf("%s cache tier: %w", fmt.Sprintf("L%d", int(t.level)+1), err)
+		}
+		ec.levels[t.level] = tier
+		ec.logger.Info("Cache tier enabled",
+			zap.String("level", tier.label()),
+			zap.String("backend", t.cfg.Backend))
+	}
+	return nil
+}
+
+// lowerTiers returns the configured tiers below L1, nearest first
+func (ec *EnterpriseCache) lowerTiers() []CacheBackend {
+	var tiers []CacheBackend
+	for _, level := range []CacheLevel{L2Disk, L3Distributed} {
+		if b := ec.levels[level]; b != nil {
+			tiers = append(tiers, b)
+		}
+	}
+	return tiers
+}
+
+// getFromLowerTiers looks key up below L1 after an L1 miss. A hit is
+// copied into L1 and any nearer tier that missed. A write still queued
+// for the tiers is served from the queue.
+func (ec *EnterpriseCache) getFromLowerTiers(key string) (interface{}, bool) {
+	if ns := ec.namespaceFor(key); ns != nil && ns.behind != nil {
+		if entry, ok := ns.behind.get(key); ok && ec.clock.Now().Before(entry.ExpiresAt) {
+			promoted := *entry
+			if err := ec.setToL1(key, &promoted); err == nil {
+				ec.trackNamespaceWrite(key, promoted.Size)
+			}
+			if entry.Compressed {
+				if v, err := ec.decompressEntryValue(entry); err == nil {
+					return v, true
+				}
+				return nil, false
+			}
+			return entry.Value, true
+		}
+	}
+
+	tiers := ec.lowerTiers()
+	for i, tier := range tiers {
+		entry, err := tier.Get(key)
+		if err != nil {
+			if !errors.Is(err, ErrCacheMiss) {
+				ec.logger.Debug("Cache tier get failed", zap.String("key", key), zap.Error(err))
+			}
+			continue
+		}
+		ec.recordCacheHit(entry.Level)
+		for _, nearer := range tiers[:i] {
+			if err := nearer.Set(key, entry); err != nil {
+				ec.logger.Debug("Cache tier backfill failed", zap.String("key", key), zap.Error(err))
+			}
+		}
+		promoted := *entry
+		promoted.Level = L1Memory
+		if err := ec.setToL1(key, &promoted); err == nil {
+			ec.trackNamespaceWrite(key, promoted.Size)
+		}
+		return entry.Value, true
+	}
+	return nil, false
+}
+
+// writeLowerTiers writes an entry stored in L1 to the lower tiers, now or,
+// under a write-behind namespace, from its queue. Failures leave the tier
+// without the entry; L1 is authoritative.
+func (ec *EnterpriseCache) writeLowerTiers(key string, entry *CacheEntry) {
+	tiers := ec.lowerTiers()
+	if len(tiers) == 0 {
+		return
+	}
+	if ns := ec.namespaceFor(key); ns != nil && ns.behind != nil {
+		ns.behind.enqueue(key, entry)
+		return
+	}
+	ec.writeTiers(tiers, key, entry)
+}
+
+// writeTiers writes entry to each of tiers, returning the first failure
+func (ec *EnterpriseCache) writeTiers(tiers []CacheBackend, key string, entry *CacheEntry) error {
+	var firstErr error
+	for _, tier := range tiers {
+		if err := tier.Set(key, entry); err != nil {
+			ec.logger.Debug("Cache tier write failed", zap.String("key", key), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
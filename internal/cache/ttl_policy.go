@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// How long data stays useful depends on when it was cached: fee estimates
+// go stale faster while blocks arrive quickly or during the hours fees
+// are volatile. A namespace's TTLRules adjust the TTL of each write while
+// a rule matches, on a UTC time-of-day schedule, on a chain's recent
+// block interval, or both. Rules are evaluated in order and the first
+// match wins; the result is still capped by the namespace's MaxTTL.
+
+var (
+	cacheTTLPolicyAdjustments = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_ttl_policy_adjustments_total",
+		Help: "Writes whose TTL a namespace TTL rule adjusted, by namespace and rule",
+	}, []string{"namespace", "rule"})
+	cacheChainBlockInterval = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cache_chain_block_interval_seconds",
+		Help: "Recent mean block interval per chain, as seen by TTL rules",
+	}, []string{"chain"})
+)
+
+// blockIntervalWeight is the weight of the newest interval in a chain's
+// mean, so a burst of fast blocks shows within three or four blocks
+const blockIntervalWeight = 0.3
+
+// TTLRule adjusts a namespace's TTLs while it matches. A rule with both a
+// schedule and an activity condition matches only when both do.
+type TTLRule struct {
+	Name string `json:"name"` // Metrics label
+
+	// Schedule: From-To as "HH:MM" in UTC, wrapping midnight when To is
+	// not after From, on Days (every day when empty)
+	From string         `json:"from,omitempty"`
+	To   string         `json:"to,omitempty"`
+	Days []time.Weekday `json:"days,omitempty"`
+
+	// Activity: matches while Chain's recent mean block interval is
+	// below BlockIntervalBelow
+	Chain              string        `json:"chain,omitempty"`
+	BlockIntervalBelow time.Duration `json:"block_interval_below,omitempty"`
+
+	// Effect: TTL replaces the TTL when set, otherwise it is scaled by Factor
+	TTL    time.Duration `json:"ttl,omitempty"`
+	Factor float64       `json:"factor,omitempty"`
+}
+
+// ttlRule is a validated TTLRule
+type ttlRule struct {
+	TTLRule
+	scheduled bool
+	from, to  int // Minutes after midnight UTC
+	days      uint8
+}
+
+// compileTTLRules validates a namespace's rules
+func compileTTLRules(rules []TTLRule) ([]ttlRule, error) {
+	out := make([]ttlRule, 0, len(rules))
+	for _, r := range rules {
+		c := ttlRule{TTLRule: r}
+		if r.Name == "" {
+			return nil, fmt.Errorf("TTL rule needs a name")
+		}
+		if (r.From == "") != (r.To == "") {
+			return nil, fmt.Errorf("TTL rule %q: from and to must be set together", r.Name)
+		}
+		if r.From != "" {
+			var err error
+			if c.from, err = parseClock(r.From); err != nil {
+				return nil, fmt.Errorf("TTL rule %q: %w", r.Name, err)
+			}
+			if c.to, err = parseClock(r.To); err != nil {
+				return nil, fmt.Errorf("TTL rule %q: %w", r.Name, err)
+			}
+			c.scheduled = true
+		}
+		for _, d := range r.Days {
+			if d < time.Sunday || d > time.Saturday {
+				return nil, fmt.Errorf("TTL rule %q: invalid weekday %d", r.Name, d)
+			}
+			c.days |= 1 << d
+		}
+		if (r.Chain == "") != (r.BlockIntervalBelow <= 0) {
+			return nil, fmt.Errorf("TTL rule %q: chain and block_interval_below must be set together", r.Name)
+		}
+		if !c.scheduled && c.days == 0 && r.Chain == "" {
+			return nil, fmt.Errorf("TTL rule %q: needs a schedule or a block interval condition", r.Name)
+		}
+		if r.TTL <= 0 && r.Factor <= 0 {
+			return nil, fmt.Errorf("TTL rule %q: needs a positive ttl or factor", r.Name)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// matches reports whether r applies at now, given chain activity
+func (r *ttlRule) matches(now time.Time, activity *blockActivity) bool {
+	now = now.UTC()
+	if r.days != 0 && r.days&(1<<now.Weekday()) == 0 {
+		return false
+	}
+	if r.scheduled {
+		m := now.Hour()*60 + now.Minute()
+		in := m >= r.from && m < r.to
+		if r.to <= r.from {
+			in = m >= r.from || m < r.to
+		}
+		if !in {
+			return false
+		}
+	}
+	if r.Chain != "" {
+		interval, ok := activity.interval(r.Chain, now)
+		if !ok || interval >= r.BlockIntervalBelow {
+			return false
+		}
+	}
+	return true
+}
+
+// policyTTL applies the namespace default and cap to a caller TTL, then
+// the first matching TTL rule
+func (ec *EnterpriseCache) policyTTL(ns *cacheNamespace, ttl time.Duration) time.Duration {
+	ttl = ns.namespaceTTL(ttl)
+	if ns == nil || len(ns.ttlRules) == 0 {
+		return ttl
+	}
+
+	now := ec.clock.Now()
+	for i := range ns.ttlRules {
+		r := &ns.ttlRules[i]
+		if !r.matches(now, &ec.activity) {
+			continue
+		}
+		adjusted := r.TTL
+		if adjusted <= 0 {
+			adjusted = time.Duration(float64(ttl) * r.Factor)
+		}
+		if ns.cfg.MaxTTL > 0 && adjusted > ns.cfg.MaxTTL {
+			adjusted = ns.cfg.MaxTTL
+		}
+		if adjusted != ttl {
+			cacheTTLPolicyAdjustments.WithLabelValues(ns.cfg.Prefix, r.Name).Inc()
+		}
+		return adjusted
+	}
+	return ttl
+}
+
+// ===== BLOCK ACTIVITY =====
+
+// blockActivity tracks each chain's recent mean block interval
+type blockActivity struct {
+	mu     sync.Mutex
+	chains map[string]*chainActivity
+}
+
+type chainActivity struct {
+	last time.Time
+	mean time.Duration // 0 until a second block arrives
+}
+
+// observe records a new block for chain at now
+func (a *blockActivity) observe(chain string, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.chains == nil {
+		a.chains = make(map[string]*chainActivity)
+	}
+	c, ok := a.chains[chain]
+	if !ok {
+		a.chains[chain] = &chainActivity{last: now}
+		return
+	}
+	interval := now.Sub(c.last)
+	if interval <= 0 {
+		return
+	}
+	c.last = now
+	if c.mean == 0 {
+		c.mean = interval
+	} else {
+		c.mean = time.Duration(blockIntervalWeight*float64(interval) + (1-blockIntervalWeight)*float64(c.mean))
+	}
+	cacheChainBlockInterval.WithLabelValues(chain).Set(c.mean.Seconds())
+}
+
+// interval returns chain's recent mean block interval. A chain that has
+// gone quiet for longer than its mean reports the time since its last
+// block instead, so a burst stops matching once it is over.
+func (a *blockActivity) interval(chain string, now time.Time) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	c, ok := a.chains[chain]
+	if !ok || c.mean == 0 {
+		return 0, false
+	}
+	return max(c.mean, now.Sub(c.last)), true
+}
+
+// BlockInterval returns chain's recent mean block interval as used by TTL
+// rules, false until two blocks have been seen
+func (ec *EnterpriseCache) BlockInterval(chain string) (time.Duration, bool) {
+	return ec.activity.interval(chain, ec.clock.Now())
+}
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// A Set waits for every lower tier by default, so a slow L3 store adds its
+// latency to each write. Namespaces that can afford the tiers lagging L1
+// may write behind instead: Set returns once L1 holds the entry and a
+// worker copies it down. The queue is bounded in length and in lag; writes
+// dropped to stay within the bounds, failed tier writes and writes still
+// queued when Shutdown's deadline passes are counted as lost, so the tiers
+// are known to be missing them. A newer write of a queued key replaces the
+// queued one.
+
+var (
+	cacheWriteBehindPending = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cache_write_behind_pending",
+		Help: "Tier writes queued per write-behind namespace",
+	}, []string{"namespace"})
+	cacheWriteBehindLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cache_write_behind_lag_seconds",
+		Help: "Time the last tier write of a write-behind namespace spent queued",
+	}, []string{"namespace"})
+	cacheWriteBehindLost = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_write_behind_lost_total",
+		Help: "Queued tier writes that never reached the tiers, by reason (overflow, lag, error, shutdown)",
+	}, []string{"namespace", "reason"})
+)
+
+// WritePolicy decides how Set reaches the L2 and L3 tiers
+type WritePolicy int
+
+const (
+	// WriteThrough writes the tiers before Set returns
+	WriteThrough WritePolicy = iota
+	// WriteBehind queues the tier writes and returns once L1 is written
+	WriteBehind
+)
+
+// Write-behind defaults for namespaces that leave the bounds unset
+const (
+	defaultWriteBehindQueue  = 10000
+	defaultWriteBehindMaxLag = 30 * time.Second
+)
+
+// pendingWrite is a tier write waiting in a write-behind queue
+type pendingWrite struct {
+	key    string
+	entry  *CacheEntry
+	queued time.Time
+}
+
+// writeBehindQueue holds one namespace's pending tier writes, oldest first
+type writeBehindQueue struct {
+	ec     *EnterpriseCache
+	prefix string
+	limit  int
+	maxLag time.Duration
+
+	mu      sync.Mutex
+	order   *list.List               // of *pendingWrite
+	keys    map[string]*list.Element // key -> element in order
+	notify  chan struct{}
+	stop    chan struct{} // Closed when the namespace is replaced
+	drainMu sync.Mutex    // Keeps one write per key in flight, in order
+
+	lost int64
+}
+
+// newWriteBehindQueue starts the queue for a write-behind namespace
+func (ec *EnterpriseCache) newWriteBehindQueue(cfg NamespaceConfig) *writeBehindQueue {
+	q := &writeBehindQueue{
+		ec:     ec,
+		prefix: cfg.Prefix,
+		limit:  cfg.WriteBehindQueue,
+		maxLag: cfg.WriteBehindMaxLag,
+		order:  list.New(),
+		keys:   make(map[string]*list.Element),
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+	if q.limit <= 0 {
+		q.limit = defaultWriteBehindQueue
+	}
+	if q.maxLag <= 0 {
+		q.maxLag = defaultWriteBehindMaxLag
+	}
+
+	ec.workerGroup.Add(1)
+	go q.run()
+	return q
+}
+
+// enqueue queues a tier write of entry, dropping the oldest queued write
+// if the queue is full
+func (q *writeBehindQueue) enqueue(key string, entry *CacheEntry) {
+	e := *entry
+	pw := &pendingWrite{key: key, entry: &e, queued: q.ec.clock.Now()}
+
+	q.mu.Lock()
+	if ele, ok := q.keys[key]; ok {
+		ele.Value = pw
+		q.order.MoveToBack(ele)
+	} else {
+		q.keys[key] = q.order.PushBack(pw)
+	}
+	overflow := 0
+	for q.order.Len() > q.limit {
+		front := q.order.Front()
+		q.order.Remove(front)
+		delete(q.keys, front.Value.(*pendingWrite).key)
+		overflow++
+	}
+	pending := q.order.Len()
+	q.mu.Unlock()
+
+	cacheWriteBehindPending.WithLabelValues(q.prefix).Set(float64(pending))
+	q.countLost("overflow", overflow)
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// get returns the queued entry for key, so reads see writes the tiers do
+// not have yet
+func (q *writeBehindQueue) get(key string) (*CacheEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if ele, ok := q.keys[key]; ok {
+		return ele.Value.(*pendingWrite).entry, true
+	}
+	return nil, false
+}
+
+func (q *writeBehindQueue) pop() (*pendingWrite, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	front := q.order.Front()
+	if front == nil {
+		return nil, 0
+	}
+	q.order.Remove(front)
+	pw := front.Value.(*pendingWrite)
+	delete(q.keys, pw.key)
+	return pw, q.order.Len()
+}
+
+// writeNext writes the oldest queued write to the tiers; false once the
+// queue is empty
+func (q *writeBehindQueue) writeNext() bool {
+	q.drainMu.Lock()
+	defer q.drainMu.Unlock()
+
+	pw, pending := q.pop()
+	if pw == nil {
+		return false
+	}
+	cacheWriteBehindPending.WithLabelValues(q.prefix).Set(float64(pending))
+
+	lag := q.ec.clock.Now().Sub(pw.queued)
+	if lag > q.maxLag {
+		q.countLost("lag", 1)
+		return true
+	}
+	cacheWriteBehindLag.WithLabelValues(q.prefix).Set(lag.Seconds())
+	if err := q.ec.writeTiers(q.ec.lowerTiers(), pw.key, pw.entry); err != nil {
+		q.countLost("error", 1)
+	}
+	return true
+}
+
+// run writes queued entries until the cache shuts down or the namespace
+// is replaced; a replaced namespace's queue is written out first
+func (q *writeBehindQueue) run() {
+	defer q.ec.workerGroup.Done()
+	for {
+		select {
+		case <-q.ec.shutdownChan:
+			return
+		case <-q.stop:
+			for q.writeNext() {
+			}
+			return
+		case <-q.notify:
+			for q.writeNext() {
+			}
+		}
+	}
+}
+
+// flush writes out the queue until it is empty or ctx ends; what is left
+// then is dropped as lost
+func (q *writeBehindQueue) flush(ctx context.Context) int {
+	for ctx.Err() == nil && q.writeNext() {
+	}
+
+	q.mu.Lock()
+	left := q.order.Len()
+	q.order.Init()
+	q.keys = make(map[string]*list.Element)
+	q.mu.Unlock()
+
+	cacheWriteBehindPending.WithLabelValues(q.prefix).Set(0)
+	q.countLost("shutdown", left)
+	return left
+}
+
+func (q *writeBehindQueue) countLost(reason string, n int) {
+	if n == 0 {
+		return
+	}
+	atomic.AddInt64(&q.lost, int64(n))
+	cacheWriteBehindLost.WithLabelValues(q.prefix, reason).Add(float64(n))
+}
+
+func (q *writeBehindQueue) pending() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return int64(q.order.Len())
+}
+
+// flushWriteBehind writes out every write-behind queue before shutdown
+func (ec *EnterpriseCache) flushWriteBehind(ctx context.Context) {
+	ec.namespaces.mu.RLock()
+	var queues []*writeBehindQueue
+	for _, ns := range ec.namespaces.namespaces {
+		if ns.behind != nil {
+			queues = append(queues, ns.behind)
+		}
+	}
+	ec.namespaces.mu.RUnlock()
+
+	for _, q := range queues {
+		if left := q.flush(ctx); left > 0 {
+			ec.logger.Warn("Cache write-behind queue not flushed before shutdown",
+				zap.String("namespace", q.prefix), zap.Int("lost", left))
+		}
+	}
+}
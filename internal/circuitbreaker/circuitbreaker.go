@@ -46,6 +46,24 @@ const (
 	FailureTypeCircuit
 )
 
+// String returns the failure type name used in logs and history
+func (f FailureType) String() string {
+	switch f {
+	case FailureTypeTimeout:
+		return "timeout"
+	case FailureTypeError:
+		return "error"
+	case FailureTypeLatency:
+		return "latency"
+	case FailureTypeResource:
+		return "resource"
+	case FailureTypeCircuit:
+		return "circuit"
+	default:
+		return "unknown"
+	}
+}
+
 // Policy defines circuit breaker behavior policies
 type Policy int
 
@@ -126,6 +144,8 @@ type CircuitBreakerMetrics struct {
 	FailedRequests      int64 `json:"failed_requests"`
 	TimeoutRequests     int64 `json:"timeout_requests"`
 	CircuitOpenRequests int64 `json:"circuit_open_requests"`
+	TrialRequests       int64 `json:"trial_requests"`
+	ProbeRequests       int64 `json:"probe_requests"`
 
 	// State tracking
 	StateChanges    int64                   `json:"state_changes"`
@@ -154,8 +174,8 @@ type CircuitBreakerMetrics struct {
 
 // EnterpriseCircuitBreaker implements comprehensive circuit breaker functionality
 type EnterpriseCircuitBreaker struct {
-	// Core configuration
-	config *EnterpriseConfig
+	// Core configuration, replaced whole by UpdateConfig; see conf
+	config atomic.Pointer[EnterpriseConfig]
 	logger *zap.Logger
 
 	// State management
@@ -170,6 +190,12 @@ type EnterpriseCircuitBreaker struct {
 	lastFailureTime      time.Time
 	lastSuccessTime      time.Time
 
+	// Half-open trial scheduling, see probe.go
+	probeGen      uint64    // Incremented on every entry into half-open
+	trialsStarted int       // Trials admitted in the current half-open period
+	nextTrialAt   time.Time // Earliest time the next trial may start
+	lastTrialAt   time.Time
+
 	// Advanced algorithms
 	slidingWindow     *SlidingWindow
 	adaptiveThreshold *AdaptiveThreshold
@@ -179,6 +205,12 @@ type EnterpriseCircuitBreaker struct {
 	metrics        *CircuitBreakerMetrics
 	latencyHistory []time.Duration
 
+	// Incident history, see history.go
+	history       *eventRing
+	burstStart    time.Time // First failure of the current run
+	burstFailures int64     // Length of the current failure run
+	burstErr      string    // Last error of the current run
+
 	// Tier management
 	currentTier string
 	tierConfigs map[string]TierConfig
@@ -230,7 +262,13 @@ func NewEnterpriseCircuitBreaker(cfg Config) (*EnterpriseCircuitBreaker, error)
 			Metrics:                cfg.Metrics,
 			TierSettings:           cfg.TierSettings,
 			EnableHealthScoring:    cfg.EnableHealthScoring,
+			HalfOpenTrials:         cfg.HalfOpenTrials,
+			ProbeInterval:          cfg.ProbeInterval,
+			ProbeJitter:            cfg.ProbeJitter,
+			HealthProbe:            cfg.HealthProbe,
+			HistorySize:            cfg.HistorySize,
 		},
+		FailureThreshold: cfg.FailureThreshold,
 		MaxFailures:      int(cfg.FailureThreshold * 10), // Convert to count
 		ResetTimeout:     cfg.Timeout,
 		HalfOpenMaxCalls: cfg.HalfOpenMaxConcurrency,
@@ -238,7 +276,6 @@ func NewEnterpriseCircuitBreaker(cfg Config) (*EnterpriseCircuitBreaker, error)
 	}
 
 	cb := &EnterpriseCircuitBreaker{
-		config:         enterpriseConfig,
 		logger:         zap.NewNop(), // Default logger
 		state:          StateClosed,
 		stateChangedAt: time.Now(),
@@ -248,7 +285,9 @@ func NewEnterpriseCircuitBreaker(cfg Config) (*EnterpriseCircuitBreaker, error)
 		shutdownChan:   make(chan struct{}),
 		metrics:        newCircuitBreakerMetrics(),
 		latencyHistory: make([]time.Duration, 0, 1000),
+		history:        newEventRing(cfg.HistorySize),
 	}
+	cb.config.Store(enterpriseConfig)
 
 	// Initialize advanced components
 	cb.slidingWindow = NewSlidingWindow(10*time.Second, time.Second)
@@ -261,6 +300,12 @@ func NewEnterpriseCircuitBreaker(cfg Config) (*EnterpriseCircuitBreaker, error)
 	return cb, nil
 }
 
+// conf returns the current configuration. Read it once per decision: a
+// concurrent UpdateConfig swaps in a new one rather than editing this one.
+func (cb *EnterpriseCircuitBreaker) conf() *EnterpriseConfig {
+	return cb.config.Load()
+}
+
 // Execute runs a function with comprehensive circuit breaker protection
 func (cb *EnterpriseCircuitBreaker) Execute(fn func() (interface{}, error)) (*ExecutionResult, error) {
 	return cb.ExecuteWithContext(context.Background(), fn)
@@ -270,20 +315,28 @@ func (cb *EnterpriseCircuitBreaker) Execute(fn func() (interface{}, error)) (*Ex
 func (cb *EnterpriseCircuitBreaker) ExecuteWithContext(ctx context.Context, fn func() (interface{}, error)) (*ExecutionResult, error) {
 	startTime := time.Now()
 
-	// Check if execution is allowed
-	if !cb.allowRequest() {
+	// Check if execution is allowed; half-open admissions are trials
+	allowed, trial := cb.admit(true)
+	if !allowed {
 		atomic.AddInt64(&cb.metrics.CircuitOpenRequests, 1)
+		state := cb.State()
 		return &ExecutionResult{
 			Success:     false,
 			Duration:    time.Since(startTime),
-			Error:       fmt.Errorf("circuit breaker is %s", cb.state.String()),
-			State:       cb.state,
+			Error:       fmt.Errorf("circuit breaker is %s", state.String()),
+			State:       state,
 			FailureType: FailureTypeCircuit,
 		}, nil
 	}
+	if trial != 0 {
+		defer cb.finishTrial(trial)
+	}
 
 	// Execute with timeout and monitoring
 	result := cb.executeWithMonitoring(ctx, fn, startTime)
+	if trial != 0 {
+		result.Metadata = map[string]interface{}{"trial": true}
+	}
 
 	// Record result and update state
 	cb.recordResult(result)
@@ -291,9 +344,11 @@ func (cb *EnterpriseCircuitBreaker) ExecuteWithContext(ctx context.Context, fn f
 	return result, nil
 }
 
-// Allow checks if a request should be allowed through
+// Allow checks if a request should be allowed through. In half-open state
+// it reports whether a trial is due without reserving it.
 func (cb *EnterpriseCircuitBreaker) Allow() bool {
-	return cb.allowRequest()
+	allowed, _ := cb.admit(false)
+	return allowed
 }
 
 // State returns the current circuit breaker state
@@ -313,7 +368,7 @@ func (cb *EnterpriseCircuitBreaker) ForceOpen() {
 	cb.stateChangedAt = time.Now()
 	cb.forceState = &cb.state
 
-	cb.notifyStateChange(oldState, cb.state)
+	cb.notifyStateChange(oldState, cb.state, "forced open")
 }
 
 // ForceClose forces the circuit breaker to closed state
@@ -326,7 +381,7 @@ func (cb *EnterpriseCircuitBreaker) ForceClose() {
 	cb.stateChangedAt = time.Now()
 	cb.forceState = &cb.state
 
-	cb.notifyStateChange(oldState, cb.state)
+	cb.notifyStateChange(oldState, cb.state, "forced closed")
 }
 
 // Reset clears the forced state and returns to normal operation
@@ -340,9 +395,11 @@ func (cb *EnterpriseCircuitBreaker) Reset() {
 	cb.consecutiveFailures = 0
 	cb.consecutiveSuccesses = 0
 	cb.halfOpenCalls = 0
+	cb.burstFailures = 0
+	cb.burstErr = ""
 	cb.stateChangedAt = time.Now()
 
-	cb.notifyStateChange(oldState, cb.state)
+	cb.notifyStateChange(oldState, cb.state, "reset")
 }
 
 // GetMetrics returns comprehensive circuit breaker metrics
@@ -357,6 +414,8 @@ func (cb *EnterpriseCircuitBreaker) GetMetrics() *CircuitBreakerMetrics {
 		FailedRequests:      atomic.LoadInt64(&cb.metrics.FailedRequests),
 		TimeoutRequests:     atomic.LoadInt64(&cb.metrics.TimeoutRequests),
 		CircuitOpenRequests: atomic.LoadInt64(&cb.metrics.CircuitOpenRequests),
+		TrialRequests:       atomic.LoadInt64(&cb.metrics.TrialRequests),
+		ProbeRequests:       atomic.LoadInt64(&cb.metrics.ProbeRequests),
 		StateChanges:        atomic.LoadInt64(&cb.metrics.StateChanges),
 		LastStateChange:     cb.metrics.LastStateChange,
 		TimeInState:         make(map[State]time.Duration),
@@ -384,7 +443,7 @@ func (cb *EnterpriseCircuitBreaker) GetMetrics() *CircuitBreakerMetrics {
 	}
 
 	// Update health score
-	if cb.config.EnableHealthScoring {
+	if cb.conf().EnableHealthScoring {
 		metrics.HealthScore = cb.healthScorer.CalculateHealth()
 	}
 
@@ -427,40 +486,6 @@ func (cb *EnterpriseCircuitBreaker) Shutdown(ctx context.Context) error {
 
 // Core implementation methods
 
-// allowRequest determines if a request should be allowed
-func (cb *EnterpriseCircuitBreaker) allowRequest() bool {
-	cb.mu.RLock()
-	defer cb.mu.RUnlock()
-
-	// Handle forced states
-	if cb.forceState != nil {
-		switch *cb.forceState {
-		case StateForceOpen:
-			return false
-		case StateForceClose:
-			return true
-		}
-	}
-
-	switch cb.state {
-	case StateClosed:
-		return true
-	case StateOpen:
-		// Check if it's time to try half-open
-		if time.Since(cb.stateChangedAt) >= cb.config.ResetTimeout {
-			cb.changeState(StateHalfOpen)
-			return true
-		}
-		return false
-	case StateHalfOpen:
-		// Allow limited requests in half-open state
-		halfOpenCalls := atomic.LoadInt64(&cb.halfOpenCalls)
-		return halfOpenCalls < int64(cb.config.HalfOpenMaxCalls)
-	default:
-		return false
-	}
-}
-
 // executeWithMonitoring executes function with comprehensive monitoring
 func (cb *EnterpriseCircuitBreaker) executeWithMonitoring(ctx context.Context, fn func() (interface{}, error), startTime time.Time) *ExecutionResult {
 	result := &ExecutionResult{
@@ -470,9 +495,9 @@ func (cb *EnterpriseCircuitBreaker) executeWithMonitoring(ctx context.Context, f
 
 	// Create execution context with timeout
 	execCtx := ctx
-	if cb.config.Timeout > 0 {
+	if cb.conf().Timeout > 0 {
 		var cancel context.CancelFunc
-		execCtx, cancel = context.WithTimeout(ctx, cb.config.Timeout)
+		execCtx, cancel = context.WithTimeout(ctx, cb.conf().Timeout)
 		defer cancel()
 	}
 
@@ -557,19 +582,20 @@ func (cb *EnterpriseCircuitBreaker) onSuccess(result *ExecutionResult) {
 	atomic.AddInt64(&cb.consecutiveSuccesses, 1)
 	atomic.StoreInt64(&cb.consecutiveFailures, 0)
 	cb.lastSuccessTime = time.Now()
+	cb.endFailureRun(cb.lastSuccessTime)
 
 	switch cb.state {
 	case StateHalfOpen:
 		successCount := atomic.LoadInt64(&cb.consecutiveSuccesses)
-		if successCount >= int64(cb.config.HalfOpenMaxCalls) {
-			cb.changeState(StateClosed)
+		if successCount >= int64(cb.trialBudget()) {
+			cb.changeState(StateClosed, fmt.Sprintf("%d trials succeeded", successCount))
 		}
 	}
 
 	// Notify callback
-	if cb.config.OnRecovery != nil && cb.consecutiveFailures == 0 {
+	if cfg := cb.conf(); cfg.OnRecovery != nil && cb.consecutiveFailures == 0 {
 		recoveryTime := time.Since(cb.lastFailureTime)
-		go cb.config.OnRecovery(cb.config.Name, recoveryTime)
+		go cfg.OnRecovery(cfg.Name, recoveryTime)
 	}
 }
 
@@ -581,24 +607,26 @@ func (cb *EnterpriseCircuitBreaker) onFailure(result *ExecutionResult) {
 	atomic.AddInt64(&cb.consecutiveFailures, 1)
 	atomic.StoreInt64(&cb.consecutiveSuccesses, 0)
 	cb.lastFailureTime = time.Now()
+	cb.trackFailure(cb.lastFailureTime, result)
 
 	switch cb.state {
 	case StateClosed:
-		if atomic.LoadInt64(&cb.consecutiveFailures) >= int64(cb.config.MaxFailures) {
-			cb.changeState(StateOpen)
+		maxFailures := cb.conf().MaxFailures
+		if failures := atomic.LoadInt64(&cb.consecutiveFailures); failures >= int64(maxFailures) {
+			cb.changeState(StateOpen, fmt.Sprintf("%d consecutive failures (max %d)", failures, maxFailures))
 		}
 	case StateHalfOpen:
-		cb.changeState(StateOpen)
+		cb.changeState(StateOpen, "half-open trial failed")
 	}
 
 	// Notify callback
-	if cb.config.OnFailure != nil {
-		go cb.config.OnFailure(cb.config.Name, result.FailureType)
+	if cfg := cb.conf(); cfg.OnFailure != nil {
+		go cfg.OnFailure(cfg.Name, result.FailureType)
 	}
 }
 
-// changeState changes the circuit breaker state
-func (cb *EnterpriseCircuitBreaker) changeState(newState State) {
+// changeState changes the circuit breaker state; reason is kept in the history
+func (cb *EnterpriseCircuitBreaker) changeState(newState State, reason string) {
 	if cb.state == newState {
 		return
 	}
@@ -611,6 +639,10 @@ func (cb *EnterpriseCircuitBreaker) changeState(newState State) {
 	switch newState {
 	case StateHalfOpen:
 		atomic.StoreInt64(&cb.halfOpenCalls, 0)
+		atomic.StoreInt64(&cb.consecutiveSuccesses, 0)
+		cb.probeGen++
+		cb.trialsStarted = 0
+		cb.nextTrialAt = cb.stateChangedAt
 	case StateClosed:
 		atomic.StoreInt64(&cb.consecutiveFailures, 0)
 	case StateOpen:
@@ -620,30 +652,33 @@ func (cb *EnterpriseCircuitBreaker) changeState(newState State) {
 	atomic.AddInt64(&cb.metrics.StateChanges, 1)
 	cb.metrics.LastStateChange = time.Now()
 
-	cb.notifyStateChange(oldState, newState)
+	cb.notifyStateChange(oldState, newState, reason)
 }
 
-// notifyStateChange notifies about state changes
-func (cb *EnterpriseCircuitBreaker) notifyStateChange(from, to State) {
-	if cb.config.OnStateChange != nil {
-		go cb.config.OnStateChange(cb.config.Name, from, to)
+// notifyStateChange records and notifies about state changes
+func (cb *EnterpriseCircuitBreaker) notifyStateChange(from, to State, reason string) {
+	cb.recordTransition(from, to, reason)
+
+	if cfg := cb.conf(); cfg.OnStateChange != nil {
+		go cfg.OnStateChange(cfg.Name, from, to)
 	}
 
 	if cb.logger != nil {
 		cb.logger.Info("Circuit breaker state changed",
-			zap.String("name", cb.config.Name),
+			zap.String("name", cb.conf().Name),
 			zap.String("from", from.String()),
-			zap.String("to", to.String()))
+			zap.String("to", to.String()),
+			zap.String("reason", reason))
 	}
 }
 
 // classifyFailure determines the type of failure
 func (cb *EnterpriseCircuitBreaker) classifyFailure(err error, duration time.Duration) FailureType {
-	if duration >= cb.config.Timeout {
+	if duration >= cb.conf().Timeout {
 		return FailureTypeTimeout
 	}
 
-	if duration >= cb.config.LatencyThreshold {
+	if duration >= cb.conf().LatencyThreshold {
 		return FailureTypeLatency
 	}
 
@@ -688,11 +723,13 @@ func (cb *EnterpriseCircuitBreaker) updateLatencyMetrics(duration time.Duration)
 	}
 }
 
-// adaptTierSettings adapts settings based on tier configuration
+// adaptTierSettings adapts settings based on tier configuration; cb.mu is held
 func (cb *EnterpriseCircuitBreaker) adaptTierSettings(tierConfig TierConfig) {
-	cb.config.MaxFailures = tierConfig.FailureThreshold
-	cb.config.ResetTimeout = tierConfig.ResetTimeout
-	cb.config.HalfOpenMaxCalls = tierConfig.HalfOpenMaxCalls
+	cfg := *cb.conf()
+	cfg.MaxFailures = tierConfig.FailureThreshold
+	cfg.ResetTimeout = tierConfig.ResetTimeout
+	cfg.HalfOpenMaxCalls = tierConfig.HalfOpenMaxCalls
+	cb.config.Store(&cfg)
 }
 
 // startBackgroundWorkers starts background maintenance workers
@@ -702,16 +739,22 @@ func (cb *EnterpriseCircuitBreaker) startBackgroundWorkers() {
 	go cb.metricsWorker()
 
 	// Health monitoring worker
-	if cb.config.EnableHealthScoring {
+	if cb.conf().EnableHealthScoring {
 		cb.workerGroup.Add(1)
 		go cb.healthWorker()
 	}
 
 	// Adaptive threshold worker
-	if cb.config.EnableAdaptive {
+	if cb.conf().EnableAdaptive {
 		cb.workerGroup.Add(1)
 		go cb.adaptiveWorker()
 	}
+
+	// Synthetic half-open probes
+	if cb.conf().HealthProbe != nil {
+		cb.workerGroup.Add(1)
+		go cb.probeWorker()
+	}
 }
 
 // metricsWorker handles periodic metrics aggregation
@@ -801,11 +844,11 @@ func (cb *EnterpriseCircuitBreaker) checkHealth() {
 		cb.metrics.mu.Unlock()
 
 		// Take action based on health score
-		if health < cb.config.HealthThreshold {
+		if health < cb.conf().HealthThreshold {
 			cb.logger.Warn("Circuit breaker health score low",
-				zap.String("name", cb.config.Name),
+				zap.String("name", cb.conf().Name),
 				zap.Float64("health_score", health),
-				zap.Float64("threshold", cb.config.HealthThreshold))
+				zap.Float64("threshold", cb.conf().HealthThreshold))
 		}
 	}
 }
@@ -816,11 +859,13 @@ func (cb *EnterpriseCircuitBreaker) adjustAdaptiveThreshold() {
 		newThreshold := cb.adaptiveThreshold.AdjustThreshold(0.5) // Pass current performance
 		
 		cb.mu.Lock()
-		cb.config.FailureThreshold = newThreshold
+		cfg := *cb.conf()
+		cfg.FailureThreshold = newThreshold
+		cb.config.Store(&cfg)
 		cb.mu.Unlock()
 
 		cb.logger.Debug("Adaptive threshold adjusted",
-			zap.String("name", cb.config.Name),
+			zap.String("name", cb.conf().Name),
 			zap.Float64("new_threshold", newThreshold))
 	}
 }
@@ -841,6 +886,15 @@ func validateConfig(cfg *Config) error {
 	if cfg.HalfOpenMaxConcurrency <= 0 {
 		return fmt.Errorf("half open max concurrency must be positive")
 	}
+	if cfg.HalfOpenTrials < 0 {
+		return fmt.Errorf("half open trials must not be negative")
+	}
+	if cfg.ProbeInterval < 0 {
+		return fmt.Errorf("probe interval must not be negative")
+	}
+	if cfg.ProbeJitter < 0 || cfg.ProbeJitter > 1 {
+		return fmt.Errorf("probe jitter must be between 0 and 1")
+	}
 	return nil
 }
 
@@ -0,0 +1,158 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Runtime reconfiguration
+//
+// UpdateConfig changes thresholds, timeouts and strategies on a live
+// breaker. The update is validated before anything is applied, and the
+// new configuration is swapped in atomically, so a request in flight sees either the old settings or the
+// new ones, never a mix. Every applied change is kept in the history.
+
+// Strategy names accepted for TripStrategy and CooldownStrategy; empty
+// selects the default
+var (
+	tripStrategies     = map[string]bool{"": true, "consecutive": true, "percentage": true, "adaptive": true}
+	cooldownStrategies = map[string]bool{"": true, "fixed": true, "linear": true, "exponential": true, "adaptive": true}
+)
+
+// ConfigUpdate lists the settings to change; nil fields are left alone
+type ConfigUpdate struct {
+	FailureThreshold *float64
+	MaxFailures      *int
+	ResetTimeout     *time.Duration
+	Timeout          *time.Duration
+	HalfOpenMaxCalls *int
+	HalfOpenTrials   *int
+	ProbeInterval    *time.Duration
+	ProbeJitter      *float64
+	LatencyThreshold *time.Duration
+	HealthThreshold  *float64
+	TripStrategy     *string
+	CooldownStrategy *string
+}
+
+// ConfigChange is one setting altered by UpdateConfig
+type ConfigChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// CurrentConfig returns a copy of the breaker's configuration
+func (cb *EnterpriseCircuitBreaker) CurrentConfig() EnterpriseConfig {
+	return *cb.conf()
+}
+
+// UpdateConfig validates and applies u, recording reason in the history.
+// It returns the settings that actually changed; none is not an error.
+func (cb *EnterpriseCircuitBreaker) UpdateConfig(u ConfigUpdate, reason string) ([]ConfigChange, error) {
+	if err := validateUpdate(&u); err != nil {
+		return nil, err
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	next := *cb.conf()
+	var changes []ConfigChange
+	setFloat := func(field string, dst *float64, v *float64) {
+		if v != nil && *v != *dst {
+			changes = append(changes, ConfigChange{field, formatFloat(*dst), formatFloat(*v)})
+			*dst = *v
+		}
+	}
+	setInt := func(field string, dst *int, v *int) {
+		if v != nil && *v != *dst {
+			changes = append(changes, ConfigChange{field, strconv.Itoa(*dst), strconv.Itoa(*v)})
+			*dst = *v
+		}
+	}
+	setDuration := func(field string, dst *time.Duration, v *time.Duration) {
+		if v != nil && *v != *dst {
+			changes = append(changes, ConfigChange{field, dst.String(), v.String()})
+			*dst = *v
+		}
+	}
+	setString := func(field string, dst *string, v *string) {
+		if v != nil && *v != *dst {
+			changes = append(changes, ConfigChange{field, *dst, *v})
+			*dst = *v
+		}
+	}
+
+	setFloat("failure_threshold", &next.FailureThreshold, u.FailureThreshold)
+	setInt("max_failures", &next.MaxFailures, u.MaxFailures)
+	setDuration("reset_timeout", &next.ResetTimeout, u.ResetTimeout)
+	setDuration("timeout", &next.Timeout, u.Timeout)
+	setInt("half_open_max_calls", &next.HalfOpenMaxCalls, u.HalfOpenMaxCalls)
+	setInt("half_open_trials", &next.HalfOpenTrials, u.HalfOpenTrials)
+	setDuration("probe_interval", &next.ProbeInterval, u.ProbeInterval)
+	setFloat("probe_jitter", &next.ProbeJitter, u.ProbeJitter)
+	setDuration("latency_threshold", &next.LatencyThreshold, u.LatencyThreshold)
+	setFloat("health_threshold", &next.HealthThreshold, u.HealthThreshold)
+	setString("trip_strategy", &next.TripStrategy, u.TripStrategy)
+	setString("cooldown_strategy", &next.CooldownStrategy, u.CooldownStrategy)
+
+	if len(changes) == 0 {
+		return nil, nil
+	}
+	cb.config.Store(&next)
+
+	if reason == "" {
+		reason = "config updated"
+	}
+	cb.history.add(Event{
+		Time:    time.Now(),
+		Type:    EventConfigChange,
+		Reason:  reason,
+		Changes: changes,
+	})
+	cb.logger.Info("Circuit breaker reconfigured",
+		zap.String("name", next.Name),
+		zap.Any("changes", changes))
+	return changes, nil
+}
+
+// validateUpdate checks the settings an update provides. Settings it
+// leaves alone are not rechecked, so a breaker built with a value the
+// update rules would refuse can still be tuned in other respects.
+func validateUpdate(u *ConfigUpdate) error {
+	switch {
+	case u.FailureThreshold != nil && (*u.FailureThreshold < 0 || *u.FailureThreshold > 1):
+		return fmt.Errorf("failure threshold must be between 0 and 1")
+	case u.MaxFailures != nil && *u.MaxFailures <= 0:
+		return fmt.Errorf("max failures must be positive")
+	case u.ResetTimeout != nil && *u.ResetTimeout <= 0:
+		return fmt.Errorf("reset timeout must be positive")
+	case u.Timeout != nil && *u.Timeout <= 0:
+		return fmt.Errorf("timeout must be positive")
+	case u.HalfOpenMaxCalls != nil && *u.HalfOpenMaxCalls <= 0:
+		return fmt.Errorf("half open max calls must be positive")
+	case u.HalfOpenTrials != nil && *u.HalfOpenTrials < 0:
+		return fmt.Errorf("half open trials must not be negative")
+	case u.ProbeInterval != nil && *u.ProbeInterval < 0:
+		return fmt.Errorf("probe interval must not be negative")
+	case u.ProbeJitter != nil && (*u.ProbeJitter < 0 || *u.ProbeJitter > 1):
+		return fmt.Errorf("probe jitter must be between 0 and 1")
+	case u.LatencyThreshold != nil && *u.LatencyThreshold < 0:
+		return fmt.Errorf("latency threshold must not be negative")
+	case u.HealthThreshold != nil && (*u.HealthThreshold < 0 || *u.HealthThreshold > 1):
+		return fmt.Errorf("health threshold must be between 0 and 1")
+	case u.TripStrategy != nil && !tripStrategies[*u.TripStrategy]:
+		return fmt.Errorf("unknown trip strategy %q", *u.TripStrategy)
+	case u.CooldownStrategy != nil && !cooldownStrategies[*u.CooldownStrategy]:
+		return fmt.Errorf("unknown cooldown strategy %q", *u.CooldownStrategy)
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,502 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Breaker groups
+//
+// A BreakerGroup arranges breakers in a tree, e.g. endpoint breakers under
+// a provider group and provider groups under a chain group. On every
+// evaluation a group rolls up the state and recent failure rate of its
+// direct members and opens itself when its RollupPolicy says so; an open
+// group can in turn force-open its members (PropagateDown) so callers stop
+// hammering a provider whose endpoints are all flapping. A rolled-up group
+// releases itself and the members it forced after Cooldown and re-evaluates
+// from fresh counts.
+
+// RollupPolicy decides when member states open the parent group
+type RollupPolicy int
+
+const (
+	RollupNone  RollupPolicy = iota // Member states never open the group
+	RollupAny                       // Any open member opens the group
+	RollupAll                       // The group opens when every member is open
+	RollupRatio                     // The group opens when OpenRatio of members are open
+)
+
+// String returns the policy name
+func (p RollupPolicy) String() string {
+	switch p {
+	case RollupNone:
+		return "none"
+	case RollupAny:
+		return "any"
+	case RollupAll:
+		return "all"
+	case RollupRatio:
+		return "ratio"
+	default:
+		return "unknown"
+	}
+}
+
+// GroupMember is anything a group can contain: an EnterpriseCircuitBreaker
+// or another BreakerGroup
+type GroupMember interface {
+	State() State
+	GetMetrics() *CircuitBreakerMetrics
+	ForceOpen()
+	Reset()
+}
+
+// GroupConfig configures a BreakerGroup
+type GroupConfig struct {
+	Name   string
+	Rollup RollupPolicy
+	// OpenRatio is the fraction of open members that opens the group under RollupRatio
+	OpenRatio float64
+	// FailureRate opens the group when the members' combined failure rate
+	// since the last evaluation reaches it; 0 disables the check
+	FailureRate float64
+	// MinRequests is the request volume needed before FailureRate applies
+	MinRequests int64
+	// PropagateDown force-opens members while the group is open
+	PropagateDown bool
+	// Cooldown is how long a rolled-up group stays open before releasing
+	Cooldown time.Duration
+	// EvalInterval is the period used by Start
+	EvalInterval time.Duration
+	Logger       *zap.Logger
+	// OnStateChange is called when the group opens or closes
+	OnStateChange func(name string, from, to State)
+}
+
+// GroupStatus is a point-in-time view of a group and its members
+type GroupStatus struct {
+	Name        string         `json:"name"`
+	State       string         `json:"state"`
+	Reason      string         `json:"reason,omitempty"`
+	OpenedAt    *time.Time     `json:"opened_at,omitempty"`
+	FailureRate float64        `json:"failure_rate"`
+	Members     []MemberStatus `json:"members"`
+}
+
+// MemberStatus describes one member of a group
+type MemberStatus struct {
+	Name           string       `json:"name"`
+	State          string       `json:"state"`
+	ForcedByParent bool         `json:"forced_by_parent,omitempty"`
+	Group          *GroupStatus `json:"group,omitempty"`
+}
+
+type groupMember struct {
+	member   GroupMember
+	forced   bool  // Force-opened by this group
+	lastReqs int64 // Request totals at the previous evaluation
+	lastFail int64
+}
+
+// BreakerGroup rolls member breakers up into a parent breaker
+type BreakerGroup struct {
+	cfg    GroupConfig
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	members     map[string]*groupMember
+	parent      *BreakerGroup
+	state       State // StateClosed, StateOpen (rolled up) or StateForceOpen
+	reason      string
+	openedAt    time.Time
+	failureRate float64
+}
+
+// NewBreakerGroup creates an empty group
+func NewBreakerGroup(cfg GroupConfig) (*BreakerGroup, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if cfg.Rollup == RollupRatio && (cfg.OpenRatio <= 0 || cfg.OpenRatio > 1) {
+		return nil, fmt.Errorf("open ratio must be in (0, 1] for ratio rollup")
+	}
+	if cfg.FailureRate < 0 || cfg.FailureRate > 1 {
+		return nil, fmt.Errorf("failure rate must be between 0 and 1")
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.EvalInterval <= 0 {
+		cfg.EvalInterval = time.Second
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	return &BreakerGroup{
+		cfg:     cfg,
+		logger:  logger,
+		members: make(map[string]*groupMember),
+		state:   StateClosed,
+	}, nil
+}
+
+// Name returns the group name
+func (g *BreakerGroup) Name() string {
+	return g.cfg.Name
+}
+
+// Add registers a breaker or sub-group under name
+func (g *BreakerGroup) Add(name string, member GroupMember) error {
+	if member == nil {
+		return fmt.Errorf("member %s is nil", name)
+	}
+	if sub, ok := member.(*BreakerGroup); ok {
+		if sub == g || sub.hasDescendant(g) {
+			return fmt.Errorf("adding group %s under %s would create a cycle", sub.cfg.Name, g.cfg.Name)
+		}
+		sub.mu.Lock()
+		if sub.parent != nil {
+			sub.mu.Unlock()
+			return fmt.Errorf("group %s already has a parent", sub.cfg.Name)
+		}
+		sub.parent = g
+		sub.mu.Unlock()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, exists := g.members[name]; exists {
+		return fmt.Errorf("member %s already registered in group %s", name, g.cfg.Name)
+	}
+	g.members[name] = &groupMember{member: member}
+	return nil
+}
+
+func (g *BreakerGroup) hasDescendant(target *BreakerGroup) bool {
+	g.mu.Lock()
+	subs := make([]*BreakerGroup, 0)
+	for _, m := range g.members {
+		if sub, ok := m.member.(*BreakerGroup); ok {
+			subs = append(subs, sub)
+		}
+	}
+	g.mu.Unlock()
+
+	for _, sub := range subs {
+		if sub == target || sub.hasDescendant(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Member returns a registered member
+func (g *BreakerGroup) Member(name string) (GroupMember, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	m, ok := g.members[name]
+	if !ok {
+		return nil, false
+	}
+	return m.member, true
+}
+
+// State returns StateOpen while the group is rolled up open and
+// StateForceOpen while it was forced open by an operator or its parent
+func (g *BreakerGroup) State() State {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state
+}
+
+// ForceOpen opens the group until Reset
+func (g *BreakerGroup) ForceOpen() {
+	g.mu.Lock()
+	from := g.state
+	g.state = StateForceOpen
+	g.reason = "forced open"
+	g.openedAt = time.Now()
+	g.mu.Unlock()
+	g.notify(from, StateForceOpen)
+}
+
+// Reset closes the group and releases members it forced open
+func (g *BreakerGroup) Reset() {
+	g.mu.Lock()
+	from := g.state
+	g.closeLocked()
+	g.mu.Unlock()
+	if from != StateClosed {
+		g.notify(from, StateClosed)
+	}
+}
+
+// closeLocked closes the group, releases forced members and restarts the
+// failure-rate window
+func (g *BreakerGroup) closeLocked() {
+	g.state = StateClosed
+	g.reason = ""
+	g.openedAt = time.Time{}
+	for _, m := range g.members {
+		if m.forced {
+			m.forced = false
+			m.member.Reset()
+		}
+		metrics := m.member.GetMetrics()
+		m.lastReqs = metrics.TotalRequests
+		m.lastFail = metrics.FailedRequests
+	}
+}
+
+// GetMetrics sums request counters over all members
+func (g *BreakerGroup) GetMetrics() *CircuitBreakerMetrics {
+	g.mu.Lock()
+	members := make([]GroupMember, 0, len(g.members))
+	for _, m := range g.members {
+		members = append(members, m.member)
+	}
+	g.mu.Unlock()
+
+	agg := newCircuitBreakerMetrics()
+	for _, m := range members {
+		mm := m.GetMetrics()
+		agg.TotalRequests += mm.TotalRequests
+		agg.SuccessfulRequests += mm.SuccessfulRequests
+		agg.FailedRequests += mm.FailedRequests
+		agg.TimeoutRequests += mm.TimeoutRequests
+		agg.CircuitOpenRequests += mm.CircuitOpenRequests
+	}
+	if agg.TotalRequests > 0 {
+		agg.FailureRate = float64(agg.FailedRequests) / float64(agg.TotalRequests)
+	}
+	return agg
+}
+
+// Allow reports whether a request to member name may proceed: the member
+// and every enclosing group must be closed
+func (g *BreakerGroup) Allow(name string) bool {
+	member, ok := g.Member(name)
+	if !ok || !g.chainAllows() {
+		return false
+	}
+	switch m := member.(type) {
+	case *EnterpriseCircuitBreaker:
+		return m.Allow()
+	default:
+		return isClosedState(m.State())
+	}
+}
+
+// Execute runs fn through member name when the group chain allows it
+func (g *BreakerGroup) Execute(ctx context.Context, name string, fn func() (interface{}, error)) (*ExecutionResult, error) {
+	member, ok := g.Member(name)
+	if !ok {
+		return nil, fmt.Errorf("member %s not found in group %s", name, g.cfg.Name)
+	}
+	cb, ok := member.(*EnterpriseCircuitBreaker)
+	if !ok {
+		return nil, fmt.Errorf("member %s of group %s is not a breaker", name, g.cfg.Name)
+	}
+	if !g.chainAllows() {
+		atomic.AddInt64(&cb.metrics.CircuitOpenRequests, 1)
+		return &ExecutionResult{
+			Success:     false,
+			Error:       fmt.Errorf("breaker group %s is open", g.openAncestor()),
+			State:       StateOpen,
+			FailureType: FailureTypeCircuit,
+		}, nil
+	}
+	return cb.ExecuteWithContext(ctx, fn)
+}
+
+func (g *BreakerGroup) chainAllows() bool {
+	return g.openAncestor() == ""
+}
+
+// openAncestor returns the name of the nearest open group from g upwards
+func (g *BreakerGroup) openAncestor() string {
+	for cur := g; cur != nil; {
+		cur.mu.Lock()
+		open, parent := cur.state != StateClosed, cur.parent
+		cur.mu.Unlock()
+		if open {
+			return cur.cfg.Name
+		}
+		cur = parent
+	}
+	return ""
+}
+
+func isClosedState(s State) bool {
+	return s == StateClosed || s == StateForceClose
+}
+
+func isOpenState(s State) bool {
+	return s == StateOpen || s == StateForceOpen
+}
+
+// Evaluate rolls member state up into the group, bottom-up through nested
+// groups, and propagates an open group down to its members
+func (g *BreakerGroup) Evaluate() {
+	g.mu.Lock()
+	subs := make([]*BreakerGroup, 0)
+	for _, m := range g.members {
+		if sub, ok := m.member.(*BreakerGroup); ok {
+			subs = append(subs, sub)
+		}
+	}
+	g.mu.Unlock()
+	for _, sub := range subs {
+		sub.Evaluate()
+	}
+
+	g.mu.Lock()
+	from := g.state
+	now := time.Now()
+
+	// Cooldown: release a rolled-up group and start measuring afresh
+	if g.state == StateOpen && now.Sub(g.openedAt) >= g.cfg.Cooldown {
+		g.closeLocked()
+	}
+
+	var open, considered int
+	var reqs, fails int64
+	for _, m := range g.members {
+		metrics := m.member.GetMetrics()
+		reqs += metrics.TotalRequests - m.lastReqs
+		fails += metrics.FailedRequests - m.lastFail
+		m.lastReqs, m.lastFail = metrics.TotalRequests, metrics.FailedRequests
+		if m.forced {
+			continue // Open because of us, says nothing about the member
+		}
+		considered++
+		if isOpenState(m.member.State()) {
+			open++
+		}
+	}
+	if reqs > 0 {
+		g.failureRate = float64(fails) / float64(reqs)
+	}
+
+	if g.state == StateClosed {
+		if reason := g.rollupReasonLocked(open, considered, reqs); reason != "" {
+			g.state = StateOpen
+			g.reason = reason
+			g.openedAt = now
+		}
+	}
+
+	if g.cfg.PropagateDown && g.state != StateClosed {
+		for _, m := range g.members {
+			if !m.forced && !isOpenState(m.member.State()) {
+				m.member.ForceOpen()
+				m.forced = true
+			}
+		}
+	}
+	to := g.state
+	g.mu.Unlock()
+
+	if from != to {
+		g.notify(from, to)
+	}
+}
+
+func (g *BreakerGroup) rollupReasonLocked(open, considered int, reqs int64) string {
+	if considered > 0 {
+		switch g.cfg.Rollup {
+		case RollupAny:
+			if open > 0 {
+				return fmt.Sprintf("%d of %d members open", open, considered)
+			}
+		case RollupAll:
+			if open == considered {
+				return "all members open"
+			}
+		case RollupRatio:
+			if float64(open)/float64(considered) >= g.cfg.OpenRatio {
+				return fmt.Sprintf("%d of %d members open", open, considered)
+			}
+		}
+	}
+	if g.cfg.FailureRate > 0 && reqs >= g.cfg.MinRequests && reqs > 0 && g.failureRate >= g.cfg.FailureRate {
+		return fmt.Sprintf("member failure rate %.2f", g.failureRate)
+	}
+	return ""
+}
+
+func (g *BreakerGroup) notify(from, to State) {
+	g.logger.Info("Breaker group state changed",
+		zap.String("group", g.cfg.Name),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()))
+	if g.cfg.OnStateChange != nil {
+		go g.cfg.OnStateChange(g.cfg.Name, from, to)
+	}
+}
+
+// Start evaluates the group every EvalInterval until ctx is cancelled.
+// Only the root group of a tree needs to be started.
+func (g *BreakerGroup) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(g.cfg.EvalInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.Evaluate()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Status returns the group tree with member states
+func (g *BreakerGroup) Status() GroupStatus {
+	g.mu.Lock()
+	st := GroupStatus{
+		Name:        g.cfg.Name,
+		State:       g.state.String(),
+		Reason:      g.reason,
+		FailureRate: g.failureRate,
+	}
+	if !g.openedAt.IsZero() {
+		t := g.openedAt
+		st.OpenedAt = &t
+	}
+	names := make([]string, 0, len(g.members))
+	for name := range g.members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	type entry struct {
+		name   string
+		member GroupMember
+		forced bool
+	}
+	entries := make([]entry, 0, len(names))
+	for _, name := range names {
+		m := g.members[name]
+		entries = append(entries, entry{name, m.member, m.forced})
+	}
+	g.mu.Unlock()
+
+	for _, e := range entries {
+		ms := MemberStatus{Name: e.name, State: e.member.State().String(), ForcedByParent: e.forced}
+		if sub, ok := e.member.(*BreakerGroup); ok {
+			sub := sub.Status()
+			ms.Group = &sub
+		}
+		st.Members = append(st.Members, ms)
+	}
+	return st
+}
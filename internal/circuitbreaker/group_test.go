@@ -0,0 +1,168 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeMember is a GroupMember whose state and counters the test sets directly
+type fakeMember struct {
+	state    State
+	requests int64
+	failures int64
+}
+
+func (f *fakeMember) State() State { return f.state }
+func (f *fakeMember) ForceOpen()   { f.state = StateForceOpen }
+func (f *fakeMember) Reset()       { f.state = StateClosed }
+
+func (f *fakeMember) GetMetrics() *CircuitBreakerMetrics {
+	m := newCircuitBreakerMetrics()
+	m.TotalRequests = f.requests
+	m.FailedRequests = f.failures
+	return m
+}
+
+func newTestGroup(t *testing.T, cfg GroupConfig, n int) (*BreakerGroup, []*fakeMember) {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = "provider"
+	}
+	g, err := NewBreakerGroup(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	members := make([]*fakeMember, n)
+	for i := range members {
+		members[i] = &fakeMember{state: StateClosed}
+		if err := g.Add(string(rune('a'+i)), members[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g, members
+}
+
+func TestBreakerGroupRollup(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    GroupConfig
+		open   int // Members opened, out of 4
+		opened bool
+	}{
+		{"none ignores open members", GroupConfig{Rollup: RollupNone}, 4, false},
+		{"any with one open", GroupConfig{Rollup: RollupAny}, 1, true},
+		{"any with none open", GroupConfig{Rollup: RollupAny}, 0, false},
+		{"all with some open", GroupConfig{Rollup: RollupAll}, 3, false},
+		{"all with all open", GroupConfig{Rollup: RollupAll}, 4, true},
+		{"ratio below", GroupConfig{Rollup: RollupRatio, OpenRatio: 0.5}, 1, false},
+		{"ratio reached", GroupConfig{Rollup: RollupRatio, OpenRatio: 0.5}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, members := newTestGroup(t, tt.cfg, 4)
+			for _, m := range members[:tt.open] {
+				m.state = StateOpen
+			}
+			g.Evaluate()
+			if got := g.State() == StateOpen; got != tt.opened {
+				t.Fatalf("group state %s, want open=%v", g.State(), tt.opened)
+			}
+			if g.chainAllows() == tt.opened {
+				t.Fatalf("group allows requests = %v while open=%v", !tt.opened, tt.opened)
+			}
+		})
+	}
+}
+
+func TestBreakerGroupFailureRate(t *testing.T) {
+	g, members := newTestGroup(t, GroupConfig{FailureRate: 0.5, MinRequests: 10}, 2)
+
+	// Every request failed, but too few to judge
+	members[0].requests, members[0].failures = 4, 4
+	g.Evaluate()
+	if g.State() != StateClosed {
+		t.Fatal("group opened below MinRequests")
+	}
+
+	// Counts are per evaluation window: 10 new requests, 4 failed
+	members[0].requests, members[0].failures = 9, 6
+	members[1].requests, members[1].failures = 5, 2
+	g.Evaluate()
+	if g.State() != StateClosed {
+		t.Fatalf("group opened at failure rate %.2f", g.Status().FailureRate)
+	}
+
+	// 10 new requests, 6 failed
+	members[0].requests, members[0].failures = 14, 10
+	members[1].requests, members[1].failures = 10, 4
+	g.Evaluate()
+	if g.State() != StateOpen {
+		t.Fatalf("group closed at failure rate %.2f", g.Status().FailureRate)
+	}
+}
+
+func TestBreakerGroupCooldownReleasesForced(t *testing.T) {
+	g, members := newTestGroup(t, GroupConfig{Rollup: RollupAny, PropagateDown: true, Cooldown: time.Millisecond}, 3)
+
+	members[0].state = StateOpen
+	g.Evaluate()
+	if g.State() != StateOpen {
+		t.Fatal("group not opened by its member")
+	}
+	for _, m := range members[1:] {
+		if m.state != StateForceOpen {
+			t.Fatalf("member not forced open: %s", m.state)
+		}
+	}
+	st := g.Status()
+	if st.Members[0].ForcedByParent || !st.Members[1].ForcedByParent {
+		t.Fatalf("forced flags wrong: %+v", st.Members)
+	}
+	// Forced members do not hold the group open on their own
+	members[0].state = StateClosed
+
+	time.Sleep(5 * time.Millisecond)
+	g.Evaluate()
+	if g.State() != StateClosed {
+		t.Fatalf("group still %s after cooldown", g.State())
+	}
+	for _, m := range members {
+		if m.state != StateClosed {
+			t.Fatalf("member left %s after cooldown", m.state)
+		}
+	}
+}
+
+func TestBreakerGroupTree(t *testing.T) {
+	chain, err := NewBreakerGroup(GroupConfig{Name: "chain", Rollup: RollupAll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, members := newTestGroup(t, GroupConfig{Rollup: RollupAny}, 1)
+	if err := chain.Add("provider", provider); err != nil {
+		t.Fatal(err)
+	}
+
+	members[0].state = StateOpen
+	chain.Evaluate()
+	if provider.State() != StateOpen || chain.State() != StateOpen {
+		t.Fatalf("rollup did not reach the root: provider %s, chain %s", provider.State(), chain.State())
+	}
+
+	if err := provider.Add("chain", chain); err == nil {
+		t.Fatal("cycle accepted")
+	}
+	if err := chain.Add("self", chain); err == nil {
+		t.Fatal("group added to itself")
+	}
+	other, err := NewBreakerGroup(GroupConfig{Name: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Add("provider", provider); err == nil {
+		t.Fatal("group accepted a second parent")
+	}
+	if err := chain.Add("provider", &fakeMember{}); err == nil {
+		t.Fatal("duplicate member name accepted")
+	}
+}
@@ -0,0 +1,143 @@
+package circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event history
+//
+// Every breaker keeps a bounded ring of recent events: state transitions
+// with the reason for them, failure bursts (runs of consecutive failures)
+// once they end, and runtime configuration changes. GetHistory exports it so an incident can be
+// reconstructed at request granularity rather than from scraped metrics.
+
+// DefaultHistorySize is the number of events kept when Config.HistorySize is unset
+const DefaultHistorySize = 256
+
+// failureBurstMin is the shortest run of consecutive failures recorded as a burst
+const failureBurstMin = 3
+
+// maxEventErrorLen bounds error text stored in an event
+const maxEventErrorLen = 256
+
+// EventType identifies what a history Event records
+type EventType string
+
+const (
+	EventStateChange  EventType = "state_change"
+	EventTrip         EventType = "trip" // Transition into open or force-open
+	EventFailureBurst EventType = "failure_burst"
+	EventConfigChange EventType = "config_change"
+)
+
+// Event is one entry in a breaker's history
+type Event struct {
+	Time     time.Time  `json:"time"`
+	Type     EventType  `json:"type"`
+	From     string     `json:"from,omitempty"`
+	To       string     `json:"to,omitempty"`
+	Reason   string     `json:"reason,omitempty"`
+	Failures int64      `json:"failures,omitempty"` // Consecutive failures at the event
+	Started  *time.Time `json:"started,omitempty"`  // First failure of a burst
+	Error    string     `json:"error,omitempty"`    // Last error seen
+
+	Changes []ConfigChange `json:"changes,omitempty"` // Settings altered by a config_change
+}
+
+// eventRing is a fixed-size ring of events
+type eventRing struct {
+	mu     sync.Mutex
+	events []Event
+	next   int
+	full   bool
+}
+
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		size = DefaultHistorySize
+	}
+	return &eventRing{events: make([]Event, size)}
+}
+
+func (r *eventRing) add(e Event) {
+	r.mu.Lock()
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// snapshot returns the events oldest first
+func (r *eventRing) snapshot() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Event(nil), r.events[:r.next]...)
+	}
+	out := make([]Event, 0, len(r.events))
+	out = append(out, r.events[r.next:]...)
+	return append(out, r.events[:r.next]...)
+}
+
+// GetHistory returns the breaker's recent events, oldest first
+func (cb *EnterpriseCircuitBreaker) GetHistory() []Event {
+	return cb.history.snapshot()
+}
+
+// recordTransition adds a state change to the history; cb.mu is held
+func (cb *EnterpriseCircuitBreaker) recordTransition(from, to State, reason string) {
+	e := Event{
+		Time:     time.Now(),
+		Type:     EventStateChange,
+		From:     from.String(),
+		To:       to.String(),
+		Reason:   reason,
+		Failures: atomic.LoadInt64(&cb.consecutiveFailures),
+	}
+	if to == StateOpen || to == StateForceOpen {
+		e.Type = EventTrip
+		e.Error = cb.burstErr
+	}
+	cb.history.add(e)
+}
+
+// trackFailure extends the current failure run; cb.mu is held
+func (cb *EnterpriseCircuitBreaker) trackFailure(now time.Time, result *ExecutionResult) {
+	if cb.burstFailures == 0 {
+		cb.burstStart = now
+	}
+	cb.burstFailures++
+	if result.Error != nil {
+		cb.burstErr = truncateError(fmt.Sprintf("%s: %v", result.FailureType, result.Error))
+	}
+}
+
+// endFailureRun records the run that a success just ended if it was long
+// enough to count as a burst; cb.mu is held
+func (cb *EnterpriseCircuitBreaker) endFailureRun(now time.Time) {
+	if cb.burstFailures >= failureBurstMin {
+		started := cb.burstStart
+		cb.history.add(Event{
+			Time:     now,
+			Type:     EventFailureBurst,
+			Reason:   fmt.Sprintf("%d consecutive failures over %s", cb.burstFailures, now.Sub(started).Round(time.Millisecond)),
+			Failures: cb.burstFailures,
+			Started:  &started,
+			Error:    cb.burstErr,
+		})
+	}
+	cb.burstFailures = 0
+	cb.burstErr = ""
+}
+
+func truncateError(s string) string {
+	if len(s) > maxEventErrorLen {
+		return s[:maxEventErrorLen] + "..."
+	}
+	return s
+}
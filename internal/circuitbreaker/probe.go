@@ -0,0 +1,184 @@
+package circuitbreaker
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Half-open probe scheduling
+//
+// Once the reset timeout has passed an open breaker goes half-open and
+// admits up to trialBudget() trial requests, spaced ProbeInterval apart
+// (spread by ProbeJitter so a fleet of breakers does not probe in lockstep)
+// and never more than HalfOpenMaxCalls at a time. All trials succeeding
+// closes the breaker; any failure reopens it. When a HealthProbe is set, a
+// background worker runs it in place of a trial slot that organic traffic
+// left unused, so recovery is detected on idle chains too.
+
+const minProbeTick = 100 * time.Millisecond
+
+// trialBudget is the number of successful trials that closes a half-open breaker
+func (cb *EnterpriseCircuitBreaker) trialBudget() int {
+	if cb.conf().HalfOpenTrials > 0 {
+		return cb.conf().HalfOpenTrials
+	}
+	if cb.conf().HalfOpenMaxCalls > 0 {
+		return cb.conf().HalfOpenMaxCalls
+	}
+	return 1
+}
+
+// probeDelay returns the spacing to the next trial: ProbeInterval spread
+// by up to +/-ProbeJitter
+func (cb *EnterpriseCircuitBreaker) probeDelay() time.Duration {
+	base := cb.conf().ProbeInterval
+	if base <= 0 || cb.conf().ProbeJitter <= 0 {
+		return base
+	}
+	spread := cb.conf().ProbeJitter * (2*rand.Float64() - 1)
+	return time.Duration(float64(base) * (1 + spread))
+}
+
+// admit decides whether a request may run. With reserve set a half-open
+// admission takes a trial slot identified by the returned generation,
+// which must be handed back through finishTrial.
+func (cb *EnterpriseCircuitBreaker) admit(reserve bool) (bool, uint64) {
+	// Fast path: no state change possible
+	cb.mu.RLock()
+	if cb.forceState == nil && cb.state == StateClosed {
+		cb.mu.RUnlock()
+		return true, 0
+	}
+	cb.mu.RUnlock()
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.admitLocked(time.Now(), reserve)
+}
+
+// admitLocked is admit with cb.mu held
+func (cb *EnterpriseCircuitBreaker) admitLocked(now time.Time, reserve bool) (bool, uint64) {
+	// Handle forced states
+	if cb.forceState != nil {
+		switch *cb.forceState {
+		case StateForceOpen:
+			return false, 0
+		case StateForceClose:
+			return true, 0
+		}
+	}
+
+	switch cb.state {
+	case StateClosed:
+		return true, 0
+	case StateOpen:
+		if now.Sub(cb.stateChangedAt) < cb.conf().ResetTimeout {
+			return false, 0
+		}
+		if !reserve {
+			return true, 0
+		}
+		cb.changeState(StateHalfOpen, "reset timeout elapsed")
+	case StateHalfOpen:
+	default:
+		return false, 0
+	}
+
+	// Half-open: one trial per due slot within the budget and concurrency cap
+	if cb.trialsStarted >= cb.trialBudget() ||
+		atomic.LoadInt64(&cb.halfOpenCalls) >= int64(cb.conf().HalfOpenMaxCalls) ||
+		now.Before(cb.nextTrialAt) {
+		return false, 0
+	}
+	if !reserve {
+		return true, 0
+	}
+
+	cb.trialsStarted++
+	atomic.AddInt64(&cb.halfOpenCalls, 1)
+	atomic.AddInt64(&cb.metrics.TrialRequests, 1)
+	cb.lastTrialAt = now
+	cb.nextTrialAt = now.Add(cb.probeDelay())
+	return true, cb.probeGen
+}
+
+// finishTrial releases a trial slot unless the breaker has since left the
+// half-open period the trial belonged to
+func (cb *EnterpriseCircuitBreaker) finishTrial(gen uint64) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == StateHalfOpen && gen == cb.probeGen && atomic.LoadInt64(&cb.halfOpenCalls) > 0 {
+		atomic.AddInt64(&cb.halfOpenCalls, -1)
+	}
+}
+
+// probeTick is how often the probe worker checks for unused trial slots
+func (cb *EnterpriseCircuitBreaker) probeTick() time.Duration {
+	tick := cb.conf().ProbeInterval
+	if tick <= 0 {
+		tick = cb.conf().ResetTimeout / 4
+	}
+	if tick < minProbeTick {
+		tick = minProbeTick
+	}
+	return tick
+}
+
+// probeWorker runs the synthetic health probe whenever a trial is due and
+// no organic request has taken a trial for a full tick
+func (cb *EnterpriseCircuitBreaker) probeWorker() {
+	defer cb.workerGroup.Done()
+
+	tick := cb.probeTick()
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cb.runHealthProbe(tick)
+		case <-cb.shutdownChan:
+			return
+		}
+	}
+}
+
+// runHealthProbe takes a due trial slot for the synthetic probe and records
+// its outcome like any other trial
+func (cb *EnterpriseCircuitBreaker) runHealthProbe(idle time.Duration) {
+	now := time.Now()
+	cb.mu.Lock()
+	if cb.forceState != nil || cb.state == StateClosed ||
+		(cb.state == StateHalfOpen && now.Sub(cb.lastTrialAt) < idle) {
+		cb.mu.Unlock()
+		return
+	}
+	allowed, gen := cb.admitLocked(now, true)
+	cb.mu.Unlock()
+	if !allowed || gen == 0 {
+		return
+	}
+	defer cb.finishTrial(gen)
+	atomic.AddInt64(&cb.metrics.ProbeRequests, 1)
+
+	ctx, cancel := context.WithTimeout(cb.ctx, cb.conf().Timeout)
+	defer cancel()
+	result := cb.executeWithMonitoring(ctx, func() (interface{}, error) {
+		if err := cb.conf().HealthProbe(ctx); err != nil {
+			return nil, fmt.Errorf("health probe: %w", err)
+		}
+		return nil, nil
+	}, now)
+	result.Metadata = map[string]interface{}{"trial": true, "probe": true}
+	cb.recordResult(result)
+
+	cb.logger.Debug("Circuit breaker health probe",
+		zap.String("name", cb.conf().Name),
+		zap.Bool("success", result.Success),
+		zap.Duration("duration", result.Duration))
+}
@@ -1,6 +1,9 @@
 package circuitbreaker
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // State represents the current state of a circuit breaker
 type State int
@@ -28,4 +31,13 @@ type Config struct {
 	// Enterprise features
 	TierSettings        interface{}
 	EnableHealthScoring bool
+
+	// Half-open probing
+	HalfOpenTrials int                             // Successful trials needed to close; defaults to HalfOpenMaxConcurrency
+	ProbeInterval  time.Duration                   // Base spacing between half-open trials; 0 admits them back to back
+	ProbeJitter    float64                         // Random spread applied to ProbeInterval as a fraction (0-1)
+	HealthProbe    func(ctx context.Context) error // Synthetic trial run when no organic request takes a due slot
+
+	// Incident history
+	HistorySize int // Events kept for GetHistory; defaults to DefaultHistorySize
 }
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TierSLA is what a tier is promised: the P99 latency target requests are
+// held to and the terms quoted to customers
+type TierSLA struct {
+	LatencyTarget   time.Duration // P99 target; also sets request deadlines
+	Uptime          string
+	Support         string
+	DataRetention   string
+	CustomEndpoints string // Empty when the tier has none
+}
+
+// SLAConfig holds the SLA of each tier, and per-chain overrides for
+// deployments whose chains are held to different targets. The zero value
+// serves the built-in defaults.
+type SLAConfig struct {
+	Tiers  map[Tier]TierSLA
+	Chains map[string]map[Tier]TierSLA // Set fields override Tiers on that chain
+}
+
+// For returns tier's SLA on chain. Tiers without an SLA get the free
+// tier's; chain overrides replace only the fields they set.
+func (c SLAConfig) For(chain string, tier Tier) TierSLA {
+	tiers := c.Tiers
+	if tiers == nil {
+		tiers = defaultTierSLAs()
+	}
+	sla, ok := tiers[tier]
+	if !ok {
+		sla = tiers[TierFree]
+	}
+	if o, ok := c.Chains[strings.ToLower(chain)][tier]; ok {
+		sla = mergeSLA(sla, o)
+	}
+	if sla.LatencyTarget <= 0 {
+		sla.LatencyTarget = 250 * time.Millisecond
+	}
+	return sla
+}
+
+// mergeSLA returns base with the fields set in o replaced
+func mergeSLA(base, o TierSLA) TierSLA {
+	if o.LatencyTarget > 0 {
+		base.LatencyTarget = o.LatencyTarget
+	}
+	if o.Uptime != "" {
+		base.Uptime = o.Uptime
+	}
+	if o.Support != "" {
+		base.Support = o.Support
+	}
+	if o.DataRetention != "" {
+		base.DataRetention = o.DataRetention
+	}
+	if o.CustomEndpoints != "" {
+		base.CustomEndpoints = o.CustomEndpoints
+	}
+	return base
+}
+
+// defaultTierSLAs returns the SLA each tier is sold with
+func defaultTierSLAs() map[Tier]TierSLA {
+	return map[Tier]TierSLA{
+		TierFree: {
+			LatencyTarget: 250 * time.Millisecond,
+			Uptime:        "95%",
+			Support:       "Community forum",
+			DataRetention: "30 days",
+		},
+		TierPro: {
+			LatencyTarget: 150 * time.Millisecond,
+			Uptime:        "99%",
+			Support:       "Email support",
+			DataRetention: "6 months",
+		},
+		TierBusiness: {
+			LatencyTarget: 100 * time.Millisecond,
+			Uptime:        "99.5%",
+			Support:       "Business hours",
+			DataRetention: "1 year",
+		},
+		TierTurbo: {
+			LatencyTarget: 75 * time.Millisecond,
+			Uptime:        "99.9%",
+			Support:       "Priority support",
+			DataRetention: "2 years",
+		},
+		TierEnterprise: {
+			LatencyTarget:   50 * time.Millisecond,
+			Uptime:          "99.99%",
+			Support:         "24/7 dedicated",
+			DataRetention:   "7 years",
+			CustomEndpoints: "Available",
+		},
+	}
+}
+
+// slaFileEntry is one tier's entry in SLA_CONFIG_FILE. Zero fields keep
+// the default; the rate limit fields are read from tier entries only.
+type slaFileEntry struct {
+	LatencyTargetMS   int    `json:"latency_target_ms"`
+	Uptime            string `json:"sla_uptime"`
+	Support           string `json:"support"`
+	DataRetention     string `json:"data_retention"`
+	CustomEndpoints   string `json:"custom_endpoints"`
+	RequestsPerSecond int    `json:"requests_per_second"`
+	RequestsPerHour   int    `json:"requests_per_hour"`
+	BurstCapacity     int    `json:"burst_capacity"`
+}
+
+func (e slaFileEntry) sla() TierSLA {
+	return TierSLA{
+		LatencyTarget:   time.Duration(e.LatencyTargetMS) * time.Millisecond,
+		Uptime:          e.Uptime,
+		Support:         e.Support,
+		DataRetention:   e.DataRetention,
+		CustomEndpoints: e.CustomEndpoints,
+	}
+}
+
+// slaFile is the layout of SLA_CONFIG_FILE. Chains are named bitcoin,
+// ethereum or solana:
+//
+//	{"tiers":  {"enterprise": {"latency_target_ms": 40, "requests_per_hour": 800000}},
+//	 "chains": {"solana": {"enterprise": {"latency_target_ms": 30}}}}
+type slaFile struct {
+	Tiers  map[Tier]slaFileEntry            `json:"tiers"`
+	Chains map[string]map[Tier]slaFileEntry `json:"chains"`
+}
+
+// loadSLAConfig returns the default SLAs overlaid with path, when set, and
+// then with LATENCY_TARGETS_MS. Rate limit fields in path are applied to
+// limits. An unreadable file is fatal: the deployment would otherwise run
+// quietly on terms it did not agree to.
+func loadSLAConfig(path string, limits map[Tier]TierRateLimit) (SLAConfig, error) {
+	c := SLAConfig{Tiers: defaultTierSLAs(), Chains: map[string]map[Tier]TierSLA{}}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return c, fmt.Errorf("read SLA config: %w", err)
+		}
+		var f slaFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return c, fmt.Errorf("parse SLA config %s: %w", path, err)
+		}
+		for tier, e := range f.Tiers {
+			if _, known := c.Tiers[tier]; !known {
+				return c, fmt.Errorf("SLA config %s: unknown tier %q", path, tier)
+			}
+			c.Tiers[tier] = mergeSLA(c.Tiers[tier], e.sla())
+			if limit, ok := limits[tier]; ok {
+				limits[tier] = e.applyRateLimits(limit)
+			}
+		}
+		for chain, tiers := range f.Chains {
+			for tier, e := range tiers {
+				if _, known := c.Tiers[tier]; !known {
+					return c, fmt.Errorf("SLA config %s: unknown tier %q for chain %s", path, tier, chain)
+				}
+				c.setChain(chain, tier, e.sla())
+			}
+		}
+	}
+	applyLatencyTargets(&c, getEnv("LATENCY_TARGETS_MS", ""))
+	return c, nil
+}
+
+// applyRateLimits returns limit with the rate fields e sets replaced
+func (e slaFileEntry) applyRateLimits(limit TierRateLimit) TierRateLimit {
+	if e.RequestsPerSecond > 0 {
+		limit.RequestsPerSecond = e.RequestsPerSecond
+	}
+	if e.RequestsPerHour > 0 {
+		limit.RequestsPerHour = e.RequestsPerHour
+		limit.RefillRate = float64(e.RequestsPerHour) / 3600.0
+	}
+	if e.BurstCapacity > 0 {
+		limit.BurstCapacity = e.BurstCapacity
+	}
+	return limit
+}
+
+// setChain merges o into chain's override for tier
+func (c *SLAConfig) setChain(chain string, tier Tier, o TierSLA) {
+	chain = strings.ToLower(chain)
+	if c.Chains[chain] == nil {
+		c.Chains[chain] = map[Tier]TierSLA{}
+	}
+	c.Chains[chain][tier] = mergeSLA(c.Chains[chain][tier], o)
+}
+
+// applyLatencyTargets sets latency targets from "tier=ms" and
+// "chain:tier=ms" entries; malformed entries are logged and skipped
+func applyLatencyTargets(c *SLAConfig, spec string) {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		chain, tierName, perChain := strings.Cut(strings.TrimSpace(name), ":")
+		if !perChain {
+			tierName, chain = chain, ""
+		}
+		tier := Tier(strings.ToLower(strings.TrimSpace(tierName)))
+		ms, err := strconv.Atoi(strings.TrimSpace(value))
+		_, known := c.Tiers[tier]
+		if !ok || err != nil || ms <= 0 || !known {
+			log.Printf("Ignoring invalid LATENCY_TARGETS_MS entry %q", entry)
+			continue
+		}
+		target := TierSLA{LatencyTarget: time.Duration(ms) * time.Millisecond}
+		if perChain {
+			c.setChain(strings.TrimSpace(chain), tier, target)
+		} else {
+			c.Tiers[tier] = mergeSLA(c.Tiers[tier], target)
+		}
+	}
+}
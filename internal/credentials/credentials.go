@@ -0,0 +1,361 @@
+// Package credentials resolves upstream provider API keys at runtime.
+//
+// A Store fetches named credentials (e.g. HELIUS_API_KEY) from an ordered
+// list of sources, typically the secure buffer service first and the
+// unified config as a fallback, and keeps the values in securebuf buffers.
+// Refreshing on an interval picks up rotated keys without a restart;
+// OnRotate lets callers recycle connections that used the old key. Redact
+// strips known key values and credential query parameters from text
+// bound for logs and metric labels.
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	refreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "credentials_refresh_total",
+		Help: "Credential lookups by source and result",
+	}, []string{"source", "result"})
+
+	rotations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "credentials_rotations_total",
+		Help: "Credential values that changed on refresh",
+	}, []string{"name"})
+)
+
+// ErrNotFound is returned by a Source that has no value for a name
+var ErrNotFound = errors.New("credential not found")
+
+// redacted replaces secret values in logged text
+const redacted = "REDACTED"
+
+// sensitiveParams are query parameters that carry credentials in provider URLs
+var sensitiveParams = map[string]bool{
+	"api-key": true, "api_key": true, "apikey": true, "key": true,
+	"token": true, "access_token": true, "auth": true, "secret": true,
+}
+
+// Source looks up a credential by name
+type Source interface {
+	Name() string
+	Lookup(ctx context.Context, name string) (string, error)
+}
+
+// ConfigSource reads credentials from the unified config and environment
+type ConfigSource struct {
+	cfg config.Config
+}
+
+// NewConfigSource returns a Source backed by cfg
+func NewConfigSource(cfg config.Config) *ConfigSource {
+	return &ConfigSource{cfg: cfg}
+}
+
+// Name implements Source
+func (s *ConfigSource) Name() string { return "config" }
+
+// Lookup implements Source
+func (s *ConfigSource) Lookup(_ context.Context, name string) (string, error) {
+	v := s.cfg.Get(name, "")
+	if _, isRef := ParseSecretRef(v); v == "" || isRef {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// BufferServiceSource reads credentials from the secure buffer service's
+// /v1/secrets endpoint
+type BufferServiceSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewBufferServiceSource returns a Source for the service at baseURL. A nil
+// client uses a 5 second timeout.
+func NewBufferServiceSource(baseURL string, client *http.Client) *BufferServiceSource {
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &BufferServiceSource{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+// Name implements Source
+func (s *BufferServiceSource) Name() string { return "buffer_service" }
+
+// Lookup implements Source
+func (s *BufferServiceSource) Lookup(ctx context.Context, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/v1/secrets?key="+url.QueryEscape(name), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secure buffer service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	default:
+		return "", fmt.Errorf("secure buffer service: unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Value     string `json:"value"`
+		Retrieved bool   `json:"retrieved"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("secure buffer service: %w", err)
+	}
+	if !body.Retrieved || body.Value == "" {
+		return "", ErrNotFound
+	}
+	return body.Value, nil
+}
+
+// Store holds the current value of each registered credential
+type Store struct {
+	sources []Source
+	logger  *zap.Logger
+
+	mu       sync.RWMutex
+	names    []string
+	values   map[string]*securebuf.Buffer
+	known    []string // Current values, for redaction
+	onRotate []func(name string)
+}
+
+// NewStore returns a Store that consults sources in order
+func NewStore(logger *zap.Logger, sources ...Source) *Store {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Store{
+		sources: sources,
+		logger:  logger,
+		values:  make(map[string]*securebuf.Buffer),
+	}
+}
+
+// Register adds credential names to fetch on refresh
+func (s *Store) Register(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range names {
+		if !contains(s.names, n) {
+			s.names = append(s.names, n)
+		}
+	}
+}
+
+// OnRotate registers fn to run after a credential's value changes
+func (s *Store) OnRotate(fn func(name string)) {
+	s.mu.Lock()
+	s.onRotate = append(s.onRotate, fn)
+	s.mu.Unlock()
+}
+
+// Get returns the current value of name
+func (s *Store) Get(name string) (string, bool) {
+	// Read under the lock: set frees replaced buffers once it holds it
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	buf := s.values[name]
+	if buf == nil {
+		return "", false
+	}
+	v, err := buf.ReadToSlice()
+	if err != nil {
+		return "", false
+	}
+	return string(v), true
+}
+
+// Refresh fetches every registered credential. A credential no source has
+// keeps its previous value, so a transient outage of the secret service
+// does not drop working keys.
+func (s *Store) Refresh(ctx context.Context) error {
+	s.mu.RLock()
+	names := append([]string(nil), s.names...)
+	s.mu.RUnlock()
+
+	var firstErr error
+	var rotated []string
+	for _, name := range names {
+		value, err := s.lookup(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		changed, err := s.set(name, value)
+		if err != nil {
+			return err
+		}
+		if changed {
+			rotated = append(rotated, name)
+		}
+	}
+
+	s.mu.RLock()
+	hooks := make([]func(string), len(s.onRotate))
+	copy(hooks, s.onRotate)
+	s.mu.RUnlock()
+	for _, name := range rotated {
+		rotations.WithLabelValues(name).Inc()
+		s.logger.Info("Credential rotated", zap.String("name", name))
+		for _, fn := range hooks {
+			fn(name)
+		}
+	}
+	return firstErr
+}
+
+// lookup returns the value from the first source that has name. If a
+// source fails while a value is already held, the lookup stops there
+// rather than falling through to a lower-priority source's older value.
+func (s *Store) lookup(ctx context.Context, name string) (string, error) {
+	_, held := s.Get(name)
+	var lastErr error = ErrNotFound
+	for _, src := range s.sources {
+		v, err := src.Lookup(ctx, name)
+		switch {
+		case err == nil:
+			refreshes.WithLabelValues(src.Name(), "ok").Inc()
+			return v, nil
+		case errors.Is(err, ErrNotFound):
+			refreshes.WithLabelValues(src.Name(), "missing").Inc()
+		default:
+			refreshes.WithLabelValues(src.Name(), "error").Inc()
+			s.logger.Warn("Credential source failed",
+				zap.String("source", src.Name()),
+				zap.String("name", name),
+				zap.String("error", s.Redact(err.Error())))
+			if held {
+				return "", err
+			}
+			lastErr = err
+		}
+	}
+	return "", lastErr
+}
+
+// set stores value for name and reports whether it replaced a different,
+// previously known value
+func (s *Store) set(name, value string) (bool, error) {
+	if old, ok := s.Get(name); ok && old == value {
+		return false, nil
+	}
+	buf, err := securebuf.New(len(value))
+	if err != nil {
+		return false, fmt.Errorf("failed to allocate secure buffer for %s: %w", name, err)
+	}
+	if err := buf.Write([]byte(value)); err != nil {
+		buf.Free()
+		return false, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	prev := s.values[name]
+	s.values[name] = buf
+	s.known = s.known[:0]
+	for _, b := range s.values {
+		if v, err := b.ReadToSlice(); err == nil && len(v) > 0 {
+			s.known = append(s.known, string(v))
+		}
+	}
+	if prev != nil {
+		prev.Free()
+	}
+	s.mu.Unlock()
+	return prev != nil, nil
+}
+
+// Run refreshes on interval until ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.logger.Warn("Credential refresh incomplete", zap.String("error", s.Redact(err.Error())))
+			}
+		}
+	}
+}
+
+// Redact removes credential query parameters, URL user info and every
+// known credential value from text
+func (s *Store) Redact(text string) string {
+	text = RedactURL(text)
+	if s == nil {
+		return text
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, v := range s.known {
+		// Very short values would redact unrelated text
+		if len(v) >= 8 {
+			text = strings.ReplaceAll(text, v, redacted)
+		}
+	}
+	return text
+}
+
+// RedactURL removes user info and credential query parameters from raw
+// when it parses as a URL, and returns other text unchanged
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if sensitiveParams[strings.ToLower(k)] {
+				q.Set(k, redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
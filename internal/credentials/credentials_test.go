@@ -0,0 +1,141 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+)
+
+type mapSource struct {
+	values map[string]string
+	err    error
+}
+
+func (m *mapSource) Name() string { return "map" }
+
+func (m *mapSource) Lookup(_ context.Context, name string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+	if v, ok := m.values[name]; ok {
+		return v, nil
+	}
+	return "", ErrNotFound
+}
+
+func TestStoreRotationAndFallback(t *testing.T) {
+	primary := &mapSource{values: map[string]string{"HELIUS_API_KEY": "primary-key-0001"}}
+	fallback := &mapSource{values: map[string]string{"HELIUS_API_KEY": "config-key-0001", "ANKR_API_KEY": "ankr-key-00001"}}
+	s := NewStore(nil, primary, fallback)
+	s.Register("HELIUS_API_KEY", "ANKR_API_KEY", "MISSING_KEY")
+
+	var rotated []string
+	s.OnRotate(func(name string) { rotated = append(rotated, name) })
+
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if v, _ := s.Get("HELIUS_API_KEY"); v != "primary-key-0001" {
+		t.Fatalf("HELIUS_API_KEY = %q, want primary source value", v)
+	}
+	if v, _ := s.Get("ANKR_API_KEY"); v != "ankr-key-00001" {
+		t.Fatalf("ANKR_API_KEY = %q, want fallback value", v)
+	}
+	if _, ok := s.Get("MISSING_KEY"); ok {
+		t.Fatal("MISSING_KEY should not resolve")
+	}
+	if len(rotated) != 0 {
+		t.Fatalf("initial load reported rotations %v", rotated)
+	}
+
+	primary.values["HELIUS_API_KEY"] = "primary-key-0002"
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if v, _ := s.Get("HELIUS_API_KEY"); v != "primary-key-0002" {
+		t.Fatalf("HELIUS_API_KEY = %q after rotation", v)
+	}
+	if len(rotated) != 1 || rotated[0] != "HELIUS_API_KEY" {
+		t.Fatalf("rotated = %v, want [HELIUS_API_KEY]", rotated)
+	}
+
+	// An outage of the primary keeps the held key instead of falling back
+	primary.err = errors.New("connection refused")
+	if err := s.Refresh(context.Background()); err == nil {
+		t.Fatal("expected refresh error while primary is down")
+	}
+	if v, _ := s.Get("HELIUS_API_KEY"); v != "primary-key-0002" {
+		t.Fatalf("HELIUS_API_KEY = %q during outage, want held value", v)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	s := NewStore(nil, &mapSource{values: map[string]string{"ANKR_API_KEY": "abcdef0123456789"}})
+	s.Register("ANKR_API_KEY")
+	if err := s.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Redact("wss://rpc.ankr.com/solana/ws/abcdef0123456789")
+	if strings.Contains(got, "abcdef0123456789") {
+		t.Fatalf("path key not redacted: %s", got)
+	}
+	got = s.Redact("wss://mainnet.helius-rpc.com/?api-key=secretvalue&cluster=mainnet")
+	if strings.Contains(got, "secretvalue") || !strings.Contains(got, "cluster=mainnet") {
+		t.Fatalf("query key not redacted: %s", got)
+	}
+}
+
+func TestBufferServiceSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "HELIUS_API_KEY" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"value":"from-service","retrieved":true}`))
+	}))
+	defer srv.Close()
+
+	src := NewBufferServiceSource(srv.URL, nil)
+	v, err := src.Lookup(context.Background(), "HELIUS_API_KEY")
+	if err != nil || v != "from-service" {
+		t.Fatalf("Lookup = %q, %v", v, err)
+	}
+	if _, err := src.Lookup(context.Background(), "OTHER"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Lookup(OTHER) err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestResolveConfig(t *testing.T) {
+	t.Setenv("PEER_HMAC", "env-hmac-secret")
+	service := &mapSource{values: map[string]string{"db_url": "postgres://sprint:s3cret@db/sprint"}}
+
+	cfg := config.Config{
+		APIKey:       "plain-key",
+		DatabaseURL:  "secretref://db_url",
+		BitcoinNodes: []string{"127.0.0.1:8333", "secretref://peer-hmac"},
+	}
+	store, err := ResolveConfig(context.Background(), &cfg, nil, service, EnvSource{})
+	if err != nil {
+		t.Fatalf("ResolveConfig: %v", err)
+	}
+	if cfg.DatabaseURL != "postgres://sprint:s3cret@db/sprint" || cfg.BitcoinNodes[1] != "env-hmac-secret" || cfg.APIKey != "plain-key" {
+		t.Fatalf("resolved config = %+v", cfg)
+	}
+	if got := store.Redact("dial postgres://sprint:s3cret@db/sprint"); strings.Contains(got, "s3cret") {
+		t.Fatalf("resolved secret not redacted: %s", got)
+	}
+
+	cfg = config.Config{LicenseKey: "secretref://missing", APIKey: "secretref://db_url"}
+	if _, err := ResolveConfig(context.Background(), &cfg, nil, service, EnvSource{}); !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("unresolved reference err = %v", err)
+	}
+	if cfg.APIKey != "secretref://db_url" {
+		t.Fatal("failed resolution must not modify the config")
+	}
+}
@@ -0,0 +1,136 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"go.uber.org/zap"
+)
+
+// Secret references
+//
+// A config value of the form secretref://peer_hmac names a secret instead
+// of holding it, so env files and deployment manifests carry no plaintext.
+// ResolveConfig replaces every reference in a Config with the secret's
+// value before subsystems are built from it, consulting the secure buffer
+// service first and the environment (PEER_HMAC) as a fallback.
+
+// SecretRefPrefix marks a config value as a reference to a named secret
+const SecretRefPrefix = "secretref://"
+
+// ParseSecretRef returns the secret name a value refers to
+func ParseSecretRef(value string) (string, bool) {
+	if !strings.HasPrefix(value, SecretRefPrefix) {
+		return "", false
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(value, SecretRefPrefix))
+	return name, name != ""
+}
+
+// EnvSource reads secrets from environment variables named after the
+// secret, upper-cased with dashes and dots as underscores
+type EnvSource struct{}
+
+// Name implements Source
+func (EnvSource) Name() string { return "env" }
+
+// Lookup implements Source
+func (EnvSource) Lookup(_ context.Context, name string) (string, error) {
+	v := os.Getenv(envName(name))
+	// A variable holding the reference itself is not a value
+	if _, isRef := ParseSecretRef(v); v == "" || isRef {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// SecretSources returns the sources secret references resolve from: the
+// secure buffer service when cfg names one, then the environment
+func SecretSources(cfg config.Config) []Source {
+	var sources []Source
+	if cfg.SecretServiceURL != "" {
+		sources = append(sources, NewBufferServiceSource(cfg.SecretServiceURL, nil))
+	}
+	return append(sources, EnvSource{})
+}
+
+// ResolveConfig replaces secret references in cfg's string fields, string
+// slices and nested structs with their values. Every reference must
+// resolve; a partially resolved config is never returned, so a missing
+// secret stops startup rather than leaving a literal reference in place.
+//
+// The returned Store holds the referenced secrets, with each registered
+// under its secret name. Run it to pick up values rotated through the
+// buffer service's leases, and use OnRotate to rebuild whatever was
+// configured from the old value; cfg itself is not updated after startup.
+func ResolveConfig(ctx context.Context, cfg *config.Config, logger *zap.Logger, sources ...Source) (*Store, error) {
+	if len(sources) == 0 {
+		sources = SecretSources(*cfg)
+	}
+	store := NewStore(logger, sources...)
+
+	var refs []*string
+	collectRefs(reflect.ValueOf(cfg).Elem(), &refs)
+	if len(refs) == 0 {
+		return store, nil
+	}
+	for _, p := range refs {
+		name, _ := ParseSecretRef(*p)
+		store.Register(name)
+	}
+	if err := store.Refresh(ctx); err != nil {
+		return nil, fmt.Errorf("resolve secret references: %w", err)
+	}
+
+	values := make([]string, len(refs))
+	var missing []string
+	for i, p := range refs {
+		name, _ := ParseSecretRef(*p)
+		v, ok := store.Get(name)
+		if !ok && !contains(missing, name) {
+			missing = append(missing, name)
+		}
+		values[i] = v
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("resolve secret references: %w: %s", ErrNotFound, strings.Join(missing, ", "))
+	}
+	for i, p := range refs {
+		*p = values[i]
+	}
+	store.logger.Info("Resolved secret references in config", zap.Int("references", len(refs)))
+	return store, nil
+}
+
+// collectRefs appends a pointer to every settable string under v that
+// holds a secret reference
+func collectRefs(v reflect.Value, refs *[]*string) {
+	switch v.Kind() {
+	case reflect.String:
+		if _, ok := ParseSecretRef(v.String()); ok && v.CanAddr() && v.CanSet() {
+			*refs = append(*refs, v.Addr().Interface().(*string))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				collectRefs(v.Field(i), refs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectRefs(v.Index(i), refs)
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			collectRefs(v.Elem(), refs)
+		}
+	}
+}
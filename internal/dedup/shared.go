@@ -0,0 +1,276 @@
+// internal/dedup/shared.go
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Shared deduplication lets several Sprint nodes agree on which blocks have
+// already been relayed. A remote store answers "first writer wins" via SETNX
+// with a TTL, while a small local L1 keeps the hot path off the network.
+
+var (
+	sharedDedupLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dedup_shared_lookups_total",
+			Help: "Shared dedup lookups by layer and result",
+		},
+		[]string{"layer", "result"},
+	)
+
+	sharedDedupErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "dedup_shared_errors_total",
+			Help: "Shared dedup backend errors (lookups fail open to local dedup)",
+		},
+		[]string{"backend"},
+	)
+
+	sharedDedupLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "dedup_shared_backend_duration_seconds",
+			Help:    "Latency of shared dedup backend calls",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1},
+		},
+		[]string{"backend"},
+	)
+)
+
+// SharedBackend is a cluster-wide store used to detect duplicates across nodes
+type SharedBackend interface {
+	// MarkSeen atomically records key and reports whether it was already present
+	MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Name identifies the backend in metrics and logs
+	Name() string
+	// Close releases backend resources
+	Close() error
+}
+
+// SharedConfig configures shared deduplication for a tier
+type SharedConfig struct {
+	Enabled   bool          `json:"enabled"`
+	L1Size    int           `json:"l1_size"`    // Entries kept in the local L1
+	L1TTL     time.Duration `json:"l1_ttl"`     // How long L1 trusts a result
+	RemoteTTL time.Duration `json:"remote_ttl"` // TTL of keys in the shared store
+	Timeout   time.Duration `json:"timeout"`    // Budget for a single backend call
+}
+
+// SharedConfigForTier returns the shared dedup settings for a service tier
+func SharedConfigForTier(tier string) SharedConfig {
+	switch strings.ToUpper(tier) {
+	case "ENTERPRISE":
+		return SharedConfig{Enabled: true, L1Size: 20000, L1TTL: 15 * time.Minute, RemoteTTL: 30 * time.Minute, Timeout: 25 * time.Millisecond}
+	case "BUSINESS", "TURBO":
+		return SharedConfig{Enabled: true, L1Size: 8000, L1TTL: 10 * time.Minute, RemoteTTL: 20 * time.Minute, Timeout: 50 * time.Millisecond}
+	case "PRO":
+		return SharedConfig{Enabled: true, L1Size: 4000, L1TTL: 10 * time.Minute, RemoteTTL: 10 * time.Minute, Timeout: 75 * time.Millisecond}
+	default: // FREE: single-node dedup only
+		return SharedConfig{Enabled: false, L1Size: 2000, L1TTL: 5 * time.Minute, RemoteTTL: 5 * time.Minute, Timeout: 100 * time.Millisecond}
+	}
+}
+
+// SharedDeduper fronts a SharedBackend with a local L1 cache
+type SharedDeduper struct {
+	mu      sync.Mutex
+	l1      map[string]time.Time // key -> expiry
+	order   []string
+	backend SharedBackend
+	cfg     SharedConfig
+	logger  *zap.Logger
+}
+
+// NewSharedDeduper creates a shared deduper; backend may be nil for L1-only operation
+func NewSharedDeduper(backend SharedBackend, cfg SharedConfig, logger *zap.Logger) *SharedDeduper {
+	if cfg.L1Size <= 0 {
+		cfg.L1Size = 2000
+	}
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = 5 * time.Minute
+	}
+	if cfg.RemoteTTL <= 0 {
+		cfg.RemoteTTL = cfg.L1TTL
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 50 * time.Millisecond
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &SharedDeduper{
+		l1:      make(map[string]time.Time, cfg.L1Size),
+		order:   make([]string, 0, cfg.L1Size),
+		backend: backend,
+		cfg:     cfg,
+		logger:  logger,
+	}
+}
+
+// Seen returns true if the block was already seen by this node or any node sharing the backend
+func (sd *SharedDeduper) Seen(blockHash string, timestamp time.Time, network string, options ...DedupeOption) bool {
+	if sd == nil || blockHash == "" {
+		return false
+	}
+	key := network + ":" + blockHash
+
+	// L1: anything recorded locally is a duplicate, regardless of who saw it first
+	if sd.l1Hit(key, timestamp) {
+		sharedDedupLookups.WithLabelValues("l1", "duplicate").Inc()
+		return true
+	}
+
+	duplicate := false
+	if sd.backend != nil && sd.cfg.Enabled {
+		ctx, cancel := context.WithTimeout(context.Background(), sd.cfg.Timeout)
+		start := time.Now()
+		existed, err := sd.backend.MarkSeen(ctx, key, sd.cfg.RemoteTTL)
+		cancel()
+		sharedDedupLatency.WithLabelValues(sd.backend.Name()).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			// Fail open: a backend outage degrades to per-node dedup, never to dropped blocks
+			sharedDedupErrors.WithLabelValues(sd.backend.Name()).Inc()
+			sd.logger.Debug("Shared dedup backend unavailable, using local result",
+				zap.String("backend", sd.backend.Name()),
+				zap.Error(err))
+		} else {
+			duplicate = existed
+		}
+	}
+
+	if duplicate {
+		sharedDedupLookups.WithLabelValues("remote", "duplicate").Inc()
+	} else {
+		sharedDedupLookups.WithLabelValues("remote", "new").Inc()
+	}
+	sd.l1Store(key, timestamp)
+	return duplicate
+}
+
+// l1Hit checks the local cache, expiring stale entries lazily
+func (sd *SharedDeduper) l1Hit(key string, now time.Time) bool {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	expiry, ok := sd.l1[key]
+	if !ok {
+		return false
+	}
+	if now.After(expiry) {
+		delete(sd.l1, key)
+		return false
+	}
+	return true
+}
+
+// l1Store records a key, evicting the oldest entries when full
+func (sd *SharedDeduper) l1Store(key string, now time.Time) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	if _, ok := sd.l1[key]; !ok {
+		sd.order = append(sd.order, key)
+	}
+	sd.l1[key] = now.Add(sd.cfg.L1TTL)
+
+	for len(sd.l1) > sd.cfg.L1Size && len(sd.order) > 0 {
+		oldest := sd.order[0]
+		sd.order = sd.order[1:]
+		delete(sd.l1, oldest)
+	}
+	// Compact the order slice once deleted keys dominate it
+	if len(sd.order) > 2*sd.cfg.L1Size {
+		live := make([]string, 0, len(sd.l1))
+		for _, k := range sd.order {
+			if _, ok := sd.l1[k]; ok {
+				live = append(live, k)
+			}
+		}
+		sd.order = live
+	}
+}
+
+// GetStats returns shared dedup statistics
+func (sd *SharedDeduper) GetStats() map[string]interface{} {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	backend := "none"
+	if sd.backend != nil {
+		backend = sd.backend.Name()
+	}
+	return map[string]interface{}{
+		"enabled":    sd.cfg.Enabled && sd.backend != nil,
+		"backend":    backend,
+		"l1_entries": len(sd.l1),
+		"l1_size":    sd.cfg.L1Size,
+		"l1_ttl":     sd.cfg.L1TTL.String(),
+		"remote_ttl": sd.cfg.RemoteTTL.String(),
+		"timeout":    sd.cfg.Timeout.String(),
+	}
+}
+
+// Close closes the shared backend
+func (sd *SharedDeduper) Close() error {
+	if sd == nil || sd.backend == nil {
+		return nil
+	}
+	return sd.backend.Close()
+}
+
+// ===== REDIS BACKEND =====
+
+// RedisBackend implements SharedBackend using Redis SET NX PX
+type RedisBackend struct {
+	client *redis.Client
+	prefix string
+	nodeID string
+}
+
+// NewRedisBackend connects to Redis using a redis:// URL
+func NewRedisBackend(url, prefix, nodeID string) (*RedisBackend, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	if prefix == "" {
+		prefix = "sprint:dedup:"
+	}
+	if nodeID == "" {
+		nodeID = "sprint"
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis ping failed: %w", err)
+	}
+
+	return &RedisBackend{client: client, prefix: prefix, nodeID: nodeID}, nil
+}
+
+// MarkSeen sets the key if absent; an existing key means another node saw it first
+func (rb *RedisBackend) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	set, err := rb.client.SetNX(ctx, rb.prefix+key, rb.nodeID, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	return !set, nil
+}
+
+// Name returns the backend name
+func (rb *RedisBackend) Name() string {
+	return "redis"
+}
+
+// Close closes the Redis client
+func (rb *RedisBackend) Close() error {
+	return rb.client.Close()
+}
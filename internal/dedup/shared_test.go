@@ -0,0 +1,86 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeBackend is an in-memory SharedBackend standing in for the cluster
+type fakeBackend struct {
+	seen  map[string]bool
+	calls int
+	err   error
+}
+
+func (f *fakeBackend) MarkSeen(_ context.Context, key string, _ time.Duration) (bool, error) {
+	f.calls++
+	if f.err != nil {
+		return false, f.err
+	}
+	existed := f.seen[key]
+	f.seen[key] = true
+	return existed, nil
+}
+
+func (f *fakeBackend) Name() string { return "fake" }
+func (f *fakeBackend) Close() error { return nil }
+
+func TestSharedDeduper(t *testing.T) {
+	now := time.Now()
+	cfg := SharedConfig{Enabled: true, L1Size: 16, L1TTL: time.Minute, RemoteTTL: time.Minute, Timeout: time.Second}
+
+	t.Run("l1 hit", func(t *testing.T) {
+		backend := &fakeBackend{seen: map[string]bool{}}
+		sd := NewSharedDeduper(backend, cfg, nil)
+		if sd.Seen("aa", now, "bitcoin") {
+			t.Fatal("first sighting reported as duplicate")
+		}
+		if !sd.Seen("aa", now.Add(time.Second), "bitcoin") {
+			t.Fatal("second sighting not a duplicate")
+		}
+		if backend.calls != 1 {
+			t.Fatalf("backend called %d times, want 1: the repeat should be answered by L1", backend.calls)
+		}
+		if sd.Seen("aa", now, "ethereum") {
+			t.Fatal("same hash on another network reported as duplicate")
+		}
+		// Once L1 expires the backend answers, and still knows the block
+		if !sd.Seen("aa", now.Add(2*time.Minute), "bitcoin") || backend.calls != 3 {
+			t.Fatalf("expired L1 entry: backend called %d times, want 3", backend.calls)
+		}
+	})
+
+	t.Run("remote hit", func(t *testing.T) {
+		// Another node relayed the block first
+		backend := &fakeBackend{seen: map[string]bool{"bitcoin:bb": true}}
+		sd := NewSharedDeduper(backend, cfg, nil)
+		if !sd.Seen("bb", now, "bitcoin") {
+			t.Fatal("block seen by another node not reported as duplicate")
+		}
+		if sd.Seen("cc", now, "bitcoin") {
+			t.Fatal("new block reported as duplicate")
+		}
+	})
+
+	t.Run("fails open", func(t *testing.T) {
+		backend := &fakeBackend{seen: map[string]bool{"bitcoin:dd": true}, err: errors.New("connection refused")}
+		sd := NewSharedDeduper(backend, cfg, nil)
+		if sd.Seen("dd", now, "bitcoin") {
+			t.Fatal("backend error suppressed a block")
+		}
+		// The local result still dedups on this node
+		if !sd.Seen("dd", now, "bitcoin") {
+			t.Fatal("repeat after a backend error not caught by L1")
+		}
+	})
+
+	t.Run("disabled tier", func(t *testing.T) {
+		backend := &fakeBackend{seen: map[string]bool{"bitcoin:ee": true}}
+		sd := NewSharedDeduper(backend, SharedConfigForTier("free"), nil)
+		if sd.Seen("ee", now, "bitcoin") || backend.calls != 0 {
+			t.Fatalf("free tier consulted the backend (%d calls)", backend.calls)
+		}
+	})
+}
@@ -0,0 +1,374 @@
+// Package endpointhealth scores upstream endpoints and trips a circuit
+// breaker on the ones that keep failing.
+//
+// A Manager tracks a pool of endpoints (RPC providers, websocket feeds,
+// HTTP backends). Each success updates an EWMA of the round-trip time and
+// each failure counts towards the breaker. Callers pick the best endpoint
+// with Pick, or walk them best first with Ranked for failover. Scoring is
+// pluggable through Config.Scorer; the default favours low latency and
+// penalizes failures and non-closed breakers.
+package endpointhealth
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	scoreGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "endpoint",
+		Name:      "score",
+		Help:      "Health score per endpoint (higher is better)",
+	}, []string{"pool", "endpoint"})
+	latencyGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "endpoint",
+		Name:      "latency_ms",
+		Help:      "EWMA latency per endpoint (milliseconds)",
+	}, []string{"pool", "endpoint"})
+	stateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "endpoint",
+		Name:      "breaker_state",
+		Help:      "Circuit breaker state: 0=closed,1=half-open,2=open",
+	}, []string{"pool", "endpoint"})
+	failuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "endpoint",
+		Name:      "failures_total",
+		Help:      "Failures recorded per endpoint",
+	}, []string{"pool", "endpoint"})
+)
+
+// BreakerState is the circuit breaker state of an endpoint
+type BreakerState int
+
+const (
+	Closed BreakerState = iota
+	HalfOpen
+	Open
+)
+
+// String returns the state name
+func (s BreakerState) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats is a point-in-time view of one endpoint
+type Stats struct {
+	URL        string       `json:"url"`
+	EWMARTT    float64      `json:"ewma_rtt_ms"`
+	Successes  int64        `json:"successes"`
+	Failures   int64        `json:"failures"`
+	LastErr    string       `json:"last_error,omitempty"`
+	LastSeen   time.Time    `json:"last_seen"`
+	State      BreakerState `json:"state"`
+	TrippedAt  time.Time    `json:"tripped_at,omitempty"`
+	BreakUntil time.Time    `json:"break_until,omitempty"`
+	Score      float64      `json:"score"`
+}
+
+// Scorer ranks an endpoint; higher is better. Scores <= 0 are treated as
+// a tiny positive weight so a pool never runs out of candidates.
+type Scorer func(Stats) float64
+
+// DefaultScorer favours low latency, decays with the failure count and
+// discounts endpoints whose breaker is not closed
+func DefaultScorer(s Stats) float64 {
+	rtt := s.EWMARTT
+	if rtt <= 0 {
+		rtt = 50
+	}
+	failPenalty := 1.0 / (1.0 + math.Log1p(float64(s.Failures)))
+	statePenalty := 1.0
+	switch s.State {
+	case Open:
+		statePenalty = 0.1
+	case HalfOpen:
+		statePenalty = 0.5
+	}
+	return (1.0 / rtt) * failPenalty * statePenalty
+}
+
+// Config tunes the breaker and scoring
+type Config struct {
+	FailureThreshold  int           // Consecutive failures that open the breaker
+	OpenDuration      time.Duration // How long an open breaker rejects traffic
+	HalfOpenSuccesses int           // Successes needed to close a half-open breaker
+	Alpha             float64       // EWMA weight of the newest latency sample
+	Scorer            Scorer
+	Label             func(url string) string // Metric label for an endpoint, e.g. with credentials redacted; defaults to the URL
+}
+
+// DefaultConfig matches the thresholds the relays have always used
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold:  5,
+		OpenDuration:      30 * time.Second,
+		HalfOpenSuccesses: 3,
+		Alpha:             0.2,
+		Scorer:            DefaultScorer,
+	}
+}
+
+type endpoint struct {
+	stats       Stats
+	consecutive int // Failures since the last success
+	probes      int // Successes while half-open
+}
+
+// Manager tracks health for a named pool of endpoints
+type Manager struct {
+	pool string
+	cfg  Config
+
+	mu        sync.Mutex
+	endpoints map[string]*endpoint
+	order     []string // Registration order, for stable tie-breaking
+}
+
+// New creates a Manager for endpoints. pool labels the Prometheus series,
+// e.g. "solana" or "ethereum". Zero Config fields take their defaults.
+func New(pool string, endpoints []string, cfg Config) *Manager {
+	def := DefaultConfig()
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = def.FailureThreshold
+	}
+	if cfg.OpenDuration <= 0 {
+		cfg.OpenDuration = def.OpenDuration
+	}
+	if cfg.HalfOpenSuccesses <= 0 {
+		cfg.HalfOpenSuccesses = def.HalfOpenSuccesses
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+		cfg.Alpha = def.Alpha
+	}
+	if cfg.Scorer == nil {
+		cfg.Scorer = def.Scorer
+	}
+
+	m := &Manager{
+		pool:      pool,
+		cfg:       cfg,
+		endpoints: make(map[string]*endpoint, len(endpoints)),
+	}
+	for _, url := range endpoints {
+		m.Add(url)
+	}
+	return m
+}
+
+// Add starts tracking url; it is a no-op for known endpoints
+func (m *Manager) Add(url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.endpoints[url]; ok {
+		return
+	}
+	m.endpoints[url] = &endpoint{stats: Stats{URL: url}}
+	m.order = append(m.order, url)
+	m.publishLocked(m.endpoints[url])
+}
+
+// RecordSuccess records a successful call that took latency. A zero
+// latency (e.g. a message received on a stream) counts without moving
+// the EWMA.
+func (m *Manager) RecordSuccess(url string, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep, ok := m.endpoints[url]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	m.refreshLocked(ep, now)
+
+	st := &ep.stats
+	st.Successes++
+	st.LastSeen = now
+	ep.consecutive = 0
+	if latency > 0 {
+		lat := float64(latency.Microseconds()) / 1000
+		if st.EWMARTT == 0 {
+			st.EWMARTT = lat
+		} else {
+			st.EWMARTT = (1-m.cfg.Alpha)*st.EWMARTT + m.cfg.Alpha*lat
+		}
+	}
+
+	if st.State == HalfOpen {
+		ep.probes++
+		if ep.probes >= m.cfg.HalfOpenSuccesses {
+			st.State = Closed
+			ep.probes = 0
+		}
+	}
+	m.publishLocked(ep)
+}
+
+// RecordFailure records a failed call with a short reason
+func (m *Manager) RecordFailure(url string, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep, ok := m.endpoints[url]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	m.refreshLocked(ep, now)
+
+	st := &ep.stats
+	st.Failures++
+	st.LastErr = reason
+	st.LastSeen = now
+	ep.consecutive++
+	failuresTotal.WithLabelValues(m.pool, m.label(url)).Inc()
+
+	switch {
+	case st.State == HalfOpen:
+		// A failed probe re-opens the breaker straight away
+		m.tripLocked(ep, now)
+	case st.State == Closed && ep.consecutive >= m.cfg.FailureThreshold:
+		m.tripLocked(ep, now)
+	}
+	m.publishLocked(ep)
+}
+
+// Pick returns the best available endpoint
+func (m *Manager) Pick() (string, bool) {
+	ranked := m.Ranked()
+	if len(ranked) == 0 {
+		return "", false
+	}
+	return ranked[0], true
+}
+
+// Ranked returns the available endpoints, best score first. Endpoints
+// with an open breaker are left out until their break expires.
+func (m *Manager) Ranked() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	type candidate struct {
+		url   string
+		score float64
+	}
+	candidates := make([]candidate, 0, len(m.order))
+	for _, url := range m.order {
+		ep := m.endpoints[url]
+		m.refreshLocked(ep, now)
+		if ep.stats.State == Open {
+			continue
+		}
+		candidates = append(candidates, candidate{url, m.scoreLocked(ep)})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	urls := make([]string, len(candidates))
+	for i, c := range candidates {
+		urls[i] = c.url
+	}
+	return urls
+}
+
+// Available reports whether url may be used right now
+func (m *Manager) Available(url string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep, ok := m.endpoints[url]
+	if !ok {
+		return false
+	}
+	m.refreshLocked(ep, time.Now())
+	return ep.stats.State != Open
+}
+
+// State returns the breaker state of url
+func (m *Manager) State(url string) (BreakerState, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ep, ok := m.endpoints[url]
+	if !ok {
+		return Closed, false
+	}
+	m.refreshLocked(ep, time.Now())
+	return ep.stats.State, true
+}
+
+// Snapshot returns the current stats of every endpoint, keyed by URL
+func (m *Manager) Snapshot() map[string]Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	out := make(map[string]Stats, len(m.endpoints))
+	for url, ep := range m.endpoints {
+		m.refreshLocked(ep, now)
+		st := ep.stats
+		st.Score = m.scoreLocked(ep)
+		out[url] = st
+	}
+	return out
+}
+
+// refreshLocked moves an open breaker to half-open once its break expired
+func (m *Manager) refreshLocked(ep *endpoint, now time.Time) {
+	if ep.stats.State == Open && !now.Before(ep.stats.BreakUntil) {
+		ep.stats.State = HalfOpen
+		ep.probes = 0
+		m.publishLocked(ep)
+	}
+}
+
+func (m *Manager) tripLocked(ep *endpoint, now time.Time) {
+	ep.stats.State = Open
+	ep.stats.TrippedAt = now
+	ep.stats.BreakUntil = now.Add(m.cfg.OpenDuration)
+	ep.probes = 0
+}
+
+func (m *Manager) scoreLocked(ep *endpoint) float64 {
+	score := m.cfg.Scorer(ep.stats)
+	if score <= 0 || math.IsNaN(score) {
+		score = 0.0001
+	}
+	return score
+}
+
+func (m *Manager) publishLocked(ep *endpoint) {
+	url := m.label(ep.stats.URL)
+	scoreGauge.WithLabelValues(m.pool, url).Set(m.scoreLocked(ep))
+	latencyGauge.WithLabelValues(m.pool, url).Set(ep.stats.EWMARTT)
+	stateGauge.WithLabelValues(m.pool, url).Set(float64(ep.stats.State))
+}
+
+// label returns the metric label for url
+func (m *Manager) label(url string) string {
+	if m.cfg.Label != nil {
+		return m.cfg.Label(url)
+	}
+	return url
+}
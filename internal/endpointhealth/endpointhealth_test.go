@@ -0,0 +1,58 @@
+package endpointhealth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerLifecycle(t *testing.T) {
+	m := New("test", []string{"a", "b"}, Config{FailureThreshold: 2, OpenDuration: 20 * time.Millisecond, HalfOpenSuccesses: 2})
+
+	m.RecordFailure("a", "boom")
+	if state, _ := m.State("a"); state != Closed {
+		t.Fatalf("state after one failure = %v, want closed", state)
+	}
+	m.RecordFailure("a", "boom")
+	if state, _ := m.State("a"); state != Open {
+		t.Fatalf("state after threshold = %v, want open", state)
+	}
+	if ranked := m.Ranked(); len(ranked) != 1 || ranked[0] != "b" {
+		t.Fatalf("ranked with a open = %v, want [b]", ranked)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if state, _ := m.State("a"); state != HalfOpen {
+		t.Fatalf("state after break = %v, want half-open", state)
+	}
+
+	// A failed probe re-opens immediately
+	m.RecordFailure("a", "still down")
+	if state, _ := m.State("a"); state != Open {
+		t.Fatalf("state after failed probe = %v, want open", state)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	m.RecordSuccess("a", time.Millisecond)
+	m.RecordSuccess("a", time.Millisecond)
+	if state, _ := m.State("a"); state != Closed {
+		t.Fatalf("state after probes = %v, want closed", state)
+	}
+}
+
+func TestPickPrefersFasterEndpoint(t *testing.T) {
+	m := New("test", []string{"slow", "fast"}, Config{})
+	m.RecordSuccess("slow", 200*time.Millisecond)
+	m.RecordSuccess("fast", 10*time.Millisecond)
+
+	if ep, ok := m.Pick(); !ok || ep != "fast" {
+		t.Fatalf("Pick() = %q, %v; want fast", ep, ok)
+	}
+
+	// A custom scorer overrides latency
+	m = New("test", []string{"slow", "fast"}, Config{Scorer: func(s Stats) float64 { return s.EWMARTT }})
+	m.RecordSuccess("slow", 200*time.Millisecond)
+	m.RecordSuccess("fast", 10*time.Millisecond)
+	if ep, _ := m.Pick(); ep != "slow" {
+		t.Fatalf("Pick() with custom scorer = %q, want slow", ep)
+	}
+}
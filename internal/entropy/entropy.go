@@ -8,14 +8,15 @@ import (
 	"time"
 )
 
-// FastEntropy returns fast entropy using Rust FFI when available, fallback to Go
+// FastEntropy returns fast entropy using Rust FFI when available, fallback to Go.
+// All output passes continuous health tests; a degraded hardware source is
+// bypassed in favour of the Go implementation until it recovers.
 func FastEntropy() ([]byte, error) {
-	// Try Rust implementation first (when CGO is enabled)
-	if rustEntropy, err := FastEntropyRust(); err == nil {
-		return rustEntropy, nil
-	}
-	// Fallback to Go implementation
-	return SimpleEntropy()
+	data, _, err := defaultMonitor.Read(map[string]func() ([]byte, error){
+		SourceHardware: FastEntropyRust,
+		SourceSoftware: SimpleEntropy,
+	})
+	return data, err
 }
 
 // HybridEntropy returns enhanced entropy using Rust FFI when available, fallback to Go
@@ -0,0 +1,428 @@
+// Package entropy provides continuous health testing for entropy sources
+package entropy
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Continuous health tests modelled on NIST SP 800-90B section 4.4. Every byte
+// produced by a source is fed through a Repetition Count Test (RCT), which
+// catches a stuck source, and an Adaptive Proportion Test (APT), which catches
+// a large loss of entropy within a sliding window. Output that fails either
+// test is discarded and the source is marked degraded.
+
+// Source names reported by the health monitor
+const (
+	SourceHardware = "hardware"
+	SourceSoftware = "software"
+)
+
+// SourceState describes the health of an entropy source
+type SourceState string
+
+const (
+	StateHealthy  SourceState = "healthy"
+	StateDegraded SourceState = "degraded"
+	StateFailed   SourceState = "failed"
+)
+
+// Health test names
+const (
+	TestRepetitionCount    = "repetition_count"
+	TestAdaptiveProportion = "adaptive_proportion"
+	TestSourceError        = "source_error"
+)
+
+var (
+	entropyBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "entropy_bytes_total",
+			Help: "Entropy bytes produced that passed continuous health tests",
+		},
+		[]string{"source"},
+	)
+
+	entropyHealthFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "entropy_health_failures_total",
+			Help: "Entropy samples rejected by continuous health tests",
+		},
+		[]string{"source", "test"},
+	)
+
+	entropySourceState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "entropy_source_healthy",
+			Help: "1 if the entropy source is healthy, 0 otherwise",
+		},
+		[]string{"source"},
+	)
+)
+
+// HealthConfig configures the continuous health tests
+type HealthConfig struct {
+	// MinEntropyPerByte is the assessed min-entropy H of one output byte
+	MinEntropyPerByte float64
+	// AlphaExponent sets the false positive rate alpha = 2^-AlphaExponent
+	AlphaExponent int
+	// WindowSize is the APT window W in bytes
+	WindowSize int
+	// RetryAfter is how long a degraded source is bypassed before being retried
+	RetryAfter time.Duration
+	// FailAfter is the number of consecutive failures after which a source is failed
+	FailAfter int
+}
+
+// DefaultHealthConfig returns conservative SP 800-90B style parameters
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		MinEntropyPerByte: 7.0,
+		AlphaExponent:     20,
+		WindowSize:        512,
+		RetryAfter:        60 * time.Second,
+		FailAfter:         5,
+	}
+}
+
+// RepetitionCutoff returns the RCT cutoff C = 1 + ceil(-log2(alpha) / H)
+func (c HealthConfig) RepetitionCutoff() int {
+	return 1 + int(math.Ceil(float64(c.AlphaExponent)/c.MinEntropyPerByte))
+}
+
+// ProportionCutoff returns the APT cutoff C = 1 + critbinom(W, 2^-H, 1-alpha)
+func (c HealthConfig) ProportionCutoff() int {
+	n := c.WindowSize
+	p := math.Pow(2, -c.MinEntropyPerByte)
+	alpha := math.Pow(2, -float64(c.AlphaExponent))
+
+	// Walk the upper tail from the top down until it exceeds alpha
+	tail := 0.0
+	for k := n; k >= 0; k-- {
+		lg, _ := math.Lgamma(float64(n + 1))
+		lk, _ := math.Lgamma(float64(k + 1))
+		lnk, _ := math.Lgamma(float64(n - k + 1))
+		tail += math.Exp(lg - lk - lnk + float64(k)*math.Log(p) + float64(n-k)*math.Log1p(-p))
+		if tail > alpha {
+			return k + 1
+		}
+	}
+	return 1
+}
+
+// HealthAlert is emitted whenever a source changes state or fails a test
+type HealthAlert struct {
+	Source    string      `json:"source"`
+	Test      string      `json:"test"`
+	State     SourceState `json:"state"`
+	Message   string      `json:"message"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// SourceHealth is a snapshot of one source's health
+type SourceHealth struct {
+	Source              string      `json:"source"`
+	State               SourceState `json:"state"`
+	Samples             uint64      `json:"samples"`
+	Bytes               uint64      `json:"bytes"`
+	RepetitionFailures  uint64      `json:"repetition_failures"`
+	ProportionFailures  uint64      `json:"proportion_failures"`
+	SourceErrors        uint64      `json:"source_errors"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	ThroughputBps       float64     `json:"throughput_bytes_per_sec"`
+	LastFailure         *time.Time  `json:"last_failure,omitempty"`
+	LastFailureTest     string      `json:"last_failure_test,omitempty"`
+}
+
+// HealthReport is a snapshot of all monitored sources
+type HealthReport struct {
+	Healthy           bool           `json:"healthy"`
+	ActiveSource      string         `json:"active_source"`
+	RepetitionCutoff  int            `json:"repetition_cutoff"`
+	ProportionCutoff  int            `json:"proportion_cutoff"`
+	ProportionWindow  int            `json:"proportion_window"`
+	MinEntropyPerByte float64        `json:"min_entropy_per_byte"`
+	Sources           []SourceHealth `json:"sources"`
+	Timestamp         time.Time      `json:"timestamp"`
+}
+
+// sourceMonitor holds continuous test state for a single source
+type sourceMonitor struct {
+	name      string
+	cfg       HealthConfig
+	rctCutoff int
+	aptCutoff int
+
+	// RCT state
+	lastByte int
+	runLen   int
+
+	// APT state
+	aptRef   byte
+	aptCount int
+	aptSeen  int
+
+	state               SourceState
+	samples             uint64
+	bytes               uint64
+	rctFailures         uint64
+	aptFailures         uint64
+	sourceErrors        uint64
+	consecutiveFailures int
+	lastFailure         time.Time
+	lastFailureTest     string
+	firstSample         time.Time
+}
+
+func newSourceMonitor(name string, cfg HealthConfig) *sourceMonitor {
+	entropySourceState.WithLabelValues(name).Set(1)
+	return &sourceMonitor{
+		name:      name,
+		cfg:       cfg,
+		rctCutoff: cfg.RepetitionCutoff(),
+		aptCutoff: cfg.ProportionCutoff(),
+		lastByte:  -1,
+		state:     StateHealthy,
+	}
+}
+
+// check runs both continuous tests over data and returns the failed test, if any
+func (m *sourceMonitor) check(data []byte) string {
+	failed := ""
+
+	for _, b := range data {
+		// Repetition Count Test
+		if int(b) == m.lastByte {
+			m.runLen++
+			if m.runLen >= m.rctCutoff && failed == "" {
+				failed = TestRepetitionCount
+			}
+		} else {
+			m.lastByte = int(b)
+			m.runLen = 1
+		}
+
+		// Adaptive Proportion Test
+		if m.aptSeen == 0 {
+			m.aptRef = b
+			m.aptCount = 1
+		} else if b == m.aptRef {
+			m.aptCount++
+			if m.aptCount >= m.aptCutoff && failed == "" {
+				failed = TestAdaptiveProportion
+			}
+		}
+		m.aptSeen++
+		if m.aptSeen >= m.cfg.WindowSize {
+			m.aptSeen = 0
+		}
+	}
+	return failed
+}
+
+func (m *sourceMonitor) snapshot(now time.Time) SourceHealth {
+	h := SourceHealth{
+		Source:              m.name,
+		State:               m.state,
+		Samples:             m.samples,
+		Bytes:               m.bytes,
+		RepetitionFailures:  m.rctFailures,
+		ProportionFailures:  m.aptFailures,
+		SourceErrors:        m.sourceErrors,
+		ConsecutiveFailures: m.consecutiveFailures,
+		LastFailureTest:     m.lastFailureTest,
+	}
+	if !m.firstSample.IsZero() {
+		if elapsed := now.Sub(m.firstSample).Seconds(); elapsed > 0 {
+			h.ThroughputBps = float64(m.bytes) / elapsed
+		}
+	}
+	if !m.lastFailure.IsZero() {
+		t := m.lastFailure
+		h.LastFailure = &t
+	}
+	return h
+}
+
+// HealthMonitor runs continuous tests over multiple sources and picks the
+// healthiest one, falling back from hardware to software entropy.
+type HealthMonitor struct {
+	mu      sync.Mutex
+	cfg     HealthConfig
+	sources map[string]*sourceMonitor
+	order   []string
+	alertFn func(HealthAlert)
+	now     func() time.Time
+}
+
+// NewHealthMonitor creates a monitor for the hardware and software sources
+func NewHealthMonitor(cfg HealthConfig) *HealthMonitor {
+	hm := &HealthMonitor{
+		cfg:     cfg,
+		sources: make(map[string]*sourceMonitor),
+		order:   []string{SourceHardware, SourceSoftware},
+		now:     time.Now,
+	}
+	for _, name := range hm.order {
+		hm.sources[name] = newSourceMonitor(name, cfg)
+	}
+	return hm
+}
+
+// SetAlertHandler registers a callback for health alerts
+func (hm *HealthMonitor) SetAlertHandler(fn func(HealthAlert)) {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	hm.alertFn = fn
+}
+
+// usable reports whether a source should be tried right now
+func (hm *HealthMonitor) usable(name string) bool {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	m := hm.sources[name]
+	if m.state == StateHealthy {
+		return true
+	}
+	// Degraded and failed sources are retried after the backoff window
+	return hm.now().Sub(m.lastFailure) >= hm.cfg.RetryAfter
+}
+
+// record runs the continuous tests over a sample and updates source state.
+// It returns an error if the sample must be discarded.
+func (hm *HealthMonitor) record(name string, data []byte, srcErr error) error {
+	hm.mu.Lock()
+	m := hm.sources[name]
+	now := hm.now()
+	if m.firstSample.IsZero() {
+		m.firstSample = now
+	}
+
+	failed := ""
+	if srcErr != nil {
+		failed = TestSourceError
+		m.sourceErrors++
+	} else {
+		m.samples++
+		failed = m.check(data)
+		switch failed {
+		case TestRepetitionCount:
+			m.rctFailures++
+		case TestAdaptiveProportion:
+			m.aptFailures++
+		}
+	}
+
+	prev := m.state
+	var alert *HealthAlert
+	if failed == "" {
+		m.bytes += uint64(len(data))
+		m.consecutiveFailures = 0
+		m.state = StateHealthy
+		if prev != StateHealthy {
+			alert = &HealthAlert{Source: name, State: StateHealthy, Message: "entropy source recovered"}
+		}
+	} else {
+		m.consecutiveFailures++
+		m.lastFailure = now
+		m.lastFailureTest = failed
+		m.state = StateDegraded
+		if m.consecutiveFailures >= hm.cfg.FailAfter {
+			m.state = StateFailed
+		}
+		// Source errors on an unavailable backend are expected and not alert-worthy
+		if failed != TestSourceError || (prev == StateHealthy && m.samples > 0) {
+			alert = &HealthAlert{Source: name, Test: failed, State: m.state,
+				Message: fmt.Sprintf("entropy source failed %s test", failed)}
+		}
+		entropyHealthFailures.WithLabelValues(name, failed).Inc()
+	}
+	if m.state == StateHealthy {
+		entropySourceState.WithLabelValues(name).Set(1)
+	} else {
+		entropySourceState.WithLabelValues(name).Set(0)
+	}
+	alertFn := hm.alertFn
+	hm.mu.Unlock()
+
+	if alert != nil && alertFn != nil {
+		alert.Timestamp = now
+		alertFn(*alert)
+	}
+	if failed == "" {
+		entropyBytes.WithLabelValues(name).Add(float64(len(data)))
+		return nil
+	}
+	if srcErr != nil {
+		return srcErr
+	}
+	return fmt.Errorf("entropy %s source failed %s health test", name, failed)
+}
+
+// Read draws a sample from the first healthy source in preference order
+func (hm *HealthMonitor) Read(sources map[string]func() ([]byte, error)) ([]byte, string, error) {
+	var lastErr error
+	for _, name := range hm.order {
+		fn, ok := sources[name]
+		if !ok || !hm.usable(name) {
+			continue
+		}
+		data, err := fn()
+		if err = hm.record(name, data, err); err != nil {
+			lastErr = err
+			continue
+		}
+		return data, name, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no healthy entropy source available")
+	}
+	return nil, "", lastErr
+}
+
+// Report returns a snapshot of all sources
+func (hm *HealthMonitor) Report() HealthReport {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	now := hm.now()
+
+	report := HealthReport{
+		RepetitionCutoff:  hm.cfg.RepetitionCutoff(),
+		ProportionCutoff:  hm.cfg.ProportionCutoff(),
+		ProportionWindow:  hm.cfg.WindowSize,
+		MinEntropyPerByte: hm.cfg.MinEntropyPerByte,
+		Timestamp:         now,
+	}
+	for _, name := range hm.order {
+		m := hm.sources[name]
+		report.Sources = append(report.Sources, m.snapshot(now))
+		if report.ActiveSource == "" && m.state == StateHealthy && m.samples > 0 {
+			report.ActiveSource = name
+		}
+	}
+	report.Healthy = report.ActiveSource != ""
+	if report.ActiveSource == "" && hm.sources[SourceSoftware].state == StateHealthy {
+		// Nothing sampled yet; software is always available as a fallback
+		report.ActiveSource = SourceSoftware
+		report.Healthy = true
+	}
+	return report
+}
+
+// defaultMonitor guards FastEntropy output
+var defaultMonitor = NewHealthMonitor(DefaultHealthConfig())
+
+// Health returns the health report for the package-level entropy sources
+func Health() HealthReport {
+	return defaultMonitor.Report()
+}
+
+// SetHealthAlertHandler registers a callback for package-level health alerts
+func SetHealthAlertHandler(fn func(HealthAlert)) {
+	defaultMonitor.SetAlertHandler(fn)
+}
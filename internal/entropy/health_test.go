@@ -0,0 +1,43 @@
+package entropy
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStuckHardwareSourceFallsBack(t *testing.T) {
+	hm := NewHealthMonitor(DefaultHealthConfig())
+
+	var alerts []HealthAlert
+	hm.SetAlertHandler(func(a HealthAlert) { alerts = append(alerts, a) })
+
+	stuck := func() ([]byte, error) { return bytes.Repeat([]byte{0xAA}, 32), nil }
+	sources := map[string]func() ([]byte, error){
+		SourceHardware: stuck,
+		SourceSoftware: SimpleEntropy,
+	}
+
+	data, source, err := hm.Read(sources)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if source != SourceSoftware {
+		t.Fatalf("source = %q, want %q", source, SourceSoftware)
+	}
+	if len(data) != 32 {
+		t.Fatalf("len = %d, want 32", len(data))
+	}
+
+	report := hm.Report()
+	if report.Sources[0].State != StateDegraded || report.Sources[0].RepetitionFailures != 1 {
+		t.Fatalf("hardware health = %+v", report.Sources[0])
+	}
+	if len(alerts) != 1 || alerts[0].Test != TestRepetitionCount {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+
+	// The degraded source is bypassed until RetryAfter elapses
+	if _, source, _ = hm.Read(sources); source != SourceSoftware || hm.Report().Sources[0].Samples != 1 {
+		t.Fatalf("degraded hardware source was retried early")
+	}
+}
@@ -0,0 +1,182 @@
+// Package eventsign signs block events with Ed25519 so consumers of the
+// webhook and stream feeds can verify an event came from Sprint.
+//
+// The signature covers a canonical encoding of the event rather than its
+// JSON, so it survives re-encoding by proxies and clients. The message is
+// these lines joined by "\n", with no trailing newline:
+//
+//	sprint-block-v1
+//	<key id>
+//	<chain>
+//	<height>
+//	<hash>
+//	<txid, or empty>
+//	<is_header: 1 or 0>
+//	<timestamp in unix seconds, 0 when unset>
+//
+// Block timestamps have one-second precision on every supported chain, so
+// the fraction is not covered. The signature is sent base64-encoded
+// (standard alphabet, padded) next to the key ID; public keys are
+// published at /api/v1/signing-keys, and pkg/sprintclient verifies them.
+package eventsign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
+)
+
+// MessageVersion is the first line of every signed message
+const MessageVersion = "sprint-block-v1"
+
+// Algorithm names the signature scheme in published keys
+const Algorithm = "ed25519"
+
+// memoSize bounds the signatures remembered per signer. One block goes to
+// every stream client and webhook, so it is signed once and reused.
+const memoSize = 1024
+
+// ErrBadSeed is returned for a key seed of the wrong length
+var ErrBadSeed = errors.New("eventsign: Ed25519 seed must be 32 bytes")
+
+// PublicKey is a verification key as published to consumers
+type PublicKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	PublicKey string `json:"public_key"` // base64
+}
+
+// Message returns the canonical bytes signed for ev under keyID
+func Message(keyID string, ev blocks.BlockEvent) []byte {
+	header := "0"
+	if ev.IsHeader {
+		header = "1"
+	}
+	var ts int64
+	if !ev.Timestamp.IsZero() {
+		ts = ev.Timestamp.Unix()
+	}
+	return []byte(strings.Join([]string{
+		MessageVersion,
+		keyID,
+		string(ev.Chain),
+		strconv.FormatUint(uint64(ev.Height), 10),
+		ev.Hash,
+		ev.TxID,
+		header,
+		strconv.FormatInt(ts, 10),
+	}, "\n"))
+}
+
+// Signer signs events with one Ed25519 key. The seed is held in a
+// securebuf buffer and only expanded for the duration of a signature.
+type Signer struct {
+	keyID string
+	seed  *securebuf.Buffer
+	pub   ed25519.PublicKey
+
+	mu   sync.Mutex
+	memo map[string]string // Message -> signature
+}
+
+// NewSigner creates a signer from a 32-byte Ed25519 seed. The caller may
+// zero seed afterwards.
+func NewSigner(keyID string, seed []byte) (*Signer, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("eventsign: key ID required")
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, ErrBadSeed
+	}
+	buf, err := securebuf.New(len(seed))
+	if err != nil {
+		return nil, fmt.Errorf("eventsign: %w", err)
+	}
+	if err := buf.Write(seed); err != nil {
+		buf.Free()
+		return nil, fmt.Errorf("eventsign: %w", err)
+	}
+
+	priv := ed25519.NewKeyFromSeed(seed)
+	pub := append(ed25519.PublicKey(nil), priv.Public().(ed25519.PublicKey)...)
+	zero(priv)
+	return &Signer{keyID: keyID, seed: buf, pub: pub, memo: make(map[string]string)}, nil
+}
+
+// GenerateSeed returns a new random Ed25519 seed
+func GenerateSeed() ([]byte, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// KeyID names the signing key
+func (s *Signer) KeyID() string { return s.keyID }
+
+// PublicKey returns the key consumers verify with
+func (s *Signer) PublicKey() PublicKey {
+	return PublicKey{
+		KeyID:     s.keyID,
+		Algorithm: Algorithm,
+		PublicKey: base64.StdEncoding.EncodeToString(s.pub),
+	}
+}
+
+// Sign returns the base64 signature of ev
+func (s *Signer) Sign(ev blocks.BlockEvent) (string, error) {
+	msg := Message(s.keyID, ev)
+	s.mu.Lock()
+	if sig, ok := s.memo[string(msg)]; ok {
+		s.mu.Unlock()
+		return sig, nil
+	}
+	s.mu.Unlock()
+
+	seed, err := s.seed.ReadToSlice()
+	if err != nil {
+		return "", fmt.Errorf("eventsign: %w", err)
+	}
+	priv := ed25519.NewKeyFromSeed(seed)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+	zero(seed)
+	zero(priv)
+
+	s.mu.Lock()
+	if len(s.memo) >= memoSize {
+		s.memo = make(map[string]string)
+	}
+	s.memo[string(msg)] = sig
+	s.mu.Unlock()
+	return sig, nil
+}
+
+// Close frees the seed
+func (s *Signer) Close() {
+	s.seed.Free()
+}
+
+// Verify checks a base64 signature of ev made with keyID's key
+func Verify(pub ed25519.PublicKey, keyID string, ev blocks.BlockEvent, signature string) bool {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(pub, Message(keyID, ev), sig)
+}
+
+// zero overwrites key material
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,67 @@
+package eventsign
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/sprintclient"
+)
+
+func TestSignVerifiesInClient(t *testing.T) {
+	seed, err := GenerateSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSigner("k1", seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	ev := blocks.BlockEvent{
+		Chain:     blocks.ChainEthereum,
+		Height:    19000000,
+		Hash:      "0xabc",
+		Timestamp: time.Unix(1700000000, 500).UTC(),
+		Source:    "relay",
+	}
+	sig, err := s.Sign(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.Sign(ev); again != sig {
+		t.Fatal("signature not reused for the same event")
+	}
+
+	// The stream message must verify after a JSON round trip into the client
+	msg := schemas.NewBlock(ev)
+	msg.KeyID, msg.Signature = s.KeyID(), sig
+	data, _ := json.Marshal(msg)
+	var blk sprintclient.Block
+	if err := json.Unmarshal(data, &blk); err != nil {
+		t.Fatal(err)
+	}
+	pub := s.PublicKey()
+	v, err := sprintclient.NewBlockVerifier([]sprintclient.SigningKey{{
+		KeyID: pub.KeyID, Algorithm: pub.Algorithm, PublicKey: pub.PublicKey,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(blk); err != nil {
+		t.Fatalf("client rejected signed block: %v", err)
+	}
+
+	blk.Height++
+	if err := v.Verify(blk); !errors.Is(err, sprintclient.ErrBadSignature) {
+		t.Fatalf("tampered block: got %v", err)
+	}
+	blk.KeyID = "k2"
+	if err := v.Verify(blk); !errors.Is(err, sprintclient.ErrUnknownKey) {
+		t.Fatalf("unknown key: got %v", err)
+	}
+}
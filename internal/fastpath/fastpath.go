@@ -1,5 +1,6 @@
 // Package fastpath provides ultra-low-latency handlers for Bitcoin Sprint.
-// These handlers are optimized for 5ms p99 latency in-region.
+// These handlers are optimized for 5ms p99 latency in-region. Responses are
+// preserialized per chain in a Registry and swapped atomically on refresh.
 package fastpath
 
 import (
@@ -34,49 +35,37 @@ func NewSnapshot(init []byte) *Snapshot {
 	return &s
 }
 
-// Global snapshots for frequently accessed endpoints
-var (
-	latestSnap = NewSnapshot([]byte(`{"height":0,"hash":""}`))
-	statusSnap = NewSnapshot([]byte(`{"status":"ok","connections":0,"uptime_seconds":0}`))
-	
-	// Simple atomic counters for metrics
-	latestHits uint64
-	statusHits uint64
-)
+// bitcoin backs the legacy single-chain handlers below
+var bitcoin = Default.Chain("bitcoin")
+
+func init() {
+	bitcoin.RefreshLatestRaw([]byte(`{"height":0,"hash":""}`))
+	bitcoin.RefreshStatusRaw([]byte(`{"status":"ok","connections":0,"uptime_seconds":0}`))
+}
 
-// LatestHandler serves pre-encoded JSON for the /latest endpoint.
+// LatestHandler serves pre-encoded JSON for the bitcoin /latest endpoint.
 // Expected p99 ≤ 5ms for in-region clients.
 func LatestHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddUint64(&latestHits, 1)
-	
-	b := latestSnap.Load()
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
-	_, _ = w.Write(b) // ~sub-ms on hit
+	bitcoin.latest.serve(w, r) // ~sub-ms on hit
 }
 
-// StatusHandler serves pre-encoded JSON for the /status endpoint.
+// StatusHandler serves pre-encoded JSON for the bitcoin /status endpoint.
 // Expected p99 ≤ 5ms for in-region clients.
 func StatusHandler(w http.ResponseWriter, r *http.Request) {
-	atomic.AddUint64(&statusHits, 1)
-	
-	b := statusSnap.Load()
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Length", strconv.Itoa(len(b)))
-	_, _ = w.Write(b) // ~sub-ms on hit
+	bitcoin.status.serve(w, r) // ~sub-ms on hit
 }
 
-// GetLatestHits returns the number of hits to the latest endpoint.
+// GetLatestHits returns the number of hits to the bitcoin latest endpoint.
 func GetLatestHits() uint64 {
-	return atomic.LoadUint64(&latestHits)
+	return bitcoin.LatestHits()
 }
 
-// GetStatusHits returns the number of hits to the status endpoint.
+// GetStatusHits returns the number of hits to the bitcoin status endpoint.
 func GetStatusHits() uint64 {
-	return atomic.LoadUint64(&statusHits)
+	return bitcoin.StatusHits()
 }
 
-// RefreshLatest updates the latest snapshot with new data.
+// RefreshLatest updates the bitcoin latest snapshot with new data.
 // This should be called from a background process, not directly in handlers.
 func RefreshLatest(height int64, hash string) {
 	b := make([]byte, 0, 96)
@@ -85,10 +74,10 @@ func RefreshLatest(height int64, hash string) {
 	b = append(b, `,"hash":"`...)
 	b = append(b, hash...)
 	b = append(b, `"}`...)
-	latestSnap.Store(b) // atomic swap
+	bitcoin.RefreshLatestRaw(b) // atomic swap
 }
 
-// RefreshStatus updates the status snapshot with new data.
+// RefreshStatus updates the bitcoin status snapshot with new data.
 // This should be called from a background process, not directly in handlers.
 func RefreshStatus(status string, connections int, uptimeSeconds int64) {
 	b := make([]byte, 0, 128)
@@ -99,12 +88,12 @@ func RefreshStatus(status string, connections int, uptimeSeconds int64) {
 	b = append(b, `,"uptime_seconds":`...)
 	b = strconv.AppendInt(b, uptimeSeconds, 10)
 	b = append(b, `}`...)
-	statusSnap.Store(b) // atomic swap
+	bitcoin.RefreshStatusRaw(b) // atomic swap
 }
 
-// RefreshLatestRaw updates the latest snapshot with raw JSON data.
+// RefreshLatestRaw updates the bitcoin latest snapshot with raw JSON data.
 // This is useful when you already have a marshalled JSON object.
 // This should be called from a background process, not directly in handlers.
 func RefreshLatestRaw(jsonData []byte) {
-	latestSnap.Store(jsonData) // atomic swap
+	bitcoin.RefreshLatestRaw(jsonData) // atomic swap
 }
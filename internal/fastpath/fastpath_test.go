@@ -3,6 +3,7 @@ package fastpath_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -67,7 +68,7 @@ func BenchmarkLatestHandler_Parallel(b *testing.B) {
 		for {
 			time.Sleep(200 * time.Millisecond)
 			height++
-			fastpath.RefreshLatest(height, "hash"+string(height))
+			fastpath.RefreshLatest(height, "hash"+strconv.FormatInt(height, 10))
 		}
 	}()
 	
@@ -106,7 +107,7 @@ func BenchmarkHighLoad(b *testing.B) {
 			select {
 			case <-ticker.C:
 				height++
-				fastpath.RefreshLatest(height, "hash"+string(height))
+				fastpath.RefreshLatest(height, "hash"+strconv.FormatInt(height, 10))
 			case <-stopChan:
 				return
 			}
@@ -173,9 +174,9 @@ func TestLatencyUnderLoad(t *testing.T) {
 	stopChan := make(chan struct{})
 	
 	// Start update goroutine
-	wg.Add(1)
+	updaterDone := make(chan struct{})
 	go func() {
-		defer wg.Done()
+		defer close(updaterDone)
 		height := int64(789123)
 		
 		ticker := time.NewTicker(50 * time.Millisecond)
@@ -185,7 +186,7 @@ func TestLatencyUnderLoad(t *testing.T) {
 			select {
 			case <-ticker.C:
 				height++
-				fastpath.RefreshLatest(height, "hash"+string(height))
+				fastpath.RefreshLatest(height, "hash"+strconv.FormatInt(height, 10))
 			case <-stopChan:
 				return
 			}
@@ -227,6 +228,7 @@ func TestLatencyUnderLoad(t *testing.T) {
 	
 	wg.Wait()
 	close(stopChan)
+	<-updaterDone
 	
 	// Calculate percentiles
 	sortDurations(latencies)
@@ -257,3 +259,22 @@ func sortDurations(durations []time.Duration) {
 		}
 	}
 }
+
+func TestLatestHandlerNotModified(t *testing.T) {
+	fastpath.RefreshLatest(789124, "00000000000000000001aa")
+
+	rec := httptest.NewRecorder()
+	fastpath.LatestHandler(rec, httptest.NewRequest("GET", "/v1/latest", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first request: code=%d etag=%q", rec.Code, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/latest", nil)
+	req.Header.Set("If-None-Match", "W/"+etag)
+	rec = httptest.NewRecorder()
+	fastpath.LatestHandler(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("conditional request: code=%d body=%q, want empty 304", rec.Code, rec.Body.String())
+	}
+}
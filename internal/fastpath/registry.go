@@ -0,0 +1,314 @@
+package fastpath
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Endpoint names used in metrics
+const (
+	EndpointLatest = "latest"
+	EndpointStatus = "status"
+)
+
+var (
+	fastpathHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fastpath_hits_total",
+			Help: "Requests served from preserialized fastpath snapshots",
+		},
+		[]string{"chain", "endpoint"},
+	)
+
+	fastpathLatency = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "fastpath_serve_duration_seconds",
+			Help:    "Time spent serving fastpath snapshots",
+			Buckets: []float64{.00001, .000025, .00005, .0001, .00025, .0005, .001, .0025, .005},
+		},
+		[]string{"chain", "endpoint"},
+	)
+
+	fastpathRefreshes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fastpath_refreshes_total",
+			Help: "Fastpath snapshot refreshes",
+		},
+		[]string{"chain", "endpoint"},
+	)
+
+	fastpathNotModified = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fastpath_not_modified_total",
+			Help: "Conditional requests answered 304 from fastpath snapshots",
+		},
+		[]string{"chain", "endpoint"},
+	)
+
+	jsonContentType = []string{"application/json"}
+)
+
+// frame is an immutable preserialized response body with its Content-Length
+// and ETag headers
+type frame struct {
+	body   []byte
+	length []string
+	etag   []string
+}
+
+// newFrame copies body; an empty etag is derived from the body
+func newFrame(body []byte, etag string) *frame {
+	b := append([]byte(nil), body...) // ensure immutable copy
+	if etag == "" {
+		etag = BodyETag(b)
+	}
+	return &frame{body: b, length: []string{strconv.Itoa(len(b))}, etag: []string{etag}}
+}
+
+// BlockETag is the ETag of a latest-block response. It is weak because the
+// body also carries detection timing that differs between relays for the
+// same block.
+func BlockETag(chain, hash string) string {
+	return `W/"` + chain + ":" + hash + `"`
+}
+
+// BodyETag is a strong ETag over an encoded response body
+func BodyETag(body []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(body)
+	return `"` + strconv.FormatUint(h.Sum64(), 16) + `"`
+}
+
+// ETagMatch reports whether an If-None-Match header value matches etag,
+// using the weak comparison RFC 9110 requires for If-None-Match
+func ETagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// endpoint holds one chain endpoint's snapshot and pre-resolved metrics
+type endpoint struct {
+	snap    atomic.Pointer[frame]
+	hits    atomic.Uint64
+	hitCtr  prometheus.Counter
+	latency prometheus.Observer
+	refresh prometheus.Counter
+	notMod  prometheus.Counter
+}
+
+func newEndpoint(chain, name string) *endpoint {
+	return &endpoint{
+		hitCtr:  fastpathHits.WithLabelValues(chain, name),
+		latency: fastpathLatency.WithLabelValues(chain, name),
+		refresh: fastpathRefreshes.WithLabelValues(chain, name),
+		notMod:  fastpathNotModified.WithLabelValues(chain, name),
+	}
+}
+
+func (e *endpoint) store(body []byte, etag string) {
+	e.snap.Store(newFrame(body, etag))
+	e.refresh.Inc()
+}
+
+// serve writes the current frame, or 304 if the request already holds it;
+// it reports false if no snapshot exists yet
+func (e *endpoint) serve(w http.ResponseWriter, r *http.Request) bool {
+	start := time.Now()
+	f := e.snap.Load()
+	if f == nil {
+		return false
+	}
+	h := w.Header()
+	h["Etag"] = f.etag
+	if r != nil && ETagMatch(r.Header.Get("If-None-Match"), f.etag[0]) {
+		w.WriteHeader(http.StatusNotModified)
+		e.hits.Add(1)
+		e.hitCtr.Inc()
+		e.notMod.Inc()
+		e.latency.Observe(time.Since(start).Seconds())
+		return true
+	}
+	h["Content-Type"] = jsonContentType
+	h["Content-Length"] = f.length
+	_, _ = w.Write(f.body)
+
+	e.hits.Add(1)
+	e.hitCtr.Inc()
+	e.latency.Observe(time.Since(start).Seconds())
+	return true
+}
+
+// ChainSnapshots holds the fastpath snapshots for a single chain
+type ChainSnapshots struct {
+	chain  string
+	latest *endpoint
+	status *endpoint
+}
+
+// Chain returns the chain name
+func (c *ChainSnapshots) Chain() string {
+	return c.chain
+}
+
+// fastBlock is the preserialized shape of a latest block response
+type fastBlock struct {
+	Chain       string    `json:"chain"`
+	Height      uint32    `json:"height"`
+	Hash        string    `json:"hash"`
+	Timestamp   time.Time `json:"timestamp"`
+	DetectedAt  time.Time `json:"detected_at"`
+	RelayTimeMs float64   `json:"relay_time_ms"`
+	Source      string    `json:"source,omitempty"`
+}
+
+// RefreshBlock serializes a block event into the latest snapshot
+func (c *ChainSnapshots) RefreshBlock(event blocks.BlockEvent) error {
+	body, err := json.Marshal(fastBlock{
+		Chain:       c.chain,
+		Height:      event.Height,
+		Hash:        event.Hash,
+		Timestamp:   event.Timestamp,
+		DetectedAt:  event.DetectedAt,
+		RelayTimeMs: event.RelayTimeMs,
+		Source:      event.Source,
+	})
+	if err != nil {
+		return err
+	}
+	c.latest.store(body, BlockETag(c.chain, event.Hash))
+	return nil
+}
+
+// RefreshLatestRaw replaces the latest snapshot with already-encoded JSON
+func (c *ChainSnapshots) RefreshLatestRaw(jsonData []byte) {
+	c.latest.store(jsonData, "")
+}
+
+// RefreshStatus updates the status snapshot
+func (c *ChainSnapshots) RefreshStatus(status string, connections int, uptimeSeconds int64) {
+	b := make([]byte, 0, 128)
+	b = append(b, `{"chain":`...)
+	b = strconv.AppendQuote(b, c.chain)
+	b = append(b, `,"status":`...)
+	b = strconv.AppendQuote(b, status)
+	b = append(b, `,"connections":`...)
+	b = strconv.AppendInt(b, int64(connections), 10)
+	b = append(b, `,"uptime_seconds":`...)
+	b = strconv.AppendInt(b, uptimeSeconds, 10)
+	b = append(b, `}`...)
+	c.status.store(b, "")
+}
+
+// RefreshStatusRaw replaces the status snapshot with already-encoded JSON
+func (c *ChainSnapshots) RefreshStatusRaw(jsonData []byte) {
+	c.status.store(jsonData, "")
+}
+
+// LatestHits returns the number of latest requests served from the snapshot
+func (c *ChainSnapshots) LatestHits() uint64 {
+	return c.latest.hits.Load()
+}
+
+// StatusHits returns the number of status requests served from the snapshot
+func (c *ChainSnapshots) StatusHits() uint64 {
+	return c.status.hits.Load()
+}
+
+// LatestHandler serves the latest snapshot, deferring to fallback until one exists
+func (c *ChainSnapshots) LatestHandler(fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.latest.serve(w, r) && fallback != nil {
+			fallback.ServeHTTP(w, r)
+		}
+	}
+}
+
+// StatusHandler serves the status snapshot, deferring to fallback until one exists
+func (c *ChainSnapshots) StatusHandler(fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.status.serve(w, r) && fallback != nil {
+			fallback.ServeHTTP(w, r)
+		}
+	}
+}
+
+// Registry holds fastpath snapshots per chain
+type Registry struct {
+	mu     sync.RWMutex
+	chains map[string]*ChainSnapshots
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{chains: make(map[string]*ChainSnapshots)}
+}
+
+// Chain returns the snapshots for a chain, creating them on first use
+func (r *Registry) Chain(chain string) *ChainSnapshots {
+	r.mu.RLock()
+	c, ok := r.chains[chain]
+	r.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok = r.chains[chain]; ok {
+		return c
+	}
+	c = &ChainSnapshots{
+		chain:  chain,
+		latest: newEndpoint(chain, EndpointLatest),
+		status: newEndpoint(chain, EndpointStatus),
+	}
+	r.chains[chain] = c
+	return c
+}
+
+// Chains returns the names of all registered chains
+func (r *Registry) Chains() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.chains))
+	for name := range r.chains {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Stats returns hit counters per chain
+func (r *Registry) Stats() map[string]map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stats := make(map[string]map[string]uint64, len(r.chains))
+	for name, c := range r.chains {
+		stats[name] = map[string]uint64{
+			EndpointLatest: c.LatestHits(),
+			EndpointStatus: c.StatusHits(),
+		}
+	}
+	return stats
+}
+
+// Default is the process-wide fastpath registry
+var Default = NewRegistry()
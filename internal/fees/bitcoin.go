@@ -0,0 +1,77 @@
+package fees
+
+import (
+	"context"
+	"math"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+)
+
+const (
+	// defaultBlockVSize is the virtual size a Bitcoin block can hold
+	defaultBlockVSize = 1_000_000
+	// minRelayFeeRate is the default minimum relay fee, sat/vB
+	minRelayFeeRate = 1.0
+)
+
+// Confirmation targets, in blocks, for each level
+const (
+	bitcoinFastTarget     = 1
+	bitcoinStandardTarget = 3
+	bitcoinSlowTarget     = 6
+)
+
+// MempoolSource estimates Bitcoin fees from the P2P mempool: the fee rate
+// needed to be among the transactions the next N blocks can hold, assuming
+// miners take the highest fee rates first
+type MempoolSource struct {
+	Mempool    *mempool.Mempool
+	BlockVSize int64 // 0 uses 1,000,000 vB
+}
+
+// Estimate implements Source
+func (s MempoolSource) Estimate(ctx context.Context) (*Estimate, error) {
+	if s.Mempool == nil {
+		return nil, ErrNoData
+	}
+	blockVSize := s.BlockVSize
+	if blockVSize <= 0 {
+		blockVSize = defaultBlockVSize
+	}
+
+	// Highest fee rate first
+	entries, _ := s.Mempool.Page(mempool.PageOptions{})
+	if len(entries) == 0 {
+		return nil, ErrNoData
+	}
+
+	rates := make([]float64, 0, len(entries))
+	vsizes := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		rates = append(rates, e.FeeRate)
+		vsizes = append(vsizes, int64(e.Size))
+	}
+
+	return &Estimate{
+		Unit:     "sat/vB",
+		Fast:     feeRateForTarget(rates, vsizes, bitcoinFastTarget*blockVSize),
+		Standard: feeRateForTarget(rates, vsizes, bitcoinStandardTarget*blockVSize),
+		Slow:     feeRateForTarget(rates, vsizes, bitcoinSlowTarget*blockVSize),
+		Source:   "mempool",
+		Samples:  len(entries),
+	}, nil
+}
+
+// feeRateForTarget returns the fee rate of the transaction at the edge of
+// the first capacity vbytes of rates (descending), or the minimum relay fee
+// if everything fits
+func feeRateForTarget(rates []float64, vsizes []int64, capacity int64) float64 {
+	var used int64
+	for i, rate := range rates {
+		used += vsizes[i]
+		if used >= capacity {
+			return math.Max(rate, minRelayFeeRate)
+		}
+	}
+	return minRelayFeeRate
+}
@@ -0,0 +1,95 @@
+package fees
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// feeHistoryBlocks is how many recent blocks eth_feeHistory covers
+const feeHistoryBlocks = 20
+
+// feeHistoryPercentiles are the reward percentiles requested for the slow,
+// standard and fast levels
+var feeHistoryPercentiles = []float64{10, 50, 90}
+
+// FeeHistorySource estimates Ethereum fees from eth_feeHistory: the next
+// block's base fee plus the median, over recent blocks, of each priority fee
+// percentile
+type FeeHistorySource struct {
+	rpc rpcClient
+}
+
+// NewFeeHistorySource queries endpoints in order until one answers
+func NewFeeHistorySource(endpoints []string, timeout time.Duration) *FeeHistorySource {
+	return &FeeHistorySource{rpc: newRPCClient(endpoints, timeout)}
+}
+
+type feeHistory struct {
+	BaseFeePerGas []string   `json:"baseFeePerGas"`
+	Reward        [][]string `json:"reward"`
+}
+
+// Estimate implements Source
+func (s *FeeHistorySource) Estimate(ctx context.Context) (*Estimate, error) {
+	var history feeHistory
+	params := []interface{}{fmt.Sprintf("0x%x", feeHistoryBlocks), "latest", feeHistoryPercentiles}
+	if err := s.rpc.call(ctx, "eth_feeHistory", params, &history); err != nil {
+		return nil, err
+	}
+	if len(history.BaseFeePerGas) == 0 {
+		return nil, ErrNoData
+	}
+
+	// The last base fee is the one the next block will charge
+	baseFee, err := weiToGwei(history.BaseFeePerGas[len(history.BaseFeePerGas)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	tips := make([]float64, len(feeHistoryPercentiles))
+	for p := range feeHistoryPercentiles {
+		samples := make([]float64, 0, len(history.Reward))
+		for _, block := range history.Reward {
+			if p >= len(block) {
+				continue
+			}
+			tip, err := weiToGwei(block[p])
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, tip)
+		}
+		sort.Float64s(samples)
+		tips[p] = percentile(samples, 50)
+	}
+
+	return &Estimate{
+		Unit:     "gwei",
+		Slow:     baseFee + tips[0],
+		Standard: baseFee + tips[1],
+		Fast:     baseFee + tips[2],
+		BaseFee:  baseFee,
+		Source:   "eth_feeHistory",
+		Samples:  len(history.Reward),
+	}, nil
+}
+
+// weiToGwei parses a hex wei quantity
+func weiToGwei(hex string) (float64, error) {
+	wei, ok := new(big.Int).SetString(trimHexPrefix(hex), 16)
+	if !ok {
+		return 0, fmt.Errorf("invalid quantity %q", hex)
+	}
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return gwei, nil
+}
+
+func trimHexPrefix(s string) string {
+	if len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return s[2:]
+	}
+	return s
+}
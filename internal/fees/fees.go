@@ -0,0 +1,114 @@
+// Package fees estimates transaction fees per chain from live sources: the
+// P2P mempool for Bitcoin, eth_feeHistory for Ethereum and recent
+// prioritization fees for Solana.
+package fees
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	// ErrUnsupportedChain is returned for chains without a registered source
+	ErrUnsupportedChain = errors.New("fee estimation not available for chain")
+	// ErrNoData is returned when a source has nothing to estimate from yet
+	ErrNoData = errors.New("no fee data available")
+)
+
+// Estimate is a fee recommendation for one chain. Slow, Standard and Fast
+// are per-unit prices in Unit for inclusion within the source's targets.
+type Estimate struct {
+	Chain     string    `json:"chain"`
+	Unit      string    `json:"unit"`
+	Slow      float64   `json:"slow"`
+	Standard  float64   `json:"standard"`
+	Fast      float64   `json:"fast"`
+	BaseFee   float64   `json:"base_fee,omitempty"` // Ethereum: next block's base fee, included in each level
+	Source    string    `json:"source"`
+	Samples   int       `json:"samples"` // Transactions, blocks or slots the estimate is drawn from
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Source produces an estimate for one chain
+type Source interface {
+	Estimate(ctx context.Context) (*Estimate, error)
+}
+
+// Estimator routes estimates to per-chain sources, collapsing concurrent
+// requests for the same chain into one upstream call
+type Estimator struct {
+	mu      sync.RWMutex
+	sources map[string]Source
+	group   singleflight.Group
+	now     func() time.Time
+}
+
+// NewEstimator returns an estimator with no sources
+func NewEstimator() *Estimator {
+	return &Estimator{
+		sources: make(map[string]Source),
+		now:     time.Now,
+	}
+}
+
+// Register sets the source for chain, replacing any previous one
+func (e *Estimator) Register(chain string, src Source) {
+	e.mu.Lock()
+	e.sources[chain] = src
+	e.mu.Unlock()
+}
+
+// Chains returns the chains with a registered source
+func (e *Estimator) Chains() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	chains := make([]string, 0, len(e.sources))
+	for chain := range e.sources {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+	return chains
+}
+
+// Estimate queries the source for chain
+func (e *Estimator) Estimate(ctx context.Context, chain string) (*Estimate, error) {
+	e.mu.RLock()
+	src, ok := e.sources[chain]
+	e.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnsupportedChain
+	}
+
+	v, err, _ := e.group.Do(chain, func() (interface{}, error) {
+		est, err := src.Estimate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		est.Chain = chain
+		if est.UpdatedAt.IsZero() {
+			est.UpdatedAt = e.now()
+		}
+		return est, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sharing a flight each get their own copy
+	est := *v.(*Estimate)
+	return &est, nil
+}
+
+// percentile returns the p-th percentile (0-100) of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p / 100 * float64(len(sorted)-1))
+	return sorted[idx]
+}
@@ -0,0 +1,95 @@
+package fees
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+)
+
+func rpcServer(t *testing.T, method string, result interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != method {
+			t.Errorf("method = %q, want %q", req.Method, method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": result})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestMempoolSource(t *testing.T) {
+	m := mempool.New()
+	defer m.Stop()
+	// 10 x 250kvB blocks' worth at rates 100, 90, ..., 10 sat/vB
+	for i := 0; i < 10; i++ {
+		m.AddWithDetails(fmt.Sprintf("tx%d", i), 250_000, 0, float64(100-10*i))
+	}
+
+	est, err := MempoolSource{Mempool: m}.Estimate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1 block holds the top 4 txs, 3 blocks all 10
+	if est.Fast != 70 || est.Standard != minRelayFeeRate || est.Slow != minRelayFeeRate {
+		t.Fatalf("estimate = %+v", est)
+	}
+
+	if _, err := (MempoolSource{Mempool: mempool.New()}).Estimate(context.Background()); !errors.Is(err, ErrNoData) {
+		t.Fatalf("empty mempool err = %v, want ErrNoData", err)
+	}
+}
+
+func TestFeeHistorySource(t *testing.T) {
+	srv := rpcServer(t, "eth_feeHistory", map[string]interface{}{
+		"oldestBlock":   "0x10",
+		"baseFeePerGas": []string{"0x3b9aca00", "0x3b9aca00", "0x77359400"}, // 1, 1, 2 gwei
+		"reward": [][]string{
+			{"0x5f5e100", "0x3b9aca00", "0xb2d05e00"}, // 0.1, 1, 3 gwei
+			{"0x5f5e100", "0x77359400", "0xee6b2800"}, // 0.1, 2, 4 gwei
+		},
+	})
+
+	// The first endpoint is down; the source fails over
+	est, err := NewFeeHistorySource([]string{"http://127.0.0.1:1", srv.URL}, time.Second).Estimate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.BaseFee != 2 || math.Abs(est.Slow-2.1) > 1e-9 || est.Standard != 3 || est.Fast != 5 {
+		t.Fatalf("estimate = %+v", est)
+	}
+}
+
+func TestPrioritizationFeeSource(t *testing.T) {
+	recent := make([]prioritizationFee, 0, 5)
+	for i, fee := range []uint64{0, 100, 200, 300, 400} {
+		recent = append(recent, prioritizationFee{Slot: uint64(i), PrioritizationFee: fee})
+	}
+	srv := rpcServer(t, "getRecentPrioritizationFees", recent)
+
+	est, err := NewPrioritizationFeeSource([]string{srv.URL}, time.Second).Estimate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if est.Slow != 100 || est.Standard != 200 || est.Fast != 300 || est.Samples != 5 {
+		t.Fatalf("estimate = %+v", est)
+	}
+}
+
+func TestEstimatorUnsupportedChain(t *testing.T) {
+	e := NewEstimator()
+	if _, err := e.Estimate(context.Background(), "dogecoin"); !errors.Is(err, ErrUnsupportedChain) {
+		t.Fatalf("err = %v, want ErrUnsupportedChain", err)
+	}
+}
@@ -0,0 +1,89 @@
+package fees
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// rpcClient calls a JSON-RPC method on the first endpoint that answers
+type rpcClient struct {
+	endpoints []string
+	client    *http.Client
+}
+
+func newRPCClient(endpoints []string, timeout time.Duration) rpcClient {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return rpcClient{endpoints: endpoints, client: &http.Client{Timeout: timeout}}
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// call tries each endpoint in order and returns the last error if none
+// succeeds
+func (c rpcClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	if len(c.endpoints) == 0 {
+		return errors.New("no endpoints configured")
+	}
+	var lastErr error
+	for _, endpoint := range c.endpoints {
+		if lastErr = c.callEndpoint(ctx, endpoint, method, params, result); lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return lastErr
+}
+
+func (c rpcClient) callEndpoint(ctx context.Context, endpoint, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status %d", method, resp.StatusCode)
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %w", method, rpcResp.Error)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
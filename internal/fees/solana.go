@@ -0,0 +1,50 @@
+package fees
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// PrioritizationFeeSource estimates Solana priority fees from
+// getRecentPrioritizationFees, which reports the lowest fee that landed a
+// transaction in each of the last ~150 slots
+type PrioritizationFeeSource struct {
+	rpc rpcClient
+}
+
+// NewPrioritizationFeeSource queries endpoints in order until one answers
+func NewPrioritizationFeeSource(endpoints []string, timeout time.Duration) *PrioritizationFeeSource {
+	return &PrioritizationFeeSource{rpc: newRPCClient(endpoints, timeout)}
+}
+
+type prioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee uint64 `json:"prioritizationFee"`
+}
+
+// Estimate implements Source
+func (s *PrioritizationFeeSource) Estimate(ctx context.Context) (*Estimate, error) {
+	var recent []prioritizationFee
+	if err := s.rpc.call(ctx, "getRecentPrioritizationFees", []interface{}{}, &recent); err != nil {
+		return nil, err
+	}
+	if len(recent) == 0 {
+		return nil, ErrNoData
+	}
+
+	fees := make([]float64, len(recent))
+	for i, f := range recent {
+		fees[i] = float64(f.PrioritizationFee)
+	}
+	sort.Float64s(fees)
+
+	return &Estimate{
+		Unit:     "micro-lamports/CU",
+		Slow:     percentile(fees, 25),
+		Standard: percentile(fees, 50),
+		Fast:     percentile(fees, 75),
+		Source:   "getRecentPrioritizationFees",
+		Samples:  len(recent),
+	}, nil
+}
@@ -0,0 +1,283 @@
+// Package lifecycle starts and stops a process's components in dependency
+// order.
+//
+// Each component declares the components it uses. Those are started before
+// it and stopped after it, so nothing is stopped while a component that
+// sends to it is still running. Every start and stop call is bounded by a
+// timeout; a stop that overruns is reported and abandoned rather than
+// holding up the rest of the shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Step statuses in a Report
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusTimeout = "timeout"
+	StatusSkipped = "skipped" // The overall deadline passed before the step ran
+)
+
+// Component is one subsystem under the Manager
+type Component struct {
+	Name      string
+	DependsOn []string // Started before this component and stopped after it
+
+	// Start and Stop may be nil. A component without Start counts as
+	// running as soon as it is added.
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+
+	Timeout time.Duration // Bounds each Start and Stop call; 0 uses the Manager default
+}
+
+// StepResult is the outcome of stopping or starting one component
+type StepResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Report is the outcome of Stop
+type Report struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Steps     []StepResult  `json:"steps"`
+}
+
+// OK reports whether every step stopped cleanly
+func (r Report) OK() bool {
+	for _, s := range r.Steps {
+		if s.Status != StatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// Manager orders components by their dependencies
+type Manager struct {
+	logger         *zap.Logger
+	defaultTimeout time.Duration
+
+	mu         sync.Mutex
+	components map[string]*Component
+	added      []string        // Registration order, the tie-break between independent components
+	running    map[string]bool // Started, or added without Start
+	stopped    bool
+}
+
+// New returns a Manager whose components default to timeout per call
+func New(logger *zap.Logger, timeout time.Duration) *Manager {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Manager{
+		logger:         logger,
+		defaultTimeout: timeout,
+		components:     make(map[string]*Component),
+		running:        make(map[string]bool),
+	}
+}
+
+// Add registers c. Dependencies may be added later but must exist by the
+// time Start or Stop is called.
+func (m *Manager) Add(c Component) error {
+	if c.Name == "" {
+		return errors.New("lifecycle: component has no name")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.components[c.Name]; ok {
+		return fmt.Errorf("lifecycle: component %q already added", c.Name)
+	}
+	m.components[c.Name] = &c
+	m.added = append(m.added, c.Name)
+	if c.Start == nil {
+		m.running[c.Name] = true
+	}
+	return nil
+}
+
+// Order returns the start order: every component after its dependencies,
+// otherwise in registration order. Stop uses the reverse.
+func (m *Manager) Order() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.orderLocked()
+}
+
+func (m *Manager) orderLocked() ([]string, error) {
+	const (
+		visiting = iota + 1
+		done
+	)
+	state := make(map[string]int, len(m.components))
+	order := make([]string, 0, len(m.components))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		for _, dep := range m.components[name].DependsOn {
+			if _, ok := m.components[dep]; !ok {
+				return fmt.Errorf("lifecycle: %q depends on unknown component %q", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range m.added {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Start starts components in dependency order. If one fails, those already
+// started are stopped again and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	order, err := m.orderLocked()
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range order {
+		m.mu.Lock()
+		c, running := m.components[name], m.running[name]
+		m.mu.Unlock()
+		if running || c.Start == nil {
+			continue
+		}
+		res := m.run(ctx, c, c.Start)
+		if res.Status != StatusOK {
+			m.logger.Error("Component failed to start",
+				zap.String("component", name), zap.String("status", res.Status), zap.String("error", res.Error))
+			m.Stop(context.Background())
+			return fmt.Errorf("lifecycle: start %s: %s", name, res.Error)
+		}
+		m.mu.Lock()
+		m.running[name] = true
+		m.mu.Unlock()
+		m.logger.Debug("Component started", zap.String("component", name), zap.Duration("took", res.Duration))
+	}
+	return nil
+}
+
+// Stop stops running components in reverse dependency order, each within
+// its timeout, and logs and returns a report. Steps still pending when ctx
+// ends are skipped. Only the first call stops anything.
+func (m *Manager) Stop(ctx context.Context) Report {
+	report := Report{StartedAt: time.Now()}
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return report
+	}
+	m.stopped = true
+	order, err := m.orderLocked()
+	if err != nil {
+		// Stop what can be stopped rather than nothing
+		m.logger.Error("Stopping components in registration order", zap.Error(err))
+		order = append([]string(nil), m.added...)
+	}
+	m.mu.Unlock()
+
+	for i := len(order) - 1; i >= 0; i-- {
+		m.mu.Lock()
+		c, running := m.components[order[i]], m.running[order[i]]
+		m.mu.Unlock()
+		if !running || c.Stop == nil {
+			continue
+		}
+		var res StepResult
+		if ctx.Err() != nil {
+			res = StepResult{Name: c.Name, Status: StatusSkipped, Error: ctx.Err().Error()}
+		} else {
+			res = m.run(ctx, c, c.Stop)
+		}
+		report.Steps = append(report.Steps, res)
+		m.mu.Lock()
+		delete(m.running, c.Name)
+		m.mu.Unlock()
+	}
+	report.Duration = time.Since(report.StartedAt)
+	m.log(report)
+	return report
+}
+
+// run calls fn for c within c's timeout. A call that overruns keeps
+// running in the background; its result is discarded.
+func (m *Manager) run(ctx context.Context, c *Component, fn func(context.Context) error) StepResult {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = m.defaultTimeout
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(stepCtx)
+	}()
+
+	res := StepResult{Name: c.Name, Status: StatusOK}
+	select {
+	case err := <-done:
+		if err != nil {
+			res.Status, res.Error = StatusFailed, err.Error()
+		}
+	case <-stepCtx.Done():
+		res.Status, res.Error = StatusTimeout, fmt.Sprintf("did not finish within %s", timeout)
+	}
+	res.Duration = time.Since(start)
+	return res
+}
+
+// log writes one line per step that did not stop cleanly and a summary
+func (m *Manager) log(r Report) {
+	steps := make([]string, 0, len(r.Steps))
+	for _, s := range r.Steps {
+		steps = append(steps, fmt.Sprintf("%s=%s(%s)", s.Name, s.Status, s.Duration.Round(time.Millisecond)))
+		if s.Status != StatusOK {
+			m.logger.Warn("Component did not stop cleanly",
+				zap.String("component", s.Name), zap.String("status", s.Status), zap.String("error", s.Error))
+		}
+	}
+	m.logger.Info("Shutdown report",
+		zap.Bool("clean", r.OK()),
+		zap.Duration("took", r.Duration),
+		zap.Strings("steps", steps))
+}
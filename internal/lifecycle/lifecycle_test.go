@@ -0,0 +1,122 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartStopOrder(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(s string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			calls = append(calls, s)
+			mu.Unlock()
+			return nil
+		}
+	}
+	component := func(name string, deps ...string) Component {
+		return Component{Name: name, DependsOn: deps, Start: record("start " + name), Stop: record("stop " + name)}
+	}
+
+	m := New(nil, time.Second)
+	// Added before its dependencies, which are ordered regardless
+	m.Add(component("api", "cache", "relays"))
+	m.Add(component("p2p"))
+	m.Add(component("relays", "p2p"))
+	m.Add(component("cache"))
+	if err := m.Add(component("cache")); err == nil {
+		t.Fatal("duplicate component accepted")
+	}
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	report := m.Stop(context.Background())
+	want := []string{
+		"start cache", "start p2p", "start relays", "start api",
+		"stop api", "stop relays", "stop p2p", "stop cache",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls = %v\nwant    %v", calls, want)
+	}
+	if !report.OK() || len(report.Steps) != 4 {
+		t.Fatalf("report = %+v", report)
+	}
+	if again := m.Stop(context.Background()); len(again.Steps) != 0 {
+		t.Fatalf("second Stop ran %d steps", len(again.Steps))
+	}
+}
+
+func TestStopReportsFailuresAndTimeouts(t *testing.T) {
+	m := New(nil, time.Second)
+	release := make(chan struct{})
+	defer close(release)
+	var lastStopped bool
+
+	m.Add(Component{Name: "store", Stop: func(context.Context) error { lastStopped = true; return nil }})
+	m.Add(Component{Name: "stuck", DependsOn: []string{"store"}, Timeout: 20 * time.Millisecond,
+		Stop: func(context.Context) error { <-release; return nil }})
+	m.Add(Component{Name: "broken", DependsOn: []string{"store"},
+		Stop: func(context.Context) error { return errors.New("close failed") }})
+	m.Add(Component{Name: "panics", DependsOn: []string{"store"},
+		Stop: func(context.Context) error { panic("send on closed channel") }})
+
+	report := m.Stop(context.Background())
+	status := make(map[string]string)
+	for _, s := range report.Steps {
+		status[s.Name] = s.Status
+	}
+	if status["stuck"] != StatusTimeout || status["broken"] != StatusFailed || status["panics"] != StatusFailed {
+		t.Fatalf("statuses = %v", status)
+	}
+	// A stuck or failing dependent does not prevent the rest stopping
+	if status["store"] != StatusOK || !lastStopped || report.Steps[len(report.Steps)-1].Name != "store" {
+		t.Fatalf("store not stopped last: %+v", report.Steps)
+	}
+	if report.OK() {
+		t.Fatal("report with failures is OK")
+	}
+
+	// Steps are skipped once the overall deadline has passed
+	m2 := New(nil, time.Second)
+	m2.Add(Component{Name: "late", Stop: func(context.Context) error { return nil }})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if r := m2.Stop(ctx); len(r.Steps) != 1 || r.Steps[0].Status != StatusSkipped {
+		t.Fatalf("cancelled stop = %+v", r.Steps)
+	}
+}
+
+func TestStartFailureAndCycles(t *testing.T) {
+	var stopped []string
+	m := New(nil, time.Second)
+	m.Add(Component{Name: "db", Start: func(context.Context) error { return nil },
+		Stop: func(context.Context) error { stopped = append(stopped, "db"); return nil }})
+	m.Add(Component{Name: "api", DependsOn: []string{"db"}, Start: func(context.Context) error { return errors.New("bind: address in use") },
+		Stop: func(context.Context) error { stopped = append(stopped, "api"); return nil }})
+	if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "api") {
+		t.Fatalf("Start err = %v", err)
+	}
+	if !reflect.DeepEqual(stopped, []string{"db"}) {
+		t.Fatalf("stopped after failed start = %v", stopped)
+	}
+
+	c := New(nil, time.Second)
+	c.Add(Component{Name: "a", DependsOn: []string{"b"}})
+	c.Add(Component{Name: "b", DependsOn: []string{"a"}})
+	if _, err := c.Order(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("cycle err = %v", err)
+	}
+	u := New(nil, time.Second)
+	u.Add(Component{Name: "a", DependsOn: []string{"missing"}})
+	if err := u.Start(context.Background()); err == nil {
+		t.Fatal("unknown dependency accepted")
+	}
+}
@@ -0,0 +1,360 @@
+// Package loadshed rejects low-tier traffic when the process is under
+// pressure so Enterprise latency targets hold.
+//
+// A Shedder samples a set of signals (goroutine count, GC pause trend and any
+// registered sources such as cache memory or p2p queue depth), each
+// normalized so 1.0 means "at capacity". The pressure score is the worst of
+// them. Every tier has a threshold; above it requests of that tier are shed
+// with increasing probability and answered 429 with Retry-After.
+package loadshed
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+)
+
+var (
+	pressureScore = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "loadshed_pressure_score",
+		Help: "Combined load pressure score (1.0 = at capacity)",
+	})
+	signalPressure = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loadshed_signal_pressure",
+		Help: "Normalized pressure per signal (1.0 = at capacity)",
+	}, []string{"signal"})
+	shedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loadshed_shed_total",
+		Help: "Requests rejected by load shedding",
+	}, []string{"tier"})
+)
+
+// Signal reports a normalized pressure where 0 is idle and 1.0 is at
+// capacity. Values above 1 are allowed and mean over capacity.
+type Signal func() float64
+
+// Config tunes sampling and per-tier shedding
+type Config struct {
+	SampleInterval time.Duration
+	Smoothing      float64 // EWMA weight of the newest sample, 0-1
+
+	GoroutineLimit int           // Goroutines considered "at capacity"
+	GCPauseTarget  time.Duration // Average GC pause considered "at capacity"
+
+	// Thresholds maps each tier to the score at which it starts shedding.
+	// Tiers without an entry are never shed.
+	Thresholds map[config.Tier]float64
+	// Ramp is the score range over which shedding goes from 0% to 100% of
+	// a tier's requests, so load does not fall off a cliff at the threshold.
+	Ramp float64
+
+	RetryAfter time.Duration // Base Retry-After; scaled up with pressure
+}
+
+// DefaultConfig sheds Free first and never sheds Enterprise
+func DefaultConfig() Config {
+	return Config{
+		SampleInterval: time.Second,
+		Smoothing:      0.3,
+		GoroutineLimit: 20000,
+		GCPauseTarget:  5 * time.Millisecond,
+		Thresholds: map[config.Tier]float64{
+			config.TierFree:     0.60,
+			config.TierPro:      0.70,
+			config.TierBusiness: 0.80,
+			config.TierTurbo:    0.90,
+		},
+		Ramp:       0.15,
+		RetryAfter: time.Second,
+	}
+}
+
+// Snapshot is the current pressure view
+type Snapshot struct {
+	Score   float64            `json:"score"`
+	Signals map[string]float64 `json:"signals"`
+	Shed    map[string]int64   `json:"shed"`
+}
+
+// Shedder samples pressure signals and decides which requests to reject
+type Shedder struct {
+	cfg    Config
+	logger *zap.Logger
+
+	mu      sync.RWMutex
+	signals map[string]Signal
+	values  map[string]float64
+	score   float64
+
+	shedMu sync.Mutex
+	shed   map[config.Tier]*int64
+
+	lastNumGC uint32 // GC count at the previous sample
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// New creates a Shedder with the built-in goroutine and GC signals
+func New(cfg Config, logger *zap.Logger) *Shedder {
+	def := DefaultConfig()
+	if cfg.SampleInterval <= 0 {
+		cfg.SampleInterval = def.SampleInterval
+	}
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = def.Smoothing
+	}
+	if cfg.GoroutineLimit <= 0 {
+		cfg.GoroutineLimit = def.GoroutineLimit
+	}
+	if cfg.GCPauseTarget <= 0 {
+		cfg.GCPauseTarget = def.GCPauseTarget
+	}
+	if cfg.Thresholds == nil {
+		cfg.Thresholds = def.Thresholds
+	}
+	if cfg.Ramp <= 0 {
+		cfg.Ramp = def.Ramp
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = def.RetryAfter
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	s := &Shedder{
+		cfg:     cfg,
+		logger:  logger,
+		signals: make(map[string]Signal),
+		values:  make(map[string]float64),
+		shed:    make(map[config.Tier]*int64),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	s.signals["goroutines"] = s.goroutinePressure
+	return s
+}
+
+// AddSignal registers or replaces a named pressure source
+func (s *Shedder) AddSignal(name string, fn Signal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.signals[name] = fn
+}
+
+// Start samples signals every SampleInterval until ctx is done
+func (s *Shedder) Start(ctx context.Context) {
+	s.Sample()
+	go func() {
+		ticker := time.NewTicker(s.cfg.SampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sample()
+			}
+		}
+	}()
+}
+
+// Sample reads every signal once and updates the smoothed score
+func (s *Shedder) Sample() {
+	s.mu.RLock()
+	signals := make(map[string]Signal, len(s.signals))
+	for name, fn := range s.signals {
+		signals[name] = fn
+	}
+	s.mu.RUnlock()
+
+	raw := make(map[string]float64, len(signals)+1)
+	raw["gc_pause"] = s.gcPausePressure()
+	for name, fn := range signals {
+		raw[name] = sanitize(fn())
+	}
+
+	s.mu.Lock()
+	prevScore := s.score
+	score := 0.0
+	for name, v := range raw {
+		if old, ok := s.values[name]; ok {
+			v = old + s.cfg.Smoothing*(v-old)
+		}
+		s.values[name] = v
+		signalPressure.WithLabelValues(name).Set(v)
+		if v > score {
+			score = v
+		}
+	}
+	s.score = score
+	s.mu.Unlock()
+
+	pressureScore.Set(score)
+	if s.crossedThreshold(prevScore, score) {
+		s.logger.Warn("Load shedding level changed",
+			zap.Float64("score", score),
+			zap.Float64("previous", prevScore))
+	}
+}
+
+// Score returns the current pressure score
+func (s *Shedder) Score() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.score
+}
+
+// Snapshot returns the score, per-signal pressure and shed counts
+func (s *Shedder) Snapshot() Snapshot {
+	s.mu.RLock()
+	snap := Snapshot{
+		Score:   s.score,
+		Signals: make(map[string]float64, len(s.values)),
+		Shed:    make(map[string]int64),
+	}
+	for name, v := range s.values {
+		snap.Signals[name] = v
+	}
+	s.mu.RUnlock()
+
+	s.shedMu.Lock()
+	for tier, n := range s.shed {
+		snap.Shed[string(tier)] = atomic.LoadInt64(n)
+	}
+	s.shedMu.Unlock()
+	return snap
+}
+
+// ShouldShed decides whether a request of tier is rejected right now
+func (s *Shedder) ShouldShed(tier config.Tier) bool {
+	threshold, ok := s.cfg.Thresholds[tier]
+	if !ok {
+		return false
+	}
+	score := s.Score()
+	if score < threshold {
+		return false
+	}
+
+	p := (score - threshold) / s.cfg.Ramp
+	if p < 1 {
+		s.randMu.Lock()
+		r := s.rand.Float64()
+		s.randMu.Unlock()
+		if r >= p {
+			return false
+		}
+	}
+
+	s.countShed(tier)
+	return true
+}
+
+// RetryAfter suggests how long a shed client should back off
+func (s *Shedder) RetryAfter() time.Duration {
+	score := s.Score()
+	return time.Duration(float64(s.cfg.RetryAfter) * math.Max(1, 1+4*(score-0.5)))
+}
+
+// Middleware sheds requests whose tier, as resolved by tierOf, is over its
+// threshold. Requests for which exempt returns true always pass.
+func (s *Shedder) Middleware(tierOf func(*http.Request) config.Tier, exempt func(*http.Request) bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exempt != nil && exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		tier := tierOf(r)
+		if s.ShouldShed(tier) {
+			retry := int(math.Ceil(s.RetryAfter().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":"Server busy","message":"Request shed under load, retry later","retry_after":` + strconv.Itoa(retry) + `}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Shedder) countShed(tier config.Tier) {
+	s.shedMu.Lock()
+	n, ok := s.shed[tier]
+	if !ok {
+		n = new(int64)
+		s.shed[tier] = n
+	}
+	s.shedMu.Unlock()
+
+	atomic.AddInt64(n, 1)
+	shedTotal.WithLabelValues(string(tier)).Inc()
+}
+
+// crossedThreshold reports whether moving from prev to cur changes which
+// tiers are eligible for shedding
+func (s *Shedder) crossedThreshold(prev, cur float64) bool {
+	thresholds := make([]float64, 0, len(s.cfg.Thresholds))
+	for _, t := range s.cfg.Thresholds {
+		thresholds = append(thresholds, t)
+	}
+	sort.Float64s(thresholds)
+	for _, t := range thresholds {
+		if (prev < t) != (cur < t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Shedder) goroutinePressure() float64 {
+	return float64(runtime.NumGoroutine()) / float64(s.cfg.GoroutineLimit)
+}
+
+// gcPausePressure reports the average pause of GCs since the last sample;
+// the EWMA in Sample turns it into a trend. ReadMemStats stops the world
+// briefly, which is fine at sampling rate.
+func (s *Shedder) gcPausePressure() float64 {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	n := ms.NumGC - s.lastNumGC
+	if n > uint32(len(ms.PauseNs)) {
+		n = uint32(len(ms.PauseNs))
+	}
+	s.lastNumGC = ms.NumGC
+	if n == 0 {
+		return 0
+	}
+
+	var total uint64
+	for i := uint32(0); i < n; i++ {
+		total += ms.PauseNs[(ms.NumGC-1-i)%uint32(len(ms.PauseNs))]
+	}
+	avg := time.Duration(total / uint64(n))
+	return avg.Seconds() / s.cfg.GCPauseTarget.Seconds()
+}
+
+func sanitize(v float64) float64 {
+	if math.IsNaN(v) || v < 0 {
+		return 0
+	}
+	if math.IsInf(v, 1) {
+		return 10
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+package loadshed
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+)
+
+func TestShedsLowerTiersFirst(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Smoothing = 1
+	s := New(cfg, nil)
+	s.AddSignal("goroutines", func() float64 { return 0 })
+
+	pressure := 0.0
+	s.AddSignal("test", func() float64 { return pressure })
+
+	pressure = 0.5
+	s.Sample()
+	if s.ShouldShed(config.TierFree) {
+		t.Fatal("free tier shed below its threshold")
+	}
+
+	// Past the free ramp but below pro
+	pressure = cfg.Thresholds[config.TierFree] + cfg.Ramp + 0.01
+	s.Sample()
+	if !s.ShouldShed(config.TierFree) {
+		t.Fatal("free tier not shed past its ramp")
+	}
+	if s.ShouldShed(config.TierBusiness) {
+		t.Fatal("business tier shed below its threshold")
+	}
+
+	pressure = 5
+	s.Sample()
+	if s.ShouldShed(config.TierEnterprise) {
+		t.Fatal("enterprise tier must never be shed")
+	}
+	if got := s.Snapshot().Shed[string(config.TierFree)]; got != 1 {
+		t.Fatalf("free shed count = %d, want 1", got)
+	}
+}
+
+func TestMiddlewareRetryAfter(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Smoothing = 1
+	s := New(cfg, nil)
+	s.AddSignal("test", func() float64 { return 2 })
+	s.Sample()
+
+	h := s.Middleware(
+		func(*http.Request) config.Tier { return config.TierFree },
+		func(r *http.Request) bool { return r.URL.Path == "/health" },
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+	)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/api/v1/latest", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("got %d with Retry-After %q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("exempt path got %d", rec.Code)
+	}
+}
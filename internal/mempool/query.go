@@ -0,0 +1,123 @@
+package mempool
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultFeeBuckets are the lower bounds (sat/vB) of the fee histogram buckets
+var DefaultFeeBuckets = []float64{1, 2, 3, 5, 8, 10, 15, 20, 30, 50, 75, 100, 150, 200, 300, 500, 1000}
+
+// FeeBucket is one fee histogram bucket covering [MinFeeRate, MaxFeeRate)
+type FeeBucket struct {
+	MinFeeRate float64 `json:"min_fee_rate"`
+	MaxFeeRate float64 `json:"max_fee_rate,omitempty"` // 0 means unbounded
+	Count      int     `json:"count"`
+	VSize      int64   `json:"vsize"`
+}
+
+// Summary describes the fee distribution of the mempool
+type Summary struct {
+	Count         int         `json:"count"`
+	VSize         int64       `json:"vsize"`
+	MinFeeRate    float64     `json:"min_fee_rate"`
+	MedianFeeRate float64     `json:"median_fee_rate"`
+	MaxFeeRate    float64     `json:"max_fee_rate"`
+	FeeHistogram  []FeeBucket `json:"fee_histogram"`
+	Timestamp     time.Time   `json:"timestamp"`
+}
+
+// Summary returns size, virtual size and a fee histogram over live entries.
+// Entries below the first bucket bound are counted in the first bucket.
+func (m *Mempool) Summary(buckets []float64) Summary {
+	if len(buckets) == 0 {
+		buckets = DefaultFeeBuckets
+	}
+
+	summary := Summary{
+		FeeHistogram: make([]FeeBucket, len(buckets)),
+		Timestamp:    time.Now(),
+	}
+	for i, min := range buckets {
+		summary.FeeHistogram[i].MinFeeRate = min
+		if i+1 < len(buckets) {
+			summary.FeeHistogram[i].MaxFeeRate = buckets[i+1]
+		}
+	}
+
+	entries := m.AllEntries()
+	if len(entries) == 0 {
+		return summary
+	}
+
+	rates := make([]float64, 0, len(entries))
+	summary.MinFeeRate = math.MaxFloat64
+	for _, e := range entries {
+		summary.Count++
+		summary.VSize += int64(e.Size)
+		rates = append(rates, e.FeeRate)
+		if e.FeeRate < summary.MinFeeRate {
+			summary.MinFeeRate = e.FeeRate
+		}
+		if e.FeeRate > summary.MaxFeeRate {
+			summary.MaxFeeRate = e.FeeRate
+		}
+
+		// Last bucket whose lower bound is <= the fee rate
+		idx := sort.SearchFloat64s(buckets, e.FeeRate)
+		if idx == len(buckets) || buckets[idx] != e.FeeRate {
+			idx--
+		}
+		if idx < 0 {
+			idx = 0
+		}
+		summary.FeeHistogram[idx].Count++
+		summary.FeeHistogram[idx].VSize += int64(e.Size)
+	}
+
+	sort.Float64s(rates)
+	summary.MedianFeeRate = rates[len(rates)/2]
+	return summary
+}
+
+// PageOptions filters and pages mempool entries
+type PageOptions struct {
+	Offset     int
+	Limit      int
+	MinFeeRate float64
+}
+
+// Page returns live entries ordered by fee rate (highest first) along with
+// the total number of entries matching the filter.
+func (m *Mempool) Page(opts PageOptions) ([]TransactionEntry, int) {
+	entries := m.AllEntries()
+
+	filtered := make([]TransactionEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.FeeRate >= opts.MinFeeRate {
+			filtered = append(filtered, *e)
+		}
+	}
+
+	// Stable order: fee rate desc, then txid so pages do not shuffle between calls
+	sort.Slice(filtered, func(i, j int) bool {
+		if filtered[i].FeeRate != filtered[j].FeeRate {
+			return filtered[i].FeeRate > filtered[j].FeeRate
+		}
+		return filtered[i].TxID < filtered[j].TxID
+	})
+
+	total := len(filtered)
+	if opts.Offset < 0 {
+		opts.Offset = 0
+	}
+	if opts.Offset >= total {
+		return []TransactionEntry{}, total
+	}
+	end := total
+	if opts.Limit > 0 && opts.Offset+opts.Limit < total {
+		end = opts.Offset + opts.Limit
+	}
+	return filtered[opts.Offset:end], total
+}
@@ -106,4 +106,109 @@ var (
 		},
 		[]string{"network"},
 	)
+
+	// P2PHandshakeKeyUsage tracks which peer key and handshake version each
+	// completed Sprint handshake used, to see when a retiring key is unused
+	P2PHandshakeKeyUsage = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_handshake_key_usage_total",
+			Help: "Completed Sprint peer handshakes by key ID, handshake version and role",
+		},
+		[]string{"key_id", "version", "role"},
+	)
+
+	// P2PAuthKeys tracks peer authentication keys by rotation state
+	P2PAuthKeys = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "p2p_auth_keys",
+			Help: "Peer authentication keys by state (primary, active, retiring)",
+		},
+		[]string{"state"},
+	)
+
+	// P2PTransportConnections tracks outbound peer connections by transport
+	// (v1 plaintext or BIP324 v2) and outcome
+	P2PTransportConnections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_transport_connections_total",
+			Help: "Outbound peer connections by transport and result (success, fallback, failure)",
+		},
+		[]string{"transport", "result"},
+	)
+
+	// P2PNetworkConnections tracks outbound peer dials by network route
+	// (ipv4, ipv6, tor, i2p) and outcome
+	P2PNetworkConnections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_network_connections_total",
+			Help: "Outbound peer dials by network (ipv4, ipv6, tor, i2p) and result",
+		},
+		[]string{"network", "result"},
+	)
+
+	// P2PPeersSkipped tracks peers and resolved peer addresses the peer
+	// policy kept from being dialled
+	P2PPeersSkipped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_peers_skipped_total",
+			Help: "Peer dials skipped by the peer policy by reason (denylist, not_allowlisted)",
+		},
+		[]string{"reason"},
+	)
+
+	// P2PBlockDownloads tracks outcomes of block requests raced across peers
+	P2PBlockDownloads = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_block_downloads_total",
+			Help: "Block download responses by result (won, late, invalid, timeout, retry)",
+		},
+		[]string{"result"},
+	)
+
+	// P2PBlockDownloadSeconds compares how fast the winning and runner-up
+	// peers delivered a raced block
+	P2PBlockDownloadSeconds = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "p2p_block_download_seconds",
+			Help:    "Time from getdata to block arrival by role (winner, runner_up)",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		},
+		[]string{"role"},
+	)
+
+	// P2PHeadersValidated tracks announced headers by validation result
+	P2PHeadersValidated = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_headers_validated_total",
+			Help: "Peer headers by validation result (valid, orphan, discontinuous, bad_pow, bad_target, bad_timestamp, invalid)",
+		},
+		[]string{"result"},
+	)
+
+	// P2PInvItems tracks announced transactions by how they were handled
+	P2PInvItems = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_inv_tx_items_total",
+			Help: "Announced transactions by outcome (requested, deferred, dropped, expired)",
+		},
+		[]string{"result"},
+	)
+
+	// P2PPeerPings tracks RTT probes of connected peers by result
+	P2PPeerPings = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "p2p_peer_pings_total",
+			Help: "Peer RTT probes by result (answered, unanswered)",
+		},
+		[]string{"result"},
+	)
+
+	// P2PPeerRTTSeconds is the distribution of measured peer round trips
+	P2PPeerRTTSeconds = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "p2p_peer_rtt_seconds",
+			Help:    "Ping round-trip time to connected peers",
+			Buckets: []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2, 5},
+		},
+	)
 )
@@ -4,8 +4,8 @@ package netkit
 
 import (
 	"context"
+	"errors"
 	"net"
-	"sync"
 	"syscall"
 	"time"
 
@@ -23,6 +23,18 @@ type ConnectionConfig struct {
 	NoDelay        bool
 	HappyEyeballs  bool
 	MaxConcurrency int
+
+	// HappyEyeballsDelay staggers connection attempts (RFC 8305)
+	HappyEyeballsDelay time.Duration
+
+	// DisableIPv4 and DisableIPv6 drop resolved addresses of that family,
+	// for hosts without a route or operators who must not use one
+	DisableIPv4 bool
+	DisableIPv6 bool
+
+	// AllowAddr, when set, drops the resolved addresses of host it
+	// rejects, so none of them is ever dialled
+	AllowAddr func(host string, ip net.IP) bool
 }
 
 // DefaultConfig returns a production-ready connection configuration
@@ -37,6 +49,8 @@ func DefaultConfig() *ConnectionConfig {
 		NoDelay:        true,
 		HappyEyeballs:  true,
 		MaxConcurrency: 4,
+
+		HappyEyeballsDelay: 250 * time.Millisecond,
 	}
 }
 
@@ -72,96 +86,162 @@ func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.
 		return (&net.Dialer{Timeout: d.config.Timeout}).DialContext(ctx, network, address)
 	}
 
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := d.resolve(ctx, network, host, port)
+	if err != nil {
+		return nil, err
+	}
+
 	if !d.config.HappyEyeballs {
 		// Use tuned dial without Happy-Eyeballs
-		return d.dialTuned(ctx, network, address)
+		return d.dialTunedAddr(ctx, addrs[0])
 	}
 
-	// Happy-Eyeballs: resolve and try multiple addresses in parallel
-	host, port, err := net.SplitHostPort(address)
+	conn, err := d.race(ctx, network, addrs)
 	if err != nil {
 		return nil, err
 	}
+	d.logger.Debug("Happy-Eyeballs connection established",
+		zap.String("address", address),
+		zap.String("remote", conn.RemoteAddr().String()))
+	return conn, nil
+}
 
-	// Resolve all addresses
-	addrs, err := net.LookupHost(host)
+// resolve returns the addresses of host allowed by network and the family
+// toggles, interleaved IPv6 first as RFC 8305 recommends
+func (d *Dialer) resolve(ctx context.Context, network, host, port string) ([]*net.TCPAddr, error) {
+	portNum, err := net.LookupPort("tcp", port)
 	if err != nil {
 		return nil, err
 	}
-
-	if len(addrs) == 0 {
-		return nil, &net.DNSError{Err: "no such host", Name: host}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert to SocketAddr
-	var sockAddrs []net.TCPAddr
-	for _, addr := range addrs {
-		if tcpAddr, err := net.ResolveTCPAddr(network, net.JoinHostPort(addr, port)); err == nil {
-			sockAddrs = append(sockAddrs, *tcpAddr)
+	allowV4 := !d.config.DisableIPv4 && network != "tcp6"
+	allowV6 := !d.config.DisableIPv6 && network != "tcp4"
+	var v4, v6 []*net.TCPAddr
+	rejected := 0
+	for _, ip := range ips {
+		if d.config.AllowAddr != nil && !d.config.AllowAddr(host, ip.IP) {
+			rejected++
+			continue
 		}
+		addr := &net.TCPAddr{IP: ip.IP, Port: portNum, Zone: ip.Zone}
+		if ip.IP.To4() != nil {
+			if allowV4 {
+				v4 = append(v4, addr)
+			}
+		} else if allowV6 {
+			v6 = append(v6, addr)
+		}
+	}
+	if len(v4)+len(v6) == 0 {
+		if len(ips) == 0 {
+			return nil, &net.DNSError{Err: "no such host", Name: host}
+		}
+		if rejected == len(ips) {
+			return nil, &net.AddrError{Err: "no permitted addresses", Addr: host}
+		}
+		return nil, &net.AddrError{Err: "no addresses in an enabled IP family", Addr: host}
 	}
 
-	if len(sockAddrs) == 0 {
-		return nil, &net.DNSError{Err: "no valid addresses", Name: host}
+	addrs := make([]*net.TCPAddr, 0, len(v4)+len(v6))
+	for i := 0; i < len(v4) || i < len(v6); i++ {
+		if i < len(v6) {
+			addrs = append(addrs, v6[i])
+		}
+		if i < len(v4) {
+			addrs = append(addrs, v4[i])
+		}
 	}
+	return addrs, nil
+}
 
-	// Try connections in parallel (up to MaxConcurrency)
-	resultChan := make(chan net.Conn, 1)
-	errorChan := make(chan error, len(sockAddrs))
-	var wg sync.WaitGroup
+// race dials addrs in order, starting the next attempt when the previous
+// one fails or has not connected within HappyEyeballsDelay, and returns
+// the first connection established
+func (d *Dialer) race(ctx context.Context, network string, addrs []*net.TCPAddr) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
 
+	delay := d.config.HappyEyeballsDelay
+	if delay <= 0 {
+		delay = 250 * time.Millisecond
+	}
 	maxConcurrent := d.config.MaxConcurrency
-	if len(sockAddrs) < maxConcurrent {
-		maxConcurrent = len(sockAddrs)
-	}
-
-	for i := 0; i < maxConcurrent; i++ {
-		wg.Add(1)
-		go func(addr net.TCPAddr) {
-			defer wg.Done()
-			conn, err := d.dialTunedAddr(ctx, &addr)
-			if err != nil {
-				errorChan <- err
-				return
-			}
-			select {
-			case resultChan <- conn:
-			default:
-				conn.Close() // Another connection succeeded first
-			}
-		}(sockAddrs[i])
+	if maxConcurrent <= 0 {
+		maxConcurrent = len(addrs)
 	}
 
-	// Wait for first success or all failures
-	go func() {
-		wg.Wait()
-		close(errorChan)
-	}()
-
-	select {
-	case conn := <-resultChan:
-		d.logger.Debug("Happy-Eyeballs connection established", zap.String("address", address))
-		return conn, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-time.After(d.config.Timeout):
-		return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: syscall.ETIMEDOUT}
+	type attempt struct {
+		conn net.Conn
+		err  error
 	}
-}
-
-// dialTuned establishes a connection with tuned TCP options
-func (d *Dialer) dialTuned(ctx context.Context, network, address string) (net.Conn, error) {
-	host, port, err := net.SplitHostPort(address)
-	if err != nil {
-		return nil, err
+	results := make(chan attempt, len(addrs))
+	next, pending := 0, 0
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := d.dialTunedAddr(ctx, addr)
+			results <- attempt{conn, err}
+		}()
 	}
-
-	addr, err := net.ResolveTCPAddr(network, net.JoinHostPort(host, port))
-	if err != nil {
-		return nil, err
+	// Attempts still in flight when race returns close their connections
+	abandon := func() {
+		go func(n int) {
+			for i := 0; i < n; i++ {
+				if a := <-results; a.conn != nil {
+					a.conn.Close()
+				}
+			}
+		}(pending)
 	}
 
-	return d.dialTunedAddr(ctx, addr)
+	start()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case a := <-results:
+			pending--
+			if a.err == nil {
+				abandon()
+				return a.conn, nil
+			}
+			lastErr = a.err
+			if next < len(addrs) {
+				start()
+				timer.Reset(delay)
+			} else if pending == 0 {
+				return nil, lastErr
+			}
+		case <-timer.C:
+			if next < len(addrs) && pending < maxConcurrent {
+				start()
+			}
+			if next < len(addrs) {
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			abandon()
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, &net.OpError{Op: "dial", Net: network, Source: nil, Addr: nil, Err: syscall.ETIMEDOUT}
+			}
+			return nil, ctx.Err()
+		}
+	}
 }
 
 // dialTunedAddr establishes a connection to a specific address with tuned options
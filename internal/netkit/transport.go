@@ -0,0 +1,243 @@
+package netkit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+	"golang.org/x/net/proxy"
+)
+
+// Transport names the route an outbound peer connection takes
+type Transport string
+
+const (
+	TransportIPv4 Transport = "ipv4"
+	TransportIPv6 Transport = "ipv6"
+	TransportTor  Transport = "tor"
+	TransportI2P  Transport = "i2p"
+)
+
+// ErrTransportDisabled is returned for peers only reachable over a
+// transport the operator has not enabled
+var ErrTransportDisabled = errors.New("transport disabled")
+
+// TransportConfig selects the transports outbound peer connections may use
+type TransportConfig struct {
+	IPv4 bool
+	IPv6 bool
+
+	// TorProxy is the SOCKS5 address of a Tor client (e.g. 127.0.0.1:9050).
+	// It is required for .onion peers.
+	TorProxy string
+	// TorOnly routes clearnet peers through TorProxy as well, so the
+	// node's own IP address is never exposed to the P2P network
+	TorOnly bool
+
+	// I2PProxy is the SOCKS5 address of an I2P router (e.g. i2pd on
+	// 127.0.0.1:4447). It is required for .i2p peers.
+	I2PProxy string
+
+	// AllowAddr filters the resolved addresses of clearnet hostnames (see
+	// ConnectionConfig). Proxied hosts are resolved by the proxy and are
+	// not checked.
+	AllowAddr func(host string, ip net.IP) bool
+
+	Timeout time.Duration
+}
+
+// TransportFromConfig reads the P2P_* transport settings
+func TransportFromConfig(cfg config.Config) TransportConfig {
+	return TransportConfig{
+		IPv4:     cfg.P2PIPv4,
+		IPv6:     cfg.P2PIPv6,
+		TorProxy: cfg.P2PTorProxy,
+		TorOnly:  cfg.P2PTorOnly,
+		I2PProxy: cfg.P2PI2PProxy,
+		Timeout:  cfg.P2PDialTimeout,
+	}
+}
+
+// Validate rejects settings that would leave no usable transport or would
+// silently fall back to clearnet
+func (tc TransportConfig) Validate() error {
+	if tc.TorOnly && tc.TorProxy == "" {
+		return errors.New("tor-only mode requires a Tor SOCKS5 proxy")
+	}
+	for name, addr := range map[string]string{"Tor": tc.TorProxy, "I2P": tc.I2PProxy} {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid %s proxy address %q: %w", name, addr, err)
+		}
+	}
+	if !tc.IPv4 && !tc.IPv6 && tc.TorProxy == "" && tc.I2PProxy == "" {
+		return errors.New("no P2P transport enabled")
+	}
+	return nil
+}
+
+// TransportForHost classifies a peer host. Hostnames that are neither
+// overlay addresses nor IP literals return "", as their family is only
+// known once resolved.
+func TransportForHost(host string) Transport {
+	lower := strings.ToLower(host)
+	switch {
+	case strings.HasSuffix(lower, ".onion"):
+		return TransportTor
+	case strings.HasSuffix(lower, ".i2p"):
+		return TransportI2P
+	}
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i] // IPv6 zone
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return TransportIPv4
+		}
+		return TransportIPv6
+	}
+	return ""
+}
+
+// TransportDialer dials peers over the transport their address requires
+type TransportDialer struct {
+	cfg    TransportConfig
+	direct *Dialer
+	logger *zap.Logger
+}
+
+// NewTransportDialer creates a dialer for the given transports
+func NewTransportDialer(cfg TransportConfig, logger *zap.Logger) (*TransportDialer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+
+	direct := DefaultConfig()
+	direct.Timeout = cfg.Timeout
+	direct.DisableIPv4 = !cfg.IPv4
+	direct.DisableIPv6 = !cfg.IPv6
+	direct.AllowAddr = cfg.AllowAddr
+
+	return &TransportDialer{
+		cfg:    cfg,
+		direct: NewDialer(direct, logger),
+		logger: logger,
+	}, nil
+}
+
+// Dial connects to address and reports the transport used
+func (td *TransportDialer) Dial(ctx context.Context, address string) (net.Conn, Transport, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, "", err
+	}
+
+	transport := TransportForHost(host)
+	switch {
+	case transport == TransportTor || (td.cfg.TorOnly && transport != TransportI2P):
+		if td.cfg.TorProxy == "" {
+			return nil, TransportTor, fmt.Errorf("%s: tor: %w", address, ErrTransportDisabled)
+		}
+		// Fresh credentials per connection put each peer on its own
+		// circuit (Tor's IsolateSOCKSAuth), so peers cannot be linked
+		// through a shared exit
+		conn, err := td.dialSOCKS(ctx, td.cfg.TorProxy, address, true)
+		return conn, TransportTor, err
+
+	case transport == TransportI2P:
+		if td.cfg.I2PProxy == "" {
+			return nil, TransportI2P, fmt.Errorf("%s: i2p: %w", address, ErrTransportDisabled)
+		}
+		conn, err := td.dialSOCKS(ctx, td.cfg.I2PProxy, address, false)
+		return conn, TransportI2P, err
+
+	case transport == TransportIPv4 && !td.cfg.IPv4, transport == TransportIPv6 && !td.cfg.IPv6:
+		return nil, transport, fmt.Errorf("%s: %s: %w", address, transport, ErrTransportDisabled)
+	}
+
+	if !td.cfg.IPv4 && !td.cfg.IPv6 {
+		return nil, transport, fmt.Errorf("%s: clearnet: %w", address, ErrTransportDisabled)
+	}
+	conn, err := td.direct.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, transport, err
+	}
+	if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok && tcp.IP.To4() == nil {
+		return conn, TransportIPv6, nil
+	}
+	return conn, TransportIPv4, nil
+}
+
+// dialSOCKS connects through a SOCKS5 proxy. Hostnames are passed to the
+// proxy unresolved, so lookups never leave the overlay network.
+func (td *TransportDialer) dialSOCKS(ctx context.Context, proxyAddr, address string, isolate bool) (net.Conn, error) {
+	var auth *proxy.Auth
+	if isolate {
+		auth = &proxy.Auth{User: randomToken(), Password: randomToken()}
+	}
+	socks, err := proxy.SOCKS5("tcp", proxyAddr, auth, &net.Dialer{Timeout: td.cfg.Timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, td.cfg.Timeout)
+	defer cancel()
+	conn, err := socks.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("%s via proxy %s: %w", address, proxyAddr, err)
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetNoDelay(true)
+	}
+	return conn, nil
+}
+
+func randomToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// PeerNetAddress implements btcd's peer.HostToNetAddrFunc without DNS
+// lookups. Onion v3 hosts map to their BIP155 address; I2P hosts and
+// unresolved hostnames are sent as the unroutable 0.0.0.0, as bitcoind
+// does for peers reached through a proxy.
+func PeerNetAddress(host string, port uint16, services wire.ServiceFlag) (*wire.NetAddressV2, error) {
+	now := time.Now()
+	lower := strings.ToLower(host)
+	if len(lower) == wire.TorV3EncodedSize && strings.HasSuffix(lower, ".onion") {
+		data, err := base32.StdEncoding.DecodeString(strings.ToUpper(lower[:len(lower)-len(".onion")]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid onion address %q: %w", host, err)
+		}
+		return wire.NetAddressV2FromBytes(now, services, data[:wire.TorV3Size], port), nil
+	}
+
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		host = host[:i]
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		return wire.NetAddressV2FromBytes(now, services, ip, port), nil
+	}
+	return wire.NetAddressV2FromBytes(now, services, net.IPv4zero.To4(), port), nil
+}
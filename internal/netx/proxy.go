@@ -0,0 +1,382 @@
+package netx
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Networks that only allow egress through a proxy route relay connections
+// through Proxies: a default proxy and per-provider rules matched on the
+// endpoint host. SOCKS5 and HTTP CONNECT proxies are supported, with
+// credentials taken from the proxy URL.
+//
+// A connection that fails at the proxy (unreachable, credentials refused,
+// proxy fault) returns a *ProxyError, while a proxy reporting the
+// destination unreachable returns a plain error, so callers can keep
+// proxy outages out of endpoint health scores.
+
+// ProxyError reports a connection that failed at its proxy before the
+// destination was tried
+type ProxyError struct {
+	Proxy string // scheme://host:port, without credentials
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return "proxy " + e.Proxy + ": " + e.Err.Error()
+}
+
+func (e *ProxyError) Unwrap() error { return e.Err }
+
+// Direct in a proxy rule bypasses the default proxy
+const Direct = "direct"
+
+type proxyRule struct {
+	match string   // Host fragment
+	proxy *url.URL // nil for a direct connection
+}
+
+// Proxies routes outbound connections by destination host
+type Proxies struct {
+	def   *url.URL
+	rules []proxyRule
+}
+
+// ParseProxies parses a default proxy URL (empty for none) and rules of
+// the form "host-fragment=proxy-url" or "host-fragment=direct"
+func ParseProxies(def string, rules []string) (*Proxies, error) {
+	p := &Proxies{}
+	if def != "" {
+		u, err := parseProxyURL(def)
+		if err != nil {
+			return nil, err
+		}
+		p.def = u
+	}
+	for _, rule := range rules {
+		match, target, ok := strings.Cut(rule, "=")
+		match = strings.ToLower(strings.TrimSpace(match))
+		target = strings.TrimSpace(target)
+		if !ok || match == "" || target == "" {
+			return nil, fmt.Errorf("proxy rule %q: want host-fragment=proxy-url", rule)
+		}
+		r := proxyRule{match: match}
+		if target != Direct {
+			u, err := parseProxyURL(target)
+			if err != nil {
+				return nil, fmt.Errorf("proxy rule for %q: %w", match, err)
+			}
+			r.proxy = u
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("proxy %s: scheme must be socks5, socks5h or http", proxyName(u))
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy %s: want host:port", proxyName(u))
+	}
+	return u, nil
+}
+
+// proxyName identifies u in errors, logs and metrics without its
+// credentials
+func proxyName(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// Configured reports whether any proxy or rule is set
+func (p *Proxies) Configured() bool {
+	return p != nil && (p.def != nil || len(p.rules) > 0)
+}
+
+// For returns the proxy for host, nil for a direct connection. The rule
+// with the longest matching fragment wins over the default.
+func (p *Proxies) For(host string) *url.URL {
+	if p == nil {
+		return nil
+	}
+	host = strings.ToLower(host)
+	best, proxy := -1, p.def
+	for _, r := range p.rules {
+		if len(r.match) > best && strings.Contains(host, r.match) {
+			best, proxy = len(r.match), r.proxy
+		}
+	}
+	return proxy
+}
+
+// DialContext connects to address through the proxy routed for its host.
+// It matches websocket.Dialer.NetDialContext and http.Transport.DialContext.
+func (p *Proxies) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	u := p.For(host)
+	if u == nil {
+		return DialContextWithResolver(ctx, network, address)
+	}
+	return DialProxy(ctx, u, address)
+}
+
+// DialProxy connects to address through the proxy u
+func DialProxy(ctx context.Context, u *url.URL, address string) (net.Conn, error) {
+	conn, err := DialContextWithResolver(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, &ProxyError{Proxy: proxyName(u), Err: err}
+	}
+
+	// Bound the handshake by ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	tunnel := conn
+	switch u.Scheme {
+	case "http":
+		tunnel, err = httpConnect(conn, u, address)
+	default:
+		err = socks5Connect(ctx, conn, u, address)
+	}
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// ===== SOCKS5 (RFC 1928, RFC 1929) =====
+
+const (
+	socksVersion      = 5
+	socksNoAuth       = 0x00
+	socksUserPass     = 0x02
+	socksNoAcceptable = 0xff
+	socksConnect      = 1
+	socksIPv4         = 1
+	socksDomain       = 3
+	socksIPv6         = 4
+)
+
+// socksReplies names SOCKS5 reply codes
+var socksReplies = map[byte]string{
+	1: "general failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// socksDestinationFailure reports whether a SOCKS5 reply code blames the
+// destination rather than the proxy
+func socksDestinationFailure(rep byte) bool {
+	return rep >= 3 && rep <= 6
+}
+
+func socks5Connect(ctx context.Context, conn net.Conn, u *url.URL, address string) error {
+	proxyErr := func(format string, args ...interface{}) error {
+		return &ProxyError{Proxy: proxyName(u), Err: fmt.Errorf(format, args...)}
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port in %q", address)
+	}
+	// socks5 resolves locally, socks5h leaves it to the proxy
+	if u.Scheme == "socks5" && net.ParseIP(host) == nil {
+		ips, err := CustomResolver().LookupIPAddr(ctx, host)
+		if err != nil {
+			return err
+		}
+		if len(ips) == 0 {
+			return fmt.Errorf("no addresses for %s", host)
+		}
+		host = ips[0].IP.String()
+	}
+
+	user := u.User.Username()
+	pass, _ := u.User.Password()
+	methods := []byte{socksNoAuth}
+	if user != "" {
+		methods = append(methods, socksUserPass)
+	}
+	if _, err := conn.Write(append([]byte{socksVersion, byte(len(methods))}, methods...)); err != nil {
+		return proxyErr("greeting: %w", err)
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return proxyErr("greeting: %w", err)
+	}
+	if reply[0] != socksVersion {
+		return proxyErr("not a SOCKS5 proxy")
+	}
+	switch reply[1] {
+	case socksNoAuth:
+	case socksUserPass:
+		if user == "" || len(user) > 255 || len(pass) > 255 {
+			return proxyErr("proxy requires a username and password")
+		}
+		req := []byte{1, byte(len(user))}
+		req = append(req, user...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err := conn.Write(req); err != nil {
+			return proxyErr("authentication: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply[:]); err != nil {
+			return proxyErr("authentication: %w", err)
+		}
+		if reply[1] != 0 {
+			return proxyErr("authentication failed")
+		}
+	case socksNoAcceptable:
+		return proxyErr("no acceptable authentication method")
+	default:
+		return proxyErr("unsupported authentication method %d", reply[1])
+	}
+
+	req := []byte{socksVersion, socksConnect, 0}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(append(req, socksIPv4), ip4...)
+		} else {
+			req = append(append(req, socksIPv6), ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("host name too long: %s", host)
+		}
+		req = append(append(req, socksDomain, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return proxyErr("connect: %w", err)
+	}
+
+	var head [4]byte
+	if _, err := io.ReadFull(conn, head[:]); err != nil {
+		return proxyErr("connect: %w", err)
+	}
+	if rep := head[1]; rep != 0 {
+		msg := socksReplies[rep]
+		if msg == "" {
+			msg = fmt.Sprintf("reply %d", rep)
+		}
+		if socksDestinationFailure(rep) {
+			return fmt.Errorf("socks5 connect %s: %s", address, msg)
+		}
+		return proxyErr("connect %s: %s", address, msg)
+	}
+
+	// Skip the bound address
+	var skip int
+	switch head[3] {
+	case socksIPv4:
+		skip = net.IPv4len
+	case socksIPv6:
+		skip = net.IPv6len
+	case socksDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return proxyErr("connect: %w", err)
+		}
+		skip = int(n[0])
+	default:
+		return proxyErr("connect: bad address type %d", head[3])
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(skip+2)); err != nil {
+		return proxyErr("connect: %w", err)
+	}
+	return nil
+}
+
+// ===== HTTP CONNECT =====
+
+// httpConnect opens a tunnel to address through an HTTP proxy. A 502, 503
+// or 504 is the proxy failing to reach the destination; any other refusal
+// is the proxy's.
+func httpConnect(conn net.Conn, u *url.URL, address string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := u.User.Username(); user != "" {
+		pass, _ := u.User.Password()
+		req.Header.Set("Proxy-Authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, &ProxyError{Proxy: proxyName(u), Err: fmt.Errorf("CONNECT: %w", err)}
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, &ProxyError{Proxy: proxyName(u), Err: fmt.Errorf("CONNECT: %w", err)}
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, fmt.Errorf("CONNECT %s via proxy: %s", address, resp.Status)
+	case http.StatusProxyAuthRequired:
+		return nil, &ProxyError{Proxy: proxyName(u), Err: errors.New("authentication failed")}
+	default:
+		return nil, &ProxyError{Proxy: proxyName(u), Err: fmt.Errorf("CONNECT %s: %s", address, resp.Status)}
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn returns bytes read past the CONNECT response first
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
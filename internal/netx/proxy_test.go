@@ -0,0 +1,172 @@
+package netx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestProxiesFor(t *testing.T) {
+	p, err := ParseProxies("socks5://proxy:1080", []string{
+		"helius=http://u:p@egress:3128",
+		"mainnet.helius=direct",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, want := range map[string]string{
+		"rpc.ankr.com":              "socks5://proxy:1080",
+		"atlas.helius-rpc.com":      "http://egress:3128",
+		"mainnet.helius-rpc.com":    "",
+		"MAINNET.HELIUS-RPC.COM":    "",
+		"eth-mainnet.example.local": "socks5://proxy:1080",
+	} {
+		got := ""
+		if u := p.For(host); u != nil {
+			got = proxyName(u)
+		}
+		if got != want {
+			t.Errorf("For(%q) = %q, want %q", host, got, want)
+		}
+	}
+
+	for _, bad := range [][]string{{"helius"}, {"helius=ftp://x:21"}, {"=direct"}, {"ankr=socks5://nohost"}} {
+		if _, err := ParseProxies("", bad); err == nil {
+			t.Errorf("ParseProxies(%q) accepted", bad)
+		}
+	}
+}
+
+// fakeSOCKS5 serves one SOCKS5 handshake requiring user:pass, answering
+// CONNECT with rep
+func fakeSOCKS5(t *testing.T, rep byte) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 512)
+		n, _ := conn.Read(buf) // greeting
+		if n < 3 {
+			return
+		}
+		conn.Write([]byte{5, socksUserPass})
+		field := func() string {
+			var n [1]byte
+			io.ReadFull(conn, n[:])
+			b := make([]byte, n[0])
+			io.ReadFull(conn, b)
+			return string(b)
+		}
+		io.ReadFull(conn, buf[:1]) // version
+		user, pass := field(), field()
+		if user != "user" || pass != "pass" {
+			conn.Write([]byte{1, 1})
+			return
+		}
+		conn.Write([]byte{1, 0})
+		conn.Read(buf) // CONNECT
+		conn.Write([]byte{5, rep, 0, socksIPv4, 0, 0, 0, 0, 0, 0})
+		if rep == 0 {
+			conn.Write([]byte("hello"))
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// fakeHTTPProxy answers one CONNECT with status
+func fakeHTTPProxy(t *testing.T, status int) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		if req.Header.Get("Proxy-Authorization") == "" {
+			status = http.StatusProxyAuthRequired
+		}
+		resp := &http.Response{StatusCode: status, ProtoMajor: 1, ProtoMinor: 1}
+		resp.Write(conn)
+		if status == http.StatusOK {
+			conn.Write([]byte("hello"))
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestDialProxyAttribution(t *testing.T) {
+	// An address nothing listens on
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := ln.Addr().String()
+	ln.Close()
+
+	for _, tc := range []struct {
+		name      string
+		proxy     string
+		wantProxy bool // failure blamed on the proxy
+		wantOK    bool
+	}{
+		{"socks ok", "socks5://user:pass@" + fakeSOCKS5(t, 0), false, true},
+		{"socks bad credentials", "socks5://user:wrong@" + fakeSOCKS5(t, 0), true, false},
+		{"socks host unreachable", "socks5h://user:pass@" + fakeSOCKS5(t, 4), false, false},
+		{"socks general failure", "socks5h://user:pass@" + fakeSOCKS5(t, 1), true, false},
+		{"http ok", "http://user:pass@" + fakeHTTPProxy(t, http.StatusOK), false, true},
+		{"http no credentials", "http://" + fakeHTTPProxy(t, http.StatusOK), true, false},
+		{"http bad gateway", "http://user:pass@" + fakeHTTPProxy(t, http.StatusBadGateway), false, false},
+		{"http forbidden", "http://user:pass@" + fakeHTTPProxy(t, http.StatusForbidden), true, false},
+		{"proxy down", "socks5://" + closed, true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			conn, err := DialProxy(ctx, u, "10.0.0.1:443")
+			if tc.wantOK {
+				if err != nil {
+					t.Fatalf("DialProxy: %v", err)
+				}
+				defer conn.Close()
+				buf := make([]byte, 5)
+				if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+					t.Fatalf("tunnel read %q, %v", buf, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("DialProxy succeeded")
+			}
+			var pe *ProxyError
+			if got := errors.As(err, &pe); got != tc.wantProxy {
+				t.Fatalf("proxy error = %v, want %v: %v", got, tc.wantProxy, err)
+			}
+			if pe != nil && pe.Proxy != proxyName(u) {
+				t.Errorf("Proxy = %q", pe.Proxy)
+			}
+		})
+	}
+}
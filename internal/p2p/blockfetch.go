@@ -0,0 +1,277 @@
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/metrics"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+const (
+	// blockFetchFanout is how many peers each block is requested from
+	blockFetchFanout = 2
+	// blockFetchTimeout bounds how long a peer has to deliver a requested block
+	blockFetchTimeout = 30 * time.Second
+	// blockFetchRetries is how many times a timed-out block is requested
+	// again from the next-best peer not yet asked
+	blockFetchRetries = 1
+	// blockLatencyAlpha weights each new block fetch latency in the EWMA
+	blockLatencyAlpha = 0.3
+)
+
+// blockFetch tracks one block requested from several peers
+type blockFetch struct {
+	requested map[string]time.Time // Peer address -> getdata sent, until it answers
+	asked     map[string]bool      // Every peer requested from, across retries
+	retries   int
+	winner    string
+	wonAt     time.Time
+}
+
+// blockDownloader races block requests across the best peers. The first
+// complete block with a valid merkle root is processed; responses from the
+// other peers are dropped and only used to compare peer latency. The P2P
+// protocol has no way to withdraw a getdata, so "cancelling" the losing
+// request means forgetting it.
+type blockDownloader struct {
+	mu       sync.Mutex
+	inflight map[chainhash.Hash]*blockFetch
+}
+
+func newBlockDownloader() *blockDownloader {
+	return &blockDownloader{inflight: make(map[chainhash.Hash]*blockFetch)}
+}
+
+// fetchBlock requests blockHash from the top-scored peers unless a request
+// is already in flight
+func (c *Client) fetchBlock(blockHash chainhash.Hash) {
+	if c.stopped.Load() {
+		return
+	}
+	d := c.fetches
+
+	d.mu.Lock()
+	if _, ok := d.inflight[blockHash]; ok {
+		d.mu.Unlock()
+		return
+	}
+	targets := c.rankPeersForBlock(blockFetchFanout)
+	if len(targets) == 0 {
+		d.mu.Unlock()
+		c.logger.Warn("No suitable peer available for block fetch",
+			zap.String("hash", blockHash.String()))
+		return
+	}
+	fetch := &blockFetch{
+		requested: make(map[string]time.Time, len(targets)),
+		asked:     make(map[string]bool, len(targets)),
+	}
+	now := time.Now()
+	for _, t := range targets {
+		fetch.requested[t.address] = now
+		fetch.asked[t.address] = true
+	}
+	d.inflight[blockHash] = fetch
+	d.mu.Unlock()
+
+	c.sendBlockRequest(blockHash, targets)
+	c.logger.Debug("Requested full block data",
+		zap.String("hash", blockHash.String()),
+		zap.Int("peers", len(targets)))
+
+	time.AfterFunc(blockFetchTimeout, func() { c.expireBlockFetch(blockHash, fetch) })
+}
+
+// sendBlockRequest queues a getdata for blockHash to each target
+func (c *Client) sendBlockRequest(blockHash chainhash.Hash, targets []rankedPeer) {
+	getData := wire.NewMsgGetData()
+	getData.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &blockHash))
+	for _, t := range targets {
+		t.peer.QueueMessage(getData, nil)
+	}
+}
+
+// acceptFetchedBlock reports whether a block received from address should
+// be processed. Unsolicited blocks are always accepted; requested blocks
+// only for the first valid response.
+func (c *Client) acceptFetchedBlock(address string, block *wire.MsgBlock) bool {
+	blockHash := block.BlockHash()
+	d := c.fetches
+
+	d.mu.Lock()
+	fetch, ok := d.inflight[blockHash]
+	if !ok {
+		d.mu.Unlock()
+		return true
+	}
+	sentAt, requested := fetch.requested[address]
+	if !requested {
+		// A block we asked others for, arriving unsolicited from this peer
+		accept := fetch.winner == "" && validMerkleRoot(block)
+		if accept {
+			fetch.winner, fetch.wonAt = address, time.Now()
+		}
+		d.mu.Unlock()
+		return accept
+	}
+	delete(fetch.requested, address)
+	latency := time.Since(sentAt)
+	valid := validMerkleRoot(block)
+
+	var accept bool
+	switch {
+	case !valid:
+		metrics.P2PBlockDownloads.WithLabelValues("invalid").Inc()
+		c.logger.Warn("Discarding block with invalid merkle root",
+			zap.String("hash", blockHash.String()),
+			zap.String("peer", address))
+	case fetch.winner == "":
+		fetch.winner, fetch.wonAt = address, time.Now()
+		accept = true
+		metrics.P2PBlockDownloads.WithLabelValues("won").Inc()
+		metrics.P2PBlockDownloadSeconds.WithLabelValues("winner").Observe(latency.Seconds())
+	default:
+		metrics.P2PBlockDownloads.WithLabelValues("late").Inc()
+		metrics.P2PBlockDownloadSeconds.WithLabelValues("runner_up").Observe(latency.Seconds())
+		c.logger.Debug("Dropped late block response",
+			zap.String("hash", blockHash.String()),
+			zap.String("peer", address),
+			zap.String("winner", fetch.winner),
+			zap.Duration("behind", time.Since(fetch.wonAt)))
+	}
+	if len(fetch.requested) == 0 {
+		delete(d.inflight, blockHash)
+	}
+	d.mu.Unlock()
+
+	c.updatePeerMetrics(address, latency, valid)
+	return accept
+}
+
+// expireBlockFetch penalises peers that never answered a block request.
+// If no peer delivered the block it is requested again from the best peer
+// not yet asked, up to blockFetchRetries times.
+func (c *Client) expireBlockFetch(blockHash chainhash.Hash, fetch *blockFetch) {
+	d := c.fetches
+
+	d.mu.Lock()
+	if d.inflight[blockHash] != fetch {
+		// Completed, or superseded by a later fetch of the same block
+		d.mu.Unlock()
+		return
+	}
+	pending := fetch.requested
+	won := fetch.winner != ""
+
+	var retry []rankedPeer
+	if !won && fetch.retries < blockFetchRetries && !c.stopped.Load() {
+		for _, r := range c.rankPeersForBlock(len(fetch.asked) + 1) {
+			if !fetch.asked[r.address] {
+				retry = append(retry, r)
+				break
+			}
+		}
+	}
+	if len(retry) == 0 {
+		delete(d.inflight, blockHash)
+	} else {
+		// Peers that timed out stay off the list; a late block from one is
+		// still accepted as unsolicited
+		fetch.retries++
+		fetch.requested = map[string]time.Time{retry[0].address: time.Now()}
+		fetch.asked[retry[0].address] = true
+	}
+	d.mu.Unlock()
+
+	switch {
+	case len(retry) > 0:
+		metrics.P2PBlockDownloads.WithLabelValues("retry").Inc()
+		c.logger.Info("Block download timed out, retrying on next-best peer",
+			zap.String("hash", blockHash.String()),
+			zap.Int("timed_out", len(pending)),
+			zap.String("peer", retry[0].address))
+		c.sendBlockRequest(blockHash, retry)
+		time.AfterFunc(blockFetchTimeout, func() { c.expireBlockFetch(blockHash, fetch) })
+	case !won:
+		metrics.P2PBlockDownloads.WithLabelValues("timeout").Inc()
+		c.logger.Warn("Block download timed out",
+			zap.String("hash", blockHash.String()),
+			zap.Int("peers", len(pending)))
+	}
+	for address := range pending {
+		c.updatePeerMetrics(address, blockFetchTimeout, false)
+	}
+}
+
+// rankedPeer is a connected peer with its block-fetch score
+type rankedPeer struct {
+	address string
+	peer    *peer.Peer
+	score   float64
+}
+
+// rankPeersForBlock returns up to n connected peers ordered by score,
+// skipping peers whose circuit breaker is open
+func (c *Client) rankPeersForBlock(n int) []rankedPeer {
+	c.peerMutex.RLock()
+	ranked := make([]rankedPeer, 0, len(c.peers))
+	for address, p := range c.peers {
+		if !p.Connected() {
+			continue
+		}
+		ranked = append(ranked, rankedPeer{address: address, peer: p})
+	}
+	c.peerMutex.RUnlock()
+
+	c.peerMetricsMu.RLock()
+	usable := ranked[:0]
+	for _, r := range ranked {
+		m := c.peerMetrics[r.address]
+		if m == nil {
+			r.score = peerBlockScore(r.peer, 0)
+		} else {
+			if time.Now().Before(m.circuitBreakerUntil) {
+				continue
+			}
+			r.score = peerBlockScore(r.peer, m.rtt) + m.qualityScore
+		}
+		usable = append(usable, r)
+	}
+	c.peerMetricsMu.RUnlock()
+
+	sort.Slice(usable, func(i, j int) bool { return usable[i].score > usable[j].score })
+	if len(usable) > n {
+		usable = usable[:n]
+	}
+	return usable
+}
+
+// validMerkleRoot checks that the block's transactions hash to the merkle
+// root committed in its header
+func validMerkleRoot(block *wire.MsgBlock) bool {
+	if len(block.Transactions) == 0 {
+		return false
+	}
+	level := make([]chainhash.Hash, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		level[i] = tx.TxHash()
+	}
+	var pair [2 * chainhash.HashSize]byte
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := 0; i < len(level)/2; i++ {
+			copy(pair[:chainhash.HashSize], level[2*i][:])
+			copy(pair[chainhash.HashSize:], level[2*i+1][:])
+			level[i] = chainhash.DoubleHashH(pair[:])
+		}
+		level = level[:len(level)/2]
+	}
+	return level[0] == block.Header.MerkleRoot
+}
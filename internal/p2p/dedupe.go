@@ -950,7 +950,13 @@ func (epd *EnterpriseP2PDeduper) GetStats() map[string]interface{} {
 
 	// Add peer reputation statistics
 	peerStatsMap := make(map[string]interface{})
+	distribution := map[string]int{"TRUSTED": 0, "HIGH": 0, "MEDIUM": 0, "LOW": 0}
+	blacklisted := 0
 	for peerID, peer := range epd.peerReputations {
+		distribution[peer.TrustLevel]++
+		if peer.IsBlacklisted {
+			blacklisted++
+		}
 		peerStatsMap[peerID] = map[string]interface{}{
 			"total_messages":   peer.TotalMessages,
 			"duplicate_count":  peer.DuplicateCount,
@@ -962,10 +968,75 @@ func (epd *EnterpriseP2PDeduper) GetStats() map[string]interface{} {
 		}
 	}
 	stats["peer_reputation_statistics"] = peerStatsMap
+	stats["peer_reputation_distribution"] = distribution
+	stats["blacklisted_peers"] = blacklisted
 
 	return stats
 }
 
+// SetTTLBounds pins the range adaptive TTL adjustment may move within. The
+// global and per-message-type TTLs are clamped into the new range at once.
+func (epd *EnterpriseP2PDeduper) SetTTLBounds(minTTL, maxTTL time.Duration) error {
+	if minTTL <= 0 || maxTTL < minTTL {
+		return fmt.Errorf("invalid TTL bounds: min %v, max %v", minTTL, maxTTL)
+	}
+
+	epd.mu.Lock()
+	defer epd.mu.Unlock()
+
+	epd.minTTL = minTTL
+	epd.maxTTL = maxTTL
+	epd.ttl = clampTTL(epd.ttl, minTTL, maxTTL)
+	p2pAdaptiveTTL.WithLabelValues("global", epd.tier).Set(epd.ttl.Seconds())
+	for messageType, typeStats := range epd.messageTypes {
+		if typeStats.AdaptiveTTL > 0 {
+			typeStats.AdaptiveTTL = clampTTL(typeStats.AdaptiveTTL, minTTL, maxTTL)
+			p2pAdaptiveTTL.WithLabelValues(messageType, epd.tier).Set(typeStats.AdaptiveTTL.Seconds())
+		}
+	}
+
+	if epd.logger != nil {
+		epd.logger.Info("P2P dedup TTL bounds updated",
+			zap.Duration("min_ttl", minTTL),
+			zap.Duration("max_ttl", maxTTL),
+			zap.Duration("current_ttl", epd.ttl))
+	}
+	return nil
+}
+
+func clampTTL(ttl, minTTL, maxTTL time.Duration) time.Duration {
+	if ttl < minTTL {
+		return minTTL
+	}
+	if ttl > maxTTL {
+		return maxTTL
+	}
+	return ttl
+}
+
+// ResetPeerReputation forgets a peer's reputation, lifting any blacklist.
+// The peer starts over as a new, MEDIUM trust peer on its next message.
+// It reports whether the peer was tracked.
+func (epd *EnterpriseP2PDeduper) ResetPeerReputation(peerID string) bool {
+	epd.mu.Lock()
+	defer epd.mu.Unlock()
+
+	peer, ok := epd.peerReputations[peerID]
+	if !ok {
+		return false
+	}
+	delete(epd.peerReputations, peerID)
+	p2pPeerReputation.DeleteLabelValues(peerID, epd.tier)
+
+	if epd.logger != nil {
+		epd.logger.Info("P2P peer reputation reset",
+			zap.String("peer_id", peerID),
+			zap.Float64("previous_score", peer.ReputationScore),
+			zap.Bool("was_blacklisted", peer.IsBlacklisted))
+	}
+	return true
+}
+
 // Cleanup performs intelligent cleanup of expired entries and peer reputation decay
 func (epd *EnterpriseP2PDeduper) Cleanup() {
 	epd.mu.Lock()
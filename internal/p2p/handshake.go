@@ -10,24 +10,56 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/metrics"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/securebuf"
 	"go.uber.org/zap"
 )
 
+// DefaultKeyID names the key passed to NewAuthenticator
+const DefaultKeyID = "default"
+
+// Handshake versions. V1 signs with the single shared secret and carries no
+// key ID; V2 names the signing key and binds the version and key ID into
+// the signature so peers can hold several keys during a rotation.
+const (
+	HandshakeV1 = 1
+	HandshakeV2 = 2
+)
+
+// minPeerKeyLen is the shortest secret accepted for a peer key
+const minPeerKeyLen = 32
+
 // HandshakeMessage is exchanged during peer connection
 type HandshakeMessage struct {
+	Version   int    `json:"v,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
 	Nonce     string `json:"nonce"`
 	Timestamp int64  `json:"ts"`
 	Signature string `json:"sig"`
 }
 
-// Authenticator handles secure peer handshakes with HMAC
+// Authenticator handles secure peer handshakes with HMAC.
+//
+// It holds a ring of keys so the shared secret can be rotated without
+// restarting the cluster at once:
+//
+//  1. AddKey the new key on every node; it is accepted but not yet used.
+//  2. SetPrimary the new key on every node; each node now signs with it
+//     while still accepting the old one.
+//  3. RetireKey the old key with a grace period; once it lapses the old
+//     key is rejected and wiped.
 type Authenticator struct {
-	secret      *securebuf.Buffer
+	keysMu  sync.RWMutex
+	keys    map[string]*peerKey
+	primary string
+	version int // Handshake version this node sends
+
 	logger      *zap.Logger
 	seen        sync.Map // key-> seenNonce
 	stopJanitor chan struct{}
@@ -38,36 +70,215 @@ type Authenticator struct {
 	handshakesFailure int64
 }
 
+type peerKey struct {
+	secret   *securebuf.Buffer
+	addedAt  time.Time
+	retireAt time.Time // Zero while the key is active
+}
+
+// usable reports whether the key may still verify handshakes
+func (k *peerKey) usable(now time.Time) bool {
+	return k.retireAt.IsZero() || now.Before(k.retireAt)
+}
+
+// KeyInfo describes a peer key without its secret
+type KeyInfo struct {
+	ID       string    `json:"id"`
+	Primary  bool      `json:"primary"`
+	AddedAt  time.Time `json:"added_at"`
+	RetireAt time.Time `json:"retire_at,omitempty"`
+}
+
 type seenNonce struct {
 	ts int64
 }
 
-// NewAuthenticator with a shared secret inside SecureBuffer
+// NewAuthenticator with a shared secret inside SecureBuffer, registered as
+// the primary key DefaultKeyID
 func NewAuthenticator(secret []byte, logger *zap.Logger) (*Authenticator, error) {
-	buf, err := securebuf.New(len(secret))
-	if err != nil {
-		return nil, err
+	a := &Authenticator{
+		keys:        make(map[string]*peerKey),
+		version:     HandshakeV2,
+		logger:      logger,
+		stopJanitor: make(chan struct{}),
 	}
-	if err := buf.Write(secret); err != nil {
-		buf.Free()
+	if err := a.addKey(DefaultKeyID, secret); err != nil {
 		return nil, err
 	}
-	a := &Authenticator{secret: buf, logger: logger, stopJanitor: make(chan struct{})}
+	a.primary = DefaultKeyID
+	a.publishKeyMetrics()
 	a.startJanitor()
 	return a, nil
 }
 
 // Close cleans up the authenticator
 func (a *Authenticator) Close() {
-	if a.secret != nil {
-		a.secret.Free()
-		a.secret = nil
+	a.keysMu.Lock()
+	for id, k := range a.keys {
+		k.secret.Free()
+		delete(a.keys, id)
 	}
+	a.keysMu.Unlock()
 	if a.janitorOnce.CompareAndSwap(false, true) {
 		close(a.stopJanitor)
 	}
 }
 
+// ===== KEY RING =====
+
+// AddKey registers a key that peers may sign with. It does not change the
+// key this node signs with; see SetPrimary.
+func (a *Authenticator) AddKey(id string, secret []byte) error {
+	if id == "" {
+		return errors.New("peer key ID must not be empty")
+	}
+	if len(secret) < minPeerKeyLen {
+		return fmt.Errorf("peer key %q must be at least %d bytes", id, minPeerKeyLen)
+	}
+	if err := a.addKey(id, secret); err != nil {
+		return err
+	}
+	a.logger.Info("Peer authentication key added", zap.String("key_id", id))
+	a.publishKeyMetrics()
+	return nil
+}
+
+func (a *Authenticator) addKey(id string, secret []byte) error {
+	buf, err := securebuf.New(len(secret))
+	if err != nil {
+		return err
+	}
+	if err := buf.Write(secret); err != nil {
+		buf.Free()
+		return err
+	}
+
+	a.keysMu.Lock()
+	defer a.keysMu.Unlock()
+	if _, exists := a.keys[id]; exists {
+		buf.Free()
+		return fmt.Errorf("peer key %q already exists", id)
+	}
+	a.keys[id] = &peerKey{secret: buf, addedAt: time.Now()}
+	return nil
+}
+
+// AddKeysFromSpec adds keys from a "id:secret,id:secret" list, as used by
+// the PEER_HMAC_KEYS environment variable
+func (a *Authenticator) AddKeysFromSpec(spec string) error {
+	for i, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(entry, ":")
+		if !ok {
+			// The entry is usually a bare secret; never echo it
+			return fmt.Errorf("peer key entry %d is not id:secret", i)
+		}
+		if err := a.AddKey(strings.TrimSpace(id), []byte(secret)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetPrimary makes id the key this node signs outgoing handshakes with
+func (a *Authenticator) SetPrimary(id string) error {
+	a.keysMu.Lock()
+	k, ok := a.keys[id]
+	if !ok || !k.usable(time.Now()) {
+		a.keysMu.Unlock()
+		return fmt.Errorf("peer key %q not found", id)
+	}
+	k.retireAt = time.Time{}
+	previous := a.primary
+	a.primary = id
+	a.keysMu.Unlock()
+
+	a.logger.Info("Peer authentication primary key changed",
+		zap.String("previous", previous),
+		zap.String("key_id", id))
+	a.publishKeyMetrics()
+	return nil
+}
+
+// RetireKey stops accepting id after grace. The primary key cannot be
+// retired; promote its replacement first.
+func (a *Authenticator) RetireKey(id string, grace time.Duration) error {
+	a.keysMu.Lock()
+	k, ok := a.keys[id]
+	if !ok {
+		a.keysMu.Unlock()
+		return fmt.Errorf("peer key %q not found", id)
+	}
+	if id == a.primary {
+		a.keysMu.Unlock()
+		return fmt.Errorf("peer key %q is primary and cannot be retired", id)
+	}
+	k.retireAt = time.Now().Add(grace)
+	a.keysMu.Unlock()
+
+	a.logger.Info("Peer authentication key retiring",
+		zap.String("key_id", id),
+		zap.Duration("grace", grace))
+	a.publishKeyMetrics()
+	return nil
+}
+
+// Keys lists the key ring, primary first
+func (a *Authenticator) Keys() []KeyInfo {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	out := make([]KeyInfo, 0, len(a.keys))
+	for id, k := range a.keys {
+		out = append(out, KeyInfo{ID: id, Primary: id == a.primary, AddedAt: k.addedAt, RetireAt: k.retireAt})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Primary != out[j].Primary {
+			return out[i].Primary
+		}
+		return out[i].AddedAt.Before(out[j].AddedAt)
+	})
+	return out
+}
+
+// SetHandshakeVersion selects the version this node sends. Use HandshakeV1
+// while peers running older builds remain in the cluster; both versions
+// are always accepted.
+func (a *Authenticator) SetHandshakeVersion(version int) error {
+	if version != HandshakeV1 && version != HandshakeV2 {
+		return fmt.Errorf("unsupported handshake version %d", version)
+	}
+	a.keysMu.Lock()
+	a.version = version
+	a.keysMu.Unlock()
+	return nil
+}
+
+// publishKeyMetrics updates the key gauge by rotation state
+func (a *Authenticator) publishKeyMetrics() {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	var active, retiring float64
+	for id, k := range a.keys {
+		switch {
+		case id == a.primary:
+		case k.retireAt.IsZero():
+			active++
+		default:
+			retiring++
+		}
+	}
+	metrics.P2PAuthKeys.WithLabelValues("primary").Set(1)
+	metrics.P2PAuthKeys.WithLabelValues("active").Set(active)
+	metrics.P2PAuthKeys.WithLabelValues("retiring").Set(retiring)
+}
+
+// ===== SIGNING =====
+
 // generateNonce creates a random base64 nonce using entropy-backed SecureBuffer
 func generateNonce() (string, error) {
 	// Use secure random bytes for nonce generation
@@ -80,12 +291,17 @@ func generateNonce() (string, error) {
 	return base64.StdEncoding.EncodeToString(nonceBytes), nil
 }
 
-// signMessage creates HMAC signature for handshake
-func (a *Authenticator) signMessage(nonce string, timestamp int64) (string, error) {
-	data := fmt.Sprintf("%s:%d", nonce, timestamp)
+// hmacWith signs data with key id, which must still be usable
+func (a *Authenticator) hmacWith(id string, data string) (string, error) {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
 
-	secretData := make([]byte, a.secret.Capacity())
-	n, err := a.secret.Read(secretData)
+	k, ok := a.keys[id]
+	if !ok || !k.usable(time.Now()) {
+		return "", fmt.Errorf("peer key %q not available", id)
+	}
+	secretData := make([]byte, k.secret.Capacity())
+	n, err := k.secret.Read(secretData)
 	if err != nil {
 		return "", err
 	}
@@ -103,86 +319,153 @@ func (a *Authenticator) signMessage(nonce string, timestamp int64) (string, erro
 	return base64.URLEncoding.EncodeToString(signature), nil
 }
 
+// signMessage creates HMAC signature for handshake
+func (a *Authenticator) signMessage(id string, version int, nonce string, timestamp int64) (string, error) {
+	if version >= HandshakeV2 {
+		return a.hmacWith(id, fmt.Sprintf("v2:%s:%s:%d", id, nonce, timestamp))
+	}
+	return a.hmacWith(id, fmt.Sprintf("%s:%d", nonce, timestamp))
+}
+
 // signAck signs an ACK message to ensure mutual authentication
-func (a *Authenticator) signAck(nonce string, timestamp int64) (string, error) {
-	data := fmt.Sprintf("ACK:%s:%d", nonce, timestamp)
-	key := make([]byte, a.secret.Capacity())
-	n, err := a.secret.Read(key)
+func (a *Authenticator) signAck(id string, version int, nonce string, timestamp int64) (string, error) {
+	if version >= HandshakeV2 {
+		return a.hmacWith(id, fmt.Sprintf("ACK:v2:%s:%s:%d", id, nonce, timestamp))
+	}
+	return a.hmacWith(id, fmt.Sprintf("ACK:%s:%d", nonce, timestamp))
+}
+
+// signaturesEqual compares two base64url signatures in constant time
+func signaturesEqual(expected, got string) (bool, error) {
+	expectedRaw, err := base64.URLEncoding.DecodeString(expected)
 	if err != nil {
-		return "", err
+		return false, err
 	}
-	defer func() {
-		for i := range key[:n] {
-			key[i] = 0
+	gotRaw, err := base64.URLEncoding.DecodeString(got)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(expectedRaw, gotRaw), nil
+}
+
+// candidateKeys returns the usable key IDs, primary first
+func (a *Authenticator) candidateKeys() []string {
+	a.keysMu.RLock()
+	defer a.keysMu.RUnlock()
+
+	now := time.Now()
+	ids := make([]string, 0, len(a.keys))
+	if k, ok := a.keys[a.primary]; ok && k.usable(now) {
+		ids = append(ids, a.primary)
+	}
+	for id, k := range a.keys {
+		if id != a.primary && k.usable(now) {
+			ids = append(ids, id)
 		}
-	}()
-	h := hmac.New(sha256.New, key[:n])
-	h.Write([]byte(data))
-	sig := h.Sum(nil)
-	return base64.URLEncoding.EncodeToString(sig), nil
+	}
+	return ids
 }
 
+// ===== HANDSHAKE =====
+
 // CreateHandshakeMessage for Sprint peer authentication
 func (a *Authenticator) CreateHandshakeMessage() (*HandshakeMessage, error) {
+	msg, _, err := a.createHandshake()
+	return msg, err
+}
+
+// createHandshake signs a handshake with the primary key and returns the
+// key's ID alongside the message
+func (a *Authenticator) createHandshake() (*HandshakeMessage, string, error) {
 	nonce, err := generateNonce()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	a.keysMu.RLock()
+	kid, version := a.primary, a.version
+	a.keysMu.RUnlock()
+
 	timestamp := time.Now().Unix()
-	signature, err := a.signMessage(nonce, timestamp)
+	signature, err := a.signMessage(kid, version, nonce, timestamp)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return &HandshakeMessage{
+	msg := &HandshakeMessage{
 		Nonce:     nonce,
 		Timestamp: timestamp,
 		Signature: signature,
-	}, nil
+	}
+	if version >= HandshakeV2 {
+		msg.Version = version
+		msg.KeyID = kid
+	}
+	return msg, kid, nil
 }
 
 // VerifyHandshakeMessage checks Sprint peer authentication
 func (a *Authenticator) VerifyHandshakeMessage(msg *HandshakeMessage) error {
+	_, err := a.verifyHandshake(msg)
+	return err
+}
+
+// verifyHandshake checks msg and returns the ID of the key that signed it.
+// V1 messages name no key, so every usable key is tried.
+func (a *Authenticator) verifyHandshake(msg *HandshakeMessage) (string, error) {
 	// Check timestamp (allow 5 minute window)
 	now := time.Now().Unix()
 	if abs64(now-msg.Timestamp) > 300 {
-		return errors.New("handshake timestamp too old or too new")
+		return "", errors.New("handshake timestamp too old or too new")
 	}
 
 	// Check for replay attacks (tracked with TTL)
 	nonceKey := fmt.Sprintf("%s:%d", msg.Nonce, msg.Timestamp)
 	if _, exists := a.seen.LoadOrStore(nonceKey, seenNonce{ts: now}); exists {
-		return errors.New("handshake replay detected")
+		return "", errors.New("handshake replay detected")
 	}
 
-	// Verify HMAC signature using raw bytes and constant time compare
-	expectedSig, err := a.signMessage(msg.Nonce, msg.Timestamp)
-	if err != nil {
-		return err
-	}
-	expectedRaw, err := base64.URLEncoding.DecodeString(expectedSig)
-	if err != nil {
-		return err
-	}
-	msgRaw, err := base64.URLEncoding.DecodeString(msg.Signature)
-	if err != nil {
-		return err
-	}
-	if !hmac.Equal(expectedRaw, msgRaw) {
-		return errors.New("handshake signature verification failed")
+	candidates := a.candidateKeys()
+	if msg.Version >= HandshakeV2 {
+		if msg.Version > HandshakeV2 {
+			return "", fmt.Errorf("unsupported handshake version %d", msg.Version)
+		}
+		candidates = []string{msg.KeyID}
 	}
 
-	a.logger.Debug("Handshake verification successful",
-		zap.String("nonce", msg.Nonce),
-		zap.Int64("timestamp", msg.Timestamp))
+	// Verify HMAC signature using raw bytes and constant time compare
+	for _, kid := range candidates {
+		expectedSig, err := a.signMessage(kid, msg.Version, msg.Nonce, msg.Timestamp)
+		if err != nil {
+			return "", fmt.Errorf("handshake key unknown or retired: %w", err)
+		}
+		ok, err := signaturesEqual(expectedSig, msg.Signature)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			a.logger.Debug("Handshake verification successful",
+				zap.String("nonce", msg.Nonce),
+				zap.String("key_id", kid),
+				zap.Int64("timestamp", msg.Timestamp))
+			return kid, nil
+		}
+	}
+	return "", errors.New("handshake signature verification failed")
+}
 
-	return nil
+// versionLabel is the metrics label for a message version
+func versionLabel(version int) string {
+	if version >= HandshakeV2 {
+		return "v2"
+	}
+	return "v1"
 }
 
 // HandshakeAck acknowledges a verified handshake from server side
 type HandshakeAck struct {
 	OK        bool   `json:"ok"`
+	KeyID     string `json:"kid,omitempty"`
 	Nonce     string `json:"nonce"`
 	Timestamp int64  `json:"ts"`
 	Signature string `json:"sig"`
@@ -194,7 +477,7 @@ func (a *Authenticator) PerformHandshakeClient(conn net.Conn, timeout time.Durat
 	defer conn.SetDeadline(time.Time{})
 
 	// Send request
-	req, err := a.CreateHandshakeMessage()
+	req, kid, err := a.createHandshake()
 	if err != nil {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return fmt.Errorf("failed to create handshake: %w", err)
@@ -219,29 +502,27 @@ func (a *Authenticator) PerformHandshakeClient(conn net.Conn, timeout time.Durat
 		return errors.New("handshake ack nonce mismatch")
 	}
 
-	expectedAckSig, err := a.signAck(ack.Nonce, ack.Timestamp)
-	if err != nil {
-		atomic.AddInt64(&a.handshakesFailure, 1)
-		return err
-	}
-	expRaw, err := base64.URLEncoding.DecodeString(expectedAckSig)
+	// The server answers with the key and version we signed with
+	expectedAckSig, err := a.signAck(kid, req.Version, ack.Nonce, ack.Timestamp)
 	if err != nil {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return err
 	}
-	msgRaw, err := base64.URLEncoding.DecodeString(ack.Signature)
+	ok, err := signaturesEqual(expectedAckSig, ack.Signature)
 	if err != nil {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return err
 	}
-	if !hmac.Equal(expRaw, msgRaw) {
+	if !ok {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return errors.New("handshake ack signature verification failed")
 	}
 
 	atomic.AddInt64(&a.handshakesSuccess, 1)
+	metrics.P2PHandshakeKeyUsage.WithLabelValues(kid, versionLabel(req.Version), "client").Inc()
 	a.logger.Info("Sprint peer handshake (client) completed",
-		zap.String("peer", conn.RemoteAddr().String()))
+		zap.String("peer", conn.RemoteAddr().String()),
+		zap.String("key_id", kid))
 	return nil
 }
 
@@ -255,17 +536,22 @@ func (a *Authenticator) PerformHandshakeServer(conn net.Conn, timeout time.Durat
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return fmt.Errorf("failed to read handshake: %w", err)
 	}
-	if err := a.VerifyHandshakeMessage(&req); err != nil {
+	kid, err := a.verifyHandshake(&req)
+	if err != nil {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return fmt.Errorf("handshake verification failed: %w", err)
 	}
 
+	// Answer with the key and version the peer used so it can verify us
 	ack := HandshakeAck{
 		OK:        true,
 		Nonce:     req.Nonce,
 		Timestamp: time.Now().Unix(),
 	}
-	sig, err := a.signAck(ack.Nonce, ack.Timestamp)
+	if req.Version >= HandshakeV2 {
+		ack.KeyID = kid
+	}
+	sig, err := a.signAck(kid, req.Version, ack.Nonce, ack.Timestamp)
 	if err != nil {
 		atomic.AddInt64(&a.handshakesFailure, 1)
 		return err
@@ -277,8 +563,10 @@ func (a *Authenticator) PerformHandshakeServer(conn net.Conn, timeout time.Durat
 	}
 
 	atomic.AddInt64(&a.handshakesSuccess, 1)
+	metrics.P2PHandshakeKeyUsage.WithLabelValues(kid, versionLabel(req.Version), "server").Inc()
 	a.logger.Info("Sprint peer handshake (server) completed",
-		zap.String("peer", conn.RemoteAddr().String()))
+		zap.String("peer", conn.RemoteAddr().String()),
+		zap.String("key_id", kid))
 	return nil
 }
 
@@ -355,6 +643,7 @@ func (a *Authenticator) startJanitor() {
 					}
 					return true
 				})
+				a.wipeRetiredKeys(now)
 			}
 		}
 	}()
@@ -364,3 +653,21 @@ func (a *Authenticator) startJanitor() {
 func (a *Authenticator) GetHandshakeMetrics() (success int64, failure int64) {
 	return atomic.LoadInt64(&a.handshakesSuccess), atomic.LoadInt64(&a.handshakesFailure)
 }
+
+// wipeRetiredKeys frees keys whose retirement grace period has lapsed
+func (a *Authenticator) wipeRetiredKeys(now time.Time) {
+	a.keysMu.Lock()
+	wiped := 0
+	for id, k := range a.keys {
+		if !k.usable(now) {
+			k.secret.Free()
+			delete(a.keys, id)
+			wiped++
+			a.logger.Info("Peer authentication key retired", zap.String("key_id", id))
+		}
+	}
+	a.keysMu.Unlock()
+	if wiped > 0 {
+		a.publishKeyMetrics()
+	}
+}
@@ -0,0 +1,214 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+const (
+	// headerChainLimit is how many recent headers are kept for continuity
+	// and median-time-past checks
+	headerChainLimit = 4096
+	// medianTimeBlocks is the number of ancestors used for median time past
+	medianTimeBlocks = 11
+	// maxRetargetFactor bounds how far the target may move between a
+	// header and its parent (difficulty retargets are clamped to 4x)
+	maxRetargetFactor = 4
+)
+
+// Header validation failures. Everything except errHeaderOrphan is
+// evidence of a spoofed or corrupt header.
+var (
+	errHeaderOrphan        = errors.New("header parent unknown")
+	errHeaderDiscontinuous = errors.New("headers do not connect")
+	errHeaderVersion       = errors.New("header version too old")
+	errHeaderTarget        = errors.New("header target out of range")
+	errHeaderPoW           = errors.New("header hash above target")
+	errHeaderTimestamp     = errors.New("header timestamp out of bounds")
+	errHeaderAnchor        = errors.New("anchor header below minimum difficulty")
+)
+
+// headerResult labels a validation error for metrics
+func headerResult(err error) string {
+	switch {
+	case err == nil:
+		return "valid"
+	case errors.Is(err, errHeaderOrphan):
+		return "orphan"
+	case errors.Is(err, errHeaderDiscontinuous):
+		return "discontinuous"
+	case errors.Is(err, errHeaderPoW):
+		return "bad_pow"
+	case errors.Is(err, errHeaderTarget):
+		return "bad_target"
+	case errors.Is(err, errHeaderTimestamp):
+		return "bad_timestamp"
+	case errors.Is(err, errHeaderAnchor):
+		return "weak_anchor"
+	default:
+		return "invalid"
+	}
+}
+
+// headerChain validates announced headers against the recent header chain:
+// proof of work against the header's target, the target against the
+// network limit and the parent's target, the timestamp against the
+// median time past and the local clock, and prev-hash continuity.
+//
+// The chain starts empty, so the first header seen becomes the anchor
+// every later header must extend. A header at the network's minimum
+// difficulty costs seconds to mine, and an anchor forged that way would
+// orphan every honest header after it, so the anchor must be the genesis
+// block or a checkpoint, follow one, or carry at least the configured
+// minimum difficulty.
+type headerChain struct {
+	mu          sync.Mutex
+	params      *chaincfg.Params
+	powLimit    *big.Int
+	anchorFloor *big.Int // Largest target an anchor may have; nil accepts any
+	checkpoints map[chainhash.Hash]bool
+	headers     map[chainhash.Hash]wire.BlockHeader
+	order       []chainhash.Hash // Insertion order, for eviction
+	tip         chainhash.Hash
+	now         func() time.Time
+}
+
+// newHeaderChain validates headers for params. Anchors that are not
+// checkpoints need at least minAnchorDifficulty; 0 accepts any.
+func newHeaderChain(params *chaincfg.Params, minAnchorDifficulty float64) *headerChain {
+	hc := &headerChain{
+		params:      params,
+		powLimit:    params.PowLimit,
+		checkpoints: map[chainhash.Hash]bool{*params.GenesisHash: true},
+		headers:     make(map[chainhash.Hash]wire.BlockHeader),
+		now:         time.Now,
+	}
+	for _, cp := range params.Checkpoints {
+		hc.checkpoints[*cp.Hash] = true
+	}
+	if minAnchorDifficulty > 1 {
+		// Difficulty is the ratio of the network's limit to the target
+		floor, _ := new(big.Float).Quo(new(big.Float).SetInt(params.PowLimit), big.NewFloat(minAnchorDifficulty)).Int(nil)
+		hc.anchorFloor = floor
+	}
+	return hc
+}
+
+// Connect validates headers, which must form a chain, and records the
+// valid ones. It returns the headers not seen before and the first
+// validation error, after which the remaining headers are ignored.
+func (hc *headerChain) Connect(headers []*wire.BlockHeader) ([]*wire.BlockHeader, error) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	var accepted []*wire.BlockHeader
+	for i, h := range headers {
+		hash := h.BlockHash()
+		if i > 0 && h.PrevBlock != headers[i-1].BlockHash() {
+			return accepted, fmt.Errorf("%w: %s does not follow %s", errHeaderDiscontinuous, hash, headers[i-1].BlockHash())
+		}
+		if _, ok := hc.headers[hash]; ok {
+			continue
+		}
+		if err := hc.check(h, hash); err != nil {
+			return accepted, fmt.Errorf("header %s: %w", hash, err)
+		}
+		hc.add(hash, *h)
+		accepted = append(accepted, h)
+	}
+	return accepted, nil
+}
+
+// Tip returns the most recently connected header hash
+func (hc *headerChain) Tip() (chainhash.Hash, bool) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return hc.tip, len(hc.headers) > 0
+}
+
+func (hc *headerChain) check(h *wire.BlockHeader, hash chainhash.Hash) error {
+	if h.Version < 1 {
+		return errHeaderVersion
+	}
+
+	target := blockchain.CompactToBig(h.Bits)
+	if target.Sign() <= 0 || target.Cmp(hc.powLimit) > 0 {
+		return fmt.Errorf("%w: bits %08x", errHeaderTarget, h.Bits)
+	}
+	if blockchain.HashToBig(&hash).Cmp(target) > 0 {
+		return errHeaderPoW
+	}
+
+	maxTime := hc.now().Add(blockchain.MaxTimeOffsetSeconds * time.Second)
+	if h.Timestamp.After(maxTime) {
+		return fmt.Errorf("%w: %s is too far in the future", errHeaderTimestamp, h.Timestamp)
+	}
+
+	parent, ok := hc.headers[h.PrevBlock]
+	if !ok {
+		if len(hc.headers) == 0 {
+			return hc.checkAnchor(h, hash, target)
+		}
+		return errHeaderOrphan
+	}
+
+	if h.Bits != parent.Bits && !hc.params.ReduceMinDifficulty {
+		parentTarget := blockchain.CompactToBig(parent.Bits)
+		lo := new(big.Int).Div(parentTarget, big.NewInt(maxRetargetFactor))
+		hi := new(big.Int).Mul(parentTarget, big.NewInt(maxRetargetFactor))
+		if target.Cmp(lo) < 0 || target.Cmp(hi) > 0 {
+			return fmt.Errorf("%w: bits %08x vs parent %08x", errHeaderTarget, h.Bits, parent.Bits)
+		}
+	}
+
+	if mtp, ok := hc.medianTimePast(h.PrevBlock); ok && !h.Timestamp.After(mtp) {
+		return fmt.Errorf("%w: %s is not after median time past %s", errHeaderTimestamp, h.Timestamp, mtp)
+	}
+	return nil
+}
+
+// checkAnchor accepts the first header of an empty chain
+func (hc *headerChain) checkAnchor(h *wire.BlockHeader, hash chainhash.Hash, target *big.Int) error {
+	if hc.checkpoints[hash] || hc.checkpoints[h.PrevBlock] {
+		return nil
+	}
+	if hc.anchorFloor != nil && target.Cmp(hc.anchorFloor) > 0 {
+		return fmt.Errorf("%w: bits %08x", errHeaderAnchor, h.Bits)
+	}
+	return nil
+}
+
+// medianTimePast returns the median timestamp of the medianTimeBlocks
+// headers ending at hash, if all of them are known
+func (hc *headerChain) medianTimePast(hash chainhash.Hash) (time.Time, bool) {
+	stamps := make([]int64, 0, medianTimeBlocks)
+	for len(stamps) < medianTimeBlocks {
+		h, ok := hc.headers[hash]
+		if !ok {
+			return time.Time{}, false
+		}
+		stamps = append(stamps, h.Timestamp.Unix())
+		hash = h.PrevBlock
+	}
+	sort.Slice(stamps, func(i, j int) bool { return stamps[i] < stamps[j] })
+	return time.Unix(stamps[len(stamps)/2], 0), true
+}
+
+func (hc *headerChain) add(hash chainhash.Hash, h wire.BlockHeader) {
+	hc.headers[hash] = h
+	hc.order = append(hc.order, hash)
+	hc.tip = hash
+	if len(hc.order) > headerChainLimit {
+		delete(hc.headers, hc.order[0])
+		hc.order = hc.order[1:]
+	}
+}
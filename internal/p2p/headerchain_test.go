@@ -0,0 +1,148 @@
+package p2p
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestHeaderChainConnect(t *testing.T) {
+	genesis := chaincfg.MainNetParams.GenesisBlock.Header
+	merkle, _ := chainhash.NewHashFromStr("0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098")
+	block1 := wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  genesis.BlockHash(),
+		MerkleRoot: *merkle,
+		Timestamp:  time.Unix(1231469665, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      2573394689,
+	}
+	if got := block1.BlockHash().String(); got != "00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048" {
+		t.Fatalf("block 1 hash = %s", got)
+	}
+
+	hc := newHeaderChain(&chaincfg.MainNetParams, 1e12)
+	accepted, err := hc.Connect([]*wire.BlockHeader{&genesis, &block1})
+	if err != nil || len(accepted) != 2 {
+		t.Fatalf("Connect = %d headers, %v", len(accepted), err)
+	}
+	if accepted, err := hc.Connect([]*wire.BlockHeader{&block1}); err != nil || len(accepted) != 0 {
+		t.Fatalf("duplicate Connect = %d headers, %v", len(accepted), err)
+	}
+
+	forged := block1
+	forged.Nonce++
+	if _, err := newHeaderChain(&chaincfg.MainNetParams, 1e12).Connect([]*wire.BlockHeader{&forged}); !errors.Is(err, errHeaderPoW) {
+		t.Fatalf("forged nonce: err = %v, want errHeaderPoW", err)
+	}
+
+	easy := block1
+	easy.Bits = 0x2100ffff
+	if _, err := hc.Connect([]*wire.BlockHeader{&easy}); !errors.Is(err, errHeaderTarget) {
+		t.Fatalf("easy target: err = %v, want errHeaderTarget", err)
+	}
+
+	// Regtest's trivial target lets the test mine a header with an
+	// unknown parent
+	regtest := newHeaderChain(&chaincfg.RegressionNetParams, 0)
+	anchor := chaincfg.RegressionNetParams.GenesisBlock.Header
+	if _, err := regtest.Connect([]*wire.BlockHeader{&anchor}); err != nil {
+		t.Fatal(err)
+	}
+	orphan := anchor
+	orphan.PrevBlock = chainhash.Hash{1}
+	for regtest.check(&orphan, orphan.BlockHash()) == errHeaderPoW {
+		orphan.Nonce++
+	}
+	if _, err := regtest.Connect([]*wire.BlockHeader{&orphan}); !errors.Is(err, errHeaderOrphan) {
+		t.Fatalf("unknown parent: err = %v, want errHeaderOrphan", err)
+	}
+
+	if _, err := hc.Connect([]*wire.BlockHeader{&block1, &genesis}); !errors.Is(err, errHeaderDiscontinuous) {
+		t.Fatalf("out of order: err = %v, want errHeaderDiscontinuous", err)
+	}
+}
+
+// mineHeader increments h's nonce until its hash meets its target
+func mineHeader(h *wire.BlockHeader) {
+	target := blockchain.CompactToBig(h.Bits)
+	for {
+		hash := h.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			return
+		}
+		h.Nonce++
+	}
+}
+
+func TestHeaderChainAnchor(t *testing.T) {
+	// Mainnet block 2 has real work at the minimum difficulty and an
+	// unknown parent: exactly what a forged anchor looks like
+	merkle, _ := chainhash.NewHashFromStr("9b0fc92260312ce44e74ef369f5c66bbb85848f2eddd5a7a1cde251e54ccfdd5")
+	prev, _ := chainhash.NewHashFromStr("00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048")
+	block2 := wire.BlockHeader{
+		Version:    1,
+		PrevBlock:  *prev,
+		MerkleRoot: *merkle,
+		Timestamp:  time.Unix(1231469744, 0),
+		Bits:       0x1d00ffff,
+		Nonce:      1639830024,
+	}
+	if got := block2.BlockHash().String(); got != "000000006a625f06636b8bb6ac7b960a8d03705d1ace08b1a19da3fdcc99ddbd" {
+		t.Fatalf("block 2 hash = %s", got)
+	}
+	mainnet := newHeaderChain(&chaincfg.MainNetParams, 1e12)
+	if _, err := mainnet.Connect([]*wire.BlockHeader{&block2}); !errors.Is(err, errHeaderAnchor) {
+		t.Fatalf("min-difficulty anchor: err = %v, want errHeaderAnchor", err)
+	}
+	if _, ok := mainnet.Tip(); ok {
+		t.Fatal("rejected anchor was recorded")
+	}
+	// Headers following a checkpoint anchor at any difficulty
+	genesis := chaincfg.MainNetParams.GenesisBlock.Header
+	if _, err := mainnet.Connect([]*wire.BlockHeader{&genesis}); err != nil {
+		t.Fatalf("genesis anchor: %v", err)
+	}
+
+	// On regtest, with no checkpoints, honest headers are mined at 256x
+	// the minimum difficulty and the floor is 16x
+	params := chaincfg.RegressionNetParams
+	params.Checkpoints = nil
+	hc := newHeaderChain(&params, 16)
+
+	forged := wire.BlockHeader{
+		Version:   1,
+		PrevBlock: chainhash.Hash{1},
+		Timestamp: time.Unix(1700000000, 0),
+		Bits:      0x207fffff,
+	}
+	mineHeader(&forged)
+	if _, err := hc.Connect([]*wire.BlockHeader{&forged}); !errors.Is(err, errHeaderAnchor) {
+		t.Fatalf("forged anchor: err = %v, want errHeaderAnchor", err)
+	}
+
+	honestBits := blockchain.BigToCompact(new(big.Int).Rsh(params.PowLimit, 8))
+	honest := make([]*wire.BlockHeader, 12)
+	prevHash := chainhash.Hash{2}
+	for i := range honest {
+		h := &wire.BlockHeader{
+			Version:   1,
+			PrevBlock: prevHash,
+			Timestamp: time.Unix(1700000000+int64(i)*600, 0),
+			Bits:      honestBits,
+		}
+		mineHeader(h)
+		honest[i] = h
+		prevHash = h.BlockHash()
+	}
+	accepted, err := hc.Connect(honest)
+	if err != nil || len(accepted) != len(honest) {
+		t.Fatalf("honest headers after forged anchor: %d accepted, %v", len(accepted), err)
+	}
+}
@@ -0,0 +1,195 @@
+package p2p
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/metrics"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+// A peer can announce thousands of transactions in one inv. Requesting
+// them all at once lets a spammy peer dictate how much we download, so tx
+// requests are capped per peer: announcements beyond the cap wait in a
+// bounded queue and are requested as earlier ones arrive, and while the
+// block pipeline is backed up no tx is requested at all.
+const (
+	maxInflightTxPerPeer = 500              // Outstanding tx getdata items per peer
+	maxDeferredTxPerPeer = 5000             // Announcements waiting per peer beyond those; more are dropped
+	getDataBatchSize     = 250              // Items per getdata message, so peers answer in small chunks
+	txRequestTimeout     = 60 * time.Second // An unanswered item frees its slot after this
+)
+
+// peerTxRequests is one peer's tx request state
+type peerTxRequests struct {
+	inflight map[chainhash.Hash]time.Time // Requested at
+	deferred []chainhash.Hash             // Announced, not yet requested, oldest first
+	queued   map[chainhash.Hash]bool      // Members of deferred
+}
+
+// txRequestTracker throttles tx requests per peer
+type txRequestTracker struct {
+	mu    sync.Mutex
+	peers map[string]*peerTxRequests
+}
+
+func newTxRequestTracker() *txRequestTracker {
+	return &txRequestTracker{peers: make(map[string]*peerTxRequests)}
+}
+
+// txAdmission is the outcome of announcing or completing tx items
+type txAdmission struct {
+	request  []chainhash.Hash // To request now
+	deferred int              // Announced items left waiting
+	dropped  int              // Announced items discarded because the queue was full
+	expired  int              // Requests that timed out
+}
+
+// announce queues hashes advertised by address and returns what to request
+// now. Nothing is requested while throttled.
+func (t *txRequestTracker) announce(address string, hashes []chainhash.Hash, now time.Time, throttled bool) txAdmission {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pr := t.peers[address]
+	if pr == nil {
+		pr = &peerTxRequests{
+			inflight: make(map[chainhash.Hash]time.Time),
+			queued:   make(map[chainhash.Hash]bool),
+		}
+		t.peers[address] = pr
+	}
+
+	var res txAdmission
+	added := 0
+	for _, h := range hashes {
+		if _, ok := pr.inflight[h]; ok || pr.queued[h] {
+			continue
+		}
+		if len(pr.inflight)+len(pr.deferred) >= maxInflightTxPerPeer+maxDeferredTxPerPeer {
+			res.dropped++
+			continue
+		}
+		pr.deferred = append(pr.deferred, h)
+		pr.queued[h] = true
+		added++
+	}
+	t.pumpLocked(pr, now, throttled, &res)
+	res.deferred = min(added, len(pr.deferred))
+	return res
+}
+
+// complete releases hash's slot once address answered with the tx or a
+// notfound, and returns what to request next
+func (t *txRequestTracker) complete(address string, hash chainhash.Hash, now time.Time, throttled bool) txAdmission {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var res txAdmission
+	pr := t.peers[address]
+	if pr == nil {
+		return res
+	}
+	delete(pr.inflight, hash)
+	t.pumpLocked(pr, now, throttled, &res)
+	return res
+}
+
+// pumpLocked expires stale requests and moves queued items into free slots
+func (t *txRequestTracker) pumpLocked(pr *peerTxRequests, now time.Time, throttled bool, res *txAdmission) {
+	for h, sent := range pr.inflight {
+		if now.Sub(sent) > txRequestTimeout {
+			delete(pr.inflight, h)
+			res.expired++
+		}
+	}
+	if throttled {
+		return
+	}
+	n := 0
+	for n < len(pr.deferred) && len(pr.inflight) < maxInflightTxPerPeer {
+		h := pr.deferred[n]
+		delete(pr.queued, h)
+		pr.inflight[h] = now
+		res.request = append(res.request, h)
+		n++
+	}
+	pr.deferred = pr.deferred[n:]
+	if len(pr.deferred) == 0 {
+		pr.deferred = nil // Let the backing array go after a burst
+	}
+}
+
+// forget drops address's state, e.g. when it reconnects
+func (t *txRequestTracker) forget(address string) {
+	t.mu.Lock()
+	delete(t.peers, address)
+	t.mu.Unlock()
+}
+
+// SetMemoryPressure defers tx downloads while high is set, leaving memory
+// to the block pipeline, e.g. from the cache's pressure notifications:
+//
+//	ec.OnMemoryPressure(func(level cache.MemoryPressureLevel) {
+//		client.SetMemoryPressure(level >= cache.PressureHigh)
+//	})
+func (c *Client) SetMemoryPressure(high bool) {
+	if c.memoryPressure.Swap(high) != high {
+		c.logger.Info("P2P memory pressure changed", zap.Bool("deferring_tx_downloads", high))
+	}
+}
+
+// txFetchThrottled reports whether the block pipeline is backed up enough,
+// or memory short enough, that tx downloads should wait
+func (c *Client) txFetchThrottled() bool {
+	if c.memoryPressure.Load() {
+		return true
+	}
+	bp := c.blockProcessor
+	return bp != nil && atomic.LoadInt64(&bp.queueDepth) > bp.maxQueueDepth*9/10
+}
+
+// handleTxAnnouncements throttles the tx items of an inv from address
+func (c *Client) handleTxAnnouncements(address string, p *peer.Peer, hashes []chainhash.Hash) {
+	res := c.txRequests.announce(address, hashes, time.Now(), c.txFetchThrottled())
+	c.sendTxRequests(address, p, res)
+}
+
+// handleTxResponse frees the slot of a tx, or a notfound item, from address
+func (c *Client) handleTxResponse(address string, p *peer.Peer, hash chainhash.Hash) {
+	res := c.txRequests.complete(address, hash, time.Now(), c.txFetchThrottled())
+	c.sendTxRequests(address, p, res)
+}
+
+// sendTxRequests sends res.request in size-capped getdata messages and
+// records the outcome
+func (c *Client) sendTxRequests(address string, p *peer.Peer, res txAdmission) {
+	metrics.P2PInvItems.WithLabelValues("requested").Add(float64(len(res.request)))
+	metrics.P2PInvItems.WithLabelValues("deferred").Add(float64(res.deferred))
+	metrics.P2PInvItems.WithLabelValues("dropped").Add(float64(res.dropped))
+	metrics.P2PInvItems.WithLabelValues("expired").Add(float64(res.expired))
+	if res.dropped > 0 {
+		c.logger.Debug("Dropped tx announcements over the per-peer queue limit",
+			zap.String("peer", address),
+			zap.Int("dropped", res.dropped))
+	}
+
+	for start := 0; start < len(res.request); start += getDataBatchSize {
+		end := min(start+getDataBatchSize, len(res.request))
+		getData := wire.NewMsgGetDataSizeHint(uint(end - start))
+		for i := start; i < end; i++ {
+			getData.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &res.request[i]))
+		}
+		p.QueueMessage(getData, nil)
+	}
+	if len(res.request) > 0 {
+		c.logger.Debug("Requested transaction inventory items",
+			zap.String("peer", address),
+			zap.Int("count", len(res.request)),
+			zap.Int("deferred", res.deferred))
+	}
+}
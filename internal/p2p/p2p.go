@@ -1,12 +1,15 @@
 package p2p
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -54,8 +57,9 @@ type Client struct {
 	peers     map[string]*peer.Peer
 	peerMutex sync.RWMutex
 
-	activePeers int32
-	stopped     atomic.Bool
+	activePeers    int32
+	stopped        atomic.Bool
+	memoryPressure atomic.Bool // Defer tx downloads while memory is short
 
 	auth *Authenticator
 
@@ -74,12 +78,42 @@ type Client struct {
 
 	// Fee estimation
 	feeEstimator *FeeEstimator
+
+	// Blocks requested from several peers at once
+	fetches *blockDownloader
+
+	// Recent validated headers for header-first relay
+	headerChain *headerChain
+
+	// Peers that rejected the BIP324 v2 transport
+	v1OnlyPeers   map[string]time.Time
+	v1OnlyPeersMu sync.Mutex
+
+	// Routes outbound dials over IPv4, IPv6, Tor or I2P
+	transport *netkit.TransportDialer
+
+	// Static peers, allowlist and denylist
+	policy *peerPolicy
+
+	// Receives each peer's version-message clock; nil when unset
+	peerTime func(peer string, t time.Time)
+
+	// Outstanding RTT probes by peer address
+	pings   map[string]pendingPing
+	pingsMu sync.Mutex
+
+	// Per-peer tx request limits
+	txRequests *txRequestTracker
+
+	// Transactions announced by BroadcastTx
+	broadcasts *txBroadcasts
 }
 
 // PeerMetrics tracks performance metrics for adaptive peer selection
 type PeerMetrics struct {
 	address             string
-	latency             time.Duration
+	latency             time.Duration // Block fetch latency EWMA; timeouts count as blockFetchTimeout
+	rtt                 time.Duration // Ping round-trip EWMA; 0 until the first pong
 	blocksReceived      int64
 	lastSeen            time.Time
 	qualityScore        float64
@@ -207,6 +241,10 @@ func New(cfg config.Config, blockChan chan blocks.BlockEvent, mem *mempool.Mempo
 	if err != nil {
 		return nil, fmt.Errorf("failed to create authenticator: %w", err)
 	}
+	if err := configurePeerKeys(auth); err != nil {
+		auth.Close()
+		return nil, fmt.Errorf("failed to configure peer keys: %w", err)
+	}
 
 	// Initialize enterprise P2P deduplicator based on service tier
 	tierStr := "FREE" // Default fallback
@@ -221,6 +259,22 @@ func New(cfg config.Config, blockChan chan blocks.BlockEvent, mem *mempool.Mempo
 
 	deduper := NewEnterpriseP2PDeduper(tierStr, logger)
 
+	// A bad transport setting fails startup rather than falling back to
+	// clearnet and exposing the node's address, and a bad peer policy
+	// rather than dialling peers the operator meant to forbid
+	policy, err := newPeerPolicy(cfg, logger)
+	if err != nil {
+		auth.Close()
+		return nil, fmt.Errorf("invalid P2P peer policy: %w", err)
+	}
+	transportCfg := netkit.TransportFromConfig(cfg)
+	transportCfg.AllowAddr = policy.allowAddr
+	transport, err := netkit.NewTransportDialer(transportCfg, logger)
+	if err != nil {
+		auth.Close()
+		return nil, fmt.Errorf("invalid P2P transport configuration: %w", err)
+	}
+
 	return &Client{
 		cfg:         cfg,
 		blockChan:   blockChan,
@@ -230,9 +284,59 @@ func New(cfg config.Config, blockChan chan blocks.BlockEvent, mem *mempool.Mempo
 		auth:        auth,
 		deduper:     deduper,
 		peerMetrics: make(map[string]*PeerMetrics),
+		fetches:     newBlockDownloader(),
+		headerChain: newHeaderChain(&chaincfg.MainNetParams, cfg.P2PAnchorMinDifficulty),
+		v1OnlyPeers: make(map[string]time.Time),
+		transport:   transport,
+		policy:      policy,
+		pings:       make(map[string]pendingPing),
+		txRequests:  newTxRequestTracker(),
+		broadcasts:  newTxBroadcasts(),
 	}, nil
 }
 
+// configurePeerKeys loads rotation keys from the environment:
+// PEER_HMAC_KEYS ("id:secret,..."), PEER_HMAC_PRIMARY (key ID to sign
+// with) and PEER_HANDSHAKE_VERSION (1 while older peers remain)
+func configurePeerKeys(auth *Authenticator) error {
+	if spec := os.Getenv("PEER_HMAC_KEYS"); spec != "" {
+		if err := auth.AddKeysFromSpec(spec); err != nil {
+			return err
+		}
+	}
+	if primary := os.Getenv("PEER_HMAC_PRIMARY"); primary != "" {
+		if err := auth.SetPrimary(primary); err != nil {
+			return err
+		}
+	}
+	if v := os.Getenv("PEER_HANDSHAKE_VERSION"); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid PEER_HANDSHAKE_VERSION: %w", err)
+		}
+		return auth.SetHandshakeVersion(version)
+	}
+	return nil
+}
+
+// Authenticator returns the client's peer authenticator, e.g. for key
+// rotation through the admin API
+func (c *Client) Authenticator() *Authenticator {
+	return c.auth
+}
+
+// OnPeerTime passes fn the clock time each peer reports in its version
+// message, e.g. timesync.Monitor.ObservePeer. Call it before Run.
+func (c *Client) OnPeerTime(fn func(peer string, t time.Time)) {
+	c.peerTime = fn
+}
+
+// Deduper returns the client's message deduper, e.g. for TTL tuning and
+// peer reputation resets through the admin API
+func (c *Client) Deduper() *EnterpriseP2PDeduper {
+	return c.deduper
+}
+
 // PeerConnection represents a peer connection result
 type PeerConnection struct {
 	Address string
@@ -242,6 +346,8 @@ type PeerConnection struct {
 func (c *Client) Run() {
 	c.logger.Info("Starting Bitcoin Sprint P2P client with parallel connection pool")
 
+	go c.runPingProber()
+
 	// Production Bitcoin seed nodes
 	nodes := []string{
 		"seed.bitcoin.sipa.be:8333",          // Pieter Wuille
@@ -253,18 +359,25 @@ func (c *Client) Run() {
 		"seed.bitcoin.jonasschnelli.ch:8333", // Jonas Schnelli
 	}
 
+	// Static peers are kept connected for the life of the client
+	for _, addr := range c.cfg.P2PStaticPeers {
+		go c.retryConnect(addr)
+	}
+
 	// Create connection pool with configurable size
 	poolSize := c.getConnectionPoolSize()
-	connectionChan := make(chan *PeerConnection, poolSize)
+	connectionChan := make(chan *PeerConnection, len(nodes))
 
 	// Start parallel connection goroutines
 	for _, nodeAddr := range nodes {
 		go c.parallelConnect(nodeAddr, connectionChan)
 	}
 
-	// Collect successful connections
+	// Collect successful connections until every seed has answered, the
+	// pool is full or the seeds stop answering
 	successfulConnections := 0
-	for successfulConnections < poolSize {
+collect:
+	for pending := len(nodes); pending > 0 && successfulConnections < poolSize; pending-- {
 		select {
 		case peerConn := <-connectionChan:
 			if peerConn != nil && peerConn.Peer != nil {
@@ -272,7 +385,7 @@ func (c *Client) Run() {
 				successfulConnections++
 			}
 		case <-time.After(30 * time.Second):
-			break
+			break collect
 		}
 	}
 
@@ -328,6 +441,11 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 		return
 	}
 
+	if !c.policy.permit(address) {
+		connectionChan <- nil
+		return
+	}
+
 	c.logger.Debug("Attempting parallel connection to peer", zap.String("address", address))
 
 	config := &peer.Config{
@@ -335,6 +453,7 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 		UserAgentVersion: "2.1.0",
 		ChainParams:      &chaincfg.MainNetParams,
 		Services:         wire.SFNodeNetwork,
+		HostToNetAddress: netkit.PeerNetAddress,
 		TrickleInterval:  time.Second * 10,
 		ProtocolVersion:  wire.ProtocolVersion,
 		Listeners: peer.MessageListeners{
@@ -359,6 +478,10 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 					return wire.NewMsgReject(msg.Command(), wire.RejectMalformed, "insufficient services")
 				}
 
+				if c.peerTime != nil {
+					c.peerTime(address, msg.Timestamp)
+				}
+
 				c.logger.Info("Bitcoin protocol handshake completed",
 					zap.String("peer", address),
 					zap.String("user_agent", msg.UserAgent),
@@ -369,6 +492,7 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 					zap.Bool("witness", (uint64(msg.Services)&SvcNodeWitness) != 0),
 					zap.Bool("p2p_v2", (uint64(msg.Services)&SvcNodeP2Pv2) != 0))
 
+				c.noteP2Pv2(address, msg.Services)
 				atomic.AddInt32(&c.activePeers, 1)
 				return nil
 			},
@@ -383,6 +507,7 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 				// Normal pong handling - no token validation needed
 				// since Sprint authentication happens at connection time
 				c.logger.Debug("Pong received", zap.String("peer", address))
+				c.handlePong(address, msg.Nonce)
 			},
 			OnBlock: func(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 				// Track peer for enterprise deduplication system (parallel connect)
@@ -390,10 +515,12 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 				if c.deduper != nil {
 					c.deduper.TrackPeer(peerAddr)
 				}
-				c.handleBlock(msg)
+				if c.acceptFetchedBlock(peerAddr, msg) {
+					c.handleBlock(msg)
+				}
 			},
 			OnHeaders: func(p *peer.Peer, msg *wire.MsgHeaders) {
-				c.handleHeaders(p, msg)
+				c.handleHeaders(address, p, msg)
 			},
 			OnInv: func(p *peer.Peer, msg *wire.MsgInv) {
 				// Track peer for enterprise deduplication system (parallel connect)
@@ -401,7 +528,7 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 				if c.deduper != nil {
 					c.deduper.TrackPeer(peerAddr)
 				}
-				c.handleInv(p, msg)
+				c.handleInv(address, p, msg)
 			},
 			OnTx: func(p *peer.Peer, msg *wire.MsgTx) {
 				// Track peer for enterprise deduplication system (parallel connect)
@@ -412,6 +539,17 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 				c.logger.Debug("Received transaction",
 					zap.String("txid", msg.TxHash().String()),
 					zap.String("peer", address))
+				c.handleTxResponse(address, p, msg.TxHash())
+			},
+			OnGetData: func(p *peer.Peer, msg *wire.MsgGetData) {
+				c.handleGetData(address, p, msg)
+			},
+			OnNotFound: func(p *peer.Peer, msg *wire.MsgNotFound) {
+				for _, inv := range msg.InvList {
+					if inv.Type == wire.InvTypeTx {
+						c.handleTxResponse(address, p, inv.Hash)
+					}
+				}
 			},
 		},
 	}
@@ -424,7 +562,9 @@ func (c *Client) parallelConnect(address string, connectionChan chan<- *PeerConn
 	}
 
 	// Set connection timeout with enhanced dialing
-	conn, err := netkit.DialHappy(address, 30*time.Second)
+	conn, err := c.dialPeer(address, func() (net.Conn, error) {
+		return c.dialTransport(address)
+	})
 	if err != nil {
 		c.logger.Warn("Failed to connect to peer with enhanced dialing",
 			zap.String("address", address),
@@ -606,13 +746,13 @@ func (c *Client) handleProcessedBlocks() {
 	}
 }
 
-// handleBlockHeaders processes block headers for faster propagation
-func (c *Client) handleBlockHeaders(msg *wire.MsgHeaders) {
+// handleBlockHeaders relays validated block headers for faster propagation
+func (c *Client) handleBlockHeaders(headers []*wire.BlockHeader) {
 	if c.stopped.Load() {
 		return
 	}
 
-	for _, hdr := range msg.Headers {
+	for _, hdr := range headers {
 		blockHash := hdr.BlockHash()
 
 		// Create header-only block event for immediate relay
@@ -638,20 +778,11 @@ func (c *Client) handleBlockHeaders(msg *wire.MsgHeaders) {
 	}
 }
 
-// requestFullBlock requests the full block data for a given header
+// requestFullBlock requests the full block data for a given header from
+// the best-scored peers in parallel, in selectBestPeerForBlock order, and
+// retries on the next-best peer if none of them delivers in time
 func (c *Client) requestFullBlock(blockHash chainhash.Hash) {
-	getData := wire.NewMsgGetData()
-	getData.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &blockHash))
-
-	// Send to first available peer
-	c.peerMutex.RLock()
-	for _, peer := range c.peers {
-		peer.QueueMessage(getData, nil)
-		c.logger.Debug("Requested full block data",
-			zap.String("hash", blockHash.String()))
-		break // Use first available peer
-	}
-	c.peerMutex.RUnlock()
+	c.fetchBlock(blockHash)
 }
 
 // updatePeerMetrics updates performance metrics for a peer
@@ -671,7 +802,11 @@ func (c *Client) updatePeerMetrics(peerAddr string, latency time.Duration, succe
 		c.peerMetrics[peerAddr] = metrics
 	}
 
-	metrics.latency = latency
+	if metrics.latency == 0 {
+		metrics.latency = latency
+	} else {
+		metrics.latency = time.Duration(blockLatencyAlpha*float64(latency) + (1-blockLatencyAlpha)*float64(metrics.latency))
+	}
 	metrics.lastSeen = time.Now()
 
 	if success {
@@ -707,6 +842,18 @@ func (c *Client) updatePeerMetrics(peerAddr string, latency time.Duration, succe
 	}
 }
 
+// penalizePeer records a protocol violation against a peer without
+// changing its measured latency
+func (c *Client) penalizePeer(peerAddr string) {
+	var latency time.Duration
+	c.peerMetricsMu.RLock()
+	if m := c.peerMetrics[peerAddr]; m != nil {
+		latency = m.latency
+	}
+	c.peerMetricsMu.RUnlock()
+	c.updatePeerMetrics(peerAddr, latency, false)
+}
+
 // calculateQualityScore calculates a quality score for peer selection
 func (c *Client) calculateQualityScore(metrics *PeerMetrics) float64 {
 	// Base score starts at 1.0
@@ -842,17 +989,19 @@ func (c *Client) retryConnect(address string) {
 			c.logger.Info("Peer connected successfully",
 				zap.String("address", address))
 
-			// Monitor this connection and restart if it fails
+			// Wait for the connection to drop, then reconnect
 			c.monitorPeerConnection(address)
-			continue
+			c.logger.Warn("Peer disconnected, reconnecting",
+				zap.String("address", address),
+				zap.Duration("retry_in", currentDelay))
+		} else {
+			// Log failure and wait with exponential backoff
+			c.logger.Warn("Peer connection failed, retrying with exponential backoff",
+				zap.String("address", address),
+				zap.Error(err),
+				zap.Duration("retry_in", currentDelay))
 		}
 
-		// Log failure and wait with exponential backoff
-		c.logger.Warn("Peer connection failed, retrying with exponential backoff",
-			zap.String("address", address),
-			zap.Error(err),
-			zap.Duration("retry_in", currentDelay))
-
 		// Wait before retrying
 		select {
 		case <-time.After(currentDelay):
@@ -869,9 +1018,21 @@ func (c *Client) retryConnect(address string) {
 
 // monitorPeerConnection watches a connected peer and returns when disconnected
 func (c *Client) monitorPeerConnection(address string) {
-	// Simple monitoring - in production you'd want more sophisticated monitoring
-	// For now, we'll rely on the peer disconnect callbacks
+	c.peerMutex.RLock()
+	p := c.peers[address]
+	c.peerMutex.RUnlock()
+	if p == nil {
+		return
+	}
+
 	c.logger.Debug("Monitoring peer connection", zap.String("address", address))
+	p.WaitForDisconnect()
+
+	c.peerMutex.Lock()
+	if c.peers[address] == p {
+		delete(c.peers, address)
+	}
+	c.peerMutex.Unlock()
 }
 
 func (c *Client) connectToPeer(address string) error {
@@ -879,6 +1040,10 @@ func (c *Client) connectToPeer(address string) error {
 		return fmt.Errorf("client stopped")
 	}
 
+	if !c.policy.permit(address) {
+		return fmt.Errorf("%s: %w", address, errPeerSkipped)
+	}
+
 	c.logger.Debug("Connecting to peer", zap.String("address", address))
 
 	config := &peer.Config{
@@ -886,10 +1051,15 @@ func (c *Client) connectToPeer(address string) error {
 		UserAgentVersion: "2.1.0",
 		ChainParams:      &chaincfg.MainNetParams,
 		Services:         wire.SFNodeNetwork,
+		HostToNetAddress: netkit.PeerNetAddress,
 		TrickleInterval:  time.Second * 10,
 		ProtocolVersion:  wire.ProtocolVersion,
 		Listeners: peer.MessageListeners{
 			OnVersion: func(p *peer.Peer, msg *wire.MsgVersion) *wire.MsgReject {
+				if c.peerTime != nil {
+					c.peerTime(address, msg.Timestamp)
+				}
+
 				c.logger.Info("Bitcoin protocol handshake completed",
 					zap.String("peer", address),
 					zap.String("user_agent", msg.UserAgent),
@@ -910,6 +1080,7 @@ func (c *Client) connectToPeer(address string) error {
 				// Normal pong handling - no token validation needed
 				// since Sprint authentication happens at connection time
 				c.logger.Debug("Pong received", zap.String("peer", address))
+				c.handlePong(address, msg.Nonce)
 			},
 			OnBlock: func(p *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 				// Track peer for enterprise deduplication system (connect to peer)
@@ -917,10 +1088,12 @@ func (c *Client) connectToPeer(address string) error {
 				if c.deduper != nil {
 					c.deduper.TrackPeer(peerAddr)
 				}
-				c.handleBlock(msg)
+				if c.acceptFetchedBlock(peerAddr, msg) {
+					c.handleBlock(msg)
+				}
 			},
 			OnHeaders: func(p *peer.Peer, msg *wire.MsgHeaders) {
-				c.handleHeaders(p, msg)
+				c.handleHeaders(address, p, msg)
 			},
 			OnInv: func(p *peer.Peer, msg *wire.MsgInv) {
 				// Track peer for enterprise deduplication system (connect to peer)
@@ -928,7 +1101,7 @@ func (c *Client) connectToPeer(address string) error {
 				if c.deduper != nil {
 					c.deduper.TrackPeer(peerAddr)
 				}
-				c.handleInv(p, msg)
+				c.handleInv(address, p, msg)
 			},
 			OnTx: func(p *peer.Peer, msg *wire.MsgTx) {
 				// Track peer for enterprise deduplication system (connect to peer)
@@ -939,22 +1112,32 @@ func (c *Client) connectToPeer(address string) error {
 				c.logger.Debug("Received transaction",
 					zap.String("txid", msg.TxHash().String()),
 					zap.String("peer", address))
+				c.handleTxResponse(address, p, msg.TxHash())
+			},
+			OnGetData: func(p *peer.Peer, msg *wire.MsgGetData) {
+				c.handleGetData(address, p, msg)
+			},
+			OnNotFound: func(p *peer.Peer, msg *wire.MsgNotFound) {
+				for _, inv := range msg.InvList {
+					if inv.Type == wire.InvTypeTx {
+						c.handleTxResponse(address, p, inv.Hash)
+					}
+				}
 			},
 		},
 	}
 
-	// Create and connect to the peer
-	netAddr, err := net.ResolveTCPAddr("tcp", address)
-	if err != nil {
-		return fmt.Errorf("failed to resolve address %s: %w", address, err)
-	}
-
-	outboundPeer, err := peer.NewOutboundPeer(config, netAddr.String())
+	// Create and connect to the peer. The address is not resolved here:
+	// onion and I2P hosts, and every host in Tor-only mode, are resolved
+	// by the proxy.
+	outboundPeer, err := peer.NewOutboundPeer(config, address)
 	if err != nil {
 		return fmt.Errorf("failed to create outbound peer: %w", err)
 	}
 
-	conn, err := net.DialTimeout("tcp", address, 30*time.Second)
+	conn, err := c.dialPeer(address, func() (net.Conn, error) {
+		return c.dialTransport(address)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to %s: %w", address, err)
 	}
@@ -964,6 +1147,7 @@ func (c *Client) connectToPeer(address string) error {
 	c.peerMutex.Lock()
 	c.peers[address] = outboundPeer
 	c.peerMutex.Unlock()
+	c.txRequests.forget(address)
 
 	c.logger.Info("Successfully connected to peer", zap.String("address", address))
 	return nil
@@ -1004,13 +1188,13 @@ func (c *Client) handleBlock(block *wire.MsgBlock) {
 	}
 }
 
-func (c *Client) handleInv(p *peer.Peer, msg *wire.MsgInv) {
+func (c *Client) handleInv(address string, p *peer.Peer, msg *wire.MsgInv) {
 	if c.stopped.Load() {
 		return
 	}
 
 	getHeaders := wire.NewMsgGetHeaders()
-	getData := wire.NewMsgGetData()
+	var txHashes []chainhash.Hash
 
 	for _, inv := range msg.InvList {
 		switch inv.Type {
@@ -1020,9 +1204,7 @@ func (c *Client) handleInv(p *peer.Peer, msg *wire.MsgInv) {
 				zap.String("hash", inv.Hash.String()))
 			getHeaders.AddBlockLocatorHash(&inv.Hash)
 		case wire.InvTypeTx:
-			c.logger.Debug("Requesting transaction from inventory",
-				zap.String("hash", inv.Hash.String()))
-			getData.AddInvVect(inv)
+			txHashes = append(txHashes, inv.Hash)
 		}
 	}
 
@@ -1033,11 +1215,9 @@ func (c *Client) handleInv(p *peer.Peer, msg *wire.MsgInv) {
 			zap.Int("count", len(getHeaders.BlockLocatorHashes)))
 	}
 
-	// Send transaction requests immediately
-	if len(getData.InvList) > 0 {
-		p.QueueMessage(getData, nil)
-		c.logger.Debug("Requested transaction inventory items",
-			zap.Int("count", len(getData.InvList)))
+	// Transactions are requested within the peer's limits
+	if len(txHashes) > 0 {
+		c.handleTxAnnouncements(address, p, txHashes)
 	}
 }
 
@@ -1052,13 +1232,14 @@ func (c *Client) GetPeerInfo() []map[string]interface{} {
 	defer c.peerMutex.RUnlock()
 
 	peerInfo := make([]map[string]interface{}, 0, len(c.peers))
-	for _, p := range c.peers {
+	for addr, p := range c.peers {
 		if p.Connected() {
 			info := map[string]interface{}{
 				"address":    p.Addr(),
 				"user_agent": p.UserAgent(),
 				"version":    p.ProtocolVersion(),
 				"connected":  p.Connected(),
+				"static":     c.policy.isStatic(addr),
 			}
 			peerInfo = append(peerInfo, info)
 		}
@@ -1067,6 +1248,11 @@ func (c *Client) GetPeerInfo() []map[string]interface{} {
 	return peerInfo
 }
 
+// QueueDepth returns the block processor's current and maximum queue depth
+func (c *Client) QueueDepth() (depth, maxDepth int64) {
+	return atomic.LoadInt64(&c.blockProcessor.queueDepth), c.blockProcessor.maxQueueDepth
+}
+
 // monitorBackpressure monitors queue depth and applies backpressure
 func (c *Client) monitorBackpressure() {
 	ticker := time.NewTicker(100 * time.Millisecond)
@@ -1100,78 +1286,69 @@ func (c *Client) monitorBackpressure() {
 	}
 }
 
-// handleHeaders processes header responses and fetches blocks from best peers
-func (c *Client) handleHeaders(p *peer.Peer, msg *wire.MsgHeaders) {
+// handleHeaders validates header responses, relays the valid ones and
+// fetches their blocks from the best peers
+func (c *Client) handleHeaders(address string, p *peer.Peer, msg *wire.MsgHeaders) {
 	if c.stopped.Load() {
 		return
 	}
 
 	c.logger.Debug("Received headers",
-		zap.String("peer", p.Addr()),
+		zap.String("peer", address),
 		zap.Int("header_count", len(msg.Headers)))
 
-	for _, header := range msg.Headers {
-		blockHash := header.BlockHash()
-
-		// Basic header validation
-		if header.Version < 1 {
-			c.logger.Warn("Invalid header version",
-				zap.String("hash", blockHash.String()),
-				zap.Int32("version", header.Version))
-			continue
-		}
-
-		// Header looks valid, now fetch the full block from the best peer
-		bestPeer := c.selectBestPeerForBlock()
-		if bestPeer != nil {
-			c.logger.Debug("Requesting block after header validation",
-				zap.String("hash", blockHash.String()),
-				zap.String("from_peer", bestPeer.Addr()))
-
-			getData := wire.NewMsgGetData()
-			getData.AddInvVect(wire.NewInvVect(wire.InvTypeBlock, &blockHash))
-			bestPeer.QueueMessage(getData, nil)
-		} else {
-			c.logger.Warn("No suitable peer available for block fetch",
-				zap.String("hash", blockHash.String()))
+	accepted, err := c.headerChain.Connect(msg.Headers)
+	metrics.P2PHeadersValidated.WithLabelValues("valid").Add(float64(len(accepted)))
+	if err != nil {
+		metrics.P2PHeadersValidated.WithLabelValues(headerResult(err)).Inc()
+		switch {
+		case errors.Is(err, errHeaderOrphan):
+			// We missed the parent; ask this peer for the gap
+			if tip, ok := c.headerChain.Tip(); ok {
+				c.requestHeadersFromPeer(p, &tip)
+			}
+		default:
+			c.logger.Warn("Rejected invalid header from peer",
+				zap.String("peer", address),
+				zap.Error(err))
+			c.penalizePeer(address)
+			// Headers without the claimed work cost nothing to forge;
+			// static peers are the operator's own and stay connected
+			if (errors.Is(err, errHeaderPoW) || errors.Is(err, errHeaderTarget)) && !c.policy.isStatic(address) {
+				p.Disconnect()
+			}
 		}
 	}
+
+	c.handleBlockHeaders(accepted)
 }
 
 // selectBestPeerForBlock selects the peer with best performance characteristics
 func (c *Client) selectBestPeerForBlock() *peer.Peer {
-	c.peerMutex.RLock()
-	defer c.peerMutex.RUnlock()
-
-	var bestPeer *peer.Peer
-	var bestScore float64
-
-	for _, peer := range c.peers {
-		if !peer.Connected() {
-			continue
-		}
-
-		// Simple scoring based on connection quality
-		// In production, this would use EWMA of response times
-		score := 1.0
-
-		// Prefer peers with witness support
-		if (uint64(peer.Services()) & SvcNodeWitness) != 0 {
-			score += 0.5
-		}
+	if best := c.rankPeersForBlock(1); len(best) > 0 {
+		return best[0].peer
+	}
+	return nil
+}
 
-		// Prefer newer protocol versions
-		if peer.ProtocolVersion() >= 70016 {
-			score += 0.3
-		}
+// peerBlockScore scores a peer's suitability for block downloads from its
+// advertised capabilities and measured ping RTT (0 when not yet measured)
+func peerBlockScore(p *peer.Peer, rtt time.Duration) float64 {
+	score := 1.0
 
-		if score > bestScore {
-			bestScore = score
-			bestPeer = peer
-		}
+	// Prefer peers with witness support
+	if (uint64(p.Services()) & SvcNodeWitness) != 0 {
+		score += 0.5
 	}
 
-	return bestPeer
+	if rtt > 0 {
+		// Up to 1.0 for a fast peer, nothing at a second or more
+		score += 1 - math.Min(rtt.Seconds(), 1)
+	} else if p.ProtocolVersion() >= 70016 {
+		// Until probed, prefer newer protocol versions
+		score += 0.3
+	}
+	return score
 }
 
 // requestHeadersFromPeer requests block headers from a peer with tier-aware limits
@@ -1342,6 +1519,7 @@ func (c *Client) addPeerSafe(address string, p *peer.Peer) {
 	c.peerMutex.Lock()
 	defer c.peerMutex.Unlock()
 	c.peers[address] = p
+	c.txRequests.forget(address)
 }
 
 // isSprintPeer checks if an address is in the configured Sprint relay peer list
@@ -1354,6 +1532,91 @@ func (c *Client) isSprintPeer(addr string) bool {
 	return false
 }
 
+// v1OnlyRetry is how long a peer that rejected the v2 transport is dialled
+// with v1 before v2 is attempted again
+const v1OnlyRetry = 24 * time.Hour
+
+// dialTransport connects to address over the route its host requires
+func (c *Client) dialTransport(address string) (net.Conn, error) {
+	conn, transport, err := c.transport.Dial(context.Background(), address)
+	if err != nil {
+		if transport != "" {
+			metrics.P2PNetworkConnections.WithLabelValues(string(transport), "failure").Inc()
+		}
+		return nil, err
+	}
+	metrics.P2PNetworkConnections.WithLabelValues(string(transport), "success").Inc()
+	c.logger.Debug("Peer dialed", zap.String("peer", address), zap.String("network", string(transport)))
+	return conn, nil
+}
+
+// dialPeer opens an outbound connection, negotiating the BIP324 v2
+// transport when enabled and falling back to v1 for peers that reject it.
+// Sprint relay peers always use v1 since their own handshake runs on the
+// raw connection.
+func (c *Client) dialPeer(address string, dial func() (net.Conn, error)) (net.Conn, error) {
+	conn, err := dial()
+	if err != nil || !c.useV2Transport(address) {
+		if err == nil {
+			metrics.P2PTransportConnections.WithLabelValues("v1", "success").Inc()
+		}
+		return conn, err
+	}
+
+	v2, err := v2Handshake(conn, chaincfg.MainNetParams.Net, true, v2HandshakeTimeout)
+	if err == nil {
+		metrics.P2PTransportConnections.WithLabelValues("v2", "success").Inc()
+		c.logger.Debug("Negotiated v2 transport", zap.String("peer", address))
+		return v2, nil
+	}
+	conn.Close()
+
+	// v1 nodes disconnect on the unexpected key bytes; remember that and
+	// reconnect in plaintext
+	c.v1OnlyPeersMu.Lock()
+	c.v1OnlyPeers[address] = time.Now()
+	c.v1OnlyPeersMu.Unlock()
+	metrics.P2PTransportConnections.WithLabelValues("v2", "fallback").Inc()
+	c.logger.Debug("v2 transport handshake failed, falling back to v1",
+		zap.String("peer", address),
+		zap.Error(err))
+
+	conn, err = dial()
+	if err != nil {
+		metrics.P2PTransportConnections.WithLabelValues("v1", "failure").Inc()
+		return nil, err
+	}
+	metrics.P2PTransportConnections.WithLabelValues("v1", "success").Inc()
+	return conn, nil
+}
+
+// useV2Transport reports whether address should be dialled with BIP324
+func (c *Client) useV2Transport(address string) bool {
+	if !c.cfg.P2PV2Transport || c.isSprintPeer(address) {
+		return false
+	}
+	c.v1OnlyPeersMu.Lock()
+	defer c.v1OnlyPeersMu.Unlock()
+	if since, ok := c.v1OnlyPeers[address]; ok {
+		if time.Since(since) < v1OnlyRetry {
+			return false
+		}
+		delete(c.v1OnlyPeers, address)
+	}
+	return true
+}
+
+// noteP2Pv2 clears a peer's v1-only mark once it advertises
+// NODE_P2P_V2, so the next connection tries v2 again
+func (c *Client) noteP2Pv2(address string, services wire.ServiceFlag) {
+	if uint64(services)&SvcNodeP2Pv2 == 0 {
+		return
+	}
+	c.v1OnlyPeersMu.Lock()
+	delete(c.v1OnlyPeers, address)
+	c.v1OnlyPeersMu.Unlock()
+}
+
 // updateNetworkHealthWithBlock updates network health metrics when a new block is received
 func (c *Client) updateNetworkHealthWithBlock(block *wire.MsgBlock) {
 	if c.networkHealth == nil {
@@ -0,0 +1,180 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/metrics"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/netkit"
+	"go.uber.org/zap"
+)
+
+// Reasons a peer is not dialled
+const (
+	skipDenylist       = "denylist"
+	skipNotAllowlisted = "not_allowlisted"
+)
+
+// errPeerSkipped is returned when the peer policy forbids a dial
+var errPeerSkipped = errors.New("peer skipped by policy")
+
+// peerPolicy decides which peers may be dialled. Static peers are always
+// permitted. Other IP addresses are checked against the deny CIDRs and, in
+// allowlist-only mode, the allow CIDRs; hostnames are checked once
+// resolved, address by address. Hosts reached through Tor or I2P are
+// resolved by the proxy, so in allowlist-only mode only static ones are
+// dialled.
+type peerPolicy struct {
+	static      map[string]bool // Static peers as configured (host:port)
+	staticHosts map[string]bool // Their hosts, whose resolved addresses are exempt
+	allowOnly   bool
+	allow       []netip.Prefix
+	deny        []netip.Prefix
+	torOnly     bool
+	logger      *zap.Logger
+}
+
+// newPeerPolicy reads the P2P_* peer selection settings
+func newPeerPolicy(cfg config.Config, logger *zap.Logger) (*peerPolicy, error) {
+	pp := &peerPolicy{
+		static:      make(map[string]bool),
+		staticHosts: make(map[string]bool),
+		allowOnly:   cfg.P2PAllowlistOnly,
+		torOnly:     cfg.P2PTorOnly,
+		logger:      logger,
+	}
+	for _, addr := range cfg.P2PStaticPeers {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("static peer %q: %w", addr, err)
+		}
+		pp.static[addr] = true
+		pp.staticHosts[host] = true
+	}
+
+	var err error
+	if pp.allow, err = parsePrefixes(cfg.P2PAllowCIDRs); err != nil {
+		return nil, fmt.Errorf("allow CIDRs: %w", err)
+	}
+	if pp.deny, err = parsePrefixes(cfg.P2PDenyCIDRs); err != nil {
+		return nil, fmt.Errorf("deny CIDRs: %w", err)
+	}
+	if pp.allowOnly && len(pp.static) == 0 && len(pp.allow) == 0 {
+		return nil, errors.New("allowlist-only mode needs static peers or allow CIDRs")
+	}
+	return pp, nil
+}
+
+// parsePrefixes parses CIDRs; a bare address is a single-host prefix
+func parsePrefixes(list []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", s)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", s)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// isStatic reports whether address is a configured static peer
+func (pp *peerPolicy) isStatic(address string) bool {
+	return pp.static[address]
+}
+
+// permit reports whether address may be dialled, logging the peers it
+// skips. Hostnames pass unless allowlist-only mode cannot check them;
+// their addresses are filtered by allowAddr when resolved.
+func (pp *peerPolicy) permit(address string) bool {
+	if pp.isStatic(address) {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return true // The dial reports the bad address
+	}
+
+	var reason string
+	if ip, err := netip.ParseAddr(stripZone(host)); err == nil {
+		reason = pp.addrReason(ip)
+	} else if pp.allowOnly && pp.proxied(host) {
+		reason = skipNotAllowlisted
+	}
+	if reason == "" {
+		return true
+	}
+	pp.skip(address, reason)
+	return false
+}
+
+// allowAddr filters the resolved addresses of host; see
+// netkit.ConnectionConfig.AllowAddr
+func (pp *peerPolicy) allowAddr(host string, ip net.IP) bool {
+	if pp.staticHosts[host] {
+		return true
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	reason := pp.addrReason(addr)
+	if reason == "" {
+		return true
+	}
+	pp.skip(net.JoinHostPort(host, addr.Unmap().String()), reason)
+	return false
+}
+
+// addrReason returns why ip may not be dialled, or ""
+func (pp *peerPolicy) addrReason(ip netip.Addr) string {
+	ip = ip.Unmap()
+	for _, prefix := range pp.deny {
+		if prefix.Contains(ip) {
+			return skipDenylist
+		}
+	}
+	if !pp.allowOnly {
+		return ""
+	}
+	for _, prefix := range pp.allow {
+		if prefix.Contains(ip) {
+			return ""
+		}
+	}
+	return skipNotAllowlisted
+}
+
+// proxied reports whether host is resolved by a proxy rather than locally
+func (pp *peerPolicy) proxied(host string) bool {
+	switch netkit.TransportForHost(host) {
+	case netkit.TransportTor, netkit.TransportI2P:
+		return true
+	}
+	return pp.torOnly
+}
+
+func (pp *peerPolicy) skip(peer, reason string) {
+	metrics.P2PPeersSkipped.WithLabelValues(reason).Inc()
+	pp.logger.Info("Skipping peer", zap.String("peer", peer), zap.String("reason", reason))
+}
+
+func stripZone(host string) string {
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		return host[:i]
+	}
+	return host
+}
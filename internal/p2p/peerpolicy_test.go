@@ -0,0 +1,88 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/netkit"
+	"go.uber.org/zap"
+)
+
+func TestPeerPolicy(t *testing.T) {
+	cfg := config.Config{
+		P2PStaticPeers: []string{"10.1.2.3:8333", "node.example:8333"},
+		P2PDenyCIDRs:   []string{"10.0.0.0/8", "2001:db8::/32", "192.0.2.7"},
+	}
+	pp, err := newPeerPolicy(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3:8333":          true, // Static beats the denylist
+		"10.9.9.9:8333":          false,
+		"[2001:db8::1]:8333":     false,
+		"[::ffff:10.0.0.1]:8333": false,
+		"192.0.2.7:8333":         false,
+		"192.0.2.8:8333":         true,
+		"seed.example:8333":      true, // Checked once resolved
+		"abc.onion:8333":         true,
+	} {
+		if got := pp.permit(addr); got != want {
+			t.Errorf("permit(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if pp.allowAddr("seed.example", net.ParseIP("10.0.0.1")) || !pp.allowAddr("node.example", net.ParseIP("10.0.0.1")) {
+		t.Error("resolved addresses: denylist not applied, or applied to a static host")
+	}
+
+	cfg.P2PAllowlistOnly = true
+	cfg.P2PAllowCIDRs = []string{"198.51.100.0/24"}
+	pp, err = newPeerPolicy(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"node.example:8333":   true,
+		"198.51.100.20:8333":  true,
+		"203.0.113.1:8333":    false,
+		"seed.example:8333":   true,
+		"abc.onion:8333":      false, // Cannot be checked
+		"198.51.100.20:18333": true,
+	} {
+		if got := pp.permit(addr); got != want {
+			t.Errorf("allowlist-only permit(%s) = %v, want %v", addr, got, want)
+		}
+	}
+	if pp.allowAddr("seed.example", net.ParseIP("203.0.113.1")) || !pp.allowAddr("seed.example", net.ParseIP("198.51.100.1")) {
+		t.Error("resolved addresses: allowlist not applied")
+	}
+
+	for _, bad := range []config.Config{
+		{P2PDenyCIDRs: []string{"10.0.0.0/33"}},
+		{P2PStaticPeers: []string{"no-port"}},
+		{P2PAllowlistOnly: true},
+	} {
+		if _, err := newPeerPolicy(bad, zap.NewNop()); err == nil {
+			t.Errorf("newPeerPolicy(%+v) accepted", bad)
+		}
+	}
+}
+
+func TestPeerPolicyFiltersResolvedAddresses(t *testing.T) {
+	pp, err := newPeerPolicy(config.Config{P2PDenyCIDRs: []string{"127.0.0.0/8", "::1/128"}}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, err := netkit.NewTransportDialer(netkit.TransportConfig{IPv4: true, IPv6: true, AllowAddr: pp.allowAddr}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = td.Dial(context.Background(), "localhost:1")
+	var addrErr *net.AddrError
+	if !errors.As(err, &addrErr) {
+		t.Fatalf("dial localhost = %v, want every address rejected", err)
+	}
+}
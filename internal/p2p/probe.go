@@ -0,0 +1,125 @@
+package p2p
+
+import (
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/metrics"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+// Block fetch latency is only measured when a peer happens to serve a
+// block, so most peers were ranked on their service flags alone. The prober
+// pings every connected peer on an interval and keeps an EWMA of the round
+// trip in PeerMetrics, which rankPeersForBlock then prefers over the flags.
+
+// pingRTTAlpha weights each new RTT sample in the EWMA
+const pingRTTAlpha = 0.3
+
+// pendingPing is a ping awaiting its pong
+type pendingPing struct {
+	nonce uint64
+	sent  time.Time
+}
+
+// runPingProber pings connected peers every P2PPingInterval until the
+// client stops
+func (c *Client) runPingProber() {
+	interval := c.cfg.P2PPingInterval
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if c.stopped.Load() {
+			return
+		}
+		c.probePeers(time.Now(), interval)
+	}
+}
+
+// probePeers sends a ping to each connected peer without one outstanding.
+// A ping unanswered after timeout counts as a failure against the peer.
+func (c *Client) probePeers(now time.Time, timeout time.Duration) {
+	c.peerMutex.RLock()
+	targets := make(map[string]*peer.Peer, len(c.peers))
+	for address, p := range c.peers {
+		if p.Connected() {
+			targets[address] = p
+		}
+	}
+	c.peerMutex.RUnlock()
+
+	var unanswered []string
+	c.pingsMu.Lock()
+	for address, p := range targets {
+		if ping, ok := c.pings[address]; ok {
+			if now.Sub(ping.sent) < timeout {
+				continue
+			}
+			unanswered = append(unanswered, address)
+		}
+		nonce, err := wire.RandomUint64()
+		if err != nil {
+			continue
+		}
+		c.pings[address] = pendingPing{nonce: nonce, sent: now}
+		p.QueueMessage(wire.NewMsgPing(nonce), nil)
+	}
+	// Forget pings to peers that have since disconnected
+	for address := range c.pings {
+		if targets[address] == nil {
+			delete(c.pings, address)
+		}
+	}
+	c.pingsMu.Unlock()
+
+	for _, address := range unanswered {
+		metrics.P2PPeerPings.WithLabelValues("unanswered").Inc()
+		c.logger.Debug("Peer did not answer ping", zap.String("peer", address))
+		c.penalizePeer(address)
+	}
+}
+
+// handlePong completes the outstanding ping with nonce, if any, and
+// records its round trip. Pongs to btcd's own keepalive pings are ignored.
+func (c *Client) handlePong(address string, nonce uint64) {
+	c.pingsMu.Lock()
+	ping, ok := c.pings[address]
+	if ok && ping.nonce == nonce {
+		delete(c.pings, address)
+	}
+	c.pingsMu.Unlock()
+	if !ok || ping.nonce != nonce {
+		return
+	}
+	rtt := time.Since(ping.sent)
+	metrics.P2PPeerPings.WithLabelValues("answered").Inc()
+	metrics.P2PPeerRTTSeconds.Observe(rtt.Seconds())
+	c.recordPeerRTT(address, rtt)
+}
+
+// recordPeerRTT folds an RTT sample into the peer's EWMA. Unlike
+// updatePeerMetrics it leaves block counts and failures alone.
+func (c *Client) recordPeerRTT(peerAddr string, rtt time.Duration) {
+	c.peerMetricsMu.Lock()
+	defer c.peerMetricsMu.Unlock()
+
+	if c.peerMetrics == nil {
+		c.peerMetrics = make(map[string]*PeerMetrics)
+	}
+	m := c.peerMetrics[peerAddr]
+	if m == nil {
+		m = &PeerMetrics{address: peerAddr}
+		c.peerMetrics[peerAddr] = m
+	}
+	if m.rtt == 0 {
+		m.rtt = rtt
+	} else {
+		m.rtt = time.Duration(pingRTTAlpha*float64(rtt) + (1-pingRTTAlpha)*float64(m.rtt))
+	}
+	m.lastSeen = time.Now()
+	m.qualityScore = c.calculateQualityScore(m)
+}
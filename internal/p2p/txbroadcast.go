@@ -0,0 +1,181 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/peer"
+	"github.com/btcsuite/btcd/wire"
+	"go.uber.org/zap"
+)
+
+// Transactions submitted through the API are announced to every connected
+// peer with an inv. A peer that does not have the transaction asks for it
+// with getdata and is sent it from the pending set. Peers no longer send
+// reject messages, so that getdata is the only acceptance signal a peer
+// gives: it did not have the transaction and is about to validate it. A
+// transaction stays pending for txBroadcastTTL after its last
+// announcement, so peers that ask late are still served.
+const txBroadcastTTL = 10 * time.Minute
+
+// ErrNoPeers is returned by BroadcastTx when no peer is connected
+var ErrNoPeers = errors.New("no connected peers")
+
+// TxBroadcastResult is how peers answered a transaction announcement
+type TxBroadcastResult struct {
+	TxID         string
+	Peers        int           // Peers the transaction was announced to
+	Requested    int           // Peers that fetched it
+	FirstRequest time.Duration // From announcement to the first fetch; 0 if none
+}
+
+// pendingTx is a transaction being broadcast
+type pendingTx struct {
+	tx        *wire.MsgTx
+	announced time.Time
+	peers     map[string]bool // Announced to
+	requested map[string]bool // Fetched by
+	first     time.Time       // First fetch
+	changed   chan struct{}   // Closed and replaced on every fetch
+}
+
+// txBroadcasts holds transactions peers may fetch
+type txBroadcasts struct {
+	mu      sync.Mutex
+	pending map[chainhash.Hash]*pendingTx
+}
+
+func newTxBroadcasts() *txBroadcasts {
+	return &txBroadcasts{pending: make(map[chainhash.Hash]*pendingTx)}
+}
+
+// add makes tx fetchable and records the peers it is announced to
+func (b *txBroadcasts) add(tx *wire.MsgTx, peers []string, now time.Time) *pendingTx {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for hash, p := range b.pending {
+		if now.Sub(p.announced) > txBroadcastTTL {
+			delete(b.pending, hash)
+		}
+	}
+	hash := tx.TxHash()
+	p := b.pending[hash]
+	if p == nil {
+		p = &pendingTx{
+			tx:        tx,
+			peers:     make(map[string]bool),
+			requested: make(map[string]bool),
+			changed:   make(chan struct{}),
+		}
+		b.pending[hash] = p
+	}
+	p.announced = now
+	for _, address := range peers {
+		p.peers[address] = true
+	}
+	return p
+}
+
+// fetch returns the pending transaction for hash and records address as
+// having fetched it
+func (b *txBroadcasts) fetch(address string, hash chainhash.Hash, now time.Time) *wire.MsgTx {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	p := b.pending[hash]
+	if p == nil || now.Sub(p.announced) > txBroadcastTTL {
+		return nil
+	}
+	if !p.requested[address] {
+		p.requested[address] = true
+		if p.first.IsZero() {
+			p.first = now
+		}
+		close(p.changed)
+		p.changed = make(chan struct{})
+	}
+	return p.tx
+}
+
+// status returns p's progress and a channel closed on the next fetch
+func (b *txBroadcasts) status(p *pendingTx) (TxBroadcastResult, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	res := TxBroadcastResult{
+		TxID:      p.tx.TxHash().String(),
+		Peers:     len(p.peers),
+		Requested: len(p.requested),
+	}
+	if !p.first.IsZero() {
+		res.FirstRequest = p.first.Sub(p.announced)
+	}
+	return res, len(p.requested) >= len(p.peers), p.changed
+}
+
+// BroadcastTx announces tx to every connected peer and serves it to those
+// that ask. It waits until every peer has fetched it or ctx is done, and
+// reports how many did; a peer that already had the transaction never
+// asks.
+func (c *Client) BroadcastTx(ctx context.Context, tx *wire.MsgTx) (TxBroadcastResult, error) {
+	c.peerMutex.RLock()
+	targets := make(map[string]*peer.Peer, len(c.peers))
+	for address, p := range c.peers {
+		if p.Connected() {
+			targets[address] = p
+		}
+	}
+	c.peerMutex.RUnlock()
+	if len(targets) == 0 {
+		return TxBroadcastResult{TxID: tx.TxHash().String()}, ErrNoPeers
+	}
+
+	addresses := make([]string, 0, len(targets))
+	for address := range targets {
+		addresses = append(addresses, address)
+	}
+	pending := c.broadcasts.add(tx, addresses, time.Now())
+
+	hash := tx.TxHash()
+	for _, p := range targets {
+		inv := wire.NewMsgInvSizeHint(1)
+		inv.AddInvVect(wire.NewInvVect(wire.InvTypeTx, &hash))
+		p.QueueMessage(inv, nil)
+	}
+	c.logger.Info("Announced transaction to peers",
+		zap.String("txid", hash.String()),
+		zap.Int("peers", len(targets)))
+
+	for {
+		res, complete, changed := c.broadcasts.status(pending)
+		if complete {
+			return res, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return res, nil
+		}
+	}
+}
+
+// handleGetData serves broadcast transactions to address. Blocks and
+// transactions we did not announce are not served.
+func (c *Client) handleGetData(address string, p *peer.Peer, msg *wire.MsgGetData) {
+	now := time.Now()
+	for _, inv := range msg.InvList {
+		if inv.Type != wire.InvTypeTx && inv.Type != wire.InvTypeWitnessTx {
+			continue
+		}
+		if tx := c.broadcasts.fetch(address, inv.Hash, now); tx != nil {
+			p.QueueMessage(tx, nil)
+			c.logger.Debug("Sent broadcast transaction",
+				zap.String("txid", inv.Hash.String()),
+				zap.String("peer", address))
+		}
+	}
+}
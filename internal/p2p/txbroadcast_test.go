@@ -0,0 +1,50 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestTxBroadcasts(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(wire.NewTxOut(1000, []byte{0x51}))
+	hash := tx.TxHash()
+	start := time.Now()
+
+	b := newTxBroadcasts()
+	p := b.add(tx, []string{"a:8333", "b:8333"}, start)
+
+	res, complete, changed := b.status(p)
+	if complete || res.Peers != 2 || res.Requested != 0 || res.FirstRequest != 0 {
+		t.Fatalf("fresh broadcast: %+v complete=%v", res, complete)
+	}
+
+	if got := b.fetch("a:8333", hash, start.Add(200*time.Millisecond)); got != tx {
+		t.Fatal("announced transaction not served")
+	}
+	select {
+	case <-changed:
+	default:
+		t.Fatal("fetch did not signal waiters")
+	}
+	// A repeat fetch is served but counted once
+	b.fetch("a:8333", hash, start.Add(time.Second))
+	res, complete, _ = b.status(p)
+	if complete || res.Requested != 1 || res.FirstRequest != 200*time.Millisecond {
+		t.Fatalf("after one peer: %+v complete=%v", res, complete)
+	}
+
+	b.fetch("b:8333", hash, start.Add(time.Second))
+	if _, complete, _ = b.status(p); !complete {
+		t.Fatal("broadcast not complete once every peer fetched it")
+	}
+
+	if b.fetch("a:8333", wire.NewMsgTx(1).TxHash(), start) != nil {
+		t.Fatal("unannounced transaction served")
+	}
+	if b.fetch("c:8333", hash, start.Add(txBroadcastTTL+time.Second)) != nil {
+		t.Fatal("expired transaction served")
+	}
+}
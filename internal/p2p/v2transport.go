@@ -0,0 +1,485 @@
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/btcec/v2/ellswift"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// BIP324 v2 encrypted transport.
+//
+// The handshake exchanges ElligatorSwift-encoded ephemeral keys followed by
+// random garbage, derives per-direction keys from the ECDH secret, and
+// confirms them with a garbage terminator and an authenticated version
+// packet. Afterwards every message travels as an encrypted packet: a
+// 3-byte length encrypted with a forward-secure ChaCha20 stream, then the
+// header byte and message contents sealed with ChaCha20-Poly1305.
+//
+// v2Conn translates between these packets and v1 wire frames so btcd's peer
+// implementation can run unchanged on top of it.
+
+const (
+	v2KeyLen           = 64
+	v2TerminatorLen    = 16
+	v2MaxGarbageLen    = 4095
+	v2LengthLen        = 3
+	v2HeaderLen        = 1
+	v2RekeyInterval    = 224
+	v2IgnoreBit        = 1 << 7
+	v2MaxContentsLen   = 1<<24 - 1
+	v1HeaderLen        = 24
+	v1CommandLen       = 12
+	v2HandshakeTimeout = 10 * time.Second
+)
+
+// v2ShortIDs maps BIP324 one-byte message IDs to v1 commands; index 0 means
+// the command follows as 12 ASCII bytes
+var v2ShortIDs = [...]string{
+	1:  wire.CmdAddr,
+	2:  wire.CmdBlock,
+	3:  "blocktxn",
+	4:  "cmpctblock",
+	5:  wire.CmdFeeFilter,
+	6:  wire.CmdFilterAdd,
+	7:  wire.CmdFilterClear,
+	8:  wire.CmdFilterLoad,
+	9:  wire.CmdGetBlocks,
+	10: "getblocktxn",
+	11: wire.CmdGetData,
+	12: wire.CmdGetHeaders,
+	13: wire.CmdHeaders,
+	14: wire.CmdInv,
+	15: wire.CmdMemPool,
+	16: wire.CmdMerkleBlock,
+	17: wire.CmdNotFound,
+	18: wire.CmdPing,
+	19: wire.CmdPong,
+	20: "sendcmpct",
+	21: wire.CmdTx,
+	22: wire.CmdGetCFilters,
+	23: wire.CmdCFilter,
+	24: wire.CmdGetCFHeaders,
+	25: wire.CmdCFHeaders,
+	26: wire.CmdGetCFCheckpt,
+	27: wire.CmdCFCheckpt,
+	28: wire.CmdAddrV2,
+}
+
+var v2ShortIDByCommand = func() map[string]byte {
+	m := make(map[string]byte, len(v2ShortIDs))
+	for id, cmd := range v2ShortIDs {
+		if cmd != "" {
+			m[cmd] = byte(id)
+		}
+	}
+	return m
+}()
+
+var (
+	errV2Decrypt     = errors.New("v2 transport: packet authentication failed")
+	errV2NoGarbage   = errors.New("v2 transport: garbage terminator not found")
+	errV2BadContents = errors.New("v2 transport: malformed message contents")
+)
+
+// fsChaCha20 is the forward-secure length cipher: a ChaCha20 stream that
+// rekeys from its own keystream every v2RekeyInterval chunks
+type fsChaCha20 struct {
+	key    [32]byte
+	stream *chacha20.Cipher
+	chunks uint64
+}
+
+func newFSChaCha20(key []byte) *fsChaCha20 {
+	f := &fsChaCha20{}
+	copy(f.key[:], key)
+	f.reset()
+	return f
+}
+
+func (f *fsChaCha20) reset() {
+	var nonce [chacha20.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], f.chunks/v2RekeyInterval)
+	f.stream, _ = chacha20.NewUnauthenticatedCipher(f.key[:], nonce[:])
+}
+
+func (f *fsChaCha20) crypt(dst, src []byte) {
+	f.stream.XORKeyStream(dst, src)
+	if (f.chunks+1)%v2RekeyInterval == 0 {
+		var next [32]byte
+		f.stream.XORKeyStream(next[:], next[:])
+		f.key = next
+		f.chunks++
+		f.reset()
+		return
+	}
+	f.chunks++
+}
+
+// fsAEAD is the forward-secure packet cipher: ChaCha20-Poly1305 with a
+// packet counter nonce, rekeyed every v2RekeyInterval packets
+type fsAEAD struct {
+	key     [32]byte
+	aead    cipher.AEAD
+	packets uint64
+}
+
+func newFSAEAD(key []byte) *fsAEAD {
+	f := &fsAEAD{}
+	copy(f.key[:], key)
+	f.aead, _ = chacha20poly1305.New(f.key[:])
+	return f
+}
+
+func (f *fsAEAD) nonce(counter uint32) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint32(nonce, counter)
+	binary.LittleEndian.PutUint64(nonce[4:], f.packets/v2RekeyInterval)
+	return nonce
+}
+
+func (f *fsAEAD) seal(dst, plaintext, aad []byte) []byte {
+	out := f.aead.Seal(dst, f.nonce(uint32(f.packets%v2RekeyInterval)), plaintext, aad)
+	f.advance()
+	return out
+}
+
+func (f *fsAEAD) open(dst, ciphertext, aad []byte) ([]byte, error) {
+	out, err := f.aead.Open(dst, f.nonce(uint32(f.packets%v2RekeyInterval)), ciphertext, aad)
+	if err != nil {
+		return nil, errV2Decrypt
+	}
+	f.advance()
+	return out, nil
+}
+
+func (f *fsAEAD) advance() {
+	if (f.packets+1)%v2RekeyInterval == 0 {
+		var zero [32]byte
+		next := f.aead.Seal(nil, f.nonce(0xFFFFFFFF), zero[:], nil)
+		copy(f.key[:], next[:32])
+		f.aead, _ = chacha20poly1305.New(f.key[:])
+	}
+	f.packets++
+}
+
+// v2Cipher holds one side's session keys after the handshake
+type v2Cipher struct {
+	sendL, recvL       *fsChaCha20
+	sendP, recvP       *fsAEAD
+	sendTerm, recvTerm []byte
+	sessionID          [32]byte
+}
+
+// newV2Cipher derives the session keys from the ECDH secret
+func newV2Cipher(secret []byte, magic wire.BitcoinNet, initiating bool) (*v2Cipher, error) {
+	salt := []byte("bitcoin_v2_shared_secret")
+	salt = binary.LittleEndian.AppendUint32(salt, uint32(magic))
+	prk := hkdf.Extract(sha256.New, secret, salt)
+
+	expand := func(info string, n int) ([]byte, error) {
+		out := make([]byte, n)
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, prk, []byte(info)), out); err != nil {
+			return nil, fmt.Errorf("v2 transport: derive %s: %w", info, err)
+		}
+		return out, nil
+	}
+
+	keys := make(map[string][]byte, 6)
+	for _, info := range []string{"initiator_L", "initiator_P", "responder_L", "responder_P", "session_id"} {
+		k, err := expand(info, 32)
+		if err != nil {
+			return nil, err
+		}
+		keys[info] = k
+	}
+	terms, err := expand("garbage_terminators", 2*v2TerminatorLen)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &v2Cipher{}
+	copy(c.sessionID[:], keys["session_id"])
+	if initiating {
+		c.sendL, c.sendP = newFSChaCha20(keys["initiator_L"]), newFSAEAD(keys["initiator_P"])
+		c.recvL, c.recvP = newFSChaCha20(keys["responder_L"]), newFSAEAD(keys["responder_P"])
+		c.sendTerm, c.recvTerm = terms[:v2TerminatorLen], terms[v2TerminatorLen:]
+	} else {
+		c.sendL, c.sendP = newFSChaCha20(keys["responder_L"]), newFSAEAD(keys["responder_P"])
+		c.recvL, c.recvP = newFSChaCha20(keys["initiator_L"]), newFSAEAD(keys["initiator_P"])
+		c.sendTerm, c.recvTerm = terms[v2TerminatorLen:], terms[:v2TerminatorLen]
+	}
+	return c, nil
+}
+
+// encrypt builds a packet carrying contents
+func (c *v2Cipher) encrypt(contents, aad []byte, ignore bool) []byte {
+	var length [v2LengthLen]byte
+	n := len(contents)
+	length[0], length[1], length[2] = byte(n), byte(n>>8), byte(n>>16)
+
+	packet := make([]byte, v2LengthLen, v2LengthLen+v2HeaderLen+n+chacha20poly1305.Overhead)
+	c.sendL.crypt(packet[:v2LengthLen], length[:])
+
+	plain := make([]byte, v2HeaderLen+n)
+	if ignore {
+		plain[0] = v2IgnoreBit
+	}
+	copy(plain[v2HeaderLen:], contents)
+	return c.sendP.seal(packet, plain, aad)
+}
+
+// decrypt reads one packet from r, returning its contents and whether the
+// sender marked it as a decoy
+func (c *v2Cipher) decrypt(r io.Reader, aad []byte) ([]byte, bool, error) {
+	var length [v2LengthLen]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return nil, false, err
+	}
+	c.recvL.crypt(length[:], length[:])
+	n := int(length[0]) | int(length[1])<<8 | int(length[2])<<16
+
+	buf := make([]byte, v2HeaderLen+n+chacha20poly1305.Overhead)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, false, err
+	}
+	plain, err := c.recvP.open(buf[:0], buf, aad)
+	if err != nil {
+		return nil, false, err
+	}
+	return plain[v2HeaderLen:], plain[0]&v2IgnoreBit != 0, nil
+}
+
+// v2Handshake performs the BIP324 handshake over conn and returns the
+// encrypted connection. Responders are only used by tests; inbound v2 is
+// not served.
+func v2Handshake(conn net.Conn, magic wire.BitcoinNet, initiating bool, timeout time.Duration) (*v2Conn, error) {
+	if timeout <= 0 {
+		timeout = v2HandshakeTimeout
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	defer conn.SetDeadline(time.Time{})
+
+	priv, ours, err := ellswift.EllswiftCreate()
+	if err != nil {
+		return nil, fmt.Errorf("v2 transport: create key: %w", err)
+	}
+	garbage, err := randomGarbage()
+	if err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReaderSize(conn, 64<<10)
+
+	var theirs [v2KeyLen]byte
+	sendKey := func() error {
+		_, err := conn.Write(append(ours[:], garbage...))
+		return err
+	}
+	readKey := func() error {
+		_, err := io.ReadFull(reader, theirs[:])
+		return err
+	}
+	steps := []func() error{sendKey, readKey}
+	if !initiating {
+		steps[0], steps[1] = readKey, sendKey
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, fmt.Errorf("v2 transport: key exchange: %w", err)
+		}
+	}
+
+	secret, err := ellswift.V2Ecdh(priv, theirs, ours, initiating)
+	if err != nil {
+		return nil, fmt.Errorf("v2 transport: ecdh: %w", err)
+	}
+	ciph, err := newV2Cipher(secret[:], magic, initiating)
+	if err != nil {
+		return nil, err
+	}
+
+	// Garbage terminator, then the (empty) version packet authenticating
+	// the garbage we sent
+	out := append([]byte{}, ciph.sendTerm...)
+	out = append(out, ciph.encrypt(nil, garbage, false)...)
+	if _, err := conn.Write(out); err != nil {
+		return nil, fmt.Errorf("v2 transport: send version: %w", err)
+	}
+
+	theirGarbage, err := readGarbage(reader, ciph.recvTerm)
+	if err != nil {
+		return nil, err
+	}
+	// The first packet authenticates the peer's garbage; decoys may precede
+	// its version packet, whose contents are reserved for future use
+	aad := theirGarbage
+	for {
+		_, ignore, err := ciph.decrypt(reader, aad)
+		if err != nil {
+			return nil, fmt.Errorf("v2 transport: read version: %w", err)
+		}
+		aad = nil
+		if !ignore {
+			break
+		}
+	}
+
+	return &v2Conn{Conn: conn, reader: reader, cipher: ciph, magic: magic}, nil
+}
+
+func randomGarbage() ([]byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(256))
+	if err != nil {
+		return nil, fmt.Errorf("v2 transport: garbage: %w", err)
+	}
+	garbage := make([]byte, n.Int64())
+	if _, err := rand.Read(garbage); err != nil {
+		return nil, fmt.Errorf("v2 transport: garbage: %w", err)
+	}
+	return garbage, nil
+}
+
+// readGarbage consumes the peer's garbage up to and including term
+func readGarbage(r *bufio.Reader, term []byte) ([]byte, error) {
+	buf := make([]byte, 0, 256)
+	for len(buf) < v2MaxGarbageLen+v2TerminatorLen {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("v2 transport: read garbage: %w", err)
+		}
+		buf = append(buf, b)
+		if len(buf) >= v2TerminatorLen && bytes.Equal(buf[len(buf)-v2TerminatorLen:], term) {
+			return buf[:len(buf)-v2TerminatorLen], nil
+		}
+	}
+	return nil, errV2NoGarbage
+}
+
+// v2Conn carries v1 wire frames over an established v2 session
+type v2Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	cipher *v2Cipher
+	magic  wire.BitcoinNet
+
+	readMu  sync.Mutex
+	pending []byte // Synthesised v1 frame not yet returned by Read
+
+	writeMu sync.Mutex
+	partial []byte // v1 bytes awaiting a complete frame
+}
+
+// SessionID identifies the session; both sides derive the same value
+func (c *v2Conn) SessionID() [32]byte {
+	return c.cipher.sessionID
+}
+
+// Read returns v1 frames rebuilt from decrypted packets
+func (c *v2Conn) Read(b []byte) (int, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for len(c.pending) == 0 {
+		contents, ignore, err := c.cipher.decrypt(c.reader, nil)
+		if err != nil {
+			return 0, err
+		}
+		if ignore {
+			continue
+		}
+		frame, err := v1Frame(c.magic, contents)
+		if err != nil {
+			return 0, err
+		}
+		c.pending = frame
+	}
+	n := copy(b, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write accepts v1 frames, possibly split across calls, and sends each
+// complete frame as one encrypted packet
+func (c *v2Conn) Write(b []byte) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.partial = append(c.partial, b...)
+	for len(c.partial) >= v1HeaderLen {
+		size := int(binary.LittleEndian.Uint32(c.partial[16:20]))
+		if size > v2MaxContentsLen-1-v1CommandLen {
+			return 0, fmt.Errorf("v2 transport: message of %d bytes too large", size)
+		}
+		if len(c.partial) < v1HeaderLen+size {
+			break
+		}
+		cmd := string(bytes.TrimRight(c.partial[4:16], "\x00"))
+		contents := v2Contents(cmd, c.partial[v1HeaderLen:v1HeaderLen+size])
+		if _, err := c.Conn.Write(c.cipher.encrypt(contents, nil, false)); err != nil {
+			return 0, err
+		}
+		c.partial = c.partial[v1HeaderLen+size:]
+	}
+	if len(c.partial) == 0 {
+		c.partial = nil
+	}
+	return len(b), nil
+}
+
+// v2Contents encodes a command and payload, using a short ID if one exists
+func v2Contents(cmd string, payload []byte) []byte {
+	if id, ok := v2ShortIDByCommand[cmd]; ok {
+		return append([]byte{id}, payload...)
+	}
+	contents := make([]byte, 1+v1CommandLen, 1+v1CommandLen+len(payload))
+	copy(contents[1:], cmd)
+	return append(contents, payload...)
+}
+
+// v1Frame rebuilds a v1 frame, including its checksum, from v2 contents
+func v1Frame(magic wire.BitcoinNet, contents []byte) ([]byte, error) {
+	if len(contents) == 0 {
+		return nil, errV2BadContents
+	}
+	var cmd string
+	payload := contents[1:]
+	switch id := contents[0]; {
+	case id == 0:
+		if len(contents) < 1+v1CommandLen {
+			return nil, errV2BadContents
+		}
+		cmd = string(bytes.TrimRight(contents[1:1+v1CommandLen], "\x00"))
+		payload = contents[1+v1CommandLen:]
+	case int(id) < len(v2ShortIDs) && v2ShortIDs[id] != "":
+		cmd = v2ShortIDs[id]
+	default:
+		// Unknown short IDs are reserved for future messages; surface them
+		// as an unknown command rather than dropping the connection
+		cmd = fmt.Sprintf("v2short%d", id)
+	}
+
+	frame := make([]byte, v1HeaderLen, v1HeaderLen+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(magic))
+	copy(frame[4:16], cmd)
+	binary.LittleEndian.PutUint32(frame[16:20], uint32(len(payload)))
+	copy(frame[20:24], chainhash.DoubleHashB(payload)[:4])
+	return append(frame, payload...), nil
+}
@@ -0,0 +1,115 @@
+package p2p
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcd/wire"
+)
+
+func TestV2TransportRoundTrip(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	type result struct {
+		conn *v2Conn
+		err  error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		raw, err := ln.Accept()
+		if err != nil {
+			accepted <- result{err: err}
+			return
+		}
+		c, err := v2Handshake(raw, wire.MainNet, false, time.Second)
+		accepted <- result{c, err}
+	}()
+
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := v2Handshake(raw, wire.MainNet, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	res := <-accepted
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	server := res.conn
+	defer server.Close()
+
+	if client.SessionID() != server.SessionID() {
+		t.Fatal("session IDs differ")
+	}
+
+	// Enough messages to cross a rekey boundary in both ciphers, mixing
+	// short-ID and long-form commands
+	go func() {
+		for i := 0; i < v2RekeyInterval+10; i++ {
+			var msg wire.Message = wire.NewMsgPing(uint64(i))
+			if i%2 == 1 {
+				msg = wire.NewMsgSendAddrV2()
+			}
+			if err := wire.WriteMessage(client, msg, wire.ProtocolVersion, wire.MainNet); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < v2RekeyInterval+10; i++ {
+		msg, _, err := wire.ReadMessage(server, wire.ProtocolVersion, wire.MainNet)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		switch m := msg.(type) {
+		case *wire.MsgPing:
+			if i%2 != 0 || m.Nonce != uint64(i) {
+				t.Fatalf("message %d: unexpected ping %d", i, m.Nonce)
+			}
+		case *wire.MsgSendAddrV2:
+			if i%2 != 1 {
+				t.Fatalf("message %d: unexpected sendaddrv2", i)
+			}
+		default:
+			t.Fatalf("message %d: unexpected %s", i, msg.Command())
+		}
+	}
+}
+
+func TestV2HandshakeFailsAgainstV1Peer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		// A v1 node rejects the key bytes as a bad message header
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 24)
+		c.Read(buf)
+		if !bytes.Equal(buf[:4], []byte{0xf9, 0xbe, 0xb4, 0xd9}) {
+			c.Close()
+		}
+	}()
+
+	raw, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	if _, err := v2Handshake(raw, wire.MainNet, true, time.Second); err == nil {
+		t.Fatal("handshake with v1 peer succeeded")
+	}
+}
@@ -0,0 +1,357 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
+	"github.com/gorilla/websocket"
+)
+
+// JSON-RPC batches put many calls in one message. Providers cap how many
+// calls a batch may hold, and a batch over the cap is usually rejected as
+// a whole, so batches are split to the cap of the provider they are sent
+// to. Each call still succeeds or fails on its own.
+
+// defaultBatchSize caps batches for providers without a known limit
+const defaultBatchSize = 20
+
+// providerBatchSizes are batch caps by endpoint host fragment, kept at or
+// under each provider's documented WebSocket limit
+var providerBatchSizes = []struct {
+	host string
+	size int
+}{
+	{"alchemy.com", 20},
+	{"infura.io", 100},
+	{"quiknode.pro", 50},
+	{"llamarpc.com", 50},
+	{"publicnode.com", 50},
+	{"ankr.com", 100},
+	{"blockpi.network", 10},
+	{"helius", 100},
+	{"solana.com", 20},
+}
+
+// ErrNoBatchResponse is a batch call's error when no provider answered it
+var ErrNoBatchResponse = errors.New("no response to batched call")
+
+// BatchRequest is one call in a JSON-RPC batch
+type BatchRequest struct {
+	Method string
+	Params []interface{}
+}
+
+// BatchResult is the outcome of one BatchRequest: its result, or the node's
+// JSON-RPC error, or the transport error that left it unanswered
+type BatchResult struct {
+	Result json.RawMessage
+	Err    error
+}
+
+// batchSizeFor returns the batch cap for endpoint
+func batchSizeFor(endpoint string) int {
+	for _, p := range providerBatchSizes {
+		if strings.Contains(endpoint, p.host) {
+			return p.size
+		}
+	}
+	return defaultBatchSize
+}
+
+// isBatchMessage reports whether a WebSocket message is a batch response
+func isBatchMessage(message []byte) bool {
+	trimmed := bytes.TrimLeft(message, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// batchSender sends reqs on conn as one batch. It returns a result per
+// request, nil where no answer arrived, and an error when conn failed.
+type batchSender func(ctx context.Context, conn *wsConn, reqs []BatchRequest) ([]*BatchResult, error)
+
+// runBatch sends reqs split to each provider's cap, walking connections in
+// health order. Calls a connection fails to answer move to the next one,
+// for up to attempts rounds; answered calls, including JSON-RPC errors,
+// are final.
+func runBatch(ctx context.Context, reqs []BatchRequest, attempts int, retryDelay time.Duration, conns func() []*wsConn, send batchSender) []BatchResult {
+	results := make([]BatchResult, len(reqs))
+	pending := make([]int, len(reqs))
+	for i := range pending {
+		pending[i] = i
+	}
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	lastErr := error(ErrNoBatchResponse)
+	for attempt := 0; attempt < attempts && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		active := conns()
+		if len(active) == 0 {
+			lastErr = fmt.Errorf("no active connections")
+			continue
+		}
+		for _, conn := range active {
+			if len(pending) == 0 || ctx.Err() != nil {
+				break
+			}
+			size := batchSizeFor(conn.endpoint)
+			var unanswered []int
+			for start := 0; start < len(pending); start += size {
+				chunk := pending[start:min(start+size, len(pending))]
+				batch := make([]BatchRequest, len(chunk))
+				for j, idx := range chunk {
+					batch[j] = reqs[idx]
+				}
+
+				replies, err := send(ctx, conn, batch)
+				for j, idx := range chunk {
+					if j < len(replies) && replies[j] != nil {
+						results[idx] = *replies[j]
+					} else {
+						unanswered = append(unanswered, idx)
+					}
+				}
+				if err != nil {
+					lastErr = err
+					// The connection failed; the rest go to the next one
+					unanswered = append(unanswered, pending[min(start+size, len(pending)):]...)
+					break
+				}
+			}
+			pending = unanswered
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		lastErr = err
+	}
+	for _, idx := range pending {
+		results[idx].Err = lastErr
+	}
+	return results
+}
+
+// rankByHealth orders conns by their provider's health, best first.
+// Connections whose breaker is open go last rather than being dropped, so
+// a request still has somewhere to go.
+func rankByHealth(conns []*wsConn, healthMgr *endpointhealth.Manager) []*wsConn {
+	byEndpoint := make(map[string][]*wsConn, len(conns))
+	for _, c := range conns {
+		byEndpoint[c.endpoint] = append(byEndpoint[c.endpoint], c)
+	}
+
+	ranked := make([]*wsConn, 0, len(conns))
+	for _, endpoint := range healthMgr.Ranked() {
+		ranked = append(ranked, byEndpoint[endpoint]...)
+		delete(byEndpoint, endpoint)
+	}
+	for _, rest := range byEndpoint {
+		ranked = append(ranked, rest...)
+	}
+	return ranked
+}
+
+// BatchCall runs reqs as JSON-RPC batches, split to each provider's cap
+// and failed over across providers like single calls. Results line up
+// with reqs; a node's error for one call leaves the others intact.
+func (er *EthereumRelay) BatchCall(ctx context.Context, reqs []BatchRequest) ([]BatchResult, error) {
+	if !er.IsConnected() {
+		return nil, fmt.Errorf("not connected to Ethereum network")
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	return runBatch(ctx, reqs, er.relayConfig.RetryAttempts, er.relayConfig.RetryDelay,
+		er.rankedConnections, er.makeBatchOn), nil
+}
+
+// makeBatchOn sends reqs as one batch on conn and waits for the answers
+func (er *EthereumRelay) makeBatchOn(ctx context.Context, conn *wsConn, reqs []BatchRequest) (_ []*BatchResult, err error) {
+	defer func(start time.Time) { logUpstreamCall(ctx, er.logger, conn.endpoint, batchMethod(reqs), start, err) }(time.Now())
+	ids := make([]int64, len(reqs))
+	batch := make([]map[string]interface{}, len(reqs))
+	for i, req := range reqs {
+		ids[i] = atomic.AddInt64(&er.requestID, 1)
+		params := req.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		batch[i] = map[string]interface{}{"jsonrpc": "2.0", "method": req.Method, "params": params, "id": ids[i]}
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	// One channel gathers every answer; handleResponse never blocks on it
+	answers := make(chan *EthereumResponse, len(reqs))
+	index := make(map[int64]int, len(reqs))
+	er.reqMu.Lock()
+	for i, id := range ids {
+		er.pendingReqs[id] = answers
+		index[id] = i
+	}
+	er.reqMu.Unlock()
+	defer func() {
+		er.reqMu.Lock()
+		for _, id := range ids {
+			delete(er.pendingReqs, id)
+		}
+		er.reqMu.Unlock()
+	}()
+
+	start := time.Now()
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		er.healthMgr.RecordFailure(conn.endpoint, fmt.Sprintf("write_error: %v", err))
+		return nil, fmt.Errorf("failed to send batch: %w", err)
+	}
+
+	timer := time.NewTimer(er.relayConfig.Timeout)
+	defer timer.Stop()
+
+	results := make([]*BatchResult, len(reqs))
+	for got := 0; got < len(reqs); {
+		select {
+		case resp := <-answers:
+			i, ok := index[resp.ID]
+			if !ok || results[i] != nil {
+				continue
+			}
+			res := &BatchResult{Result: resp.Result}
+			if resp.Error != nil {
+				res.Err = resp.Error
+			}
+			results[i] = res
+			got++
+		case <-timer.C:
+			er.healthMgr.RecordFailure(conn.endpoint, "request_timeout")
+			return results, fmt.Errorf("batch timeout: %d of %d calls unanswered", len(reqs)-countAnswered(results), len(reqs))
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	er.healthMgr.RecordSuccess(conn.endpoint, time.Since(start))
+	return results, nil
+}
+
+// batchMethod names a batch in logs by its first call and size
+func batchMethod(reqs []BatchRequest) string {
+	return fmt.Sprintf("batch(%s x%d)", reqs[0].Method, len(reqs))
+}
+
+// countAnswered counts the non-nil entries of results
+func countAnswered(results []*BatchResult) int {
+	n := 0
+	for _, r := range results {
+		if r != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// BatchCall runs reqs as JSON-RPC batches, split to each provider's cap
+// and failed over across providers in health order. Results line up with
+// reqs; a node's error for one call leaves the others intact.
+func (sr *SolanaRelay) BatchCall(ctx context.Context, reqs []BatchRequest) ([]BatchResult, error) {
+	if !sr.IsConnected() {
+		return nil, fmt.Errorf("not connected to Solana network")
+	}
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+	ranked := func() []*wsConn { return rankByHealth(sr.activeConnections(), sr.healthMgr) }
+	return runBatch(ctx, reqs, sr.relayConfig.RetryAttempts, sr.relayConfig.RetryDelay,
+		ranked, sr.makeBatchOn), nil
+}
+
+// makeBatchOn sends reqs as one batch on wc and waits for the answers
+func (sr *SolanaRelay) makeBatchOn(ctx context.Context, wc *wsConn, reqs []BatchRequest) (_ []*BatchResult, err error) {
+	defer func(start time.Time) {
+		logUpstreamCall(ctx, sr.logger, sr.creds.Redact(wc.endpoint), batchMethod(reqs), start, err)
+	}(time.Now())
+	ids := make([]int64, len(reqs))
+	batch := make([]map[string]interface{}, len(reqs))
+	for i, req := range reqs {
+		ids[i] = atomic.AddInt64(&sr.requestID, 1)
+		params := req.Params
+		if params == nil {
+			params = []interface{}{}
+		}
+		batch[i] = map[string]interface{}{"jsonrpc": "2.0", "method": req.Method, "params": params, "id": ids[i]}
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	answers := make(chan *SolanaResponse, len(reqs))
+	index := make(map[int64]int, len(reqs))
+	sr.reqMu.Lock()
+	for i, id := range ids {
+		sr.pendingReqs[id] = answers
+		index[id] = i
+	}
+	sr.reqMu.Unlock()
+	defer func() {
+		sr.reqMu.Lock()
+		for _, id := range ids {
+			delete(sr.pendingReqs, id)
+		}
+		sr.reqMu.Unlock()
+	}()
+
+	start := time.Now()
+	wc.writeMu.Lock()
+	_ = wc.Conn.SetWriteDeadline(time.Now().Add(8 * time.Second))
+	err = wc.Conn.WriteMessage(websocket.TextMessage, data)
+	wc.writeMu.Unlock()
+	if err != nil {
+		sr.healthMgr.RecordFailure(wc.endpoint, fmt.Sprintf("write_error: %v", err))
+		return nil, fmt.Errorf("failed to send batch to %s: %w", sr.creds.Redact(wc.endpoint), err)
+	}
+
+	timer := time.NewTimer(sr.relayConfig.Timeout)
+	defer timer.Stop()
+
+	results := make([]*BatchResult, len(reqs))
+	for got := 0; got < len(reqs); {
+		select {
+		case resp := <-answers:
+			i, ok := index[resp.ID]
+			if !ok || results[i] != nil {
+				continue
+			}
+			res := &BatchResult{Result: resp.Result}
+			if resp.Error != nil {
+				res.Err = resp.Error
+			}
+			results[i] = res
+			got++
+		case <-timer.C:
+			sr.healthMgr.RecordFailure(wc.endpoint, "request_timeout")
+			return results, fmt.Errorf("batch timeout on %s: %d of %d calls unanswered",
+				sr.creds.Redact(wc.endpoint), len(reqs)-countAnswered(results), len(reqs))
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	sr.healthMgr.RecordSuccess(wc.endpoint, time.Since(start))
+	return results, nil
+}
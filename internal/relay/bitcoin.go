@@ -502,7 +502,15 @@ func (br *BitcoinRelay) GetConfig() RelayConfig {
 
 // connectToPeer establishes connection to a single peer
 func (br *BitcoinRelay) connectToPeer(ctx context.Context, endpoint string) {
-	conn, err := netkit.DialHappy(endpoint, br.relayConfig.Timeout)
+	transport := netkit.TransportFromConfig(br.cfg)
+	transport.Timeout = br.relayConfig.Timeout
+	dialer, err := netkit.NewTransportDialer(transport, br.logger)
+	if err != nil {
+		br.logger.Error("Invalid P2P transport configuration", zap.Error(err))
+		return
+	}
+
+	conn, _, err := dialer.Dial(ctx, endpoint)
 	if err != nil {
 		br.logger.Warn("Failed to connect to peer with enhanced dialing",
 			zap.String("endpoint", endpoint),
@@ -522,6 +530,7 @@ func (br *BitcoinRelay) connectToPeer(ctx context.Context, endpoint string) {
 		},
 		ChainParams:      &chaincfg.MainNetParams,
 		Services:         wire.SFNodeNetwork | wire.SFNodeWitness,
+		HostToNetAddress: netkit.PeerNetAddress,
 		UserAgentName:    "Bitcoin-Sprint",
 		UserAgentVersion: "2.1.0",
 	}, endpoint)
@@ -1,9 +1,11 @@
 package relay
 
 import (
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/dedup"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -61,6 +63,10 @@ type BlockDeduper struct {
 	crossNetworkDedup   bool
 	intelligentEviction bool
 	priorityHandling    bool
+
+	// Cluster-wide deduplication (optional)
+	sharedBackend dedup.SharedBackend
+	shared        *dedup.SharedDeduper
 }
 
 // NetworkConfig holds network-specific deduplication configuration
@@ -208,8 +214,74 @@ func (bd *BlockDeduper) getPriorityForNetwork(network string) int {
 	}
 }
 
-// Seen returns true if hash is already seen within TTL with enterprise features
+// Seen returns true if hash is already seen within TTL with enterprise features.
+// When a shared backend is enabled, hashes that are new to this node are also
+// checked against the cluster so that only one node relays each block.
 func (bd *BlockDeduper) Seen(hash string, now time.Time, network string, options ...dedup.DedupeOption) bool {
+	if bd == nil {
+		return false
+	}
+	if bd.seenLocal(hash, now, network, options...) {
+		return true
+	}
+
+	bd.mu.RLock()
+	shared, tier := bd.shared, bd.tier
+	bd.mu.RUnlock()
+	if shared == nil || !shared.Seen(hash, now, network, options...) {
+		return false
+	}
+
+	bd.mu.Lock()
+	bd.duplicatesFound++
+	bd.mu.Unlock()
+	duplicateBlocksSuppressed.WithLabelValues(network, "shared", tier).Inc()
+	return true
+}
+
+// EnableSharedDedup routes hashes new to this node through a cluster-wide backend.
+// Settings come from the tier; tiers without shared dedup keep the backend idle.
+func (bd *BlockDeduper) EnableSharedDedup(backend dedup.SharedBackend) {
+	bd.mu.Lock()
+	defer bd.mu.Unlock()
+
+	bd.sharedBackend = backend
+	bd.shared = dedup.NewSharedDeduper(backend, dedup.SharedConfigForTier(bd.tier), bd.logger)
+
+	if bd.logger != nil {
+		bd.logger.Info("Shared deduplication configured",
+			zap.String("backend", backend.Name()),
+			zap.String("tier", bd.tier),
+			zap.Bool("enabled", dedup.SharedConfigForTier(bd.tier).Enabled))
+	}
+}
+
+// configureSharedDedup attaches the Redis shared dedup backend when configured.
+// The deduper adopts the node tier, from cfg or else TIER, so shared settings
+// match the deployment.
+func configureSharedDedup(bd *BlockDeduper, cfg config.Config, logger *zap.Logger) {
+	if cfg.DedupRedisURL == "" || bd == nil {
+		return
+	}
+
+	backend, err := dedup.NewRedisBackend(cfg.DedupRedisURL, "", cfg.NodeID)
+	if err != nil {
+		if logger != nil {
+			logger.Warn("Shared dedup backend unavailable, using per-node dedup", zap.Error(err))
+		}
+		return
+	}
+
+	tier := cfg.Tier
+	if tier == "" {
+		tier = config.TierFromEnv()
+	}
+	bd.SetTier(strings.ToUpper(string(tier)))
+	bd.EnableSharedDedup(backend)
+}
+
+// seenLocal checks this node's deduplication state only
+func (bd *BlockDeduper) seenLocal(hash string, now time.Time, network string, options ...dedup.DedupeOption) bool {
 	start := time.Now()
 	defer func() {
 		bd.avgProcessingTime = time.Since(start)
@@ -429,6 +501,9 @@ func (bd *BlockDeduper) GetStats() map[string]interface{} {
 		stats["total_requests"] = bd.totalRequests
 		stats["duplicates_found"] = bd.duplicatesFound
 		stats["avg_processing_time_ms"] = bd.avgProcessingTime.Milliseconds()
+		if bd.shared != nil {
+			stats["shared"] = bd.shared.GetStats()
+		}
 		return stats
 	}
 
@@ -441,7 +516,7 @@ func (bd *BlockDeduper) GetStats() map[string]interface{} {
 		hitRate = float64(bd.duplicatesFound) / float64(bd.totalRequests)
 	}
 
-	return map[string]interface{}{
+	stats := map[string]interface{}{
 		"mode":                   "legacy",
 		"tier":                   bd.tier,
 		"total_cached":           len(bd.set),
@@ -455,6 +530,10 @@ func (bd *BlockDeduper) GetStats() map[string]interface{} {
 		"intelligent_eviction":   bd.intelligentEviction,
 		"priority_handling":      bd.priorityHandling,
 	}
+	if bd.shared != nil {
+		stats["shared"] = bd.shared.GetStats()
+	}
+	return stats
 }
 
 // SetTier updates the service tier and reconfigures accordingly
@@ -495,6 +574,11 @@ func (bd *BlockDeduper) SetTier(tier string) {
 		}
 	}
 
+	// Re-derive shared dedup settings for the new tier
+	if bd.sharedBackend != nil {
+		bd.shared = dedup.NewSharedDeduper(bd.sharedBackend, dedup.SharedConfigForTier(tier), bd.logger)
+	}
+
 	if bd.logger != nil {
 		bd.logger.Info("Service tier updated",
 			zap.String("old_tier", oldTier),
@@ -548,6 +632,11 @@ func (bd *BlockDeduper) GetNetworkConfig(network string) *NetworkConfig {
 
 // Close gracefully shuts down the deduper
 func (bd *BlockDeduper) Close() error {
+	if bd.shared != nil {
+		if err := bd.shared.Close(); err != nil && bd.logger != nil {
+			bd.logger.Warn("Failed to close shared dedup backend", zap.Error(err))
+		}
+	}
 	if bd.adaptive != nil {
 		return bd.adaptive.Close()
 	}
@@ -0,0 +1,677 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/mempool"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/reqid"
+)
+
+// Esplora polling limits. On startup or after a long outage only the most
+// recent blocks are emitted; older ones are still reachable by height.
+const (
+	defaultEsploraPollInterval = 10 * time.Second
+	maxEsploraCatchUp          = 6
+	maxEsploraResponseBytes    = 4 << 20
+)
+
+// ErrEsploraNotFound is returned when a backend does not know a block
+var ErrEsploraNotFound = errors.New("esplora: not found")
+
+var (
+	esploraRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "esplora",
+		Name:      "requests_total",
+		Help:      "Esplora HTTP requests by endpoint and result",
+	}, []string{"endpoint", "result"})
+)
+
+// EsploraRelay implements RelayClient for Bitcoin on top of Esplora/Electrs
+// HTTP APIs (blockstream.info, mempool.space or a self-hosted electrs).
+// It complements the P2P relay where outbound 8333 is blocked: blocks are
+// discovered by polling the tip and endpoints are scored and failed over
+// through endpointhealth like the other relays.
+type EsploraRelay struct {
+	cfg    config.Config
+	logger *zap.Logger
+	mem    *mempool.Mempool
+
+	httpClient *http.Client
+	healthMgr  *endpointhealth.Manager
+	connected  atomic.Bool
+
+	pollInterval time.Duration
+	lastHeight   uint64
+	heightMu     sync.Mutex
+	streaming    atomic.Bool
+
+	relayConfig RelayConfig
+
+	health    *HealthStatus
+	healthMu  sync.RWMutex
+	metrics   *RelayMetrics
+	metricsMu sync.RWMutex
+}
+
+// EsploraBlock is the block summary returned by /block/:hash
+type EsploraBlock struct {
+	ID                string  `json:"id"`
+	Height            uint64  `json:"height"`
+	Version           int32   `json:"version"`
+	Timestamp         int64   `json:"timestamp"`
+	MedianTime        int64   `json:"mediantime"`
+	TxCount           int     `json:"tx_count"`
+	Size              int     `json:"size"`
+	Weight            int     `json:"weight"`
+	MerkleRoot        string  `json:"merkle_root"`
+	PreviousBlockHash string  `json:"previousblockhash"`
+	Nonce             uint32  `json:"nonce"`
+	Bits              uint32  `json:"bits"`
+	Difficulty        float64 `json:"difficulty"`
+}
+
+// EsploraMempoolInfo is the backlog summary returned by /mempool
+type EsploraMempoolInfo struct {
+	Count        int          `json:"count"`
+	VSize        int64        `json:"vsize"`
+	TotalFee     int64        `json:"total_fee"`
+	FeeHistogram [][2]float64 `json:"fee_histogram"`
+}
+
+// EsploraMempoolTx is an entry of /mempool/recent
+type EsploraMempoolTx struct {
+	TxID  string `json:"txid"`
+	Fee   int64  `json:"fee"`
+	VSize int    `json:"vsize"`
+	Value int64  `json:"value"`
+}
+
+// NewEsploraRelay creates a Bitcoin relay backed by Esplora HTTP endpoints.
+// mem may be nil; when set, recently seen mempool transactions are added to it.
+func NewEsploraRelay(cfg config.Config, logger *zap.Logger, mem *mempool.Mempool) *EsploraRelay {
+	endpoints := make([]string, 0)
+	for _, endpoint := range cfg.GetStringSlice("ESPLORA_ENDPOINTS") {
+		if !isValidEndpoint(endpoint) {
+			logger.Warn("Skipping invalid Esplora endpoint", zap.String("endpoint", endpoint))
+			continue
+		}
+		endpoints = append(endpoints, strings.TrimRight(endpoint, "/"))
+	}
+	if len(endpoints) == 0 {
+		endpoints = []string{
+			"https://blockstream.info/api",
+			"https://mempool.space/api",
+		}
+		logger.Info("Using fallback Esplora endpoints", zap.Strings("endpoints", endpoints))
+	}
+
+	timeout := cfg.GetDuration("ESPLORA_TIMEOUT")
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	pollInterval := cfg.GetDuration("ESPLORA_POLL_INTERVAL")
+	if pollInterval == 0 {
+		pollInterval = defaultEsploraPollInterval
+	}
+
+	retryAttempts := cfg.GetInt("MAX_RETRY_ATTEMPTS")
+	if retryAttempts == 0 {
+		retryAttempts = 3
+	}
+
+	relayConfig := RelayConfig{
+		Network:           "bitcoin",
+		Endpoints:         endpoints,
+		Timeout:           timeout,
+		RetryAttempts:     retryAttempts,
+		RetryDelay:        2 * time.Second,
+		MaxConcurrency:    len(endpoints),
+		BufferSize:        1000,
+		EnableCompression: true,
+	}
+
+	return &EsploraRelay{
+		cfg:          cfg,
+		logger:       logger,
+		mem:          mem,
+		httpClient:   newHTTPClient(newRelayProxies(cfg, logger), timeout),
+		healthMgr:    endpointhealth.New("esplora", endpoints, endpointhealth.Config{}),
+		pollInterval: pollInterval,
+		relayConfig:  relayConfig,
+		health: &HealthStatus{
+			IsHealthy:       false,
+			ConnectionState: "disconnected",
+		},
+		metrics: &RelayMetrics{},
+	}
+}
+
+// Connect probes every endpoint and succeeds if at least one answers
+func (er *EsploraRelay) Connect(ctx context.Context) error {
+	er.logger.Info("Connecting to Esplora backends",
+		zap.Strings("endpoints", er.relayConfig.Endpoints))
+
+	var (
+		wg        sync.WaitGroup
+		reachable int32
+	)
+	for _, endpoint := range er.relayConfig.Endpoints {
+		wg.Add(1)
+		go func(endpoint string) {
+			defer wg.Done()
+			body, err := er.getFrom(ctx, endpoint, "/blocks/tip/height")
+			if err != nil {
+				er.logger.Warn("Esplora endpoint unreachable",
+					zap.String("endpoint", endpoint),
+					zap.Error(err))
+				return
+			}
+			if height, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 64); err == nil {
+				er.observeHeight(height)
+			}
+			atomic.AddInt32(&reachable, 1)
+		}(endpoint)
+	}
+	wg.Wait()
+
+	if reachable == 0 {
+		err := fmt.Errorf("no Esplora endpoint reachable")
+		er.updateHealth(false, "disconnected", err)
+		return err
+	}
+
+	er.connected.Store(true)
+	er.metricsMu.Lock()
+	er.metrics.ConnectionUptime = 0
+	er.metricsMu.Unlock()
+	er.updateHealth(true, "connected", nil)
+	er.logger.Info("Connected to Esplora backends", zap.Int32("reachable", reachable))
+	return nil
+}
+
+// Disconnect stops serving requests; HTTP connections are pooled and idle
+func (er *EsploraRelay) Disconnect() error {
+	er.connected.Store(false)
+	er.httpClient.CloseIdleConnections()
+	er.updateHealth(false, "disconnected", nil)
+	return nil
+}
+
+// IsConnected returns true if at least one endpoint answered on Connect
+func (er *EsploraRelay) IsConnected() bool {
+	return er.connected.Load()
+}
+
+// StreamBlocks polls the chain tip and emits every new block in height
+// order until ctx is done
+func (er *EsploraRelay) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
+	if !er.IsConnected() {
+		return fmt.Errorf("not connected to Esplora backends")
+	}
+	if !er.streaming.CompareAndSwap(false, true) {
+		return fmt.Errorf("esplora block stream already running")
+	}
+
+	go func() {
+		defer er.streaming.Store(false)
+
+		ticker := time.NewTicker(er.pollInterval)
+		defer ticker.Stop()
+		for {
+			if err := er.pollTip(ctx, blockChan); err != nil && ctx.Err() == nil {
+				er.logger.Debug("Esplora tip poll failed", zap.Error(err))
+			}
+			if er.mem != nil {
+				er.pollMempool(ctx)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// GetLatestBlock returns the current chain tip
+func (er *EsploraRelay) GetLatestBlock() (*blocks.BlockEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), er.relayConfig.Timeout)
+	defer cancel()
+
+	body, err := er.get(ctx, "/blocks/tip/hash")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tip hash: %w", err)
+	}
+	return er.blockByHash(ctx, strings.TrimSpace(string(body)))
+}
+
+// GetBlockByHash returns the block with the given hash
+func (er *EsploraRelay) GetBlockByHash(hash string) (*blocks.BlockEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), er.relayConfig.Timeout)
+	defer cancel()
+	return er.blockByHash(ctx, hash)
+}
+
+// GetBlockByHeight returns the main-chain block at height
+func (er *EsploraRelay) GetBlockByHeight(height uint64) (*blocks.BlockEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), er.relayConfig.Timeout)
+	defer cancel()
+	return er.blockByHeight(ctx, height)
+}
+
+// GetMempoolInfo returns the backend's mempool backlog summary
+func (er *EsploraRelay) GetMempoolInfo(ctx context.Context) (*EsploraMempoolInfo, error) {
+	body, err := er.get(ctx, "/mempool")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mempool info: %w", err)
+	}
+	var info EsploraMempoolInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse mempool info: %w", err)
+	}
+	return &info, nil
+}
+
+// GetRecentMempoolTxs returns the transactions most recently added to the
+// backend's mempool
+func (er *EsploraRelay) GetRecentMempoolTxs(ctx context.Context) ([]EsploraMempoolTx, error) {
+	body, err := er.get(ctx, "/mempool/recent")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent mempool transactions: %w", err)
+	}
+	var txs []EsploraMempoolTx
+	if err := json.Unmarshal(body, &txs); err != nil {
+		return nil, fmt.Errorf("failed to parse recent mempool transactions: %w", err)
+	}
+	return txs, nil
+}
+
+// GetNetworkInfo returns Bitcoin network information from the tip block
+func (er *EsploraRelay) GetNetworkInfo() (*NetworkInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), er.relayConfig.Timeout)
+	defer cancel()
+
+	body, err := er.get(ctx, "/blocks/tip/hash")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tip hash: %w", err)
+	}
+	block, err := er.fetchBlock(ctx, strings.TrimSpace(string(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	difficulty := strconv.FormatFloat(block.Difficulty, 'f', 0, 64)
+	return &NetworkInfo{
+		Network:     "bitcoin",
+		BlockHeight: block.Height,
+		BlockHash:   block.ID,
+		Difficulty:  &difficulty,
+		PeerCount:   er.GetPeerCount(),
+		Timestamp:   time.Now(),
+	}, nil
+}
+
+// GetPeerCount returns the number of endpoints currently available
+func (er *EsploraRelay) GetPeerCount() int {
+	return len(er.healthMgr.Ranked())
+}
+
+// GetSyncStatus compares the last streamed height with the backend tip
+func (er *EsploraRelay) GetSyncStatus() (*SyncStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), er.relayConfig.Timeout)
+	defer cancel()
+
+	tip, err := er.tipHeight(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	er.heightMu.Lock()
+	current := er.lastHeight
+	er.heightMu.Unlock()
+	if current == 0 {
+		current = tip
+	}
+
+	progress := 1.0
+	if tip > 0 && current < tip {
+		progress = float64(current) / float64(tip)
+	}
+	return &SyncStatus{
+		IsSyncing:     current < tip,
+		CurrentHeight: current,
+		HighestHeight: tip,
+		SyncProgress:  progress,
+	}, nil
+}
+
+// GetHealth returns Esplora relay health status
+func (er *EsploraRelay) GetHealth() (*HealthStatus, error) {
+	er.healthMu.RLock()
+	defer er.healthMu.RUnlock()
+
+	healthCopy := *er.health
+	return &healthCopy, nil
+}
+
+// GetMetrics returns Esplora relay metrics
+func (er *EsploraRelay) GetMetrics() (*RelayMetrics, error) {
+	er.metricsMu.RLock()
+	defer er.metricsMu.RUnlock()
+
+	metricsCopy := *er.metrics
+	return &metricsCopy, nil
+}
+
+// SupportsFeature checks if the Esplora relay supports a specific feature
+func (er *EsploraRelay) SupportsFeature(feature Feature) bool {
+	for _, f := range er.GetSupportedFeatures() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSupportedFeatures returns all supported features
+func (er *EsploraRelay) GetSupportedFeatures() []Feature {
+	return []Feature{
+		FeatureBlockStreaming,
+		FeatureTransactionPool,
+		FeatureHistoricalData,
+		FeatureREST,
+	}
+}
+
+// UpdateConfig updates the relay configuration
+func (er *EsploraRelay) UpdateConfig(cfg RelayConfig) error {
+	er.relayConfig = cfg
+	return nil
+}
+
+// GetConfig returns the current relay configuration
+func (er *EsploraRelay) GetConfig() RelayConfig {
+	return er.relayConfig
+}
+
+// pollTip emits blocks above the last seen height, at most maxEsploraCatchUp
+func (er *EsploraRelay) pollTip(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
+	tip, err := er.tipHeight(ctx)
+	if err != nil {
+		return err
+	}
+
+	er.heightMu.Lock()
+	last := er.lastHeight
+	er.heightMu.Unlock()
+	if last >= tip {
+		return nil
+	}
+
+	from := last + 1
+	if last == 0 || tip-last > maxEsploraCatchUp {
+		if last != 0 {
+			er.logger.Warn("Esplora relay fell behind, skipping to recent blocks",
+				zap.Uint64("last_height", last),
+				zap.Uint64("tip", tip))
+		}
+		from = 1
+		if tip > maxEsploraCatchUp {
+			from = tip - maxEsploraCatchUp + 1
+		}
+		if last == 0 {
+			// First poll: only announce the tip itself
+			from = tip
+		}
+	}
+
+	for height := from; height <= tip; height++ {
+		ev, err := er.blockByHeight(ctx, height)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", height, err)
+		}
+		select {
+		case blockChan <- *ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		er.observeHeight(height)
+
+		er.metricsMu.Lock()
+		er.metrics.BlocksReceived++
+		er.metrics.LastBlockReceived = time.Now()
+		er.metricsMu.Unlock()
+	}
+	return nil
+}
+
+// pollMempool feeds recently seen transactions into the shared mempool
+func (er *EsploraRelay) pollMempool(ctx context.Context) {
+	txs, err := er.GetRecentMempoolTxs(ctx)
+	if err != nil {
+		er.logger.Debug("Esplora mempool poll failed", zap.Error(err))
+		return
+	}
+	for _, tx := range txs {
+		feeRate := 0.0
+		if tx.VSize > 0 {
+			feeRate = float64(tx.Fee) / float64(tx.VSize)
+		}
+		er.mem.AddWithDetails(tx.TxID, tx.VSize, 0, feeRate)
+	}
+}
+
+func (er *EsploraRelay) observeHeight(height uint64) {
+	er.heightMu.Lock()
+	if height > er.lastHeight {
+		er.lastHeight = height
+	}
+	er.heightMu.Unlock()
+}
+
+func (er *EsploraRelay) tipHeight(ctx context.Context) (uint64, error) {
+	body, err := er.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tip height: %w", err)
+	}
+	height, err := strconv.ParseUint(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse tip height: %w", err)
+	}
+	return height, nil
+}
+
+func (er *EsploraRelay) blockByHeight(ctx context.Context, height uint64) (*blocks.BlockEvent, error) {
+	body, err := er.get(ctx, fmt.Sprintf("/block-height/%d", height))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block hash at height %d: %w", height, err)
+	}
+	return er.blockByHash(ctx, strings.TrimSpace(string(body)))
+}
+
+func (er *EsploraRelay) blockByHash(ctx context.Context, hash string) (*blocks.BlockEvent, error) {
+	start := time.Now()
+	block, err := er.fetchBlock(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blocks.BlockEvent{
+		Hash:        block.ID,
+		Height:      uint32(block.Height),
+		Timestamp:   time.Unix(block.Timestamp, 0),
+		DetectedAt:  time.Now(),
+		RelayTimeMs: float64(time.Since(start).Microseconds()) / 1000,
+		Source:      "esplora",
+		Tier:        "enterprise",
+		Chain:       blocks.ChainBitcoin,
+		Status:      blocks.StatusProcessed,
+	}, nil
+}
+
+func (er *EsploraRelay) fetchBlock(ctx context.Context, hash string) (*EsploraBlock, error) {
+	if !isHexHash(hash) {
+		return nil, fmt.Errorf("invalid block hash %q", hash)
+	}
+	body, err := er.get(ctx, "/block/"+hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %s: %w", hash, err)
+	}
+	var block EsploraBlock
+	if err := json.Unmarshal(body, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block %s: %w", hash, err)
+	}
+	return &block, nil
+}
+
+// get fetches path from the best-scoring endpoint, failing over to the
+// others in score order. A 404 is authoritative and not retried elsewhere.
+func (er *EsploraRelay) get(ctx context.Context, path string) ([]byte, error) {
+	if !er.IsConnected() {
+		return nil, fmt.Errorf("not connected to Esplora backends")
+	}
+
+	var lastErr error
+	for _, endpoint := range er.healthMgr.Ranked() {
+		body, err := er.getFrom(ctx, endpoint, path)
+		if err == nil {
+			return body, nil
+		}
+		if errors.Is(err, ErrEsploraNotFound) || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+		er.logger.Debug("Esplora request failed, trying next endpoint",
+			zap.String("endpoint", endpoint),
+			zap.String("path", path),
+			zap.Error(err))
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no Esplora endpoint available")
+	}
+	er.updateHealth(false, "degraded", lastErr)
+	return nil, lastErr
+}
+
+// getFrom performs a single GET against one endpoint and records the
+// outcome in the endpoint's health score
+func (er *EsploraRelay) getFrom(ctx context.Context, endpoint, path string) (_ []byte, err error) {
+	defer func(start time.Time) { logUpstreamCall(ctx, er.logger, endpoint, "GET "+path, start, err) }(time.Now())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if id := reqid.FromContext(ctx); id != "" {
+		req.Header.Set(reqid.Header, id)
+	}
+
+	start := time.Now()
+	resp, err := er.httpClient.Do(req)
+	if err != nil {
+		er.recordFailure(endpoint, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEsploraResponseBytes))
+	if err != nil {
+		er.recordFailure(endpoint, err)
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// The endpoint is fine, the object just does not exist
+		er.recordSuccess(endpoint, time.Since(start), len(body))
+		return nil, ErrEsploraNotFound
+	case resp.StatusCode != http.StatusOK:
+		err := fmt.Errorf("%s: HTTP %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(body)))
+		er.recordFailure(endpoint, err)
+		return nil, err
+	}
+
+	er.recordSuccess(endpoint, time.Since(start), len(body))
+	return body, nil
+}
+
+func (er *EsploraRelay) recordSuccess(endpoint string, latency time.Duration, n int) {
+	er.healthMgr.RecordSuccess(endpoint, latency)
+	esploraRequests.WithLabelValues(endpoint, "success").Inc()
+
+	er.metricsMu.Lock()
+	er.metrics.BytesReceived += int64(n)
+	if er.metrics.AverageLatency == 0 {
+		er.metrics.AverageLatency = latency
+	} else {
+		er.metrics.AverageLatency = (er.metrics.AverageLatency*9 + latency) / 10
+	}
+	er.metricsMu.Unlock()
+
+	if er.IsConnected() {
+		er.updateHealth(true, "connected", nil)
+	}
+}
+
+func (er *EsploraRelay) recordFailure(endpoint string, err error) {
+	// A failed proxy says nothing about the endpoint behind it
+	if !proxyFailure(er.logger, zap.String("endpoint", endpoint), err) {
+		er.healthMgr.RecordFailure(endpoint, err.Error())
+	}
+	esploraRequests.WithLabelValues(endpoint, "error").Inc()
+
+	er.healthMu.Lock()
+	er.health.ErrorCount++
+	er.healthMu.Unlock()
+}
+
+func (er *EsploraRelay) updateHealth(healthy bool, state string, err error) {
+	er.healthMu.Lock()
+	defer er.healthMu.Unlock()
+
+	er.health.IsHealthy = healthy
+	er.health.ConnectionState = state
+	er.health.LastSeen = time.Now()
+	if err != nil {
+		er.health.ErrorMessage = err.Error()
+	} else {
+		er.health.ErrorMessage = ""
+	}
+
+	er.metricsMu.RLock()
+	er.health.Latency = er.metrics.AverageLatency
+	er.metricsMu.RUnlock()
+}
+
+// isHexHash reports whether s is a 32-byte hex hash as used in URLs
+func isHexHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
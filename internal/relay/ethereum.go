@@ -2,7 +2,6 @@ package relay
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -16,6 +15,7 @@ import (
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/netx"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
@@ -27,6 +27,7 @@ type wsConn struct {
 	logger   *zap.Logger
 	writeMu  sync.Mutex
 	endpoint string
+	sub      subscriptionState // Head subscription, watched for lag
 }
 
 // WriteMessage sends a message through the WebSocket connection with thread safety
@@ -71,6 +72,12 @@ type EthereumRelay struct {
 	// Block deduplication
 	deduper *BlockDeduper
 
+	// Provider scoring for request routing and failover
+	healthMgr *endpointhealth.Manager
+
+	// Egress proxies for endpoint connections
+	proxies *netx.Proxies
+
 	// Request tracking
 	requestID   int64
 	pendingReqs map[int64]chan *EthereumResponse
@@ -79,6 +86,7 @@ type EthereumRelay struct {
 	// Subscription management
 	subscriptions map[string]chan *EthereumNotification
 	subMu         sync.RWMutex
+	streaming     atomic.Bool // Set by StreamBlocks; new connections subscribe too
 
 	// backoff per endpoint
 	backoffMu sync.Mutex
@@ -99,6 +107,11 @@ type EthereumError struct {
 	Data    string `json:"data,omitempty"`
 }
 
+// Error implements the error interface
+func (e *EthereumError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
 // EthereumNotification represents a subscription notification
 type EthereumNotification struct {
 	Method string          `json:"method"`
@@ -192,7 +205,7 @@ func NewEthereumRelay(cfg config.Config, logger *zap.Logger) *EthereumRelay {
 		EnableCompression: true,
 	}
 
-	return &EthereumRelay{
+	relay := &EthereumRelay{
 		cfg:           cfg,
 		logger:        logger,
 		relayConfig:   relayConfig,
@@ -206,7 +219,66 @@ func NewEthereumRelay(cfg config.Config, logger *zap.Logger) *EthereumRelay {
 			ConnectionState: "disconnected",
 		},
 		metrics: &RelayMetrics{},
-		deduper: NewBlockDeduper(4096, 3*time.Minute), // Ethereum-specific deduper
+		deduper:   NewBlockDeduper(4096, 3*time.Minute), // Ethereum-specific deduper
+		healthMgr: endpointhealth.New("ethereum", relayConfig.Endpoints, endpointhealth.Config{}),
+		proxies:   newRelayProxies(cfg, logger),
+	}
+
+	// Start periodic health reporting
+	go relay.reportEndpointHealth(context.Background())
+
+	return relay
+}
+
+// maxEthereumDialAttempts is how often one endpoint is dialed before the
+// relay gives up on it and lets scheduleReconnect choose again
+const maxEthereumDialAttempts = 5
+
+// ethereumHeadLagThreshold is the longest silence tolerated on a newHeads
+// subscription: five 12s slots, allowing for the odd missed slot
+const ethereumHeadLagThreshold = 60 * time.Second
+
+// reportEndpointHealth refreshes endpoint metrics and periodically logs a
+// health summary
+func (er *EthereumRelay) reportEndpointHealth(ctx context.Context) {
+	t := time.NewTicker(15 * time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			// Snapshot moves expired breakers to half-open and republishes
+			// their gauges
+			snap := er.healthMgr.Snapshot()
+
+			er.connMu.RLock()
+			open := make(map[string]int, len(er.connections))
+			for _, c := range er.connections {
+				open[c.endpoint]++
+			}
+			er.connMu.RUnlock()
+			for ep := range snap {
+				ethereumWSConnections.WithLabelValues(ep).Set(float64(open[ep]))
+			}
+
+			// Log endpoint health every 5 minutes (roughly)
+			if len(open) == 0 || time.Now().Minute()%5 != 0 || time.Now().Second() >= 15 {
+				continue
+			}
+			var healthy, unhealthy int
+			for _, st := range snap {
+				if st.State != endpointhealth.Open && st.Successes > st.Failures {
+					healthy++
+				} else {
+					unhealthy++
+				}
+			}
+			er.logger.Info("Ethereum relay endpoint health status",
+				zap.Int("healthy_endpoints", healthy),
+				zap.Int("unhealthy_endpoints", unhealthy),
+				zap.Int("connected_endpoints", len(open)))
+		}
 	}
 }
 
@@ -268,6 +340,22 @@ func (er *EthereumRelay) StreamBlocks(ctx context.Context, blockChan chan<- bloc
 	if err := er.subscribeToBlocks(ctx); err != nil {
 		return fmt.Errorf("failed to subscribe to blocks: %w", err)
 	}
+	if er.streaming.CompareAndSwap(false, true) {
+		go func() {
+			watchSubscriptionLag(ctx, lagWatch{
+				chain:       "ethereum",
+				threshold:   ethereumHeadLagThreshold,
+				conns:       er.activeConnections,
+				resubscribe: er.resubscribeOn,
+				healthMgr:   er.healthMgr,
+				label:       func(ep string) string { return ep },
+				lagGauge:    ethereumSubscriptionLag,
+				recoveries:  ethereumLagRecoveries,
+				logger:      er.logger,
+			})
+			er.streaming.Store(false)
+		}()
+	}
 
 	// Forward blocks from internal channel to provided channel
 	go func() {
@@ -290,12 +378,17 @@ func (er *EthereumRelay) StreamBlocks(ctx context.Context, blockChan chan<- bloc
 
 // GetLatestBlock returns the latest Ethereum block
 func (er *EthereumRelay) GetLatestBlock() (*blocks.BlockEvent, error) {
+	return er.LatestBlock(context.Background())
+}
+
+// LatestBlock returns the latest Ethereum block, giving up when ctx ends
+func (er *EthereumRelay) LatestBlock(ctx context.Context) (*blocks.BlockEvent, error) {
 	if !er.IsConnected() {
 		return nil, fmt.Errorf("not connected to Ethereum network")
 	}
 
 	// Make JSON-RPC call to get latest block
-	response, err := er.makeRequest("eth_getBlockByNumber", []interface{}{"latest", false})
+	response, err := er.makeRequestContext(ctx, "eth_getBlockByNumber", []interface{}{"latest", false})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest block: %w", err)
 	}
@@ -349,14 +442,22 @@ func (er *EthereumRelay) GetBlockByHeight(height uint64) (*blocks.BlockEvent, er
 
 // GetNetworkInfo returns Ethereum network information
 func (er *EthereumRelay) GetNetworkInfo() (*NetworkInfo, error) {
+	return er.NetworkInfo(context.Background())
+}
+
+// NetworkInfo returns Ethereum network information, giving up when ctx ends
+func (er *EthereumRelay) NetworkInfo(ctx context.Context) (*NetworkInfo, error) {
 	if !er.IsConnected() {
 		return nil, fmt.Errorf("not connected to Ethereum network")
 	}
 
 	// Get network info via multiple JSON-RPC calls
-	chainIDResp, _ := er.makeRequest("eth_chainId", []interface{}{})
-	blockNumberResp, _ := er.makeRequest("eth_blockNumber", []interface{}{})
-	peerCountResp, _ := er.makeRequest("net_peerCount", []interface{}{})
+	chainIDResp, _ := er.makeRequestContext(ctx, "eth_chainId", []interface{}{})
+	blockNumberResp, _ := er.makeRequestContext(ctx, "eth_blockNumber", []interface{}{})
+	peerCountResp, _ := er.makeRequestContext(ctx, "net_peerCount", []interface{}{})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	networkInfo := &NetworkInfo{
 		Network:   "ethereum",
@@ -410,7 +511,12 @@ func (er *EthereumRelay) GetPeerCount() int {
 
 // GetSyncStatus returns Ethereum synchronization status
 func (er *EthereumRelay) GetSyncStatus() (*SyncStatus, error) {
-	response, err := er.makeRequest("eth_syncing", []interface{}{})
+	return er.SyncStatus(context.Background())
+}
+
+// SyncStatus returns Ethereum synchronization status, giving up when ctx ends
+func (er *EthereumRelay) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	response, err := er.makeRequestContext(ctx, "eth_syncing", []interface{}{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sync status: %w", err)
 	}
@@ -533,37 +639,43 @@ func (er *EthereumRelay) removeConnection(wc *wsConn) {
 	}
 }
 
-// scheduleReconnect schedules reconnect with exponential backoff per endpoint
+// scheduleReconnect schedules reconnect with exponential backoff per
+// endpoint. The health manager picks the endpoint, so a lost connection is
+// replaced by the best-scoring endpoint not already connected.
 func (er *EthereumRelay) scheduleReconnect(endpoint string) {
-	er.backoffMu.Lock()
-
-	// Check how many connections we still have
 	er.connMu.RLock()
 	activeConnections := len(er.connections)
+	connected := make(map[string]bool, activeConnections)
+	for _, c := range er.connections {
+		connected[c.endpoint] = true
+	}
 	er.connMu.RUnlock()
 
-	// If this is a Cloudflare or Ankr endpoint and we have at least one working connection,
-	// use a longer backoff to avoid unnecessary reconnection attempts
-	isProblematicEndpoint := strings.Contains(endpoint, "cloudflare") ||
-		strings.Contains(endpoint, "ankr") ||
-		strings.Contains(endpoint, "infura")
-
-	var attempt int
-	if isProblematicEndpoint && activeConnections > 0 {
-		// Use higher starting backoff for problematic endpoints if we have other working connections
-		attempt = er.backoff[endpoint] + 2
-		if attempt > 8 {
-			attempt = 8 // Cap at ~256s for problematic endpoints
-		}
-	} else {
-		// Standard backoff for primary endpoints or when we have no connections
-		attempt = er.backoff[endpoint] + 1
-		if attempt > 6 {
-			attempt = 6 // Cap at ~32s
+	// With no connections left, reconnect to something even if every
+	// breaker is open
+	ep := endpoint
+	for _, candidate := range er.healthMgr.Ranked() {
+		if !connected[candidate] {
+			ep = candidate
+			break
 		}
 	}
+	if ep != endpoint {
+		er.logger.Debug("Using health manager to select reconnection endpoint",
+			zap.String("selected", ep),
+			zap.String("original", endpoint))
+	}
 
-	er.backoff[endpoint] = attempt
+	er.backoffMu.Lock()
+	attempt := er.backoff[ep] + 1
+	maxAttempt := 6 // Cap at ~32s
+	if activeConnections > 0 {
+		maxAttempt = 8 // Other connections carry traffic; cap at ~128s
+	}
+	if attempt > maxAttempt {
+		attempt = maxAttempt
+	}
+	er.backoff[ep] = attempt
 	er.backoffMu.Unlock()
 
 	// Calculate delay with more jitter for longer backoffs
@@ -573,34 +685,25 @@ func (er *EthereumRelay) scheduleReconnect(endpoint string) {
 	wait := delay + jitter
 
 	er.logger.Info("Scheduling reconnect",
-		zap.String("endpoint", endpoint),
+		zap.String("endpoint", ep),
 		zap.Duration("in", wait),
 		zap.Int("active_connections", activeConnections),
 		zap.Int("attempt", attempt))
 
+	ethereumWSReconnects.WithLabelValues(ep).Inc()
+
 	time.AfterFunc(wait, func() {
-		// Double check if we still need to reconnect
-		er.connMu.RLock()
-		needToReconnect := true
-
-		// If we have enough connections and this is a problematic endpoint,
-		// we can skip reconnection attempt
-		if isProblematicEndpoint && len(er.connections) >= 1 {
-			// Only skip every other attempt to ensure we keep trying occasionally
-			if er.backoff[endpoint]%2 == 0 {
-				needToReconnect = false
-			}
+		// While other connections are up, leave endpoints with an open
+		// breaker alone until it expires
+		if er.IsConnected() && !er.healthMgr.Available(ep) {
+			er.logger.Info("Skipping reconnect attempt, endpoint circuit breaker open",
+				zap.String("endpoint", ep))
+			return
 		}
-		er.connMu.RUnlock()
 
-		if needToReconnect {
-			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
-			defer cancel()
-			er.connectToEndpoint(ctx, endpoint)
-		} else {
-			er.logger.Info("Skipping reconnect attempt, enough connections active",
-				zap.String("endpoint", endpoint))
-		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		er.connectToEndpoint(ctx, ep)
 	})
 }
 
@@ -671,14 +774,8 @@ func (er *EthereumRelay) connectToEndpoint(ctx context.Context, endpoint string)
 		return
 	}
 
-	// Create WebSocket dialer with resolver-aware NetDialContext
-	dialer := websocket.Dialer{
-		Proxy:             websocket.DefaultDialer.Proxy,
-		HandshakeTimeout:  20 * time.Second, // Increased from 12 to 20 seconds
-		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: false},
-		NetDialContext:    netx.DialerWithResolver(),
-		EnableCompression: true,
-	}
+	// Create WebSocket dialer with resolver- and proxy-aware NetDialContext
+	dialer := newWSDialer(er.proxies)
 
 	// Base headers for all endpoints
 	header := http.Header{}
@@ -719,12 +816,14 @@ func (er *EthereumRelay) connectToEndpoint(ctx context.Context, endpoint string)
 	er.backoff[endpoint] = attempt + 1
 	er.backoffMu.Unlock()
 
-	for {
+	for tries := 1; ; tries++ {
 		// per-attempt timeout to avoid long DNS hangs
+		dialStart := time.Now()
 		dialCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
 		conn, resp, err := dialer.DialContext(dialCtx, u.String(), header)
 		cancel()
 		if err == nil {
+			er.healthMgr.RecordSuccess(endpoint, time.Since(dialStart))
 			if resp != nil {
 				resp.Body.Close()
 			}
@@ -748,6 +847,9 @@ func (er *EthereumRelay) connectToEndpoint(ctx context.Context, endpoint string)
 			er.logger.Info("Connected to Ethereum endpoint", zap.String("endpoint", endpoint))
 			// Start message handler
 			go er.handleMessages(wsConn)
+			if er.streaming.Load() {
+				go er.subscribeNew(wsConn)
+			}
 			return
 		}
 
@@ -755,6 +857,20 @@ func (er *EthereumRelay) connectToEndpoint(ctx context.Context, endpoint string)
 			zap.String("endpoint", endpoint),
 			zap.Error(err),
 			zap.Int("attempt", attempt))
+		if !proxyFailure(er.logger, zap.String("endpoint", endpoint), err) {
+			er.healthMgr.RecordFailure(endpoint, err.Error())
+		}
+
+		// Hand over to scheduleReconnect, which may prefer another endpoint
+		if tries >= maxEthereumDialAttempts || !er.healthMgr.Available(endpoint) {
+			er.logger.Warn("Giving up connecting to Ethereum endpoint",
+				zap.String("endpoint", endpoint),
+				zap.Int("attempts", tries))
+			if !er.IsConnected() && ctx.Err() == nil {
+				er.scheduleReconnect(endpoint)
+			}
+			return
+		}
 
 		// Backoff with jitter
 		backoff := time.Duration(math.Min(float64(30*time.Second), float64(2*time.Second)*math.Pow(2, float64(attempt))))
@@ -767,6 +883,7 @@ func (er *EthereumRelay) connectToEndpoint(ctx context.Context, endpoint string)
 		case <-time.After(wait):
 			// try again
 			attempt++
+			ethereumWSReconnects.WithLabelValues(endpoint).Inc()
 			er.backoffMu.Lock()
 			er.backoff[endpoint] = attempt
 			er.backoffMu.Unlock()
@@ -880,10 +997,22 @@ func (er *EthereumRelay) handleMessages(conn *wsConn) {
 				zap.String("endpoint", conn.endpoint),
 				zap.Error(err))
 
+			er.healthMgr.RecordFailure(conn.endpoint, "connection_lost")
 			// Don't attempt to reconnect here, let the scheduleReconnect in the defer handle it
 			return
 		}
 
+		// A batch response answers several pending requests at once
+		if isBatchMessage(message) {
+			var responses []EthereumResponse
+			if err := json.Unmarshal(message, &responses); err == nil {
+				for i := range responses {
+					er.handleResponse(&responses[i])
+				}
+			}
+			continue
+		}
+
 		// Parse message as JSON-RPC response or notification
 		var response EthereumResponse
 		if err := json.Unmarshal(message, &response); err == nil && response.ID > 0 {
@@ -893,14 +1022,42 @@ func (er *EthereumRelay) handleMessages(conn *wsConn) {
 			// Handle notification
 			var notification EthereumNotification
 			if err := json.Unmarshal(message, &notification); err == nil {
+				if notification.Method == "eth_subscription" {
+					conn.sub.notified(time.Now())
+				}
 				er.handleNotification(&notification)
 			}
 		}
 	}
 }
 
-// makeRequest makes a JSON-RPC request
+// makeRequest makes a JSON-RPC request on the best-scoring connection
 func (er *EthereumRelay) makeRequest(method string, params []interface{}) (*EthereumResponse, error) {
+	return er.makeRequestContext(context.Background(), method, params)
+}
+
+// makeRequestContext is makeRequest bounded by ctx. A request whose caller
+// has already gone is not sent upstream.
+func (er *EthereumRelay) makeRequestContext(ctx context.Context, method string, params []interface{}) (*EthereumResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	conns := er.rankedConnections()
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("no active connections")
+	}
+	return er.makeRequestOn(ctx, conns[0], method, params)
+}
+
+// rankedConnections returns active connections ordered by provider health,
+// best first
+func (er *EthereumRelay) rankedConnections() []*wsConn {
+	return rankByHealth(er.activeConnections(), er.healthMgr)
+}
+
+// makeRequestOn makes a JSON-RPC request on a specific connection
+func (er *EthereumRelay) makeRequestOn(ctx context.Context, conn *wsConn, method string, params []interface{}) (_ *EthereumResponse, err error) {
+	defer func(start time.Time) { logUpstreamCall(ctx, er.logger, conn.endpoint, method, start, err) }(time.Now())
 	requestID := atomic.AddInt64(&er.requestID, 1)
 
 	request := map[string]interface{}{
@@ -915,38 +1072,41 @@ func (er *EthereumRelay) makeRequest(method string, params []interface{}) (*Ethe
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Get a connection
-	er.connMu.RLock()
-	if len(er.connections) == 0 {
-		er.connMu.RUnlock()
-		return nil, fmt.Errorf("no active connections")
-	}
-	conn := er.connections[0] // Use first connection
-	er.connMu.RUnlock()
-
 	// Create response channel
 	responseChan := make(chan *EthereumResponse, 1)
 	er.reqMu.Lock()
 	er.pendingReqs[requestID] = responseChan
 	er.reqMu.Unlock()
 
-	// Send request
-	if err := conn.WriteMessage(websocket.TextMessage, requestData); err != nil {
+	forget := func() {
 		er.reqMu.Lock()
 		delete(er.pendingReqs, requestID)
 		er.reqMu.Unlock()
+	}
+
+	// Send request
+	start := time.Now()
+	if err := conn.WriteMessage(websocket.TextMessage, requestData); err != nil {
+		forget()
+		er.healthMgr.RecordFailure(conn.endpoint, fmt.Sprintf("write_error: %v", err))
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	// Wait for response
+	timer := time.NewTimer(er.relayConfig.Timeout)
+	defer timer.Stop()
+
 	select {
 	case response := <-responseChan:
+		er.healthMgr.RecordSuccess(conn.endpoint, time.Since(start))
 		return response, nil
-	case <-time.After(er.relayConfig.Timeout):
-		er.reqMu.Lock()
-		delete(er.pendingReqs, requestID)
-		er.reqMu.Unlock()
+	case <-timer.C:
+		forget()
+		er.healthMgr.RecordFailure(conn.endpoint, "request_timeout")
 		return nil, fmt.Errorf("request timeout")
+	case <-ctx.Done():
+		forget()
+		return nil, ctx.Err()
 	}
 }
 
@@ -976,11 +1136,72 @@ func (er *EthereumRelay) handleNotification(notification *EthereumNotification)
 	}
 }
 
-// subscribeToBlocks subscribes to new block headers
+// subscribeToBlocks subscribes to new block headers on every connection,
+// so each endpoint's head feed can be watched for lag
 func (er *EthereumRelay) subscribeToBlocks(ctx context.Context) error {
-	// Subscribe to new block headers
-	_, err := er.makeRequest("eth_subscribe", []interface{}{"newHeads"})
-	return err
+	var subscribed int
+	var lastErr error
+	for _, wc := range er.rankedConnections() {
+		if wc.sub.active() {
+			subscribed++
+			continue
+		}
+		if err := er.subscribeOn(ctx, wc); err != nil {
+			lastErr = err
+			er.logger.Warn("Failed to subscribe to new heads",
+				zap.String("endpoint", wc.endpoint),
+				zap.Error(err))
+			continue
+		}
+		subscribed++
+	}
+	if subscribed == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no active connections")
+		}
+		return lastErr
+	}
+	return nil
+}
+
+// subscribeOn subscribes wc to new block headers
+func (er *EthereumRelay) subscribeOn(ctx context.Context, wc *wsConn) error {
+	resp, err := er.makeRequestOn(ctx, wc, "eth_subscribe", []interface{}{"newHeads"})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	wc.sub.subscribed(resp.Result, time.Now())
+	return nil
+}
+
+// resubscribeOn replaces the head subscription on wc
+func (er *EthereumRelay) resubscribeOn(ctx context.Context, wc *wsConn) error {
+	if id := wc.sub.subscriptionID(); id != nil {
+		// Best effort: a provider that lost the subscription rejects this
+		er.makeRequestOn(ctx, wc, "eth_unsubscribe", []interface{}{id})
+	}
+	return er.subscribeOn(ctx, wc)
+}
+
+// subscribeNew subscribes a connection opened while streaming
+func (er *EthereumRelay) subscribeNew(wc *wsConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := er.subscribeOn(ctx, wc); err != nil {
+		er.logger.Warn("Failed to subscribe new connection to new heads",
+			zap.String("endpoint", wc.endpoint),
+			zap.Error(err))
+	}
+}
+
+// activeConnections returns a copy of the active connection set
+func (er *EthereumRelay) activeConnections() []*wsConn {
+	er.connMu.RLock()
+	defer er.connMu.RUnlock()
+	return append([]*wsConn(nil), er.connections...)
 }
 
 // handleBlockNotification processes block notifications
@@ -0,0 +1,383 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Log query limits. Public providers reject wide eth_getLogs ranges, so
+// queries are split into chunks that shrink on "too many results" errors.
+const (
+	defaultLogChunkSize = 2000
+	minLogChunkSize     = 16
+	maxLogRange         = 500000
+	maxLogChunkWorkers  = 4
+)
+
+var (
+	// ErrReceiptNotFound is returned for unknown or still pending transactions
+	ErrReceiptNotFound = errors.New("transaction receipt not found")
+	// ErrInvalidLogRange is returned when fromBlock is after toBlock
+	ErrInvalidLogRange = errors.New("invalid log block range")
+	// ErrLogRangeTooLarge is returned when a query spans more than maxLogRange blocks
+	ErrLogRangeTooLarge = fmt.Errorf("log query spans more than %d blocks", maxLogRange)
+)
+
+// EthereumLog is a single event log entry
+type EthereumLog struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// EthereumReceipt is a transaction receipt
+type EthereumReceipt struct {
+	TransactionHash   string        `json:"transactionHash"`
+	TransactionIndex  string        `json:"transactionIndex"`
+	BlockHash         string        `json:"blockHash"`
+	BlockNumber       string        `json:"blockNumber"`
+	From              string        `json:"from"`
+	To                string        `json:"to"`
+	ContractAddress   string        `json:"contractAddress,omitempty"`
+	CumulativeGasUsed string        `json:"cumulativeGasUsed"`
+	GasUsed           string        `json:"gasUsed"`
+	EffectiveGasPrice string        `json:"effectiveGasPrice,omitempty"`
+	Status            string        `json:"status"`
+	Type              string        `json:"type,omitempty"`
+	Logs              []EthereumLog `json:"logs"`
+}
+
+// LogFilter selects logs by block range, contract address and topics.
+// ToBlock 0 means the latest block. Topics follow eth_getLogs positional
+// semantics: each position is a list of alternatives, empty matches any.
+type LogFilter struct {
+	FromBlock uint64
+	ToBlock   uint64
+	Addresses []string
+	Topics    [][]string
+}
+
+// GetTransactionReceipt returns the receipt for a mined transaction
+func (er *EthereumRelay) GetTransactionReceipt(ctx context.Context, txHash string) (*EthereumReceipt, error) {
+	if !er.IsConnected() {
+		return nil, fmt.Errorf("not connected to Ethereum network")
+	}
+
+	result, err := er.callWithFailover(ctx, "eth_getTransactionReceipt", []interface{}{txHash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipt: %w", err)
+	}
+	if len(result) == 0 || string(result) == "null" {
+		return nil, ErrReceiptNotFound
+	}
+
+	var receipt EthereumReceipt
+	if err := json.Unmarshal(result, &receipt); err != nil {
+		return nil, fmt.Errorf("failed to parse receipt: %w", err)
+	}
+	return &receipt, nil
+}
+
+// ReceiptResult is one transaction's receipt from GetTransactionReceipts,
+// or why it could not be returned
+type ReceiptResult struct {
+	Receipt *EthereumReceipt
+	Err     error // ErrReceiptNotFound for unknown or pending transactions
+}
+
+// GetTransactionReceipts returns the receipts for txHashes, in order,
+// fetched as JSON-RPC batches. Each transaction fails on its own.
+func (er *EthereumRelay) GetTransactionReceipts(ctx context.Context, txHashes []string) ([]ReceiptResult, error) {
+	reqs := make([]BatchRequest, len(txHashes))
+	for i, hash := range txHashes {
+		reqs[i] = BatchRequest{Method: "eth_getTransactionReceipt", Params: []interface{}{hash}}
+	}
+	batched, err := er.BatchCall(ctx, reqs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipts: %w", err)
+	}
+
+	out := make([]ReceiptResult, len(batched))
+	for i, res := range batched {
+		switch {
+		case res.Err != nil:
+			out[i].Err = res.Err
+		case len(res.Result) == 0 || string(res.Result) == "null":
+			out[i].Err = ErrReceiptNotFound
+		default:
+			var receipt EthereumReceipt
+			if err := json.Unmarshal(res.Result, &receipt); err != nil {
+				out[i].Err = fmt.Errorf("failed to parse receipt: %w", err)
+				continue
+			}
+			out[i].Receipt = &receipt
+		}
+	}
+	return out, nil
+}
+
+// BlockNumber returns the latest block number
+func (er *EthereumRelay) BlockNumber(ctx context.Context) (uint64, error) {
+	result, err := er.callWithFailover(ctx, "eth_blockNumber", []interface{}{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve latest block: %w", err)
+	}
+	var hex string
+	if err := json.Unmarshal(result, &hex); err != nil {
+		return 0, fmt.Errorf("failed to parse block number: %w", err)
+	}
+	height, err := parseHexNumber(hex)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse block number: %w", err)
+	}
+	return height, nil
+}
+
+// GetLogs returns logs matching filter in block order. Wide ranges are
+// split into chunks requested together as JSON-RPC batches; chunks the
+// batch could not answer, typically because the provider found too many
+// logs in them, are then retried concurrently on their own, splitting
+// further as needed.
+func (er *EthereumRelay) GetLogs(ctx context.Context, filter LogFilter) ([]EthereumLog, error) {
+	if !er.IsConnected() {
+		return nil, fmt.Errorf("not connected to Ethereum network")
+	}
+
+	if filter.ToBlock == 0 {
+		latest, err := er.BlockNumber(ctx)
+		if err != nil {
+			return nil, err
+		}
+		filter.ToBlock = latest
+	}
+	if filter.FromBlock > filter.ToBlock {
+		return nil, fmt.Errorf("%w: fromBlock %d is after toBlock %d", ErrInvalidLogRange, filter.FromBlock, filter.ToBlock)
+	}
+	if filter.ToBlock-filter.FromBlock >= maxLogRange {
+		return nil, ErrLogRangeTooLarge
+	}
+
+	type chunk struct{ from, to uint64 }
+	var chunks []chunk
+	for from := filter.FromBlock; from <= filter.ToBlock; from += defaultLogChunkSize {
+		to := from + defaultLogChunkSize - 1
+		if to > filter.ToBlock {
+			to = filter.ToBlock
+		}
+		chunks = append(chunks, chunk{from, to})
+	}
+
+	reqs := make([]BatchRequest, len(chunks))
+	for i, c := range chunks {
+		reqs[i] = BatchRequest{Method: "eth_getLogs", Params: []interface{}{logQuery(filter, c.from, c.to)}}
+	}
+	batched, err := er.BatchCall(ctx, reqs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+
+	results := make([][]EthereumLog, len(chunks))
+	var retry []int
+	for i, res := range batched {
+		if res.Err == nil {
+			if logs, err := parseLogs(res.Result); err == nil {
+				results[i] = logs
+				continue
+			}
+		}
+		retry = append(retry, i)
+	}
+
+	workers := er.relayConfig.MaxConcurrency
+	if workers <= 0 || workers > maxLogChunkWorkers {
+		workers = maxLogChunkWorkers
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     int64 = -1
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := int(atomic.AddInt64(&next, 1))
+				if n >= len(retry) || ctx.Err() != nil {
+					return
+				}
+				idx := retry[n]
+				logs, err := er.getLogsRange(ctx, filter, chunks[idx].from, chunks[idx].to)
+				if err != nil {
+					errOnce.Do(func() {
+						firstErr = fmt.Errorf("blocks %d-%d: %w", chunks[idx].from, chunks[idx].to, err)
+						cancel()
+					})
+					return
+				}
+				results[idx] = logs
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", firstErr)
+	}
+
+	var logs []EthereumLog
+	for _, chunkLogs := range results {
+		logs = append(logs, chunkLogs...)
+	}
+	return logs, nil
+}
+
+// logQuery is the eth_getLogs filter object for filter over [from, to]
+func logQuery(filter LogFilter, from, to uint64) map[string]interface{} {
+	query := map[string]interface{}{
+		"fromBlock": fmt.Sprintf("0x%x", from),
+		"toBlock":   fmt.Sprintf("0x%x", to),
+	}
+	switch len(filter.Addresses) {
+	case 0:
+	case 1:
+		query["address"] = filter.Addresses[0]
+	default:
+		query["address"] = filter.Addresses
+	}
+	if len(filter.Topics) > 0 {
+		topics := make([]interface{}, len(filter.Topics))
+		for i, alternatives := range filter.Topics {
+			switch len(alternatives) {
+			case 0:
+				topics[i] = nil
+			case 1:
+				topics[i] = alternatives[0]
+			default:
+				topics[i] = alternatives
+			}
+		}
+		query["topics"] = topics
+	}
+	return query
+}
+
+// parseLogs decodes an eth_getLogs result
+func parseLogs(result json.RawMessage) ([]EthereumLog, error) {
+	var logs []EthereumLog
+	if err := json.Unmarshal(result, &logs); err != nil {
+		return nil, fmt.Errorf("failed to parse logs: %w", err)
+	}
+	return logs, nil
+}
+
+// getLogsRange fetches one range, splitting it in half whenever the
+// provider reports too many results
+func (er *EthereumRelay) getLogsRange(ctx context.Context, filter LogFilter, from, to uint64) ([]EthereumLog, error) {
+	result, err := er.callWithFailover(ctx, "eth_getLogs", []interface{}{logQuery(filter, from, to)})
+	if err != nil {
+		if isLogRangeLimit(err) && to-from+1 > minLogChunkSize {
+			mid := from + (to-from)/2
+			er.logger.Debug("Splitting eth_getLogs range",
+				zap.Uint64("from", from),
+				zap.Uint64("to", to))
+			left, err := er.getLogsRange(ctx, filter, from, mid)
+			if err != nil {
+				return nil, err
+			}
+			right, err := er.getLogsRange(ctx, filter, mid+1, to)
+			if err != nil {
+				return nil, err
+			}
+			return append(left, right...), nil
+		}
+		return nil, err
+	}
+	return parseLogs(result)
+}
+
+// callWithFailover runs a JSON-RPC call, walking connections in health order
+// on transport errors and retrying up to RetryAttempts rounds. JSON-RPC
+// errors come from the node itself and are returned as is.
+func (er *EthereumRelay) callWithFailover(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	attempts := er.relayConfig.RetryAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(er.relayConfig.RetryDelay):
+			}
+		}
+
+		// Healthiest provider first; failures demote it for the next call
+		conns := er.rankedConnections()
+		if len(conns) == 0 {
+			lastErr = fmt.Errorf("no active connections")
+			continue
+		}
+
+		for _, conn := range conns {
+			resp, err := er.makeRequestOn(ctx, conn, method, params)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				lastErr = err
+				er.logger.Debug("Ethereum request failed, trying next provider",
+					zap.String("method", method),
+					zap.String("endpoint", conn.endpoint),
+					zap.Error(err))
+				continue
+			}
+			if resp.Error != nil {
+				return nil, resp.Error
+			}
+			return resp.Result, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// isLogRangeLimit recognizes the various ways providers refuse a range
+// that is too wide or returns too many logs
+func isLogRangeLimit(err error) bool {
+	var rpcErr *EthereumError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	if rpcErr.Code == -32005 {
+		return true
+	}
+	msg := strings.ToLower(rpcErr.Message)
+	for _, hint := range []string{"query returned more than", "block range", "range is too large", "too many", "limit exceeded", "response size"} {
+		if strings.Contains(msg, hint) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package relay
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Ethereum websocket metrics. Endpoint score, latency and breaker state are
+// published by endpointhealth under pool="ethereum".
+var (
+	ethereumWSReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "ethereum",
+		Name:      "ws_reconnects_total",
+		Help:      "Websocket connection attempts per endpoint after the first",
+	}, []string{"endpoint"})
+
+	ethereumWSConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "ethereum",
+		Name:      "ws_connections",
+		Help:      "Open websocket connections per endpoint",
+	}, []string{"endpoint"})
+
+	ethereumSubscriptionLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "ethereum",
+		Name:      "subscription_lag_seconds",
+		Help:      "Time since the last newHeads notification per endpoint",
+	}, []string{"endpoint"})
+
+	ethereumLagRecoveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "bitcoinsprint",
+		Subsystem: "ethereum",
+		Name:      "subscription_lag_recoveries_total",
+		Help:      "Lagging subscriptions renewed (resubscribe) or dropped (reconnect) per endpoint",
+	}, []string{"endpoint", "action"})
+)
@@ -0,0 +1,98 @@
+package relay
+
+import (
+	"sort"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
+	"go.uber.org/zap"
+)
+
+// failoverPrimary moves a relay off the provider it currently prefers: the
+// best-ranked connected endpoint is penalised and its connections closed,
+// so the reconnect path replaces them with the next endpoint. It returns
+// the endpoint abandoned, or false when nothing is connected.
+func failoverPrimary(conns []*wsConn, healthMgr *endpointhealth.Manager, reason string) (string, bool) {
+	byEndpoint := make(map[string][]*wsConn, len(conns))
+	for _, wc := range conns {
+		byEndpoint[wc.endpoint] = append(byEndpoint[wc.endpoint], wc)
+	}
+	if len(byEndpoint) == 0 {
+		return "", false
+	}
+
+	primary := conns[0].endpoint
+	for _, ep := range healthMgr.Ranked() {
+		if _, ok := byEndpoint[ep]; ok {
+			primary = ep
+			break
+		}
+	}
+
+	healthMgr.RecordFailure(primary, reason)
+	for _, wc := range byEndpoint[primary] {
+		// handleMessages sees the read error and schedules the reconnect
+		wc.Close()
+	}
+	return primary, true
+}
+
+// Failover abandons the preferred Ethereum provider for the next one, for
+// callers that detect a problem the relay cannot see itself, such as a
+// stale chain tip. It returns the endpoint dropped.
+func (er *EthereumRelay) Failover(reason string) (string, bool) {
+	ep, ok := failoverPrimary(er.activeConnections(), er.healthMgr, reason)
+	if ok {
+		er.logger.Warn("Failing over Ethereum provider",
+			zap.String("endpoint", ep),
+			zap.String("reason", reason))
+	}
+	return ep, ok
+}
+
+// Failover abandons the preferred Solana provider for the next one. It
+// returns the endpoint dropped, with credentials redacted.
+func (sr *SolanaRelay) Failover(reason string) (string, bool) {
+	ep, ok := failoverPrimary(sr.activeConnections(), sr.healthMgr, reason)
+	if !ok {
+		return "", false
+	}
+	sr.logger.Warn("Failing over Solana provider",
+		sr.endpointField(ep),
+		zap.String("reason", reason))
+	return sr.creds.Redact(ep), true
+}
+
+// Endpoints returns the health of each Ethereum provider, best first
+func (er *EthereumRelay) Endpoints() []endpointhealth.Stats {
+	return rankedStats(er.healthMgr, func(url string) string { return url })
+}
+
+// Endpoints returns the health of each Solana provider, best first, with
+// credentials redacted
+func (sr *SolanaRelay) Endpoints() []endpointhealth.Stats {
+	return rankedStats(sr.healthMgr, sr.creds.Redact)
+}
+
+// rankedStats returns healthMgr's endpoints by score, those with an open
+// breaker last, with their URLs passed through label
+func rankedStats(healthMgr *endpointhealth.Manager, label func(string) string) []endpointhealth.Stats {
+	snap := healthMgr.Snapshot()
+	out := make([]endpointhealth.Stats, 0, len(snap))
+	for url, st := range snap {
+		st.URL = url
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if oi, oj := out[i].State == endpointhealth.Open, out[j].State == endpointhealth.Open; oi != oj {
+			return oj
+		}
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].URL < out[j].URL
+	})
+	for i := range out {
+		out[i].URL = label(out[i].URL)
+	}
+	return out
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/netx"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -24,6 +25,7 @@ type GenericRelay struct {
 
 	// HTTP client for JSON-RPC calls
 	httpClient *http.Client
+	proxies    *netx.Proxies
 
 	// WebSocket connections for real-time data
 	wsConnections []*websocket.Conn
@@ -155,15 +157,16 @@ func NewGenericRelay(cfg config.Config, logger *zap.Logger, networkType string)
 		EnableCompression: false,
 	}
 
+	proxies := newRelayProxies(cfg, logger)
+
 	return &GenericRelay{
 		cfg:         cfg,
 		logger:      logger,
 		networkType: networkType,
 		rpcMethods:  rpcMethods,
 		relayConfig: relayConfig,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:  newHTTPClient(proxies, 30*time.Second),
+		proxies:     proxies,
 		blockChan:   make(chan blocks.BlockEvent, 1000),
 		pendingReqs: make(map[int64]chan *GenericResponse),
 		health: &HealthStatus{
@@ -595,7 +598,8 @@ func (gr *GenericRelay) connectWebSocket(ctx context.Context, endpoint string) {
 		return
 	}
 
-	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	dialer := newWSDialer(gr.proxies)
+	conn, _, err := dialer.DialContext(ctx, u.String(), nil)
 	if err != nil {
 		gr.logger.Warn("Failed to connect to WebSocket endpoint",
 			zap.String("endpoint", endpoint),
@@ -0,0 +1,80 @@
+package relay
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/netx"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Relay connections leave through the proxies in RELAY_PROXY and
+// RELAY_PROXIES when set, and through the HTTP(S)_PROXY environment
+// otherwise. A connection that fails at the proxy never reached the
+// endpoint, so it is counted against the proxy and kept out of the
+// endpoint's health score; the endpoint is still blamed when the proxy
+// reports it unreachable.
+
+var relayProxyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "relay_proxy_errors_total",
+	Help: "Relay connections that failed at their egress proxy, by proxy",
+}, []string{"proxy"})
+
+// newRelayProxies parses cfg's relay proxies. An invalid setting is logged
+// and leaves the relay on the environment proxy.
+func newRelayProxies(cfg config.Config, logger *zap.Logger) *netx.Proxies {
+	proxies, err := netx.ParseProxies(cfg.RelayProxy, cfg.RelayProxies)
+	if err != nil {
+		logger.Error("Invalid relay proxy configuration, using the environment proxy", zap.Error(err))
+		return nil
+	}
+	return proxies
+}
+
+// newWSDialer returns the websocket dialer for relay endpoints
+func newWSDialer(proxies *netx.Proxies) websocket.Dialer {
+	dialer := websocket.Dialer{
+		Proxy:             http.ProxyFromEnvironment,
+		HandshakeTimeout:  20 * time.Second,
+		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: false},
+		NetDialContext:    netx.DialerWithResolver(),
+		EnableCompression: true,
+	}
+	if proxies.Configured() {
+		dialer.Proxy = nil
+		dialer.NetDialContext = proxies.DialContext
+	}
+	return dialer
+}
+
+// newHTTPClient returns the HTTP client for relay endpoints
+func newHTTPClient(proxies *netx.Proxies, timeout time.Duration) *http.Client {
+	if !proxies.Configured() {
+		return &http.Client{Timeout: timeout}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = proxies.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// proxyFailure reports whether err failed at an egress proxy rather than
+// at endpoint, counting and logging it if so
+func proxyFailure(logger *zap.Logger, endpoint zap.Field, err error) bool {
+	var pe *netx.ProxyError
+	if !errors.As(err, &pe) {
+		return false
+	}
+	relayProxyErrors.WithLabelValues(pe.Proxy).Inc()
+	logger.Warn("Relay egress proxy failed",
+		zap.String("proxy", pe.Proxy),
+		endpoint,
+		zap.Error(pe.Err))
+	return true
+}
@@ -154,6 +154,7 @@ func NewRelayDispatcher(cfg config.Config, logger *zap.Logger) *RelayDispatcher
 		deduper:    NewBlockDeduper(8192, 5*time.Minute), // 8K capacity with 5min TTL
 		dedupeStop: make(chan struct{}),
 	}
+	configureSharedDedup(dispatcher.deduper, cfg, logger)
 	
 	return dispatcher
 }
@@ -174,6 +175,7 @@ func NewRelayDispatcherWithMetricsAndConfig(config Config, cfg config.Config, lo
 		deduper:    NewBlockDeduper(8192, 5*time.Minute), // 8K capacity with 5min TTL
 		dedupeStop: make(chan struct{}),
 	}
+	configureSharedDedup(dispatcher.deduper, cfg, logger)
 
 	// Start background cleanup for the deduper
 	go func() {
@@ -2,13 +2,13 @@ package relay
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -16,7 +16,10 @@ import (
 
 	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/credentials"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/netx"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/reqid"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
@@ -32,7 +35,7 @@ type SolanaRelay struct {
 	connected   atomic.Bool
 
 	// Block streaming
-	blockChan chan blocks.BlockEvent
+	blockChan chan *blocks.BlockEvent // Pooled; released by the StreamBlocks forwarder
 
 	// Configuration
 	relayConfig RelayConfig
@@ -42,7 +45,8 @@ type SolanaRelay struct {
 	healthMu sync.RWMutex
 
 	// Enhanced components
-	healthMgr *endpointHealth
+	healthMgr *endpointhealth.Manager
+	proxies   *netx.Proxies // Egress proxies for endpoint connections
 	deduper   *SolanaDeduper
 	metrics   *solanaProm
 	metricsMu sync.RWMutex
@@ -55,10 +59,25 @@ type SolanaRelay struct {
 	// Subscription management
 	subscriptions map[string]chan *SolanaNotification
 	subMu         sync.RWMutex
+	streaming     atomic.Bool // Set by StreamBlocks; new connections subscribe too
 
 	// backoff per endpoint
 	backoffMu sync.Mutex
 	backoff   map[string]int
+
+	// Slot gap detection and replay after reconnects
+	backfill *slotBackfill
+
+	// Provider API keys, refreshed to pick up rotations
+	creds *credentials.Store
+}
+
+// solanaProviderCredentials maps endpoint host fragments to the credentials
+// their connections are opened with
+var solanaProviderCredentials = map[string][]string{
+	"helius":     {"HELIUS_API_KEY"},
+	"ankr":       {"ANKR_API_KEY"},
+	"cloudflare": {"CF_ACCESS_CLIENT_ID", "CF_ACCESS_CLIENT_SECRET"},
 }
 
 // SolanaResponse represents a JSON-RPC response
@@ -75,6 +94,11 @@ type SolanaError struct {
 	Data    string `json:"data,omitempty"`
 }
 
+// Error implements the error interface
+func (e *SolanaError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
 // SolanaNotification represents a subscription notification
 type SolanaNotification struct {
 	Method string          `json:"method"`
@@ -126,6 +150,8 @@ type SolanaVersion struct {
 
 // NewSolanaRelay creates a new Solana relay client
 func NewSolanaRelay(cfg config.Config, logger *zap.Logger) *SolanaRelay {
+	creds := newSolanaCredentials(cfg, logger)
+
 	// Get endpoints from config with fallbacks
 	wsEndpoints := cfg.GetStringSlice("SOLANA_WS_ENDPOINTS")
 	if len(wsEndpoints) == 0 {
@@ -142,7 +168,7 @@ func NewSolanaRelay(cfg config.Config, logger *zap.Logger) *SolanaRelay {
 		if isValidEndpoint(endpoint) {
 			validEndpoints = append(validEndpoints, endpoint)
 		} else {
-			logger.Warn("Skipping invalid Solana endpoint with placeholder API key", zap.String("endpoint", endpoint))
+			logger.Warn("Skipping invalid Solana endpoint with placeholder API key", zap.String("endpoint", creds.Redact(endpoint)))
 		}
 	}
 
@@ -191,14 +217,14 @@ func NewSolanaRelay(cfg config.Config, logger *zap.Logger) *SolanaRelay {
 	// Add any custom endpoints from config if available
 	if customEndpoints := cfg.GetStringSlice("SOLANA_RPC_ENDPOINTS"); len(customEndpoints) > 0 {
 		logger.Info("Custom Solana RPC endpoints configured",
-			zap.Strings("custom_endpoints", customEndpoints))
+			zap.Strings("custom_endpoints", redactAll(creds, customEndpoints)))
 	}
 
 	relay := &SolanaRelay{
 		cfg:           cfg,
 		logger:        logger,
 		relayConfig:   relayConfig,
-		blockChan:     make(chan blocks.BlockEvent, 2000),
+		blockChan:     make(chan *blocks.BlockEvent, 2000),
 		pendingReqs:   make(map[int64]chan *SolanaResponse),
 		subscriptions: make(map[string]chan *SolanaNotification),
 		backoff:       make(map[string]int),
@@ -206,10 +232,14 @@ func NewSolanaRelay(cfg config.Config, logger *zap.Logger) *SolanaRelay {
 			IsHealthy:       false,
 			ConnectionState: "disconnected",
 		},
-		healthMgr: newEndpointHealth(relayConfig.Endpoints),
-		deduper:   newSolanaDeduper(),
+		healthMgr: endpointhealth.New("solana", relayConfig.Endpoints, endpointhealth.Config{Label: creds.Redact}),
+		deduper:   newSolanaDeduperFromConfig(cfg, logger),
 		metrics:   newSolanaProm("bitcoinsprint"),
+		backfill:  newSlotBackfill(),
+		creds:     creds,
+		proxies:   newRelayProxies(cfg, logger),
 	}
+	creds.OnRotate(relay.recycleConnections)
 
 	// Start periodic health reporting
 	go func() {
@@ -228,20 +258,12 @@ func (sr *SolanaRelay) reportEndpointHealth(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-t.C:
-			snap := sr.healthMgr.snapshot()
+			snap := sr.healthMgr.Snapshot()
 			for ep, st := range snap {
-				sr.metrics.endpointLatency.WithLabelValues(ep).Set(st.ewmaRTT)
-				sr.metrics.endpointScore.WithLabelValues(ep).Set(st.score())
-				var state float64
-				switch st.state {
-				case breakerClosed:
-					state = 0
-				case breakerHalfOpen:
-					state = 1
-				case breakerOpen:
-					state = 2
-				}
-				sr.metrics.endpointState.WithLabelValues(ep).Set(state)
+				label := sr.creds.Redact(ep)
+				sr.metrics.endpointLatency.WithLabelValues(label).Set(st.EWMARTT)
+				sr.metrics.endpointScore.WithLabelValues(label).Set(st.Score)
+				sr.metrics.endpointState.WithLabelValues(label).Set(float64(st.State))
 			}
 
 			// Log endpoint health every 5 minutes (roughly)
@@ -258,7 +280,7 @@ func (sr *SolanaRelay) reportEndpointHealth(ctx context.Context) {
 				// Count healthy/unhealthy endpoints
 				var healthy, unhealthy int
 				for _, st := range snap {
-					if st.state != breakerOpen && st.successes > st.failures {
+					if st.State != endpointhealth.Open && st.Successes > st.Failures {
 						healthy++
 					} else {
 						unhealthy++
@@ -287,7 +309,13 @@ func (sr *SolanaRelay) Connect(ctx context.Context) error {
 	}
 
 	sr.logger.Info("Connecting to Solana network",
-		zap.Strings("endpoints", sr.relayConfig.Endpoints))
+		zap.Strings("endpoints", redactAll(sr.creds, sr.relayConfig.Endpoints)))
+
+	// Keys may have rotated since construction; keep following them
+	if err := sr.creds.Refresh(ctx); err != nil {
+		sr.logger.Warn("Solana credential refresh incomplete", zap.String("error", sr.creds.Redact(err.Error())))
+	}
+	go sr.creds.Run(ctx, sr.cfg.CredentialRefresh)
 
 	for _, endpoint := range sr.relayConfig.Endpoints {
 		go sr.connectToEndpoint(ctx, endpoint)
@@ -330,6 +358,10 @@ func (sr *SolanaRelay) IsConnected() bool {
 	return len(sr.connections) > 0
 }
 
+// solanaSlotLagThreshold is the longest silence tolerated on a slot
+// subscription; slots arrive every ~400ms
+const solanaSlotLagThreshold = 10 * time.Second
+
 // StreamBlocks streams Solana blocks
 func (sr *SolanaRelay) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
 	if !sr.IsConnected() {
@@ -341,13 +373,35 @@ func (sr *SolanaRelay) StreamBlocks(ctx context.Context, blockChan chan<- blocks
 		return fmt.Errorf("failed to subscribe to blocks: %w", err)
 	}
 
+	// Replay slots missed while a connection was down
+	sr.startBackfillWorker(ctx)
+
+	if sr.streaming.CompareAndSwap(false, true) {
+		go func() {
+			watchSubscriptionLag(ctx, lagWatch{
+				chain:       "solana",
+				threshold:   solanaSlotLagThreshold,
+				conns:       sr.activeConnections,
+				resubscribe: sr.resubscribeOn,
+				healthMgr:   sr.healthMgr,
+				label:       sr.creds.Redact,
+				lagGauge:    sr.metrics.subscriptionLag,
+				recoveries:  sr.metrics.lagRecoveries,
+				logger:      sr.logger,
+			})
+			sr.streaming.Store(false)
+		}()
+	}
+
 	// Forward blocks from internal channel to provided channel
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case block := <-sr.blockChan:
+			case ev := <-sr.blockChan:
+				block := *ev
+				ev.Release()
 				select {
 				case blockChan <- block:
 				case <-ctx.Done():
@@ -362,12 +416,17 @@ func (sr *SolanaRelay) StreamBlocks(ctx context.Context, blockChan chan<- blocks
 
 // GetLatestBlock returns the latest Solana block
 func (sr *SolanaRelay) GetLatestBlock() (*blocks.BlockEvent, error) {
+	return sr.LatestBlock(context.Background())
+}
+
+// LatestBlock returns the latest Solana block, giving up when ctx ends
+func (sr *SolanaRelay) LatestBlock(ctx context.Context) (*blocks.BlockEvent, error) {
 	if !sr.IsConnected() {
 		return nil, fmt.Errorf("not connected to Solana network")
 	}
 
 	// Get latest slot
-	slotResponse, err := sr.makeRequest("getSlot", []interface{}{})
+	slotResponse, err := sr.makeRequestContext(ctx, "getSlot", []interface{}{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest slot: %w", err)
 	}
@@ -378,7 +437,7 @@ func (sr *SolanaRelay) GetLatestBlock() (*blocks.BlockEvent, error) {
 	}
 
 	// Get block for this slot
-	blockResponse, err := sr.makeRequest("getBlock", []interface{}{slot, map[string]interface{}{
+	blockResponse, err := sr.makeRequestContext(ctx, "getBlock", []interface{}{slot, map[string]interface{}{
 		"encoding":                       "json",
 		"maxSupportedTransactionVersion": 0,
 	}})
@@ -423,14 +482,22 @@ func (sr *SolanaRelay) GetBlockByHeight(height uint64) (*blocks.BlockEvent, erro
 
 // GetNetworkInfo returns Solana network information
 func (sr *SolanaRelay) GetNetworkInfo() (*NetworkInfo, error) {
+	return sr.NetworkInfo(context.Background())
+}
+
+// NetworkInfo returns Solana network information, giving up when ctx ends
+func (sr *SolanaRelay) NetworkInfo(ctx context.Context) (*NetworkInfo, error) {
 	if !sr.IsConnected() {
 		return nil, fmt.Errorf("not connected to Solana network")
 	}
 
 	// Get multiple pieces of network info
-	slotResp, _ := sr.makeRequest("getSlot", []interface{}{})
-	heightResp, _ := sr.makeRequest("getBlockHeight", []interface{}{})
-	_, _ = sr.makeRequest("getEpochInfo", []interface{}{})
+	slotResp, _ := sr.makeRequestContext(ctx, "getSlot", []interface{}{})
+	heightResp, _ := sr.makeRequestContext(ctx, "getBlockHeight", []interface{}{})
+	_, _ = sr.makeRequestContext(ctx, "getEpochInfo", []interface{}{})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	networkInfo := &NetworkInfo{
 		Network:   "solana",
@@ -464,7 +531,12 @@ func (sr *SolanaRelay) GetPeerCount() int {
 
 // GetSyncStatus returns Solana synchronization status
 func (sr *SolanaRelay) GetSyncStatus() (*SyncStatus, error) {
-	healthResp, err := sr.makeRequest("getHealth", []interface{}{})
+	return sr.SyncStatus(context.Background())
+}
+
+// SyncStatus returns Solana synchronization status, giving up when ctx ends
+func (sr *SolanaRelay) SyncStatus(ctx context.Context) (*SyncStatus, error) {
+	healthResp, err := sr.makeRequestContext(ctx, "getHealth", []interface{}{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get health status: %w", err)
 	}
@@ -477,7 +549,7 @@ func (sr *SolanaRelay) GetSyncStatus() (*SyncStatus, error) {
 	// If health is "ok", assume synced
 	isSynced := health == "ok"
 
-	slotResp, _ := sr.makeRequest("getSlot", []interface{}{})
+	slotResp, _ := sr.makeRequestContext(ctx, "getSlot", []interface{}{})
 	var currentSlot uint64
 	if slotResp != nil {
 		json.Unmarshal(slotResp.Result, &currentSlot)
@@ -557,7 +629,7 @@ func (sr *SolanaRelay) GetConfig() RelayConfig {
 // connectToEndpoint establishes a WebSocket connection to an endpoint
 func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 	// We ignore the `endpoint` parameter and select the best available
-	ep, ok := sr.healthMgr.pickWeighted()
+	ep, ok := sr.healthMgr.Pick()
 	if !ok {
 		sr.logger.Warn("No Solana endpoints available (breaker-open/all unhealthy)")
 		return
@@ -566,22 +638,16 @@ func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 	u, err := url.Parse(ep)
 	if err != nil {
 		sr.logger.Warn("Invalid endpoint URL",
-			zap.String("endpoint", ep),
+			sr.endpointField(ep),
 			zap.Error(err))
 
 		// Record error in endpoint health tracker
-		sr.healthMgr.recordFailure(ep, err.Error())
+		sr.healthMgr.RecordFailure(ep, err.Error())
 		return
 	}
 
-	// Use a websocket dialer that respects a custom resolver and TLS
-	dialer := websocket.Dialer{
-		Proxy:             http.ProxyFromEnvironment,
-		HandshakeTimeout:  20 * time.Second,
-		TLSClientConfig:   &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: false},
-		NetDialContext:    netx.DialerWithResolver(),
-		EnableCompression: true,
-	}
+	// Use a websocket dialer that respects a custom resolver, proxies and TLS
+	dialer := newWSDialer(sr.proxies)
 
 	// Base headers for all endpoints
 	header := http.Header{}
@@ -590,29 +656,8 @@ func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 	header.Set("Pragma", "no-cache")
 	header.Set("Cache-Control", "no-cache")
 
-	// Endpoint-specific configuration
-	if strings.Contains(endpoint, "cloudflare") {
-		// Cloudflare requires specific headers
-		header.Set("Origin", "https://www.cloudflare-eth.com")
-		header.Set("CF-Access-Client-Id", sr.cfg.Get("CF_ACCESS_CLIENT_ID", ""))
-		header.Set("CF-Access-Client-Secret", sr.cfg.Get("CF_ACCESS_CLIENT_SECRET", ""))
-	} else if strings.Contains(endpoint, "ankr") {
-		// Ankr API requires JWT or API key
-		apiKey := sr.cfg.Get("ANKR_API_KEY", "")
-		if apiKey != "" {
-			header.Set("Authorization", "Bearer "+apiKey)
-		}
-		header.Set("Origin", "https://www.ankr.com")
-	} else if strings.Contains(endpoint, "helius") {
-		// Helius API requires API key
-		apiKey := sr.cfg.Get("HELIUS_API_KEY", "")
-		if apiKey != "" {
-			// Helius uses apiKey URL parameter
-			q := u.Query()
-			q.Set("api-key", apiKey)
-			u.RawQuery = q.Encode()
-		}
-	}
+	// Endpoint-specific credentials, read per dial so rotations apply
+	sr.applyCredentials(ep, u, header)
 
 	var attempt int
 	for {
@@ -621,11 +666,11 @@ func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 		// Only try a limited number of times before giving up on this endpoint
 		if attempt > 5 {
 			sr.logger.Warn("Giving up connecting to Solana endpoint after multiple failures",
-				zap.String("endpoint", ep),
+				sr.endpointField(ep),
 				zap.Int("attempts", attempt))
 
 			// Record multiple failures in endpoint health
-			sr.healthMgr.recordFailure(ep, "max_retries_exceeded")
+			sr.healthMgr.RecordFailure(ep, "max_retries_exceeded")
 			return
 		}
 
@@ -647,10 +692,10 @@ func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 			sr.addConnection(wc)
 
 			// Record successful connection in endpoint health tracker
-			sr.healthMgr.recordSuccess(ep, connectionTime)
+			sr.healthMgr.RecordSuccess(ep, connectionTime)
 
 			sr.logger.Info("Connected to Solana endpoint",
-				zap.String("endpoint", ep),
+				sr.endpointField(ep),
 				zap.Duration("connection_time", connectionTime))
 
 			// Update metrics
@@ -658,17 +703,23 @@ func (sr *SolanaRelay) connectToEndpoint(ctx context.Context, endpoint string) {
 
 			// Start message handler
 			go sr.handleMessages(wc)
+			if sr.streaming.Load() {
+				go sr.subscribeNew(wc)
+			}
 			return
 		}
 
 		sr.logger.Warn("Failed to connect to Solana endpoint",
-			zap.String("endpoint", ep),
+			sr.endpointField(ep),
 			zap.Error(err),
 			zap.Int("attempt", attempt),
 			zap.Duration("connection_attempt_time", connectionTime))
 
-		// Record failed connection in endpoint health tracker
-		sr.healthMgr.recordFailure(ep, err.Error())
+		// Record failed connection in endpoint health tracker, unless it
+		// failed at the proxy and never reached the endpoint
+		if !proxyFailure(sr.logger, sr.endpointField(ep), err) {
+			sr.healthMgr.RecordFailure(ep, err.Error())
+		}
 
 		// Update metrics
 		sr.metrics.wsReconnects.Inc()
@@ -700,7 +751,7 @@ func (sr *SolanaRelay) installWSHandlers(wc *wsConn) {
 	wc.Conn.SetPongHandler(func(data string) error {
 		_ = wc.Conn.SetReadDeadline(time.Now().Add(45 * time.Second))
 		sr.logger.Debug("Received pong",
-			zap.String("endpoint", wc.endpoint),
+			sr.endpointField(wc.endpoint),
 			zap.String("data", data))
 		return nil
 	})
@@ -739,7 +790,7 @@ func (sr *SolanaRelay) installWSHandlers(wc *wsConn) {
 
 				if err != nil {
 					sr.logger.Warn("Ping failed",
-						zap.String("endpoint", wc.endpoint),
+						sr.endpointField(wc.endpoint),
 						zap.Error(err))
 					return
 				}
@@ -765,11 +816,11 @@ func (sr *SolanaRelay) sendHeartbeat(wc *wsConn) {
 
 	if err != nil {
 		sr.logger.Warn("Failed to send heartbeat",
-			zap.String("endpoint", wc.endpoint),
+			sr.endpointField(wc.endpoint),
 			zap.Error(err))
 	} else {
 		sr.logger.Debug("Sent heartbeat to keep connection alive",
-			zap.String("endpoint", wc.endpoint))
+			sr.endpointField(wc.endpoint))
 	}
 }
 
@@ -779,10 +830,10 @@ func (sr *SolanaRelay) handleMessages(wc *wsConn) {
 		_ = wc.Conn.Close()
 		sr.removeConnection(wc)
 		sr.updateHealth(sr.IsConnected(), "connection_lost", nil)
-		sr.logger.Warn("Solana WebSocket handler exited", zap.String("endpoint", wc.endpoint))
+		sr.logger.Warn("Solana WebSocket handler exited", sr.endpointField(wc.endpoint))
 
 		// Record connection failure in health tracking
-		sr.healthMgr.recordFailure(wc.endpoint, "connection_lost")
+		sr.healthMgr.RecordFailure(wc.endpoint, "connection_lost")
 
 		sr.scheduleReconnect(wc.endpoint)
 	}()
@@ -791,22 +842,33 @@ func (sr *SolanaRelay) handleMessages(wc *wsConn) {
 		_, message, err := wc.Conn.ReadMessage()
 		if err != nil {
 			sr.logger.Warn("WebSocket read error",
-				zap.String("endpoint", wc.endpoint),
+				sr.endpointField(wc.endpoint),
 				zap.Error(err))
 
 			// Record read failure in health tracking
-			sr.healthMgr.recordFailure(wc.endpoint, fmt.Sprintf("ws_read_error: %v", err))
+			sr.healthMgr.RecordFailure(wc.endpoint, fmt.Sprintf("ws_read_error: %v", err))
 
 			// Don't break immediately, try to reconnect
 			if sr.shouldReconnect(err) {
-				sr.logger.Info("Attempting to reconnect Solana WebSocket", zap.String("endpoint", wc.endpoint))
+				sr.logger.Info("Attempting to reconnect Solana WebSocket", sr.endpointField(wc.endpoint))
 				return
 			}
 			return
 		}
 
 		// Track successful read
-		sr.healthMgr.recordSuccess(wc.endpoint, 0)
+		sr.healthMgr.RecordSuccess(wc.endpoint, 0)
+
+		// A batch response answers several pending requests at once
+		if isBatchMessage(message) {
+			var responses []SolanaResponse
+			if err := json.Unmarshal(message, &responses); err == nil {
+				for i := range responses {
+					sr.handleResponse(&responses[i])
+				}
+			}
+			continue
+		}
 
 		// Parse message as JSON-RPC response or notification
 		var response SolanaResponse
@@ -817,6 +879,9 @@ func (sr *SolanaRelay) handleMessages(wc *wsConn) {
 			// Handle notification
 			var notification SolanaNotification
 			if err := json.Unmarshal(message, &notification); err == nil {
+				if notification.Method == "slotNotification" {
+					wc.sub.notified(time.Now())
+				}
 				sr.handleNotification(&notification)
 			}
 		}
@@ -846,22 +911,21 @@ func (sr *SolanaRelay) removeConnection(wc *wsConn) {
 	if len(sr.connections) == 0 {
 		sr.connected.Store(false)
 	}
+
+	// The dropped connection may have carried the slot subscription
+	sr.backfill.markResync()
 }
 
 // makeRequest makes a JSON-RPC request with intelligent endpoint selection
 func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*SolanaResponse, error) {
-	requestID := atomic.AddInt64(&sr.requestID, 1)
-
-	request := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"method":  method,
-		"params":  params,
-		"id":      requestID,
-	}
+	return sr.makeRequestContext(context.Background(), method, params)
+}
 
-	requestData, err := json.Marshal(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+// makeRequestContext is makeRequest bounded by ctx. A request whose caller
+// has already gone is not sent upstream.
+func (sr *SolanaRelay) makeRequestContext(ctx context.Context, method string, params []interface{}) (*SolanaResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
 	// Get active connections
@@ -883,11 +947,11 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 	var wc *wsConn
 
 	// Get best endpoint using weighted selection
-	if bestEndpoint, ok := sr.healthMgr.pickWeighted(); ok {
+	if bestEndpoint, ok := sr.healthMgr.Pick(); ok {
 		if conn, exists := connMap[bestEndpoint]; exists {
 			wc = conn
 			sr.logger.Debug("Selected endpoint using weighted health strategy",
-				zap.String("endpoint", bestEndpoint),
+				sr.endpointField(bestEndpoint), reqid.Field(ctx),
 				zap.String("method", method))
 		}
 	}
@@ -898,10 +962,32 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 		wc = sr.connections[rand.Intn(n)]
 		sr.connMu.RUnlock()
 		sr.logger.Debug("Using fallback random endpoint selection",
-			zap.String("endpoint", wc.endpoint),
+			sr.endpointField(wc.endpoint), reqid.Field(ctx),
 			zap.String("method", method))
 	}
 
+	return sr.makeRequestOn(ctx, wc, method, params)
+}
+
+// makeRequestOn makes a JSON-RPC request on a specific connection
+func (sr *SolanaRelay) makeRequestOn(ctx context.Context, wc *wsConn, method string, params []interface{}) (_ *SolanaResponse, err error) {
+	defer func(start time.Time) {
+		logUpstreamCall(ctx, sr.logger, sr.creds.Redact(wc.endpoint), method, start, err)
+	}(time.Now())
+	requestID := atomic.AddInt64(&sr.requestID, 1)
+
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      requestID,
+	}
+
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
 	// Create response channel
 	responseChan := make(chan *SolanaResponse, 1)
 	sr.reqMu.Lock()
@@ -922,9 +1008,9 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 		sr.reqMu.Unlock()
 
 		// Record error in endpoint health tracker
-		sr.healthMgr.recordFailure(wc.endpoint, fmt.Sprintf("write_error: %v", err))
+		sr.healthMgr.RecordFailure(wc.endpoint, fmt.Sprintf("write_error: %v", err))
 
-		return nil, fmt.Errorf("failed to send request to %s: %w", wc.endpoint, err)
+		return nil, fmt.Errorf("failed to send request to %s: %w", sr.creds.Redact(wc.endpoint), err)
 	}
 
 	// Wait for response with timeout
@@ -933,7 +1019,7 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 	case response = <-responseChan:
 		// Record successful response in endpoint health tracker
 		responseTime := time.Since(startTime)
-		sr.healthMgr.recordSuccess(wc.endpoint, responseTime)
+		sr.healthMgr.RecordSuccess(wc.endpoint, responseTime)
 
 		// Update metrics
 		// Note: Detailed request metrics not implemented in current solanaProm struct
@@ -942,11 +1028,11 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 		if response.Error != nil {
 			// Some errors should be considered endpoint health issues
 			if response.Error.Code < -32000 || response.Error.Code == -32603 || response.Error.Code == -32010 {
-				sr.healthMgr.recordFailure(wc.endpoint, fmt.Sprintf("rpc_error: %d: %s",
+				sr.healthMgr.RecordFailure(wc.endpoint, fmt.Sprintf("rpc_error: %d: %s",
 					response.Error.Code, response.Error.Message))
 
 				sr.logger.Warn("Solana RPC error affects endpoint health",
-					zap.String("endpoint", wc.endpoint),
+					sr.endpointField(wc.endpoint), reqid.Field(ctx),
 					zap.Int("error_code", response.Error.Code),
 					zap.String("error_message", response.Error.Message))
 			}
@@ -959,9 +1045,14 @@ func (sr *SolanaRelay) makeRequest(method string, params []interface{}) (*Solana
 		sr.reqMu.Unlock()
 
 		// Record timeout in endpoint health tracker
-		sr.healthMgr.recordFailure(wc.endpoint, "request_timeout")
+		sr.healthMgr.RecordFailure(wc.endpoint, "request_timeout")
 
-		return nil, fmt.Errorf("request timeout for %s", wc.endpoint)
+		return nil, fmt.Errorf("request timeout for %s", sr.creds.Redact(wc.endpoint))
+	case <-ctx.Done():
+		sr.reqMu.Lock()
+		delete(sr.pendingReqs, requestID)
+		sr.reqMu.Unlock()
+		return nil, ctx.Err()
 	}
 }
 
@@ -1019,7 +1110,7 @@ func (sr *SolanaRelay) handleNotification(notification *SolanaNotification) {
 	}
 
 	// Create block hash from the slot
-	blockHash := fmt.Sprintf("slot:%d", wrap.Params.Result.Slot)
+	blockHash := solanaSlotHash(wrap.Params.Result.Slot)
 
 	// Validate the block hash/slot (Solana slots are always > 0 for real blocks)
 	if wrap.Params.Result.Slot == 0 {
@@ -1028,6 +1119,8 @@ func (sr *SolanaRelay) handleNotification(notification *SolanaNotification) {
 		return
 	}
 
+	sr.trackSlot(wrap.Params.Result.Slot)
+
 	now := time.Now()
 
 	// Check if we've already seen this block recently via the adaptive deduper
@@ -1042,15 +1135,14 @@ func (sr *SolanaRelay) handleNotification(notification *SolanaNotification) {
 		return
 	}
 
-	// Create rich block event with additional metadata
-	ev := blocks.BlockEvent{
-		Hash:       blockHash,
-		Height:     uint32(wrap.Params.Result.Slot),
-		Timestamp:  now,
-		DetectedAt: now,
-		Source:     "solana-relay",
-		Tier:       "enterprise",
-	}
+	// Slots are frequent enough that events come from the pool
+	ev := blocks.AcquireEvent()
+	ev.Hash = blockHash
+	ev.Height = uint32(wrap.Params.Result.Slot)
+	ev.Timestamp = now
+	ev.DetectedAt = now
+	ev.Source = "solana-relay"
+	ev.Tier = "enterprise"
 
 	// Update metrics for successful block
 	sr.metrics.dupDropped.Inc()
@@ -1064,6 +1156,7 @@ func (sr *SolanaRelay) handleNotification(notification *SolanaNotification) {
 			zap.String("hash", blockHash))
 	default:
 		// Channel full - update metrics and log warning
+		ev.Release()
 		sr.metrics.dupDropped.Inc()
 
 		sr.logger.Warn("Dropped Solana block due to full channel",
@@ -1074,11 +1167,71 @@ func (sr *SolanaRelay) handleNotification(notification *SolanaNotification) {
 	}
 }
 
-// subscribeToBlocks subscribes to slot updates (Solana's equivalent of blocks)
+// subscribeToBlocks subscribes to slot updates (Solana's equivalent of
+// blocks) on every connection, so each endpoint can be watched for lag
 func (sr *SolanaRelay) subscribeToBlocks(ctx context.Context) error {
-	// Subscribe to slot notifications
-	_, err := sr.makeRequest("slotSubscribe", []interface{}{})
-	return err
+	sr.connMu.RLock()
+	conns := append([]*wsConn(nil), sr.connections...)
+	sr.connMu.RUnlock()
+
+	var subscribed int
+	lastErr := fmt.Errorf("no active connections")
+	for _, wc := range conns {
+		if wc.sub.active() {
+			subscribed++
+			continue
+		}
+		if err := sr.subscribeOn(ctx, wc); err != nil {
+			lastErr = err
+			sr.logger.Warn("Failed to subscribe to slots", sr.endpointField(wc.endpoint), zap.Error(err))
+			continue
+		}
+		subscribed++
+	}
+	if subscribed == 0 {
+		return lastErr
+	}
+	return nil
+}
+
+// subscribeOn subscribes wc to slot notifications
+func (sr *SolanaRelay) subscribeOn(ctx context.Context, wc *wsConn) error {
+	resp, err := sr.makeRequestOn(ctx, wc, "slotSubscribe", []interface{}{})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("slotSubscribe: %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	wc.sub.subscribed(resp.Result, time.Now())
+	return nil
+}
+
+// resubscribeOn replaces the slot subscription on wc. Slots notified while
+// the subscription was silent are recovered by the backfill worker.
+func (sr *SolanaRelay) resubscribeOn(ctx context.Context, wc *wsConn) error {
+	if id := wc.sub.subscriptionID(); id != nil {
+		// Best effort: a provider that lost the subscription rejects this
+		sr.makeRequestOn(ctx, wc, "slotUnsubscribe", []interface{}{id})
+	}
+	sr.backfill.markResync()
+	return sr.subscribeOn(ctx, wc)
+}
+
+// subscribeNew subscribes a connection opened while streaming
+func (sr *SolanaRelay) subscribeNew(wc *wsConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := sr.subscribeOn(ctx, wc); err != nil {
+		sr.logger.Warn("Failed to subscribe new connection to slots", sr.endpointField(wc.endpoint), zap.Error(err))
+	}
+}
+
+// activeConnections returns a copy of the active connection set
+func (sr *SolanaRelay) activeConnections() []*wsConn {
+	sr.connMu.RLock()
+	defer sr.connMu.RUnlock()
+	return append([]*wsConn(nil), sr.connections...)
 }
 
 // scheduleReconnect schedules reconnect with exponential backoff per endpoint
@@ -1098,11 +1251,11 @@ func (sr *SolanaRelay) scheduleReconnect(endpoint string) {
 	ep := endpoint // default to the endpoint that just disconnected
 	if !forcedReconnect {
 		// Let the health manager choose a good endpoint
-		if selected, ok := sr.healthMgr.pickWeighted(); ok {
+		if selected, ok := sr.healthMgr.Pick(); ok {
 			ep = selected
 			sr.logger.Debug("Using health manager to select reconnection endpoint",
-				zap.String("selected", ep),
-				zap.String("original", endpoint))
+				zap.String("selected", sr.creds.Redact(ep)),
+				zap.String("original", sr.creds.Redact(endpoint)))
 		}
 	}
 
@@ -1133,7 +1286,7 @@ func (sr *SolanaRelay) scheduleReconnect(endpoint string) {
 	wait := delay + jitter
 
 	sr.logger.Info("Scheduling reconnect",
-		zap.String("endpoint", ep),
+		sr.endpointField(ep),
 		zap.Duration("in", wait),
 		zap.Int("active_connections", activeConnections),
 		zap.Int("attempt", attempt))
@@ -1150,15 +1303,13 @@ func (sr *SolanaRelay) scheduleReconnect(endpoint string) {
 		// If we have enough connections, defer to the health manager
 		if !needToReconnect {
 			// Let the health manager decide if this endpoint is worth trying
-			sr.healthMgr.mu.RLock()
-			stats, exists := sr.healthMgr.stats[ep]
-			sr.healthMgr.mu.RUnlock()
-			if exists && stats.state != breakerOpen {
+			state, exists := sr.healthMgr.State(ep)
+			if exists && state != endpointhealth.Open {
 				// Endpoint is not in circuit breaker open state, try to connect
 				needToReconnect = true
 			} else {
 				sr.logger.Info("Health manager suggests skipping reconnect (circuit breaker open)",
-					zap.String("endpoint", ep))
+					sr.endpointField(ep))
 			}
 		}
 
@@ -1168,11 +1319,16 @@ func (sr *SolanaRelay) scheduleReconnect(endpoint string) {
 			sr.connectToEndpoint(ctx, ep)
 		} else {
 			sr.logger.Info("Skipping reconnect attempt, enough connections active or endpoint in circuit breaker",
-				zap.String("endpoint", ep))
+				sr.endpointField(ep))
 		}
 	})
 }
 
+// solanaSlotHash is the hash slot events are keyed and deduplicated by
+func solanaSlotHash(slot uint64) string {
+	return "slot:" + strconv.FormatUint(slot, 10)
+}
+
 // convertToBlockEvent converts SolanaBlock to BlockEvent
 func (sr *SolanaRelay) convertToBlockEvent(solanaBlock *SolanaBlock) *blocks.BlockEvent {
 	event := &blocks.BlockEvent{
@@ -1235,3 +1391,98 @@ func (sr *SolanaRelay) updateHealth(healthy bool, state string, err error) {
 		sr.health.ErrorMessage = ""
 	}
 }
+
+// ===== PROVIDER CREDENTIALS =====
+
+// newSolanaCredentials builds the key store for Solana providers: the
+// secure buffer service when configured, then the unified config
+func newSolanaCredentials(cfg config.Config, logger *zap.Logger) *credentials.Store {
+	var sources []credentials.Source
+	if cfg.SecretServiceURL != "" {
+		sources = append(sources, credentials.NewBufferServiceSource(cfg.SecretServiceURL, nil))
+	}
+	sources = append(sources, credentials.NewConfigSource(cfg))
+
+	creds := credentials.NewStore(logger, sources...)
+	for _, names := range solanaProviderCredentials {
+		creds.Register(names...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := creds.Refresh(ctx); err != nil {
+		logger.Warn("Solana credentials incomplete, continuing with what was found", zap.String("error", creds.Redact(err.Error())))
+	}
+	return creds
+}
+
+// applyCredentials adds the provider's current key to the dial URL or headers
+func (sr *SolanaRelay) applyCredentials(endpoint string, u *url.URL, header http.Header) {
+	switch {
+	case strings.Contains(endpoint, "cloudflare"):
+		// Cloudflare requires specific headers
+		header.Set("Origin", "https://www.cloudflare-eth.com")
+		if id, ok := sr.creds.Get("CF_ACCESS_CLIENT_ID"); ok {
+			header.Set("CF-Access-Client-Id", id)
+		}
+		if secret, ok := sr.creds.Get("CF_ACCESS_CLIENT_SECRET"); ok {
+			header.Set("CF-Access-Client-Secret", secret)
+		}
+	case strings.Contains(endpoint, "ankr"):
+		// Ankr API requires JWT or API key
+		if apiKey, ok := sr.creds.Get("ANKR_API_KEY"); ok {
+			header.Set("Authorization", "Bearer "+apiKey)
+		}
+		header.Set("Origin", "https://www.ankr.com")
+	case strings.Contains(endpoint, "helius"):
+		// Helius uses an api-key URL parameter
+		if apiKey, ok := sr.creds.Get("HELIUS_API_KEY"); ok {
+			q := u.Query()
+			q.Set("api-key", apiKey)
+			u.RawQuery = q.Encode()
+		}
+	}
+}
+
+// recycleConnections closes connections opened with a rotated credential;
+// the reconnect path dials them again with the new value
+func (sr *SolanaRelay) recycleConnections(name string) {
+	sr.connMu.RLock()
+	var stale []*wsConn
+	for _, wc := range sr.connections {
+		for fragment, names := range solanaProviderCredentials {
+			if strings.Contains(wc.endpoint, fragment) && containsString(names, name) {
+				stale = append(stale, wc)
+			}
+		}
+	}
+	sr.connMu.RUnlock()
+
+	for _, wc := range stale {
+		sr.logger.Info("Reconnecting Solana endpoint after credential rotation",
+			sr.endpointField(wc.endpoint),
+			zap.String("credential", name))
+		_ = wc.Conn.Close()
+	}
+}
+
+// endpointField logs an endpoint with credentials redacted
+func (sr *SolanaRelay) endpointField(endpoint string) zap.Field {
+	return zap.String("endpoint", sr.creds.Redact(endpoint))
+}
+
+func redactAll(creds *credentials.Store, endpoints []string) []string {
+	out := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		out[i] = creds.Redact(ep)
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+)
+
+// Backfill tuning. A gap larger than maxBackfillSlots (roughly 20 minutes
+// of slots) is trimmed to its most recent part: consumers care about
+// recent blocks and a full replay would starve the live stream.
+const (
+	maxBackfillSlots     = 3000
+	backfillBatchSize    = 20 // getBlock calls per JSON-RPC batch
+	backfillRequestsRate = 10 // getBlock calls per second
+	backfillQueueSize    = 16
+	getBlocksMaxRange    = 500000
+)
+
+// slotGap is an inclusive range of slots missed while disconnected
+type slotGap struct {
+	from, to uint64
+}
+
+// slotBackfill tracks the last streamed slot and replays gaps
+type slotBackfill struct {
+	mu       sync.Mutex
+	lastSlot uint64
+	resync   bool // Set when a connection dropped; next slot is checked for a gap
+
+	gaps    chan slotGap
+	limiter *rate.Limiter
+	started sync.Once
+}
+
+func newSlotBackfill() *slotBackfill {
+	return &slotBackfill{
+		gaps:    make(chan slotGap, backfillQueueSize),
+		limiter: rate.NewLimiter(rate.Limit(backfillRequestsRate), backfillBatchSize),
+	}
+}
+
+// markResync flags that slots may have been missed, e.g. on disconnect
+func (b *slotBackfill) markResync() {
+	b.mu.Lock()
+	b.resync = true
+	b.mu.Unlock()
+}
+
+// observe records a live slot and returns the gap to backfill, if any
+func (b *slotBackfill) observe(slot uint64) (slotGap, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	last, resync := b.lastSlot, b.resync
+	if slot > b.lastSlot {
+		b.lastSlot = slot
+	}
+	b.resync = false
+
+	// Without a drop, small jumps are just skipped leader slots
+	if !resync || last == 0 || slot <= last+1 {
+		return slotGap{}, false
+	}
+
+	gap := slotGap{from: last + 1, to: slot - 1}
+	if gap.to-gap.from+1 > maxBackfillSlots {
+		gap.from = gap.to - maxBackfillSlots + 1
+	}
+	return gap, true
+}
+
+// LastSlot returns the most recent slot seen on the live stream
+func (sr *SolanaRelay) LastSlot() uint64 {
+	sr.backfill.mu.Lock()
+	defer sr.backfill.mu.Unlock()
+	return sr.backfill.lastSlot
+}
+
+// trackSlot feeds a live slot to gap detection and queues any gap
+func (sr *SolanaRelay) trackSlot(slot uint64) {
+	gap, ok := sr.backfill.observe(slot)
+	if !ok {
+		return
+	}
+
+	sr.logger.Info("Detected Solana slot gap after reconnect",
+		zap.Uint64("from", gap.from),
+		zap.Uint64("to", gap.to))
+	sr.metrics.backfillGaps.Inc()
+
+	select {
+	case sr.backfill.gaps <- gap:
+	default:
+		sr.logger.Warn("Solana backfill queue full, dropping gap",
+			zap.Uint64("from", gap.from),
+			zap.Uint64("to", gap.to))
+	}
+}
+
+// startBackfillWorker replays queued gaps until ctx is done. Safe to call
+// more than once; only the first call starts a worker.
+func (sr *SolanaRelay) startBackfillWorker(ctx context.Context) {
+	sr.backfill.started.Do(func() {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case gap := <-sr.backfill.gaps:
+					if err := sr.backfillGap(ctx, gap); err != nil && ctx.Err() == nil {
+						sr.logger.Warn("Solana backfill incomplete",
+							zap.Uint64("from", gap.from),
+							zap.Uint64("to", gap.to),
+							zap.Error(err))
+					}
+				}
+			}
+		}()
+	})
+}
+
+// backfillGap lists produced blocks in the gap, then fetches them in
+// rate-limited JSON-RPC batches and emits each batch in slot order
+func (sr *SolanaRelay) backfillGap(ctx context.Context, gap slotGap) error {
+	slots, err := sr.confirmedSlots(gap.from, gap.to)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(slots); start += backfillBatchSize {
+		end := start + backfillBatchSize
+		if end > len(slots) {
+			end = len(slots)
+		}
+		batch := slots[start:end]
+
+		if err := sr.backfill.limiter.WaitN(ctx, len(batch)); err != nil {
+			return err
+		}
+
+		reqs := make([]BatchRequest, len(batch))
+		for i, slot := range batch {
+			reqs[i] = BatchRequest{Method: "getBlock", Params: backfillBlockParams(slot)}
+		}
+		results, err := sr.BatchCall(ctx, reqs)
+		if err != nil {
+			return err
+		}
+
+		events := make([]*blocks.BlockEvent, len(batch))
+		for i, res := range results {
+			err := res.Err
+			if err == nil {
+				events[i], err = sr.parseBackfillBlock(batch[i], res.Result)
+			}
+			if err != nil {
+				sr.metrics.backfillMissed.Inc()
+				sr.logger.Debug("Failed to backfill Solana slot",
+					zap.Uint64("slot", batch[i]),
+					zap.Error(err))
+			}
+		}
+
+		for i, ev := range events {
+			if ev == nil {
+				continue
+			}
+			// The live stream may have delivered it meanwhile
+			if sr.deduper.isDup(solanaSlotHash(batch[i])) {
+				continue
+			}
+			select {
+			case sr.blockChan <- ev:
+				sr.metrics.backfilledBlocks.Inc()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	sr.logger.Info("Solana backfill complete",
+		zap.Uint64("from", gap.from),
+		zap.Uint64("to", gap.to),
+		zap.Int("blocks", len(slots)))
+	return nil
+}
+
+// confirmedSlots returns slots in [from, to] that produced a block, so
+// skipped leader slots are not requested one by one
+func (sr *SolanaRelay) confirmedSlots(from, to uint64) ([]uint64, error) {
+	var slots []uint64
+	for start := from; start <= to; start += getBlocksMaxRange {
+		end := start + getBlocksMaxRange - 1
+		if end > to {
+			end = to
+		}
+
+		resp, err := sr.makeRequest("getBlocks", []interface{}{start, end})
+		if err != nil {
+			return nil, fmt.Errorf("getBlocks %d-%d: %w", start, end, err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("getBlocks %d-%d: %d: %s", start, end, resp.Error.Code, resp.Error.Message)
+		}
+
+		var chunk []uint64
+		if err := json.Unmarshal(resp.Result, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse getBlocks result: %w", err)
+		}
+		slots = append(slots, chunk...)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	return slots, nil
+}
+
+// backfillBlockParams are getBlock params fetching slot header-only
+func backfillBlockParams(slot uint64) []interface{} {
+	return []interface{}{slot, map[string]interface{}{
+		"encoding":                       "json",
+		"transactionDetails":             "none",
+		"rewards":                        false,
+		"maxSupportedTransactionVersion": 0,
+	}}
+}
+
+// parseBackfillBlock decodes a getBlock result for slot and marks it
+// backfilled
+func (sr *SolanaRelay) parseBackfillBlock(slot uint64, result json.RawMessage) (*blocks.BlockEvent, error) {
+	var block SolanaBlock
+	if err := json.Unmarshal(result, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block: %w", err)
+	}
+	// getBlock does not echo the slot
+	block.Slot = slot
+
+	ev := sr.convertToBlockEvent(&block)
+	ev.Backfilled = true
+	return ev, nil
+}
@@ -3,9 +3,11 @@ package relay
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/PayRpc/Bitcoin-Sprint/internal/config"
 	"github.com/PayRpc/Bitcoin-Sprint/internal/dedup"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -27,7 +29,7 @@ var (
 	solanaAdaptiveTTL = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "solana_relay_adaptive_ttl_seconds",
 		Help: "Current adaptive TTL for Solana deduplication",
-	}, []string{"tier"})
+	}, []string{"type", "tier"})
 
 	solanaDuplicateRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "solana_relay_duplicate_rate",
@@ -35,6 +37,13 @@ var (
 	}, []string{"type", "tier"})
 )
 
+// Adaptive TTL defaults, overridable with SOL_DEDUP_* settings
+const (
+	DefaultSolanaDedupMinTTL     = 5 * time.Second
+	DefaultSolanaDedupMaxTTL     = 5 * time.Minute
+	DefaultSolanaDedupTargetRate = 0.3 // Duplicate rate the TTL is steered toward
+)
+
 // SolanaDeduper provides enterprise-grade Solana-specific deduplication
 type SolanaDeduper struct {
 	// Core deduplication
@@ -45,8 +54,10 @@ type SolanaDeduper struct {
 	ttl         time.Duration
 	minTTL      time.Duration
 	maxTTL      time.Duration
-	dupCount    int64
+	targetRate  float64 // Duplicate rate the advanced adjustment steers toward
+	dupCount    int64   // Decayed at each adjustment, like totalCount
 	totalCount  int64
+	suppressed  int64 // Duplicates suppressed since start
 	lastAdjust  time.Time
 	adjustEvery time.Duration
 
@@ -118,8 +129,9 @@ func NewSolanaDeduper(tier string, logger *zap.Logger) *SolanaDeduper {
 		order:       make([]string, 0, capacity),
 		capacity:    capacity,
 		ttl:         getSolanaBaseTTL(tier),
-		minTTL:      5 * time.Second,
-		maxTTL:      5 * time.Minute,
+		minTTL:      DefaultSolanaDedupMinTTL,
+		maxTTL:      DefaultSolanaDedupMaxTTL,
+		targetRate:  DefaultSolanaDedupTargetRate,
 		adjustEvery: 30 * time.Second,
 		lastAdjust:  time.Now(),
 		logger:      logger,
@@ -150,6 +162,7 @@ func NewSolanaDeduper(tier string, logger *zap.Logger) *SolanaDeduper {
 			AdaptiveTTL: sd.ttl,
 		}
 	}
+	sd.publishLocked()
 
 	if logger != nil {
 		logger.Info("Enterprise Solana Deduper initialized",
@@ -228,6 +241,7 @@ func (sd *SolanaDeduper) IsDuplicate(hash, itemType string, options ...dedup.Ded
 		if now.Sub(entry.LastSeen) <= currentTTL {
 			// It's a duplicate
 			sd.dupCount++
+			sd.suppressed++
 			typeStats.Duplicates++
 			entry.LastSeen = now
 			entry.SeenCount++
@@ -314,11 +328,11 @@ func (sd *SolanaDeduper) generateKey(hash, itemType string, opts *dedup.DedupeOp
 	slotPrefix := "unknown"
 	if opts.Properties != nil {
 		if slotNum, ok := opts.Properties["slot_number"].(uint64); ok {
-			slotPrefix = fmt.Sprintf("slot_%d", slotNum)
+			slotPrefix = "slot_" + strconv.FormatUint(slotNum, 10)
 		}
 	}
 
-	return fmt.Sprintf("%s:%s:%s", slotPrefix, itemType, hash)
+	return slotPrefix + ":" + itemType + ":" + hash
 }
 
 // getAdaptiveTTL returns adaptive TTL for a specific type
@@ -452,9 +466,7 @@ func (sd *SolanaDeduper) adjustTTLWithML() {
 		sd.adjustTTLBasic(globalRate)
 	}
 
-	// Update metrics
-	solanaAdaptiveTTL.WithLabelValues(sd.tier).Set(sd.ttl.Seconds())
-	solanaDuplicateRate.WithLabelValues("global", sd.tier).Set(globalRate)
+	sd.publishLocked()
 
 	// Reset counters with partial decay
 	sd.totalCount = sd.totalCount / 2
@@ -472,7 +484,7 @@ func (sd *SolanaDeduper) adjustTTLWithAdvancedML(globalRate float64) {
 	}
 
 	// Multi-factor TTL adjustment
-	targetRate := 0.3 // Target 30% duplicate rate for optimal performance
+	targetRate := sd.targetRate
 	rateDelta := globalRate - targetRate
 
 	// Calculate TTL adjustment factor
@@ -526,24 +538,23 @@ func (sd *SolanaDeduper) adjustTTLWithAdvancedML(globalRate float64) {
 			if newTypeTTL >= sd.minTTL && newTypeTTL <= sd.maxTTL {
 				typeStats.AdaptiveTTL = newTypeTTL
 			}
-
-			// Update type-specific metrics
-			solanaDuplicateRate.WithLabelValues(itemType, sd.tier).Set(typeRate)
 		}
 	}
 }
 
-// adjustTTLBasic performs basic TTL adjustment for non-enterprise tiers
+// adjustTTLBasic performs basic TTL adjustment for non-enterprise tiers.
+// The steps scale with the target rate; at the default 30% they fall at
+// 50%, 25% and 5%.
 func (sd *SolanaDeduper) adjustTTLBasic(rate float64) {
 	switch {
-	case rate > 0.50:
+	case rate > sd.targetRate*5/3:
 		// Lots of duplicates, increase TTL
 		sd.ttl = sd.ttl + 10*time.Second
 		solanaTTLAdjustments.WithLabelValues("increase", sd.tier).Inc()
-	case rate > 0.25:
+	case rate > sd.targetRate*5/6:
 		sd.ttl = sd.ttl + 5*time.Second
 		solanaTTLAdjustments.WithLabelValues("increase", sd.tier).Inc()
-	case rate < 0.05:
+	case rate < sd.targetRate/6:
 		// Few duplicates: shrink TTL
 		if sd.ttl > 10*time.Second {
 			sd.ttl = sd.ttl - 5*time.Second
@@ -625,6 +636,8 @@ func (sd *SolanaDeduper) GetStats() map[string]interface{} {
 		"current_ttl_seconds":   sd.ttl.Seconds(),
 		"min_ttl_seconds":       sd.minTTL.Seconds(),
 		"max_ttl_seconds":       sd.maxTTL.Seconds(),
+		"target_duplicate_rate": sd.targetRate,
+		"duplicates_suppressed": sd.suppressed,
 		"capacity":              sd.capacity,
 		"current_size":          len(sd.seen),
 		"slot_velocity":         sd.slotVelocity,
@@ -660,6 +673,79 @@ func (sd *SolanaDeduper) GetStats() map[string]interface{} {
 	return stats
 }
 
+// SetTTLBounds pins the range adaptive TTL adjustment may move within. The
+// global and per-type TTLs are clamped into the new range at once.
+func (sd *SolanaDeduper) SetTTLBounds(minTTL, maxTTL time.Duration) error {
+	if minTTL <= 0 || maxTTL < minTTL {
+		return fmt.Errorf("invalid TTL bounds: min %v, max %v", minTTL, maxTTL)
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.minTTL = minTTL
+	sd.maxTTL = maxTTL
+	sd.ttl = clampSolanaTTL(sd.ttl, minTTL, maxTTL)
+	for _, typeStats := range sd.typeStats {
+		if typeStats.AdaptiveTTL > 0 {
+			typeStats.AdaptiveTTL = clampSolanaTTL(typeStats.AdaptiveTTL, minTTL, maxTTL)
+		}
+	}
+	sd.publishLocked()
+
+	if sd.logger != nil {
+		sd.logger.Info("Solana dedup TTL bounds updated",
+			zap.Duration("min_ttl", minTTL),
+			zap.Duration("max_ttl", maxTTL),
+			zap.Duration("current_ttl", sd.ttl))
+	}
+	return nil
+}
+
+// SetTargetDuplicateRate sets the duplicate rate, between 0 and 1, that
+// TTL adjustment steers toward. A higher target keeps shorter TTLs.
+func (sd *SolanaDeduper) SetTargetDuplicateRate(rate float64) error {
+	if rate <= 0 || rate >= 1 {
+		return fmt.Errorf("invalid target duplicate rate %v: want between 0 and 1", rate)
+	}
+
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.targetRate = rate
+
+	if sd.logger != nil {
+		sd.logger.Info("Solana dedup target duplicate rate updated", zap.Float64("target_rate", rate))
+	}
+	return nil
+}
+
+// publishLocked sets the TTL and duplicate rate gauges; sd.mu is held
+func (sd *SolanaDeduper) publishLocked() {
+	solanaAdaptiveTTL.WithLabelValues("global", sd.tier).Set(sd.ttl.Seconds())
+	globalRate := 0.0
+	if sd.totalCount > 0 {
+		globalRate = float64(sd.dupCount) / float64(sd.totalCount)
+	}
+	solanaDuplicateRate.WithLabelValues("global", sd.tier).Set(globalRate)
+
+	for itemType, typeStats := range sd.typeStats {
+		solanaAdaptiveTTL.WithLabelValues(itemType, sd.tier).Set(sd.getAdaptiveTTL(itemType, typeStats).Seconds())
+		if typeStats.TotalSeen > 0 {
+			solanaDuplicateRate.WithLabelValues(itemType, sd.tier).Set(float64(typeStats.Duplicates) / float64(typeStats.TotalSeen))
+		}
+	}
+}
+
+func clampSolanaTTL(ttl, minTTL, maxTTL time.Duration) time.Duration {
+	if ttl < minTTL {
+		return minTTL
+	}
+	if ttl > maxTTL {
+		return maxTTL
+	}
+	return ttl
+}
+
 // Cleanup performs intelligent cleanup of expired entries
 func (sd *SolanaDeduper) Cleanup() {
 	sd.mu.Lock()
@@ -765,6 +851,30 @@ func newSolanaDeduper() *SolanaDeduper {
 	return NewSolanaDeduper("FREE", nil)
 }
 
+// newSolanaDeduperFromConfig is the relay's deduper tuned by the SOL_DEDUP_*
+// settings. Invalid settings are logged and leave the defaults.
+func newSolanaDeduperFromConfig(cfg config.Config, logger *zap.Logger) *SolanaDeduper {
+	sd := newSolanaDeduper()
+	if cfg.SolanaDedupMinTTL > 0 || cfg.SolanaDedupMaxTTL > 0 {
+		minTTL, maxTTL := cfg.SolanaDedupMinTTL, cfg.SolanaDedupMaxTTL
+		if minTTL <= 0 {
+			minTTL = DefaultSolanaDedupMinTTL
+		}
+		if maxTTL <= 0 {
+			maxTTL = DefaultSolanaDedupMaxTTL
+		}
+		if err := sd.SetTTLBounds(minTTL, maxTTL); err != nil {
+			logger.Warn("Ignoring Solana dedup TTL bounds", zap.Error(err))
+		}
+	}
+	if cfg.SolanaDedupTargetRate > 0 {
+		if err := sd.SetTargetDuplicateRate(cfg.SolanaDedupTargetRate); err != nil {
+			logger.Warn("Ignoring Solana dedup target rate", zap.Error(err))
+		}
+	}
+	return sd
+}
+
 func (sd *SolanaDeduper) isDup(key string) bool {
 	return sd.IsDuplicate(key, "unknown")
 }
@@ -780,3 +890,8 @@ func (sd *SolanaDeduper) stats() (ttl time.Duration, dupRate float64) {
 	}
 	return ttl, dupRate
 }
+
+// Deduper returns the relay's block deduper, for stats and tuning
+func (sr *SolanaRelay) Deduper() *SolanaDeduper {
+	return sr.deduper
+}
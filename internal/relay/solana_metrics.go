@@ -13,6 +13,13 @@ type solanaProm struct {
 	wsReconnects prometheus.Counter
 	dupDropped   prometheus.Counter
 	ttlSeconds   prometheus.Gauge
+
+	backfillGaps     prometheus.Counter
+	backfilledBlocks prometheus.Counter
+	backfillMissed   prometheus.Counter
+
+	subscriptionLag *prometheus.GaugeVec
+	lagRecoveries   *prometheus.CounterVec // endpoint, action (resubscribe, reconnect)
 }
 
 func newSolanaProm(namespace string) *solanaProm {
@@ -59,5 +66,40 @@ func newSolanaProm(namespace string) *solanaProm {
 			Name:      "dedup_ttl_seconds",
 			Help:      "Current adaptive dedup TTL (seconds)",
 		}),
+
+		backfillGaps: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "backfill_gaps_total",
+			Help:      "Slot gaps detected after reconnects",
+		}),
+
+		backfilledBlocks: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "backfilled_blocks_total",
+			Help:      "Blocks replayed by the backfill worker",
+		}),
+
+		backfillMissed: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "backfill_failed_slots_total",
+			Help:      "Slots the backfill worker could not fetch",
+		}),
+
+		subscriptionLag: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "subscription_lag_seconds",
+			Help:      "Time since the last slot notification per endpoint",
+		}, lbls),
+
+		lagRecoveries: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "solana",
+			Name:      "subscription_lag_recoveries_total",
+			Help:      "Lagging subscriptions renewed (resubscribe) or dropped (reconnect) per endpoint",
+		}, []string{"endpoint", "action"}),
 	}
 }
@@ -0,0 +1,167 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/endpointhealth"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Providers sometimes stop delivering head notifications while keeping the
+// socket open, so pings and heartbeats still succeed. Each subscribed
+// connection is watched for notification lag: the first time no
+// notification arrives within the expected cadence the endpoint is
+// penalised and the subscription renewed; if it stays silent the
+// connection is closed and the usual reconnect path takes over.
+
+// subscriptionState is the head subscription carried by one connection
+type subscriptionState struct {
+	mu           sync.Mutex
+	id           json.RawMessage // Provider subscription ID; nil when not subscribed
+	last         time.Time       // Last notification, or the (re)subscribe time
+	resubscribed bool            // Renewed for lag and not heard from since
+}
+
+// subscribed records a new subscription
+func (s *subscriptionState) subscribed(id json.RawMessage, now time.Time) {
+	s.mu.Lock()
+	s.id = id
+	s.last = now
+	s.mu.Unlock()
+}
+
+// notified records a notification
+func (s *subscriptionState) notified(now time.Time) {
+	s.mu.Lock()
+	s.last = now
+	s.resubscribed = false
+	s.mu.Unlock()
+}
+
+// active reports whether the connection carries a subscription
+func (s *subscriptionState) active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id != nil
+}
+
+// subscriptionID returns the provider subscription ID
+func (s *subscriptionState) subscriptionID() json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// lagWatch configures watchSubscriptionLag for one relay
+type lagWatch struct {
+	chain     string
+	threshold time.Duration // Longest expected gap between notifications
+	conns     func() []*wsConn
+	// resubscribe replaces the subscription on a connection
+	resubscribe func(ctx context.Context, wc *wsConn) error
+	healthMgr   *endpointhealth.Manager
+	label       func(endpoint string) string // Metric label (credentials redacted)
+	lagGauge    *prometheus.GaugeVec
+	recoveries  *prometheus.CounterVec // Labelled endpoint and action
+	logger      *zap.Logger
+}
+
+// watchSubscriptionLag checks subscribed connections until ctx ends
+func watchSubscriptionLag(ctx context.Context, w lagWatch) {
+	interval := w.threshold / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	reported := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			for label := range reported {
+				w.lagGauge.DeleteLabelValues(label)
+			}
+			return
+		case now := <-t.C:
+			lags := make(map[string]time.Duration)
+			for _, wc := range w.conns() {
+				lag, ok := w.check(ctx, wc, now)
+				if !ok {
+					continue
+				}
+				// Several connections may share an endpoint; report the worst
+				label := w.label(wc.endpoint)
+				if cur, seen := lags[label]; !seen || lag > cur {
+					lags[label] = lag
+				}
+				reported[label] = true
+			}
+			for label := range reported {
+				lag, ok := lags[label]
+				if !ok {
+					w.lagGauge.DeleteLabelValues(label)
+					delete(reported, label)
+					continue
+				}
+				w.lagGauge.WithLabelValues(label).Set(lag.Seconds())
+			}
+		}
+	}
+}
+
+// check measures the lag on wc and escalates when it exceeds the threshold
+func (w lagWatch) check(ctx context.Context, wc *wsConn, now time.Time) (time.Duration, bool) {
+	wc.sub.mu.Lock()
+	if wc.sub.id == nil {
+		wc.sub.mu.Unlock()
+		return 0, false
+	}
+	lag := now.Sub(wc.sub.last)
+	escalate := wc.sub.resubscribed
+	if lag > w.threshold && !escalate {
+		// Restart the clock so the renewed subscription gets a full window
+		wc.sub.resubscribed = true
+		wc.sub.last = now
+	}
+	wc.sub.mu.Unlock()
+
+	if lag <= w.threshold {
+		return lag, true
+	}
+
+	label := w.label(wc.endpoint)
+	w.healthMgr.RecordFailure(wc.endpoint, "subscription_lag")
+	if escalate {
+		w.logger.Warn("Subscription still silent after resubscribe, reconnecting",
+			zap.String("chain", w.chain),
+			zap.String("endpoint", label),
+			zap.Duration("lag", lag))
+		w.recoveries.WithLabelValues(label, "reconnect").Inc()
+		// handleMessages sees the read error and schedules the reconnect
+		wc.Close()
+		return lag, true
+	}
+
+	w.logger.Warn("Subscription lagging, resubscribing",
+		zap.String("chain", w.chain),
+		zap.String("endpoint", label),
+		zap.Duration("lag", lag),
+		zap.Duration("threshold", w.threshold))
+	w.recoveries.WithLabelValues(label, "resubscribe").Inc()
+	go func() {
+		rctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+		if err := w.resubscribe(rctx, wc); err != nil {
+			w.logger.Warn("Resubscribe failed",
+				zap.String("chain", w.chain),
+				zap.String("endpoint", label),
+				zap.Error(err))
+		}
+	}()
+	return lag, true
+}
@@ -0,0 +1,30 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/reqid"
+	"go.uber.org/zap"
+)
+
+// logUpstreamCall records an upstream call made for an API request under
+// the request's ID, so the request can be followed into the relay. Calls
+// the relay makes on its own, such as polling, carry no ID and are not
+// logged.
+func logUpstreamCall(ctx context.Context, logger *zap.Logger, endpoint, method string, start time.Time, err error) {
+	if logger == nil || reqid.FromContext(ctx) == "" {
+		return
+	}
+	fields := []zap.Field{
+		reqid.Field(ctx),
+		zap.String("endpoint", endpoint),
+		zap.String("method", method),
+		zap.Duration("latency", time.Since(start)),
+	}
+	if err != nil {
+		logger.Debug("Upstream call failed", append(fields, zap.Error(err))...)
+		return
+	}
+	logger.Debug("Upstream call", fields...)
+}
@@ -0,0 +1,78 @@
+// Package reqid carries a request ID from the API through the calls a
+// request makes, so its log lines in the API, relays and cache can be
+// found with one grep for the ID.
+//
+// The API assigns each request an ID, taking the caller's X-Request-ID
+// when it is well formed, and returns it in the response. Code running on
+// behalf of the request adds it to its log lines with Field or Logger.
+package reqid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+// Header carries the request ID in requests and responses
+const Header = "X-Request-ID"
+
+// maxLen bounds IDs taken from callers
+const maxLen = 128
+
+type contextKey struct{}
+
+// New returns a random request ID
+func New() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Valid reports whether id, taken from a caller, can be used as is. IDs
+// are limited to a safe character set so they cannot forge log lines or
+// headers.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithID returns ctx carrying id
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID in ctx, or ""
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Field returns ctx's request ID as a log field; it is skipped outside a
+// request
+func Field(ctx context.Context) zap.Field {
+	if id := FromContext(ctx); id != "" {
+		return zap.String("request_id", id)
+	}
+	return zap.Skip()
+}
+
+// Logger returns logger with ctx's request ID added to every line
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id := FromContext(ctx); id != "" {
+		return logger.With(zap.String("request_id", id))
+	}
+	return logger
+}
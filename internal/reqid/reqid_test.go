@@ -0,0 +1,47 @@
+package reqid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestValid(t *testing.T) {
+	for id, want := range map[string]bool{
+		New():                    true,
+		"req_1700000000_abc-DE":  true,
+		"trace:span.1":           true,
+		"":                       false,
+		"two words":              false,
+		"line\nbreak":            false,
+		strings.Repeat("a", 129): false,
+	} {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", id, got, want)
+		}
+	}
+}
+
+func TestLoggerAddsID(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	logger := zap.New(core)
+
+	Logger(context.Background(), logger).Info("outside")
+	ctx := WithID(context.Background(), "abc123")
+	Logger(ctx, logger).Info("inside")
+	logger.Info("field", Field(ctx))
+
+	entries := logs.All()
+	if _, ok := entries[0].ContextMap()["request_id"]; ok {
+		t.Error("request_id logged outside a request")
+	}
+	for _, e := range entries[1:] {
+		if e.ContextMap()["request_id"] != "abc123" {
+			t.Errorf("%s: request_id = %v", e.Message, e.ContextMap()["request_id"])
+		}
+	}
+}
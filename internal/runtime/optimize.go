@@ -39,6 +39,7 @@ type SystemOptimizationConfig struct {
 	EnableNUMAOptimization bool
 	EnableLatencyTuning  bool
 	CPUAffinity          []int
+	MemoryLimitBytes     int64 // Memory MemoryLimitPercent applies to; 0 estimates it
 }
 
 // DefaultConfig returns optimized configuration based on detected system capabilities
@@ -215,7 +216,10 @@ func (so *SystemOptimizer) applyMemoryOptimizations() error {
 	
 	// Set memory limit based on system memory
 	if so.config.MemoryLimitPercent > 0 {
-		systemMem := estimateSystemMemory()
+		systemMem := so.config.MemoryLimitBytes
+		if systemMem <= 0 {
+			systemMem = estimateSystemMemory()
+		}
 		memLimit := systemMem * int64(so.config.MemoryLimitPercent) / 100
 		
 		if memLimit > 0 {
@@ -577,8 +581,7 @@ func GetOptimalGOMAXPROCS() int {
 func IsRealTimeCapable() bool {
 	switch runtime.GOOS {
 	case "linux":
-		// Check for RT kernel capabilities
-		return true // Simplified check
+		return detectRTCapable("/")
 	case "windows":
 		// Check for high priority capabilities
 		return true // Simplified check
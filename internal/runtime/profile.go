@@ -0,0 +1,297 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The Enterprise and Turbo profiles assume a host that can honor them:
+// real-time scheduling, cores to pin and, for Turbo, enough of them to
+// absorb a GC running ten times as often. In a container with a CPU quota
+// or without CAP_SYS_NICE they cost latency instead of saving it. The
+// auto profile reads the host's limits and picks the highest profile the
+// configured tier asks for that the host supports; naming a profile
+// (RUNTIME_PROFILE) overrides the choice.
+
+// Profile names a SystemOptimizationConfig preset
+type Profile string
+
+const (
+	ProfileAuto       Profile = "auto"
+	ProfileDefault    Profile = "default"
+	ProfileEnterprise Profile = "enterprise"
+	ProfileTurbo      Profile = "turbo"
+)
+
+// Minimum effective CPUs for each profile
+const (
+	enterpriseMinCPUs = 4
+	turboMinCPUs      = 8
+)
+
+// ParseProfile parses a profile name; empty is auto
+func ParseProfile(s string) (Profile, error) {
+	switch p := Profile(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return ProfileAuto, nil
+	case ProfileAuto, ProfileDefault, ProfileEnterprise, ProfileTurbo:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown runtime profile %q (want auto, default, enterprise or turbo)", s)
+	}
+}
+
+// Environment is what the host lets the process use
+type Environment struct {
+	NumCPU      int     // Logical CPUs visible to the process
+	CPUs        float64 // CPUs usable under the container quota; NumCPU without one
+	CPUQuota    bool    // A cgroup CPU quota applies
+	MemoryLimit int64   // Bytes, the cgroup limit or physical memory; 0 if unknown
+	Container   bool
+	RTCapable   bool // Real-time scheduling is permitted (CAP_SYS_NICE or an RT priority rlimit)
+	NUMANodes   int  // 0 if unknown
+}
+
+// DetectEnvironment reads the host's CPU, memory, scheduling and NUMA
+// limits. Outside Linux only the CPU count is known.
+func DetectEnvironment() Environment {
+	return detectEnvironment("/")
+}
+
+// detectEnvironment reads the proc and sys files under root
+func detectEnvironment(root string) Environment {
+	env := Environment{NumCPU: runtime.NumCPU()}
+	env.CPUs = float64(env.NumCPU)
+	if runtime.GOOS != "linux" {
+		return env
+	}
+
+	if quota, ok := cgroupCPUQuota(root); ok && quota < env.CPUs {
+		env.CPUs, env.CPUQuota = quota, true
+	}
+	env.MemoryLimit = meminfoTotal(root)
+	if limit, ok := cgroupMemoryLimit(root); ok && (env.MemoryLimit == 0 || limit < env.MemoryLimit) {
+		env.MemoryLimit = limit
+	}
+	env.Container = os.Getenv("KUBERNETES_SERVICE_HOST") != "" ||
+		fileExists(filepath.Join(root, ".dockerenv")) ||
+		fileExists(filepath.Join(root, "run/.containerenv"))
+	env.RTCapable = detectRTCapable(root)
+	nodes, _ := filepath.Glob(filepath.Join(root, "sys/devices/system/node/node[0-9]*"))
+	env.NUMANodes = len(nodes)
+	return env
+}
+
+// cgroupCPUQuota returns the CPU quota in CPUs, from cgroup v2 or v1
+func cgroupCPUQuota(root string) (float64, bool) {
+	if data, err := os.ReadFile(filepath.Join(root, "sys/fs/cgroup/cpu.max")); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err1 := strconv.ParseFloat(fields[0], 64)
+			period, err2 := strconv.ParseFloat(fields[1], 64)
+			if err1 == nil && err2 == nil && quota > 0 && period > 0 {
+				return quota / period, true
+			}
+		}
+		return 0, false
+	}
+	quota, err1 := readInt(filepath.Join(root, "sys/fs/cgroup/cpu/cpu.cfs_quota_us"))
+	period, err2 := readInt(filepath.Join(root, "sys/fs/cgroup/cpu/cpu.cfs_period_us"))
+	if err1 != nil || err2 != nil || quota <= 0 || period <= 0 {
+		return 0, false
+	}
+	return float64(quota) / float64(period), true
+}
+
+// cgroupMemoryLimit returns the memory limit in bytes, from cgroup v2 or v1
+func cgroupMemoryLimit(root string) (int64, bool) {
+	limit, err := readInt(filepath.Join(root, "sys/fs/cgroup/memory.max"))
+	if err != nil {
+		limit, err = readInt(filepath.Join(root, "sys/fs/cgroup/memory/memory.limit_in_bytes"))
+	}
+	// v1 reports "unlimited" as a huge page-aligned number
+	if err != nil || limit <= 0 || limit >= 1<<62 {
+		return 0, false
+	}
+	return limit, true
+}
+
+// meminfoTotal returns physical memory in bytes, 0 if unknown
+func meminfoTotal(root string) int64 {
+	f, err := os.Open(filepath.Join(root, "proc/meminfo"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemTotal:" {
+			kb, _ := strconv.ParseInt(fields[1], 10, 64)
+			return kb * 1024
+		}
+	}
+	return 0
+}
+
+// capSysNice is the CAP_SYS_NICE bit in /proc/self/status capability masks
+const capSysNice = 23
+
+// detectRTCapable reports whether the process may use real-time
+// scheduling: it holds CAP_SYS_NICE or has a non-zero RT priority rlimit
+func detectRTCapable(root string) bool {
+	if data, err := os.ReadFile(filepath.Join(root, "proc/self/status")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if hex, ok := strings.CutPrefix(line, "CapEff:"); ok {
+				caps, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+				if err == nil && caps&(1<<capSysNice) != 0 {
+					return true
+				}
+			}
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "proc/self/limits")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if rest, ok := strings.CutPrefix(line, "Max realtime priority"); ok {
+				fields := strings.Fields(rest)
+				if len(fields) > 0 && fields[0] != "0" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// tierProfile is the profile a service tier asks for
+func tierProfile(tier string) Profile {
+	switch strings.ToLower(tier) {
+	case "turbo", "enterprise":
+		return ProfileTurbo
+	case "business":
+		return ProfileEnterprise
+	default:
+		return ProfileDefault
+	}
+}
+
+// unmet returns what env lacks to honor p
+func unmet(env Environment, p Profile) []string {
+	minCPUs := 0
+	switch p {
+	case ProfileTurbo:
+		minCPUs = turboMinCPUs
+	case ProfileEnterprise:
+		minCPUs = enterpriseMinCPUs
+	default:
+		return nil
+	}
+	var missing []string
+	if env.CPUs < float64(minCPUs) {
+		missing = append(missing, fmt.Sprintf("%s needs %d CPUs, %.1f available", p, minCPUs, env.CPUs))
+	}
+	if !env.RTCapable {
+		missing = append(missing, fmt.Sprintf("%s needs real-time scheduling (CAP_SYS_NICE or an RT priority rlimit)", p))
+	}
+	return missing
+}
+
+// SelectProfile picks the highest profile up to the one tier asks for that
+// env can honor, with the reasons for any step down
+func SelectProfile(env Environment, tier string) (Profile, []string) {
+	p := tierProfile(tier)
+	reasons := []string{fmt.Sprintf("tier %q asks for %s", tier, p)}
+	for {
+		missing := unmet(env, p)
+		if len(missing) == 0 {
+			return p, reasons
+		}
+		reasons = append(reasons, missing...)
+		if p == ProfileTurbo {
+			p = ProfileEnterprise
+		} else {
+			p = ProfileDefault
+		}
+	}
+}
+
+// ProfileConfig returns the preset for p, fitted to env: threads follow
+// a CPU quota, pinning needs whole cores, NUMA tuning needs several nodes
+// and the memory limit is taken from env's limit
+func ProfileConfig(p Profile, env Environment) *SystemOptimizationConfig {
+	var config *SystemOptimizationConfig
+	switch p {
+	case ProfileTurbo:
+		config = TurboConfig()
+	case ProfileEnterprise:
+		config = EnterpriseConfig()
+	default:
+		config = DefaultConfig()
+	}
+
+	if env.CPUQuota {
+		config.MaxThreads = max(1, int(math.Ceil(env.CPUs)))
+		config.EnableCPUPinning = false
+	}
+	if env.NUMANodes > 0 {
+		config.EnableNUMAOptimization = env.NUMANodes > 1
+	}
+	config.EnableRTPriority = config.EnableRTPriority && env.RTCapable
+	config.MemoryLimitBytes = env.MemoryLimit
+	return config
+}
+
+// AutoConfig returns the configuration for the named profile, selecting
+// one for tier from the detected environment when the name is auto. A
+// named profile the host cannot honor is still used, with a warning.
+func AutoConfig(name, tier string, logger *zap.Logger) (*SystemOptimizationConfig, error) {
+	p, err := ParseProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	env := DetectEnvironment()
+	fields := []zap.Field{
+		zap.Int("num_cpu", env.NumCPU),
+		zap.Float64("cpus", env.CPUs),
+		zap.Int64("memory_limit", env.MemoryLimit),
+		zap.Bool("container", env.Container),
+		zap.Bool("rt_capable", env.RTCapable),
+		zap.Int("numa_nodes", env.NUMANodes),
+	}
+
+	if p == ProfileAuto {
+		var reasons []string
+		p, reasons = SelectProfile(env, tier)
+		logger.Info("Selected runtime profile",
+			append(fields, zap.String("profile", string(p)), zap.Strings("reasons", reasons))...)
+	} else {
+		if missing := unmet(env, p); len(missing) > 0 {
+			logger.Warn("Configured runtime profile exceeds what the host supports",
+				append(fields, zap.String("profile", string(p)), zap.Strings("missing", missing))...)
+		} else {
+			logger.Info("Using configured runtime profile", append(fields, zap.String("profile", string(p)))...)
+		}
+	}
+	return ProfileConfig(p, env), nil
+}
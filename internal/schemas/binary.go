@@ -0,0 +1,181 @@
+package schemas
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Block messages are small but, on a Solana stream, several arrive every
+// second, and most of each JSON message is field names and RFC 3339
+// timestamps. Streams may instead carry blocks as protobuf (see
+// proto/block.v1.proto), chosen with a subprotocol or ?encoding=proto.
+// Blocks are then binary frames; markers and other messages stay JSON.
+
+// Block stream subprotocols
+const (
+	SubprotocolBlockJSON  = "sprint.block.v1+json"
+	SubprotocolBlockProto = "sprint.block.v1+proto"
+)
+
+// Field numbers of proto/block.v1.proto
+const (
+	blockFieldVersion protowire.Number = iota + 1
+	blockFieldHash
+	blockFieldHeight
+	blockFieldTimestamp
+	blockFieldDetectedAt
+	blockFieldRelayTimeMs
+	blockFieldSource
+	blockFieldTxID
+	blockFieldTier
+	blockFieldIsHeader
+	blockFieldChain
+	blockFieldStatus
+	blockFieldProcessedAt
+	blockFieldBackfilled
+	blockFieldKeyID
+	blockFieldSignature
+)
+
+// AppendBlockProto appends the protobuf encoding of m to b. Like proto3,
+// it leaves out fields with zero values.
+func AppendBlockProto(b []byte, m BlockV1) []byte {
+	b = appendVarint(b, blockFieldVersion, uint64(m.Version))
+	b = appendString(b, blockFieldHash, m.Hash)
+	b = appendVarint(b, blockFieldHeight, uint64(m.Height))
+	b = appendTime(b, blockFieldTimestamp, m.Timestamp)
+	b = appendTime(b, blockFieldDetectedAt, m.DetectedAt)
+	if m.RelayTimeMs != 0 {
+		b = protowire.AppendTag(b, blockFieldRelayTimeMs, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(m.RelayTimeMs))
+	}
+	b = appendString(b, blockFieldSource, m.Source)
+	b = appendString(b, blockFieldTxID, m.TxID)
+	b = appendString(b, blockFieldTier, m.Tier)
+	b = appendBool(b, blockFieldIsHeader, m.IsHeader)
+	b = appendString(b, blockFieldChain, string(m.Chain))
+	b = appendString(b, blockFieldStatus, string(m.Status))
+	if m.ProcessedAt != nil {
+		b = appendTime(b, blockFieldProcessedAt, *m.ProcessedAt)
+	}
+	b = appendBool(b, blockFieldBackfilled, m.Backfilled)
+	b = appendString(b, blockFieldKeyID, m.KeyID)
+	b = appendString(b, blockFieldSignature, m.Signature)
+	return b
+}
+
+// UnmarshalBlockProto decodes a block encoded by AppendBlockProto. Unknown
+// fields are skipped, so fields added to the version decode as before.
+func UnmarshalBlockProto(data []byte) (BlockV1, error) {
+	m := BlockV1{Type: TypeBlock}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return m, fmt.Errorf("block proto: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return m, fmt.Errorf("block proto field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			switch num {
+			case blockFieldVersion:
+				m.Version = int(v)
+			case blockFieldHeight:
+				m.Height = uint32(v)
+			case blockFieldTimestamp:
+				m.Timestamp = unixNano(v)
+			case blockFieldDetectedAt:
+				m.DetectedAt = unixNano(v)
+			case blockFieldIsHeader:
+				m.IsHeader = v != 0
+			case blockFieldProcessedAt:
+				t := unixNano(v)
+				m.ProcessedAt = &t
+			case blockFieldBackfilled:
+				m.Backfilled = v != 0
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return m, fmt.Errorf("block proto field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			switch num {
+			case blockFieldHash:
+				m.Hash = v
+			case blockFieldSource:
+				m.Source = v
+			case blockFieldTxID:
+				m.TxID = v
+			case blockFieldTier:
+				m.Tier = v
+			case blockFieldChain:
+				m.Chain = blocks.Chain(v)
+			case blockFieldStatus:
+				m.Status = blocks.BlockStatus(v)
+			case blockFieldKeyID:
+				m.KeyID = v
+			case blockFieldSignature:
+				m.Signature = v
+			}
+		case typ == protowire.Fixed64Type && num == blockFieldRelayTimeMs:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return m, fmt.Errorf("block proto field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			m.RelayTimeMs = math.Float64frombits(v)
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return m, fmt.Errorf("block proto field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return m, nil
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	return appendVarint(b, num, 1)
+}
+
+// appendTime encodes t as unix nanoseconds; the zero time is left out
+func appendTime(b []byte, num protowire.Number, t time.Time) []byte {
+	if t.IsZero() {
+		return b
+	}
+	return appendVarint(b, num, uint64(t.UnixNano()))
+}
+
+func unixNano(v uint64) time.Time {
+	return time.Unix(0, int64(v)).UTC()
+}
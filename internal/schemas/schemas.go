@@ -0,0 +1,204 @@
+// Package schemas defines the versioned messages sent over WebSockets — the
+// block stream and the cb-monitor feed — and serves their JSON Schema
+// documents.
+//
+// Every message carries "type" and "version". Fields may be added to a
+// version; renaming, removing or retyping a field needs a new version and a
+// new schema document alongside the old one.
+package schemas
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+)
+
+// Message types
+const (
+	TypeStatusUpdate = "status_update"
+	TypeAlert        = "alert"
+	TypeBlock        = "block"
+)
+
+// V1 is the first version of every message type
+const V1 = 1
+
+//go:embed json/*.json
+var documents embed.FS
+
+// MonitorMessage is the cb-monitor WebSocket envelope. Build it with
+// NewStatusUpdate or NewAlert so Data always matches Type.
+type MonitorMessage struct {
+	Type      string      `json:"type"`
+	Version   int         `json:"version"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// BreakerConfigV1 summarises a breaker's configuration
+type BreakerConfigV1 struct {
+	MaxFailures      int           `json:"max_failures"`
+	ResetTimeout     time.Duration `json:"reset_timeout"`
+	FailureThreshold float64       `json:"failure_threshold"`
+	EnableAdaptive   bool          `json:"enable_adaptive"`
+	EnableHealth     bool          `json:"enable_health"`
+}
+
+// BreakerStatusV1 is one breaker in a status_update
+type BreakerStatusV1 struct {
+	Name            string                                `json:"name"`
+	State           string                                `json:"state"`
+	Metrics         *circuitbreaker.CircuitBreakerMetrics `json:"metrics"`
+	Health          float64                               `json:"health"`
+	LastStateChange time.Time                             `json:"last_state_change"`
+	Configuration   BreakerConfigV1                       `json:"configuration"`
+	Instance        string                                `json:"instance,omitempty"` // Reporting instance, in a federated cb-monitor
+}
+
+// NewBreakerConfig summarises cfg
+func NewBreakerConfig(cfg circuitbreaker.EnterpriseConfig) BreakerConfigV1 {
+	return BreakerConfigV1{
+		MaxFailures:      cfg.MaxFailures,
+		ResetTimeout:     cfg.ResetTimeout,
+		FailureThreshold: cfg.FailureThreshold,
+		EnableAdaptive:   cfg.EnableAdaptive,
+		EnableHealth:     cfg.EnableHealthScoring,
+	}
+}
+
+// NewBreakerStatus reports cb's current state under name
+func NewBreakerStatus(name string, cb *circuitbreaker.EnterpriseCircuitBreaker) BreakerStatusV1 {
+	metrics := cb.GetMetrics()
+	return BreakerStatusV1{
+		Name:            name,
+		State:           cb.State().String(),
+		Metrics:         metrics,
+		Health:          metrics.HealthScore,
+		LastStateChange: metrics.LastStateChange,
+		Configuration:   NewBreakerConfig(cb.CurrentConfig()),
+	}
+}
+
+// AlertV1 is the payload of an alert
+type AlertV1 struct {
+	Level     string                 `json:"level"`
+	Kind      string                 `json:"kind"` // Condition, e.g. "open"; repeats of a kind are deduplicated
+	Message   string                 `json:"message"`
+	Breaker   string                 `json:"breaker"`
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// NewStatusUpdate wraps breaker statuses keyed by name
+func NewStatusUpdate(statuses map[string]BreakerStatusV1, ts time.Time) MonitorMessage {
+	return MonitorMessage{Type: TypeStatusUpdate, Version: V1, Timestamp: ts, Data: statuses}
+}
+
+// NewAlert wraps an alert
+func NewAlert(alert AlertV1, ts time.Time) MonitorMessage {
+	return MonitorMessage{Type: TypeAlert, Version: V1, Timestamp: ts, Data: alert}
+}
+
+// BlockV1 is a block event on the stream. The event's fields stay at the
+// top level, so clients written before versioning keep working.
+type BlockV1 struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	blocks.BlockEvent
+	// Ed25519 signature of the event (see internal/eventsign), present
+	// when the server signs events
+	KeyID     string `json:"key_id,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// NewBlock wraps a block event
+func NewBlock(ev blocks.BlockEvent) BlockV1 {
+	return BlockV1{Type: TypeBlock, Version: V1, BlockEvent: ev}
+}
+
+// Document returns the JSON Schema for a message type and version
+func Document(typ string, version int) ([]byte, bool) {
+	data, err := documents.ReadFile(fmt.Sprintf("json/%s.v%d.json", typ, version))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Ref names one schema document
+type Ref struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Path    string `json:"path"`
+}
+
+// List returns every schema document, relative to prefix
+func List(prefix string) []Ref {
+	entries, _ := documents.ReadDir("json")
+	refs := make([]Ref, 0, len(entries))
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".json")
+		i := strings.LastIndex(name, ".v")
+		if i < 0 {
+			continue
+		}
+		typ := name[:i]
+		var version int
+		if _, err := fmt.Sscanf(name[i+2:], "%d", &version); err != nil {
+			continue
+		}
+		refs = append(refs, Ref{Type: typ, Version: version, Path: fmt.Sprintf("%s/%s/v%d", prefix, typ, version)})
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Type != refs[j].Type {
+			return refs[i].Type < refs[j].Type
+		}
+		return refs[i].Version < refs[j].Version
+	})
+	return refs
+}
+
+// Handler serves the schema index at prefix and each document at
+// prefix/{type}/v{version}
+func Handler(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if rest == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"schemas": List(prefix)})
+			return
+		}
+
+		var version int
+		parts := strings.Split(rest, "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := fmt.Sscanf(parts[1], "v%d", &version); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		doc, ok := Document(parts[0], version)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/schema+json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		w.Write(doc)
+	})
+}
@@ -0,0 +1,283 @@
+package schemas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/circuitbreaker"
+)
+
+// validate checks v against the subset of JSON Schema the documents use:
+// type, const, enum, required, properties and additionalProperties
+func validate(schema map[string]interface{}, v interface{}, path string) []string {
+	var errs []string
+	if c, ok := schema["const"]; ok && fmt.Sprint(c) != fmt.Sprint(v) {
+		errs = append(errs, fmt.Sprintf("%s: %v != const %v", path, v, c))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v not in %v", path, v, enum))
+		}
+	}
+	if t, ok := schema["type"]; ok && !typeMatches(t, v) {
+		return append(errs, fmt.Sprintf("%s: %T is not %v", path, v, t))
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return errs
+	}
+	required, _ := schema["required"].([]interface{})
+	for _, r := range required {
+		if _, ok := obj[r.(string)]; !ok {
+			errs = append(errs, fmt.Sprintf("%s: missing %s", path, r))
+		}
+	}
+	props, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if sub, ok := props[k].(map[string]interface{}); ok {
+			errs = append(errs, validate(sub, obj[k], path+"."+k)...)
+			continue
+		}
+		switch extra := schema["additionalProperties"].(type) {
+		case bool:
+			if !extra {
+				errs = append(errs, fmt.Sprintf("%s: undeclared field %s", path, k))
+			}
+		case map[string]interface{}:
+			errs = append(errs, validate(extra, obj[k], path+"."+k)...)
+		}
+	}
+	return errs
+}
+
+func typeMatches(t interface{}, v interface{}) bool {
+	if list, ok := t.([]interface{}); ok {
+		for _, one := range list {
+			if typeMatches(one, v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch t {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "null":
+		return v == nil
+	}
+	return false
+}
+
+// checkStrict marshals msg and validates it against the typ/version schema.
+// Schemas forbid undeclared fields, so adding a field to a message type
+// without documenting it fails here.
+func checkStrict(t *testing.T, typ string, msg interface{}) {
+	t.Helper()
+	doc, ok := Document(typ, V1)
+	if !ok {
+		t.Fatalf("no schema for %s v1", typ)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(doc, &schema); err != nil {
+		t.Fatalf("%s schema: %v", typ, err)
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if errs := validate(schema, v, typ); len(errs) > 0 {
+		t.Fatalf("%s does not match its schema:\n%s\n%s", typ, strings.Join(errs, "\n"), b)
+	}
+}
+
+func TestBlockV1MatchesSchema(t *testing.T) {
+	now := time.Now()
+	checkStrict(t, TypeBlock, NewBlock(blocks.BlockEvent{
+		Hash:        "00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054",
+		Height:      840000,
+		Timestamp:   now,
+		DetectedAt:  now,
+		RelayTimeMs: 12.5,
+		Source:      "p2p",
+		TxID:        "abc",
+		Tier:        "enterprise",
+		IsHeader:    true,
+		Chain:       blocks.ChainBitcoin,
+		Status:      blocks.StatusPending,
+		ProcessedAt: &now,
+		Backfilled:  true,
+	}))
+}
+
+func TestAlertV1MatchesSchema(t *testing.T) {
+	checkStrict(t, TypeAlert, NewAlert(AlertV1{
+		Level:     "critical",
+		Kind:      "open",
+		Message:   "Circuit breaker is open",
+		Breaker:   "ethereum",
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"state": "open"},
+	}, time.Now()))
+}
+
+func TestStatusUpdateV1MatchesSchema(t *testing.T) {
+	checkStrict(t, TypeStatusUpdate, NewStatusUpdate(map[string]BreakerStatusV1{
+		"ethereum": {
+			Name:            "ethereum",
+			State:           "closed",
+			Metrics:         &circuitbreaker.CircuitBreakerMetrics{},
+			Health:          0.9,
+			LastStateChange: time.Now(),
+			Configuration:   BreakerConfigV1{MaxFailures: 10, ResetTimeout: time.Minute},
+			Instance:        "eu1",
+		},
+	}, time.Now()))
+}
+
+func TestHandlerServesDocuments(t *testing.T) {
+	h := Handler("/schemas")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/schemas", nil))
+	var index struct {
+		Schemas []Ref `json:"schemas"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil || len(index.Schemas) != 3 {
+		t.Fatalf("index = %s (%v)", rec.Body, err)
+	}
+
+	for _, ref := range index.Schemas {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", ref.Path, nil))
+		if rec.Code != 200 || !json.Valid(rec.Body.Bytes()) {
+			t.Fatalf("%s: status %d", ref.Path, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/schemas/block/v2", nil))
+	if rec.Code != 404 {
+		t.Fatalf("unknown version status = %d, want 404", rec.Code)
+	}
+}
+
+// solanaBlock is a typical signed Solana slot on the stream
+func solanaBlock() BlockV1 {
+	now := time.Unix(1700000000, 123456789).UTC()
+	msg := NewBlock(blocks.BlockEvent{
+		Hash:        "5eykt4UsFv8P8NJdTREpY1vzqKqZKvdpKuc147dw2N9d",
+		Height:      250000000,
+		Timestamp:   now,
+		DetectedAt:  now.Add(40 * time.Millisecond),
+		RelayTimeMs: 3.25,
+		Source:      "solana-ws",
+		Tier:        "enterprise",
+		Chain:       blocks.ChainSolana,
+		Status:      blocks.StatusPending,
+	})
+	msg.KeyID = "2024-01"
+	msg.Signature = "MEUCIQDk2n0v3Xo6f5QmKq1rWl0P7yJk8Qh8f8D0l0bX9x1e6wIgQ3n1v5bR8tZ2e1l9o0C8m7V6f5x4D3c2B1a0Z9y8X7w="
+	return msg
+}
+
+func TestBlockProtoRoundTrip(t *testing.T) {
+	msg := solanaBlock()
+	processed := msg.Timestamp.Add(time.Second)
+	msg.ProcessedAt = &processed
+	msg.IsHeader, msg.Backfilled, msg.TxID = true, true, "tx"
+
+	got, err := UnmarshalBlockProto(AppendBlockProto(nil, msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(msg)
+	if b, _ := json.Marshal(got); string(b) != string(want) {
+		t.Fatalf("round trip:\n got %s\nwant %s", b, want)
+	}
+
+	if _, err := UnmarshalBlockProto([]byte{0x12, 0x40, 'x'}); err == nil {
+		t.Fatal("truncated frame decoded")
+	}
+}
+
+// The block benchmarks report the encoded size as B/msg, to compare the
+// bytes a stream saves with protobuf alongside the CPU
+func BenchmarkBlockEncodeJSON(b *testing.B) {
+	msg := solanaBlock()
+	var data []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		data, _ = json.Marshal(msg)
+	}
+	b.ReportMetric(float64(len(data)), "B/msg")
+}
+
+func BenchmarkBlockEncodeProto(b *testing.B) {
+	msg := solanaBlock()
+	var buf []byte
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf = AppendBlockProto(buf[:0], msg)
+	}
+	b.ReportMetric(float64(len(buf)), "B/msg")
+}
+
+func BenchmarkBlockDecodeJSON(b *testing.B) {
+	data, _ := json.Marshal(solanaBlock())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var msg BlockV1
+		if err := json.Unmarshal(data, &msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data)), "B/msg")
+}
+
+func BenchmarkBlockDecodeProto(b *testing.B) {
+	data := AppendBlockProto(nil, solanaBlock())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := UnmarshalBlockProto(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(len(data)), "B/msg")
+}
@@ -42,6 +42,18 @@ func FastEntropy() ([]byte, error) {
 	return entropy, err
 }
 
+// fastEntropyBatch returns n consecutive 32-byte entropy values
+func fastEntropyBatch(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, errors.New("invalid batch size: must be positive")
+	}
+	entropy := make([]byte, n*EntropySize)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
 // SystemFingerprint gets unique system identifier for entropy
 func SystemFingerprint() ([32]byte, error) {
 	var fingerprint [32]byte
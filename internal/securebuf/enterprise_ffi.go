@@ -431,6 +431,20 @@ func FastEntropy() ([]byte, error) {
 	return output, nil
 }
 
+// fastEntropyBatch returns n consecutive 32-byte fast entropy values from a
+// single FFI call
+func fastEntropyBatch(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, errors.New("invalid batch size: must be positive")
+	}
+	output := make([]byte, n*EntropySize)
+	result := C.fast_entropy_batch_c((*C.uchar)(unsafe.Pointer(&output[0])), C.size_t(n))
+	if result != 0 {
+		return nil, fmt.Errorf("failed to generate fast entropy batch: error %d", result)
+	}
+	return output, nil
+}
+
 // HybridEntropy returns entropy using Bitcoin headers
 func HybridEntropy(blockHeaders [][]byte) ([]byte, error) {
 	output := make([]byte, 32)
@@ -0,0 +1,237 @@
+package securebuf
+
+// Batched entropy
+//
+// Every FastEntropy call is a CGO crossing for 32 bytes. Callers that need
+// entropy on a hot path request many values at once instead: a batch is
+// generated by one FFI call and delivered on a channel, and EntropyPool keeps
+// batches ready so single values are served without crossing at all.
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// EntropySize is the size of one fast entropy value
+const EntropySize = 32
+
+// MaxEntropyBatch bounds the values generated by one FFI call
+const MaxEntropyBatch = 1024
+
+// defaultEntropyPoolBatch is the batch size of the shared pool
+const defaultEntropyPoolBatch = 64
+
+// ErrEntropyPoolClosed is returned by a closed EntropyPool
+var ErrEntropyPoolClosed = errors.New("entropy pool closed")
+
+// EntropyBatch is the result of an entropy request
+type EntropyBatch struct {
+	Buffers [][EntropySize]byte
+	Err     error
+}
+
+// FastEntropyBatch returns n fast entropy values, generated with one FFI call
+// per MaxEntropyBatch values
+func FastEntropyBatch(n int) ([][EntropySize]byte, error) {
+	if n <= 0 {
+		return nil, errors.New("invalid batch size: must be positive")
+	}
+	out := make([][EntropySize]byte, 0, n)
+	for len(out) < n {
+		chunk := n - len(out)
+		if chunk > MaxEntropyBatch {
+			chunk = MaxEntropyBatch
+		}
+		raw, err := fastEntropyBatch(chunk)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < chunk; i++ {
+			var b [EntropySize]byte
+			copy(b[:], raw[i*EntropySize:])
+			out = append(out, b)
+		}
+		clear(raw)
+	}
+	return out, nil
+}
+
+// RequestFastEntropy generates n fast entropy values off the caller's
+// goroutine. The batch arrives on the returned channel, which is then closed.
+func RequestFastEntropy(n int) <-chan EntropyBatch {
+	ch := make(chan EntropyBatch, 1)
+	go func() {
+		defer close(ch)
+		buffers, err := FastEntropyBatch(n)
+		ch <- EntropyBatch{Buffers: buffers, Err: err}
+	}()
+	return ch
+}
+
+// EntropyPoolStats reports how an EntropyPool has been served
+type EntropyPoolStats struct {
+	Batches   uint64 `json:"batches"`   // Background batch requests
+	Served    uint64 `json:"served"`    // Values handed out
+	Fallbacks uint64 `json:"fallbacks"` // Batches generated synchronously because the pool was empty
+	Ready     int    `json:"ready"`     // Values waiting in the pool
+}
+
+// EntropyPool keeps fast entropy values ready, refilling in batches in the
+// background once half of them have been used
+type EntropyPool struct {
+	ready     chan [EntropySize]byte
+	refill    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	batch     int
+
+	batches   atomic.Uint64
+	served    atomic.Uint64
+	fallbacks atomic.Uint64
+}
+
+// NewEntropyPool starts a pool that requests batch values at a time and
+// holds up to twice that many
+func NewEntropyPool(batch int) *EntropyPool {
+	if batch <= 0 {
+		batch = defaultEntropyPoolBatch
+	}
+	if batch > MaxEntropyBatch {
+		batch = MaxEntropyBatch
+	}
+	p := &EntropyPool{
+		ready:  make(chan [EntropySize]byte, 2*batch),
+		refill: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		batch:  batch,
+	}
+	go p.run()
+	p.signal()
+	return p
+}
+
+var (
+	sharedPoolOnce sync.Once
+	sharedPool     *EntropyPool
+)
+
+// SharedEntropyPool returns the process-wide pool used by the cache and API
+// layers
+func SharedEntropyPool() *EntropyPool {
+	sharedPoolOnce.Do(func() {
+		sharedPool = NewEntropyPool(defaultEntropyPoolBatch)
+	})
+	return sharedPool
+}
+
+func (p *EntropyPool) signal() {
+	select {
+	case p.refill <- struct{}{}:
+	default:
+	}
+}
+
+func (p *EntropyPool) run() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-p.refill:
+		}
+
+		for len(p.ready) <= cap(p.ready)-p.batch {
+			var res EntropyBatch
+			select {
+			case res = <-RequestFastEntropy(p.batch):
+			case <-p.done:
+				return
+			}
+			p.batches.Add(1)
+			if res.Err != nil {
+				break // Get falls back and signals again
+			}
+			for _, b := range res.Buffers {
+				select {
+				case p.ready <- b:
+				default:
+					// Full: Get raced us, so drop the rest
+				}
+			}
+		}
+	}
+}
+
+// Get returns one fast entropy value. An empty pool costs the caller a
+// synchronous batch rather than a wait; the rest of the batch refills it.
+func (p *EntropyPool) Get() ([EntropySize]byte, error) {
+	select {
+	case <-p.done:
+		return [EntropySize]byte{}, ErrEntropyPoolClosed
+	default:
+	}
+
+	select {
+	case b := <-p.ready:
+		if len(p.ready) <= cap(p.ready)/2 {
+			p.signal()
+		}
+		p.served.Add(1)
+		return b, nil
+	default:
+	}
+
+	buffers, err := FastEntropyBatch(p.batch)
+	if err != nil {
+		return [EntropySize]byte{}, err
+	}
+	p.fallbacks.Add(1)
+	for _, b := range buffers[1:] {
+		select {
+		case p.ready <- b:
+		default:
+		}
+	}
+	p.served.Add(1)
+	return buffers[0], nil
+}
+
+// Read fills b with pooled entropy, so the pool can stand in for
+// crypto/rand.Reader
+func (p *EntropyPool) Read(b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		v, err := p.Get()
+		if err != nil {
+			return n, err
+		}
+		n += copy(b[n:], v[:])
+		clear(v[:])
+	}
+	return n, nil
+}
+
+// Stats returns the pool counters
+func (p *EntropyPool) Stats() EntropyPoolStats {
+	return EntropyPoolStats{
+		Batches:   p.batches.Load(),
+		Served:    p.served.Load(),
+		Fallbacks: p.fallbacks.Load(),
+		Ready:     len(p.ready),
+	}
+}
+
+// Close stops background refills and wipes the values still pooled
+func (p *EntropyPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		for {
+			select {
+			case b := <-p.ready:
+				clear(b[:])
+			default:
+				return
+			}
+		}
+	})
+}
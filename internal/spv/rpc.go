@@ -0,0 +1,138 @@
+package spv
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+// rpcInvalidAddressOrKey is bitcoind's error code for unknown blocks and
+// transactions
+const rpcInvalidAddressOrKey = -5
+
+// rpcError is a JSON-RPC error returned by bitcoind
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// RPCSource builds proofs from a bitcoind JSON-RPC endpoint
+type RPCSource struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewRPCSource returns a source for the node at url. A zero timeout uses
+// 30 seconds.
+func NewRPCSource(url, username, password string, timeout time.Duration) *RPCSource {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &RPCSource{
+		url:      url,
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Proof returns the inclusion proof for txid in blockHash. It asks for a
+// merkleblock first, which is a few hundred bytes, and falls back to the
+// full block when the node cannot produce one.
+func (s *RPCSource) Proof(ctx context.Context, txid, blockHash chainhash.Hash) (*Proof, error) {
+	var raw string
+	err := s.call(ctx, "gettxoutproof", []interface{}{[]string{txid.String()}, blockHash.String()}, &raw)
+	if err == nil {
+		mb, err := decodeMerkleBlock(raw)
+		if err != nil {
+			return nil, err
+		}
+		return ProofFromMerkleBlock(mb, txid)
+	}
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	if err := s.call(ctx, "getblock", []interface{}{blockHash.String(), 0}, &raw); err != nil {
+		var rerr *rpcError
+		if errors.As(err, &rerr) && rerr.Code == rpcInvalidAddressOrKey {
+			return nil, ErrBlockNotFound
+		}
+		return nil, err
+	}
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block hex: %w", err)
+	}
+	var block wire.MsgBlock
+	if err := block.Deserialize(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("invalid block: %w", err)
+	}
+	if block.BlockHash() != blockHash {
+		return nil, fmt.Errorf("node returned block %s for %s", block.BlockHash(), blockHash)
+	}
+	return BuildProof(&block, txid)
+}
+
+func decodeMerkleBlock(raw string) (*wire.MsgMerkleBlock, error) {
+	b, err := hex.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merkleblock hex: %w", err)
+	}
+	var mb wire.MsgMerkleBlock
+	if err := mb.BtcDecode(bytes.NewReader(b), wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		return nil, fmt.Errorf("invalid merkleblock: %w", err)
+	}
+	return &mb, nil
+}
+
+func (s *RPCSource) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "1.0",
+		"id":      "spv",
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.username, s.password)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	// bitcoind reports RPC errors with a 404 or 500 status and a JSON body
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("%s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s: %w", method, rpcResp.Error)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
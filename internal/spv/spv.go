@@ -0,0 +1,267 @@
+// Package spv builds Merkle inclusion proofs for Bitcoin transactions.
+//
+// A Proof carries the 80-byte block header and the Merkle branch from a
+// transaction to the header's Merkle root, so a light client that already
+// follows the header chain can verify a confirmation without trusting the
+// server that produced the proof. Proofs are built either from a BIP37
+// merkleblock (bitcoind's gettxoutproof) or, when that is unavailable,
+// from the full block.
+package spv
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+var (
+	// ErrTxNotInBlock is returned when the block does not contain the transaction
+	ErrTxNotInBlock = errors.New("transaction not in block")
+
+	// ErrBlockNotFound is returned when the node does not know the block
+	ErrBlockNotFound = errors.New("block not found")
+)
+
+// Proof sources
+const (
+	SourceMerkleBlock = "merkleblock"
+	SourceBlock       = "block"
+)
+
+// Proof is a Merkle inclusion proof. Hashes are hex in display (reversed)
+// byte order, as in bitcoind's RPC output.
+type Proof struct {
+	TxID        string   `json:"txid"`
+	BlockHash   string   `json:"block_hash"`
+	Header      string   `json:"header"` // Serialized 80-byte block header
+	MerkleRoot  string   `json:"merkle_root"`
+	Position    uint32   `json:"pos"` // Index of the transaction in the block
+	TxCount     uint32   `json:"tx_count"`
+	Branch      []string `json:"merkle"` // Sibling hashes from the leaf up to the root
+	Source      string   `json:"source"`
+	MerkleBlock string   `json:"merkleblock,omitempty"` // Raw BIP37 merkleblock when that was the source
+}
+
+// BuildProof builds the proof for txid from a full block
+func BuildProof(block *wire.MsgBlock, txid chainhash.Hash) (*Proof, error) {
+	level := make([]chainhash.Hash, len(block.Transactions))
+	pos := -1
+	for i, tx := range block.Transactions {
+		level[i] = tx.TxHash()
+		if level[i] == txid {
+			pos = i
+		}
+	}
+	if pos < 0 {
+		return nil, ErrTxNotInBlock
+	}
+
+	var branch []chainhash.Hash
+	for idx := pos; len(level) > 1; idx /= 2 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, level[idx^1])
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			next[i] = hashPair(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+
+	return newProof(&block.Header, txid, uint32(pos), uint32(len(block.Transactions)), branch, SourceBlock)
+}
+
+// ProofFromMerkleBlock extracts the proof for txid from a BIP37 merkleblock
+func ProofFromMerkleBlock(mb *wire.MsgMerkleBlock, txid chainhash.Hash) (*Proof, error) {
+	if mb.Transactions == 0 {
+		return nil, ErrTxNotInBlock
+	}
+
+	d := &pmtDecoder{txCount: mb.Transactions, hashes: mb.Hashes, flags: mb.Flags, target: txid}
+	height := uint(0)
+	for d.width(height) > 1 {
+		height++
+	}
+	node, err := d.walk(height, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !node.found {
+		return nil, ErrTxNotInBlock
+	}
+	if node.hash != mb.Header.MerkleRoot {
+		return nil, fmt.Errorf("merkleblock root mismatch")
+	}
+
+	p, err := newProof(&mb.Header, txid, node.leaf, mb.Transactions, node.branch, SourceMerkleBlock)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := mb.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err == nil {
+		p.MerkleBlock = hex.EncodeToString(buf.Bytes())
+	}
+	return p, nil
+}
+
+func newProof(header *wire.BlockHeader, txid chainhash.Hash, pos, txCount uint32, branch []chainhash.Hash, source string) (*Proof, error) {
+	var buf bytes.Buffer
+	if err := header.Serialize(&buf); err != nil {
+		return nil, fmt.Errorf("failed to serialize header: %w", err)
+	}
+	p := &Proof{
+		TxID:       txid.String(),
+		BlockHash:  header.BlockHash().String(),
+		Header:     hex.EncodeToString(buf.Bytes()),
+		MerkleRoot: header.MerkleRoot.String(),
+		Position:   pos,
+		TxCount:    txCount,
+		Branch:     make([]string, len(branch)),
+		Source:     source,
+	}
+	for i, h := range branch {
+		p.Branch[i] = h.String()
+	}
+	return p, nil
+}
+
+// Verify checks the proof the way a light client would: the header hashes
+// to BlockHash and the branch folds TxID into the header's Merkle root.
+// It does not check proof of work or that the block is on the best chain.
+func (p *Proof) Verify() error {
+	raw, err := hex.DecodeString(p.Header)
+	if err != nil || len(raw) != wire.MaxBlockHeaderPayload {
+		return fmt.Errorf("invalid header")
+	}
+	var header wire.BlockHeader
+	if err := header.Deserialize(bytes.NewReader(raw)); err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	if header.BlockHash().String() != p.BlockHash {
+		return fmt.Errorf("header does not hash to block %s", p.BlockHash)
+	}
+
+	h, err := chainhash.NewHashFromStr(p.TxID)
+	if err != nil {
+		return fmt.Errorf("invalid txid: %w", err)
+	}
+	acc := *h
+	pos := p.Position
+	for _, s := range p.Branch {
+		sibling, err := chainhash.NewHashFromStr(s)
+		if err != nil {
+			return fmt.Errorf("invalid branch hash: %w", err)
+		}
+		if pos&1 == 0 {
+			acc = hashPair(acc, *sibling)
+		} else {
+			acc = hashPair(*sibling, acc)
+		}
+		pos >>= 1
+	}
+	if acc != header.MerkleRoot {
+		return fmt.Errorf("branch does not match merkle root")
+	}
+	return nil
+}
+
+func hashPair(left, right chainhash.Hash) chainhash.Hash {
+	var buf [chainhash.HashSize * 2]byte
+	copy(buf[:chainhash.HashSize], left[:])
+	copy(buf[chainhash.HashSize:], right[:])
+	return chainhash.DoubleHashH(buf[:])
+}
+
+// pmtDecoder walks a BIP37 partial Merkle tree depth first, consuming flag
+// bits and hashes in the order they were written
+type pmtDecoder struct {
+	txCount    uint32
+	hashes     []*chainhash.Hash
+	flags      []byte
+	bitsUsed   int
+	hashesUsed int
+	target     chainhash.Hash
+}
+
+// pmtNode is a subtree result; branch and leaf are set when the target
+// transaction is under it
+type pmtNode struct {
+	hash   chainhash.Hash
+	found  bool
+	branch []chainhash.Hash
+	leaf   uint32
+}
+
+func (d *pmtDecoder) width(height uint) uint32 {
+	return (d.txCount + (1 << height) - 1) >> height
+}
+
+func (d *pmtDecoder) nextBit() (bool, error) {
+	if d.bitsUsed >= len(d.flags)*8 {
+		return false, fmt.Errorf("merkleblock: flag bits exhausted")
+	}
+	bit := d.flags[d.bitsUsed/8]&(1<<(uint(d.bitsUsed)%8)) != 0
+	d.bitsUsed++
+	return bit, nil
+}
+
+func (d *pmtDecoder) nextHash() (chainhash.Hash, error) {
+	if d.hashesUsed >= len(d.hashes) {
+		return chainhash.Hash{}, fmt.Errorf("merkleblock: hashes exhausted")
+	}
+	h := *d.hashes[d.hashesUsed]
+	d.hashesUsed++
+	return h, nil
+}
+
+func (d *pmtDecoder) walk(height uint, pos uint32) (pmtNode, error) {
+	parentOfMatch, err := d.nextBit()
+	if err != nil {
+		return pmtNode{}, err
+	}
+	if height == 0 || !parentOfMatch {
+		h, err := d.nextHash()
+		if err != nil {
+			return pmtNode{}, err
+		}
+		node := pmtNode{hash: h}
+		if height == 0 && parentOfMatch && h == d.target {
+			node.found, node.leaf = true, pos
+		}
+		return node, nil
+	}
+
+	left, err := d.walk(height-1, pos*2)
+	if err != nil {
+		return pmtNode{}, err
+	}
+	right := left
+	if pos*2+1 < d.width(height-1) {
+		if right, err = d.walk(height-1, pos*2+1); err != nil {
+			return pmtNode{}, err
+		}
+		// Identical siblings would let a tree with a duplicated
+		// transaction hash to the same root (CVE-2012-2459)
+		if right.hash == left.hash {
+			return pmtNode{}, fmt.Errorf("merkleblock: duplicate subtree hashes")
+		}
+	} else {
+		right.found = false
+	}
+
+	node := pmtNode{hash: hashPair(left.hash, right.hash)}
+	switch {
+	case left.found:
+		node.found, node.leaf = true, left.leaf
+		node.branch = append(left.branch, right.hash)
+	case right.found:
+		node.found, node.leaf = true, right.leaf
+		node.branch = append(right.branch, left.hash)
+	}
+	return node, nil
+}
@@ -0,0 +1,93 @@
+package spv
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/bloom"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+)
+
+func testBlock(n int) *wire.MsgBlock {
+	block := wire.NewMsgBlock(wire.NewBlockHeader(1, &chainhash.Hash{}, &chainhash.Hash{}, 0x1d00ffff, 0))
+	for i := 0; i < n; i++ {
+		tx := wire.NewMsgTx(1)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: uint32(i)}, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(int64(i), nil))
+		block.AddTransaction(tx)
+	}
+	hashes := make([]chainhash.Hash, n)
+	for i, tx := range block.Transactions {
+		hashes[i] = tx.TxHash()
+	}
+	for len(hashes) > 1 {
+		if len(hashes)%2 == 1 {
+			hashes = append(hashes, hashes[len(hashes)-1])
+		}
+		next := make([]chainhash.Hash, len(hashes)/2)
+		for i := range next {
+			next[i] = hashPair(hashes[2*i], hashes[2*i+1])
+		}
+		hashes = next
+	}
+	block.Header.MerkleRoot = hashes[0]
+	return block
+}
+
+func TestBuildProofVerifies(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 8} {
+		block := testBlock(n)
+		for i, tx := range block.Transactions {
+			p, err := BuildProof(block, tx.TxHash())
+			if err != nil {
+				t.Fatalf("n=%d tx=%d: %v", n, i, err)
+			}
+			if p.Position != uint32(i) {
+				t.Fatalf("n=%d tx=%d: pos = %d", n, i, p.Position)
+			}
+			if err := p.Verify(); err != nil {
+				t.Fatalf("n=%d tx=%d: Verify: %v", n, i, err)
+			}
+		}
+	}
+
+	if _, err := BuildProof(testBlock(3), chainhash.Hash{1}); !errors.Is(err, ErrTxNotInBlock) {
+		t.Fatalf("missing tx err = %v, want ErrTxNotInBlock", err)
+	}
+}
+
+func TestProofFromMerkleBlock(t *testing.T) {
+	block := testBlock(7)
+	for i, tx := range block.Transactions {
+		txid := tx.TxHash()
+		filter := bloom.NewFilter(1, 0, 0.0001, wire.BloomUpdateNone)
+		filter.AddHash(&txid)
+		mb, _ := bloom.NewMerkleBlock(btcutil.NewBlock(block), filter)
+
+		p, err := ProofFromMerkleBlock(mb, txid)
+		if err != nil {
+			t.Fatalf("tx=%d: %v", i, err)
+		}
+		want, _ := BuildProof(block, txid)
+		if p.Position != want.Position || len(p.Branch) != len(want.Branch) {
+			t.Fatalf("tx=%d: merkleblock proof %+v differs from block proof %+v", i, p, want)
+		}
+		if err := p.Verify(); err != nil {
+			t.Fatalf("tx=%d: Verify: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyRejectsTamperedBranch(t *testing.T) {
+	block := testBlock(4)
+	p, err := BuildProof(block, block.Transactions[2].TxHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Branch[0] = chainhash.Hash{9}.String()
+	if err := p.Verify(); err == nil {
+		t.Fatal("tampered branch verified")
+	}
+}
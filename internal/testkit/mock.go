@@ -0,0 +1,476 @@
+package testkit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+)
+
+// ErrNoBlock is returned for the latest block before the first Advance
+var ErrNoBlock = errors.New("testkit: no block produced yet")
+
+// ErrNotConnected is returned by MockRelay calls that need a connection
+var ErrNotConnected = errors.New("testkit: relay not connected")
+
+// scriptEpoch is the timestamp of height 0 in generated scripts
+var scriptEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// blockSpacing is each chain's nominal block interval, used to space
+// generated timestamps
+func blockSpacing(chain blocks.Chain) time.Duration {
+	switch chain {
+	case blocks.ChainBitcoin:
+		return 10 * time.Minute
+	case blocks.ChainEthereum:
+		return 12 * time.Second
+	case blocks.ChainSolana:
+		return 400 * time.Millisecond
+	default:
+		return time.Minute
+	}
+}
+
+// Blocks returns n consecutive blocks of chain starting at height from.
+// The same arguments always give the same blocks: hashes derive from
+// chain and height, and timestamps from a fixed epoch.
+func Blocks(chain blocks.Chain, from uint32, n int) []blocks.BlockEvent {
+	out := make([]blocks.BlockEvent, n)
+	for i := range out {
+		height := from + uint32(i)
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", chain, height)))
+		ts := scriptEpoch.Add(time.Duration(height) * blockSpacing(chain))
+		out[i] = blocks.BlockEvent{
+			Hash:       hex.EncodeToString(sum[:]),
+			Height:     height,
+			Timestamp:  ts,
+			DetectedAt: ts,
+			Source:     "testkit",
+			Tier:       "mock",
+			Chain:      chain,
+			Status:     blocks.StatusProcessed,
+		}
+	}
+	return out
+}
+
+// feed plays a block script to any number of streams. Blocks are only
+// produced by advance, so tests control exactly when each one appears.
+type feed struct {
+	mu        sync.Mutex
+	script    []blocks.BlockEvent
+	published []blocks.BlockEvent
+	subs      map[chan blocks.BlockEvent]struct{}
+	err       error
+	failed    chan struct{} // Closed while err is set
+}
+
+func newFeed(script []blocks.BlockEvent) *feed {
+	return &feed{
+		script: append([]blocks.BlockEvent(nil), script...),
+		subs:   make(map[chan blocks.BlockEvent]struct{}),
+		failed: make(chan struct{}),
+	}
+}
+
+// advance publishes the next scripted block. It reports false once the
+// script is exhausted.
+func (f *feed) advance() (blocks.BlockEvent, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.published) == len(f.script) {
+		return blocks.BlockEvent{}, false
+	}
+	block := f.script[len(f.published)]
+	f.published = append(f.published, block)
+	for sub := range f.subs {
+		// Buffered for the whole script, so this never blocks
+		sub <- block
+	}
+	return block, true
+}
+
+// setError makes calls fail with err and ends open streams; nil recovers
+func (f *feed) setError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case err != nil && f.err == nil:
+		close(f.failed)
+	case err == nil && f.err != nil:
+		f.failed = make(chan struct{})
+	}
+	f.err = err
+}
+
+func (f *feed) latest() (blocks.BlockEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return blocks.BlockEvent{}, f.err
+	}
+	if len(f.published) == 0 {
+		return blocks.BlockEvent{}, ErrNoBlock
+	}
+	return f.published[len(f.published)-1], nil
+}
+
+// find returns the first published block matching
+func (f *feed) find(match func(blocks.BlockEvent) bool) (blocks.BlockEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return blocks.BlockEvent{}, f.err
+	}
+	for _, b := range f.published {
+		if match(b) {
+			return b, nil
+		}
+	}
+	return blocks.BlockEvent{}, errors.New("testkit: block not found")
+}
+
+// stream sends blocks published from now on to out until ctx ends or the
+// feed fails
+func (f *feed) stream(ctx context.Context, out chan<- blocks.BlockEvent) error {
+	f.mu.Lock()
+	if f.err != nil {
+		err := f.err
+		f.mu.Unlock()
+		return err
+	}
+	sub := make(chan blocks.BlockEvent, len(f.script))
+	f.subs[sub] = struct{}{}
+	failed := f.failed
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.subs, sub)
+		f.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-failed:
+			return f.failure()
+		case block := <-sub:
+			select {
+			case out <- block:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-failed:
+				return f.failure()
+			}
+		}
+	}
+}
+
+func (f *feed) failure() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err == nil {
+		return errors.New("testkit: backend failed")
+	}
+	return f.err
+}
+
+func (f *feed) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.published)
+}
+
+// MockBackend is a ChainBackend that plays a fixed block script. Nothing
+// happens on its own: each Advance publishes the next block to
+// GetLatestBlock and every open stream.
+type MockBackend struct {
+	feed *feed
+	mu   sync.Mutex
+	pool int
+	eta  float64
+}
+
+// NewMockBackend returns a backend that will play script, e.g. one built
+// with Blocks
+func NewMockBackend(script []blocks.BlockEvent) *MockBackend {
+	return &MockBackend{feed: newFeed(script), eta: 600}
+}
+
+// Advance publishes the next scripted block, reporting false once the
+// script is exhausted
+func (m *MockBackend) Advance() (blocks.BlockEvent, bool) { return m.feed.advance() }
+
+// SetError makes GetLatestBlock and StreamBlocks fail with err and ends
+// open streams with it; nil recovers
+func (m *MockBackend) SetError(err error) { m.feed.setError(err) }
+
+// Driver returns the Driver the contract tests use to control m
+func (m *MockBackend) Driver() Driver {
+	return Driver{Advance: func() { m.Advance() }, Fail: m.SetError}
+}
+
+// SetMempoolSize sets the size GetMempoolSize reports
+func (m *MockBackend) SetMempoolSize(n int) {
+	m.mu.Lock()
+	m.pool = n
+	m.mu.Unlock()
+}
+
+// GetLatestBlock implements ChainBackend
+func (m *MockBackend) GetLatestBlock() (blocks.BlockEvent, error) { return m.feed.latest() }
+
+// GetMempoolSize implements ChainBackend
+func (m *MockBackend) GetMempoolSize() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pool
+}
+
+// GetStatus implements ChainBackend
+func (m *MockBackend) GetStatus() map[string]interface{} {
+	status := map[string]interface{}{
+		"status":       "connected",
+		"mempool_size": m.GetMempoolSize(),
+		"blocks":       m.feed.count(),
+	}
+	if block, err := m.feed.latest(); err == nil {
+		status["chain"] = string(block.Chain)
+		status["block_height"] = block.Height
+	} else {
+		status["status"] = "unavailable"
+	}
+	return status
+}
+
+// GetPredictiveETA implements ChainBackend
+func (m *MockBackend) GetPredictiveETA() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.eta
+}
+
+// StreamBlocks implements ChainBackend. It returns ctx's error once ctx
+// ends, or the injected error.
+func (m *MockBackend) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
+	return m.feed.stream(ctx, blockChan)
+}
+
+// MockRelay is a relay.RelayClient that plays a fixed block script, like
+// MockBackend. Block calls need Connect first.
+type MockRelay struct {
+	feed *feed
+
+	mu        sync.Mutex
+	connected bool
+	config    relay.RelayConfig
+	peers     int
+	since     time.Time
+}
+
+// NewMockRelay returns a disconnected relay that will play script
+func NewMockRelay(network string, script []blocks.BlockEvent) *MockRelay {
+	return &MockRelay{
+		feed:   newFeed(script),
+		config: relay.RelayConfig{Network: network, Endpoints: []string{"mock://" + network}, Timeout: time.Second},
+		peers:  1,
+	}
+}
+
+// Advance publishes the next scripted block, reporting false once the
+// script is exhausted
+func (m *MockRelay) Advance() (blocks.BlockEvent, bool) { return m.feed.advance() }
+
+// SetError makes block calls and Connect fail with err and ends open
+// streams with it; nil recovers
+func (m *MockRelay) SetError(err error) { m.feed.setError(err) }
+
+// Driver returns the Driver the contract tests use to control m
+func (m *MockRelay) Driver() Driver {
+	return Driver{Advance: func() { m.Advance() }, Fail: m.SetError}
+}
+
+// Connect implements relay.RelayClient
+func (m *MockRelay) Connect(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.feed.mu.Lock()
+	err := m.feed.err
+	m.feed.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.connected, m.since = true, time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+// Disconnect implements relay.RelayClient
+func (m *MockRelay) Disconnect() error {
+	m.mu.Lock()
+	m.connected = false
+	m.mu.Unlock()
+	return nil
+}
+
+// IsConnected implements relay.RelayClient
+func (m *MockRelay) IsConnected() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.connected
+}
+
+// StreamBlocks implements relay.RelayClient
+func (m *MockRelay) StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error {
+	if !m.IsConnected() {
+		return ErrNotConnected
+	}
+	return m.feed.stream(ctx, blockChan)
+}
+
+// GetLatestBlock implements relay.RelayClient
+func (m *MockRelay) GetLatestBlock() (*blocks.BlockEvent, error) {
+	if !m.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	block, err := m.feed.latest()
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetBlockByHash implements relay.RelayClient for published blocks
+func (m *MockRelay) GetBlockByHash(hash string) (*blocks.BlockEvent, error) {
+	return m.lookup(func(b blocks.BlockEvent) bool { return b.Hash == hash })
+}
+
+// GetBlockByHeight implements relay.RelayClient for published blocks
+func (m *MockRelay) GetBlockByHeight(height uint64) (*blocks.BlockEvent, error) {
+	return m.lookup(func(b blocks.BlockEvent) bool { return uint64(b.Height) == height })
+}
+
+func (m *MockRelay) lookup(match func(blocks.BlockEvent) bool) (*blocks.BlockEvent, error) {
+	if !m.IsConnected() {
+		return nil, ErrNotConnected
+	}
+	block, err := m.feed.find(match)
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// GetNetworkInfo implements relay.RelayClient
+func (m *MockRelay) GetNetworkInfo() (*relay.NetworkInfo, error) {
+	block, err := m.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &relay.NetworkInfo{
+		Network:     m.GetConfig().Network,
+		BlockHeight: uint64(block.Height),
+		BlockHash:   block.Hash,
+		PeerCount:   m.GetPeerCount(),
+		Timestamp:   block.Timestamp,
+	}, nil
+}
+
+// GetPeerCount implements relay.RelayClient
+func (m *MockRelay) GetPeerCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.connected {
+		return 0
+	}
+	return m.peers
+}
+
+// GetSyncStatus implements relay.RelayClient. A mock is always synced to
+// the last published block.
+func (m *MockRelay) GetSyncStatus() (*relay.SyncStatus, error) {
+	block, err := m.GetLatestBlock()
+	if err != nil {
+		return nil, err
+	}
+	return &relay.SyncStatus{
+		CurrentHeight: uint64(block.Height),
+		HighestHeight: uint64(block.Height),
+		SyncProgress:  1,
+	}, nil
+}
+
+// GetHealth implements relay.RelayClient
+func (m *MockRelay) GetHealth() (*relay.HealthStatus, error) {
+	m.feed.mu.Lock()
+	err := m.feed.err
+	m.feed.mu.Unlock()
+
+	h := &relay.HealthStatus{IsHealthy: m.IsConnected() && err == nil, LastSeen: time.Now(), ConnectionState: "disconnected"}
+	if m.IsConnected() {
+		h.ConnectionState = "connected"
+	}
+	if err != nil {
+		h.ErrorMessage = err.Error()
+	}
+	return h, nil
+}
+
+// GetMetrics implements relay.RelayClient
+func (m *MockRelay) GetMetrics() (*relay.RelayMetrics, error) {
+	m.mu.Lock()
+	since := m.since
+	m.mu.Unlock()
+
+	metrics := &relay.RelayMetrics{BlocksReceived: int64(m.feed.count())}
+	if !since.IsZero() {
+		metrics.ConnectionUptime = time.Since(since)
+	}
+	if block, err := m.feed.latest(); err == nil {
+		metrics.LastBlockReceived = block.DetectedAt
+	}
+	return metrics, nil
+}
+
+// mockFeatures are the features MockRelay supports
+var mockFeatures = []relay.Feature{relay.FeatureBlockStreaming, relay.FeatureHistoricalData}
+
+// SupportsFeature implements relay.RelayClient
+func (m *MockRelay) SupportsFeature(feature relay.Feature) bool {
+	for _, f := range mockFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSupportedFeatures implements relay.RelayClient
+func (m *MockRelay) GetSupportedFeatures() []relay.Feature {
+	return append([]relay.Feature(nil), mockFeatures...)
+}
+
+// UpdateConfig implements relay.RelayClient
+func (m *MockRelay) UpdateConfig(cfg relay.RelayConfig) error {
+	m.mu.Lock()
+	m.config = cfg
+	m.mu.Unlock()
+	return nil
+}
+
+// GetConfig implements relay.RelayClient
+func (m *MockRelay) GetConfig() relay.RelayConfig {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.config
+}
@@ -0,0 +1,419 @@
+// Package testkit checks ChainBackend and relay.RelayClient
+// implementations against the behaviour the API relies on, and provides
+// scripted mocks of both for API and end-to-end tests. Run the contract
+// from the implementation's own tests:
+//
+//	func TestContract(t *testing.T) {
+//		testkit.RunChainBackend(t, func(t *testing.T) (testkit.ChainBackend, testkit.Driver) {
+//			m := testkit.NewMockBackend(testkit.Blocks(blocks.ChainBitcoin, 850000, 100))
+//			return wrap(m), m.Driver()
+//		})
+//	}
+//
+// The contract:
+//   - GetLatestBlock never returns a block without a hash and a nil error,
+//     and heights it reports do not go backwards.
+//   - StreamBlocks delivers blocks in non-decreasing height order, none of
+//     them newer than what GetLatestBlock reports afterwards.
+//   - StreamBlocks returns promptly, with nil or the context's error, once
+//     its context ends, including a context that ended before the call,
+//     and sends nothing after returning.
+//   - When the block source fails, open streams end with an error and
+//     GetLatestBlock returns an error or a block it already reported.
+//   - Every method is safe for concurrent use.
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+)
+
+// ChainBackend is the block source interface served by the API. It has
+// the method set of api.ChainBackend, which testkit does not import so
+// that package api's own tests can use it.
+type ChainBackend interface {
+	GetLatestBlock() (blocks.BlockEvent, error)
+	GetMempoolSize() int
+	GetStatus() map[string]interface{}
+	GetPredictiveETA() float64
+	StreamBlocks(ctx context.Context, blockChan chan<- blocks.BlockEvent) error
+}
+
+// Driver controls the block source behind an implementation under test.
+// Either func may be nil.
+type Driver struct {
+	// Advance makes the source produce its next block. Nil for sources
+	// that produce blocks on their own, such as a regtest node.
+	Advance func()
+	// Fail makes the source fail with err, or recover for nil. Nil skips
+	// the failure tests.
+	Fail func(err error)
+}
+
+// blockTimeout bounds every wait for a block or a returning stream
+const blockTimeout = 5 * time.Second
+
+// errInjected is the failure the contract injects through Driver.Fail
+var errInjected = errors.New("testkit: injected failure")
+
+// subject is the part of both interfaces the shared tests exercise
+type subject struct {
+	latest func() (blocks.BlockEvent, error)
+	stream func(ctx context.Context, out chan<- blocks.BlockEvent) error
+	drv    Driver
+}
+
+var sharedTests = []struct {
+	name string
+	fn   func(t *testing.T, s subject)
+}{
+	{"LatestBlock", testLatestBlock},
+	{"StreamDelivers", testStreamDelivers},
+	{"StreamCancel", testStreamCancel},
+	{"CancelledContext", testCancelledContext},
+	{"Failure", testFailure},
+}
+
+// RunChainBackend runs the contract tests against ChainBackends returned
+// by open, one per subtest
+func RunChainBackend(t *testing.T, open func(t *testing.T) (ChainBackend, Driver)) {
+	for _, tt := range sharedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, drv := open(t)
+			tt.fn(t, subject{latest: b.GetLatestBlock, stream: b.StreamBlocks, drv: drv})
+		})
+	}
+	t.Run("Concurrent", func(t *testing.T) {
+		b, drv := open(t)
+		testConcurrentBackend(t, b, drv)
+	})
+}
+
+// RunRelayClient runs the contract tests against RelayClients returned by
+// open, one per subtest. open returns a disconnected client; each subtest
+// connects it and disconnects it at the end.
+func RunRelayClient(t *testing.T, open func(t *testing.T) (relay.RelayClient, Driver)) {
+	connect := func(t *testing.T) (relay.RelayClient, Driver) {
+		c, drv := open(t)
+		ctx, cancel := context.WithTimeout(context.Background(), blockTimeout)
+		defer cancel()
+		if err := c.Connect(ctx); err != nil {
+			t.Fatalf("Connect: %v", err)
+		}
+		t.Cleanup(func() { _ = c.Disconnect() })
+		return c, drv
+	}
+
+	for _, tt := range sharedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, drv := connect(t)
+			latest := func() (blocks.BlockEvent, error) {
+				block, err := c.GetLatestBlock()
+				switch {
+				case err != nil:
+					return blocks.BlockEvent{}, err
+				case block == nil:
+					t.Fatal("GetLatestBlock returned neither a block nor an error")
+				}
+				return *block, nil
+			}
+			tt.fn(t, subject{latest: latest, stream: c.StreamBlocks, drv: drv})
+		})
+	}
+	t.Run("Connection", func(t *testing.T) {
+		c, _ := connect(t)
+		testConnection(t, c)
+	})
+	t.Run("BlockLookup", func(t *testing.T) {
+		c, drv := connect(t)
+		testBlockLookup(t, c, drv)
+	})
+	t.Run("Features", func(t *testing.T) {
+		c, _ := connect(t)
+		for _, f := range c.GetSupportedFeatures() {
+			if !c.SupportsFeature(f) {
+				t.Errorf("feature %q listed but SupportsFeature is false", f)
+			}
+		}
+	})
+}
+
+// checkBlock fails unless block looks like a real block
+func checkBlock(t *testing.T, what string, block blocks.BlockEvent) {
+	t.Helper()
+	if block.Hash == "" {
+		t.Fatalf("%s: block at height %d has no hash", what, block.Height)
+	}
+}
+
+// advance asks the source for a block when it can be driven
+func advance(drv Driver) {
+	if drv.Advance != nil {
+		drv.Advance()
+	}
+}
+
+// waitLatest polls GetLatestBlock, advancing the source, until it returns
+// a block
+func waitLatest(t *testing.T, s subject) blocks.BlockEvent {
+	t.Helper()
+	deadline := time.Now().Add(blockTimeout)
+	for {
+		block, err := s.latest()
+		if err == nil {
+			checkBlock(t, "GetLatestBlock", block)
+			return block
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GetLatestBlock: no block within %v: %v", blockTimeout, err)
+		}
+		advance(s.drv)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// openStream runs StreamBlocks in the background. The returned channel
+// yields its error once it returns.
+func openStream(ctx context.Context, s subject) (<-chan blocks.BlockEvent, <-chan error) {
+	out := make(chan blocks.BlockEvent, 16)
+	done := make(chan error, 1)
+	go func() { done <- s.stream(ctx, out) }()
+	return out, done
+}
+
+// nextStreamed waits for the stream's next block, advancing the source
+// until one arrives. A stream subscribes asynchronously, so the first
+// advances may be missed.
+func nextStreamed(t *testing.T, s subject, out <-chan blocks.BlockEvent, done <-chan error) blocks.BlockEvent {
+	t.Helper()
+	deadline := time.NewTimer(blockTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	advance(s.drv)
+	for {
+		select {
+		case block := <-out:
+			checkBlock(t, "StreamBlocks", block)
+			return block
+		case err := <-done:
+			t.Fatalf("StreamBlocks returned before delivering a block: %v", err)
+		case <-tick.C:
+			advance(s.drv)
+		case <-deadline.C:
+			t.Fatalf("StreamBlocks: no block within %v", blockTimeout)
+		}
+	}
+}
+
+// waitReturn waits for a stream to return and checks its error is nil or
+// ctx's
+func waitReturn(t *testing.T, ctx context.Context, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil && !errors.Is(err, ctx.Err()) {
+			t.Fatalf("StreamBlocks returned %v after its context ended; want nil or %v", err, ctx.Err())
+		}
+	case <-time.After(blockTimeout):
+		t.Fatalf("StreamBlocks still running %v after its context ended", blockTimeout)
+	}
+}
+
+func testLatestBlock(t *testing.T, s subject) {
+	first := waitLatest(t, s)
+	advance(s.drv)
+	second := waitLatest(t, s)
+	if second.Height < first.Height {
+		t.Fatalf("latest block went backwards: %d then %d", first.Height, second.Height)
+	}
+	if s.drv.Advance != nil && second.Hash == first.Hash {
+		t.Fatalf("latest block unchanged at height %d after Advance", first.Height)
+	}
+}
+
+func testStreamDelivers(t *testing.T, s subject) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, done := openStream(ctx, s)
+
+	n := 1
+	if s.drv.Advance != nil {
+		n = 3
+	}
+	var last blocks.BlockEvent
+	for i := 0; i < n; i++ {
+		block := nextStreamed(t, s, out, done)
+		if i > 0 && block.Height < last.Height {
+			t.Fatalf("stream went backwards: %d then %d", last.Height, block.Height)
+		}
+		last = block
+	}
+	if latest := waitLatest(t, s); latest.Height < last.Height {
+		t.Fatalf("GetLatestBlock at %d behind streamed block %d", latest.Height, last.Height)
+	}
+	cancel()
+	waitReturn(t, ctx, done)
+}
+
+func testStreamCancel(t *testing.T, s subject) {
+	ctx, cancel := context.WithCancel(context.Background())
+	out, done := openStream(ctx, s)
+	nextStreamed(t, s, out, done)
+	cancel()
+	waitReturn(t, ctx, done)
+
+	// Drain what was sent before returning, then nothing more may arrive
+	for len(out) > 0 {
+		<-out
+	}
+	advance(s.drv)
+	select {
+	case block := <-out:
+		t.Fatalf("StreamBlocks sent block %d after returning", block.Height)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func testCancelledContext(t *testing.T, s subject) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, done := openStream(ctx, s)
+	waitReturn(t, ctx, done)
+}
+
+func testFailure(t *testing.T, s subject) {
+	if s.drv.Fail == nil {
+		t.Skip("driver cannot inject failures")
+	}
+	seen := waitLatest(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, done := openStream(ctx, s)
+	if block := nextStreamed(t, s, out, done); block.Height > seen.Height {
+		seen = block
+	}
+
+	s.drv.Fail(errInjected)
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("StreamBlocks returned nil after its source failed")
+		}
+	case <-time.After(blockTimeout):
+		t.Fatalf("StreamBlocks still running %v after its source failed", blockTimeout)
+	}
+	if block, err := s.latest(); err == nil {
+		checkBlock(t, "GetLatestBlock after failure", block)
+		if block.Height > seen.Height {
+			t.Fatalf("GetLatestBlock reported unseen height %d after failure", block.Height)
+		}
+	}
+
+	s.drv.Fail(nil)
+	advance(s.drv)
+	waitLatest(t, s)
+}
+
+func testConcurrentBackend(t *testing.T, b ChainBackend, drv Driver) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := subject{latest: b.GetLatestBlock, stream: b.StreamBlocks, drv: drv}
+	out, done := openStream(ctx, s)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if block, err := b.GetLatestBlock(); err == nil && block.Hash == "" {
+					errs <- fmt.Errorf("GetLatestBlock: block at height %d has no hash", block.Height)
+					return
+				}
+				if n := b.GetMempoolSize(); n < 0 {
+					errs <- fmt.Errorf("GetMempoolSize: %d", n)
+					return
+				}
+				if eta := b.GetPredictiveETA(); eta < 0 || math.IsNaN(eta) {
+					errs <- fmt.Errorf("GetPredictiveETA: %v", eta)
+					return
+				}
+				if b.GetStatus() == nil {
+					errs <- errors.New("GetStatus: nil")
+					return
+				}
+			}
+		}()
+	}
+	nextStreamed(t, s, out, done)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	cancel()
+	waitReturn(t, ctx, done)
+}
+
+func testConnection(t *testing.T, c relay.RelayClient) {
+	if !c.IsConnected() {
+		t.Fatal("IsConnected false after Connect")
+	}
+	if err := c.Disconnect(); err != nil {
+		t.Fatalf("Disconnect: %v", err)
+	}
+	if c.IsConnected() {
+		t.Fatal("IsConnected true after Disconnect")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), blockTimeout)
+	defer cancel()
+	if err := c.Connect(ctx); err != nil {
+		t.Fatalf("reconnect: %v", err)
+	}
+	if !c.IsConnected() {
+		t.Fatal("IsConnected false after reconnecting")
+	}
+}
+
+func testBlockLookup(t *testing.T, c relay.RelayClient, drv Driver) {
+	latest := func() (blocks.BlockEvent, error) {
+		block, err := c.GetLatestBlock()
+		if err != nil || block == nil {
+			return blocks.BlockEvent{}, errors.New("no block")
+		}
+		return *block, nil
+	}
+	tip := waitLatest(t, subject{latest: latest, drv: drv})
+
+	if c.SupportsFeature(relay.FeatureHistoricalData) {
+		byHash, err := c.GetBlockByHash(tip.Hash)
+		if err != nil || byHash == nil {
+			t.Fatalf("GetBlockByHash(%s): %v", tip.Hash, err)
+		}
+		if byHash.Height != tip.Height {
+			t.Fatalf("GetBlockByHash: height %d, want %d", byHash.Height, tip.Height)
+		}
+		byHeight, err := c.GetBlockByHeight(uint64(tip.Height))
+		if err != nil || byHeight == nil {
+			t.Fatalf("GetBlockByHeight(%d): %v", tip.Height, err)
+		}
+		if byHeight.Hash != tip.Hash {
+			t.Fatalf("GetBlockByHeight: hash %s, want %s", byHeight.Hash, tip.Hash)
+		}
+	}
+
+	unknown := "0000000000000000000000000000000000000000000000000000000000000000"
+	if block, err := c.GetBlockByHash(unknown); err == nil {
+		t.Fatalf("GetBlockByHash(unknown) returned %v and no error", block)
+	}
+}
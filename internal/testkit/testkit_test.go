@@ -0,0 +1,66 @@
+package testkit_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/relay"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/testkit"
+)
+
+func TestMockBackendContract(t *testing.T) {
+	testkit.RunChainBackend(t, func(t *testing.T) (testkit.ChainBackend, testkit.Driver) {
+		m := testkit.NewMockBackend(testkit.Blocks(blocks.ChainBitcoin, 850000, 500))
+		return m, m.Driver()
+	})
+}
+
+func TestMockRelayContract(t *testing.T) {
+	testkit.RunRelayClient(t, func(t *testing.T) (relay.RelayClient, testkit.Driver) {
+		m := testkit.NewMockRelay("ethereum", testkit.Blocks(blocks.ChainEthereum, 19000000, 500))
+		return m, m.Driver()
+	})
+}
+
+func TestBlocksDeterministic(t *testing.T) {
+	a := testkit.Blocks(blocks.ChainSolana, 10, 3)
+	b := testkit.Blocks(blocks.ChainSolana, 10, 3)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("block %d differs between runs", i)
+		}
+		if a[i].Height != uint32(10+i) {
+			t.Fatalf("block %d at height %d", i, a[i].Height)
+		}
+	}
+	if a[0].Hash == testkit.Blocks(blocks.ChainEthereum, 10, 1)[0].Hash {
+		t.Fatal("hash does not depend on chain")
+	}
+}
+
+func TestMockBackendScript(t *testing.T) {
+	m := testkit.NewMockBackend(testkit.Blocks(blocks.ChainBitcoin, 1, 2))
+	if _, err := m.GetLatestBlock(); err != testkit.ErrNoBlock {
+		t.Fatalf("latest before Advance: %v", err)
+	}
+	m.Advance()
+	m.Advance()
+	if _, ok := m.Advance(); ok {
+		t.Fatal("Advance past the end of the script")
+	}
+	if latest, _ := m.GetLatestBlock(); latest.Height != 2 {
+		t.Fatalf("latest at %d, want 2", latest.Height)
+	}
+
+	m.SetError(errors.New("down"))
+	if _, err := m.GetLatestBlock(); err == nil {
+		t.Fatal("latest succeeded while failing")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.StreamBlocks(ctx, make(chan blocks.BlockEvent)); err == nil {
+		t.Fatal("stream opened while failing")
+	}
+}
@@ -0,0 +1,96 @@
+package timesync
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ntpEpochOffset is the seconds from the NTP epoch (1900) to the Unix epoch
+const ntpEpochOffset = 2208988800
+
+// Sample is one NTP measurement
+type Sample struct {
+	Skew    time.Duration // Local clock minus server clock
+	RTT     time.Duration // Round trip excluding server processing
+	Stratum uint8
+}
+
+var (
+	errNTPShort        = errors.New("timesync: short NTP response")
+	errNTPUnsync       = errors.New("timesync: NTP server not synchronized")
+	errNTPOriginateMis = errors.New("timesync: NTP response does not match request")
+)
+
+// Query measures the local clock against one NTP server with a single
+// SNTP (RFC 4330) exchange. server is host or host:port.
+func Query(ctx context.Context, server string) (Sample, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return Sample{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0<<6 | 4<<3 | 3 // No leap warning, version 4, client mode
+	t1 := time.Now()
+	binary.BigEndian.PutUint64(req[40:], toNTP(t1))
+	if _, err := conn.Write(req); err != nil {
+		return Sample{}, err
+	}
+
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	if err != nil {
+		return Sample{}, err
+	}
+	if n < 48 {
+		return Sample{}, errNTPShort
+	}
+	if resp[0]>>6 == 3 || resp[0]&0x7 != 4 {
+		return Sample{}, errNTPUnsync
+	}
+	if resp[1] == 0 || resp[1] > 15 {
+		return Sample{}, fmt.Errorf("%w: stratum %d", errNTPUnsync, resp[1])
+	}
+	if binary.BigEndian.Uint64(resp[24:]) != binary.BigEndian.Uint64(req[40:]) {
+		return Sample{}, errNTPOriginateMis
+	}
+
+	t2 := fromNTP(binary.BigEndian.Uint64(resp[32:]))
+	t3 := fromNTP(binary.BigEndian.Uint64(resp[40:]))
+	// t4-t1 uses the monotonic clock; the server's stamps are wall time
+	elapsed := t4.Sub(t1)
+	local4 := t1.Round(0).Add(elapsed)
+	offset := (t2.Sub(t1.Round(0)) + t3.Sub(local4)) / 2
+	rtt := elapsed - t3.Sub(t2)
+	if rtt < 0 {
+		rtt = 0
+	}
+	return Sample{Skew: -offset, RTT: rtt, Stratum: resp[1]}, nil
+}
+
+// toNTP encodes t as a 64-bit NTP timestamp
+func toNTP(t time.Time) uint64 {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+	return secs<<32 | frac
+}
+
+// fromNTP decodes a 64-bit NTP timestamp
+func fromNTP(v uint64) time.Time {
+	secs := int64(v>>32) - ntpEpochOffset
+	nanos := int64((v & 0xffffffff) * 1e9 >> 32)
+	return time.Unix(secs, nanos)
+}
@@ -0,0 +1,292 @@
+// Package timesync estimates how far the local clock has drifted from
+// real time, so latency figures and TTLs computed against remote
+// timestamps can be corrected.
+//
+// Skew is measured against NTP servers when they are reachable and
+// otherwise against the timestamps peers report, such as the Bitcoin
+// version message. Peer timestamps carry network delay and whole-second
+// precision, so only their median is trusted and only for gross drift.
+package timesync
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	clockSkew = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "timesync_clock_skew_seconds",
+		Help: "Estimated local clock skew; positive when the local clock is ahead",
+	})
+
+	clockSkewExceeded = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "timesync_skew_exceeded",
+		Help: "1 while the estimated clock skew is beyond the configured maximum",
+	})
+
+	syncQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "timesync_ntp_queries_total",
+		Help: "NTP queries by result",
+	}, []string{"result"})
+
+	peerSamples = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "timesync_peer_samples_total",
+		Help: "Peer timestamps recorded as skew samples",
+	})
+)
+
+// Source names where the current skew estimate came from
+type Source string
+
+const (
+	SourceNone  Source = "none"
+	SourceNTP   Source = "ntp"
+	SourcePeers Source = "peers"
+)
+
+// State is the clock's standing against the configured maximum skew
+type State string
+
+const (
+	StateUnknown State = "unknown"
+	StateOK      State = "ok"
+	StateSkewed  State = "skewed"
+)
+
+const (
+	// maxPeerSamples bounds the peer timestamps kept for the median
+	maxPeerSamples = 64
+
+	// minPeerSamples is the fewest peer timestamps trusted for an estimate
+	minPeerSamples = 5
+
+	// peerSampleTTL drops peer timestamps too old to describe the clock now
+	peerSampleTTL = 6 * time.Hour
+)
+
+// Config configures a Monitor
+type Config struct {
+	Servers  []string      // NTP servers as host or host:port; empty uses peers only
+	Interval time.Duration // Between NTP rounds
+	MaxSkew  time.Duration // Skew beyond this is reported as StateSkewed
+	Timeout  time.Duration // Per NTP query
+}
+
+// Status is the clock skew estimate as reported in health
+type Status struct {
+	SkewSeconds    float64    `json:"skew_seconds"`
+	MaxSkewSeconds float64    `json:"max_skew_seconds"`
+	State          State      `json:"state"`
+	Source         Source     `json:"source"`
+	Server         string     `json:"server,omitempty"`
+	RTTSeconds     float64    `json:"rtt_seconds,omitempty"`
+	PeerSamples    int        `json:"peer_samples"`
+	LastSync       *time.Time `json:"last_sync,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+type peerSample struct {
+	skew time.Duration
+	at   time.Time
+}
+
+// Monitor tracks the local clock's skew. The zero skew is assumed until
+// a source has been measured.
+type Monitor struct {
+	cfg    Config
+	logger *zap.Logger
+	now    func() time.Time
+	query  func(ctx context.Context, server string) (Sample, error)
+
+	mu       sync.RWMutex
+	skew     time.Duration
+	source   Source
+	ntp      Sample
+	ntpAt    time.Time
+	ntpFrom  string
+	lastErr  string
+	peers    []peerSample
+	peerNext int
+}
+
+// New creates a monitor; call Run to start measuring against NTP
+func New(cfg Config, logger *zap.Logger) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Monitor{
+		cfg:    cfg,
+		logger: logger,
+		now:    time.Now,
+		query:  Query,
+		source: SourceNone,
+	}
+}
+
+// Run queries the NTP servers every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	if len(m.cfg.Servers) == 0 {
+		return
+	}
+	m.Sync(ctx)
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Sync(ctx)
+		}
+	}
+}
+
+// Sync runs one NTP round, trying each server in turn until one answers
+func (m *Monitor) Sync(ctx context.Context) error {
+	var err error
+	for _, server := range m.cfg.Servers {
+		qctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+		var s Sample
+		s, err = m.query(qctx, server)
+		cancel()
+		if err != nil {
+			syncQueries.WithLabelValues("error").Inc()
+			m.logger.Debug("NTP query failed", zap.String("server", server), zap.Error(err))
+			continue
+		}
+		syncQueries.WithLabelValues("ok").Inc()
+
+		m.mu.Lock()
+		m.ntp, m.ntpAt, m.ntpFrom, m.lastErr = s, m.now(), server, ""
+		m.recomputeLocked()
+		m.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		m.mu.Lock()
+		m.lastErr = err.Error()
+		m.recomputeLocked()
+		m.mu.Unlock()
+	}
+	return err
+}
+
+// ObservePeer records the time a peer reported at the moment it arrived.
+// Its signature suits p2p.Client.OnPeerTime.
+func (m *Monitor) ObservePeer(peer string, remote time.Time) {
+	if remote.IsZero() {
+		return
+	}
+	local := m.now()
+	peerSamples.Inc()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := peerSample{skew: local.Sub(remote), at: local}
+	if len(m.peers) < maxPeerSamples {
+		m.peers = append(m.peers, s)
+	} else {
+		m.peers[m.peerNext] = s
+		m.peerNext = (m.peerNext + 1) % maxPeerSamples
+	}
+	m.recomputeLocked()
+}
+
+// recomputeLocked picks the best estimate: a recent NTP measurement, or
+// the median of recent peer timestamps
+func (m *Monitor) recomputeLocked() {
+	now := m.now()
+	switch {
+	case !m.ntpAt.IsZero() && now.Sub(m.ntpAt) <= 3*m.cfg.Interval:
+		m.skew, m.source = m.ntp.Skew, SourceNTP
+	default:
+		var skews []time.Duration
+		for _, s := range m.peers {
+			if now.Sub(s.at) <= peerSampleTTL {
+				skews = append(skews, s.skew)
+			}
+		}
+		if len(skews) < minPeerSamples {
+			m.skew, m.source = 0, SourceNone
+			break
+		}
+		sort.Slice(skews, func(i, j int) bool { return skews[i] < skews[j] })
+		m.skew, m.source = skews[len(skews)/2], SourcePeers
+	}
+
+	clockSkew.Set(m.skew.Seconds())
+	if m.exceededLocked() {
+		clockSkewExceeded.Set(1)
+	} else {
+		clockSkewExceeded.Set(0)
+	}
+}
+
+func (m *Monitor) exceededLocked() bool {
+	if m.cfg.MaxSkew <= 0 || m.source == SourceNone {
+		return false
+	}
+	return m.skew > m.cfg.MaxSkew || m.skew < -m.cfg.MaxSkew
+}
+
+// Skew returns how far the local clock is ahead of real time (negative
+// when behind), or zero when nothing has been measured
+func (m *Monitor) Skew() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.skew
+}
+
+// Correct converts a local clock reading to estimated real time
+func (m *Monitor) Correct(t time.Time) time.Time {
+	return t.Add(-m.Skew())
+}
+
+// Now returns the estimated real time
+func (m *Monitor) Now() time.Time {
+	return m.Correct(m.now())
+}
+
+// Status reports the current estimate
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	now := m.now()
+	st := Status{
+		SkewSeconds:    m.skew.Seconds(),
+		MaxSkewSeconds: m.cfg.MaxSkew.Seconds(),
+		State:          StateOK,
+		Source:         m.source,
+		LastError:      m.lastErr,
+	}
+	for _, s := range m.peers {
+		if now.Sub(s.at) <= peerSampleTTL {
+			st.PeerSamples++
+		}
+	}
+	if !m.ntpAt.IsZero() {
+		at := m.ntpAt.UTC()
+		st.LastSync = &at
+		st.Server = m.ntpFrom
+		st.RTTSeconds = m.ntp.RTT.Seconds()
+	}
+	switch {
+	case m.source == SourceNone:
+		st.State = StateUnknown
+	case m.exceededLocked():
+		st.State = StateSkewed
+	}
+	return st
+}
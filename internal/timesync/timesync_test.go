@@ -0,0 +1,59 @@
+package timesync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSkewEstimate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	m := New(Config{Servers: []string{"ntp.test"}, Interval: time.Minute, MaxSkew: time.Second}, nil)
+	m.now = func() time.Time { return now }
+
+	if st := m.Status(); st.State != StateUnknown || m.Skew() != 0 {
+		t.Fatalf("unmeasured clock: %+v", st)
+	}
+
+	// Peers report a clock 3s behind ours, with one outlier
+	for i := 0; i < minPeerSamples; i++ {
+		m.ObservePeer("peer", now.Add(-3*time.Second))
+	}
+	m.ObservePeer("liar", now.Add(time.Hour))
+	if got := m.Skew(); got != 3*time.Second {
+		t.Fatalf("peer skew = %v, want 3s", got)
+	}
+	if st := m.Status(); st.State != StateSkewed || st.Source != SourcePeers {
+		t.Fatalf("status = %+v", st)
+	}
+	if got := m.Correct(now); !got.Equal(now.Add(-3 * time.Second)) {
+		t.Fatalf("Correct = %v", got)
+	}
+
+	// A fresh NTP measurement takes precedence over peers
+	m.query = func(context.Context, string) (Sample, error) {
+		return Sample{Skew: 200 * time.Millisecond, RTT: 10 * time.Millisecond, Stratum: 2}, nil
+	}
+	if err := m.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := m.Status(); st.State != StateOK || st.Source != SourceNTP || m.Skew() != 200*time.Millisecond {
+		t.Fatalf("after NTP: %+v", st)
+	}
+
+	// Once NTP goes stale, the peer median applies again
+	m.query = func(context.Context, string) (Sample, error) { return Sample{}, errors.New("timeout") }
+	now = now.Add(5 * time.Minute)
+	if err := m.Sync(context.Background()); err == nil {
+		t.Fatal("expected sync error")
+	}
+	if st := m.Status(); st.Source != SourcePeers || st.LastError == "" {
+		t.Fatalf("after NTP failure: %+v", st)
+	}
+
+	ts := time.Unix(1700000000, 250000000)
+	if got := fromNTP(toNTP(ts)); got.Sub(ts).Abs() > time.Microsecond {
+		t.Fatalf("NTP timestamp round trip: %v", got)
+	}
+}
@@ -0,0 +1,143 @@
+// Package trafficcapture records request timings and outcomes as JSON lines
+// so production traffic can be replayed offline.
+//
+// The API server writes one Record per sampled request through a Writer;
+// cb-loadtest reads the file back with ReadFile and replays the recorded
+// arrivals, latencies and failures against a circuit breaker config.
+package trafficcapture
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record is one captured request
+type Record struct {
+	Time      time.Time `json:"ts"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Chain     string    `json:"chain,omitempty"`
+	Tier      string    `json:"tier,omitempty"`
+	Status    int       `json:"status"`
+	LatencyMs float64   `json:"latency_ms"`
+}
+
+// Latency returns the recorded handler latency
+func (r Record) Latency() time.Duration {
+	return time.Duration(r.LatencyMs * float64(time.Millisecond))
+}
+
+// Failed reports whether the request counts as a failure for a circuit
+// breaker: server errors and upstream timeouts, not client errors
+func (r Record) Failed() bool {
+	return r.Status >= 500
+}
+
+// queueSize bounds records waiting to be written; beyond it they are dropped
+const queueSize = 4096
+
+// Writer appends sampled records to a JSON lines file from a background
+// goroutine so request handling never waits on disk
+type Writer struct {
+	sampleRate float64
+	queue      chan Record
+	done       chan struct{}
+	dropped    int64
+	closeOnce  sync.Once
+}
+
+// NewWriter opens path for appending. sampleRate is the fraction of
+// records kept; values outside (0, 1] keep every record.
+func NewWriter(path string, sampleRate float64) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+	w := &Writer{
+		sampleRate: sampleRate,
+		queue:      make(chan Record, queueSize),
+		done:       make(chan struct{}),
+	}
+	go w.run(f)
+	return w, nil
+}
+
+// Write queues rec if it is sampled. It never blocks; records arriving
+// while the queue is full are counted in Dropped.
+func (w *Writer) Write(rec Record) {
+	if w.sampleRate < 1 && rand.Float64() >= w.sampleRate {
+		return
+	}
+	select {
+	case w.queue <- rec:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+	}
+}
+
+// Dropped returns the number of records lost to a full queue
+func (w *Writer) Dropped() int64 {
+	return atomic.LoadInt64(&w.dropped)
+}
+
+// Close flushes queued records and closes the file. Write must not be
+// called after Close.
+func (w *Writer) Close() {
+	w.closeOnce.Do(func() {
+		close(w.queue)
+		<-w.done
+	})
+}
+
+func (w *Writer) run(f *os.File) {
+	defer close(w.done)
+	defer f.Close()
+
+	buf := bufio.NewWriter(f)
+	enc := json.NewEncoder(buf)
+	for rec := range w.queue {
+		enc.Encode(rec)
+		// Flush once the queue drains so a crash loses little
+		if len(w.queue) == 0 {
+			buf.Flush()
+		}
+	}
+	buf.Flush()
+}
+
+// ReadFile loads every record in a capture file, in file order. Malformed
+// lines are reported with their line number.
+func ReadFile(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,34 @@
+package trafficcapture
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteAndReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	w, err := NewWriter(path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	w.Write(Record{Time: start, Method: "GET", Path: "/v1/eth/latest", Chain: "ethereum", Status: 200, LatencyMs: 12.5})
+	w.Write(Record{Time: start.Add(time.Second), Method: "GET", Path: "/v1/eth/latest", Chain: "ethereum", Status: 504, LatencyMs: 3000})
+	w.Close()
+
+	records, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if !records[0].Time.Equal(start) || records[0].Failed() || records[0].Latency() != 12500*time.Microsecond {
+		t.Fatalf("first record = %+v", records[0])
+	}
+	if !records[1].Failed() {
+		t.Fatalf("504 should count as a failure: %+v", records[1])
+	}
+}
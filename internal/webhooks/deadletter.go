@@ -0,0 +1,150 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a delivery that exhausted its retries
+type DeadLetter struct {
+	ID             string    `json:"id"`
+	SubscriptionID string    `json:"subscription_id"`
+	Owner          string    `json:"owner"`
+	URL            string    `json:"url"`
+	Event          Event     `json:"event"`
+	Attempts       int       `json:"attempts"`
+	LastError      string    `json:"last_error"`
+	FailedAt       time.Time `json:"failed_at"`
+}
+
+// DeadLetterStore keeps failed deliveries for inspection and replay
+type DeadLetterStore interface {
+	Put(dl DeadLetter) error
+	List(owner string) ([]DeadLetter, error)
+	Get(id string) (DeadLetter, bool)
+	Delete(id string) error
+}
+
+// FileDeadLetterStore holds dead letters in memory and, when path is set,
+// persists them as a JSON file rewritten atomically on every change. The
+// oldest entries are dropped once max is reached.
+type FileDeadLetterStore struct {
+	path string
+	max  int
+
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+// NewFileDeadLetterStore loads any dead letters saved at path. An empty
+// path keeps them in memory only.
+func NewFileDeadLetterStore(path string, max int) (*FileDeadLetterStore, error) {
+	if max <= 0 {
+		max = 10000
+	}
+	s := &FileDeadLetterStore{path: path, max: max, letters: make(map[string]DeadLetter)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	var letters []DeadLetter
+	if err := json.Unmarshal(data, &letters); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letters: %w", err)
+	}
+	for _, dl := range letters {
+		s.letters[dl.ID] = dl
+	}
+	return s, nil
+}
+
+// Put stores dl, evicting the oldest entry when full
+func (s *FileDeadLetterStore) Put(dl DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.letters[dl.ID] = dl
+	if len(s.letters) > s.max {
+		oldest := ""
+		for id, l := range s.letters {
+			if oldest == "" || l.FailedAt.Before(s.letters[oldest].FailedAt) {
+				oldest = id
+			}
+		}
+		delete(s.letters, oldest)
+	}
+	return s.saveLocked()
+}
+
+// List returns owner's dead letters, oldest first
+func (s *FileDeadLetterStore) List(owner string) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]DeadLetter, 0)
+	for _, dl := range s.letters {
+		if dl.Owner == owner {
+			out = append(out, dl)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FailedAt.Before(out[j].FailedAt) })
+	return out, nil
+}
+
+// Get returns the dead letter with id
+func (s *FileDeadLetterStore) Get(id string) (DeadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dl, ok := s.letters[id]
+	return dl, ok
+}
+
+// Delete removes a dead letter
+func (s *FileDeadLetterStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.letters[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.letters, id)
+	return s.saveLocked()
+}
+
+func (s *FileDeadLetterStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+
+	letters := make([]DeadLetter, 0, len(s.letters))
+	for _, dl := range s.letters {
+		letters = append(letters, dl)
+	}
+	data, err := json.Marshal(letters)
+	if err != nil {
+		return fmt.Errorf("failed to encode dead letters: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write dead letters: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace dead letters: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,691 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/eventsign"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+var (
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Webhook delivery attempts by result (success, retry, dead_letter)",
+	}, []string{"event", "result"})
+	webhookDeliverySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "webhook_delivery_seconds",
+		Help:    "Webhook callback round-trip time",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+	webhookQueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_queue_depth",
+		Help: "Webhook deliveries waiting for a worker",
+	})
+	webhookSubscriptions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_subscriptions",
+		Help: "Registered webhook subscriptions",
+	})
+	webhookReorgs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_reorgs_detected_total",
+		Help: "Reorgs detected by the webhook dispatcher",
+	}, []string{"chain"})
+)
+
+// Config tunes delivery
+type Config struct {
+	Workers        int
+	QueueSize      int
+	Timeout        time.Duration // Per-attempt HTTP timeout
+	MaxAttempts    int           // Attempts before a delivery is dead-lettered
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	RatePerDestination  float64 // Deliveries per second per destination host
+	BurstPerDestination int
+
+	MaxPerOwner   int    // Subscriptions per API key
+	ReorgWindow   int    // Recent heights remembered per chain for reorg detection
+	StatePath     string // Subscriptions file; empty keeps them in memory only
+	AllowInsecure bool   // Accept http:// callbacks, for local development
+
+	// Signer adds an Ed25519 signature of the block to every event, so
+	// receivers can verify it came from Sprint; nil leaves events unsigned
+	Signer *eventsign.Signer
+}
+
+// DefaultConfig returns production delivery settings
+func DefaultConfig() Config {
+	return Config{
+		Workers:             8,
+		QueueSize:           4096,
+		Timeout:             10 * time.Second,
+		MaxAttempts:         8,
+		InitialBackoff:      time.Second,
+		MaxBackoff:          10 * time.Minute,
+		RatePerDestination:  10,
+		BurstPerDestination: 20,
+		MaxPerOwner:         20,
+		ReorgWindow:         128,
+	}
+}
+
+// delivery is one event on its way to one subscription
+type delivery struct {
+	id       string
+	sub      Subscription
+	event    Event
+	body     []byte
+	attempts int
+	lastErr  string
+}
+
+// Dispatcher fans block events out to webhook subscriptions
+type Dispatcher struct {
+	cfg         Config
+	client      *http.Client
+	deadLetters DeadLetterStore
+	logger      *zap.Logger
+
+	mu   sync.RWMutex
+	subs map[string]*Subscription // id -> subscription
+
+	limitersMu sync.Mutex
+	limiters   map[string]*rate.Limiter // destination host -> limiter
+
+	chainsMu sync.Mutex
+	chains   map[string]*chainTip
+
+	queue  chan *delivery
+	shed   atomic.Bool // Halve the queue while memory is short
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// chainTip remembers recently notified heights to detect reorgs and
+// suppress duplicate notifications from multiple relays
+type chainTip struct {
+	hashes map[uint32]string
+	tip    uint32
+}
+
+// New creates a Dispatcher. Zero Config fields take their defaults.
+func New(cfg Config, deadLetters DeadLetterStore, logger *zap.Logger) (*Dispatcher, error) {
+	def := DefaultConfig()
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = def.QueueSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = def.MaxAttempts
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = def.InitialBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = def.MaxBackoff
+	}
+	if cfg.RatePerDestination <= 0 {
+		cfg.RatePerDestination = def.RatePerDestination
+	}
+	if cfg.BurstPerDestination <= 0 {
+		cfg.BurstPerDestination = def.BurstPerDestination
+	}
+	if cfg.MaxPerOwner <= 0 {
+		cfg.MaxPerOwner = def.MaxPerOwner
+	}
+	if cfg.ReorgWindow <= 0 {
+		cfg.ReorgWindow = def.ReorgWindow
+	}
+	if deadLetters == nil {
+		deadLetters, _ = NewFileDeadLetterStore("", 0)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		cfg:         cfg,
+		client:      &http.Client{Timeout: cfg.Timeout},
+		deadLetters: deadLetters,
+		logger:      logger.Named("webhooks"),
+		subs:        make(map[string]*Subscription),
+		limiters:    make(map[string]*rate.Limiter),
+		chains:      make(map[string]*chainTip),
+		queue:       make(chan *delivery, cfg.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	if err := d.load(); err != nil {
+		cancel()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Start launches the delivery workers
+func (d *Dispatcher) Start() {
+	for i := 0; i < d.cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	d.logger.Info("Webhook dispatcher started",
+		zap.Int("workers", d.cfg.Workers),
+		zap.Int("subscriptions", d.count()))
+}
+
+// Stop cancels pending retries and waits for in-flight deliveries
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// DeadLetters returns the dispatcher's dead letter store
+func (d *Dispatcher) DeadLetters() DeadLetterStore {
+	return d.deadLetters
+}
+
+// ===== SUBSCRIPTIONS =====
+
+// Register creates a subscription for owner. The returned copy carries the
+// signing secret; later reads do not.
+func (d *Dispatcher) Register(owner, rawURL, chain string, events []EventType) (Subscription, error) {
+	if _, err := validateURL(rawURL, d.cfg.AllowInsecure); err != nil {
+		return Subscription{}, err
+	}
+	if chain == "" {
+		return Subscription{}, fmt.Errorf("webhook chain is required")
+	}
+	if len(events) == 0 {
+		events = []EventType{EventBlock, EventReorg}
+	}
+	for _, e := range events {
+		if e != EventBlock && e != EventReorg {
+			return Subscription{}, fmt.Errorf("unknown webhook event type %q", e)
+		}
+	}
+
+	id, err := randomID("wh_", 12)
+	if err != nil {
+		return Subscription{}, err
+	}
+	secret, err := randomID("whsec_", 24)
+	if err != nil {
+		return Subscription{}, err
+	}
+	sub := &Subscription{
+		ID:        id,
+		Owner:     owner,
+		URL:       rawURL,
+		Chain:     chain,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	d.mu.Lock()
+	owned := 0
+	for _, s := range d.subs {
+		if s.Owner == owner {
+			owned++
+		}
+	}
+	if owned >= d.cfg.MaxPerOwner {
+		d.mu.Unlock()
+		return Subscription{}, ErrTooManyWebhooks
+	}
+	d.subs[id] = sub
+	err = d.saveLocked()
+	d.mu.Unlock()
+
+	webhookSubscriptions.Set(float64(d.count()))
+	return *sub, err
+}
+
+// List returns owner's subscriptions without their secrets
+func (d *Dispatcher) List(owner string) []Subscription {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	out := make([]Subscription, 0)
+	for _, s := range d.subs {
+		if s.Owner == owner {
+			c := *s
+			c.Secret = ""
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Delete removes one of owner's subscriptions
+func (d *Dispatcher) Delete(owner, id string) error {
+	d.mu.Lock()
+	s, ok := d.subs[id]
+	if !ok || s.Owner != owner {
+		d.mu.Unlock()
+		return ErrNotFound
+	}
+	delete(d.subs, id)
+	err := d.saveLocked()
+	d.mu.Unlock()
+
+	webhookSubscriptions.Set(float64(d.count()))
+	return err
+}
+
+// Replay re-queues one of owner's dead letters with a fresh attempt budget
+func (d *Dispatcher) Replay(owner, deadLetterID string) error {
+	dl, ok := d.deadLetters.Get(deadLetterID)
+	if !ok || dl.Owner != owner {
+		return ErrNotFound
+	}
+	d.mu.RLock()
+	sub, ok := d.subs[dl.SubscriptionID]
+	var c Subscription
+	if ok {
+		c = *sub
+	}
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("subscription %s no longer exists: %w", dl.SubscriptionID, ErrNotFound)
+	}
+
+	body, err := json.Marshal(dl.Event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	if err := d.deadLetters.Delete(deadLetterID); err != nil {
+		return err
+	}
+	d.enqueue(&delivery{id: dl.ID, sub: c, event: dl.Event, body: body})
+	return nil
+}
+
+func (d *Dispatcher) count() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.subs)
+}
+
+// storedSubscription persists the owner alongside the public fields
+type storedSubscription struct {
+	Owner string `json:"owner"`
+	Subscription
+}
+
+func (d *Dispatcher) load() error {
+	if d.cfg.StatePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(d.cfg.StatePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read webhook subscriptions: %w", err)
+	}
+	var stored []storedSubscription
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to decode webhook subscriptions: %w", err)
+	}
+	for i := range stored {
+		sub := stored[i].Subscription
+		sub.Owner = stored[i].Owner
+		d.subs[sub.ID] = &sub
+	}
+	webhookSubscriptions.Set(float64(len(d.subs)))
+	return nil
+}
+
+func (d *Dispatcher) saveLocked() error {
+	if d.cfg.StatePath == "" {
+		return nil
+	}
+	stored := make([]storedSubscription, 0, len(d.subs))
+	for _, s := range d.subs {
+		stored = append(stored, storedSubscription{Owner: s.Owner, Subscription: *s})
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook subscriptions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(d.cfg.StatePath), 0o700); err != nil {
+		return fmt.Errorf("failed to create webhook state directory: %w", err)
+	}
+	tmp := d.cfg.StatePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write webhook subscriptions: %w", err)
+	}
+	return os.Rename(tmp, d.cfg.StatePath)
+}
+
+// ===== EVENTS =====
+
+// Publish turns a block event into block (and, if the block replaces one
+// already notified, reorg) notifications for every matching subscription.
+// Transaction events and repeats of a notified block are ignored.
+func (d *Dispatcher) Publish(event blocks.BlockEvent) {
+	if event.TxID != "" || event.Hash == "" || event.IsHeader {
+		return
+	}
+	chain := string(event.Chain)
+	if chain == "" {
+		chain = string(blocks.ChainBitcoin)
+	}
+
+	reorg, fresh := d.observe(chain, event)
+	if !fresh {
+		return
+	}
+	if reorg != nil {
+		webhookReorgs.WithLabelValues(chain).Inc()
+		d.logger.Info("Reorg detected",
+			zap.String("chain", chain),
+			zap.Uint32("height", reorg.Height),
+			zap.String("old_hash", reorg.OldHash),
+			zap.String("new_hash", reorg.NewHash),
+			zap.Int("depth", reorg.Depth))
+		d.fanOut(chain, EventReorg, event, reorg)
+	}
+	d.fanOut(chain, EventBlock, event, nil)
+}
+
+// observe records event in the chain's recent history. It reports whether
+// the block is new and, if it replaces a different hash at its height, the
+// reorg that implies.
+func (d *Dispatcher) observe(chain string, event blocks.BlockEvent) (*Reorg, bool) {
+	d.chainsMu.Lock()
+	defer d.chainsMu.Unlock()
+
+	ct, ok := d.chains[chain]
+	if !ok {
+		ct = &chainTip{hashes: make(map[uint32]string)}
+		d.chains[chain] = ct
+	}
+
+	var reorg *Reorg
+	if old, seen := ct.hashes[event.Height]; seen {
+		if old == event.Hash {
+			return nil, false
+		}
+		reorg = &Reorg{Height: event.Height, OldHash: old, NewHash: event.Hash}
+		for h := range ct.hashes {
+			if h >= event.Height {
+				delete(ct.hashes, h)
+				reorg.Depth++
+			}
+		}
+		ct.tip = event.Height
+	}
+
+	ct.hashes[event.Height] = event.Hash
+	if event.Height > ct.tip {
+		ct.tip = event.Height
+	}
+	window := uint32(d.cfg.ReorgWindow)
+	if ct.tip > window {
+		for h := range ct.hashes {
+			if h < ct.tip-window {
+				delete(ct.hashes, h)
+			}
+		}
+	}
+	return reorg, true
+}
+
+func (d *Dispatcher) fanOut(chain string, typ EventType, block blocks.BlockEvent, reorg *Reorg) {
+	d.mu.RLock()
+	targets := make([]Subscription, 0)
+	for _, s := range d.subs {
+		if s.wants(chain, typ) {
+			targets = append(targets, *s)
+		}
+	}
+	d.mu.RUnlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	id, err := randomID("evt_", 12)
+	if err != nil {
+		d.logger.Error("Failed to create webhook event", zap.Error(err))
+		return
+	}
+	event := Event{
+		ID:        id,
+		Type:      typ,
+		Chain:     chain,
+		Block:     block,
+		Reorg:     reorg,
+		CreatedAt: time.Now().UTC(),
+	}
+	if d.cfg.Signer != nil {
+		sig, err := d.cfg.Signer.Sign(block)
+		if err != nil {
+			d.logger.Error("Failed to sign webhook event", zap.Error(err))
+			return
+		}
+		event.KeyID, event.Signature = d.cfg.Signer.KeyID(), sig
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event", zap.Error(err))
+		return
+	}
+
+	for _, sub := range targets {
+		deliveryID, err := randomID("dlv_", 12)
+		if err != nil {
+			continue
+		}
+		d.enqueue(&delivery{id: deliveryID, sub: sub, event: event, body: body})
+	}
+}
+
+// ===== DELIVERY =====
+
+// ShedLoad caps the delivery queue at half its size while shed is set,
+// e.g. under memory pressure. Deliveries past the cap are dead-lettered,
+// so they can still be replayed.
+func (d *Dispatcher) ShedLoad(shed bool) {
+	if d.shed.Swap(shed) != shed {
+		d.logger.Info("Webhook load shedding changed", zap.Bool("shedding", shed))
+	}
+}
+
+// enqueue hands dl to the workers, dead-lettering it if the queue is full
+func (d *Dispatcher) enqueue(dl *delivery) {
+	if d.shed.Load() && len(d.queue) >= cap(d.queue)/2 {
+		dl.lastErr = "delivery queue shed under memory pressure"
+		d.deadLetter(dl)
+		return
+	}
+	select {
+	case d.queue <- dl:
+		webhookQueueDepth.Set(float64(len(d.queue)))
+	default:
+		dl.lastErr = "delivery queue full"
+		d.deadLetter(dl)
+	}
+}
+
+// enqueueAfter re-queues dl after delay unless the dispatcher stops first
+func (d *Dispatcher) enqueueAfter(dl *delivery, delay time.Duration) {
+	t := time.NewTimer(delay)
+	go func() {
+		defer t.Stop()
+		select {
+		case <-t.C:
+			d.enqueue(dl)
+		case <-d.ctx.Done():
+		}
+	}()
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case dl := <-d.queue:
+			webhookQueueDepth.Set(float64(len(d.queue)))
+			d.process(dl)
+		}
+	}
+}
+
+func (d *Dispatcher) process(dl *delivery) {
+	// Respect the destination's rate limit without spending an attempt
+	if delay := d.limiter(dl.sub.URL).Reserve().Delay(); delay > 0 {
+		d.enqueueAfter(dl, delay)
+		return
+	}
+
+	dl.attempts++
+	retryAfter, err := d.send(dl)
+	typ := string(dl.event.Type)
+	if err == nil {
+		webhookDeliveries.WithLabelValues(typ, "success").Inc()
+		return
+	}
+	dl.lastErr = err.Error()
+
+	var perm permanentError
+	if errors.As(err, &perm) || dl.attempts >= d.cfg.MaxAttempts {
+		d.deadLetter(dl)
+		return
+	}
+
+	webhookDeliveries.WithLabelValues(typ, "retry").Inc()
+	delay := d.backoff(dl.attempts)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	d.logger.Debug("Webhook delivery failed, retrying",
+		zap.String("subscription", dl.sub.ID),
+		zap.Int("attempt", dl.attempts),
+		zap.Duration("retry_in", delay),
+		zap.Error(err))
+	d.enqueueAfter(dl, delay)
+}
+
+// permanentError marks a failure retrying cannot fix, e.g. a 404 or 410
+type permanentError struct{ reason string }
+
+func (e permanentError) Error() string {
+	return e.reason
+}
+
+// send posts the signed payload. It returns the server's Retry-After, if any.
+func (d *Dispatcher) send(dl *delivery) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.sub.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, permanentError{reason: err.Error()}
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Bitcoin-Sprint-Webhooks/1.0")
+	req.Header.Set("X-Sprint-Event", string(dl.event.Type))
+	req.Header.Set("X-Sprint-Delivery", dl.id)
+	req.Header.Set(SignatureHeader, Sign(dl.sub.Secret, now, dl.body))
+
+	resp, err := d.client.Do(req)
+	webhookDeliverySeconds.Observe(time.Since(now).Seconds())
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return retryAfter, fmt.Errorf("callback returned status %d", resp.StatusCode)
+	default:
+		return 0, permanentError{reason: fmt.Sprintf("callback rejected delivery with status %d", resp.StatusCode)}
+	}
+}
+
+// backoff doubles from InitialBackoff up to MaxBackoff with ±20% jitter
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempt && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	jitter := 0.8 + 0.4*rand.Float64()
+	return time.Duration(float64(delay) * jitter)
+}
+
+func (d *Dispatcher) limiter(rawURL string) *rate.Limiter {
+	host := rawURL
+	if u, err := validateURL(rawURL, true); err == nil {
+		host = u.Host
+	}
+
+	d.limitersMu.Lock()
+	defer d.limitersMu.Unlock()
+	l, ok := d.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(d.cfg.RatePerDestination), d.cfg.BurstPerDestination)
+		d.limiters[host] = l
+	}
+	return l
+}
+
+func (d *Dispatcher) deadLetter(dl *delivery) {
+	webhookDeliveries.WithLabelValues(string(dl.event.Type), "dead_letter").Inc()
+	d.logger.Warn("Webhook delivery dead-lettered",
+		zap.String("subscription", dl.sub.ID),
+		zap.String("delivery", dl.id),
+		zap.Int("attempts", dl.attempts),
+		zap.String("error", dl.lastErr))
+
+	err := d.deadLetters.Put(DeadLetter{
+		ID:             dl.id,
+		SubscriptionID: dl.sub.ID,
+		Owner:          dl.sub.Owner,
+		URL:            dl.sub.URL,
+		Event:          dl.event,
+		Attempts:       dl.attempts,
+		LastError:      dl.lastErr,
+		FailedAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		d.logger.Error("Failed to store webhook dead letter", zap.Error(err))
+	}
+}
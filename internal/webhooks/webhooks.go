@@ -0,0 +1,164 @@
+// Package webhooks pushes block and reorg notifications to HTTPS callbacks.
+//
+// API keys register a callback URL for a chain and a set of event types.
+// The Dispatcher consumes block events, detects reorgs, and delivers a
+// signed JSON payload to every matching subscription. Failed deliveries are
+// retried with exponential backoff; deliveries that exhaust their attempts
+// go to a DeadLetterStore so they can be inspected and replayed. Each
+// destination host is rate limited so a burst of blocks cannot flood a
+// receiver.
+//
+// Payloads are signed with HMAC-SHA256 over "<timestamp>.<body>" using the
+// subscription secret and sent as:
+//
+//	X-Sprint-Signature: t=<unix seconds>,v1=<hex digest>
+//
+// With a Config.Signer, the event body also carries key_id and an Ed25519
+// signature of the block (see internal/eventsign), which any consumer can
+// check against the published key without the subscription secret.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+)
+
+// EventType is a kind of notification a subscription can receive
+type EventType string
+
+const (
+	EventBlock EventType = "block"
+	EventReorg EventType = "reorg"
+)
+
+// SignatureHeader carries the payload signature
+const SignatureHeader = "X-Sprint-Signature"
+
+var (
+	ErrNotFound        = errors.New("webhook subscription not found")
+	ErrTooManyWebhooks = errors.New("webhook subscription limit reached")
+)
+
+// Subscription is a registered callback
+type Subscription struct {
+	ID        string      `json:"id"`
+	Owner     string      `json:"-"` // API key hash
+	URL       string      `json:"url"`
+	Chain     string      `json:"chain"`
+	Events    []EventType `json:"events"`
+	Secret    string      `json:"secret,omitempty"` // Only returned when the subscription is created
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// wants reports whether the subscription receives typ events for chain
+func (s *Subscription) wants(chain string, typ EventType) bool {
+	if s.Chain != chain {
+		return false
+	}
+	for _, e := range s.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Reorg describes a block replaced at an already notified height
+type Reorg struct {
+	Height  uint32 `json:"height"`
+	OldHash string `json:"old_hash"`
+	NewHash string `json:"new_hash"`
+	Depth   int    `json:"depth"` // Notified blocks invalidated, including this height
+}
+
+// Event is the JSON payload posted to a callback
+type Event struct {
+	ID        string            `json:"id"`
+	Type      EventType         `json:"type"`
+	Chain     string            `json:"chain"`
+	Block     blocks.BlockEvent `json:"block"`
+	Reorg     *Reorg            `json:"reorg,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	KeyID     string            `json:"key_id,omitempty"`    // Key that signed Block
+	Signature string            `json:"signature,omitempty"` // Base64 Ed25519 signature of Block
+}
+
+// Sign returns the SignatureHeader value for body sent at ts
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + t + ",v1=" + digest(secret, t, body)
+}
+
+// Verify checks a SignatureHeader value against body, rejecting
+// signatures older than tolerance (0 disables the age check)
+func Verify(secret, header string, body []byte, tolerance time.Duration) bool {
+	var t, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			t = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || sig == "" {
+		return false
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(digest(secret, t, body)))
+}
+
+func digest(secret, t string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateURL accepts only absolute HTTPS URLs, or HTTP when insecure
+func validateURL(raw string, allowInsecure bool) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("webhook url must be absolute")
+	}
+	switch u.Scheme {
+	case "https":
+	case "http":
+		if !allowInsecure {
+			return nil, fmt.Errorf("webhook url must use https")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported webhook url scheme %q", u.Scheme)
+	}
+	if u.User != nil {
+		return nil, fmt.Errorf("webhook url must not embed credentials")
+	}
+	return u, nil
+}
+
+// randomID returns a hex identifier with the given prefix
+func randomID(prefix string, n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate id: %w", err)
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
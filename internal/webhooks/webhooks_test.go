@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"go.uber.org/zap"
+)
+
+func TestDeliverySignedAndReorgDetected(t *testing.T) {
+	var secret atomic.Value
+	received := make(chan string, 8)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify(secret.Load().(string), r.Header.Get(SignatureHeader), body, time.Minute) {
+			t.Errorf("bad signature on %s delivery", r.Header.Get("X-Sprint-Event"))
+		}
+		received <- r.Header.Get("X-Sprint-Event")
+	}))
+	defer srv.Close()
+
+	d, err := New(Config{AllowInsecure: true}, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Start()
+	defer d.Stop()
+
+	sub, err := d.Register("owner", srv.URL, "bitcoin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Store(sub.Secret)
+
+	d.Publish(blocks.BlockEvent{Hash: "aa", Height: 100, Chain: blocks.ChainBitcoin})
+	d.Publish(blocks.BlockEvent{Hash: "aa", Height: 100, Chain: blocks.ChainBitcoin}) // duplicate
+	d.Publish(blocks.BlockEvent{Hash: "bb", Height: 100, Chain: blocks.ChainBitcoin}) // replaces aa
+
+	got := map[string]int{}
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-received:
+			got[e]++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for deliveries, got %v", got)
+		}
+	}
+	if got["block"] != 2 || got["reorg"] != 1 {
+		t.Fatalf("deliveries = %v, want 2 block and 1 reorg", got)
+	}
+}
+
+func TestFailedDeliveryIsDeadLettered(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	d, err := New(Config{AllowInsecure: true, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Start()
+	defer d.Stop()
+
+	if _, err := d.Register("owner", srv.URL, "bitcoin", []EventType{EventBlock}); err != nil {
+		t.Fatal(err)
+	}
+	d.Publish(blocks.BlockEvent{Hash: "aa", Height: 1})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if letters, _ := d.DeadLetters().List("owner"); len(letters) == 1 {
+			if letters[0].Attempts != 3 || calls.Load() != 3 {
+				t.Fatalf("attempts = %d, calls = %d; want 3", letters[0].Attempts, calls.Load())
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("delivery was not dead-lettered")
+}
@@ -0,0 +1,173 @@
+// Package cachebackend is the plug-in interface for the lower tiers of the
+// Bitcoin Sprint cache.
+//
+// The cache keeps hot entries in memory (L1) and can back that with up to
+// two further tiers, L2 and L3, each served by a Backend. A store such as
+// S3 or Cassandra implements Backend and registers a factory under a name,
+// usually from an init function in the binary that links it:
+//
+//	func init() {
+//		cachebackend.RegisterBackendFactory("s3", newS3Backend)
+//	}
+//
+// The cache then opens it from configuration:
+//
+//	"l2_backend": {"backend": "s3", "options": {"bucket": "sprint-cache"}}
+//
+// # Consistency expectations
+//
+// L1 stays authoritative; lower tiers are consulted on an L1 miss, and a
+// hit is copied back into L1. Writes go through to every tier, but a
+// failed tier write only gets logged and counted, so a tier may miss
+// entries L1 has. A Backend must:
+//
+//   - return ErrNotFound for a key it does not hold, or whose entry is
+//     past its ExpiresAt (expired entries may be removed lazily)
+//   - return the latest completed Set for a key to later calls from the
+//     same process; concurrent Sets of one key may land in either order
+//   - treat Delete of a missing key as success
+//   - be safe for concurrent use
+//   - not retain the Entry passed to Set or returned from Get; callers
+//     may modify it afterwards
+//   - give up and return an error once ctx is done
+//
+// Stores that can return older values after a newer Set completes should
+// not be used as a tier. Package cachebackendtest checks these rules
+// against any Backend.
+package cachebackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Get for missing and expired keys
+var ErrNotFound = errors.New("cachebackend: not found")
+
+// Entry is one cached value as stored in a tier
+type Entry struct {
+	Key       string
+	Value     []byte // JSON encoding of the cached value
+	CreatedAt time.Time
+	ExpiresAt time.Time // Zero never expires
+}
+
+// Expired reports whether e is past its expiry at now
+func (e *Entry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Stats describes a backend's contents and traffic. Fields a store cannot
+// report cheaply may be left zero; Stats is called from metrics loops.
+type Stats struct {
+	Entries int64 `json:"entries"`
+	Size    int64 `json:"size"` // Bytes held
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Errors  int64 `json:"errors"`
+}
+
+// Backend is a cache tier
+type Backend interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, entry *Entry) error
+	Delete(ctx context.Context, key string) error
+	Clear(ctx context.Context) error
+	Stats() Stats
+	Close() error
+}
+
+// Options are a backend's settings from the cache configuration, decoded
+// from JSON: numbers arrive as float64
+type Options map[string]any
+
+// String returns the string option key, or def when unset
+func (o Options) String(key, def string) string {
+	if v, ok := o[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+// Int returns the numeric option key, or def when unset
+func (o Options) Int(key string, def int) int {
+	switch v := o[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
+
+// Duration returns option key given as a Go duration string ("5s") or a
+// number of seconds, or def when unset or invalid
+func (o Options) Duration(key string, def time.Duration) time.Duration {
+	switch v := o[key].(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	case float64:
+		return time.Duration(v * float64(time.Second))
+	case int:
+		return time.Duration(v) * time.Second
+	}
+	return def
+}
+
+// BackendFactory opens a backend from its options
+type BackendFactory func(opts Options) (Backend, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]BackendFactory)
+)
+
+// RegisterBackendFactory makes a backend available under name. It panics
+// if name is already registered or factory is nil.
+func RegisterBackendFactory(name string, factory BackendFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if factory == nil {
+		panic("cachebackend: RegisterBackendFactory factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("cachebackend: RegisterBackendFactory called twice for " + name)
+	}
+	factories[name] = factory
+}
+
+// Open opens the backend registered under name
+func Open(name string, opts Options) (Backend, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("cachebackend: unknown backend %q (registered: %v)", name, Backends())
+	}
+	if opts == nil {
+		opts = Options{}
+	}
+	b, err := factory(opts)
+	if err != nil {
+		return nil, fmt.Errorf("cachebackend: open %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// Backends returns the registered backend names, sorted
+func Backends() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
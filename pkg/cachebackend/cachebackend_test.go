@@ -0,0 +1,46 @@
+package cachebackend_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/cachebackend"
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/cachebackend/cachebackendtest"
+)
+
+func TestMemoryContract(t *testing.T) {
+	cachebackendtest.Run(t, func(t *testing.T) cachebackend.Backend {
+		return cachebackend.NewMemory(0)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	b, err := cachebackend.Open("memory", cachebackend.Options{"max_entries": float64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	if _, err := cachebackend.Open("s3", nil); err == nil {
+		t.Fatal("unregistered backend opened")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate registration did not panic")
+		}
+	}()
+	cachebackend.RegisterBackendFactory("memory", func(cachebackend.Options) (cachebackend.Backend, error) { return nil, nil })
+}
+
+func TestOptions(t *testing.T) {
+	opts := cachebackend.Options{"bucket": "b", "n": float64(3), "ttl": "2m", "secs": float64(1.5)}
+	if opts.String("bucket", "") != "b" || opts.String("missing", "d") != "d" {
+		t.Fatal("String")
+	}
+	if opts.Int("n", 0) != 3 || opts.Int("bucket", 7) != 7 {
+		t.Fatal("Int")
+	}
+	if opts.Duration("ttl", 0) != 2*time.Minute || opts.Duration("secs", 0) != 1500*time.Millisecond || opts.Duration("bucket", time.Second) != time.Second {
+		t.Fatal("Duration")
+	}
+}
@@ -0,0 +1,195 @@
+// Package cachebackendtest checks a cachebackend.Backend against the
+// consistency rules documented in package cachebackend. Run it from the
+// backend's own tests:
+//
+//	func TestContract(t *testing.T) {
+//		cachebackendtest.Run(t, func(t *testing.T) cachebackend.Backend {
+//			return openTestBucket(t)
+//		})
+//	}
+package cachebackendtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/cachebackend"
+)
+
+// Run runs the contract tests. open must return an empty backend; Run
+// closes it at the end of each subtest.
+func Run(t *testing.T, open func(t *testing.T) cachebackend.Backend) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, b cachebackend.Backend)
+	}{
+		{"MissingKey", testMissingKey},
+		{"ReadYourWrites", testReadYourWrites},
+		{"Expiry", testExpiry},
+		{"Delete", testDelete},
+		{"Clear", testClear},
+		{"NoAliasing", testNoAliasing},
+		{"Concurrent", testConcurrent},
+		{"CancelledContext", testCancelledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := open(t)
+			defer b.Close()
+			tt.fn(t, b)
+		})
+	}
+}
+
+func entry(key, value string, ttl time.Duration) *cachebackend.Entry {
+	now := time.Now()
+	return &cachebackend.Entry{Key: key, Value: []byte(value), CreatedAt: now, ExpiresAt: now.Add(ttl)}
+}
+
+func mustGet(t *testing.T, b cachebackend.Backend, key, want string) {
+	t.Helper()
+	e, err := b.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	if e.Key != key || string(e.Value) != want {
+		t.Fatalf("Get(%q) = %q %q, want %q", key, e.Key, e.Value, want)
+	}
+}
+
+func mustMiss(t *testing.T, b cachebackend.Backend, key string) {
+	t.Helper()
+	if _, err := b.Get(context.Background(), key); !errors.Is(err, cachebackend.ErrNotFound) {
+		t.Fatalf("Get(%q) err = %v, want ErrNotFound", key, err)
+	}
+}
+
+func mustSet(t *testing.T, b cachebackend.Backend, e *cachebackend.Entry) {
+	t.Helper()
+	if err := b.Set(context.Background(), e); err != nil {
+		t.Fatalf("Set(%q): %v", e.Key, err)
+	}
+}
+
+func testMissingKey(t *testing.T, b cachebackend.Backend) {
+	mustMiss(t, b, "never-set")
+}
+
+func testReadYourWrites(t *testing.T, b cachebackend.Backend) {
+	mustSet(t, b, entry("k", `"v1"`, time.Minute))
+	mustGet(t, b, "k", `"v1"`)
+	mustSet(t, b, entry("k", `"v2"`, time.Minute))
+	mustGet(t, b, "k", `"v2"`)
+
+	e := entry("meta", `{"a":1}`, time.Hour)
+	mustSet(t, b, e)
+	got, err := b.Get(context.Background(), "meta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stores may keep expiry at a coarser precision
+	if d := got.ExpiresAt.Sub(e.ExpiresAt); d < -time.Second || d > time.Second {
+		t.Fatalf("ExpiresAt = %v, want %v", got.ExpiresAt, e.ExpiresAt)
+	}
+}
+
+func testExpiry(t *testing.T, b cachebackend.Backend) {
+	mustSet(t, b, entry("expired", `1`, -time.Second))
+	mustMiss(t, b, "expired")
+
+	forever := &cachebackend.Entry{Key: "forever", Value: []byte(`1`), CreatedAt: time.Now()}
+	mustSet(t, b, forever)
+	mustGet(t, b, "forever", `1`)
+}
+
+func testDelete(t *testing.T, b cachebackend.Backend) {
+	mustSet(t, b, entry("k", `1`, time.Minute))
+	for i := 0; i < 2; i++ {
+		if err := b.Delete(context.Background(), "k"); err != nil {
+			t.Fatalf("Delete #%d: %v", i+1, err)
+		}
+	}
+	mustMiss(t, b, "k")
+}
+
+func testClear(t *testing.T, b cachebackend.Backend) {
+	for i := 0; i < 3; i++ {
+		mustSet(t, b, entry(fmt.Sprintf("k%d", i), `1`, time.Minute))
+	}
+	if err := b.Clear(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		mustMiss(t, b, fmt.Sprintf("k%d", i))
+	}
+}
+
+func testNoAliasing(t *testing.T, b cachebackend.Backend) {
+	e := entry("k", `"abc"`, time.Minute)
+	mustSet(t, b, e)
+	e.Value[1] = 'X'
+	mustGet(t, b, "k", `"abc"`)
+
+	got, _ := b.Get(context.Background(), "k")
+	got.Value[1] = 'Y'
+	mustGet(t, b, "k", `"abc"`)
+}
+
+func testConcurrent(t *testing.T, b cachebackend.Backend) {
+	const workers, rounds = 8, 50
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			ctx := context.Background()
+			own := fmt.Sprintf("own-%d", w)
+			for i := 0; i < rounds; i++ {
+				want := []byte(fmt.Sprint(i))
+				if err := b.Set(ctx, entry(own, string(want), time.Minute)); err != nil {
+					errs <- err
+					return
+				}
+				// Each worker's own key must read back its latest write
+				got, err := b.Get(ctx, own)
+				if err != nil || !bytes.Equal(got.Value, want) {
+					errs <- fmt.Errorf("worker %d round %d: got %v %v", w, i, got, err)
+					return
+				}
+				// The shared key may hold any worker's write
+				if err := b.Set(ctx, entry("shared", fmt.Sprint(w), time.Minute)); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := b.Get(ctx, "shared"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func testCancelledContext(t *testing.T, b cachebackend.Backend) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Set(ctx, entry("k", `1`, time.Minute)); err == nil {
+		// A store may complete a write it started before noticing ctx, but
+		// must then make it visible
+		mustGet(t, b, "k", `1`)
+	}
+	if _, err := b.Get(ctx, "k"); err == nil {
+		t.Fatal("Get with a cancelled context succeeded")
+	}
+}
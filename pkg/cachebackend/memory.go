@@ -0,0 +1,120 @@
+package cachebackend
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+func init() {
+	RegisterBackendFactory("memory", func(opts Options) (Backend, error) {
+		return NewMemory(opts.Int("max_entries", 0)), nil
+	})
+}
+
+// Memory is an in-process Backend. It is the reference implementation of
+// the consistency rules and a stand-in for remote stores in tests; as a
+// real tier it only adds capacity beyond L1's entry limit.
+type Memory struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]Entry
+	size    int64
+	stats   Stats
+}
+
+// NewMemory returns an empty Memory backend holding up to maxEntries
+// entries, or any number when maxEntries is 0. When full, Set evicts an
+// arbitrary entry.
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{maxEntries: maxEntries, entries: make(map[string]Entry)}
+}
+
+// Get implements Backend
+func (m *Memory) Get(ctx context.Context, key string) (*Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if ok && e.Expired(time.Now()) {
+		m.removeLocked(key)
+		ok = false
+	}
+	if !ok {
+		m.stats.Misses++
+		return nil, ErrNotFound
+	}
+	m.stats.Hits++
+	e.Value = append([]byte(nil), e.Value...)
+	return &e, nil
+}
+
+// Set implements Backend
+func (m *Memory) Set(ctx context.Context, entry *Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	e := *entry
+	e.Value = append([]byte(nil), entry.Value...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(e.Key)
+	if m.maxEntries > 0 && len(m.entries) >= m.maxEntries {
+		for k := range m.entries {
+			m.removeLocked(k)
+			break
+		}
+	}
+	m.entries[e.Key] = e
+	m.size += int64(len(e.Value))
+	return nil
+}
+
+// Delete implements Backend
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.removeLocked(key)
+	m.mu.Unlock()
+	return nil
+}
+
+// Clear implements Backend
+func (m *Memory) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.entries = make(map[string]Entry)
+	m.size = 0
+	m.mu.Unlock()
+	return nil
+}
+
+// Stats implements Backend
+func (m *Memory) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.stats
+	st.Entries = int64(len(m.entries))
+	st.Size = m.size
+	return st
+}
+
+// Close implements Backend
+func (m *Memory) Close() error {
+	return m.Clear(context.Background())
+}
+
+func (m *Memory) removeLocked(key string) {
+	if e, ok := m.entries[key]; ok {
+		m.size -= int64(len(e.Value))
+		delete(m.entries, key)
+	}
+}
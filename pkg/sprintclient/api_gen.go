@@ -0,0 +1,353 @@
+// Code generated by openapigen from config/bitcoin-sprint-api.yaml. DO NOT EDIT.
+
+package sprintclient
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListKeys calls GET /api/v1/admin/keys.
+//
+// List customer keys.
+func (c *Client) ListKeys(ctx context.Context, params *ListKeysParams) (*KeyList, error) {
+	var query url.Values
+	if params != nil {
+		query = params.query()
+	}
+	var out KeyList
+	if err := c.do(ctx, "GET", "/api/v1/admin/keys", query, nil, &out, authAdminKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListKeysParams are the optional query parameters of ListKeys
+type ListKeysParams struct {
+	Limit int
+	// next_cursor from the previous page
+	Cursor string
+	// Field to sort by, prefixed with - for descending
+	Sort           string
+	CustomerID     string
+	Tier           string
+	SubscriptionID string
+}
+
+func (p *ListKeysParams) query() url.Values {
+	q := url.Values{}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	if p.Cursor != "" {
+		q.Set("cursor", p.Cursor)
+	}
+	if p.Sort != "" {
+		q.Set("sort", p.Sort)
+	}
+	if p.CustomerID != "" {
+		q.Set("customer_id", p.CustomerID)
+	}
+	if p.Tier != "" {
+		q.Set("tier", p.Tier)
+	}
+	if p.SubscriptionID != "" {
+		q.Set("subscription_id", p.SubscriptionID)
+	}
+	return q
+}
+
+// CreateKey calls POST /api/v1/admin/keys.
+//
+// Provision a customer key.
+//
+// Send an Idempotency-Key to make retries safe: a repeat with the same
+// key and body within the idempotency TTL returns the original key
+// with Idempotent-Replayed: true instead of provisioning another.
+func (c *Client) CreateKey(ctx context.Context, body *CreateKeyRequest) (*CreatedKey, error) {
+	var out CreatedKey
+	if err := c.do(ctx, "POST", "/api/v1/admin/keys", nil, body, &out, authAdminKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetKeyTier calls POST /api/v1/admin/keys/{keyId}/tier.
+//
+// Change the tier of a customer key.
+func (c *Client) SetKeyTier(ctx context.Context, keyID string, body *SetKeyTierRequest) (*KeyTierChange, error) {
+	var out KeyTierChange
+	if err := c.do(ctx, "POST", "/api/v1/admin/keys/"+url.PathEscape(keyID)+"/tier", nil, body, &out, authAdminKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSigningKeys calls GET /api/v1/signing-keys.
+//
+// Public keys for verifying signed block events.
+func (c *Client) GetSigningKeys(ctx context.Context) (*SigningKeys, error) {
+	var out SigningKeys
+	if err := c.do(ctx, "GET", "/api/v1/signing-keys", nil, nil, &out, authNone); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Universal calls GET /api/v1/universal/{chain}/{method}.
+//
+// Call a method on any supported chain.
+//
+// Single endpoint for all chains; the response shape depends on the method and the caller's tier.
+func (c *Client) Universal(ctx context.Context, chain string, method string) (UniversalResponse, error) {
+	var out UniversalResponse
+	if err := c.do(ctx, "GET", "/api/v1/universal/"+url.PathEscape(chain)+"/"+url.PathEscape(method), nil, nil, &out, authAPIKey); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetHealth calls GET /health.
+//
+// Liveness check.
+func (c *Client) GetHealth(ctx context.Context) (*HealthResponse, error) {
+	var out HealthResponse
+	if err := c.do(ctx, "GET", "/health", nil, nil, &out, authNone); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReadiness calls GET /readyz.
+//
+// Readiness check.
+//
+// Not ready while draining for shutdown or while every chain's backend circuit breaker is open
+func (c *Client) GetReadiness(ctx context.Context) (*ReadinessResponse, error) {
+	var out ReadinessResponse
+	if err := c.do(ctx, "GET", "/readyz", nil, nil, &out, authNone); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFees calls GET /v1/{chain}/fees.
+//
+// Fee estimates for a chain.
+//
+// Estimates are refreshed at most as often as the caller's tier allows.
+func (c *Client) GetFees(ctx context.Context, chain string) (*FeeEstimate, error) {
+	var out FeeEstimate
+	if err := c.do(ctx, "GET", "/v1/"+url.PathEscape(chain)+"/fees", nil, nil, &out, authAPIKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLatestBlock calls GET /v1/{chain}/latest.
+//
+// Latest block seen on a chain.
+func (c *Client) GetLatestBlock(ctx context.Context, chain string) (*Block, error) {
+	var out Block
+	if err := c.do(ctx, "GET", "/v1/"+url.PathEscape(chain)+"/latest", nil, nil, &out, authAPIKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BroadcastTx calls POST /v1/{chain}/tx.
+//
+// Broadcast a signed transaction.
+//
+// Submits the transaction on every path configured for the chain at
+// once (the Bitcoin node, Esplora endpoints and P2P peers; RPC
+// endpoints for Ethereum and Solana) and reports each path's answer.
+// Answers 502 with the same body when no path accepted it.
+func (c *Client) BroadcastTx(ctx context.Context, chain string, body *TxBroadcastRequest) (*TxBroadcastResult, error) {
+	var out TxBroadcastResult
+	if err := c.do(ctx, "POST", "/v1/"+url.PathEscape(chain)+"/tx", nil, body, &out, authAPIKey); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Block is the Block schema
+//
+// A block event; the stream adds type and version (block v1)
+type Block struct {
+	// "block" on the stream
+	Type        string    `json:"type,omitempty"`
+	Version     int       `json:"version,omitempty"`
+	Hash        string    `json:"hash"`
+	Height      int64     `json:"height"`
+	Timestamp   time.Time `json:"timestamp,omitempty"`
+	DetectedAt  time.Time `json:"detected_at,omitempty"`
+	RelayTimeMs float64   `json:"relay_time_ms,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	TxID        string    `json:"txid,omitempty"`
+	Tier        string    `json:"tier,omitempty"`
+	IsHeader    bool      `json:"is_header,omitempty"`
+	Chain       string    `json:"chain"`
+	Status      string    `json:"status,omitempty"`
+	// Replayed after a stream gap, not live
+	Backfilled bool `json:"backfilled,omitempty"`
+	// Signing key, when block event signing is enabled
+	KeyID string `json:"key_id,omitempty"`
+	// Base64 Ed25519 signature over the canonical block message
+	Signature string `json:"signature,omitempty"`
+}
+
+// CreateKeyRequest is the CreateKeyRequest schema
+type CreateKeyRequest struct {
+	Tier       string `json:"tier"`
+	CustomerID string `json:"customer_id,omitempty"`
+	// 0 keeps the default of one year
+	TTLDays int `json:"ttl_days,omitempty"`
+}
+
+// CreatedKey is the CreatedKey schema
+type CreatedKey struct {
+	APIKey     string    `json:"api_key,omitempty"`
+	KeyID      string    `json:"key_id,omitempty"`
+	Tier       string    `json:"tier,omitempty"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	RateLimit  int       `json:"rate_limit,omitempty"`
+}
+
+// FeeEstimate is the FeeEstimate schema
+type FeeEstimate struct {
+	Chain    string  `json:"chain"`
+	Unit     string  `json:"unit"`
+	Slow     float64 `json:"slow,omitempty"`
+	Standard float64 `json:"standard,omitempty"`
+	Fast     float64 `json:"fast,omitempty"`
+	// Ethereum: next block's base fee, included in each level
+	BaseFee                float64   `json:"base_fee,omitempty"`
+	Source                 string    `json:"source,omitempty"`
+	Samples                int       `json:"samples,omitempty"`
+	UpdatedAt              time.Time `json:"updated_at,omitempty"`
+	AgeSeconds             float64   `json:"age_seconds,omitempty"`
+	RefreshIntervalSeconds float64   `json:"refresh_interval_seconds,omitempty"`
+}
+
+// HealthResponse is the HealthResponse schema
+type HealthResponse struct {
+	Status    string      `json:"status,omitempty"`
+	Timestamp time.Time   `json:"timestamp,omitempty"`
+	Version   string      `json:"version,omitempty"`
+	Service   string      `json:"service,omitempty"`
+	Uptime    string      `json:"uptime,omitempty"`
+	Clock     ClockStatus `json:"clock,omitempty"`
+}
+
+// KeyList is the KeyList schema
+type KeyList struct {
+	Keys  []CustomerKey `json:"keys,omitempty"`
+	Count int           `json:"count,omitempty"`
+	Limit int           `json:"limit,omitempty"`
+	// Absent on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// KeyTierChange is the KeyTierChange schema
+type KeyTierChange struct {
+	KeyID        string `json:"key_id,omitempty"`
+	PreviousTier string `json:"previous_tier,omitempty"`
+	Tier         string `json:"tier,omitempty"`
+	RateLimit    int    `json:"rate_limit,omitempty"`
+}
+
+// ReadinessResponse is the ReadinessResponse schema
+type ReadinessResponse struct {
+	Status string `json:"status,omitempty"`
+	// Backend circuit breaker state by chain
+	CircuitBreakers map[string]string `json:"circuit_breakers,omitempty"`
+	Timestamp       time.Time         `json:"timestamp,omitempty"`
+}
+
+// SetKeyTierRequest is the SetKeyTierRequest schema
+type SetKeyTierRequest struct {
+	Tier   string `json:"tier"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// SigningKeys is the SigningKeys schema
+type SigningKeys struct {
+	// Keys block events are signed with; empty when unsigned
+	Keys []SigningKey `json:"keys"`
+}
+
+// TxBroadcastRequest is the TxBroadcastRequest schema
+type TxBroadcastRequest struct {
+	// Signed transaction, hex encoded; Solana also accepts base64
+	Tx string `json:"tx"`
+}
+
+// TxBroadcastResult is the TxBroadcastResult schema
+type TxBroadcastResult struct {
+	Chain    string `json:"chain"`
+	TxID     string `json:"txid,omitempty"`
+	Accepted bool   `json:"accepted"`
+	// Time until the first path accepted the transaction
+	FirstSeenMs float64        `json:"first_seen_ms,omitempty"`
+	Paths       []TxPathResult `json:"paths"`
+}
+
+// UniversalResponse is the UniversalResponse schema
+//
+// Method result plus tier and latency metadata; fields vary by method
+type UniversalResponse map[string]interface{}
+
+// ClockStatus is the ClockStatus schema
+//
+// Local clock skew against NTP or peer timestamps
+type ClockStatus struct {
+	// Positive when the local clock is ahead
+	SkewSeconds    float64   `json:"skew_seconds,omitempty"`
+	MaxSkewSeconds float64   `json:"max_skew_seconds,omitempty"`
+	State          string    `json:"state,omitempty"`
+	Source         string    `json:"source,omitempty"`
+	Server         string    `json:"server,omitempty"`
+	RttSeconds     float64   `json:"rtt_seconds,omitempty"`
+	PeerSamples    int       `json:"peer_samples,omitempty"`
+	LastSync       time.Time `json:"last_sync,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+}
+
+// CustomerKey is the CustomerKey schema
+type CustomerKey struct {
+	Hash           string    `json:"hash,omitempty"`
+	Tier           string    `json:"tier,omitempty"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at,omitempty"`
+	LastUsed       time.Time `json:"last_used,omitempty"`
+	RequestCount   int       `json:"request_count,omitempty"`
+	CustomerID     string    `json:"customer_id,omitempty"`
+	SubscriptionID string    `json:"subscription_id,omitempty"`
+}
+
+// SigningKey is the SigningKey schema
+type SigningKey struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	// Base64 public key
+	PublicKey string `json:"public_key"`
+}
+
+// TxPathResult is the TxPathResult schema
+type TxPathResult struct {
+	Path         string  `json:"path"`
+	Accepted     bool    `json:"accepted"`
+	AlreadyKnown bool    `json:"already_known,omitempty"`
+	TxID         string  `json:"txid,omitempty"`
+	Error        string  `json:"error,omitempty"`
+	LatencyMs    float64 `json:"latency_ms,omitempty"`
+	// P2P: peers the transaction was announced to
+	Peers int `json:"peers,omitempty"`
+	// P2P: peers that fetched it
+	PeersRequested int `json:"peers_requested,omitempty"`
+}
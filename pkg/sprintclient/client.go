@@ -0,0 +1,207 @@
+// Package sprintclient is the Go client for the Bitcoin Sprint API.
+//
+// The REST methods and models in api_gen.go are generated from
+// config/bitcoin-sprint-api.yaml; regenerate them after changing the spec:
+//
+//	go generate ./pkg/sprintclient
+//
+// Block streaming (StreamBlocks), signed block verification (BlockVerifier)
+// and error handling are written by hand.
+// Every method returns an *Error for non-2xx responses, which matches the
+// sentinel errors with errors.Is:
+//
+//	c, _ := sprintclient.New("https://api.bitcoin-sprint.com", sprintclient.WithAPIKey(key))
+//	fees, err := c.GetFees(ctx, "bitcoin")
+//	if errors.Is(err, sprintclient.ErrRateLimited) {
+//		// back off for err.(*sprintclient.Error).RetryAfter
+//	}
+package sprintclient
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// authMode selects the credential a request carries
+type authMode int
+
+const (
+	authNone authMode = iota
+	authAPIKey
+	authAdminKey
+)
+
+// Client calls one Sprint API server. It is safe for concurrent use.
+type Client struct {
+	baseURL   string // Without a trailing slash
+	apiKey    string
+	adminKey  string
+	userAgent string
+	http      *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithAPIKey sets the customer key sent as X-API-Key
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithAdminKey sets the operator key sent as X-Admin-Key on key
+// management calls
+func WithAdminKey(key string) Option {
+	return func(c *Client) { c.adminKey = key }
+}
+
+// WithHTTPClient replaces the default HTTP client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://api.bitcoin-sprint.com"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("sprintclient: invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("sprintclient: base URL must be http or https, got %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery, u.Fragment = "", ""
+
+	c := &Client{
+		baseURL:   u.String(),
+		userAgent: "sprintclient-go",
+		http:      defaultHTTPClient(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func defaultHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   10 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+	}
+}
+
+// endpoint appends an escaped path and query to the base URL
+func (c *Client) endpoint(path string, query url.Values) string {
+	if len(query) == 0 {
+		return c.baseURL + path
+	}
+	return c.baseURL + path + "?" + query.Encode()
+}
+
+// setAuth adds the credential for mode
+func (c *Client) setAuth(h http.Header, mode authMode) {
+	switch mode {
+	case authAPIKey:
+		if c.apiKey != "" {
+			h.Set("X-API-Key", c.apiKey)
+		}
+	case authAdminKey:
+		if c.adminKey != "" {
+			h.Set("X-Admin-Key", c.adminKey)
+		}
+	}
+}
+
+// idempotencyKeyCtx is the context key for WithIdempotencyKey
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey returns a context whose mutating calls carry key as
+// the Idempotency-Key header. Retrying a call with the same key, e.g.
+// after a timeout, returns the server's original response instead of
+// repeating the change:
+//
+//	ctx := sprintclient.WithIdempotencyKey(ctx, uuid)
+//	key, err := c.CreateKey(ctx, req) // Safe to retry with ctx
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// do sends a JSON request and decodes a 2xx response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}, auth authMode) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("sprintclient: encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path, query), reqBody)
+	if err != nil {
+		return fmt.Errorf("sprintclient: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.setAuth(req.Header, auth)
+	if key, _ := ctx.Value(idempotencyKeyCtx{}).(string); key != "" && method != http.MethodGet {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	// The server stops working on the request when the caller would stop
+	// waiting, and says so with ErrLatencyBudgetExceeded
+	if deadline, ok := ctx.Deadline(); ok {
+		ms := max(time.Until(deadline).Milliseconds(), 1)
+		req.Header.Set("X-Sprint-Budget-Ms", strconv.FormatInt(ms, 10))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("sprintclient: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("sprintclient: decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package sprintclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	sblocks "github.com/PayRpc/Bitcoin-Sprint/internal/blocks"
+	"github.com/PayRpc/Bitcoin-Sprint/internal/schemas"
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/sprintclient/internal/openapigen"
+	"github.com/gorilla/websocket"
+)
+
+func TestGeneratedCodeUpToDate(t *testing.T) {
+	spec, err := os.ReadFile("../../config/bitcoin-sprint-api.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := openapigen.Generate(spec, "config/bitcoin-sprint-api.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("api_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("api_gen.go is stale; run go generate ./pkg/sprintclient")
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/bitcoin/fees":
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"error": "slow down"})
+		default:
+			// Middleware answers in plain text
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetFees(context.Background(), "bitcoin")
+	var apiErr *Error
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.Message != "slow down" || apiErr.RetryAfter != 2*time.Second {
+		t.Fatalf("GetFees err = %#v", err)
+	}
+
+	_, err = c.GetLatestBlock(context.Background(), "bitcoin")
+	if !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrNotFound) || err.(*Error).Message != "Unauthorized" {
+		t.Fatalf("GetLatestBlock err = %#v", err)
+	}
+}
+
+func TestKeyManagementUsesAdminKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Admin-Key") != "admin" || r.Header.Get("X-API-Key") != "" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/admin/keys":
+			if q := r.URL.Query(); q.Get("tier") != "pro" || q.Get("limit") != "10" || q.Has("cursor") {
+				t.Errorf("query = %v", q)
+			}
+			if r.Header.Get("Idempotency-Key") != "" {
+				t.Errorf("GET sent Idempotency-Key")
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"keys":        []map[string]interface{}{{"hash": "abc", "tier": "pro"}},
+				"count":       1,
+				"next_cursor": "c1",
+			})
+		case r.Method == http.MethodPost && r.URL.EscapedPath() == "/api/v1/admin/keys/a%2Fb/tier":
+			if r.Header.Get("Idempotency-Key") != "retry-1" {
+				t.Errorf("Idempotency-Key = %q", r.Header.Get("Idempotency-Key"))
+			}
+			var req SetKeyTierRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(KeyTierChange{KeyID: "a/b", PreviousTier: "free", Tier: req.Tier})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL+"/", WithAPIKey("customer"), WithAdminKey("admin"))
+
+	ctx := WithIdempotencyKey(context.Background(), "retry-1")
+	list, err := c.ListKeys(ctx, &ListKeysParams{Tier: "pro", Limit: 10})
+	if err != nil || len(list.Keys) != 1 || list.Keys[0].Hash != "abc" || list.NextCursor != "c1" {
+		t.Fatalf("ListKeys = %+v, %v", list, err)
+	}
+	change, err := c.SetKeyTier(ctx, "a/b", &SetKeyTierRequest{Tier: "enterprise"})
+	if err != nil || change.Tier != "enterprise" {
+		t.Fatalf("SetKeyTier = %+v, %v", change, err)
+	}
+}
+
+func TestStreamBlocksResumesAfterDisconnect(t *testing.T) {
+	var upgrader websocket.Upgrader
+	connects := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/bitcoin/stream" || r.Header.Get("X-API-Key") != "k" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		from := r.URL.Query().Get("from_height")
+		connects <- from
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if from == "" {
+			conn.WriteJSON(map[string]interface{}{"type": "block", "version": 1, "hash": "h100", "height": 100, "chain": "bitcoin"})
+			return // Drop the connection
+		}
+		conn.WriteJSON(map[string]interface{}{"type": "replay_start", "chain": "bitcoin", "count": 2})
+		conn.WriteJSON(map[string]interface{}{"type": "block", "version": 1, "hash": "h100", "height": 100, "chain": "bitcoin"})
+		conn.WriteJSON(map[string]interface{}{"type": "block", "version": 1, "hash": "h101", "height": 101, "chain": "bitcoin"})
+		conn.WriteJSON(map[string]interface{}{"type": "replay_end", "chain": "bitcoin", "count": 2})
+		conn.ReadMessage() // Hold open until the client leaves
+	}))
+	defer srv.Close()
+
+	c, _ := New(srv.URL, WithAPIKey("k"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	blocks := c.StreamBlocks(ctx, "bitcoin", StreamOptions{MinBackoff: 10 * time.Millisecond})
+
+	for _, want := range []int64{100, 101} {
+		select {
+		case b := <-blocks:
+			if b.Height != want {
+				t.Fatalf("height = %d, want %d", b.Height, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for block %d", want)
+		}
+	}
+	if first, second := <-connects, <-connects; first != "" || second != "100" {
+		t.Fatalf("from_height on connects = %q, %q", first, second)
+	}
+
+	cancel()
+	for range blocks {
+	}
+}
+
+func TestStreamBlocksBinary(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{schemas.SubprotocolBlockProto}}
+	ts := time.Unix(1700000000, 0).UTC()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if conn.Subprotocol() != schemas.SubprotocolBlockProto {
+			t.Errorf("subprotocol = %q", conn.Subprotocol())
+			return
+		}
+		msg := schemas.NewBlock(sblocks.BlockEvent{Hash: "s1", Height: 250000000, Timestamp: ts, RelayTimeMs: 1.5, Chain: sblocks.ChainSolana})
+		msg.KeyID, msg.Signature = "k1", "c2ln"
+		conn.WriteJSON(map[string]interface{}{"type": "replay_start", "chain": "solana", "count": 0})
+		conn.WriteMessage(websocket.BinaryMessage, schemas.AppendBlockProto(nil, msg))
+		conn.ReadMessage()
+	}))
+	defer srv.Close()
+
+	c, _ := New(srv.URL, WithAPIKey("k"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	blocks := c.StreamBlocks(ctx, "solana", StreamOptions{Binary: true})
+
+	select {
+	case b := <-blocks:
+		want := Block{Type: "block", Version: 1, Hash: "s1", Height: 250000000, Timestamp: ts, RelayTimeMs: 1.5, Chain: "solana", KeyID: "k1", Signature: "c2ln"}
+		if b != want {
+			t.Fatalf("block = %+v, want %+v", b, want)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for block")
+	}
+	cancel()
+	for range blocks {
+	}
+}
+
+func TestLatencyBudgetFromDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Sprint-Budget-Ms") == "" {
+			t.Error("no budget header sent for a deadline")
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+		w.Write([]byte(`{"error":"latency budget exceeded","code":"budget_exceeded","budget_ms":200,"partial":{"found":1}}`))
+	}))
+	defer srv.Close()
+	c, _ := New(srv.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := c.GetFees(ctx, "ethereum")
+	var apiErr *Error
+	if !errors.Is(err, ErrLatencyBudgetExceeded) || !errors.As(err, &apiErr) || string(apiErr.Partial) != `{"found":1}` {
+		t.Fatalf("GetFees err = %#v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package sprintclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sentinel errors matched by *Error through errors.Is
+var (
+	ErrBadRequest   = errors.New("sprintclient: bad request")
+	ErrUnauthorized = errors.New("sprintclient: unauthorized")
+	ErrForbidden    = errors.New("sprintclient: forbidden")
+	ErrBudget       = errors.New("sprintclient: monthly cost budget exhausted")
+	ErrNotFound     = errors.New("sprintclient: not found")
+	ErrRateLimited  = errors.New("sprintclient: rate limited")
+	ErrUnavailable  = errors.New("sprintclient: service unavailable")
+
+	// ErrLatencyBudgetExceeded is returned when the server ran out of the
+	// budget sent from the context deadline; Error.Partial holds what it
+	// gathered in time, if anything
+	ErrLatencyBudgetExceeded = errors.New("sprintclient: latency budget exceeded")
+)
+
+// Error is a non-2xx response from the API
+type Error struct {
+	StatusCode int
+	Message    string          // The "error" field of a JSON body, or the plain-text body
+	RetryAfter time.Duration   // From Retry-After on 429 and 503; zero when absent
+	Code       string          // Machine-readable "code" field, when the body has one
+	Partial    json.RawMessage // Data gathered before a latency budget ran out
+	RequestID  string          // X-Request-ID of the response, for finding the request in server logs
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("sprintclient: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("sprintclient: %d %s", e.StatusCode, e.Message)
+}
+
+// Is matches the sentinel for the status code
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrBudget:
+		return e.StatusCode == http.StatusPaymentRequired
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusServiceUnavailable
+	case ErrLatencyBudgetExceeded:
+		return e.Code == "budget_exceeded"
+	}
+	return false
+}
+
+// Temporary reports whether retrying the request later may succeed
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newError builds an *Error from a failed response. The server answers
+// with {"error": "..."} from handlers and plain text from middleware.
+func newError(resp *http.Response) *Error {
+	e := &Error{StatusCode: resp.StatusCode, RequestID: resp.Header.Get("X-Request-ID")}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	var payload struct {
+		Error   string          `json:"error"`
+		Code    string          `json:"code"`
+		Partial json.RawMessage `json:"partial"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		e.Message, e.Code, e.Partial = payload.Error, payload.Code, payload.Partial
+	} else {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	return e
+}
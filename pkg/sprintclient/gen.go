@@ -0,0 +1,31 @@
+//go:build ignore
+
+// gen regenerates api_gen.go from the OpenAPI spec. Run it through
+// go generate ./pkg/sprintclient.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/PayRpc/Bitcoin-Sprint/pkg/sprintclient/internal/openapigen"
+)
+
+func main() {
+	spec := flag.String("spec", "../../config/bitcoin-sprint-api.yaml", "OpenAPI spec")
+	out := flag.String("out", "api_gen.go", "output file")
+	flag.Parse()
+
+	src, err := os.ReadFile(*spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := openapigen.Generate(src, "config/bitcoin-sprint-api.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, code, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
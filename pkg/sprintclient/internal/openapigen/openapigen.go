@@ -0,0 +1,599 @@
+// Package openapigen turns operations of the Sprint OpenAPI spec into the
+// typed methods and models of pkg/sprintclient.
+//
+// Only operations with an operationId and a JSON success response are
+// generated, together with the component schemas they reach. The rest of
+// the spec (the WebSocket stream, legacy routes) is left to hand-written
+// code.
+package openapigen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Security scheme names mapped to the client's auth modes
+const (
+	schemeAPIKey   = "SprintApiKey"
+	schemeAdminKey = "SprintAdminKey"
+)
+
+type document struct {
+	Paths      map[string]*pathItem `yaml:"paths"`
+	Components struct {
+		Schemas map[string]*schema `yaml:"schemas"`
+	} `yaml:"components"`
+}
+
+type pathItem struct {
+	Get    *operation `yaml:"get"`
+	Post   *operation `yaml:"post"`
+	Put    *operation `yaml:"put"`
+	Patch  *operation `yaml:"patch"`
+	Delete *operation `yaml:"delete"`
+}
+
+// operations returns the item's operations in a fixed method order
+func (p *pathItem) operations() []struct {
+	method string
+	op     *operation
+} {
+	all := []struct {
+		method string
+		op     *operation
+	}{{"GET", p.Get}, {"POST", p.Post}, {"PUT", p.Put}, {"PATCH", p.Patch}, {"DELETE", p.Delete}}
+	out := all[:0]
+	for _, m := range all {
+		if m.op != nil {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+type operation struct {
+	OperationID string                `yaml:"operationId"`
+	Summary     string                `yaml:"summary"`
+	Description string                `yaml:"description"`
+	Parameters  []parameter           `yaml:"parameters"`
+	RequestBody *content              `yaml:"requestBody"`
+	Responses   map[string]*content   `yaml:"responses"`
+	Security    []map[string][]string `yaml:"security"`
+}
+
+type parameter struct {
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+// content is a request body or response
+type content struct {
+	Content map[string]struct {
+		Schema *schema `yaml:"schema"`
+	} `yaml:"content"`
+}
+
+func (c *content) jsonSchema() *schema {
+	if c == nil {
+		return nil
+	}
+	return c.Content["application/json"].Schema
+}
+
+type schema struct {
+	Ref                  string      `yaml:"$ref"`
+	Type                 string      `yaml:"type"`
+	Format               string      `yaml:"format"`
+	Description          string      `yaml:"description"`
+	Required             []string    `yaml:"required"`
+	Properties           properties  `yaml:"properties"`
+	Items                *schema     `yaml:"items"`
+	AdditionalProperties interface{} `yaml:"additionalProperties"`
+}
+
+// properties keeps the spec's field order
+type properties []property
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+func (p *properties) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: properties must be a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var s schema
+		if err := node.Content[i+1].Decode(&s); err != nil {
+			return err
+		}
+		*p = append(*p, property{name: node.Content[i].Value, schema: &s})
+	}
+	return nil
+}
+
+func (s *schema) refName() string {
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+func (s *schema) isStruct() bool {
+	return s.Type == "object" && len(s.Properties) > 0
+}
+
+// generator accumulates the output file
+type generator struct {
+	doc     *document
+	buf     bytes.Buffer
+	models  map[string]bool // Component schemas to emit
+	imports map[string]bool
+}
+
+// Generate returns the gofmt'ed client code for spec. source names the
+// spec in the generated header.
+func Generate(spec []byte, source string) ([]byte, error) {
+	var doc document
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	g := &generator{doc: &doc, models: make(map[string]bool), imports: make(map[string]bool)}
+
+	paths := make([]string, 0, len(doc.Paths))
+	for p := range doc.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var body bytes.Buffer
+	seen := make(map[string]string)
+	for _, p := range paths {
+		for _, m := range doc.Paths[p].operations() {
+			if m.op.OperationID == "" || successSchema(m.op) == nil {
+				continue
+			}
+			name := exportName(m.op.OperationID)
+			if prev, dup := seen[name]; dup {
+				return nil, fmt.Errorf("operation %s on %s %s collides with %s", name, m.method, p, prev)
+			}
+			seen[name] = m.method + " " + p
+			if err := g.operation(&body, m.method, p, name, m.op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", m.method, p, err)
+			}
+		}
+	}
+	if err := g.modelsTo(&body); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&g.buf, "// Code generated by openapigen from %s. DO NOT EDIT.\n\n", source)
+	g.buf.WriteString("package sprintclient\n\n")
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		g.buf.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&g.buf, "\t%q\n", imp)
+		}
+		g.buf.WriteString(")\n\n")
+	}
+	g.buf.Write(body.Bytes())
+
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, g.buf.Bytes())
+	}
+	return out, nil
+}
+
+// successSchema is the JSON schema of the first 2xx response
+func successSchema(op *operation) *schema {
+	for _, code := range []string{"200", "201", "202"} {
+		if s := op.Responses[code].jsonSchema(); s != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+// operation writes the method (and query parameter struct) for op
+func (g *generator) operation(w *bytes.Buffer, method, path, name string, op *operation) error {
+	g.imports["context"] = true
+
+	var pathParams, queryParams []parameter
+	for _, p := range op.Parameters {
+		switch p.In {
+		case "path":
+			pathParams = append(pathParams, p)
+		case "query":
+			queryParams = append(queryParams, p)
+		}
+	}
+
+	result := successSchema(op)
+	resultType, err := g.typeOf(result)
+	if err != nil {
+		return err
+	}
+	pointer := result.Ref != "" && g.doc.Components.Schemas[result.refName()] != nil && g.doc.Components.Schemas[result.refName()].isStruct()
+
+	args := []string{"ctx context.Context"}
+	for _, p := range pathParams {
+		args = append(args, paramIdent(p.Name)+" string")
+	}
+	bodyArg := "nil"
+	if s := op.RequestBody.jsonSchema(); s != nil {
+		t, err := g.typeOf(s)
+		if err != nil {
+			return err
+		}
+		args = append(args, "body *"+t)
+		bodyArg = "body"
+	}
+	if len(queryParams) > 0 {
+		args = append(args, "params *"+name+"Params")
+	}
+
+	ret := resultType
+	if pointer {
+		ret = "*" + resultType
+	}
+
+	fmt.Fprintf(w, "// %s calls %s %s.\n", name, method, path)
+	if doc := strings.TrimSpace(sentence(op.Summary) + "\n\n" + strings.TrimSpace(op.Description)); doc != "" {
+		w.WriteString("//\n")
+		writeComment(w, "", doc)
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), ret)
+
+	queryArg := "nil"
+	if len(queryParams) > 0 {
+		g.imports["net/url"] = true
+		w.WriteString("\tvar query url.Values\n\tif params != nil {\n\t\tquery = params.query()\n\t}\n")
+		queryArg = "query"
+	}
+
+	fmt.Fprintf(w, "\tvar out %s\n", resultType)
+	fmt.Fprintf(w, "\tif err := c.do(ctx, %q, %s, %s, %s, &out, %s); err != nil {\n\t\treturn nil, err\n\t}\n",
+		method, g.pathExpr(path), queryArg, bodyArg, authMode(op))
+	if pointer {
+		w.WriteString("\treturn &out, nil\n}\n\n")
+	} else {
+		w.WriteString("\treturn out, nil\n}\n\n")
+	}
+
+	if len(queryParams) > 0 {
+		return g.paramsStruct(w, name, queryParams)
+	}
+	return nil
+}
+
+// pathExpr builds path with its {params} escaped
+func (g *generator) pathExpr(path string) string {
+	var parts []string
+	for rest := path; rest != ""; {
+		open := strings.Index(rest, "{")
+		if open < 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest))
+			break
+		}
+		end := strings.Index(rest[open:], "}")
+		if end < 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest))
+			break
+		}
+		if open > 0 {
+			parts = append(parts, fmt.Sprintf("%q", rest[:open]))
+		}
+		g.imports["net/url"] = true
+		parts = append(parts, "url.PathEscape("+paramIdent(rest[open+1:open+end])+")")
+		rest = rest[open+end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+// paramsStruct writes the query parameter struct for an operation
+func (g *generator) paramsStruct(w *bytes.Buffer, name string, params []parameter) error {
+	fmt.Fprintf(w, "// %sParams are the optional query parameters of %s\n", name, name)
+	fmt.Fprintf(w, "type %sParams struct {\n", name)
+	types := make([]string, len(params))
+	for i, p := range params {
+		t, err := g.typeOf(p.Schema)
+		if err != nil {
+			return err
+		}
+		types[i] = t
+		if p.Description != "" {
+			writeComment(w, "\t", p.Description)
+		}
+		fmt.Fprintf(w, "\t%s %s\n", exportName(p.Name), t)
+	}
+	w.WriteString("}\n\n")
+
+	g.imports["net/url"] = true
+	fmt.Fprintf(w, "func (p *%sParams) query() url.Values {\n\tq := url.Values{}\n", name)
+	for i, p := range params {
+		field := "p." + exportName(p.Name)
+		switch types[i] {
+		case "string":
+			fmt.Fprintf(w, "\tif %s != \"\" {\n\t\tq.Set(%q, %s)\n\t}\n", field, p.Name, field)
+		case "int":
+			g.imports["strconv"] = true
+			fmt.Fprintf(w, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.Itoa(%s))\n\t}\n", field, p.Name, field)
+		case "int64":
+			g.imports["strconv"] = true
+			fmt.Fprintf(w, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.FormatInt(%s, 10))\n\t}\n", field, p.Name, field)
+		case "float64":
+			g.imports["strconv"] = true
+			fmt.Fprintf(w, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.FormatFloat(%s, 'f', -1, 64))\n\t}\n", field, p.Name, field)
+		case "bool":
+			fmt.Fprintf(w, "\tif %s {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, p.Name)
+		default:
+			return fmt.Errorf("query parameter %s has unsupported type %s", p.Name, types[i])
+		}
+	}
+	w.WriteString("\treturn q\n}\n\n")
+	return nil
+}
+
+// modelsTo writes every component schema reached by an operation, and
+// those they reach in turn
+func (g *generator) modelsTo(w *bytes.Buffer) error {
+	written := make(map[string]bool)
+	for {
+		var pending []string
+		for name := range g.models {
+			if !written[name] {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		sort.Strings(pending)
+		for _, name := range pending {
+			written[name] = true
+			if err := g.model(w, name, g.doc.Components.Schemas[name]); err != nil {
+				return fmt.Errorf("schema %s: %w", name, err)
+			}
+		}
+	}
+}
+
+func (g *generator) model(w *bytes.Buffer, name string, s *schema) error {
+	fmt.Fprintf(w, "// %s is the %s schema\n", exportName(name), name)
+	if s.Description != "" {
+		w.WriteString("//\n")
+		writeComment(w, "", s.Description)
+	}
+	if !s.isStruct() {
+		t, err := g.typeOf(s)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "type %s %s\n\n", exportName(name), t)
+		return nil
+	}
+	fmt.Fprintf(w, "type %s ", exportName(name))
+	if err := g.structType(w, s, "\t"); err != nil {
+		return err
+	}
+	w.WriteString("\n\n")
+	return nil
+}
+
+func (g *generator) structType(w *bytes.Buffer, s *schema, indent string) error {
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	w.WriteString("struct {\n")
+	for _, p := range s.Properties {
+		if p.schema.Description != "" {
+			writeComment(w, indent, p.schema.Description)
+		}
+		fmt.Fprintf(w, "%s%s ", indent, exportName(p.name))
+		if p.schema.isStruct() && p.schema.Ref == "" {
+			if err := g.structType(w, p.schema, indent+"\t"); err != nil {
+				return err
+			}
+		} else {
+			t, err := g.typeOf(p.schema)
+			if err != nil {
+				return fmt.Errorf("property %s: %w", p.name, err)
+			}
+			w.WriteString(t)
+		}
+		tag := p.name
+		if !required[p.name] {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(w, " `json:%q`\n", tag)
+	}
+	w.WriteString(indent[:len(indent)-1] + "}")
+	return nil
+}
+
+// typeOf returns the Go type for s, queueing referenced models
+func (g *generator) typeOf(s *schema) (string, error) {
+	if s == nil {
+		return "", fmt.Errorf("missing schema")
+	}
+	if s.Ref != "" {
+		name := s.refName()
+		if g.doc.Components.Schemas[name] == nil {
+			return "", fmt.Errorf("unknown schema %s", s.Ref)
+		}
+		g.models[name] = true
+		return exportName(name), nil
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		if s.Items == nil {
+			return "[]interface{}", nil
+		}
+		t, err := g.typeOf(s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + t, nil
+	case "object":
+		if s.isStruct() {
+			var b bytes.Buffer
+			if err := g.structType(&b, s, "\t"); err != nil {
+				return "", err
+			}
+			return b.String(), nil
+		}
+		if extra, ok := s.AdditionalProperties.(map[string]interface{}); ok && len(extra) > 0 {
+			var items schema
+			raw, _ := yaml.Marshal(extra)
+			if err := yaml.Unmarshal(raw, &items); err != nil {
+				return "", err
+			}
+			t, err := g.typeOf(&items)
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + t, nil
+		}
+		return "map[string]interface{}", nil
+	}
+	return "interface{}", nil
+}
+
+// authMode names the client auth constant for op's security requirement.
+// Operations without their own requirement use the customer API key.
+func authMode(op *operation) string {
+	if op.Security == nil {
+		return "authAPIKey"
+	}
+	mode := "authNone"
+	for _, req := range op.Security {
+		if _, ok := req[schemeAdminKey]; ok {
+			return "authAdminKey"
+		}
+		if _, ok := req[schemeAPIKey]; ok {
+			mode = "authAPIKey"
+		}
+	}
+	return mode
+}
+
+// sentence ends s with a period, so gofmt does not take a one-line
+// summary for a doc comment heading
+func sentence(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasSuffix(s, ".") {
+		return s
+	}
+	return s + "."
+}
+
+func writeComment(w *bytes.Buffer, indent, text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line = strings.TrimRight(line, " "); line == "" {
+			fmt.Fprintf(w, "%s//\n", indent)
+		} else {
+			fmt.Fprintf(w, "%s// %s\n", indent, line)
+		}
+	}
+}
+
+// initialisms are spelled in upper case, as golint expects
+var initialisms = map[string]string{
+	"api":  "API",
+	"http": "HTTP",
+	"id":   "ID",
+	"ip":   "IP",
+	"json": "JSON",
+	"ttl":  "TTL",
+	"txid": "TxID",
+	"url":  "URL",
+}
+
+// exportName converts snake_case or camelCase to an exported Go name
+func exportName(s string) string {
+	var b strings.Builder
+	for _, word := range words(s) {
+		if up, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(up)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// goKeywords cannot be used as parameter names
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+}
+
+// paramIdent converts a parameter name to an unexported Go identifier
+func paramIdent(s string) string {
+	ws := words(s)
+	if len(ws) == 0 {
+		return "param"
+	}
+	first := strings.ToLower(ws[0])
+	ident := first + exportName(strings.Join(ws[1:], "_"))
+	if goKeywords[ident] {
+		ident += "Value"
+	}
+	return ident
+}
+
+// words splits s at separators and lower-to-upper case changes
+func words(s string) []string {
+	var out []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			out = append(out, string(cur))
+			cur = cur[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ' || r == '.':
+			flush()
+		case i > 0 && r >= 'A' && r <= 'Z' && runes[i-1] >= 'a' && runes[i-1] <= 'z':
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+	flush()
+	return out
+}
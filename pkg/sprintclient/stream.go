@@ -0,0 +1,238 @@
+package sprintclient
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamPingInterval = 30 * time.Second // The server drops streams silent for 60s
+	streamSeenHashes   = 256              // Recent blocks remembered to drop replayed repeats
+)
+
+// streamDialer offers permessage-deflate; the server decides per tier
+// whether to accept it
+var streamDialer = &websocket.Dialer{
+	Proxy:             http.ProxyFromEnvironment,
+	HandshakeTimeout:  45 * time.Second,
+	EnableCompression: true,
+}
+
+// StreamOptions configures StreamBlocks
+type StreamOptions struct {
+	// FromHeight or FromTime replay retained blocks on the first connection;
+	// both zero starts with live blocks
+	FromHeight uint32
+	FromTime   time.Time
+
+	// Binary asks for blocks as protobuf frames, which are about half the
+	// size of JSON and cheaper to decode; worth it on busy chains such as
+	// Solana. Servers without it keep sending JSON, which is still read.
+	Binary bool
+
+	MinBackoff time.Duration // First reconnect delay; defaults to 1s
+	MaxBackoff time.Duration // Reconnect delay cap; defaults to 30s
+
+	// OnError receives connection errors. A rejected handshake other than
+	// 429 is permanent and closes the stream; everything else reconnects.
+	OnError func(error)
+}
+
+// StreamBlocks streams blocks of chain from /v1/{chain}/stream until ctx
+// ends, then closes the channel. Dropped connections are re-established
+// with backoff and resume from the last height received, so blocks
+// published while disconnected are replayed; repeats are dropped.
+func (c *Client) StreamBlocks(ctx context.Context, chain string, opts StreamOptions) <-chan Block {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 30 * time.Second
+		if opts.MaxBackoff < opts.MinBackoff {
+			opts.MaxBackoff = opts.MinBackoff
+		}
+	}
+
+	out := make(chan Block, 64)
+	s := &blockStream{
+		client: c,
+		chain:  chain,
+		opts:   opts,
+		out:    out,
+		seen:   make(map[string]bool, streamSeenHashes),
+	}
+	go func() {
+		defer close(out)
+		s.run(ctx)
+	}()
+	return out
+}
+
+// blockStream is the state of one StreamBlocks call
+type blockStream struct {
+	client *Client
+	chain  string
+	opts   StreamOptions
+	out    chan<- Block
+
+	lastHeight int64 // Highest height delivered, once received is set
+	received   bool
+	seen       map[string]bool
+	seenOrder  []string
+}
+
+func (s *blockStream) run(ctx context.Context) {
+	backoff := s.opts.MinBackoff
+	for ctx.Err() == nil {
+		conn, resp, err := streamDialer.DialContext(ctx, s.url(), s.header())
+		if err != nil {
+			if resp != nil {
+				apiErr := newError(resp)
+				resp.Body.Close()
+				s.report(apiErr)
+				if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+					return
+				}
+				if apiErr.RetryAfter > backoff {
+					backoff = apiErr.RetryAfter
+				}
+			} else if ctx.Err() == nil {
+				s.report(err)
+			}
+		} else {
+			backoff = s.opts.MinBackoff
+			if err := s.read(ctx, conn); err != nil && ctx.Err() == nil {
+				s.report(err)
+			}
+		}
+
+		// Backoff with jitter
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > s.opts.MaxBackoff {
+			backoff = s.opts.MaxBackoff
+		}
+	}
+}
+
+// url builds the stream URL, resuming from the last block received
+func (s *blockStream) url() string {
+	q := url.Values{}
+	switch {
+	case s.received:
+		// Resume at the last height rather than after it, so a block
+		// replaced by a reorg at that height is not missed
+		q.Set("from_height", strconv.FormatInt(s.lastHeight, 10))
+	case s.opts.FromHeight > 0:
+		q.Set("from_height", strconv.FormatUint(uint64(s.opts.FromHeight), 10))
+	case !s.opts.FromTime.IsZero():
+		q.Set("from_time", s.opts.FromTime.UTC().Format(time.RFC3339))
+	}
+
+	// http(s)://... becomes ws(s)://...
+	return strings.Replace(s.client.endpoint("/v1/"+url.PathEscape(s.chain)+"/stream", q), "http", "ws", 1)
+}
+
+func (s *blockStream) header() http.Header {
+	h := http.Header{}
+	h.Set("User-Agent", s.client.userAgent)
+	s.client.setAuth(h, authAPIKey)
+	if s.opts.Binary {
+		h.Set("Sec-WebSocket-Protocol", streamSubprotocolProto)
+	}
+	return h
+}
+
+// read delivers blocks from conn until it fails or ctx ends
+func (s *blockStream) read(ctx context.Context, conn *websocket.Conn) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(streamPingInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				conn.Close()
+				return
+			case <-t.C:
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+			}
+		}
+	}()
+
+	for {
+		typ, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		msg, err := decodeStreamMessage(typ, data)
+		if err != nil {
+			s.report(err)
+			continue
+		}
+		// Replay markers and other non-block frames
+		if (msg.Type != "" && msg.Type != "block") || msg.Hash == "" {
+			continue
+		}
+		if !s.remember(msg.Hash) {
+			continue
+		}
+		if !s.received || msg.Height > s.lastHeight {
+			s.lastHeight = msg.Height
+		}
+		s.received = true
+
+		select {
+		case s.out <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decodeStreamMessage decodes a block frame: binary frames are protobuf
+// blocks, text frames JSON blocks or markers
+func decodeStreamMessage(typ int, data []byte) (Block, error) {
+	if typ == websocket.BinaryMessage {
+		return decodeBlockProto(data)
+	}
+	var msg Block
+	err := json.Unmarshal(data, &msg)
+	return msg, err
+}
+
+// remember records hash and reports whether it is new
+func (s *blockStream) remember(hash string) bool {
+	if s.seen[hash] {
+		return false
+	}
+	s.seen[hash] = true
+	s.seenOrder = append(s.seenOrder, hash)
+	if len(s.seenOrder) > streamSeenHashes {
+		delete(s.seen, s.seenOrder[0])
+		s.seenOrder = s.seenOrder[1:]
+	}
+	return true
+}
+
+func (s *blockStream) report(err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
@@ -0,0 +1,87 @@
+package sprintclient
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// streamSubprotocolProto asks the server to send blocks as protobuf binary
+// frames; see internal/schemas/proto/block.v1.proto for the message
+const streamSubprotocolProto = "sprint.block.v1+proto"
+
+// decodeBlockProto decodes a binary block frame. Unknown fields are
+// skipped, as the server may add fields to the version.
+func decodeBlockProto(data []byte) (Block, error) {
+	b := Block{Type: "block"}
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return b, fmt.Errorf("sprintclient: block frame: %w", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		switch {
+		case typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(data)
+			if n < 0 {
+				return b, fmt.Errorf("sprintclient: block frame field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			switch num {
+			case 1:
+				b.Version = int(v)
+			case 3:
+				b.Height = int64(v)
+			case 4:
+				b.Timestamp = time.Unix(0, int64(v)).UTC()
+			case 5:
+				b.DetectedAt = time.Unix(0, int64(v)).UTC()
+			case 10:
+				b.IsHeader = v != 0
+			case 14:
+				b.Backfilled = v != 0
+			}
+		case typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(data)
+			if n < 0 {
+				return b, fmt.Errorf("sprintclient: block frame field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			switch num {
+			case 2:
+				b.Hash = v
+			case 7:
+				b.Source = v
+			case 8:
+				b.TxID = v
+			case 9:
+				b.Tier = v
+			case 11:
+				b.Chain = v
+			case 12:
+				b.Status = v
+			case 15:
+				b.KeyID = v
+			case 16:
+				b.Signature = v
+			}
+		case typ == protowire.Fixed64Type && num == 6:
+			v, n := protowire.ConsumeFixed64(data)
+			if n < 0 {
+				return b, fmt.Errorf("sprintclient: block frame field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+			b.RelayTimeMs = math.Float64frombits(v)
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return b, fmt.Errorf("sprintclient: block frame field %d: %w", num, protowire.ParseError(n))
+			}
+			data = data[n:]
+		}
+	}
+	return b, nil
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+"crypto/tls"
+"crypto/x509"
 "encoding/json"
+"errors"
 "fmt"
 "log"
 "net/http"
@@ -36,18 +39,98 @@ if port == "" {
 port = "8081"
 }
 
-http.HandleFunc("/v1/secrets", handleSecrets)
-http.HandleFunc("/v1/service-keys", handleServiceKeys)
+// Secrets endpoints need a client certificate when mutual TLS is on
+mtls := os.Getenv("ENABLE_MTLS") == "true"
+http.Handle("/v1/secrets", requireClientCert(mtls, http.HandlerFunc(handleSecrets)))
+http.Handle("/v1/service-keys", requireClientCert(mtls, http.HandlerFunc(handleServiceKeys)))
 http.HandleFunc("/health", handleHealth)
 
+server := &http.Server{
+Addr:              ":" + port,
+ReadHeaderTimeout: 10 * time.Second,
+}
+
 fmt.Printf("SecureBuffer service starting on port %s...\n", port)
-log.Printf("SecureBuffer service listening on :%s", port)
 
-if err := http.ListenAndServe(":"+port, nil); err != nil {
+if os.Getenv("ENABLE_TLS") == "true" {
+tlsCfg, err := serverTLSConfig(mtls)
+if err != nil {
+log.Fatal("SecureBuffer TLS configuration failed:", err)
+}
+server.TLSConfig = tlsCfg
+log.Printf("SecureBuffer service listening on :%s (TLS)", port)
+if err := server.ListenAndServeTLS(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")); err != nil {
+log.Fatal("SecureBuffer service failed to start:", err)
+}
+return
+}
+
+log.Printf("SecureBuffer service listening on :%s", port)
+if err := server.ListenAndServe(); err != nil {
 log.Fatal("SecureBuffer service failed to start:", err)
 }
 }
 
+// serverTLSConfig mirrors the main service's TLS settings: TLS 1.2+ with
+// ECDHE AEAD suites, and client certificates verified against
+// TLS_CLIENT_CA_FILE when mutual TLS is enabled
+func serverTLSConfig(mtls bool) (*tls.Config, error) {
+if os.Getenv("TLS_CERT_FILE") == "" || os.Getenv("TLS_KEY_FILE") == "" {
+return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE are required")
+}
+
+cfg := &tls.Config{
+MinVersion: tls.VersionTLS12,
+CipherSuites: []uint16{
+tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+},
+CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+}
+switch os.Getenv("TLS_MIN_VERSION") {
+case "", "1.2":
+case "1.3":
+cfg.MinVersion = tls.VersionTLS13
+default:
+return nil, fmt.Errorf("unsupported TLS_MIN_VERSION %q", os.Getenv("TLS_MIN_VERSION"))
+}
+
+if caFile := os.Getenv("TLS_CLIENT_CA_FILE"); caFile != "" {
+pem, err := os.ReadFile(caFile)
+if err != nil {
+return nil, fmt.Errorf("read client CA: %w", err)
+}
+pool := x509.NewCertPool()
+if !pool.AppendCertsFromPEM(pem) {
+return nil, fmt.Errorf("no certificates in %s", caFile)
+}
+cfg.ClientCAs = pool
+cfg.ClientAuth = tls.VerifyClientCertIfGiven
+} else if mtls {
+return nil, errors.New("ENABLE_MTLS requires TLS_CLIENT_CA_FILE")
+}
+return cfg, nil
+}
+
+func requireClientCert(enabled bool, next http.Handler) http.Handler {
+if !enabled {
+return next
+}
+return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+w.Header().Set("Content-Type", "application/json")
+w.WriteHeader(http.StatusForbidden)
+json.NewEncoder(w).Encode(map[string]string{"error": "client certificate required"})
+return
+}
+next.ServeHTTP(w, r)
+})
+}
+
 func handleSecrets(w http.ResponseWriter, r *http.Request) {
 w.Header().Set("Content-Type", "application/json")
 
@@ -310,6 +310,7 @@ extern "C"
 
 	// === Direct Entropy Functions ===
 	SECUREBUFFER_API int fast_entropy_c(unsigned char *output);
+	SECUREBUFFER_API int fast_entropy_batch_c(unsigned char *output, size_t count);
 	SECUREBUFFER_API int hybrid_entropy_c(
 		const unsigned char **headers,
 		const size_t *header_lengths,
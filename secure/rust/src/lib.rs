@@ -1022,6 +1022,28 @@ pub enum UniversalBloomFilterError {
     0 // Success
 }
 
+/// Generate `count` independent 32-byte fast entropy values in one call - Direct FFI export
+#[no_mangle]
+/// # Safety
+///
+/// `output` must be a valid, non-null pointer to at least `count * 32` writable
+/// bytes. The caller retains ownership of the output buffer. Each 32-byte chunk
+/// is filled from a separate `fast_entropy` draw.
+pub unsafe extern "C" fn fast_entropy_batch_c(output: *mut u8, count: usize) -> c_int {
+    if output.is_null() {
+        return -1; // Null pointer error
+    }
+    if count.checked_mul(32).is_none() {
+        return -2; // Size overflow
+    }
+
+    for i in 0..count {
+        let entropy_data = entropy::fast_entropy();
+        std::ptr::copy_nonoverlapping(entropy_data.as_ptr(), output.add(i * 32), 32);
+    }
+    0 // Success
+}
+
 /// Generate hybrid entropy with Bitcoin headers (32 bytes) - Direct FFI export
 #[no_mangle]
 /// # Safety